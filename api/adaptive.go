@@ -0,0 +1,308 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// Defaults of the adaptive controller and its policies
+const (
+	DefaultAdaptInterval   = 5 * time.Second
+	DefaultAdaptRaiseAbove = 90.0 // Percent of the ceiling
+	DefaultAdaptLowerBelow = 50.0 // Percent of the ceiling
+	DefaultAdaptHysteresis = 3    // Consecutive samples
+	DefaultReclaimAbove    = 90.0 // Percent of the interface bandwidth
+)
+
+// AdaptivePolicy bounds the ceiling the adaptive controller gives a class. The ceiling
+// rises while the class uses more than RaiseAbove percent of it and falls while it uses
+// less than LowerBelow percent, one Step at a time and only after the same decision was
+// reached on Hysteresis consecutive samples.
+type AdaptivePolicy struct {
+	MinCeil    string  // Lowest ceiling, e.g. "50mbps"; never below the class guarantee
+	MaxCeil    string  // Highest ceiling
+	Step       string  // Ceiling change per adjustment; a tenth of the range when empty
+	RaiseAbove float64 // DefaultAdaptRaiseAbove when zero
+	LowerBelow float64 // DefaultAdaptLowerBelow when zero
+	Hysteresis int     // DefaultAdaptHysteresis when zero
+}
+
+// CeilAdjustment reports a ceiling changed by the adaptive controller
+type CeilAdjustment struct {
+	Class  string
+	From   tc.Bandwidth
+	To     tc.Bandwidth
+	Reason string // "busy", "idle" or "reclaim"
+}
+
+// adaptiveClass is a class under an adaptive policy and the decisions seen so far
+type adaptiveClass struct {
+	policy         AdaptivePolicy
+	min, max, step tc.Bandwidth
+	streak         int // Consecutive samples deciding to raise (positive) or lower (negative)
+}
+
+// AdaptiveController adjusts the ceilings of classes to their measured load, read from a
+// PressureMonitor: bulk classes borrow the bandwidth left unused, and give it back at once
+// when the interface saturates or a class without a policy, typically an interactive one,
+// starts dropping packets. Ceilings are changed in place, keeping the class counters.
+type AdaptiveController struct {
+	controller   *TrafficController
+	monitor      *PressureMonitor
+	interval     time.Duration
+	reclaimAbove float64
+	logger       logging.Logger
+
+	mu       sync.Mutex
+	classes  map[string]*adaptiveClass
+	onAdjust []func(CeilAdjustment)
+
+	adjustments atomic.Uint64
+}
+
+// NewAdaptiveController creates an adaptive controller reading class pressure from the
+// monitor, which must be running. A non-positive interval selects DefaultAdaptInterval.
+func (controller *TrafficController) NewAdaptiveController(monitor *PressureMonitor, interval time.Duration) *AdaptiveController {
+	if interval <= 0 {
+		interval = DefaultAdaptInterval
+	}
+	return &AdaptiveController{
+		controller:   controller,
+		monitor:      monitor,
+		interval:     interval,
+		reclaimAbove: DefaultReclaimAbove,
+		logger:       controller.logger,
+		classes:      make(map[string]*adaptiveClass),
+	}
+}
+
+// WithReclaimAbove sets the interface utilization, in percent, above which every adaptive
+// class is lowered towards its minimum ceiling
+func (a *AdaptiveController) WithReclaimAbove(percent float64) *AdaptiveController {
+	a.reclaimAbove = percent
+	return a
+}
+
+// OnAdjust registers a hook run from the controller's goroutine after a ceiling changed
+func (a *AdaptiveController) OnAdjust(hook func(CeilAdjustment)) *AdaptiveController {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onAdjust = append(a.onAdjust, hook)
+	return a
+}
+
+// Adapt puts the named class under the policy
+func (a *AdaptiveController) Adapt(class string, policy AdaptivePolicy) error {
+	a.controller.mu.Lock()
+	a.controller.finalizePendingClasses()
+	known := a.controller.hasClass(class)
+	a.controller.mu.Unlock()
+	if !known {
+		return fmt.Errorf("unknown traffic class %q", class)
+	}
+
+	min, err := tc.ParseBandwidth(policy.MinCeil)
+	if err != nil {
+		return fmt.Errorf("invalid minimum ceiling for class %q: %w", class, err)
+	}
+	max, err := tc.ParseBandwidth(policy.MaxCeil)
+	if err != nil {
+		return fmt.Errorf("invalid maximum ceiling for class %q: %w", class, err)
+	}
+	if !max.GreaterThan(min) {
+		return fmt.Errorf("maximum ceiling %s of class %q must be above its minimum %s", max, class, min)
+	}
+	step := max.Sub(min).DivScalar(10)
+	if policy.Step != "" {
+		if step, err = tc.ParseBandwidth(policy.Step); err != nil || step.IsZero() {
+			return fmt.Errorf("invalid ceiling step %q for class %q", policy.Step, class)
+		}
+	}
+
+	if policy.RaiseAbove == 0 {
+		policy.RaiseAbove = DefaultAdaptRaiseAbove
+	}
+	if policy.LowerBelow == 0 {
+		policy.LowerBelow = DefaultAdaptLowerBelow
+	}
+	if policy.LowerBelow < 0 || policy.LowerBelow >= policy.RaiseAbove {
+		return fmt.Errorf("class %q must be lowered below a smaller utilization (%g%%) than it is raised above (%g%%)",
+			class, policy.LowerBelow, policy.RaiseAbove)
+	}
+	if policy.Hysteresis == 0 {
+		policy.Hysteresis = DefaultAdaptHysteresis
+	}
+	if policy.Hysteresis < 0 {
+		return fmt.Errorf("hysteresis of class %q must be a positive number of samples, got %d", class, policy.Hysteresis)
+	}
+
+	a.mu.Lock()
+	a.classes[class] = &adaptiveClass{policy: policy, min: min, max: max, step: step}
+	a.mu.Unlock()
+	return nil
+}
+
+// Run adjusts the ceilings until the context is cancelled
+func (a *AdaptiveController) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	a.logger.Info("Adapting class ceilings to load",
+		logging.String("device", a.controller.deviceName),
+		logging.String("interval", a.interval.String()),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := a.adjust(); err != nil {
+				a.logger.Error("Failed to adjust class ceilings",
+					logging.String("device", a.controller.deviceName),
+					logging.Error(err),
+				)
+			}
+		}
+	}
+}
+
+// Adjustments returns the number of ceilings changed
+func (a *AdaptiveController) Adjustments() uint64 {
+	return a.adjustments.Load()
+}
+
+// adjust evaluates the latest pressure of every class and applies the ceilings it decides
+// on. When applying fails, the ceilings are restored.
+func (a *AdaptiveController) adjust() ([]CeilAdjustment, error) {
+	decisions, err := a.applyDecisions()
+	if err != nil || len(decisions) == 0 {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	hooks := append([]func(CeilAdjustment){}, a.onAdjust...)
+	a.mu.Unlock()
+	for _, adjustment := range decisions {
+		a.adjustments.Add(1)
+		a.logger.Info("Class ceiling adjusted",
+			logging.String("class_name", adjustment.Class),
+			logging.String("from", adjustment.From.String()),
+			logging.String("to", adjustment.To.String()),
+			logging.String("reason", adjustment.Reason),
+		)
+		for _, hook := range hooks {
+			hook(adjustment)
+		}
+	}
+	return decisions, nil
+}
+
+// applyDecisions decides on the ceilings and applies them while holding the controller's
+// lock, so that an Apply from another goroutine neither changes the classes under the
+// decision nor sees the ceilings before they are applied
+func (a *AdaptiveController) applyDecisions() ([]CeilAdjustment, error) {
+	controller := a.controller
+	controller.mu.Lock()
+	defer controller.mu.Unlock()
+
+	a.mu.Lock()
+	decisions := a.decide()
+	a.mu.Unlock()
+	if len(decisions) == 0 {
+		return nil, nil
+	}
+
+	previous := make(map[*TrafficClass]classBandwidth)
+	for _, adjustment := range decisions {
+		class := controller.classByName(adjustment.Class)
+		previous[class] = class.bandwidth()
+		class.maxBandwidth, class.maxSpec = adjustment.To, ""
+	}
+	if _, err := controller.apply(); err != nil {
+		for class, bandwidth := range previous {
+			class.setBandwidth(bandwidth)
+		}
+		return nil, err
+	}
+	return decisions, nil
+}
+
+// decide returns the ceilings to change, in class name order; the caller holds the lock and
+// the controller's
+func (a *AdaptiveController) decide() []CeilAdjustment {
+	controller := a.controller
+
+	// Bandwidth is reclaimed when the interface saturates or a class the controller leaves
+	// alone drops packets
+	var used uint64
+	reclaim := false
+	for _, class := range controller.classes {
+		pressure, ok := a.monitor.Pressure(class.name)
+		if !ok {
+			continue
+		}
+		used += pressure.RateBPS
+		if _, adaptive := a.classes[class.name]; !adaptive && pressure.Dropping() {
+			reclaim = true
+		}
+	}
+	if total := controller.totalBandwidth.BitsPerSecond(); total > 0 && float64(used)/float64(total)*100 > a.reclaimAbove {
+		reclaim = true
+	}
+
+	names := make([]string, 0, len(a.classes))
+	for name := range a.classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var decisions []CeilAdjustment
+	for _, name := range names {
+		adaptive, class := a.classes[name], controller.classByName(name)
+		pressure, ok := a.monitor.Pressure(name)
+		if class == nil || !ok {
+			continue
+		}
+		floor := tc.MaxBandwidth(adaptive.min, class.guaranteedBandwidth)
+		ceiling := class.maxBandwidth
+
+		// Reclaiming acts at once; other decisions must hold for several samples
+		if reclaim && ceiling.GreaterThan(floor) {
+			adaptive.streak = 0
+			decisions = append(decisions, CeilAdjustment{name, ceiling, tc.MaxBandwidth(ceiling.Sub(adaptive.step), floor), "reclaim"})
+			continue
+		}
+
+		direction := 0
+		switch {
+		case reclaim:
+		case pressure.Above(adaptive.policy.RaiseAbove) && ceiling.LessThan(adaptive.max):
+			direction = 1
+		case pressure.Utilization < adaptive.policy.LowerBelow && ceiling.GreaterThan(floor):
+			direction = -1
+		}
+		if direction == 0 || (adaptive.streak > 0) != (direction > 0) {
+			adaptive.streak = 0
+		}
+		adaptive.streak += direction
+		if direction == 0 || adaptive.streak*direction < adaptive.policy.Hysteresis {
+			continue
+		}
+		adaptive.streak = 0
+
+		if direction > 0 {
+			decisions = append(decisions, CeilAdjustment{name, ceiling, tc.MinBandwidth(ceiling.Add(adaptive.step), adaptive.max), "busy"})
+		} else {
+			decisions = append(decisions, CeilAdjustment{name, ceiling, tc.MaxBandwidth(ceiling.Sub(adaptive.step), floor), "idle"})
+		}
+	}
+	return decisions
+}
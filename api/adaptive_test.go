@@ -0,0 +1,152 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestAdaptiveController_LendsAndReclaims(t *testing.T) {
+	mock := netlink.NewMockAdapter()
+	device := tc.MustNewDeviceName("eth0")
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(mock)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("interactive").WithGuaranteedBandwidth("20mbps").
+		WithSoftLimitBandwidth("100mbps").WithPriority(0).ForPort(22)
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").
+		WithSoftLimitBandwidth("30mbps").WithPriority(4).ForPort(873)
+	require.NoError(t, controller.Apply())
+
+	monitor := controller.NewPressureMonitor(time.Hour)
+	adaptive := controller.NewAdaptiveController(monitor, 0)
+	assert.Equal(t, DefaultAdaptInterval, adaptive.interval)
+	require.NoError(t, adaptive.Adapt("bulk", AdaptivePolicy{MinCeil: "30mbps", MaxCeil: "80mbps", Step: "10mbps", Hysteresis: 2}))
+	var seen []CeilAdjustment
+	adaptive.OnAdjust(func(adjustment CeilAdjustment) { seen = append(seen, adjustment) })
+
+	start := time.Now()
+	sent := map[string]uint64{}
+	// sampleAt feeds each class's rate in Mbps over the second before the sample
+	sampleAt := func(second int, interactive, bulk float64, interactiveDrops uint64) {
+		sent["1:10"] += uint64(interactive * 1e6 / 8)
+		sent["1:14"] += uint64(bulk * 1e6 / 8)
+		mock.SetClassStatistics(device, tc.MustParseHandle("1:10"), netlink.ClassStats{BytesSent: sent["1:10"], PacketsDropped: interactiveDrops})
+		mock.SetClassStatistics(device, tc.MustParseHandle("1:14"), netlink.ClassStats{BytesSent: sent["1:14"]})
+		monitor.sample(start.Add(time.Duration(second) * time.Second))
+	}
+	ceilOf := func(class string) tc.Bandwidth { return controller.classByName(class).maxBandwidth }
+
+	sampleAt(0, 0, 0, 0)
+
+	// Bulk at 29 of its 30Mbps must stay busy for two samples before it is raised
+	sampleAt(1, 5, 29, 0)
+	adjustments, err := adaptive.adjust()
+	require.NoError(t, err)
+	assert.Empty(t, adjustments)
+	sampleAt(2, 5, 29, 0)
+	adjustments, err = adaptive.adjust()
+	require.NoError(t, err)
+	require.Len(t, adjustments, 1)
+	assert.Equal(t, "busy", adjustments[0].Reason)
+	assert.Equal(t, tc.Mbps(40), ceilOf("bulk"))
+	assert.Equal(t, []tc.Handle{tc.MustParseHandle("1:14")}, mock.ChangedClasses(device))
+
+	// A dropping interactive class claws the bandwidth back at once
+	sampleAt(3, 30, 39, 1500)
+	adjustments, err = adaptive.adjust()
+	require.NoError(t, err)
+	require.Len(t, adjustments, 1)
+	assert.Equal(t, "reclaim", adjustments[0].Reason)
+	assert.Equal(t, tc.Mbps(30), ceilOf("bulk"))
+
+	// At the minimum ceiling, an idle class is left alone
+	sampleAt(4, 5, 1, 1500)
+	sampleAt(5, 5, 1, 1500)
+	adjustments, err = adaptive.adjust()
+	require.NoError(t, err)
+	assert.Empty(t, adjustments)
+
+	assert.Len(t, seen, 2)
+	assert.Equal(t, uint64(2), adaptive.Adjustments())
+	assert.Equal(t, tc.Mbps(100), ceilOf("interactive"))
+}
+
+func TestAdaptiveController_LowersIdleClass(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").
+		WithSoftLimitBandwidth("60mbps").WithPriority(4)
+	require.NoError(t, controller.Apply())
+
+	monitor := controller.NewPressureMonitor(time.Hour)
+	adaptive := controller.NewAdaptiveController(monitor, time.Minute)
+	require.NoError(t, adaptive.Adapt("bulk", AdaptivePolicy{MinCeil: "5mbps", MaxCeil: "80mbps", Step: "40mbps", Hysteresis: 1}))
+
+	// The ceiling never drops below the guarantee
+	monitor.sample(time.Now())
+	adjustments, err := adaptive.adjust()
+	require.NoError(t, err)
+	require.Len(t, adjustments, 1)
+	assert.Equal(t, "idle", adjustments[0].Reason)
+	assert.Equal(t, tc.Mbps(20), adjustments[0].To)
+
+	adjustments, err = adaptive.adjust()
+	require.NoError(t, err)
+	require.Len(t, adjustments, 1)
+	assert.Equal(t, tc.Mbps(10), adjustments[0].To)
+}
+
+func TestAdaptiveController_ConcurrentApply(t *testing.T) {
+	mock := netlink.NewMockAdapter()
+	device := tc.MustNewDeviceName("eth0")
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(mock)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").
+		WithSoftLimitBandwidth("20mbps").WithPriority(4).ForPort(873)
+	require.NoError(t, controller.Apply())
+
+	monitor := controller.NewPressureMonitor(time.Hour)
+	adaptive := controller.NewAdaptiveController(monitor, time.Minute)
+	require.NoError(t, adaptive.Adapt("bulk", AdaptivePolicy{MinCeil: "20mbps", MaxCeil: "80mbps", Step: "10mbps", Hysteresis: 1}))
+
+	// The adaptive controller raises the busy class while the caller applies; run with -race
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for second := 0; second < 10; second++ {
+			// 19Mbps keeps the class busy until its ceiling has been raised
+			mock.SetClassStatistics(device, tc.MustParseHandle("1:14"), netlink.ClassStats{BytesSent: uint64(second) * 19e6 / 8})
+			monitor.sample(start.Add(time.Duration(second) * time.Second))
+			_, err := adaptive.adjust()
+			assert.NoError(t, err)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, controller.Apply())
+	}
+	<-done
+
+	assert.Equal(t, uint64(1), adaptive.Adjustments())
+	assert.Equal(t, tc.Mbps(30), controller.classByName("bulk").maxBandwidth)
+}
+
+func TestAdaptiveController_RejectsInvalidPolicies(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").WithPriority(4)
+	adaptive := controller.NewAdaptiveController(controller.NewPressureMonitor(0), 0)
+
+	assert.Error(t, adaptive.Adapt("unknown", AdaptivePolicy{MinCeil: "10mbps", MaxCeil: "50mbps"}))
+	assert.Error(t, adaptive.Adapt("bulk", AdaptivePolicy{MinCeil: "50mbps", MaxCeil: "10mbps"}))
+	assert.Error(t, adaptive.Adapt("bulk", AdaptivePolicy{MinCeil: "fast", MaxCeil: "50mbps"}))
+	assert.Error(t, adaptive.Adapt("bulk", AdaptivePolicy{MinCeil: "10mbps", MaxCeil: "50mbps", RaiseAbove: 40}))
+	assert.Error(t, adaptive.Adapt("bulk", AdaptivePolicy{MinCeil: "10mbps", MaxCeil: "50mbps", Hysteresis: -1}))
+	assert.NoError(t, adaptive.Adapt("bulk", AdaptivePolicy{MinCeil: "10mbps", MaxCeil: "50mbps"}))
+	assert.Equal(t, tc.Mbps(4), adaptive.classes["bulk"].step)
+}
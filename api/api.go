@@ -3,11 +3,17 @@ package api
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/domain/events"
 	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
 	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/statstore"
 	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
 	"github.com/rng999/traffic-control-go/pkg/logging"
 	"github.com/rng999/traffic-control-go/pkg/tc"
@@ -17,10 +23,63 @@ import (
 type TrafficController struct {
 	deviceName      string
 	totalBandwidth  tc.Bandwidth
+	bandwidthErr    error // Invalid total bandwidth, reported by validation
 	classes         []*TrafficClass
 	pendingBuilders []*TrafficClassBuilder
 	logger          logging.Logger
 	service         *application.TrafficControlService
+
+	// mu serialises changing the configuration and applying it, so that the scheduler and
+	// the watchers applying from their own goroutines do not interleave with the caller
+	mu sync.Mutex
+
+	applyTimeout     time.Duration // Bound on a whole Apply, zero for none
+	operationTimeout time.Duration // Bound on each netlink operation, zero for none
+	ignoreLinkSpeed  bool          // Apply totals above the link speed the device reports
+	ownership        OwnershipPolicy
+	oversubscription OversubscriptionPolicy
+
+	defaultClass *defaultClassSpec // Class of unclassified traffic, nil for the unnamed 1Mbps class
+
+	classTemplates map[string]ClassTemplate // Templates of CreateClassFromTemplate by name
+
+	ingressDevice string // Device whose ingress is redirected to deviceName, an IFB device
+
+	bpfClassifiers []*bpfClassifier // Attached by Apply to the clsact qdisc
+	hookFilters    []*hookFilter    // Attached by Apply to the clsact qdisc
+	ownsClsact     bool             // Apply added the clsact qdisc, which is deleted once unused
+
+	history    *eventstore.SQLiteEventStore // Database of the configuration history, nil when kept in memory
+	statistics statstore.Store              // Class samples of StatisticsRecorder, in the history's database if any
+	actor      string                       // Author recorded for changes, the process user when empty
+}
+
+// Default deadlines of Apply, so that a hung netlink socket cannot block the caller
+const (
+	DefaultApplyTimeout     = 30 * time.Second
+	DefaultOperationTimeout = 5 * time.Second
+)
+
+// ApplyError is returned by Apply when reconciliation stopped part way, after a failed
+// netlink change or when a deadline passed. The changes it made were rolled back, deleted
+// objects being re-created from the events that created them; an object this library did
+// not create cannot be re-created, and its loss is reported as an incomplete rollback.
+type ApplyError struct {
+	Err          error
+	NotAttempted []string // Objects reconciliation did not reach, e.g. "class 1:11"
+}
+
+// Error implements the error interface
+func (e *ApplyError) Error() string {
+	if len(e.NotAttempted) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (not attempted: %s)", e.Err, strings.Join(e.NotAttempted, ", "))
+}
+
+// Unwrap returns the underlying error
+func (e *ApplyError) Unwrap() error {
+	return e.Err
 }
 
 // TrafficClass represents a traffic classification with its rules
@@ -28,12 +87,46 @@ type TrafficClass struct {
 	name                string
 	guaranteedBandwidth tc.Bandwidth
 	maxBandwidth        tc.Bandwidth
-	priority            *uint8 // Priority is now required and must be explicitly set (0-7, where 0 is highest)
+	bandwidthErrs       []error // Invalid bandwidths given to the builder, reported by validation
+	priority            *uint8  // Priority is now required and must be explicitly set (0-7, where 0 is highest)
 	filters             []Filter
+	lowLatency          bool           // Latency-sensitive class with an aggressive leaf AQM
+	leafQdisc           LeafQdisc      // Queueing discipline under the class, nil for the kernel default
+	actions             []FilterAction // Run on packets matched by the class's filters
+	profile             string         // Tuning profile of the burst, cburst and quantum, empty for the default
+	parentName          string         // Class it is nested under, empty for the root qdisc
+	template            string         // Class template it was created from, empty for none
+
+	borrowingDisabled bool // Ceiling held at the guarantee
+	borrowWeight      int  // Quantum in full frames, 0 for the calculated one
+	quantum           int  // Quantum in bytes, 0 for the calculated one
+	borrowPriority    int  // HTB priority the class borrows at
+
+	guaranteedSpec string       // Guarantee relative to the total, e.g. "30%"; empty when absolute
+	scaledFrom     tc.Bandwidth // Configured guarantee while ScaleGuarantees scales it, zero otherwise
+	maxSpec        string       // Ceiling relative to the total or the guarantee; empty when absolute
+
+	pinnedHandle string    // Handle given with WithHandle, empty to have one assigned
+	handle       tc.Handle // Handle assigned at validation, zero before
+
+	schedules      []classSchedule
+	activeSchedule int            // 1 + index of the schedule in effect, 0 for none
+	unscheduled    classBandwidth // Configured bandwidth, saved while a schedule is in effect
 }
 
 // Priority型は削除: uint8を直接使用
 
+// Low-latency class tuning used by WithLowLatency
+const (
+	lowLatencyHTBQuantum    = 1514  // One full Ethernet frame per round
+	lowLatencyCodelTarget   = 1000  // 1ms target delay (microseconds)
+	lowLatencyCodelInterval = 20000 // 20ms interval (microseconds)
+	lowLatencyCodelQuantum  = 300   // Favour small packets in the flow scheduler
+	lowLatencyCodelLimit    = 1000  // Short queue: latency traffic must not build a backlog
+	lowLatencyCodelFlows    = 1024
+	lowLatencyMaxSharePct   = 20.0 // Guarantee may not exceed this share of the interface
+)
+
 // Filter represents a packet matching rule
 type Filter struct {
 	filterType FilterType
@@ -49,6 +142,10 @@ const (
 	SourcePortFilter
 	DestinationPortFilter
 	ProtocolFilter
+	U32Filter
+	FlowerFilter
+	CgroupFilter
+	DSCPFilter
 )
 
 // NetworkInterface creates a new traffic controller for a network interface
@@ -65,13 +162,53 @@ func NetworkInterface(deviceName string) *TrafficController {
 	service := application.NewTrafficControlService(eventStore, netlinkAdapter, logger)
 
 	return &TrafficController{
-		deviceName: deviceName,
-		classes:    make([]*TrafficClass, 0),
-		logger:     logger,
-		service:    service,
+		deviceName:       deviceName,
+		classes:          make([]*TrafficClass, 0),
+		logger:           logger,
+		service:          service,
+		applyTimeout:     DefaultApplyTimeout,
+		operationTimeout: DefaultOperationTimeout,
+		statistics:       statstore.NewMemoryStore(),
 	}
 }
 
+// WithNetlinkAdapter changes the controller's devices through the given adapter instead of
+// the kernel. It is meant for tests, with the fake adapter of the api/tctest package, and
+// must be called before Apply.
+func (controller *TrafficController) WithNetlinkAdapter(adapter netlink.Adapter) *TrafficController {
+	controller.service = controller.service.WithNetlinkAdapter(adapter)
+	return controller
+}
+
+// WithExecBackend changes the controller's devices by running the tc binary at tcPath,
+// /sbin/tc when empty, instead of speaking netlink: for restricted environments and kernel
+// features the netlink path cannot configure. Statistics are read from tc's output; watching
+// for changes and cgroup filters are not supported. It must be called before Apply.
+func (controller *TrafficController) WithExecBackend(tcPath string) *TrafficController {
+	return controller.WithNetlinkAdapter(netlink.NewExecAdapter(tcPath))
+}
+
+// WithApplyTimeout bounds the time a whole Apply may take; a non-positive timeout removes
+// the bound. Objects not reached in time are reported by the ApplyError.
+func (controller *TrafficController) WithApplyTimeout(timeout time.Duration) *TrafficController {
+	controller.applyTimeout = timeout
+	return controller
+}
+
+// WithOperationTimeout bounds the time each netlink operation of Apply may take; a
+// non-positive timeout removes the bound
+func (controller *TrafficController) WithOperationTimeout(timeout time.Duration) *TrafficController {
+	controller.operationTimeout = timeout
+	return controller
+}
+
+// WithIgnoreLinkSpeed lets Apply set a total bandwidth above the speed the device's link
+// reports, for drivers reporting a wrong speed or links about to be renegotiated faster
+func (controller *TrafficController) WithIgnoreLinkSpeed() *TrafficController {
+	controller.ignoreLinkSpeed = true
+	return controller
+}
+
 // WithHardLimitBandwidth sets the absolute physical bandwidth limit for the network interface
 func (controller *TrafficController) WithHardLimitBandwidth(bandwidth string) *TrafficController {
 	controller.logger.Info("Setting hard limit bandwidth",
@@ -79,7 +216,12 @@ func (controller *TrafficController) WithHardLimitBandwidth(bandwidth string) *T
 		logging.String("operation", logging.OperationConfigLoad),
 	)
 
-	controller.totalBandwidth = tc.MustParseBandwidth(bandwidth)
+	total, err := tc.ParseBandwidth(bandwidth)
+	if err != nil {
+		controller.bandwidthErr = fmt.Errorf("invalid total bandwidth %q: %w", bandwidth, err)
+		return controller
+	}
+	controller.totalBandwidth, controller.bandwidthErr = total, nil
 	return controller
 }
 
@@ -113,18 +255,43 @@ type TrafficClassBuilder struct {
 	finalized  bool
 }
 
-// WithGuaranteedBandwidth sets the minimum guaranteed bandwidth
+// WithGuaranteedBandwidth sets the minimum guaranteed bandwidth, absolute like "30mbps" or a
+// share of the interface total like "30%", resolved whenever the configuration is applied
 func (b *TrafficClassBuilder) WithGuaranteedBandwidth(bandwidth string) *TrafficClassBuilder {
-	b.class.guaranteedBandwidth = tc.MustParseBandwidth(bandwidth)
+	b.class.guaranteedSpec = ""
+	b.class.scaledFrom = tc.Bandwidth{}
+	if isRelativeBandwidth(bandwidth) {
+		b.class.guaranteedSpec = bandwidth
+		return b
+	}
+	b.class.guaranteedBandwidth = b.parseBandwidth("guaranteed", bandwidth)
 	return b
 }
 
-// WithSoftLimitBandwidth sets the policy-based bandwidth limit (borrowing allowed)
+// WithSoftLimitBandwidth sets the policy-based bandwidth limit (borrowing allowed), absolute,
+// a share of the interface total like "50%", or a multiple of the guarantee like
+// "2x guaranteed"
 func (b *TrafficClassBuilder) WithSoftLimitBandwidth(bandwidth string) *TrafficClassBuilder {
-	b.class.maxBandwidth = tc.MustParseBandwidth(bandwidth)
+	b.class.maxSpec = ""
+	b.class.borrowingDisabled = false
+	if isRelativeBandwidth(bandwidth) {
+		b.class.maxSpec = bandwidth
+		return b
+	}
+	b.class.maxBandwidth = b.parseBandwidth("maximum", bandwidth)
 	return b
 }
 
+// parseBandwidth parses an absolute bandwidth given to the builder. An invalid one is
+// recorded for validation to report, so that the fluent calls need not return errors.
+func (b *TrafficClassBuilder) parseBandwidth(kind, bandwidth string) tc.Bandwidth {
+	parsed, err := tc.ParseBandwidth(bandwidth)
+	if err != nil {
+		b.class.bandwidthErrs = append(b.class.bandwidthErrs, fmt.Errorf("invalid %s bandwidth %q: %w", kind, bandwidth, err))
+	}
+	return parsed
+}
+
 // WithPriority sets the traffic class to a specific priority level (0-7)
 func (b *TrafficClassBuilder) WithPriority(priority int) *TrafficClassBuilder {
 	// HTB supports priority values 0-7, where lower numbers = higher priority
@@ -139,6 +306,37 @@ func (b *TrafficClassBuilder) WithPriority(priority int) *TrafficClassBuilder {
 	return b
 }
 
+// WithLowLatency configures the class for sparse, latency-sensitive traffic such as
+// games or VoIP: highest priority, a one-frame HTB quantum and an FQ_CODEL leaf
+// qdisc with a 1ms target. The guarantee must stay small (see validate).
+func (b *TrafficClassBuilder) WithLowLatency() *TrafficClassBuilder {
+	b.class.lowLatency = true
+	p := uint8(0)
+	b.class.priority = &p
+	return b
+}
+
+// WithLeafQdisc attaches a queueing discipline under the class to control how its
+// flows share the class bandwidth, e.g. WithLeafQdisc(api.SFQ().Perturb(10))
+func (b *TrafficClassBuilder) WithLeafQdisc(leaf LeafQdisc) *TrafficClassBuilder {
+	b.class.leafQdisc = leaf
+	return b
+}
+
+// WithActions sets the action chain run, in order, on packets matched by the class's filters,
+// e.g. WithActions(api.MarkPackets(0x10), api.Police("20Mbps", 64000), api.RedirectTo("scrub0"))
+func (b *TrafficClassBuilder) WithActions(actions ...FilterAction) *TrafficClassBuilder {
+	b.class.actions = append(b.class.actions, actions...)
+	return b
+}
+
+// WithDSCPRemark rewrites the DSCP of packets matched by the class's filters, e.g.
+// WithDSCPRemark(46) to mark them Expedited Forwarding for the next hops. It runs after the
+// actions given to WithActions so far.
+func (b *TrafficClassBuilder) WithDSCPRemark(dscp uint8) *TrafficClassBuilder {
+	return b.WithActions(RemarkDSCP(dscp))
+}
+
 // ForDestination adds a destination IP filter
 func (b *TrafficClassBuilder) ForDestination(ip string) *TrafficClassBuilder {
 	b.class.filters = append(b.class.filters, Filter{
@@ -195,6 +393,84 @@ func (b *TrafficClassBuilder) ForProtocols(protocols ...string) *TrafficClassBui
 	return b
 }
 
+// ForProtocol adds a protocol filter: "tcp", "udp", "icmp" or a protocol number
+func (b *TrafficClassBuilder) ForProtocol(protocol string) *TrafficClassBuilder {
+	return b.ForProtocols(protocol)
+}
+
+// ForSourcePort adds source port filters
+func (b *TrafficClassBuilder) ForSourcePort(ports ...int) *TrafficClassBuilder {
+	for _, port := range ports {
+		b.class.filters = append(b.class.filters, Filter{
+			filterType: SourcePortFilter,
+			value:      port,
+		})
+	}
+	return b
+}
+
+// ForPortRange adds filters matching TCP and UDP destination ports from min to max, inclusive,
+// e.g. ForPortRange(10000, 20000) for RTP. Like the other filters it matches on its own; use
+// ForFlower(api.Flower().Protocol("udp").DestinationPortRange(10000, 20000)) to require UDP.
+func (b *TrafficClassBuilder) ForPortRange(min, max int) *TrafficClassBuilder {
+	// u32 could only cover a range with many masked filters; flower compares ranges directly
+	// but needs the transport protocol
+	for _, protocol := range []string{"tcp", "udp"} {
+		b.ForFlower(Flower().Protocol(protocol).DestinationPortRange(min, max))
+	}
+	return b
+}
+
+// ForDSCP adds a filter matching IPv4 packets with the DSCP, from 0 to 63, e.g. ForDSCP(46)
+func (b *TrafficClassBuilder) ForDSCP(dscp uint8) *TrafficClassBuilder {
+	b.class.filters = append(b.class.filters, Filter{
+		filterType: DSCPFilter,
+		value:      strconv.Itoa(int(dscp)),
+	})
+	return b
+}
+
+// ForTrafficClass adds a filter matching IPv4 packets marked with the diffserv class, e.g.
+// ForTrafficClass("EF"), ForTrafficClass("AF41") or ForTrafficClass("CS1")
+func (b *TrafficClassBuilder) ForTrafficClass(name string) *TrafficClassBuilder {
+	b.class.filters = append(b.class.filters, Filter{
+		filterType: DSCPFilter,
+		value:      name,
+	})
+	return b
+}
+
+// ForU32 adds a filter matching packets on which all conditions of the u32 match hold,
+// e.g. ForU32(api.U32().Protocol("tcp").TCPFlags(api.TCPFlagSYN, api.TCPFlagSYN))
+func (b *TrafficClassBuilder) ForU32(match *U32Match) *TrafficClassBuilder {
+	b.class.filters = append(b.class.filters, Filter{
+		filterType: U32Filter,
+		value:      match,
+	})
+	return b
+}
+
+// ForFlower adds a flower filter matching packets on which all conditions of the flower match
+// hold, e.g. ForFlower(api.Flower().VLAN(100).VLANPriority(5))
+func (b *TrafficClassBuilder) ForFlower(match *FlowerMatch) *TrafficClassBuilder {
+	b.class.filters = append(b.class.filters, Filter{
+		filterType: FlowerFilter,
+		value:      match,
+	})
+	return b
+}
+
+// ForCgroup adds a filter matching traffic sent by processes in the cgroup, e.g.
+// ForCgroup("/sys/fs/cgroup/myapp.slice") on cgroup v2 or ForCgroup("/sys/fs/cgroup/net_cls/myapp")
+// on cgroup v1, where the cgroup's net_cls class ID is set to the class
+func (b *TrafficClassBuilder) ForCgroup(path string) *TrafficClassBuilder {
+	b.class.filters = append(b.class.filters, Filter{
+		filterType: CgroupFilter,
+		value:      path,
+	})
+	return b
+}
+
 // Apply completes the builder and adds the class to the controller
 func (b *TrafficClassBuilder) Apply() error {
 	return b.controller.Apply()
@@ -245,6 +521,68 @@ func (controller *TrafficController) CreateFQCODELQdisc(handle string) *FQCODELQ
 	}
 }
 
+// CreateCAKEQdisc creates a CAKE (Common Applications Kept Enhanced) qdisc with fluent interface.
+// The bandwidth is the shaper rate, e.g. "100Mbps"; an empty bandwidth leaves the qdisc unshaped.
+func (controller *TrafficController) CreateCAKEQdisc(handle string, bandwidth string) *CAKEQdiscBuilder {
+	return &CAKEQdiscBuilder{
+		controller: controller,
+		handle:     handle,
+		bandwidth:  bandwidth,
+		rtt:        100000,          // 100ms, the "internet" preset
+		diffserv:   "diffserv3",     // kernel default
+		ackFilter:  "no-ack-filter", // ACK filtering disabled by default
+	}
+}
+
+// CreateNETEMQdisc creates a root NETEM (Network Emulator) qdisc with fluent interface,
+// for emulating delay, jitter, loss, duplication, corruption and reordering on the device
+func (controller *TrafficController) CreateNETEMQdisc(handle string) *NETEMQdiscBuilder {
+	return &NETEMQdiscBuilder{
+		controller: controller,
+		handle:     handle,
+		netem:      NETEM(),
+	}
+}
+
+// CreateREDQdisc creates a root RED (Random Early Detection) qdisc with fluent interface.
+// Marking starts when the average queue exceeds min bytes and reaches the full probability
+// at max bytes.
+func (controller *TrafficController) CreateREDQdisc(handle string, min, max uint32) *REDQdiscBuilder {
+	return &REDQdiscBuilder{
+		controller: controller,
+		handle:     handle,
+		red:        RED(min, max),
+	}
+}
+
+// CreateGREDQdisc creates a root GRED (Generalized RED) qdisc with the given number of
+// virtual queues with fluent interface
+func (controller *TrafficController) CreateGREDQdisc(handle string, queues uint32) *GREDQdiscBuilder {
+	return &GREDQdiscBuilder{
+		controller: controller,
+		handle:     handle,
+		gred:       GRED(queues),
+	}
+}
+
+// CreateCODELQdisc creates a root CoDel (Controlled Delay) qdisc with fluent interface
+func (controller *TrafficController) CreateCODELQdisc(handle string) *CODELQdiscBuilder {
+	return &CODELQdiscBuilder{
+		controller: controller,
+		handle:     handle,
+		codel:      CODEL(),
+	}
+}
+
+// CreateETSQdisc creates a root ETS (Enhanced Transmission Selection) qdisc with fluent interface
+func (controller *TrafficController) CreateETSQdisc(handle string) *ETSQdiscBuilder {
+	return &ETSQdiscBuilder{
+		controller: controller,
+		handle:     handle,
+		ets:        ETS(),
+	}
+}
+
 // HTBQdiscBuilder provides fluent interface for HTB qdiscs
 type HTBQdiscBuilder struct {
 	controller   *TrafficController
@@ -274,21 +612,24 @@ func (b *HTBQdiscBuilder) AddClass(parent, handle, name, rate, ceil string) *HTB
 }
 
 func (b *HTBQdiscBuilder) Apply() error {
-	ctx := context.Background()
-
-	// Create HTB qdisc
-	if err := b.controller.service.CreateHTBQdisc(ctx, b.controller.deviceName, b.handle, b.defaultClass); err != nil {
-		return fmt.Errorf("failed to create HTB qdisc: %w", err)
+	if err := b.checkOversubscription(); err != nil {
+		return err
 	}
+	return b.controller.inTransaction(b.controller.actorContext(context.Background()), func(ctx context.Context) error {
+		// Create HTB qdisc
+		if err := b.controller.service.CreateHTBQdisc(ctx, b.controller.deviceName, b.handle, b.defaultClass); err != nil {
+			return fmt.Errorf("failed to create HTB qdisc: %w", err)
+		}
 
-	// Create classes
-	for _, class := range b.classes {
-		if err := b.controller.service.CreateHTBClass(ctx, b.controller.deviceName, class.parent, class.handle, class.rate, class.ceil); err != nil {
-			return fmt.Errorf("failed to create HTB class %s: %w", class.name, err)
+		// Create classes
+		for _, class := range b.classes {
+			if err := b.controller.service.CreateHTBClass(ctx, b.controller.deviceName, class.parent, class.handle, class.rate, class.ceil); err != nil {
+				return fmt.Errorf("failed to create HTB class %s: %w", class.name, err)
+			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 // TBFQdiscBuilder provides fluent interface for TBF qdiscs
@@ -317,7 +658,7 @@ func (b *TBFQdiscBuilder) WithBurst(burst uint32) *TBFQdiscBuilder {
 }
 
 func (b *TBFQdiscBuilder) Apply() error {
-	ctx := context.Background()
+	ctx := b.controller.actorContext(context.Background())
 	return b.controller.service.CreateTBFQdisc(ctx, b.controller.deviceName, b.handle, b.rate, b.buffer, b.limit, b.burst)
 }
 
@@ -337,7 +678,7 @@ func (b *PRIOQdiscBuilder) WithPriomap(priomap []uint8) *PRIOQdiscBuilder {
 }
 
 func (b *PRIOQdiscBuilder) Apply() error {
-	ctx := context.Background()
+	ctx := b.controller.actorContext(context.Background())
 	return b.controller.service.CreatePRIOQdisc(ctx, b.controller.deviceName, b.handle, b.bands, b.priomap)
 }
 
@@ -384,10 +725,300 @@ func (b *FQCODELQdiscBuilder) WithECN(ecn bool) *FQCODELQdiscBuilder {
 }
 
 func (b *FQCODELQdiscBuilder) Apply() error {
-	ctx := context.Background()
+	ctx := b.controller.actorContext(context.Background())
 	return b.controller.service.CreateFQCODELQdisc(ctx, b.controller.deviceName, b.handle, b.limit, b.flows, b.target, b.interval, b.quantum, b.ecn)
 }
 
+// CAKEQdiscBuilder provides fluent interface for CAKE qdiscs
+type CAKEQdiscBuilder struct {
+	controller *TrafficController
+	handle     string
+	bandwidth  string
+	rtt        uint32
+	diffserv   string
+	nat        bool
+	wash       bool
+	ackFilter  string
+}
+
+// WithRTT sets the expected round trip time in microseconds
+func (b *CAKEQdiscBuilder) WithRTT(rtt uint32) *CAKEQdiscBuilder {
+	b.rtt = rtt
+	return b
+}
+
+// WithDiffserv sets the priority tin layout: "diffserv3", "diffserv4", "diffserv8",
+// "besteffort" or "precedence"
+func (b *CAKEQdiscBuilder) WithDiffserv(mode string) *CAKEQdiscBuilder {
+	b.diffserv = mode
+	return b
+}
+
+// WithNAT enables flow lookup through NAT, for fairness between hosts behind a router
+func (b *CAKEQdiscBuilder) WithNAT(nat bool) *CAKEQdiscBuilder {
+	b.nat = nat
+	return b
+}
+
+// WithWash clears DSCP marks after classification
+func (b *CAKEQdiscBuilder) WithWash(wash bool) *CAKEQdiscBuilder {
+	b.wash = wash
+	return b
+}
+
+// WithAckFilter enables filtering of redundant TCP ACKs; aggressive filtering drops
+// more ACKs at the cost of stricter assumptions about the TCP stack
+func (b *CAKEQdiscBuilder) WithAckFilter(enabled, aggressive bool) *CAKEQdiscBuilder {
+	switch {
+	case enabled && aggressive:
+		b.ackFilter = "ack-filter-aggressive"
+	case enabled:
+		b.ackFilter = "ack-filter"
+	default:
+		b.ackFilter = "no-ack-filter"
+	}
+	return b
+}
+
+func (b *CAKEQdiscBuilder) Apply() error {
+	ctx := b.controller.actorContext(context.Background())
+	return b.controller.service.CreateCAKEQdisc(ctx, b.controller.deviceName, b.handle, b.bandwidth, b.rtt, b.diffserv, b.nat, b.wash, b.ackFilter)
+}
+
+// NETEMQdiscBuilder provides fluent interface for root NETEM qdiscs
+type NETEMQdiscBuilder struct {
+	controller *TrafficController
+	handle     string
+	netem      *NETEMQdisc
+}
+
+// WithDelay delays every packet by the given duration
+func (b *NETEMQdiscBuilder) WithDelay(delay time.Duration) *NETEMQdiscBuilder {
+	b.netem.Delay(delay)
+	return b
+}
+
+// WithJitter varies the delay randomly by up to the given duration
+func (b *NETEMQdiscBuilder) WithJitter(jitter time.Duration) *NETEMQdiscBuilder {
+	b.netem.Jitter(jitter)
+	return b
+}
+
+// WithLoss drops the given percentage of packets
+func (b *NETEMQdiscBuilder) WithLoss(percent float32) *NETEMQdiscBuilder {
+	b.netem.Loss(percent)
+	return b
+}
+
+// WithDuplicate duplicates the given percentage of packets
+func (b *NETEMQdiscBuilder) WithDuplicate(percent float32) *NETEMQdiscBuilder {
+	b.netem.Duplicate(percent)
+	return b
+}
+
+// WithCorrupt flips a random bit in the given percentage of packets
+func (b *NETEMQdiscBuilder) WithCorrupt(percent float32) *NETEMQdiscBuilder {
+	b.netem.Corrupt(percent)
+	return b
+}
+
+// WithReorder sends the given percentage of packets immediately, ahead of delayed ones
+func (b *NETEMQdiscBuilder) WithReorder(percent float32, gap uint32) *NETEMQdiscBuilder {
+	b.netem.Reorder(percent, gap)
+	return b
+}
+
+// WithLimit sets the queue limit in packets
+func (b *NETEMQdiscBuilder) WithLimit(limit uint32) *NETEMQdiscBuilder {
+	b.netem.Limit(limit)
+	return b
+}
+
+func (b *NETEMQdiscBuilder) Apply() error {
+	ctx := b.controller.actorContext(context.Background())
+	return b.controller.service.CreateNETEMQdisc(ctx, b.controller.deviceName, "", b.handle, b.netem.params)
+}
+
+// REDQdiscBuilder provides fluent interface for root RED qdiscs
+type REDQdiscBuilder struct {
+	controller *TrafficController
+	handle     string
+	red        *REDQdisc
+}
+
+// WithLimit sets the hard queue limit in bytes
+func (b *REDQdiscBuilder) WithLimit(bytes uint32) *REDQdiscBuilder {
+	b.red.Limit(bytes)
+	return b
+}
+
+// WithAvpkt sets the average packet size in bytes
+func (b *REDQdiscBuilder) WithAvpkt(bytes uint32) *REDQdiscBuilder {
+	b.red.Avpkt(bytes)
+	return b
+}
+
+// WithBurst sets how many packets may arrive at once; zero derives it from the thresholds
+func (b *REDQdiscBuilder) WithBurst(packets uint32) *REDQdiscBuilder {
+	b.red.Burst(packets)
+	return b
+}
+
+// WithBandwidth sets the link rate used to age the average while the queue is idle
+func (b *REDQdiscBuilder) WithBandwidth(bandwidth string) *REDQdiscBuilder {
+	b.red.Bandwidth(bandwidth)
+	return b
+}
+
+// WithProbability sets the marking probability at the max threshold, between 0 and 1
+func (b *REDQdiscBuilder) WithProbability(probability float64) *REDQdiscBuilder {
+	b.red.Probability(probability)
+	return b
+}
+
+// WithECN marks ECN-capable packets instead of dropping them
+func (b *REDQdiscBuilder) WithECN(ecn bool) *REDQdiscBuilder {
+	b.red.ECN(ecn)
+	return b
+}
+
+// WithHardDrop drops rather than marks packets once the average exceeds max
+func (b *REDQdiscBuilder) WithHardDrop(hardDrop bool) *REDQdiscBuilder {
+	b.red.HardDrop(hardDrop)
+	return b
+}
+
+// WithAdaptive lets the kernel tune the probability to keep the average between the thresholds
+func (b *REDQdiscBuilder) WithAdaptive(adaptive bool) *REDQdiscBuilder {
+	b.red.Adaptive(adaptive)
+	return b
+}
+
+func (b *REDQdiscBuilder) Apply() error {
+	ctx := b.controller.actorContext(context.Background())
+	return b.controller.service.CreateREDQdisc(ctx, b.controller.deviceName, "", b.handle, b.red.params)
+}
+
+// GREDQdiscBuilder provides fluent interface for root GRED qdiscs
+type GREDQdiscBuilder struct {
+	controller *TrafficController
+	handle     string
+	gred       *GREDQdisc
+}
+
+// WithDefault selects the virtual queue of packets whose tc_index selects none
+func (b *GREDQdiscBuilder) WithDefault(dp uint32) *GREDQdiscBuilder {
+	b.gred.Default(dp)
+	return b
+}
+
+// WithGRIO enables RIO mode, where virtual queue averages include higher priority backlog
+func (b *GREDQdiscBuilder) WithGRIO(grio bool) *GREDQdiscBuilder {
+	b.gred.GRIO(grio)
+	return b
+}
+
+// WithECN marks ECN-capable packets instead of dropping them
+func (b *GREDQdiscBuilder) WithECN(ecn bool) *GREDQdiscBuilder {
+	b.gred.ECN(ecn)
+	return b
+}
+
+// WithHardDrop drops rather than marks packets once the average exceeds max
+func (b *GREDQdiscBuilder) WithHardDrop(hardDrop bool) *GREDQdiscBuilder {
+	b.gred.HardDrop(hardDrop)
+	return b
+}
+
+// WithVirtualQueue configures virtual queue dp with the thresholds of a RED qdisc
+func (b *GREDQdiscBuilder) WithVirtualQueue(dp uint32, priority uint8, red *REDQdisc) *GREDQdiscBuilder {
+	b.gred.VirtualQueue(dp, priority, red)
+	return b
+}
+
+func (b *GREDQdiscBuilder) Apply() error {
+	ctx := b.controller.actorContext(context.Background())
+	return b.controller.service.CreateGREDQdisc(ctx, b.controller.deviceName, "", b.handle, b.gred.params)
+}
+
+// CODELQdiscBuilder provides fluent interface for root CoDel qdiscs
+type CODELQdiscBuilder struct {
+	controller *TrafficController
+	handle     string
+	codel      *CODELQdisc
+}
+
+// WithLimit sets the queue limit in packets
+func (b *CODELQdiscBuilder) WithLimit(limit uint32) *CODELQdiscBuilder {
+	b.codel.Limit(limit)
+	return b
+}
+
+// WithTarget sets the acceptable standing queue delay
+func (b *CODELQdiscBuilder) WithTarget(target time.Duration) *CODELQdiscBuilder {
+	b.codel.Target(target)
+	return b
+}
+
+// WithInterval sets the window over which the queue delay must fall below the target
+func (b *CODELQdiscBuilder) WithInterval(interval time.Duration) *CODELQdiscBuilder {
+	b.codel.Interval(interval)
+	return b
+}
+
+// WithCEThreshold ECN marks packets queued longer than the threshold; zero disables it
+func (b *CODELQdiscBuilder) WithCEThreshold(threshold time.Duration) *CODELQdiscBuilder {
+	b.codel.CEThreshold(threshold)
+	return b
+}
+
+// WithECN marks ECN-capable packets instead of dropping them
+func (b *CODELQdiscBuilder) WithECN(ecn bool) *CODELQdiscBuilder {
+	b.codel.ECN(ecn)
+	return b
+}
+
+func (b *CODELQdiscBuilder) Apply() error {
+	ctx := b.controller.actorContext(context.Background())
+	return b.controller.service.CreateCODELQdisc(ctx, b.controller.deviceName, "", b.handle, b.codel.params)
+}
+
+// ETSQdiscBuilder provides fluent interface for root ETS qdiscs
+type ETSQdiscBuilder struct {
+	controller *TrafficController
+	handle     string
+	ets        *ETSQdisc
+}
+
+// WithBands sets the number of bands; by default it is the strict bands plus the quanta
+func (b *ETSQdiscBuilder) WithBands(bands uint8) *ETSQdiscBuilder {
+	b.ets.Bands(bands)
+	return b
+}
+
+// WithStrictBands sets the number of strict priority bands
+func (b *ETSQdiscBuilder) WithStrictBands(bands uint8) *ETSQdiscBuilder {
+	b.ets.Strict(bands)
+	return b
+}
+
+// WithBandQuanta sets the bytes each bandwidth-sharing band may send per round
+func (b *ETSQdiscBuilder) WithBandQuanta(bytes ...uint32) *ETSQdiscBuilder {
+	b.ets.Quanta(bytes...)
+	return b
+}
+
+// WithPriomap maps packet priority i to the i-th band
+func (b *ETSQdiscBuilder) WithPriomap(bands ...uint8) *ETSQdiscBuilder {
+	b.ets.Priomap(bands...)
+	return b
+}
+
+func (b *ETSQdiscBuilder) Apply() error {
+	ctx := b.controller.actorContext(context.Background())
+	return b.controller.service.CreateETSQdisc(ctx, b.controller.deviceName, "", b.handle, b.ets.params)
+}
+
 // finalizePendingClasses automatically registers all pending class builders
 func (controller *TrafficController) finalizePendingClasses() {
 	for _, builder := range controller.pendingBuilders {
@@ -399,8 +1030,18 @@ func (controller *TrafficController) finalizePendingClasses() {
 	controller.pendingBuilders = nil // Clear pending builders
 }
 
-// Apply applies the configuration
+// Apply applies the configuration. It may be called while a scheduler or watcher of the
+// controller applies from another goroutine; the applies run one after the other.
 func (controller *TrafficController) Apply() error {
+	controller.mu.Lock()
+	defer controller.mu.Unlock()
+	_, err := controller.apply()
+	return err
+}
+
+// apply applies the configuration and returns what reconciliation changed. The caller
+// holds mu.
+func (controller *TrafficController) apply() (*application.ReconcileResult, error) {
 	// Finalize any pending class builders
 	controller.finalizePendingClasses()
 
@@ -415,18 +1056,147 @@ func (controller *TrafficController) Apply() error {
 			logging.Error(err),
 			logging.String("operation", logging.OperationValidation),
 		)
-		return err
+		return nil, err
 	}
 
 	controller.logger.Info("Configuration validation successful")
 
-	// Apply configuration through the application service
-	ctx := context.Background()
+	// Plan the desired state, then reconcile the device towards it so that only the
+	// differences reach the kernel; a failure part way through rolls back the changes
+	ctx := controller.ownershipContext(controller.actorContext(context.Background()))
+	if controller.applyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, controller.applyTimeout)
+		defer cancel()
+	}
+	ctx = netlink.WithOperationTimeout(ctx, controller.operationTimeout)
+
+	if err := controller.checkVLANDevice(); err != nil {
+		return nil, err
+	}
+	if err := controller.checkLinkSpeed(); err != nil {
+		return nil, err
+	}
+	if err := controller.checkPrivileges(ctx); err != nil {
+		return nil, err
+	}
 
+	desired, err := controller.plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if controller.ingressDevice != "" {
+		if err := controller.service.CreateIngressRedirect(ctx, controller.ingressDevice, controller.deviceName); err != nil {
+			return nil, err
+		}
+	}
+
+	var result *application.ReconcileResult
+	if err := controller.inTransaction(ctx, func(ctx context.Context) error {
+		result, err = controller.service.Reconcile(ctx, controller.deviceName, desired)
+		return err
+	}); err != nil {
+		applyErr := &ApplyError{Err: err}
+		if result != nil {
+			applyErr.NotAttempted = result.NotAttempted
+		}
+		return nil, applyErr
+	}
+
+	if err := controller.attachToClsact(ctx); err != nil {
+		return nil, err
+	}
+
+	if !result.Changed() {
+		controller.logger.Info("Traffic control configuration already up to date",
+			logging.String("device", controller.deviceName),
+		)
+		return result, nil
+	}
+
+	controller.logger.Info("Traffic control configuration applied successfully",
+		logging.String("device", controller.deviceName),
+		logging.String("total_bandwidth", controller.totalBandwidth.String()),
+		logging.Int("classes_applied", len(controller.classes)),
+	)
+
+	return result, nil
+}
+
+// plan applies the configuration to an empty in-memory copy of the device and returns
+// the events describing the desired state
+func (controller *TrafficController) plan(ctx context.Context) ([]events.DomainEvent, error) {
+	planner, err := controller.planner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return planner.GetDeviceHistory(ctx, controller.deviceName)
+}
+
+// planner applies the configuration to a service backed by an in-memory event store and a
+// mock netlink adapter, whose records are then the planned state of the device
+func (controller *TrafficController) planner(ctx context.Context) (*application.TrafficControlService, error) {
+	planner := application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), netlink.NewMockAdapter(), controller.logger)
+	if err := controller.applyConfiguration(ctx, planner); err != nil {
+		return nil, err
+	}
+	return planner, nil
+}
+
+// inTransaction runs fn and rolls back all changes it made if it fails
+func (controller *TrafficController) inTransaction(ctx context.Context, fn func(context.Context) error) error {
+	tx, err := controller.service.BeginTransaction(ctx, controller.deviceName)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(ctx); err != nil {
+		// Roll back even when the failure was the deadline passing
+		if rollbackErr := tx.Rollback(context.WithoutCancel(ctx)); rollbackErr != nil {
+			controller.logger.Error("Rollback after failed apply was incomplete",
+				logging.Error(rollbackErr),
+				logging.String("device", controller.deviceName),
+			)
+			return fmt.Errorf("%w (rollback incomplete: %v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// filterPriorityStride returns the width of the filter priority range of each class: ten,
+// or the most matches of a class when one has more, so that the ranges never overlap
+func (controller *TrafficController) filterPriorityStride(classes []*TrafficClass) int {
+	stride := 10
+	for _, class := range classes {
+		if matches, _ := controller.filterMatches(class); len(matches) > stride {
+			stride = len(matches)
+		}
+	}
+	return stride
+}
+
+// filterMatches returns the matches of the class's filters, with the filter kind of each
+func (controller *TrafficController) filterMatches(class *TrafficClass) ([]map[string]string, []string) {
+	var matches []map[string]string
+	var kinds []string
+	for _, filter := range class.filters {
+		for _, match := range controller.buildFilterMatches(filter) {
+			matches = append(matches, match)
+			kinds = append(kinds, filter.kind())
+		}
+	}
+	return matches, kinds
+}
+
+// applyConfiguration creates the root qdisc, classes, filters and default class through the given service
+func (controller *TrafficController) applyConfiguration(ctx context.Context, service *application.TrafficControlService) error {
 	// Create HTB qdisc
 	handle := "1:0"
-	defaultClass := "1:999" // Default class for unclassified traffic
-	if err := controller.service.CreateHTBQdisc(ctx, controller.deviceName, handle, defaultClass); err != nil {
+	if err := service.CreateHTBQdisc(ctx, controller.deviceName, handle, defaultClassHandle); err != nil {
 		controller.logger.Error("Failed to create HTB qdisc",
 			logging.Error(err),
 			logging.String("device", controller.deviceName),
@@ -434,10 +1204,16 @@ func (controller *TrafficController) Apply() error {
 		return fmt.Errorf("failed to create HTB qdisc: %w", err)
 	}
 
-	// Create classes
-	for i, class := range controller.classes {
-		classID := fmt.Sprintf("1:%d", int(*class.priority)+10) // Use priority to determine handle (1:10-1:17)
-		parent := "1:0"                                         // Parent is the root qdisc
+	// Create classes, parents before the classes nested under them
+	ordered, _ := controller.classesParentsFirst()
+	stride := controller.filterPriorityStride(ordered)
+	for i, class := range ordered {
+		classID := classHandle(class)
+		parent := "1:0" // Parent is the root qdisc
+		classParent := parent
+		if parentClass := controller.parentOf(class); parentClass != nil {
+			classParent = classHandle(parentClass)
+		}
 
 		controller.logger.Debug("Creating HTB class",
 			logging.String("class_name", class.name),
@@ -447,8 +1223,7 @@ func (controller *TrafficController) Apply() error {
 		)
 
 		// Use advanced HTB class creation to include priority and other parameters
-		if err := controller.service.CreateHTBClassWithAdvancedParameters(ctx, controller.deviceName, parent, classID, class.name,
-			class.guaranteedBandwidth.String(), class.maxBandwidth.String(), *class.priority); err != nil {
+		if err := controller.createClass(ctx, service, classParent, classID, class); err != nil {
 			controller.logger.Error("Failed to create HTB class",
 				logging.Error(err),
 				logging.String("class_name", class.name),
@@ -456,7 +1231,10 @@ func (controller *TrafficController) Apply() error {
 			return fmt.Errorf("failed to create HTB class %s: %w", class.name, err)
 		}
 
-		// Create filters for the class
+		// Create filters for the class; parents only lend bandwidth to the classes under them
+		if controller.hasChildren(class) {
+			continue
+		}
 		if len(class.filters) == 0 {
 			// Create a catch-all filter if no specific filters are defined
 			priority := uint16(100) // Default priority for catch-all
@@ -464,8 +1242,8 @@ func (controller *TrafficController) Apply() error {
 			flowID := classID
 			match := make(map[string]string) // Empty match = catch all
 
-			if err := controller.service.CreateFilter(ctx, controller.deviceName, parent, priority,
-				protocol, flowID, match); err != nil {
+			if err := service.CreateFilterWithActions(ctx, controller.deviceName, parent, priority,
+				protocol, flowID, match, filterActionSpecs(class.actions)); err != nil {
 				controller.logger.Error("Failed to create catch-all filter",
 					logging.Error(err),
 					logging.String("class_name", class.name),
@@ -474,31 +1252,26 @@ func (controller *TrafficController) Apply() error {
 			}
 		} else {
 			// Create explicit filters
-			for j, filter := range class.filters {
-				// Use different priority ranges for each class to avoid conflicts
+			matches, kinds := controller.filterMatches(class)
+			for j, match := range matches {
+				// Use different priority ranges for each class to avoid conflicts: with the
+				// usual stride, class 0 gets 100-109, class 1 110-119, etc.
 				// Check for potential overflow before conversion
-				baseValue := 100 + i*10
-				if baseValue > 65525 || j > 9 { // Prevent overflow
+				value := 100 + i*stride + j
+				if value > 65535 { // Prevent overflow
 					return fmt.Errorf("too many filters or classes: would overflow uint16")
 				}
 				// #nosec G115 -- overflow check performed above
-				basePriority := uint16(baseValue) // Class 0: 100-109, Class 1: 110-119, etc.
-				// #nosec G115 -- overflow check performed above
-				priority := basePriority + uint16(j)
+				priority := uint16(value)
 				protocol := "ip"
 				flowID := classID
 
-				match := controller.buildFilterMatch(filter)
-				if len(match) == 0 {
-					continue // Skip unsupported filters
-				}
-
-				if err := controller.service.CreateFilter(ctx, controller.deviceName, parent, priority,
-					protocol, flowID, match); err != nil {
+				if err := service.CreateFilterOfKind(ctx, kinds[j], controller.deviceName, parent, priority,
+					protocol, flowID, match, filterActionSpecs(class.actions)); err != nil {
 					controller.logger.Error("Failed to create filter",
 						logging.Error(err),
 						logging.String("class_name", class.name),
-						logging.String("filter_type", fmt.Sprintf("%v", filter.filterType)),
+						logging.String("match", fmt.Sprintf("%v", match)),
 					)
 					return fmt.Errorf("failed to create filter for class %s: %w", class.name, err)
 				}
@@ -506,24 +1279,107 @@ func (controller *TrafficController) Apply() error {
 		}
 	}
 
-	// Create default class for unclassified traffic
-	if err := controller.service.CreateHTBClass(ctx, controller.deviceName, "1:0", "1:999",
-		"1mbit", controller.totalBandwidth.String()); err != nil {
+	// Create default class for unclassified traffic, named when configured so that its
+	// statistics tell what it holds
+	defaultRate := fmt.Sprintf("%dbit", controller.defaultClassRate().BitsPerSecond())
+	var err error
+	if controller.defaultClass != nil {
+		err = service.CreateHTBClassWithAdvancedParameters(ctx, controller.deviceName, "1:0", defaultClassHandle,
+			controller.defaultClass.name, defaultRate, controller.totalBandwidth.String(), defaultClassPriority)
+	} else {
+		err = service.CreateHTBClass(ctx, controller.deviceName, "1:0", defaultClassHandle,
+			defaultRate, controller.totalBandwidth.String())
+	}
+	if err != nil {
 		controller.logger.Error("Failed to create default HTB class",
 			logging.Error(err),
 		)
 		return fmt.Errorf("failed to create default HTB class: %w", err)
 	}
 
-	controller.logger.Info("Traffic control configuration applied successfully",
-		logging.String("device", controller.deviceName),
-		logging.String("total_bandwidth", controller.totalBandwidth.String()),
-		logging.Int("classes_applied", len(controller.classes)),
-	)
+	return nil
+}
+
+// classHandle returns the HTB class handle of a traffic class: the one assigned at
+// validation, or before that the one its priority derives (1:10-1:17)
+func classHandle(class *TrafficClass) string {
+	if class.handle.Minor() != 0 {
+		return class.handle.String()
+	}
+	return fmt.Sprintf("1:%d", int(*class.priority)+10)
+}
+
+// leafHandle returns the handle of a traffic class's leaf qdisc; its major number mirrors
+// the class minor (1:10 -> 10:0)
+func leafHandle(class *TrafficClass) string {
+	_, minor, _ := strings.Cut(classHandle(class), ":")
+	return minor + ":0"
+}
+
+// createClass creates the HTB class for a traffic class, with the parameters its borrowing
+// controls and tuning profile set, and its leaf qdisc: the configured one, or the leaf AQM
+// for low-latency classes
+func (controller *TrafficController) createClass(ctx context.Context, service *application.TrafficControlService, parent, classID string, class *TrafficClass) error {
+	var err error
+	if params := class.htbParameters(); params == (application.HTBClassParameters{}) {
+		err = service.CreateHTBClassWithAdvancedParameters(ctx, controller.deviceName, parent, classID, class.name,
+			class.guaranteedBandwidth.String(), class.maxBandwidth.String(), *class.priority)
+	} else {
+		err = service.CreateHTBClassWithParameters(ctx, controller.deviceName, parent, classID, class.name,
+			class.guaranteedBandwidth.String(), class.maxBandwidth.String(), *class.priority, params)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !class.lowLatency {
+		if class.leafQdisc == nil {
+			return nil
+		}
+		if err := class.leafQdisc.create(ctx, service, controller.deviceName, classID, leafHandle(class)); err != nil {
+			return fmt.Errorf("failed to create leaf qdisc: %w", err)
+		}
+		return nil
+	}
+
+	if err := service.CreateLeafFQCODELQdisc(ctx, controller.deviceName, classID, leafHandle(class),
+		lowLatencyCodelLimit, lowLatencyCodelFlows, lowLatencyCodelTarget, lowLatencyCodelInterval,
+		lowLatencyCodelQuantum, true); err != nil {
+		return fmt.Errorf("failed to create low-latency leaf qdisc: %w", err)
+	}
 
 	return nil
 }
 
+// buildFilterMatches converts a Filter to the match maps of the CQRS commands installing it;
+// unsupported filters yield none
+func (controller *TrafficController) buildFilterMatches(filter Filter) []map[string]string {
+	if u32, ok := filter.value.(*U32Match); ok && filter.filterType == U32Filter {
+		return u32.matches()
+	}
+	if flower, ok := filter.value.(*FlowerMatch); ok && filter.filterType == FlowerFilter {
+		return []map[string]string{flower.matches()}
+	}
+
+	match := controller.buildFilterMatch(filter)
+	if len(match) == 0 {
+		return nil
+	}
+	return []map[string]string{match}
+}
+
+// kind returns the classifier installing the filter
+func (filter Filter) kind() string {
+	switch filter.filterType {
+	case FlowerFilter:
+		return "flower"
+	case CgroupFilter:
+		return "cgroup"
+	default:
+		return "u32"
+	}
+}
+
 // buildFilterMatch converts a Filter to a match map for the CQRS command
 func (controller *TrafficController) buildFilterMatch(filter Filter) map[string]string {
 	match := make(map[string]string)
@@ -549,107 +1405,19 @@ func (controller *TrafficController) buildFilterMatch(filter Filter) map[string]
 		if proto, ok := filter.value.(string); ok {
 			match["protocol"] = proto
 		}
-	}
-
-	return match
-}
-
-// validate checks if the configuration is valid
-func (controller *TrafficController) validate() error {
-	controller.logger.Debug("Starting configuration validation",
-		logging.String("operation", logging.OperationValidation),
-		logging.Int("class_count", len(controller.classes)),
-	)
-
-	if controller.totalBandwidth.BitsPerSecond() == 0 {
-		controller.logger.Warn("Total bandwidth not set",
-			logging.String("validation_error", "missing_total_bandwidth"),
-		)
-		return fmt.Errorf("total bandwidth not set. Use WithHardLimitBandwidth() to specify the interface bandwidth")
-	}
-
-	// Check if all classes have priority set
-	for _, class := range controller.classes {
-		if class.priority == nil {
-			controller.logger.Warn("Traffic class missing priority",
-				logging.String("class_name", class.name),
-				logging.String("validation_error", "missing_priority"),
-			)
-			return fmt.Errorf(
-				"class '%s' does not have a priority set\n"+
-					"Priority is required for all traffic classes. Use WithPriority(0-7) to set it",
-				class.name,
-			)
+	case CgroupFilter:
+		if path, ok := filter.value.(string); ok {
+			match["cgroup"] = path
 		}
-	}
-
-	// Check if guaranteed bandwidth sum doesn't exceed total
-	var totalGuaranteed tc.Bandwidth
-	for _, class := range controller.classes {
-		totalGuaranteed = totalGuaranteed.Add(class.guaranteedBandwidth)
-
-		controller.logger.Debug("Validating traffic class",
-			logging.String("class_name", class.name),
-			logging.String("guaranteed_bandwidth", class.guaranteedBandwidth.String()),
-			logging.String("max_bandwidth", class.maxBandwidth.String()),
-			logging.Int("priority", int(*class.priority)),
-		)
-
-		// Check if max bandwidth exceeds total
-		if class.maxBandwidth.GreaterThan(controller.totalBandwidth) {
-			controller.logger.Warn("Class max bandwidth exceeds total bandwidth",
-				logging.String("class_name", class.name),
-				logging.String("max_bandwidth", class.maxBandwidth.String()),
-				logging.String("total_bandwidth", controller.totalBandwidth.String()),
-				logging.String("validation_error", "max_exceeds_total"),
-			)
-			return fmt.Errorf(
-				"class '%s' has max bandwidth (%s) higher than total bandwidth (%s)\n"+
-					"Suggestion: Either reduce the max bandwidth or increase the total bandwidth",
-				class.name,
-				class.maxBandwidth,
-				controller.totalBandwidth,
-			)
-		}
-
-		// Check if guaranteed > max
-		if class.guaranteedBandwidth.GreaterThan(class.maxBandwidth) && class.maxBandwidth.BitsPerSecond() > 0 {
-			controller.logger.Warn("Class guaranteed bandwidth exceeds max bandwidth",
-				logging.String("class_name", class.name),
-				logging.String("guaranteed_bandwidth", class.guaranteedBandwidth.String()),
-				logging.String("max_bandwidth", class.maxBandwidth.String()),
-				logging.String("validation_error", "guaranteed_exceeds_max"),
-			)
-			return fmt.Errorf(
-				"class '%s' has guaranteed bandwidth (%s) higher than max bandwidth (%s)\n"+
-					"Suggestion: Set max bandwidth higher than or equal to guaranteed bandwidth",
-				class.name,
-				class.guaranteedBandwidth,
-				class.maxBandwidth,
-			)
+	case DSCPFilter:
+		if name, ok := filter.value.(string); ok {
+			if dscp, err := entities.ParseDSCP(name); err == nil {
+				match["dscp"] = strconv.Itoa(int(dscp))
+			}
 		}
 	}
 
-	if totalGuaranteed.GreaterThan(controller.totalBandwidth) {
-		controller.logger.Warn("Total guaranteed bandwidth exceeds interface bandwidth",
-			logging.String("total_guaranteed", totalGuaranteed.String()),
-			logging.String("total_bandwidth", controller.totalBandwidth.String()),
-			logging.String("validation_error", "total_guaranteed_exceeds_total"),
-		)
-		return fmt.Errorf(
-			"total guaranteed bandwidth (%s) exceeds interface bandwidth (%s)\n"+
-				"Suggestion: Reduce guaranteed bandwidths or increase total bandwidth",
-			totalGuaranteed,
-			controller.totalBandwidth,
-		)
-	}
-
-	controller.logger.Debug("Configuration validation completed successfully",
-		logging.String("total_guaranteed", totalGuaranteed.String()),
-		logging.String("total_bandwidth", controller.totalBandwidth.String()),
-	)
-
-	return nil
+	return match
 }
 
 // GetStatistics retrieves current traffic control statistics
@@ -681,3 +1449,12 @@ func (controller *TrafficController) GetClassStatistics(handle string) (*qmodels
 	ctx := context.Background()
 	return controller.service.GetClassStatistics(ctx, controller.deviceName, handle)
 }
+
+// ReadCurrentConfiguration reads the qdiscs, classes and filters currently installed on the
+// interface, including configuration that was not created through this controller. Apply
+// adopts such objects, without re-creating them, when the kernel reports them as configured:
+// the HTB qdisc with its default class, HTB classes with their rates, and their filters.
+func (controller *TrafficController) ReadCurrentConfiguration() (*qmodels.ConfigurationView, error) {
+	ctx := context.Background()
+	return controller.service.ReadCurrentConfiguration(ctx, controller.deviceName)
+}
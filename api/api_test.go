@@ -1,14 +1,20 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
 	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
 	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
 	"github.com/rng999/traffic-control-go/pkg/tc"
 )
 
@@ -74,12 +80,15 @@ func TestTrafficController_WithHardLimitBandwidth(t *testing.T) {
 		}
 	})
 
-	t.Run("panics_with_invalid_bandwidth_format", func(t *testing.T) {
+	t.Run("reports_invalid_bandwidth_format", func(t *testing.T) {
 		controller := NetworkInterface("eth0")
 
-		assert.Panics(t, func() {
+		assert.NotPanics(t, func() {
 			controller.WithHardLimitBandwidth("invalid")
 		})
+		report := controller.Validate()
+		require.NotNil(t, findIssue(report.Errors, "invalid_bandwidth"))
+		assert.Contains(t, report.Err().Error(), `invalid total bandwidth "invalid"`)
 	})
 }
 
@@ -238,6 +247,43 @@ func TestTrafficClassBuilder(t *testing.T) {
 		assert.Equal(t, ProtocolFilter, builder.class.filters[1].filterType)
 		assert.Equal(t, "udp", builder.class.filters[1].value)
 	})
+
+	t.Run("adds_single_protocol_filter", func(t *testing.T) {
+		builder := controller.CreateTrafficClass("voip")
+
+		builder.ForProtocol("udp")
+
+		require.Len(t, builder.class.filters, 1)
+		assert.Equal(t, ProtocolFilter, builder.class.filters[0].filterType)
+		assert.Equal(t, "udp", builder.class.filters[0].value)
+	})
+
+	t.Run("adds_source_port_filters", func(t *testing.T) {
+		builder := controller.CreateTrafficClass("dns")
+
+		builder.ForSourcePort(53, 853)
+
+		require.Len(t, builder.class.filters, 2)
+		assert.Equal(t, SourcePortFilter, builder.class.filters[0].filterType)
+		assert.Equal(t, 53, builder.class.filters[0].value)
+		assert.Equal(t, SourcePortFilter, builder.class.filters[1].filterType)
+		assert.Equal(t, 853, builder.class.filters[1].value)
+	})
+
+	t.Run("adds_port_range_filters", func(t *testing.T) {
+		builder := controller.CreateTrafficClass("rtp")
+
+		builder.ForPortRange(10000, 20000)
+
+		// One flower filter per transport protocol
+		require.Len(t, builder.class.filters, 2)
+		for i, protocol := range []string{"tcp", "udp"} {
+			assert.Equal(t, FlowerFilter, builder.class.filters[i].filterType)
+			flower := builder.class.filters[i].value.(*FlowerMatch)
+			require.NoError(t, flower.err())
+			assert.Equal(t, map[string]string{"protocol": protocol, "dst_port_range": "10000-20000"}, flower.matches())
+		}
+	})
 }
 
 // TestTrafficController_Validation tests configuration validation
@@ -338,6 +384,531 @@ func TestTrafficController_Validation(t *testing.T) {
 	})
 }
 
+// TestTrafficClassBuilder_WithLowLatency tests the low-latency class option
+func TestTrafficClassBuilder_WithLowLatency(t *testing.T) {
+	t.Run("sets_highest_priority", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		builder := controller.CreateTrafficClass("voip").WithLowLatency()
+
+		assert.True(t, builder.class.lowLatency)
+		require.NotNil(t, builder.class.priority)
+		assert.Equal(t, uint8(0), *builder.class.priority)
+	})
+
+	t.Run("rejects_large_guarantee", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("gaming").
+			WithGuaranteedBandwidth("50mbps").
+			WithLowLatency()
+
+		err := controller.Apply()
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "low-latency class 'gaming'")
+	})
+
+	t.Run("attaches_fq_codel_leaf_qdisc", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("voip").
+			WithGuaranteedBandwidth("2mbps").
+			WithSoftLimitBandwidth("10mbps").
+			WithLowLatency()
+
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+		require.NoError(t, controller.Apply())
+
+		device, _ := tc.NewDeviceName("eth0")
+		qdiscs := mockNetlinkAdapter.GetQdiscs(device)
+		require.True(t, qdiscs.IsSuccess())
+
+		var leaf *netlink.QdiscInfo
+		for _, q := range qdiscs.Value() {
+			if q.Handle == tc.NewHandle(0x10, 0) {
+				q := q
+				leaf = &q
+			}
+		}
+		require.NotNil(t, leaf, "expected leaf qdisc 10:0")
+		require.NotNil(t, leaf.Parent)
+		assert.Equal(t, "1:10", leaf.Parent.String())
+	})
+}
+
+// TestTrafficClassBuilder_WithActions tests action chains on class filters
+func TestTrafficClassBuilder_WithActions(t *testing.T) {
+	t.Run("applies_action_chain_to_class_filters", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("suspicious").
+			WithGuaranteedBandwidth("10mbps").
+			WithPriority(5).
+			ForSource("203.0.113.0/24").
+			WithActions(MarkPackets(0x10), Police("20mbps", 64000), RedirectTo("scrub0"))
+
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+		require.NoError(t, controller.Apply())
+
+		device, _ := tc.NewDeviceName("eth0")
+		filters := mockNetlinkAdapter.GetFilters(device).Value()
+		require.Len(t, filters, 1)
+		require.Len(t, filters[0].Actions, 3)
+		assert.Equal(t, entities.ActionTypeMark, filters[0].Actions[0].Type())
+		assert.Equal(t, entities.ActionTypePolice, filters[0].Actions[1].Type())
+		assert.Equal(t, entities.ActionTypeRedirect, filters[0].Actions[2].Type())
+	})
+
+	t.Run("rejects_redirect_before_end_of_chain", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("suspicious").
+			WithGuaranteedBandwidth("10mbps").
+			WithPriority(5).
+			WithActions(RedirectTo("scrub0"), MarkPackets(0x10))
+
+		err := controller.Apply()
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "before the end of its action chain")
+	})
+
+	t.Run("applies_skbedit_and_verdict_actions", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("blocked").
+			WithGuaranteedBandwidth("10mbps").
+			WithPriority(5).
+			ForSource("198.51.100.0/24").
+			WithActions(SetPriority("1:15"), SetQueue(2), MirrorTo("mon0"), Drop())
+
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+		require.NoError(t, controller.Apply())
+
+		filters := mockNetlinkAdapter.GetFilters(tc.MustNewDeviceName("eth0")).Value()
+		require.Len(t, filters, 1)
+		var actions []string
+		for _, action := range filters[0].Actions {
+			actions = append(actions, action.String())
+		}
+		assert.Equal(t, []string{"skbedit priority 1:15", "skbedit queue_mapping 2", "mirred egress mirror dev mon0", "gact drop"}, actions)
+	})
+
+	t.Run("rejects_drop_before_end_of_chain", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("blocked").
+			WithGuaranteedBandwidth("10mbps").
+			WithPriority(5).
+			WithActions(Drop(), MarkPackets(0x10))
+
+		err := controller.Apply()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "has a drop before the end of its action chain")
+	})
+
+	t.Run("rejects_invalid_priority", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("blocked").
+			WithGuaranteedBandwidth("10mbps").
+			WithPriority(5).
+			WithActions(SetPriority("high"))
+
+		err := controller.Apply()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid packet priority")
+	})
+}
+
+// TestTrafficClassBuilder_DSCP tests diffserv classification and remarking
+func TestTrafficClassBuilder_DSCP(t *testing.T) {
+	t.Run("matches_and_remarks_dscp", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("voice").
+			WithGuaranteedBandwidth("10mbps").
+			WithPriority(0).
+			ForTrafficClass("EF").
+			ForDSCP(34)
+		controller.CreateTrafficClass("bulk").
+			WithGuaranteedBandwidth("10mbps").
+			WithPriority(6).
+			ForSource("10.0.0.0/8").
+			WithDSCPRemark(8)
+
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+		require.NoError(t, controller.Apply())
+
+		var dscpMatches []string
+		var remarks []string
+		for _, filter := range mockNetlinkAdapter.GetFilters(tc.MustNewDeviceName("eth0")).Value() {
+			for _, match := range filter.Matches {
+				if match.Type == entities.MatchTypeDSCP {
+					dscpMatches = append(dscpMatches, match.Value.(string))
+				}
+			}
+			for _, action := range filter.Actions {
+				if remark, ok := action.(*entities.DSCPRemarkAction); ok {
+					remarks = append(remarks, remark.String())
+				}
+			}
+		}
+		assert.ElementsMatch(t, []string{"ip tos 0xb8 0xfc", "ip tos 0x88 0xfc"}, dscpMatches)
+		assert.Equal(t, []string{"pedit ex munge ip dsfield set 8"}, remarks)
+	})
+
+	t.Run("rejects_unknown_traffic_class", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("voice").
+			WithGuaranteedBandwidth("10mbps").
+			WithPriority(0).
+			ForTrafficClass("AF44")
+
+		err := controller.Apply()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid DSCP filter")
+	})
+
+	t.Run("rejects_dscp_remark_out_of_range", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("voice").
+			WithGuaranteedBandwidth("10mbps").
+			WithPriority(0).
+			WithDSCPRemark(64)
+
+		err := controller.Apply()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "remarks packets with DSCP 64")
+	})
+}
+
+// TestTrafficController_ManyFilterMatches tests that a class with more than ten filter
+// matches gets its filters without sharing priorities with the next class
+func TestTrafficController_ManyFilterMatches(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("media").
+		WithGuaranteedBandwidth("10mbps").
+		WithPriority(1).
+		ForPort(80, 443, 554, 1935, 8080, 8443).
+		ForPortRange(10000, 20000).
+		ForPortRange(30000, 31000).
+		ForPortRange(40000, 41000)
+	controller.CreateTrafficClass("ssh").
+		WithGuaranteedBandwidth("10mbps").
+		WithPriority(2).
+		ForPort(22)
+
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+	require.NoError(t, controller.Apply())
+
+	priorities := map[string][]uint16{}
+	for _, filter := range mockNetlinkAdapter.GetFilters(tc.MustNewDeviceName("eth0")).Value() {
+		priorities[filter.FlowID.String()] = append(priorities[filter.FlowID.String()], filter.Priority)
+	}
+	media, ssh := priorities["1:11"], priorities["1:12"]
+	sort.Slice(media, func(i, j int) bool { return media[i] < media[j] })
+	require.Len(t, media, 12)
+	assert.Equal(t, uint16(100), media[0])
+	assert.Equal(t, uint16(111), media[11])
+	assert.Equal(t, []uint16{112}, ssh)
+}
+
+// TestTrafficClassBuilder_WithLeafQdisc tests attaching leaf qdiscs to classes
+func TestTrafficClassBuilder_WithLeafQdisc(t *testing.T) {
+	t.Run("attaches_sfq_and_fq_leaf_qdiscs", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("web").
+			WithGuaranteedBandwidth("20mbps").
+			WithPriority(1).
+			WithLeafQdisc(SFQ().Perturb(10))
+		controller.CreateTrafficClass("uploads").
+			WithGuaranteedBandwidth("10mbps").
+			WithPriority(4).
+			WithLeafQdisc(FQ().FlowLimit(50).MaxRate("5mbps"))
+
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+		require.NoError(t, controller.Apply())
+
+		device, _ := tc.NewDeviceName("eth0")
+		leaves := make(map[tc.Handle]netlink.QdiscInfo)
+		for _, q := range mockNetlinkAdapter.GetQdiscs(device).Value() {
+			if q.Parent != nil {
+				leaves[q.Handle] = q
+			}
+		}
+		require.Len(t, leaves, 2)
+		assert.Equal(t, entities.QdiscTypeSFQ, leaves[tc.NewHandle(0x11, 0)].Type)
+		assert.Equal(t, "1:11", leaves[tc.NewHandle(0x11, 0)].Parent.String())
+		assert.Equal(t, entities.QdiscTypeFQ, leaves[tc.NewHandle(0x14, 0)].Type)
+		assert.Equal(t, "1:14", leaves[tc.NewHandle(0x14, 0)].Parent.String())
+	})
+
+	t.Run("rejects_leaf_qdisc_on_low_latency_class", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("voip").
+			WithGuaranteedBandwidth("2mbps").
+			WithLowLatency().
+			WithLeafQdisc(SFQ())
+
+		err := controller.Apply()
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "also sets a leaf qdisc")
+	})
+
+	t.Run("rejects_invalid_leaf_parameters", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("web").
+			WithGuaranteedBandwidth("20mbps").
+			WithPriority(1).
+			WithLeafQdisc(FQ().Limit(10).FlowLimit(100))
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), netlink.NewMockAdapter(), controller.logger)
+
+		err := controller.Apply()
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "flow limit")
+	})
+
+	t.Run("reports_leaf_qdisc_statistics", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("voip").
+			WithGuaranteedBandwidth("2mbps").
+			WithSoftLimitBandwidth("10mbps").
+			WithLowLatency()
+		controller.CreateTrafficClass("web").
+			WithGuaranteedBandwidth("20mbps").
+			WithPriority(1).
+			WithLeafQdisc(SFQ())
+
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+		require.NoError(t, controller.Apply())
+
+		device, _ := tc.NewDeviceName("eth0")
+		mockNetlinkAdapter.SetQdiscStatistics(device, tc.NewHandle(0x10, 0), netlink.QdiscStats{PacketsSent: 900, PacketsDropped: 12})
+		mockNetlinkAdapter.SetFQCodelStatistics(device, tc.NewHandle(0x10, 0), &netlink.FQCodelQdiscStats{DropOverlimit: 5, ECNMarks: 7, NewFlowCount: 40})
+		mockNetlinkAdapter.SetSFQStatistics(device, tc.NewHandle(0x11, 0), &netlink.SFQQdiscStats{ActiveFlows: 3, FlowBuckets: 1024})
+
+		voip, err := controller.GetClassStatistics("1:10")
+		require.NoError(t, err)
+		require.NotNil(t, voip.LeafQdisc)
+		assert.Equal(t, "fq_codel", voip.LeafQdisc.Type)
+		assert.Equal(t, uint64(12), voip.LeafQdisc.PacketsDropped)
+		require.NotNil(t, voip.LeafQdisc.FQCodel)
+		assert.Equal(t, uint32(5), voip.LeafQdisc.FQCodel.DropOverlimit)
+		assert.Equal(t, uint32(40), voip.LeafQdisc.FQCodel.NewFlowCount)
+
+		stats, err := controller.GetRealtimeStatistics()
+		require.NoError(t, err)
+		leafTypes := make(map[string]string)
+		for _, class := range stats.ClassStats {
+			if class.LeafQdisc == nil {
+				continue
+			}
+			leafTypes[class.Handle] = class.LeafQdisc.Type
+			if class.Handle == "1:11" {
+				require.NotNil(t, class.LeafQdisc.SFQ)
+				assert.Equal(t, uint32(3), class.LeafQdisc.SFQ.ActiveFlows)
+				assert.Nil(t, class.LeafQdisc.FQCodel)
+			}
+		}
+		// Classes without a qdisc of their own report none
+		assert.Equal(t, map[string]string{"1:10": "fq_codel", "1:11": "sfq"}, leafTypes)
+	})
+
+	t.Run("reports_codel_drop_state", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("bulk").
+			WithGuaranteedBandwidth("20mbps").
+			WithPriority(4).
+			WithLeafQdisc(CODEL())
+
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+		require.NoError(t, controller.Apply())
+
+		handle, err := controller.ClassHandleByName("bulk")
+		require.NoError(t, err)
+		bulk, err := controller.GetClassStatistics(handle)
+		require.NoError(t, err)
+		require.NotNil(t, bulk.LeafQdisc)
+
+		device, _ := tc.NewDeviceName("eth0")
+		mockNetlinkAdapter.SetCodelStatistics(device, tc.MustParseHandle(bulk.LeafQdisc.Handle), &netlink.CodelQdiscStats{
+			Count: 6, LastCount: 4, LDelay: 12 * time.Millisecond, Dropping: true, CEMarks: 2,
+		})
+		bulk, err = controller.GetClassStatistics(handle)
+		require.NoError(t, err)
+		assert.Equal(t, "codel", bulk.LeafQdisc.Type)
+		assert.Equal(t, &qmodels.CodelStatisticsView{Count: 6, LastCount: 4, LDelayUs: 12000, Dropping: true, CEMarks: 2}, bulk.LeafQdisc.Codel)
+
+		root, err := controller.GetQdiscStatistics("1:0")
+		require.NoError(t, err)
+		assert.Equal(t, &qmodels.HTBQdiscStatisticsView{DirectPackets: 100, Version: 3}, root.HTB)
+	})
+}
+
+// TestTrafficController_ApplyRollback tests that a failed Apply leaves no partial configuration
+func TestTrafficController_ApplyRollback(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("10mbps").
+		WithPriority(1).
+		ForPort(80)
+	controller.CreateTrafficClass("api").
+		WithGuaranteedBandwidth("10mbps").
+		WithPriority(2)
+
+	// The kernel refuses the second class
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	adapter := &failingClassAdapter{MockAdapter: mockNetlinkAdapter, failAt: 2}
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), adapter, controller.logger)
+
+	err := controller.Apply()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "class 1:12")
+
+	device, _ := tc.NewDeviceName("eth0")
+	assert.Empty(t, mockNetlinkAdapter.GetQdiscs(device).Value())
+	assert.Empty(t, mockNetlinkAdapter.GetClasses(device).Value())
+	assert.Empty(t, mockNetlinkAdapter.GetFilters(device).Value())
+
+	config, err := controller.service.GetConfiguration(context.Background(), "eth0")
+	require.NoError(t, err)
+	assert.Empty(t, config.Qdiscs)
+	assert.Empty(t, config.Classes)
+	assert.Empty(t, config.Filters)
+
+	// Once fixed, the same controller applies cleanly
+	adapter.failAt = 0
+	assert.NoError(t, controller.Apply())
+}
+
+// TestTrafficController_ApplyRollbackRestoresDeletions tests that a failed Apply re-creates
+// the objects it deleted before failing
+func TestTrafficController_ApplyRollbackRestoresDeletions(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("10mbps").WithPriority(1).ForPort(80)
+	controller.CreateTrafficClass("api").WithGuaranteedBandwidth("10mbps").WithPriority(2).ForPort(8080)
+
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	adapter := &failingClassAdapter{MockAdapter: mockNetlinkAdapter}
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), adapter, controller.logger)
+	require.NoError(t, controller.Apply())
+
+	device, _ := tc.NewDeviceName("eth0")
+	installed := func() ([]netlink.ClassInfo, []netlink.FilterInfo) {
+		classes := mockNetlinkAdapter.GetClasses(device).Value()
+		sort.Slice(classes, func(i, j int) bool { return classes[i].Handle.String() < classes[j].Handle.String() })
+		filters := mockNetlinkAdapter.GetFilters(device).Value()
+		for i := range filters {
+			filters[i].Handle = tc.Handle{} // Assigned anew when a filter is re-created
+		}
+		sort.Slice(filters, func(i, j int) bool { return filters[i].Priority < filters[j].Priority })
+		return classes, filters
+	}
+	classesBefore, filtersBefore := installed()
+	configBefore, err := controller.service.GetConfiguration(context.Background(), "eth0")
+	require.NoError(t, err)
+
+	// The api class and the filter of web are deleted, the new filter of web is added, then
+	// the kernel refuses the video class
+	controller.classes = nil
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("10mbps").WithPriority(1).ForPort(443)
+	controller.CreateTrafficClass("video").WithGuaranteedBandwidth("10mbps").WithPriority(3).ForPort(1935)
+	adapter.adds, adapter.failAt = 0, 1
+
+	err = controller.Apply()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "class add refused")
+	assert.NotContains(t, err.Error(), "rollback incomplete")
+
+	classesAfter, filtersAfter := installed()
+	assert.Equal(t, classesBefore, classesAfter)
+	assert.Equal(t, filtersBefore, filtersAfter)
+	configAfter, err := controller.service.GetConfiguration(context.Background(), "eth0")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, configBefore.Classes, configAfter.Classes)
+	assert.Len(t, configAfter.Filters, len(configBefore.Filters))
+}
+
+// failingClassAdapter is a mock adapter refusing the failAt-th class add, 0 for none
+type failingClassAdapter struct {
+	*netlink.MockAdapter
+	failAt int
+	adds   int
+}
+
+func (a *failingClassAdapter) AddClass(ctx context.Context, class interface{}) error {
+	if a.adds++; a.adds == a.failAt {
+		return errors.New("class add refused")
+	}
+	return a.MockAdapter.AddClass(ctx, class)
+}
+
+// hangingAdapter is a mock adapter whose class adds never return, like a hung netlink socket
+type hangingAdapter struct {
+	*netlink.MockAdapter
+}
+
+func (a hangingAdapter) AddClass(ctx context.Context, class interface{}) error {
+	select {}
+}
+
+// TestTrafficController_ApplyTimeout tests that a hung netlink operation fails Apply
+func TestTrafficController_ApplyTimeout(t *testing.T) {
+	controller := NetworkInterface("eth0").
+		WithOperationTimeout(50 * time.Millisecond).
+		WithApplyTimeout(time.Second)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("10mbps").
+		WithPriority(1).
+		ForPort(80)
+
+	adapter := hangingAdapter{netlink.NewMockAdapter()}
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), adapter, controller.logger)
+
+	start := time.Now()
+	err := controller.Apply()
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var applyErr *ApplyError
+	require.ErrorAs(t, err, &applyErr)
+	assert.NotEmpty(t, applyErr.NotAttempted)
+	for _, object := range applyErr.NotAttempted {
+		assert.NotContains(t, object, "qdisc")
+	}
+
+	// The root qdisc added before the hang is rolled back
+	device, _ := tc.NewDeviceName("eth0")
+	assert.Empty(t, adapter.GetQdiscs(device).Value())
+}
+
 // TestHTBQdiscBuilder tests HTB qdisc builder
 func TestHTBQdiscBuilder(t *testing.T) {
 	controller := NetworkInterface("eth0")
@@ -471,6 +1042,206 @@ func TestFQCODELQdiscBuilder(t *testing.T) {
 	})
 }
 
+// TestCAKEQdiscBuilder tests CAKE qdisc builder
+func TestCAKEQdiscBuilder(t *testing.T) {
+	controller := NetworkInterface("eth0")
+
+	t.Run("creates_cake_qdisc_builder_with_defaults", func(t *testing.T) {
+		builder := controller.CreateCAKEQdisc("1:0", "100Mbps")
+
+		assert.NotNil(t, builder)
+		assert.Equal(t, "100Mbps", builder.bandwidth)
+		assert.Equal(t, uint32(100000), builder.rtt)
+		assert.Equal(t, "diffserv3", builder.diffserv)
+		assert.Equal(t, "no-ack-filter", builder.ackFilter)
+		assert.False(t, builder.nat)
+		assert.False(t, builder.wash)
+	})
+
+	t.Run("applies_customized_qdisc", func(t *testing.T) {
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+		err := controller.CreateCAKEQdisc("1:0", "50Mbps").
+			WithRTT(20000).
+			WithDiffserv("diffserv4").
+			WithNAT(true).
+			WithWash(true).
+			WithAckFilter(true, false).
+			Apply()
+		require.NoError(t, err)
+
+		device, _ := tc.NewDeviceName("eth0")
+		qdiscs := mockNetlinkAdapter.GetQdiscs(device).Value()
+		require.Len(t, qdiscs, 1)
+		assert.Equal(t, entities.QdiscTypeCAKE, qdiscs[0].Type)
+	})
+
+	t.Run("reports_tin_statistics", func(t *testing.T) {
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+		require.NoError(t, controller.CreateCAKEQdisc("1:0", "50Mbps").WithDiffserv("diffserv3").Apply())
+
+		device, _ := tc.NewDeviceName("eth0")
+		mockNetlinkAdapter.SetCAKEStatistics(device, tc.NewHandle(1, 0), &netlink.CAKEQdiscStats{
+			CapacityEstimate: 6250000,
+			Tins: []netlink.CAKETinStats{
+				{Name: "Bulk", SentBytes: 1000},
+				{Name: "Best Effort", SentBytes: 50000, DroppedPackets: 3},
+				{Name: "Voice", SentBytes: 2000, PeakDelayUs: 800},
+			},
+		})
+
+		stats, err := controller.GetQdiscStatistics("1:0")
+		require.NoError(t, err)
+		require.Len(t, stats.CAKETins, 3)
+		assert.Equal(t, "Best Effort", stats.CAKETins[1].Name)
+		assert.Equal(t, uint32(3), stats.CAKETins[1].PacketsDropped)
+		assert.Equal(t, uint32(800), stats.CAKETins[2].PeakDelayUs)
+		assert.Equal(t, uint64(50000000), stats.DetailedStats["cake_capacity_estimate_bps"])
+	})
+
+	t.Run("rejects_unknown_diffserv_mode", func(t *testing.T) {
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), netlink.NewMockAdapter(), controller.logger)
+
+		err := controller.CreateCAKEQdisc("1:0", "50Mbps").WithDiffserv("diffserv5").Apply()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "diffserv")
+	})
+}
+
+func TestNETEMQdiscBuilder(t *testing.T) {
+	controller := NetworkInterface("eth0")
+
+	t.Run("applies_emulated_conditions", func(t *testing.T) {
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+		err := controller.CreateNETEMQdisc("1:0").
+			WithDelay(100*time.Millisecond).
+			WithJitter(10*time.Millisecond).
+			WithLoss(1.5).
+			WithDuplicate(0.1).
+			WithCorrupt(0.01).
+			WithReorder(25, 5).
+			Apply()
+		require.NoError(t, err)
+
+		device, _ := tc.NewDeviceName("eth0")
+		qdiscs := mockNetlinkAdapter.GetQdiscs(device).Value()
+		require.Len(t, qdiscs, 1)
+		assert.Equal(t, entities.QdiscTypeNETEM, qdiscs[0].Type)
+	})
+
+	t.Run("rejects_invalid_conditions", func(t *testing.T) {
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), netlink.NewMockAdapter(), controller.logger)
+
+		err := controller.CreateNETEMQdisc("1:0").WithLoss(150).Apply()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "loss")
+
+		err = controller.CreateNETEMQdisc("1:0").WithReorder(25, 0).Apply()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "delay")
+	})
+}
+
+func TestAQMQdiscBuilders(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	device, _ := tc.NewDeviceName("eth0")
+
+	t.Run("applies_red_qdisc", func(t *testing.T) {
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+		err := controller.CreateREDQdisc("1:0", 30000, 90000).
+			WithBandwidth("1gbit").
+			WithProbability(0.05).
+			WithECN(true).
+			WithAdaptive(true).
+			Apply()
+		require.NoError(t, err)
+
+		qdiscs := mockNetlinkAdapter.GetQdiscs(device).Value()
+		require.Len(t, qdiscs, 1)
+		assert.Equal(t, entities.QdiscTypeRED, qdiscs[0].Type)
+	})
+
+	t.Run("applies_gred_virtual_queues", func(t *testing.T) {
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+		err := controller.CreateGREDQdisc("1:0", 4).
+			WithDefault(1).
+			WithGRIO(true).
+			WithVirtualQueue(0, 1, RED(15000, 45000)).
+			WithVirtualQueue(1, 2, RED(30000, 90000).Probability(0.1)).
+			Apply()
+		require.NoError(t, err)
+
+		qdiscs := mockNetlinkAdapter.GetQdiscs(device).Value()
+		require.Len(t, qdiscs, 1)
+		assert.Equal(t, entities.QdiscTypeGRED, qdiscs[0].Type)
+	})
+
+	t.Run("applies_codel_qdisc", func(t *testing.T) {
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+		err := controller.CreateCODELQdisc("1:0").
+			WithTarget(2 * time.Millisecond).
+			WithInterval(50 * time.Millisecond).
+			WithCEThreshold(time.Millisecond).
+			WithECN(true).
+			Apply()
+		require.NoError(t, err)
+
+		qdiscs := mockNetlinkAdapter.GetQdiscs(device).Value()
+		require.Len(t, qdiscs, 1)
+		assert.Equal(t, entities.QdiscTypeCODEL, qdiscs[0].Type)
+	})
+
+	t.Run("attaches_aqm_leaf_qdiscs", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("bulk").
+			WithGuaranteedBandwidth("20mbps").
+			WithPriority(4).
+			WithLeafQdisc(RED(20000, 60000).Bandwidth("100mbit").ECN(true))
+		controller.CreateTrafficClass("interactive").
+			WithGuaranteedBandwidth("10mbps").
+			WithPriority(1).
+			WithLeafQdisc(CODEL().Target(2 * time.Millisecond))
+
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+		require.NoError(t, controller.Apply())
+
+		types := make(map[tc.Handle]entities.QdiscType)
+		for _, q := range mockNetlinkAdapter.GetQdiscs(device).Value() {
+			types[q.Handle] = q.Type
+		}
+		assert.Equal(t, entities.QdiscTypeRED, types[tc.NewHandle(0x14, 0)])
+		assert.Equal(t, entities.QdiscTypeCODEL, types[tc.NewHandle(0x11, 0)])
+	})
+
+	t.Run("rejects_invalid_thresholds", func(t *testing.T) {
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), netlink.NewMockAdapter(), controller.logger)
+
+		err := controller.CreateREDQdisc("1:0", 90000, 30000).Apply()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "max threshold")
+
+		err = controller.CreateGREDQdisc("1:0", 2).WithVirtualQueue(3, 0, RED(30000, 90000)).Apply()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "virtual queue 3")
+
+		err = controller.CreateCODELQdisc("1:0").WithTarget(0).Apply()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "target")
+	})
+}
+
 // TestBuildFilterMatch tests the internal filter matching logic
 func TestBuildFilterMatch(t *testing.T) {
 	controller := NetworkInterface("eth0")
@@ -566,3 +1337,203 @@ func TestFinalizePendingClasses(t *testing.T) {
 		assert.True(t, builder.finalized)
 	})
 }
+
+// TestTrafficController_ReadCurrentConfiguration tests adopting configuration installed outside the controller
+func TestTrafficController_ReadCurrentConfiguration(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+	// Pre-existing setup, e.g. created with the tc command
+	ctx := context.Background()
+	device, _ := tc.NewDeviceName("eth0")
+	root := tc.NewHandle(1, 0)
+	classHandle := tc.NewHandle(1, 0x20)
+	require.NoError(t, mockNetlinkAdapter.AddQdisc(ctx, entities.NewQdisc(device, root, entities.QdiscTypeHTB)))
+	require.NoError(t, mockNetlinkAdapter.AddClass(ctx, entities.NewClass(device, classHandle, root, "", entities.Priority(4))))
+	filter := entities.NewFilter(device, root, 100, tc.NewHandle(0x800, 1))
+	filter.SetFlowID(classHandle)
+	require.NoError(t, mockNetlinkAdapter.AddFilter(ctx, filter))
+
+	config, err := controller.ReadCurrentConfiguration()
+	require.NoError(t, err)
+
+	assert.Equal(t, "eth0", config.DeviceName)
+	require.Len(t, config.Qdiscs, 1)
+	assert.Equal(t, root.String(), config.Qdiscs[0].Handle)
+	assert.Equal(t, "htb", config.Qdiscs[0].Type)
+	require.Len(t, config.Classes, 1)
+	assert.Equal(t, "1:20", config.Classes[0].Handle)
+	assert.Equal(t, root.String(), config.Classes[0].Parent)
+	require.Len(t, config.Filters, 1)
+	assert.Equal(t, "1:20", config.Filters[0].FlowID)
+	assert.Equal(t, uint16(100), config.Filters[0].Priority)
+
+	// Reading is side-effect free: nothing is recorded in the event store
+	stored, err := controller.service.GetConfiguration(ctx, "eth0")
+	require.NoError(t, err)
+	assert.Empty(t, stored.Qdiscs)
+}
+
+// TestTrafficController_AdoptInstalledConfiguration tests that a controller without recorded
+// state adopts a device already holding its configuration instead of re-creating it
+func TestTrafficController_AdoptInstalledConfiguration(t *testing.T) {
+	configure := func(controller *TrafficController, webCeil string) {
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("web").WithGuaranteedBandwidth("30mbps").WithSoftLimitBandwidth(webCeil).
+			WithPriority(1).ForPort(80, 443)
+		controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").WithPriority(6).ForSource("10.0.0.0/24")
+	}
+
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	installer := NetworkInterface("eth0")
+	installer.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, installer.logger)
+	configure(installer, "60mbps")
+	require.NoError(t, installer.Apply())
+
+	device, _ := tc.NewDeviceName("eth0")
+	installed := mockNetlinkAdapter.GetFilters(device).Value()
+
+	// E.g. the daemon restarted with an empty state directory
+	controller := NetworkInterface("eth0")
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+	configure(controller, "60mbps")
+
+	commands, err := controller.DryRun()
+	require.NoError(t, err)
+	assert.Empty(t, commands, "the installed configuration is the desired one")
+
+	require.NoError(t, controller.Apply())
+	assert.ElementsMatch(t, installed, mockNetlinkAdapter.GetFilters(device).Value(), "nothing was re-created")
+	stored, err := controller.service.GetConfiguration(context.Background(), "eth0")
+	require.NoError(t, err)
+	assert.Len(t, stored.Classes, 3, "the adopted classes are recorded")
+
+	// Once adopted, a changed class is changed in place
+	service := controller.service
+	controller = NetworkInterface("eth0")
+	controller.service = service
+	configure(controller, "80mbps")
+	require.NoError(t, controller.Apply())
+	assert.Equal(t, []tc.Handle{tc.NewHandle(1, 0x11)}, mockNetlinkAdapter.ChangedClasses(device))
+	assert.ElementsMatch(t, installed, mockNetlinkAdapter.GetFilters(device).Value())
+}
+
+// TestTrafficController_ApplyIsIdempotent tests that re-applying only changes what differs
+func TestTrafficController_ApplyIsIdempotent(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithSoftLimitBandwidth("60mbps").
+		WithPriority(1).
+		ForPort(80)
+	controller.CreateTrafficClass("bulk").
+		WithGuaranteedBandwidth("10mbps").
+		WithSoftLimitBandwidth("50mbps").
+		WithPriority(4).
+		ForPort(22)
+
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+	ctx := context.Background()
+	device, _ := tc.NewDeviceName("eth0")
+
+	require.NoError(t, controller.Apply())
+	history, err := controller.service.GetDeviceHistory(ctx, "eth0")
+	require.NoError(t, err)
+	applied := len(history)
+
+	t.Run("unchanged_configuration_is_a_no_op", func(t *testing.T) {
+		require.NoError(t, controller.Apply())
+
+		history, err := controller.service.GetDeviceHistory(ctx, "eth0")
+		require.NoError(t, err)
+		assert.Len(t, history, applied)
+		assert.Len(t, mockNetlinkAdapter.GetClasses(device).Value(), 3)
+	})
+
+	t.Run("changed_class_is_changed_in_place", func(t *testing.T) {
+		controller.classes[1].maxBandwidth = tc.MustParseBandwidth("80mbps")
+		require.NoError(t, controller.Apply())
+
+		history, err := controller.service.GetDeviceHistory(ctx, "eth0")
+		require.NoError(t, err)
+		// One change event; the class keeps its filter
+		assert.Len(t, history, applied+1)
+		applied = len(history)
+		assert.Equal(t, []tc.Handle{tc.NewHandle(1, 0x14)}, mockNetlinkAdapter.ChangedClasses(device))
+
+		assert.Len(t, mockNetlinkAdapter.GetQdiscs(device).Value(), 1)
+		assert.Len(t, mockNetlinkAdapter.GetClasses(device).Value(), 3)
+		assert.Len(t, mockNetlinkAdapter.GetFilters(device).Value(), 2)
+	})
+
+	t.Run("foreign_objects_are_removed", func(t *testing.T) {
+		foreign := entities.NewClass(device, tc.NewHandle(1, 0x50), tc.NewHandle(1, 0), "", entities.Priority(4))
+		require.NoError(t, mockNetlinkAdapter.AddClass(ctx, foreign))
+
+		require.NoError(t, controller.Apply())
+
+		for _, class := range mockNetlinkAdapter.GetClasses(device).Value() {
+			assert.NotEqual(t, foreign.Handle(), class.Handle)
+		}
+		history, err := controller.service.GetDeviceHistory(ctx, "eth0")
+		require.NoError(t, err)
+		assert.Len(t, history, applied)
+	})
+
+	t.Run("missing_kernel_objects_are_restored", func(t *testing.T) {
+		require.True(t, mockNetlinkAdapter.DeleteClass(device, tc.NewHandle(1, 0x11)).IsSuccess())
+
+		require.NoError(t, controller.Apply())
+
+		assert.Len(t, mockNetlinkAdapter.GetClasses(device).Value(), 3)
+	})
+}
+
+// TestTrafficClassBuilder_ForPortRange tests that port ranges are installed as flower filters
+func TestTrafficClassBuilder_ForPortRange(t *testing.T) {
+	t.Run("installs_flower_filters", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("rtp").
+			WithGuaranteedBandwidth("20mbps").
+			WithPriority(0).
+			ForPortRange(10000, 20000).
+			ForSourcePort(5060)
+
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+		require.NoError(t, controller.Apply())
+
+		var ranges, ports []string
+		for _, filter := range mockNetlinkAdapter.GetFilters(tc.MustNewDeviceName("eth0")).Value() {
+			for _, match := range filter.Matches {
+				value := match.Value.(string)
+				switch match.Type {
+				case entities.MatchTypePortRange:
+					assert.Equal(t, entities.FilterKindFlower, filter.Kind)
+					ranges = append(ranges, value)
+				case entities.MatchTypePortSource:
+					ports = append(ports, value)
+				}
+			}
+		}
+		assert.Equal(t, []string{"dport range 10000-20000", "dport range 10000-20000"}, ranges)
+		assert.Equal(t, []string{"ip sport 5060 0xffff"}, ports)
+	})
+
+	t.Run("fails_on_invalid_range", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("rtp").
+			WithGuaranteedBandwidth("20mbps").
+			WithPriority(0).
+			ForPortRange(20000, 10000)
+
+		err := controller.Apply()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid destination port range 20000-10000")
+	})
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+// WithActor records actor as the author of the configuration changes the controller
+// makes from now on. By default changes are attributed to the user running the process.
+func (controller *TrafficController) WithActor(actor string) *TrafficController {
+	controller.actor = actor
+	return controller
+}
+
+// AuditLog returns the configuration changes recorded for the device since the given
+// time, oldest first: who made each change, when, and the object added, modified or
+// deleted. A zero time returns the whole history.
+func (controller *TrafficController) AuditLog(since time.Time) ([]qmodels.AuditEntryView, error) {
+	return controller.service.AuditLog(context.Background(), controller.deviceName, since)
+}
+
+// ExportAuditLog writes the audit log since the given time as JSON Lines, one change
+// per line
+func (controller *TrafficController) ExportAuditLog(w io.Writer, since time.Time) error {
+	entries, err := controller.AuditLog(since)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write audit log: %w", err)
+		}
+	}
+	return nil
+}
+
+// actorContext returns a context attributing the changes made with it to the controller's
+// actor
+func (controller *TrafficController) actorContext(ctx context.Context) context.Context {
+	actor := controller.actor
+	if actor == "" {
+		actor = processUser()
+	}
+	return eventstore.WithActor(ctx, actor)
+}
+
+// processUser names the user running the process
+func processUser() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	return "uid:" + strconv.Itoa(os.Getuid())
+}
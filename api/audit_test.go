@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+func TestTrafficController_AuditLog(t *testing.T) {
+	controller := NetworkInterface("eth0").WithActor("alice")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithPriority(1).
+		ForPort(80)
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), netlink.NewMockAdapter(), controller.logger)
+	start := time.Now()
+	require.NoError(t, controller.Apply())
+
+	entries, err := controller.AuditLog(time.Time{})
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+
+	objects := make(map[string]int)
+	for _, entry := range entries {
+		assert.Equal(t, "alice", entry.Actor)
+		assert.Equal(t, "eth0", entry.Device)
+		assert.Equal(t, "added", entry.Action)
+		assert.False(t, entry.Time.Before(start.Add(-time.Second)))
+		assert.NotContains(t, entry.Details, "DeviceName")
+		objects[entry.Object]++
+	}
+	assert.Equal(t, 1, objects["qdisc"])
+	assert.Equal(t, 2, objects["class"]) // web and default classes
+	assert.Equal(t, 1, objects["filter"])
+	assert.Equal(t, "HTBQdiscCreated", entries[0].Event)
+	assert.Equal(t, "1:", entries[0].Handle)
+
+	// Removing the class is recorded as deletions by the same actor
+	controller.WithActor("bob")
+	controller.classes = nil
+	controller.CreateTrafficClass("bulk").
+		WithGuaranteedBandwidth("10mbps").
+		WithPriority(4)
+	require.NoError(t, controller.Apply())
+
+	entries, err = controller.AuditLog(time.Time{})
+	require.NoError(t, err)
+	var deleted []qmodels.AuditEntryView
+	for _, entry := range entries {
+		if entry.Action == "deleted" {
+			deleted = append(deleted, entry)
+		}
+	}
+	require.NotEmpty(t, deleted)
+	for _, entry := range deleted {
+		assert.Equal(t, "bob", entry.Actor)
+	}
+
+	entries, err = controller.AuditLog(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestTrafficController_ExportAuditLog(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithPriority(1)
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), netlink.NewMockAdapter(), controller.logger)
+	require.NoError(t, controller.Apply())
+
+	var out bytes.Buffer
+	require.NoError(t, controller.ExportAuditLog(&out, time.Time{}))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.NotEmpty(t, lines)
+	for _, line := range lines {
+		var entry qmodels.AuditEntryView
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		// Changes default to the user running the process
+		assert.Equal(t, processUser(), entry.Actor)
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// Bounds of the borrowing controls. HTB warns about quanta above maxHTBQuantum, which let a
+// class send that much before the next class of its priority is served.
+const (
+	borrowWeightQuantum = 1514 // Bytes per unit of borrow weight: one full Ethernet frame
+	maxBorrowWeight     = 100
+	maxHTBQuantum       = 200000
+	maxBorrowPriority   = 7
+)
+
+// WithBorrowingDisabled keeps the class at its guarantee: it never borrows idle bandwidth,
+// so bulk traffic in it cannot crowd out the classes that do. It replaces any soft limit
+// given before, and WithSoftLimitBandwidth given after enables borrowing again.
+func (b *TrafficClassBuilder) WithBorrowingDisabled() *TrafficClassBuilder {
+	b.class.borrowingDisabled = true
+	b.class.maxBandwidth, b.class.maxSpec = tc.Bandwidth{}, ""
+	return b
+}
+
+// WithBorrowWeight sets the share of idle bandwidth the class borrows relative to the other
+// classes borrowing at its borrow priority: HTB lends in rounds of one quantum per class,
+// and the weight makes the quantum that many full frames (1-100). Without a weight the
+// quantum follows the guarantee, so high guarantees also borrow the most. It replaces a
+// quantum given with WithQuantum.
+func (b *TrafficClassBuilder) WithBorrowWeight(weight int) *TrafficClassBuilder {
+	b.class.borrowWeight = weight
+	b.class.quantum = 0
+	return b
+}
+
+// WithQuantum sets the bytes the class may send per round while borrowing (1-200000),
+// replacing a weight given with WithBorrowWeight
+func (b *TrafficClassBuilder) WithQuantum(bytes int) *TrafficClassBuilder {
+	b.class.quantum = bytes
+	b.class.borrowWeight = 0
+	return b
+}
+
+// WithBorrowPriority sets the HTB priority the class borrows idle bandwidth at (0-7, 0
+// first): classes of a lower number are lent what they ask for before the others get any.
+// Every class borrows at 0 by default, sharing by quantum. Unlike WithPriority, it does not
+// change the classification order or the class handle.
+func (b *TrafficClassBuilder) WithBorrowPriority(priority int) *TrafficClassBuilder {
+	b.class.borrowPriority = priority
+	return b
+}
+
+// htbParameters returns the HTB parameters the borrowing controls and tuning profile of the
+// class set; zero values are calculated
+func (class *TrafficClass) htbParameters() application.HTBClassParameters {
+	params := application.HTBClassParameters{Profile: class.profile}
+	switch {
+	case class.quantum > 0:
+		params.Quantum = uint32(class.quantum) // #nosec G115 -- validated to 1-200000
+	case class.borrowWeight > 0:
+		params.Quantum = uint32(class.borrowWeight) * borrowWeightQuantum // #nosec G115 -- validated to 1-100
+	case class.lowLatency:
+		params.Quantum = lowLatencyHTBQuantum
+	}
+	if class.borrowPriority > 0 {
+		params.HTBPrio = uint32(class.borrowPriority) // #nosec G115 -- validated to 0-7
+	}
+	return params
+}
+
+// validateBorrowing checks the borrowing controls of the class
+func (controller *TrafficController) validateBorrowing(report *ValidationReport, class *TrafficClass) {
+	if class.borrowWeight < 0 || class.borrowWeight > maxBorrowWeight {
+		controller.addError(report, "invalid_borrow_weight", class.name,
+			"class '%s' has a borrow weight of %d, outside 1-%d",
+			class.name, class.borrowWeight, maxBorrowWeight)
+	}
+	if class.quantum < 0 || class.quantum > maxHTBQuantum {
+		controller.addError(report, "invalid_quantum", class.name,
+			"class '%s' has a quantum of %d bytes, outside 1-%d\n"+
+				"Suggestion: Use WithBorrowWeight() to set the share of idle bandwidth in frames",
+			class.name, class.quantum, maxHTBQuantum)
+	}
+	if class.borrowPriority < 0 || class.borrowPriority > maxBorrowPriority {
+		controller.addError(report, "invalid_borrow_priority", class.name,
+			"class '%s' has a borrow priority of %d, outside 0-%d",
+			class.name, class.borrowPriority, maxBorrowPriority)
+	}
+}
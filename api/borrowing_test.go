@@ -0,0 +1,105 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+// plannedClasses plans the configuration and returns the planned classes by name
+func plannedClasses(t *testing.T, controller *TrafficController) map[string]qmodels.PlannedClassView {
+	t.Helper()
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	classes := make(map[string]qmodels.PlannedClassView)
+	for _, class := range plan.Classes {
+		classes[class.Name] = class
+	}
+	return classes
+}
+
+func TestBorrowingControls(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("interactive").WithGuaranteedBandwidth("10mbps").WithSoftLimitBandwidth("100mbps").WithPriority(1).
+		ForPort(22).WithBorrowWeight(4)
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("60mbps").WithSoftLimitBandwidth("100mbps").WithPriority(2).
+		ForPort(873).WithBorrowingDisabled()
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("20mbps").WithSoftLimitBandwidth("100mbps").WithPriority(3).
+		ForPort(80).WithQuantum(9000).WithBorrowPriority(3)
+	controller.CreateTrafficClass("voice").WithGuaranteedBandwidth("5mbps").WithSoftLimitBandwidth("10mbps").WithLowLatency().ForPort(5060)
+
+	report := controller.Validate()
+	require.Empty(t, report.Errors)
+	assert.Nil(t, findIssue(report.Warnings, "missing_max_bandwidth"), "the bulk class has no ceiling on purpose")
+
+	classes := plannedClasses(t, controller)
+	assert.Equal(t, uint32(4*1514), classes["interactive"].Quantum)
+	assert.Equal(t, uint32(0), classes["interactive"].HTBPrio)
+	assert.Equal(t, "60.0Mbps", classes["bulk"].Ceil, "the ceiling is the guarantee")
+	assert.Equal(t, uint32(7500), classes["bulk"].Quantum)
+	assert.Equal(t, uint32(9000), classes["web"].Quantum)
+	assert.Equal(t, uint32(3), classes["web"].HTBPrio)
+	assert.Equal(t, uint32(1514), classes["voice"].Quantum)
+
+	require.NoError(t, controller.Apply())
+}
+
+func TestBorrowingControls_LastCallWins(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("20mbps").WithPriority(1).ForPort(80).
+		WithBorrowingDisabled().WithSoftLimitBandwidth("50mbps").
+		WithQuantum(9000).WithBorrowWeight(2)
+	controller.CreateTrafficClass("voice").WithGuaranteedBandwidth("5mbps").WithLowLatency().ForPort(5060).
+		WithQuantum(3028)
+
+	classes := plannedClasses(t, controller)
+	assert.Equal(t, "50.0Mbps", classes["web"].Ceil)
+	assert.Equal(t, uint32(2*1514), classes["web"].Quantum)
+	assert.Equal(t, uint32(3028), classes["voice"].Quantum, "an explicit quantum replaces the low-latency one")
+}
+
+func TestBorrowingControls_Validation(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("a").WithGuaranteedBandwidth("10mbps").WithPriority(1).WithBorrowWeight(101)
+	controller.CreateTrafficClass("b").WithGuaranteedBandwidth("10mbps").WithPriority(2).WithQuantum(250000)
+	controller.CreateTrafficClass("c").WithGuaranteedBandwidth("10mbps").WithPriority(3).WithBorrowPriority(8)
+
+	report := controller.Validate()
+	require.Len(t, report.Errors, 3)
+	assert.Equal(t, "invalid_borrow_weight", report.Errors[0].Code)
+	assert.Equal(t, "invalid_quantum", report.Errors[1].Code)
+	assert.Equal(t, "invalid_borrow_priority", report.Errors[2].Code)
+}
+
+func TestBorrowingControls_Config(t *testing.T) {
+	config, err := ParseConfigFromYAML([]byte(`
+device: eth0
+bandwidth: 100mbps
+classes:
+  - name: interactive
+    guaranteed: 10mbps
+    priority: 1
+    borrow_weight: 4
+  - name: bulk
+    guaranteed: 60mbps
+    priority: 2
+    borrowing: false
+    borrow_priority: 5
+`))
+	require.NoError(t, err)
+
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	require.NoError(t, controller.ApplyConfig(config))
+	classes := plannedClasses(t, controller)
+	assert.Equal(t, "15.0Mbps", classes["interactive"].Ceil, "the burst ratio applies")
+	assert.Equal(t, uint32(4*1514), classes["interactive"].Quantum)
+	assert.Equal(t, "60.0Mbps", classes["bulk"].Ceil, "no burst ratio without borrowing")
+	assert.Equal(t, uint32(5), classes["bulk"].HTBPrio)
+}
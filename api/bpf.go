@@ -0,0 +1,234 @@
+package api
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// DefaultBPFPinPath is where the maps of BPF classifiers are pinned unless PinPath is set,
+// the directory tc pins global maps in
+const DefaultBPFPinPath = netlink.DefaultBPFPinPath
+
+// BPFClassifier is a BPF program classifying the device's traffic where u32 and flower
+// matches fall short, such as by TLS server name or by process. The program runs in
+// direct-action mode on a hook of the clsact qdisc: its return code is the verdict, and it
+// selects a class by setting skb->priority to the class handle, which HTB honors for its
+// leaf classes.
+//
+// The program is loaded from an ELF object compiled for tc, e.g. with clang -target bpf.
+// Maps are defined in the legacy "maps" section or the BTF-described ".maps" section. CO-RE
+// programs and programs with global variables or calls to other functions are loaded and
+// pinned with bpftool instead, and attached with Pinned.
+type BPFClassifier struct {
+	Object   string // ELF object file
+	Section  string // Program section of the object; the first "classifier" or "tc" section when empty
+	Pinned   string // Path of a program pinned in the BPF file system, instead of Object
+	PinPath  string // Directory the object's maps are pinned in, by name; DefaultBPFPinPath when empty
+	ClassMap string // Map the program reads the class of a packet from, for SelectClass
+	Ingress  bool   // Attach to the ingress hook instead of the egress one
+	Priority uint16 // Filter priority on the hook; 1 when zero
+}
+
+// bpfClassifier is a BPF classifier of the controller
+type bpfClassifier struct {
+	name     string
+	spec     BPFClassifier
+	attached bool // Attached by an earlier Apply
+}
+
+// WithBPFClassifier adds a BPF classifier, which Apply attaches to the device's clsact
+// qdisc, adding the qdisc if missing; one added by another tool is shared. Maps already pinned under the pin path are reused,
+// so the class selections made with SelectClass survive reloading the program.
+func (controller *TrafficController) WithBPFClassifier(name string, classifier BPFClassifier) *TrafficController {
+	controller.bpfClassifiers = append(controller.bpfClassifiers, &bpfClassifier{name: name, spec: classifier})
+
+	controller.logger.Info("Adding BPF classifier",
+		logging.String("device", controller.deviceName),
+		logging.String("classifier", name),
+	)
+	return controller
+}
+
+// SelectClass directs the packets for which the classifier's program looks up key in its
+// class map to the named traffic class. The map's values are class handles as 32-bit
+// integers in host byte order, the value the program stores in skb->priority.
+func (controller *TrafficController) SelectClass(classifier string, key []byte, class string) error {
+	path, err := controller.classMapPath(classifier)
+	if err != nil {
+		return err
+	}
+
+	controller.finalizePendingClasses()
+	if controller.classByName(class) == nil {
+		return fmt.Errorf("unknown traffic class %q", class)
+	}
+	// Validation assigns the class handles
+	if err := controller.validate(); err != nil {
+		return err
+	}
+	handle, err := tc.ParseHandle(classHandle(controller.classByName(class)))
+	if err != nil {
+		return err
+	}
+
+	value := make([]byte, 4)
+	binary.NativeEndian.PutUint32(value, handle.ToUint32())
+	if err := controller.service.UpdateBPFMap(path, key, value); err != nil {
+		return fmt.Errorf("failed to select class %q for classifier %s: %w", class, classifier, err)
+	}
+
+	controller.logger.Info("Selected class for BPF classifier key",
+		logging.String("classifier", classifier),
+		logging.String("class_name", class),
+		logging.String("key", fmt.Sprintf("%x", key)),
+	)
+	return nil
+}
+
+// UnselectClass removes the key from the classifier's class map, so that the program no
+// longer selects a class for it
+func (controller *TrafficController) UnselectClass(classifier string, key []byte) error {
+	path, err := controller.classMapPath(classifier)
+	if err != nil {
+		return err
+	}
+	return controller.service.DeleteBPFMapEntry(path, key)
+}
+
+// RemoveBPFClassifier detaches the named classifier from the device and forgets it. Its
+// pinned maps are kept. The clsact qdisc is released as by RemoveHookFilter.
+func (controller *TrafficController) RemoveBPFClassifier(name string) error {
+	for i, classifier := range controller.bpfClassifiers {
+		if classifier.name != name {
+			continue
+		}
+		spec := classifier.netlinkClassifier()
+		if err := controller.service.DetachBPFClassifier(controller.deviceName, spec.Hook, spec.Priority); err != nil {
+			return err
+		}
+		controller.bpfClassifiers = append(controller.bpfClassifiers[:i], controller.bpfClassifiers[i+1:]...)
+
+		controller.logger.Info("Removed BPF classifier",
+			logging.String("device", controller.deviceName),
+			logging.String("classifier", name),
+		)
+		return controller.releaseClsact()
+	}
+	return fmt.Errorf("unknown BPF classifier %q", name)
+}
+
+// classMapPath returns the path of the named classifier's pinned class map
+func (controller *TrafficController) classMapPath(name string) (string, error) {
+	classifier := controller.bpfClassifierByName(name)
+	if classifier == nil {
+		return "", fmt.Errorf("unknown BPF classifier %q", name)
+	}
+	if classifier.spec.ClassMap == "" {
+		return "", fmt.Errorf("BPF classifier %s has no class map", name)
+	}
+	return filepath.Join(classifier.netlinkClassifier().PinPath, classifier.spec.ClassMap), nil
+}
+
+// bpfClassifierByName returns the named BPF classifier, nil if there is none
+func (controller *TrafficController) bpfClassifierByName(name string) *bpfClassifier {
+	for _, classifier := range controller.bpfClassifiers {
+		if classifier.name == name {
+			return classifier
+		}
+	}
+	return nil
+}
+
+// netlinkClassifier returns the classifier with its defaults applied
+func (c *bpfClassifier) netlinkClassifier() netlink.BPFClassifier {
+	classifier := netlink.BPFClassifier{
+		Name:     c.name,
+		Hook:     netlink.HookEgress,
+		Priority: c.spec.Priority,
+		Object:   c.spec.Object,
+		Section:  c.spec.Section,
+		Pinned:   c.spec.Pinned,
+		PinPath:  c.spec.PinPath,
+	}
+	if c.spec.Ingress {
+		classifier.Hook = netlink.HookIngress
+	}
+	if classifier.Priority == 0 {
+		classifier.Priority = 1
+	}
+	if classifier.PinPath == "" {
+		classifier.PinPath = DefaultBPFPinPath
+	}
+	return classifier
+}
+
+// pendingBPFClassifiers returns the classifiers Apply has yet to attach
+func (controller *TrafficController) pendingBPFClassifiers() []netlink.BPFClassifier {
+	var pending []netlink.BPFClassifier
+	for _, classifier := range controller.bpfClassifiers {
+		if !classifier.attached {
+			pending = append(pending, classifier.netlinkClassifier())
+		}
+	}
+	return pending
+}
+
+// attachBPFClassifiers attaches the classifiers not attached yet
+func (controller *TrafficController) attachBPFClassifiers(ctx context.Context) error {
+	for _, classifier := range controller.bpfClassifiers {
+		if classifier.attached {
+			continue
+		}
+		if err := controller.service.AttachBPFClassifier(ctx, controller.deviceName, classifier.netlinkClassifier()); err != nil {
+			return err
+		}
+		classifier.attached = true
+	}
+	return nil
+}
+
+// validateBPFClassifiers checks that every classifier names one program and has a hook and
+// priority of its own
+func (controller *TrafficController) validateBPFClassifiers(report *ValidationReport) {
+	names := make(map[string]bool)
+	slots := make(map[string]string)
+	for _, classifier := range controller.bpfClassifiers {
+		spec := classifier.netlinkClassifier()
+		switch {
+		case classifier.name == "":
+			controller.addError(report, "invalid_bpf_classifier", "",
+				"a BPF classifier has no name\n"+
+					"Suggestion: Name every classifier passed to WithBPFClassifier()")
+		case names[classifier.name]:
+			controller.addError(report, "invalid_bpf_classifier", "",
+				"BPF classifier name '%s' is used more than once", classifier.name)
+		case (spec.Object == "") == (spec.Pinned == ""):
+			controller.addError(report, "invalid_bpf_classifier", "",
+				"BPF classifier '%s' must set exactly one of Object and Pinned", classifier.name)
+		case spec.Pinned != "" && spec.Section != "":
+			controller.addError(report, "invalid_bpf_classifier", "",
+				"BPF classifier '%s' sets a section for a pinned program\n"+
+					"Suggestion: Sections select a program of an object; drop Section or use Object", classifier.name)
+		case strings.Contains(classifier.spec.ClassMap, "/"):
+			controller.addError(report, "invalid_bpf_classifier", "",
+				"BPF classifier '%s' has class map '%s'; name a map under the pin path instead of a path",
+				classifier.name, classifier.spec.ClassMap)
+		}
+		names[classifier.name] = true
+
+		slot := fmt.Sprintf("%s prio %d", spec.Hook, spec.Priority)
+		if other, taken := slots[slot]; taken {
+			controller.addError(report, "invalid_bpf_classifier", "",
+				"BPF classifiers '%s' and '%s' are both attached to %s\n"+
+					"Suggestion: Give each classifier on a hook its own Priority", other, classifier.name, slot)
+		}
+		slots[slot] = classifier.name
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func newBPFController(mock *netlink.MockAdapter) *TrafficController {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(mock)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("video").WithGuaranteedBandwidth("40mbps").WithPriority(2)
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").WithPriority(6)
+	controller.WithBPFClassifier("sni", BPFClassifier{Object: "sni.o", ClassMap: "sni_classes"})
+	return controller
+}
+
+func TestWithBPFClassifier_AttachedByApply(t *testing.T) {
+	mock := netlink.NewMockAdapter()
+	device := tc.MustNewDeviceName("eth0")
+	controller := newBPFController(mock)
+	require.NoError(t, controller.Apply())
+
+	classifiers := mock.BPFClassifiers(device)
+	require.Len(t, classifiers, 1)
+	assert.Equal(t, netlink.BPFClassifier{
+		Name:     "sni",
+		Hook:     netlink.HookEgress,
+		Priority: 1,
+		Object:   "sni.o",
+		PinPath:  DefaultBPFPinPath,
+	}, classifiers[0])
+
+	// Applying again does not reload the program
+	mock.DetachBPFClassifier(device, netlink.HookEgress, 1)
+	require.NoError(t, controller.Apply())
+	assert.Empty(t, mock.BPFClassifiers(device))
+
+	require.NoError(t, controller.RemoveBPFClassifier("sni"))
+	assert.Error(t, controller.RemoveBPFClassifier("sni"))
+}
+
+func TestSelectClass(t *testing.T) {
+	mock := netlink.NewMockAdapter()
+	controller := newBPFController(mock)
+	require.NoError(t, controller.Apply())
+
+	key := []byte("example.com\x00")
+	require.NoError(t, controller.SelectClass("sni", key, "video"))
+	value, ok := mock.BPFMapEntry(DefaultBPFPinPath+"/sni_classes", key)
+	require.True(t, ok)
+	assert.Equal(t, tc.MustParseHandle("1:12").ToUint32(), binary.NativeEndian.Uint32(value))
+
+	require.NoError(t, controller.UnselectClass("sni", key))
+	_, ok = mock.BPFMapEntry(DefaultBPFPinPath+"/sni_classes", key)
+	assert.False(t, ok)
+
+	assert.Error(t, controller.SelectClass("sni", key, "unknown"))
+	assert.Error(t, controller.SelectClass("quic", key, "video"))
+	controller.WithBPFClassifier("process", BPFClassifier{Pinned: "/sys/fs/bpf/process", Priority: 2})
+	assert.Error(t, controller.SelectClass("process", key, "video"))
+}
+
+func TestWithBPFClassifier_Validation(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").WithPriority(6)
+	controller.WithBPFClassifier("sni", BPFClassifier{Object: "sni.o"})
+	controller.WithBPFClassifier("quic", BPFClassifier{Object: "quic.o", Pinned: "/sys/fs/bpf/quic", Priority: 2})
+	controller.WithBPFClassifier("process", BPFClassifier{Pinned: "/sys/fs/bpf/process", Section: "tc", Priority: 3})
+	controller.WithBPFClassifier("ingress", BPFClassifier{Object: "ingress.o", Ingress: true})
+	controller.WithBPFClassifier("dns", BPFClassifier{Object: "dns.o", Priority: 1})
+
+	report := controller.Validate()
+	require.Len(t, report.Errors, 3)
+	for _, issue := range report.Errors {
+		assert.Equal(t, "invalid_bpf_classifier", issue.Code)
+	}
+	assert.Contains(t, report.Errors[0].Message, "exactly one of Object and Pinned")
+	assert.Contains(t, report.Errors[1].Message, "sets a section for a pinned program")
+	assert.Contains(t, report.Errors[2].Message, "'sni' and 'dns' are both attached to egress prio 1")
+}
+
+func TestWithBPFClassifier_DryRun(t *testing.T) {
+	controller := newBPFController(netlink.NewMockAdapter())
+	controller.WithBPFClassifier("process", BPFClassifier{Pinned: "/sys/fs/bpf/process", Ingress: true})
+
+	commands, err := controller.DryRun()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(commands), 3)
+	assert.Equal(t, []string{
+		"tc qdisc add dev eth0 clsact",
+		"tc filter replace dev eth0 egress prio 1 handle 1 bpf da obj sni.o",
+		"tc filter replace dev eth0 ingress prio 1 handle 1 bpf da pinned /sys/fs/bpf/process",
+	}, commands[len(commands)-3:])
+
+	// Attached classifiers are left out
+	require.NoError(t, controller.Apply())
+	commands, err = controller.DryRun()
+	require.NoError(t, err)
+	for _, command := range commands {
+		assert.NotContains(t, command, "bpf")
+		assert.NotContains(t, command, "clsact")
+	}
+}
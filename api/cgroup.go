@@ -0,0 +1,22 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// validateCgroupPath checks that a cgroup filter names an existing cgroup directory
+func validateCgroupPath(path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("cgroup path %q is not absolute", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("cgroup %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("cgroup %s is not a directory", path)
+	}
+	return nil
+}
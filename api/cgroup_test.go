@@ -0,0 +1,71 @@
+package api
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestTrafficClassBuilder_ForCgroup(t *testing.T) {
+	cgroup := t.TempDir()
+
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("backup").
+		WithGuaranteedBandwidth("10mbps").
+		WithPriority(6).
+		ForCgroup(cgroup)
+
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+	require.NoError(t, controller.Apply())
+
+	var cgroupFilters []netlink.FilterInfo
+	for _, filter := range mockNetlinkAdapter.GetFilters(tc.MustNewDeviceName("eth0")).Value() {
+		if filter.Kind == entities.FilterKindCgroup {
+			cgroupFilters = append(cgroupFilters, filter)
+		}
+	}
+	require.Len(t, cgroupFilters, 1)
+	require.Len(t, cgroupFilters[0].Matches, 1)
+	assert.Equal(t, "cgroup "+cgroup, cgroupFilters[0].Matches[0].Value)
+}
+
+func TestTrafficClassBuilder_ForCgroupInvalid(t *testing.T) {
+	cgroup := t.TempDir()
+
+	tests := []struct {
+		name    string
+		path    string
+		actions []FilterAction
+		want    string
+	}{
+		{"relative_path", "myapp", nil, "not absolute"},
+		{"missing_cgroup", filepath.Join(cgroup, "missing"), nil, "invalid cgroup filter"},
+		{"with_actions", cgroup, []FilterAction{MarkPackets(0x10)}, "combines a cgroup filter with actions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := NetworkInterface("eth0")
+			controller.WithHardLimitBandwidth("100mbps")
+			controller.CreateTrafficClass("backup").
+				WithGuaranteedBandwidth("10mbps").
+				WithPriority(6).
+				ForCgroup(tt.path).
+				WithActions(tt.actions...)
+
+			err := controller.Apply()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
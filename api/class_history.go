@@ -0,0 +1,266 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/statstore"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// DefaultRecordInterval is the default sampling interval of a StatisticsRecorder
+const DefaultRecordInterval = time.Minute
+
+// DefaultStatisticsRetention is how long a StatisticsRecorder keeps samples unless told
+// otherwise. Downsampled by DefaultDownsampleTiers, a week of minutes is about seven hundred
+// rows per class.
+const DefaultStatisticsRetention = 7 * 24 * time.Hour
+
+// DownsampleTier thins the samples older than After to one per Resolution, see
+// WithDownsampling
+type DownsampleTier struct {
+	After      time.Duration
+	Resolution time.Duration
+}
+
+// DefaultDownsampleTiers are the tiers of a StatisticsRecorder unless told otherwise: samples
+// are kept as taken for six hours, then one per five minutes, and one per hour after a day
+var DefaultDownsampleTiers = []DownsampleTier{
+	{After: 6 * time.Hour, Resolution: 5 * time.Minute},
+	{After: 24 * time.Hour, Resolution: time.Hour},
+}
+
+// Intervals GetClassHistory commonly aggregates samples over
+const (
+	IntervalMinute = time.Minute
+	IntervalHour   = time.Hour
+	IntervalDay    = 24 * time.Hour
+)
+
+// TimeRange is a window of time; a zero bound leaves that side open
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Last returns the window of the duration up to now, e.g. Last(24 * time.Hour)
+func Last(d time.Duration) TimeRange {
+	now := time.Now()
+	return TimeRange{From: now.Add(-d), To: now}
+}
+
+// ClassHistoryPoint is the traffic of a class over one interval of its history
+type ClassHistoryPoint struct {
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	BytesSent      uint64    `json:"bytes_sent"`
+	PacketsSent    uint64    `json:"packets_sent"`
+	PacketsDropped uint64    `json:"packets_dropped"`
+	Overlimits     uint64    `json:"overlimits"`
+	RateBPS        uint64    `json:"rate_bps"`     // Average rate over the time the samples cover
+	PeakBacklog    uint64    `json:"peak_backlog"` // Largest backlog sampled, in bytes
+}
+
+// StatisticsRecorder samples the counters of the interface's named classes at a fixed
+// interval into the controller's statistics history, which GetClassHistory queries. The
+// history is kept in memory, or with the configuration history in the database opened by
+// PersistHistory, where it outlives the process.
+type StatisticsRecorder struct {
+	controller  *TrafficController
+	interval    time.Duration
+	retention   time.Duration
+	tiers       []DownsampleTier
+	downsampled time.Time // When the samples were last downsampled
+	logger      logging.Logger
+}
+
+// NewStatisticsRecorder creates a statistics recorder for the controller's interface. A
+// non-positive interval selects DefaultRecordInterval. Samples older than
+// DefaultStatisticsRetention are deleted and older ones are thinned by
+// DefaultDownsampleTiers; see WithRetention and WithDownsampling.
+func (controller *TrafficController) NewStatisticsRecorder(interval time.Duration) *StatisticsRecorder {
+	if interval <= 0 {
+		interval = DefaultRecordInterval
+	}
+
+	return &StatisticsRecorder{
+		controller: controller,
+		interval:   interval,
+		retention:  DefaultStatisticsRetention,
+		tiers:      DefaultDownsampleTiers,
+		logger:     controller.logger,
+	}
+}
+
+// WithRetention deletes samples older than the duration as new ones are recorded. A
+// non-positive duration keeps them forever, letting the history grow without bound.
+func (r *StatisticsRecorder) WithRetention(retention time.Duration) *StatisticsRecorder {
+	r.retention = retention
+	return r
+}
+
+// WithDownsampling replaces the downsampling tiers. Each tier thins the samples older than
+// its After to one per its Resolution, so that the history of a long-running recorder stays
+// small while recent traffic keeps the sampling interval. Without tiers every sample is kept
+// until the retention deletes it.
+func (r *StatisticsRecorder) WithDownsampling(tiers ...DownsampleTier) *StatisticsRecorder {
+	r.tiers = tiers
+	return r
+}
+
+// Run records samples until the context is cancelled
+func (r *StatisticsRecorder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.sample(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			r.sample(ctx, now)
+		}
+	}
+}
+
+// sample records the counters of every class applied under a name; a failed poll records
+// nothing
+func (r *StatisticsRecorder) sample(ctx context.Context, now time.Time) {
+	controller := r.controller
+	config, err := controller.service.GetConfiguration(ctx, controller.deviceName)
+	if err != nil {
+		r.logger.Debug("Failed to read class names", logging.Error(err))
+		return
+	}
+	names := make(map[string]string, len(config.Classes))
+	for _, class := range config.Classes {
+		if class.Name != "" {
+			names[class.Handle] = class.Name
+		}
+	}
+
+	stats, err := controller.GetRealtimeStatistics()
+	if err != nil {
+		r.logger.Debug("Failed to poll statistics", logging.Error(err))
+		return
+	}
+
+	var samples []statstore.ClassSample
+	for _, class := range stats.ClassStats {
+		name, ok := names[class.Handle]
+		if !ok {
+			continue
+		}
+		samples = append(samples, statstore.ClassSample{
+			Device:         controller.deviceName,
+			Handle:         class.Handle,
+			Name:           name,
+			Time:           now,
+			BytesSent:      class.BytesSent,
+			PacketsSent:    class.PacketsSent,
+			PacketsDropped: class.PacketsDropped,
+			Overlimits:     class.Overlimits,
+			BacklogBytes:   class.BacklogBytes,
+		})
+	}
+	if err := controller.statistics.Append(ctx, samples); err != nil {
+		r.logger.Warn("Failed to record statistics", logging.Error(err))
+	}
+
+	if r.retention > 0 {
+		if err := controller.statistics.Prune(ctx, now.Add(-r.retention)); err != nil {
+			r.logger.Warn("Failed to prune statistics", logging.Error(err))
+		}
+	}
+	r.downsample(ctx, now)
+}
+
+// downsample thins the samples by the tiers, at most once per the finest resolution as
+// samples of a shorter time cannot fill an interval
+func (r *StatisticsRecorder) downsample(ctx context.Context, now time.Time) {
+	if len(r.tiers) == 0 {
+		return
+	}
+	finest := r.tiers[0].Resolution
+	for _, tier := range r.tiers[1:] {
+		finest = min(finest, tier.Resolution)
+	}
+	if now.Sub(r.downsampled) < finest {
+		return
+	}
+	r.downsampled = now
+
+	for _, tier := range r.tiers {
+		if err := r.controller.statistics.Downsample(ctx, now.Add(-tier.After), tier.Resolution); err != nil {
+			r.logger.Warn("Failed to downsample statistics",
+				logging.String("resolution", tier.Resolution.String()),
+				logging.Error(err),
+			)
+		}
+	}
+}
+
+// GetClassHistory returns the traffic of the class applied under the name over the window,
+// from the samples of a StatisticsRecorder, one point per interval aligned to the clock:
+//
+//	points, err := controller.GetClassHistory("web-traffic", api.Last(24*time.Hour), api.IntervalHour)
+//
+// A zero interval returns one point per pair of consecutive samples. Traffic is counted
+// from the first sample in the window, so the window should start a sampling interval
+// before the traffic of interest. Intervals without samples are left out. A class
+// recreated by a change, which restarts its counters, counts from zero again. Where the
+// recorder downsampled the samples, a point finer than the tier's resolution holds the
+// traffic since the previous sample kept. It fails
+// with tcerrors.ErrNotFound when no class of that name was applied or recorded.
+func (controller *TrafficController) GetClassHistory(name string, window TimeRange, interval time.Duration) ([]ClassHistoryPoint, error) {
+	samples, err := controller.statistics.ClassSamples(context.Background(), controller.deviceName, name, window.From, window.To)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		if _, err := controller.ClassHandleByName(name); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	var points []ClassHistoryPoint
+	var covered []time.Duration // Time the samples of each point cover, which its rate is averaged over
+	for i := 1; i < len(samples); i++ {
+		previous, current := samples[i-1], samples[i]
+		start, end := previous.Time, current.Time
+		if interval > 0 {
+			start = current.Time.Truncate(interval)
+			end = start.Add(interval)
+		}
+
+		if len(points) == 0 || !points[len(points)-1].Start.Equal(start) {
+			points = append(points, ClassHistoryPoint{Start: start, End: end})
+			covered = append(covered, 0)
+		}
+		point := &points[len(points)-1]
+		point.BytesSent += classCounterDelta(current.BytesSent, previous.BytesSent)
+		point.PacketsSent += classCounterDelta(current.PacketsSent, previous.PacketsSent)
+		point.PacketsDropped += classCounterDelta(current.PacketsDropped, previous.PacketsDropped)
+		point.Overlimits += classCounterDelta(current.Overlimits, previous.Overlimits)
+		point.PeakBacklog = max(point.PeakBacklog, current.BacklogBytes)
+		covered[len(covered)-1] += current.Time.Sub(previous.Time)
+	}
+
+	for i := range points {
+		if seconds := covered[i].Seconds(); seconds > 0 {
+			points[i].RateBPS = uint64(float64(points[i].BytesSent) * 8 / seconds)
+		}
+	}
+	return points, nil
+}
+
+// classCounterDelta returns the increase of a class counter between samples. Counters that
+// went backwards belong to a recreated class, whose traffic since then is the counter.
+func classCounterDelta(current, previous uint64) uint64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
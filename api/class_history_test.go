@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+func TestTrafficController_GetClassHistory(t *testing.T) {
+	device := tc.MustNewDeviceName("eth0")
+	web := tc.NewHandle(1, 0x11)
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	// record samples the web class at 10:00, 10:30, 11:00 and 11:30 with the byte counters given
+	record := func(recorder *StatisticsRecorder, adapter *netlink.MockAdapter, bytes ...uint64) {
+		for i, sent := range bytes {
+			adapter.SetClassStatistics(device, web, netlink.ClassStats{BytesSent: sent, PacketsSent: sent / 1000, BacklogBytes: sent / 100})
+			recorder.sample(ctx, start.Add(time.Duration(i)*30*time.Minute))
+		}
+	}
+
+	t.Run("aggregates_per_interval", func(t *testing.T) {
+		controller, adapter := shapedController(t)
+		record(controller.NewStatisticsRecorder(0), adapter, 0, 18_000_000, 36_000_000, 45_000_000)
+
+		points, err := controller.GetClassHistory("web", TimeRange{}, IntervalHour)
+		require.NoError(t, err)
+		require.Len(t, points, 2)
+		assert.Equal(t, start, points[0].Start)
+		assert.Equal(t, start.Add(time.Hour), points[0].End)
+		assert.Equal(t, uint64(18_000_000), points[0].BytesSent)
+		assert.Equal(t, uint64(18_000), points[0].PacketsSent)
+		assert.Equal(t, uint64(80_000), points[0].RateBPS, "18 MB over 30 minutes")
+		assert.Equal(t, uint64(180_000), points[0].PeakBacklog)
+		assert.Equal(t, uint64(27_000_000), points[1].BytesSent)
+
+		pairs, err := controller.GetClassHistory("web", TimeRange{}, 0)
+		require.NoError(t, err)
+		assert.Len(t, pairs, 3)
+	})
+
+	t.Run("limits_to_window", func(t *testing.T) {
+		controller, adapter := shapedController(t)
+		record(controller.NewStatisticsRecorder(0), adapter, 0, 18_000_000, 36_000_000, 45_000_000)
+
+		points, err := controller.GetClassHistory("web", TimeRange{From: start.Add(time.Hour)}, IntervalHour)
+		require.NoError(t, err)
+		require.Len(t, points, 1)
+		assert.Equal(t, uint64(9_000_000), points[0].BytesSent)
+	})
+
+	t.Run("counts_recreated_class_from_zero", func(t *testing.T) {
+		controller, adapter := shapedController(t)
+		record(controller.NewStatisticsRecorder(0), adapter, 0, 18_000_000, 1_000_000)
+
+		points, err := controller.GetClassHistory("web", TimeRange{}, IntervalDay)
+		require.NoError(t, err)
+		require.Len(t, points, 1)
+		assert.Equal(t, uint64(19_000_000), points[0].BytesSent)
+	})
+
+	t.Run("prunes_beyond_retention", func(t *testing.T) {
+		controller, adapter := shapedController(t)
+		record(controller.NewStatisticsRecorder(0).WithRetention(time.Hour), adapter, 0, 18_000_000, 36_000_000, 45_000_000)
+
+		points, err := controller.GetClassHistory("web", TimeRange{}, 0)
+		require.NoError(t, err)
+		assert.Len(t, points, 2, "the 10:00 sample is older than an hour")
+	})
+
+	t.Run("prunes_beyond_default_retention", func(t *testing.T) {
+		for _, test := range []struct {
+			recorder func(*TrafficController) *StatisticsRecorder
+			samples  int
+		}{
+			{func(c *TrafficController) *StatisticsRecorder { return c.NewStatisticsRecorder(0) }, 2},
+			{func(c *TrafficController) *StatisticsRecorder { return c.NewStatisticsRecorder(0).WithRetention(0) }, 3},
+		} {
+			controller, adapter := shapedController(t)
+			recorder := test.recorder(controller)
+			for i, at := range []time.Time{start, start.Add(time.Hour), start.Add(DefaultStatisticsRetention + 30*time.Minute)} {
+				adapter.SetClassStatistics(device, web, netlink.ClassStats{BytesSent: uint64(i) * 1000})
+				recorder.sample(ctx, at)
+			}
+
+			pairs, err := controller.GetClassHistory("web", TimeRange{}, 0)
+			require.NoError(t, err)
+			assert.Len(t, pairs, test.samples-1)
+		}
+	})
+
+	t.Run("downsamples_old_samples", func(t *testing.T) {
+		controller, adapter := shapedController(t)
+		recorder := controller.NewStatisticsRecorder(0)
+		// A sample every ten minutes for two days, 1 MB apart
+		for i := 0; i <= 2*24*6; i++ {
+			adapter.SetClassStatistics(device, web, netlink.ClassStats{BytesSent: uint64(i) * 1_000_000})
+			recorder.sample(ctx, start.Add(time.Duration(i)*10*time.Minute))
+		}
+
+		samples, err := controller.statistics.ClassSamples(ctx, "eth0", "web", time.Time{}, time.Time{})
+		require.NoError(t, err)
+		// The first sample, one per hour for the first day, and every sample of the second,
+		// which the five-minute tier keeps
+		assert.Len(t, samples, 1+24+24*6+1)
+
+		days, err := controller.GetClassHistory("web", TimeRange{}, IntervalDay)
+		require.NoError(t, err)
+		require.Len(t, days, 3)
+		assert.Equal(t, uint64(2*24*6*1_000_000), days[0].BytesSent+days[1].BytesSent+days[2].BytesSent,
+			"the traffic between the samples kept is unchanged")
+
+		withoutTiers, adapter := shapedController(t)
+		recorder = withoutTiers.NewStatisticsRecorder(0).WithDownsampling()
+		for i := 0; i <= 2*24*6; i++ {
+			adapter.SetClassStatistics(device, web, netlink.ClassStats{BytesSent: uint64(i) * 1_000_000})
+			recorder.sample(ctx, start.Add(time.Duration(i)*10*time.Minute))
+		}
+		samples, err = withoutTiers.statistics.ClassSamples(ctx, "eth0", "web", time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Len(t, samples, 2*24*6+1)
+	})
+
+	t.Run("rejects_unknown_name", func(t *testing.T) {
+		controller, _ := shapedController(t)
+
+		_, err := controller.GetClassHistory("video", Last(24*time.Hour), IntervalHour)
+		assert.True(t, errors.Is(err, tcerrors.ErrNotFound))
+
+		points, err := controller.GetClassHistory("web", Last(24*time.Hour), IntervalHour)
+		require.NoError(t, err)
+		assert.Empty(t, points, "nothing is recorded yet")
+	})
+
+	t.Run("persists_with_history", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.db")
+		adapter := netlink.NewMockAdapter()
+		controller := NetworkInterface("eth0").WithNetlinkAdapter(adapter)
+		require.NoError(t, controller.PersistHistory(path))
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("web").WithGuaranteedBandwidth("30mbps").WithPriority(1)
+		require.NoError(t, controller.Apply())
+		record(controller.NewStatisticsRecorder(0), adapter, 0, 18_000_000)
+		require.NoError(t, controller.CloseHistory())
+
+		reader := NetworkInterface("eth0").WithNetlinkAdapter(adapter)
+		require.NoError(t, reader.PersistHistory(path))
+		defer func() { _ = reader.CloseHistory() }()
+
+		points, err := reader.GetClassHistory("web", TimeRange{}, IntervalHour)
+		require.NoError(t, err)
+		require.Len(t, points, 1)
+		assert.Equal(t, uint64(18_000_000), points[0].BytesSent)
+	})
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// defaultHookFilterPriority is the priority of the first filter on a hook given none; each
+// later filter on the hook starts 10 above the last, leaving the priorities below to BPF
+// classifiers
+const defaultHookFilterPriority = 100
+
+// HookFilterBuilder configures a filter on a hook of the device's clsact qdisc. Hook
+// filters belong to no class: they run their actions on the packets they match, on ingress
+// as the device receives them and on egress before the root qdisc queues them. They police,
+// mirror, redirect, mark or drop traffic without a classful qdisc, and work alongside one.
+type HookFilterBuilder struct {
+	controller *TrafficController
+	filter     *hookFilter
+}
+
+// hookFilter is a hook filter of the controller
+type hookFilter struct {
+	name     string
+	hook     netlink.ClsactHook
+	priority uint16 // Priority of the first of its tc filters
+	filters  []Filter
+	actions  []FilterAction
+	attached bool // Attached by an earlier Apply
+}
+
+// OnIngress adds a filter running actions on the traffic the device receives, before any
+// qdisc, e.g. OnIngress("ssh").ForPort(22).WithActions(api.Police("1mbit", 16000)).
+// Apply adds the clsact qdisc if missing; one added by another tool is shared.
+func (controller *TrafficController) OnIngress(name string) *HookFilterBuilder {
+	return controller.onHook(name, netlink.HookIngress)
+}
+
+// OnEgress adds a filter running actions on the traffic the device sends, before its root
+// qdisc, e.g. OnEgress("monitor").ForDestination("10.0.0.0/8").WithActions(api.MirrorTo("mon0"))
+func (controller *TrafficController) OnEgress(name string) *HookFilterBuilder {
+	return controller.onHook(name, netlink.HookEgress)
+}
+
+// onHook adds a hook filter and returns its builder
+func (controller *TrafficController) onHook(name string, hook netlink.ClsactHook) *HookFilterBuilder {
+	// Priorities are fixed when the filter is added, so that removing an earlier filter
+	// leaves them in place
+	priority := defaultHookFilterPriority
+	for _, other := range controller.hookFilters {
+		if other.hook == hook && int(other.priority) >= priority {
+			priority = int(other.priority) + 10
+		}
+	}
+	filter := &hookFilter{name: name, hook: hook, priority: uint16(priority)} // #nosec G115 -- validation catches priorities taken twice
+	controller.hookFilters = append(controller.hookFilters, filter)
+
+	controller.logger.Info("Adding hook filter",
+		logging.String("device", controller.deviceName),
+		logging.String("filter", name),
+		logging.String("hook", string(hook)),
+	)
+	return &HookFilterBuilder{controller: controller, filter: filter}
+}
+
+// ForDestination matches packets sent to the IP address or network
+func (b *HookFilterBuilder) ForDestination(ip string) *HookFilterBuilder {
+	b.filter.filters = append(b.filter.filters, Filter{filterType: DestinationIPFilter, value: ip})
+	return b
+}
+
+// ForSource matches packets sent from the IP address or network
+func (b *HookFilterBuilder) ForSource(ip string) *HookFilterBuilder {
+	b.filter.filters = append(b.filter.filters, Filter{filterType: SourceIPFilter, value: ip})
+	return b
+}
+
+// ForPort matches packets sent to the destination ports
+func (b *HookFilterBuilder) ForPort(ports ...int) *HookFilterBuilder {
+	for _, port := range ports {
+		b.filter.filters = append(b.filter.filters, Filter{filterType: DestinationPortFilter, value: port})
+	}
+	return b
+}
+
+// ForSourcePort matches packets sent from the source ports
+func (b *HookFilterBuilder) ForSourcePort(ports ...int) *HookFilterBuilder {
+	for _, port := range ports {
+		b.filter.filters = append(b.filter.filters, Filter{filterType: SourcePortFilter, value: port})
+	}
+	return b
+}
+
+// ForProtocol matches packets of the transport protocol: "tcp", "udp", "icmp" or a number
+func (b *HookFilterBuilder) ForProtocol(protocol string) *HookFilterBuilder {
+	b.filter.filters = append(b.filter.filters, Filter{filterType: ProtocolFilter, value: protocol})
+	return b
+}
+
+// ForU32 matches packets on which all conditions of the u32 match hold
+func (b *HookFilterBuilder) ForU32(match *U32Match) *HookFilterBuilder {
+	b.filter.filters = append(b.filter.filters, Filter{filterType: U32Filter, value: match})
+	return b
+}
+
+// ForFlower matches packets on which all conditions of the flower match hold
+func (b *HookFilterBuilder) ForFlower(match *FlowerMatch) *HookFilterBuilder {
+	b.filter.filters = append(b.filter.filters, Filter{filterType: FlowerFilter, value: match})
+	return b
+}
+
+// WithActions sets the action chain run, in order, on the matched packets. Like class
+// filters, a packet matching any of the conditions is matched; without conditions every
+// packet is.
+func (b *HookFilterBuilder) WithActions(actions ...FilterAction) *HookFilterBuilder {
+	b.filter.actions = append(b.filter.actions, actions...)
+	return b
+}
+
+// WithPriority sets the tc priority of the filter; a filter with several conditions takes
+// the following priorities as well. By default a hook's first filter takes priority 100 and
+// each later one 10 above the filter added before it.
+func (b *HookFilterBuilder) WithPriority(priority uint16) *HookFilterBuilder {
+	b.filter.priority = priority
+	return b
+}
+
+// Apply completes the builder and applies the controller's configuration
+func (b *HookFilterBuilder) Apply() error {
+	return b.controller.Apply()
+}
+
+// RemoveHookFilter detaches the named hook filter from the device and forgets it. The
+// clsact qdisc is deleted with the last filter if Apply added it and no other tool has
+// filters on it.
+func (controller *TrafficController) RemoveHookFilter(name string) error {
+	for i, filter := range controller.hookFilters {
+		if filter.name != name {
+			continue
+		}
+		for _, spec := range controller.hookFilterSpecs(filter) {
+			if err := controller.service.DetachHookFilter(controller.deviceName, spec.Hook, spec.Priority); err != nil {
+				return err
+			}
+		}
+		controller.hookFilters = append(controller.hookFilters[:i], controller.hookFilters[i+1:]...)
+
+		controller.logger.Info("Removed hook filter",
+			logging.String("device", controller.deviceName),
+			logging.String("filter", name),
+		)
+		return controller.releaseClsact()
+	}
+	return fmt.Errorf("unknown hook filter %q", name)
+}
+
+// hookFilterSpecs returns the tc filters of a hook filter, one per match map at consecutive
+// priorities, or a single match-all one
+func (controller *TrafficController) hookFilterSpecs(filter *hookFilter) []application.HookFilter {
+	priority := filter.priority
+	var specs []application.HookFilter
+	for _, condition := range filter.filters {
+		for _, match := range controller.buildFilterMatches(condition) {
+			specs = append(specs, application.HookFilter{
+				Hook:     filter.hook,
+				Kind:     condition.kind(),
+				Priority: priority + uint16(len(specs)), // #nosec G115 -- validation bounds the count
+				Match:    match,
+				Actions:  filterActionSpecs(filter.actions),
+			})
+		}
+	}
+	if len(specs) == 0 {
+		specs = append(specs, application.HookFilter{
+			Hook:     filter.hook,
+			Priority: priority,
+			Match:    map[string]string{},
+			Actions:  filterActionSpecs(filter.actions),
+		})
+	}
+	return specs
+}
+
+// pendingHookFilters returns the tc filters of the hook filters Apply has yet to attach
+func (controller *TrafficController) pendingHookFilters() []application.HookFilter {
+	var pending []application.HookFilter
+	for _, filter := range controller.hookFilters {
+		if !filter.attached {
+			pending = append(pending, controller.hookFilterSpecs(filter)...)
+		}
+	}
+	return pending
+}
+
+// attachToClsact adds the clsact qdisc if needed and attaches the BPF classifiers and hook
+// filters not attached yet
+func (controller *TrafficController) attachToClsact(ctx context.Context) error {
+	classifiers := controller.pendingBPFClassifiers()
+	filters := controller.pendingHookFilters()
+	if len(classifiers) == 0 && len(filters) == 0 {
+		return nil
+	}
+
+	// A plain ingress qdisc, e.g. of another tool, serves the ingress hook only
+	hook := netlink.HookIngress
+	for _, classifier := range classifiers {
+		if classifier.Hook == netlink.HookEgress {
+			hook = netlink.HookEgress
+		}
+	}
+	for _, filter := range filters {
+		if filter.Hook == netlink.HookEgress {
+			hook = netlink.HookEgress
+		}
+	}
+	created, err := controller.service.EnsureClsactQdisc(ctx, controller.deviceName, hook)
+	if err != nil {
+		return err
+	}
+	if created {
+		controller.ownsClsact = true
+	}
+
+	if err := controller.attachBPFClassifiers(ctx); err != nil {
+		return err
+	}
+	for _, filter := range controller.hookFilters {
+		if filter.attached {
+			continue
+		}
+		for _, spec := range controller.hookFilterSpecs(filter) {
+			if err := controller.service.AttachHookFilter(ctx, controller.deviceName, spec); err != nil {
+				return fmt.Errorf("failed to attach hook filter %s: %w", filter.name, err)
+			}
+		}
+		filter.attached = true
+	}
+	return nil
+}
+
+// releaseClsact deletes the clsact qdisc once the controller attaches nothing to it, if Apply
+// added it; the adapter keeps it while another tool has filters on it
+func (controller *TrafficController) releaseClsact() error {
+	if !controller.ownsClsact || len(controller.bpfClassifiers) > 0 || len(controller.hookFilters) > 0 {
+		return nil
+	}
+	return controller.service.DeleteClsactQdisc(controller.deviceName)
+}
+
+// validateHookFilters checks that every hook filter has actions the adapter can install and
+// priorities of its own on its hook, shared with neither other hook filters nor BPF
+// classifiers
+func (controller *TrafficController) validateHookFilters(report *ValidationReport) {
+	slots := make(map[string]string)
+	for _, classifier := range controller.bpfClassifiers {
+		spec := classifier.netlinkClassifier()
+		slots[fmt.Sprintf("%s prio %d", spec.Hook, spec.Priority)] = fmt.Sprintf("BPF classifier '%s'", classifier.name)
+	}
+
+	names := make(map[string]bool)
+	for _, filter := range controller.hookFilters {
+		switch {
+		case filter.name == "":
+			controller.addError(report, "invalid_hook_filter", "",
+				"a hook filter has no name\n"+
+					"Suggestion: Name every filter passed to OnIngress() and OnEgress()")
+			continue
+		case names[filter.name]:
+			controller.addError(report, "invalid_hook_filter", "",
+				"hook filter name '%s' is used more than once", filter.name)
+			continue
+		case len(filter.actions) == 0:
+			controller.addError(report, "invalid_hook_filter", "",
+				"hook filter '%s' has no actions\n"+
+					"Suggestion: Hook filters select no class; give the actions to run with WithActions()", filter.name)
+			continue
+		}
+		names[filter.name] = true
+
+		specs := controller.hookFilterSpecs(filter)
+		if int(specs[0].Priority)+len(specs) > 0x10000 {
+			controller.addError(report, "invalid_hook_filter", "",
+				"hook filter '%s' needs %d priorities from %d, past the highest", filter.name, len(specs), specs[0].Priority)
+			continue
+		}
+		for _, spec := range specs {
+			if err := controller.service.CheckHookFilter(controller.deviceName, spec); err != nil {
+				controller.addError(report, "invalid_hook_filter", "", "hook filter '%s': %v", filter.name, err)
+				break
+			}
+		}
+		for _, spec := range specs {
+			slot := fmt.Sprintf("%s prio %d", spec.Hook, spec.Priority)
+			if other, taken := slots[slot]; taken {
+				controller.addError(report, "invalid_hook_filter", "",
+					"%s and hook filter '%s' are both attached to %s\n"+
+						"Suggestion: Give the filter another priority with WithPriority()", other, filter.name, slot)
+				break
+			}
+			slots[slot] = fmt.Sprintf("hook filter '%s'", filter.name)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// hookFilters returns the filters on the device's clsact hooks
+func hookFilters(mock *netlink.MockAdapter, device tc.DeviceName) []netlink.FilterInfo {
+	var filters []netlink.FilterInfo
+	for _, filter := range mock.GetFilters(device).Value() {
+		if filter.Parent.Major() == 0xffff {
+			filters = append(filters, filter)
+		}
+	}
+	return filters
+}
+
+func newHookFilterController(mock *netlink.MockAdapter) *TrafficController {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(mock)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").WithPriority(6)
+	controller.OnIngress("ssh").ForPort(22).WithActions(Police("1mbit", 16000))
+	controller.OnEgress("monitor").ForDestination("10.0.0.0/8").ForDestination("192.168.0.0/16").
+		WithActions(MirrorTo("mon0"))
+	return controller
+}
+
+func TestOnIngress_AttachedByApply(t *testing.T) {
+	mock := netlink.NewMockAdapter()
+	device := tc.MustNewDeviceName("eth0")
+	controller := newHookFilterController(mock)
+	require.NoError(t, controller.Apply())
+
+	assert.True(t, mock.HasClsactQdisc(device))
+	filters := hookFilters(mock, device)
+	require.Len(t, filters, 3)
+	assert.Equal(t, tc.MustParseHandle("ffff:fff2"), filters[0].Parent)
+	assert.Equal(t, uint16(100), filters[0].Priority)
+	assert.Equal(t, tc.Handle{}, filters[0].FlowID)
+	require.Len(t, filters[0].Actions, 1)
+	assert.IsType(t, &entities.PoliceAction{}, filters[0].Actions[0])
+	for i, filter := range filters[1:] {
+		assert.Equal(t, tc.MustParseHandle("ffff:fff3"), filter.Parent)
+		assert.Equal(t, uint16(100+i), filter.Priority)
+	}
+
+	// Reconciling the classes leaves the hook filters alone, and they are not added twice
+	controller.CreateTrafficClass("video").WithGuaranteedBandwidth("20mbps").WithPriority(2)
+	require.NoError(t, controller.Apply())
+	assert.Len(t, hookFilters(mock, device), 3)
+
+	require.NoError(t, controller.RemoveHookFilter("ssh"))
+	assert.Len(t, hookFilters(mock, device), 2)
+	assert.True(t, mock.HasClsactQdisc(device))
+	require.NoError(t, controller.RemoveHookFilter("monitor"))
+	assert.Empty(t, hookFilters(mock, device))
+	assert.False(t, mock.HasClsactQdisc(device), "the clsact qdisc added by Apply is deleted with its last filter")
+	assert.Error(t, controller.RemoveHookFilter("monitor"))
+}
+
+func TestOnIngress_SharedClsactQdisc(t *testing.T) {
+	mock := netlink.NewMockAdapter()
+	device := tc.MustNewDeviceName("eth0")
+
+	// Another tool added the qdisc
+	_, err := mock.EnsureClsactQdisc(context.Background(), device, netlink.HookIngress)
+	require.NoError(t, err)
+
+	controller := newHookFilterController(mock)
+	require.NoError(t, controller.Apply())
+	require.NoError(t, controller.RemoveHookFilter("ssh"))
+	require.NoError(t, controller.RemoveHookFilter("monitor"))
+	assert.True(t, mock.HasClsactQdisc(device), "a clsact qdisc of another tool is left in place")
+}
+
+func TestOnIngress_KeepsForeignFilters(t *testing.T) {
+	mock := netlink.NewMockAdapter()
+	device := tc.MustNewDeviceName("eth0")
+	controller := newHookFilterController(mock)
+	require.NoError(t, controller.Apply())
+
+	// Another tool attaches to the qdisc Apply added
+	foreign := entities.NewFilter(device, tc.MustParseHandle("ffff:fff3"), 1, tc.NewHandle(0x800, 1))
+	foreign.AddAction(entities.NewPassAction())
+	require.NoError(t, mock.AddFilter(context.Background(), foreign))
+
+	require.NoError(t, controller.RemoveHookFilter("ssh"))
+	require.NoError(t, controller.RemoveHookFilter("monitor"))
+	assert.Len(t, hookFilters(mock, device), 1)
+	assert.True(t, mock.HasClsactQdisc(device))
+}
+
+func TestOnIngress_Validation(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").WithPriority(6)
+	controller.OnIngress("count").ForPort(80)
+	controller.OnIngress("block").ForSource("192.0.2.0/24").WithActions(Drop(), MarkPackets(1))
+	controller.OnEgress("loop").WithActions(RedirectTo("eth0"))
+	controller.OnIngress("hairpin").WithActions(RedirectTo("eth0"))
+	controller.WithBPFClassifier("sni", BPFClassifier{Object: "sni.o", Priority: 120})
+	controller.OnEgress("late").WithActions(Pass())
+	controller.OnEgress("sni_twin").WithPriority(120).WithActions(Pass())
+
+	report := controller.Validate()
+	require.Len(t, report.Errors, 4)
+	for _, issue := range report.Errors {
+		assert.Equal(t, "invalid_hook_filter", issue.Code)
+	}
+	assert.Contains(t, report.Errors[0].Message, "hook filter 'count' has no actions")
+	assert.Contains(t, report.Errors[1].Message, "hook filter 'block': invalid action chain")
+	assert.Contains(t, report.Errors[2].Message, "hook filter 'loop': cannot redirect packets of eth0 to itself")
+	assert.Contains(t, report.Errors[3].Message, "BPF classifier 'sni' and hook filter 'sni_twin' are both attached to egress prio 120")
+}
+
+func TestOnIngress_DryRun(t *testing.T) {
+	controller := newHookFilterController(netlink.NewMockAdapter())
+
+	commands, err := controller.DryRun()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(commands), 4)
+	assert.Equal(t, []string{
+		"tc qdisc add dev eth0 clsact",
+		"tc filter replace dev eth0 ingress protocol ip prio 100 u32 match ip dport 22 0xffff action police rate 1000000bit burst 16000 conform-exceed drop/pipe",
+		"tc filter replace dev eth0 egress protocol ip prio 100 u32 match ip dst 10.0.0.0/8 action mirred egress mirror dev mon0",
+		"tc filter replace dev eth0 egress protocol ip prio 101 u32 match ip dst 192.168.0.0/16 action mirred egress mirror dev mon0",
+	}, commands[len(commands)-4:])
+}
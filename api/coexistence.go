@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+)
+
+// OwnershipPolicy decides what Apply, DryRun and Reset do with the qdiscs, classes and
+// filters other tools install on the device
+type OwnershipPolicy int
+
+const (
+	// ExclusiveOwnership makes the configuration the only one on the device: Apply deletes
+	// whatever it does not install. This is the default.
+	ExclusiveOwnership OwnershipPolicy = iota
+	// Coexistence shares the device with other tools, such as systemd-networkd or Docker.
+	// The controller's objects are the root qdisc 1: and everything attached below it;
+	// Apply and Reset never delete the others, Delete refuses them and Plan reports them.
+	// Apply fails instead of replacing a root qdisc of another tool.
+	Coexistence
+)
+
+// String returns the name of the policy
+func (policy OwnershipPolicy) String() string {
+	if policy == Coexistence {
+		return "coexistence"
+	}
+	return "exclusive"
+}
+
+// WithOwnershipPolicy sets what the controller does with the objects of other tools on the
+// device, e.g. WithOwnershipPolicy(api.Coexistence) on a host where systemd-networkd also
+// installs qdiscs
+func (controller *TrafficController) WithOwnershipPolicy(policy OwnershipPolicy) *TrafficController {
+	controller.ownership = policy
+	return controller
+}
+
+// ownershipContext returns ctx carrying the controller's ownership policy to the service
+func (controller *TrafficController) ownershipContext(ctx context.Context) context.Context {
+	if controller.ownership == Coexistence {
+		return application.WithCoexistence(ctx)
+	}
+	return ctx
+}
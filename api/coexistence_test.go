@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// addForeignTree installs a root qdisc 8001: with a class and a filter, as another tool
+// such as systemd-networkd would
+func addForeignTree(t *testing.T, mock *netlink.MockAdapter) {
+	ctx := context.Background()
+	device := tc.MustNewDeviceName("eth0")
+	root := tc.MustParseHandle("8001:0")
+	class := tc.MustParseHandle("8001:1")
+
+	require.NoError(t, mock.AddQdisc(ctx, entities.NewQdisc(device, root, entities.QdiscTypeHTB)))
+	require.NoError(t, mock.AddClass(ctx, entities.NewClass(device, class, root, "", entities.Priority(0))))
+	filter := entities.NewFilter(device, root, 1, tc.NewHandle(0x800, 0x800))
+	filter.SetFlowID(class)
+	require.NoError(t, mock.AddFilter(ctx, filter))
+}
+
+func TestCoexistence_ForeignRootQdisc(t *testing.T) {
+	mock := netlink.NewMockAdapter()
+	device := tc.MustNewDeviceName("eth0")
+	addForeignTree(t, mock)
+
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(mock).WithOwnershipPolicy(Coexistence)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("30mbps").WithPriority(1).ForPort(80)
+
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	assert.Equal(t, []qmodels.ForeignObjectView{
+		{Object: "class 8001:1", Parent: "8001:"},
+		{Object: "filter 8001: prio 1 flowid 8001:1", Parent: "8001:"},
+		{Object: "qdisc 8001:"},
+	}, plan.Foreign)
+
+	// Apply refuses to replace the root qdisc of the other tool
+	_, err = controller.DryRun()
+	assert.True(t, errors.Is(err, tcerrors.ErrHandleConflict))
+	err = controller.Apply()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, tcerrors.ErrHandleConflict))
+	assert.ErrorContains(t, err, "qdisc 8001: on device eth0 belongs to another tool")
+	assert.Len(t, mock.GetQdiscs(device).Value(), 1)
+	assert.Len(t, mock.GetFilters(device).Value(), 1)
+
+	// Nor does it delete the other tool's objects
+	deleted, err := controller.Reset()
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+	_, err = controller.Delete(Objects{Classes: []string{"8001:1"}})
+	assert.ErrorContains(t, err, "class 8001:1 on device eth0 belongs to another tool")
+	assert.Len(t, mock.GetClasses(device).Value(), 1)
+
+	// Exclusive ownership replaces them
+	controller.WithOwnershipPolicy(ExclusiveOwnership)
+	plan, err = controller.Plan()
+	require.NoError(t, err)
+	assert.Empty(t, plan.Foreign)
+	require.NoError(t, controller.Apply())
+	for _, qdisc := range mock.GetQdiscs(device).Value() {
+		assert.NotEqual(t, tc.MustParseHandle("8001:0"), qdisc.Handle)
+	}
+}
+
+func TestCoexistence_OwnsUnrecordedTree(t *testing.T) {
+	mock := netlink.NewMockAdapter()
+	device := tc.MustNewDeviceName("eth0")
+
+	previous := NetworkInterface("eth0").WithNetlinkAdapter(mock)
+	previous.WithHardLimitBandwidth("100mbps")
+	previous.CreateTrafficClass("web").WithGuaranteedBandwidth("30mbps").WithPriority(1).WithLeafQdisc(SFQ()).ForPort(80)
+	previous.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").WithPriority(6)
+	require.NoError(t, previous.Apply())
+
+	// A new process has no records of the tree under 1:, which is still recognised as the
+	// library's own and reconciled
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(mock).WithOwnershipPolicy(Coexistence)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").WithPriority(6)
+
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	assert.Empty(t, plan.Foreign)
+	require.NoError(t, controller.Apply())
+
+	handles := make(map[string]bool)
+	for _, class := range mock.GetClasses(device).Value() {
+		handles[class.Handle.String()] = true
+	}
+	assert.Equal(t, map[string]bool{"1:16": true, "1:999": true}, handles)
+	require.Len(t, mock.GetQdiscs(device).Value(), 1, "the leaf qdisc of the removed class is removed too")
+}
@@ -9,6 +9,7 @@ import (
 
 	yaml "gopkg.in/yaml.v3"
 
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
 	"github.com/rng999/traffic-control-go/pkg/tc"
 )
 
@@ -20,6 +21,13 @@ type TrafficControlConfig struct {
 	Defaults  *DefaultConfig       `yaml:"defaults,omitempty" json:"defaults,omitempty"`
 	Classes   []TrafficClassConfig `yaml:"classes" json:"classes"`
 	Rules     []TrafficRuleConfig  `yaml:"rules,omitempty" json:"rules,omitempty"`
+
+	// Templates are classes parameterized by ${variables}, added once per instance
+	Templates []ClassTemplateConfig `yaml:"templates,omitempty" json:"templates,omitempty"`
+	Instances []ClassInstanceConfig `yaml:"instances,omitempty" json:"instances,omitempty"`
+
+	// Oversubscription is "strict" (the default), "warn" or "scale", see OversubscriptionPolicy
+	Oversubscription string `yaml:"oversubscription,omitempty" json:"oversubscription,omitempty"`
 }
 
 // DefaultConfig represents default settings
@@ -30,12 +38,26 @@ type DefaultConfig struct {
 
 // TrafficClassConfig represents a traffic class configuration
 type TrafficClassConfig struct {
-	Name       string               `yaml:"name" json:"name"`
-	Parent     string               `yaml:"parent,omitempty" json:"parent,omitempty"`
-	Guaranteed string               `yaml:"guaranteed" json:"guaranteed"`
-	Maximum    string               `yaml:"maximum,omitempty" json:"maximum,omitempty"`
-	Priority   *int                 `yaml:"priority,omitempty" json:"priority,omitempty"`
-	Children   []TrafficClassConfig `yaml:"children,omitempty" json:"children,omitempty"`
+	Name           string               `yaml:"name" json:"name"`
+	Parent         string               `yaml:"parent,omitempty" json:"parent,omitempty"` // Class to nest under, see WithParent; children nest under the class listing them
+	Guaranteed     string               `yaml:"guaranteed" json:"guaranteed"`
+	Maximum        string               `yaml:"maximum,omitempty" json:"maximum,omitempty"`
+	Priority       *int                 `yaml:"priority,omitempty" json:"priority,omitempty"`
+	Handle         string               `yaml:"handle,omitempty" json:"handle,omitempty"`                   // Pinned class handle, e.g. "1:20"
+	Profile        string               `yaml:"profile,omitempty" json:"profile,omitempty"`                 // Burst and quantum tuning profile, e.g. "low-latency"
+	Borrowing      *bool                `yaml:"borrowing,omitempty" json:"borrowing,omitempty"`             // false keeps the class at its guarantee, see WithBorrowingDisabled
+	Quantum        int                  `yaml:"quantum,omitempty" json:"quantum,omitempty"`                 // Bytes per borrowing round, see WithQuantum
+	BorrowWeight   int                  `yaml:"borrow_weight,omitempty" json:"borrow_weight,omitempty"`     // Quantum in full frames, see WithBorrowWeight
+	BorrowPriority int                  `yaml:"borrow_priority,omitempty" json:"borrow_priority,omitempty"` // HTB priority, see WithBorrowPriority
+	Schedules      []ScheduleConfig     `yaml:"schedules,omitempty" json:"schedules,omitempty"`
+	Children       []TrafficClassConfig `yaml:"children,omitempty" json:"children,omitempty"`
+}
+
+// ScheduleConfig gives a class another bandwidth during a time window (see WithSchedule)
+type ScheduleConfig struct {
+	Window     string `yaml:"window" json:"window"` // e.g. "01:00-06:00" or "* 1-5 * * mon-fri"
+	Guaranteed string `yaml:"guaranteed,omitempty" json:"guaranteed,omitempty"`
+	Maximum    string `yaml:"maximum,omitempty" json:"maximum,omitempty"`
 }
 
 // TrafficRuleConfig represents a traffic rule configuration
@@ -54,6 +76,7 @@ type MatchConfig struct {
 	DestPort      []int    `yaml:"dest_port,omitempty" json:"dest_port,omitempty"`
 	Protocol      string   `yaml:"protocol,omitempty" json:"protocol,omitempty"`
 	Application   []string `yaml:"application,omitempty" json:"application,omitempty"`
+	Cgroup        string   `yaml:"cgroup,omitempty" json:"cgroup,omitempty"`
 }
 
 // LoadConfigFromYAML loads configuration from a YAML file
@@ -69,6 +92,11 @@ func LoadConfigFromYAML(filename string) (*TrafficControlConfig, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	return ParseConfigFromYAML(data)
+}
+
+// ParseConfigFromYAML parses and validates a YAML configuration read by the caller
+func ParseConfigFromYAML(data []byte) (*TrafficControlConfig, error) {
 	var config TrafficControlConfig
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
@@ -94,6 +122,11 @@ func LoadConfigFromJSON(filename string) (*TrafficControlConfig, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	return ParseConfigFromJSON(data)
+}
+
+// ParseConfigFromJSON parses and validates a JSON configuration read by the caller
+func ParseConfigFromJSON(data []byte) (*TrafficControlConfig, error) {
 	var config TrafficControlConfig
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
@@ -116,25 +149,34 @@ func (c *TrafficControlConfig) Validate() error {
 		return fmt.Errorf("bandwidth is required")
 	}
 
-	if len(c.Classes) == 0 {
+	classes, rules, err := c.expandTemplates()
+	if err != nil {
+		return err
+	}
+
+	if len(classes) == 0 {
 		return fmt.Errorf("at least one class is required")
 	}
 
+	if _, err := ParseOversubscriptionPolicy(c.Oversubscription); err != nil {
+		return err
+	}
+
 	// Validate class names are unique
 	classNames := make(map[string]bool)
-	for i := range c.Classes {
-		if err := validateClassConfig(&c.Classes[i], classNames, ""); err != nil {
+	for i := range classes {
+		if err := validateClassConfig(&classes[i], classNames, ""); err != nil {
 			return err
 		}
 	}
 
 	// Validate rules reference existing classes
-	for i := range c.Rules {
-		if c.Rules[i].Target == "" {
-			return fmt.Errorf("rule %s: target is required", c.Rules[i].Name)
+	for i := range rules {
+		if rules[i].Target == "" {
+			return fmt.Errorf("rule %s: target is required", rules[i].Name)
 		}
-		if !classNames[c.Rules[i].Target] {
-			return fmt.Errorf("rule %s: target class '%s' not found", c.Rules[i].Name, c.Rules[i].Target)
+		if !classNames[rules[i].Target] {
+			return fmt.Errorf("rule %s: target class '%s' not found", rules[i].Name, rules[i].Target)
 		}
 	}
 
@@ -177,9 +219,70 @@ func validateClassConfig(class *TrafficClassConfig, classNames map[string]bool,
 
 // ApplyConfig applies a structured configuration using the chain API
 func (controller *TrafficController) ApplyConfig(config *TrafficControlConfig) error {
+	controller.mu.Lock()
+	defer controller.mu.Unlock()
+	return controller.applyConfig(config)
+}
+
+// applyConfig is ApplyConfig for a caller holding mu
+func (controller *TrafficController) applyConfig(config *TrafficControlConfig) error {
+	if err := controller.configure(config); err != nil {
+		return err
+	}
+
+	// Apply the configuration
+	_, err := controller.apply()
+	return err
+}
+
+// ReplaceConfig replaces the controller's classes and rules with those of a configuration
+// and applies it. Only what differs from the configuration applied before reaches the
+// device. When the configuration is invalid or applying it fails, the controller keeps its
+// previous configuration.
+func (controller *TrafficController) ReplaceConfig(config *TrafficControlConfig) error {
+	controller.mu.Lock()
+	defer controller.mu.Unlock()
+
+	previousDevice, previousBandwidth, previousBandwidthErr := controller.deviceName, controller.totalBandwidth, controller.bandwidthErr
+	previousClasses := controller.classes
+	controller.classes, controller.pendingBuilders = nil, nil
+
+	if err := controller.applyConfig(config); err != nil {
+		controller.deviceName, controller.totalBandwidth, controller.bandwidthErr = previousDevice, previousBandwidth, previousBandwidthErr
+		controller.classes, controller.pendingBuilders = previousClasses, nil
+		return err
+	}
+	return nil
+}
+
+// DryRunConfig builds the controller's classes and rules from a configuration like
+// ApplyConfig, then returns the tc commands applying it would run instead of running them
+func (controller *TrafficController) DryRunConfig(config *TrafficControlConfig) ([]string, error) {
+	if err := controller.configure(config); err != nil {
+		return nil, err
+	}
+	return controller.DryRun()
+}
+
+// PlanConfig builds the controller's classes and rules from a configuration like
+// ApplyConfig, then returns the resolved plan without touching the device
+func (controller *TrafficController) PlanConfig(config *TrafficControlConfig) (*qmodels.PlanView, error) {
+	if err := controller.configure(config); err != nil {
+		return nil, err
+	}
+	return controller.Plan()
+}
+
+// configure builds the controller's classes and rules from a configuration
+func (controller *TrafficController) configure(config *TrafficControlConfig) error {
 	// Set device and bandwidth
 	controller.deviceName = config.Device
-	controller.totalBandwidth = tc.MustParseBandwidth(config.Bandwidth)
+	controller.WithHardLimitBandwidth(config.Bandwidth)
+	policy, err := ParseOversubscriptionPolicy(config.Oversubscription)
+	if err != nil {
+		return err
+	}
+	controller.oversubscription = policy
 
 	// Apply defaults
 	defaults := config.Defaults
@@ -189,8 +292,12 @@ func (controller *TrafficController) ApplyConfig(config *TrafficControlConfig) e
 		}
 	}
 
-	// Create classes
-	if err := controller.createClassesFromConfig(config.Classes, defaults, ""); err != nil {
+	// Create classes, those of template instances included
+	classes, rules, err := config.expandTemplates()
+	if err != nil {
+		return err
+	}
+	if err := controller.createClassesFromConfig(classes, defaults, ""); err != nil {
 		return err
 	}
 
@@ -198,14 +305,13 @@ func (controller *TrafficController) ApplyConfig(config *TrafficControlConfig) e
 	controller.finalizePendingClasses()
 
 	// Apply rules
-	for i := range config.Rules {
-		if err := controller.createRuleFromConfig(&config.Rules[i]); err != nil {
+	for i := range rules {
+		if err := controller.createRuleFromConfig(&rules[i]); err != nil {
 			return err
 		}
 	}
 
-	// Apply the configuration
-	return controller.Apply()
+	return nil
 }
 
 // createClassesFromConfig recursively creates classes from configuration
@@ -222,11 +328,16 @@ func (controller *TrafficController) createClassesFromConfig(classes []TrafficCl
 			WithGuaranteedBandwidth(classConfig.Guaranteed)
 
 		// Apply maximum bandwidth
-		if classConfig.Maximum != "" {
+		if classConfig.Borrowing != nil && !*classConfig.Borrowing {
+			builder.WithBorrowingDisabled()
+		} else if classConfig.Maximum != "" {
 			builder.WithSoftLimitBandwidth(classConfig.Maximum)
-		} else if defaults.BurstRatio > 1.0 {
-			// Calculate burst based on guaranteed and ratio
-			guaranteed := tc.MustParseBandwidth(classConfig.Guaranteed)
+		} else if defaults.BurstRatio > 1.0 && isRelativeBandwidth(classConfig.Guaranteed) {
+			// The guarantee is only known once the total is
+			builder.WithSoftLimitBandwidth(fmt.Sprintf("%gx guaranteed", defaults.BurstRatio))
+		} else if guaranteed, err := tc.ParseBandwidth(classConfig.Guaranteed); err == nil && defaults.BurstRatio > 1.0 {
+			// Calculate burst based on guaranteed and ratio; an invalid guarantee is
+			// reported by validation
 			burst := fmt.Sprintf("%dMbps", int(float64(guaranteed.MegabitsPerSecond())*defaults.BurstRatio))
 			builder.WithSoftLimitBandwidth(burst)
 		}
@@ -237,6 +348,30 @@ func (controller *TrafficController) createClassesFromConfig(classes []TrafficCl
 		}
 		// Note: validation will catch missing priority later
 
+		// Children are nested under the class listing them, top-level classes under the
+		// class their parent field names
+		if parentName != "" {
+			builder.WithParent(parentName)
+		} else if classConfig.Parent != "" {
+			builder.WithParent(classConfig.Parent)
+		}
+		if classConfig.Handle != "" {
+			builder.WithHandle(classConfig.Handle)
+		}
+		if classConfig.Profile != "" {
+			builder.WithProfile(classConfig.Profile)
+		}
+		if classConfig.Quantum != 0 {
+			builder.WithQuantum(classConfig.Quantum)
+		}
+		if classConfig.BorrowWeight != 0 {
+			builder.WithBorrowWeight(classConfig.BorrowWeight)
+		}
+		builder.WithBorrowPriority(classConfig.BorrowPriority)
+		for _, schedule := range classConfig.Schedules {
+			builder.WithSchedule(schedule.Window, schedule.Guaranteed, schedule.Maximum)
+		}
+
 		// The builder is automatically added to pendingBuilders in CreateTrafficClass
 		// No need to manually append to controller.classes here
 
@@ -304,6 +439,13 @@ func (controller *TrafficController) createRuleFromConfig(rule *TrafficRuleConfi
 		})
 	}
 
+	if match.Cgroup != "" {
+		targetClass.filters = append(targetClass.filters, Filter{
+			filterType: CgroupFilter,
+			value:      match.Cgroup,
+		})
+	}
+
 	return nil
 }
 
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// defaultClassName names the HTB default class for unclassified traffic in contention reports
+const defaultClassName = "default"
+
+// Default class for unclassified traffic, see applyConfiguration
+var (
+	defaultClassMaxRate  = tc.Mbps(1)
+	defaultClassPriority = uint8(0) // Created without a priority, so HTB serves it first
+)
+
+// defaultClassRate returns the rate guaranteed to the default class: the bandwidth given to
+// WithDefaultClass, or else 1Mbps at most the interface's
+func (controller *TrafficController) defaultClassRate() tc.Bandwidth {
+	if controller.defaultClass != nil {
+		if rate, err := resolveBandwidth(controller.defaultClass.bandwidth, controller.totalBandwidth); err == nil {
+			return rate
+		}
+	}
+	if controller.totalBandwidth.LessThan(defaultClassMaxRate) {
+		return controller.totalBandwidth
+	}
+	return defaultClassMaxRate
+}
+
+// defaultClassLabel names the default class: the name given to WithDefaultClass, or
+// defaultClassName
+func (controller *TrafficController) defaultClassLabel() string {
+	if controller.defaultClass != nil {
+		return controller.defaultClass.name
+	}
+	return defaultClassName
+}
+
+// ClassAllocation is the bandwidth a traffic class receives under worst-case contention
+type ClassAllocation struct {
+	Class      string
+	Priority   uint8
+	Guaranteed tc.Bandwidth
+	Ceiling    tc.Bandwidth // Bandwidth the class offers in the simulation
+	Allocated  tc.Bandwidth
+}
+
+// Starved reports whether the class receives less than its guarantee
+func (a ClassAllocation) Starved() bool {
+	return a.Allocated.LessThan(a.Guaranteed)
+}
+
+// ContentionReport is the outcome of simulating every class, including the default class,
+// offering its ceiling at once
+type ContentionReport struct {
+	TotalBandwidth tc.Bandwidth
+	Allocations    []ClassAllocation // In HTB service order: priority, then configuration order
+}
+
+// Starved returns the classes that receive less than their guarantee
+func (r *ContentionReport) Starved() []ClassAllocation {
+	var starved []ClassAllocation
+	for _, allocation := range r.Allocations {
+		if allocation.Starved() {
+			starved = append(starved, allocation)
+		}
+	}
+	return starved
+}
+
+// SimulateContention simulates worst-case contention on the interface: every traffic class
+// offers its ceiling at the same time. The report shows which classes HTB would starve
+// below their guarantee, a misconfiguration that only shows under load.
+func (controller *TrafficController) SimulateContention() (*ContentionReport, error) {
+	controller.finalizePendingClasses()
+
+	if controller.totalBandwidth.BitsPerSecond() == 0 {
+		return nil, fmt.Errorf("total bandwidth not set. Use WithHardLimitBandwidth() to specify the interface bandwidth")
+	}
+	for _, class := range controller.classes {
+		if class.priority == nil {
+			return nil, fmt.Errorf("class '%s' does not have a priority set", class.name)
+		}
+	}
+
+	return controller.simulateContention(), nil
+}
+
+// simulateContention distributes the total bandwidth with every class backlogged up to its
+// ceiling. All classes must have a priority.
+func (controller *TrafficController) simulateContention() *ContentionReport {
+	allocations := controller.classAllocations(controller.defaultClassRate())
+	distribute(allocations, controller.totalBandwidth)
+
+	return &ContentionReport{
+		TotalBandwidth: controller.totalBandwidth,
+		Allocations:    allocations,
+	}
+}
+
+// classAllocations returns an allocation for every class of the root qdisc and the default
+// class guaranteed defaultRate, in HTB service order, offering its ceiling. Nested classes
+// share the allocation of their top-level class. All classes must have a priority.
+func (controller *TrafficController) classAllocations(defaultRate tc.Bandwidth) []ClassAllocation {
+	allocations := make([]ClassAllocation, 0, len(controller.classes)+1)
+	for _, class := range controller.childrenOf(nil) {
+		ceiling := class.maxBandwidth
+		if ceiling.LessThan(class.guaranteedBandwidth) {
+			ceiling = class.guaranteedBandwidth
+		}
+		allocations = append(allocations, ClassAllocation{
+			Class:      class.name,
+			Priority:   *class.priority,
+			Guaranteed: class.guaranteedBandwidth,
+			Ceiling:    ceiling,
+		})
+	}
+	allocations = append(allocations, ClassAllocation{
+		Class:      controller.defaultClassLabel(),
+		Priority:   defaultClassPriority,
+		Guaranteed: defaultRate,
+		Ceiling:    controller.totalBandwidth,
+	})
+	sort.SliceStable(allocations, func(i, j int) bool {
+		return allocations[i].Priority < allocations[j].Priority
+	})
+	return allocations
+}
+
+// distribute shares the total bandwidth the way HTB does among classes offering their
+// Ceiling: first each class up to its guarantee, in priority order while bandwidth lasts,
+// then the remainder is lent to the classes still below their ceiling, highest priority
+// first, shared within a priority in proportion to the guarantees. The allocations must be
+// in service order.
+func distribute(allocations []ClassAllocation, total tc.Bandwidth) {
+	remaining := total.BitsPerSecond()
+
+	// Guarantees, as far as the class uses them
+	for i := range allocations {
+		grant := min(allocations[i].Guaranteed.BitsPerSecond(), allocations[i].Ceiling.BitsPerSecond(), remaining)
+		allocations[i].Allocated = tc.Bps(grant)
+		remaining -= grant
+	}
+
+	// Borrowing, one priority level at a time
+	for start := 0; start < len(allocations) && remaining > 0; {
+		end := start
+		for end < len(allocations) && allocations[end].Priority == allocations[start].Priority {
+			end++
+		}
+		remaining = lend(allocations[start:end], remaining)
+		start = end
+	}
+}
+
+// lend shares the available bandwidth among classes of the same priority in proportion to
+// their guarantees, capped at their ceilings, and returns what is left over
+func lend(allocations []ClassAllocation, available uint64) uint64 {
+	for available > 0 {
+		var weights uint64
+		for _, allocation := range allocations {
+			if allocation.Allocated.LessThan(allocation.Ceiling) {
+				weights += max(allocation.Guaranteed.BitsPerSecond(), 1)
+			}
+		}
+		if weights == 0 {
+			return available
+		}
+
+		lent := uint64(0)
+		for i := range allocations {
+			allocation := &allocations[i]
+			if !allocation.Allocated.LessThan(allocation.Ceiling) {
+				continue
+			}
+			share := uint64(float64(available) * float64(max(allocation.Guaranteed.BitsPerSecond(), 1)) / float64(weights))
+			share = max(min(share, allocation.Ceiling.Subtract(allocation.Allocated).BitsPerSecond(), available-lent), 1)
+			allocation.Allocated = allocation.Allocated.Add(tc.Bps(share))
+			lent += share
+			if lent == available {
+				break
+			}
+		}
+		available -= lent
+	}
+	return 0
+}
+
+// validateContention fails when worst-case contention starves a class below its guarantee.
+// The default class created when WithDefaultClass is not used is left out of the guarantees:
+// it only takes what the configured classes leave, so that guarantees filling the link pass.
+func (controller *TrafficController) validateContention() error {
+	defaultRate := tc.Bandwidth{}
+	if controller.defaultClass != nil {
+		defaultRate = controller.defaultClassRate()
+	}
+	allocations := controller.classAllocations(defaultRate)
+	distribute(allocations, controller.totalBandwidth)
+
+	report := &ContentionReport{TotalBandwidth: controller.totalBandwidth, Allocations: allocations}
+	starved := report.Starved()
+	if len(starved) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, 0, len(starved))
+	for _, allocation := range starved {
+		descriptions = append(descriptions, fmt.Sprintf("'%s' (priority %d) gets %s of %s guaranteed",
+			allocation.Class, allocation.Priority, allocation.Allocated, allocation.Guaranteed))
+	}
+	suggestion := "Reduce guaranteed bandwidths"
+	if controller.defaultClass != nil {
+		suggestion = fmt.Sprintf("Leave room for the %s default class for unclassified traffic or reduce guaranteed bandwidths", defaultRate)
+	}
+	return fmt.Errorf("when all classes offer their ceiling, %s\nSuggestion: %s", strings.Join(descriptions, ", "), suggestion)
+}
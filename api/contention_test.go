@@ -0,0 +1,100 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestTrafficController_SimulateContention(t *testing.T) {
+	t.Run("lends_spare_bandwidth_by_priority", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("interactive").
+			WithGuaranteedBandwidth("20mbps").
+			WithSoftLimitBandwidth("50mbps").
+			WithPriority(1)
+		controller.CreateTrafficClass("bulk").
+			WithGuaranteedBandwidth("30mbps").
+			WithSoftLimitBandwidth("100mbps").
+			WithPriority(5)
+
+		report, err := controller.SimulateContention()
+		require.NoError(t, err)
+		assert.Empty(t, report.Starved())
+
+		allocated := make(map[string]tc.Bandwidth)
+		var total tc.Bandwidth
+		for _, allocation := range report.Allocations {
+			allocated[allocation.Class] = allocation.Allocated
+			total = total.Add(allocation.Allocated)
+		}
+
+		// The default class has priority 0 and borrows everything left first
+		assert.Equal(t, tc.Mbps(20), allocated["interactive"])
+		assert.Equal(t, tc.Mbps(30), allocated["bulk"])
+		assert.Equal(t, tc.Mbps(50), allocated[defaultClassName])
+		assert.Equal(t, tc.Mbps(100), total)
+	})
+
+	t.Run("reports_low_priority_class_starved_by_default_class", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("web").
+			WithGuaranteedBandwidth("60mbps").
+			WithPriority(1)
+		controller.CreateTrafficClass("backup").
+			WithGuaranteedBandwidth("40mbps"). // Guarantees fill the link, leaving nothing for the default class
+			WithPriority(6)
+
+		report, err := controller.SimulateContention()
+		require.NoError(t, err)
+
+		starved := report.Starved()
+		require.Len(t, starved, 1)
+		assert.Equal(t, "backup", starved[0].Class)
+		assert.Equal(t, tc.Mbps(39), starved[0].Allocated)
+	})
+
+	t.Run("requires_total_bandwidth", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.CreateTrafficClass("web").WithPriority(1)
+
+		_, err := controller.SimulateContention()
+		assert.Error(t, err)
+	})
+}
+
+func TestTrafficController_ValidateContention(t *testing.T) {
+	newController := func() *TrafficController {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("web").
+			WithGuaranteedBandwidth("60mbps").
+			WithPriority(1)
+		controller.CreateTrafficClass("backup").
+			WithGuaranteedBandwidth("40mbps").
+			WithPriority(6)
+		return controller
+	}
+
+	t.Run("guarantees_filling_the_link", func(t *testing.T) {
+		// The implicit default class only takes what the configured classes leave
+		report := newController().Validate()
+		assert.True(t, report.Valid(), "%v", report.Errors)
+	})
+
+	t.Run("configured_default_class", func(t *testing.T) {
+		// A default class given a bandwidth counts with the other guarantees
+		controller := newController()
+		controller.WithDefaultClass("rest", "1mbps")
+
+		err := controller.Apply()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "101.0Mbps")
+	})
+}
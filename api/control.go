@@ -0,0 +1,411 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// DefaultControlSocket is the default path of the local control socket
+const DefaultControlSocket = "/run/traffic-control.sock"
+
+// Control socket methods
+const (
+	ControlMethodApply  = "apply"
+	ControlMethodPlan   = "plan"
+	ControlMethodStats  = "stats"
+	ControlMethodHealth = "health"
+	ControlMethodSchema = "schema"
+)
+
+// JSON-RPC 2.0 error codes used by the control socket
+const (
+	controlErrParse          = -32700
+	controlErrInvalidRequest = -32600
+	controlErrMethodNotFound = -32601
+	controlErrInvalidParams  = -32602
+	controlErrServer         = -32000
+)
+
+// controlRequest is a JSON-RPC 2.0 request; one request is sent per line
+type controlRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// controlResponse is a JSON-RPC 2.0 response; one response is sent per line
+type controlResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *ControlError   `json:"error,omitempty"`
+}
+
+// ControlError is an error returned by the control socket
+type ControlError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e *ControlError) Error() string {
+	return fmt.Sprintf("control error %d: %s", e.Code, e.Message)
+}
+
+// ControlHealth is the result of the health method
+type ControlHealth struct {
+	Status    string    `json:"status"`
+	Device    string    `json:"device"`
+	StartedAt time.Time `json:"started_at"`
+	LastApply time.Time `json:"last_apply,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// ControlDryRun is the result of the apply method on a server in dry-run mode
+type ControlDryRun struct {
+	Applied  bool     `json:"applied"`
+	Commands []string `json:"commands"`
+}
+
+// ControlServer exposes a traffic controller on a local unix socket, or on any stream such as
+// a bridge process's stdin and stdout, so that tools talk to one long-running process instead
+// of each creating its own controller. The protocol is JSON-RPC 2.0 with one request and one
+// response per line, offering the methods "apply" and "plan" (params: a TrafficControlConfig),
+// "stats", "health" and "schema", which describes the params and results of every method.
+type ControlServer struct {
+	controller *TrafficController
+	logger     logging.Logger
+	startedAt  time.Time
+
+	dryRun  bool                              // Answer apply requests with the tc commands instead of applying
+	onApply func(*TrafficControlConfig) error // Applies in place of the controller, see OnApply
+
+	mu        sync.Mutex // Serialises applies and guards the fields below
+	lastApply time.Time
+	lastError string
+}
+
+// NewControlServer creates a control server for the controller
+func (controller *TrafficController) NewControlServer() *ControlServer {
+	return &ControlServer{
+		controller: controller,
+		logger:     controller.logger,
+		startedAt:  time.Now(),
+	}
+}
+
+// Serve answers control requests on the unix socket until the context is cancelled
+func (s *ControlServer) Serve(ctx context.Context, socketPath string) error {
+	s.logger.Info("Serving control socket",
+		logging.String("socket", socketPath),
+	)
+
+	return serveUnix(ctx, socketPath, s.handle)
+}
+
+// ServeStream answers control requests read from r, writing the responses to w, until r is
+// exhausted. It lets programs in other languages drive the library through a child process's
+// stdin and stdout.
+func (s *ControlServer) ServeStream(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.logger.Info("Serving control requests on a stream")
+
+	return serveLines(ctx, r, w, s.handle)
+}
+
+// handle answers a single JSON-RPC request line
+func (s *ControlServer) handle(_ context.Context, line []byte) interface{} {
+	var request controlRequest
+	if err := json.Unmarshal(line, &request); err != nil {
+		return controlFailure(nil, controlErrParse, fmt.Sprintf("invalid JSON: %v", err))
+	}
+	if request.JSONRPC != "2.0" || request.Method == "" {
+		return controlFailure(request.ID, controlErrInvalidRequest, "expected a JSON-RPC 2.0 request with a method")
+	}
+
+	var (
+		result interface{}
+		err    *ControlError
+	)
+	switch request.Method {
+	case ControlMethodApply:
+		result, err = s.apply(request.Params)
+	case ControlMethodPlan:
+		result, err = s.plan(request.Params)
+	case ControlMethodStats:
+		result, err = s.stats()
+	case ControlMethodHealth:
+		result = s.health()
+	case ControlMethodSchema:
+		result = ControlSchema()
+	default:
+		err = &ControlError{Code: controlErrMethodNotFound, Message: fmt.Sprintf("unknown method %q", request.Method)}
+	}
+
+	if err != nil {
+		return controlFailure(request.ID, err.Code, err.Message)
+	}
+	return controlResponse{JSONRPC: "2.0", ID: request.ID, Result: result}
+}
+
+// controlFailure builds an error response
+func controlFailure(id json.RawMessage, code int, message string) controlResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return controlResponse{JSONRPC: "2.0", ID: id, Error: &ControlError{Code: code, Message: message}}
+}
+
+// parseConfig decodes and validates the configuration of an apply or plan request
+func (s *ControlServer) parseConfig(params json.RawMessage) (*TrafficControlConfig, *ControlError) {
+	var config TrafficControlConfig
+	if err := json.Unmarshal(params, &config); err != nil {
+		return nil, &ControlError{Code: controlErrInvalidParams, Message: fmt.Sprintf("invalid configuration: %v", err)}
+	}
+
+	if config.Device == "" {
+		config.Device = s.controller.deviceName
+	}
+	if config.Device != s.controller.deviceName {
+		return nil, &ControlError{Code: controlErrInvalidParams,
+			Message: fmt.Sprintf("configuration targets device %s but this controller manages %s", config.Device, s.controller.deviceName)}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, &ControlError{Code: controlErrInvalidParams, Message: err.Error()}
+	}
+	return &config, nil
+}
+
+// WithDryRun makes the server answer apply requests with the tc commands applying the
+// configuration would run, as {"applied": false, "commands": [...]}, instead of applying it
+func (s *ControlServer) WithDryRun() *ControlServer {
+	s.dryRun = true
+	return s
+}
+
+// OnApply makes the server hand the validated configuration of apply requests to fn, which
+// applies it and returns the error, instead of replacing the controller's configuration
+// itself. A process using the controller from one goroutine, like tcd, applies there.
+func (s *ControlServer) OnApply(fn func(*TrafficControlConfig) error) *ControlServer {
+	s.onApply = fn
+	return s
+}
+
+// apply replaces the controller's configuration with the one given and applies it.
+// On failure the previous configuration is kept.
+func (s *ControlServer) apply(params json.RawMessage) (interface{}, *ControlError) {
+	config, controlErr := s.parseConfig(params)
+	if controlErr != nil {
+		return nil, controlErr
+	}
+	if s.dryRun {
+		return s.dryRunApply(config)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if s.onApply != nil {
+		err = s.onApply(config)
+	} else {
+		err = s.controller.ReplaceConfig(config)
+	}
+	s.lastApply = time.Now()
+	if err != nil {
+		s.lastError = err.Error()
+		return nil, &ControlError{Code: controlErrServer, Message: err.Error()}
+	}
+
+	s.lastError = ""
+	return map[string]bool{"applied": true}, nil
+}
+
+// dryRunApply returns the tc commands applying the configuration would run. The
+// configuration is built on a copy of the controller sharing its service, so that the
+// commands cover only what differs from the recorded and installed state.
+func (s *ControlServer) dryRunApply(config *TrafficControlConfig) (interface{}, *ControlError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	planner := &TrafficController{
+		deviceName:       config.Device,
+		classes:          make([]*TrafficClass, 0),
+		logger:           s.logger,
+		service:          s.controller.service,
+		ingressDevice:    s.controller.ingressDevice,
+		applyTimeout:     s.controller.applyTimeout,
+		operationTimeout: s.controller.operationTimeout,
+	}
+	commands, err := planner.DryRunConfig(config)
+	if err != nil {
+		return nil, &ControlError{Code: controlErrInvalidParams, Message: err.Error()}
+	}
+	if commands == nil {
+		commands = []string{}
+	}
+	return ControlDryRun{Applied: false, Commands: commands}, nil
+}
+
+// plan applies the configuration to an in-memory copy of the device and returns the qdiscs,
+// classes and filters an apply would install, without touching the kernel
+func (s *ControlServer) plan(params json.RawMessage) (interface{}, *ControlError) {
+	config, controlErr := s.parseConfig(params)
+	if controlErr != nil {
+		return nil, controlErr
+	}
+
+	planner := &TrafficController{
+		deviceName:       config.Device,
+		classes:          make([]*TrafficClass, 0),
+		logger:           s.logger,
+		service:          application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), netlink.NewMockAdapter(), s.logger),
+		applyTimeout:     s.controller.applyTimeout,
+		operationTimeout: s.controller.operationTimeout,
+	}
+	if err := planner.ApplyConfig(config); err != nil {
+		return nil, &ControlError{Code: controlErrInvalidParams, Message: err.Error()}
+	}
+
+	view, err := planner.ReadCurrentConfiguration()
+	if err != nil {
+		return nil, &ControlError{Code: controlErrServer, Message: err.Error()}
+	}
+	return view, nil
+}
+
+// stats returns the device statistics
+func (s *ControlServer) stats() (interface{}, *ControlError) {
+	stats, err := s.controller.GetStatistics()
+	if err != nil {
+		return nil, &ControlError{Code: controlErrServer, Message: err.Error()}
+	}
+	return stats, nil
+}
+
+// health reports the state of the server
+func (s *ControlServer) health() ControlHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return ControlHealth{
+		Status:    "ok",
+		Device:    s.controller.deviceName,
+		StartedAt: s.startedAt,
+		LastApply: s.lastApply,
+		LastError: s.lastError,
+	}
+}
+
+// ControlClient talks to a ControlServer over its unix socket
+type ControlClient struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int
+}
+
+// DialControl connects to the control socket at the given path
+func DialControl(socketPath string) (*ControlClient, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to control socket %s: %w", socketPath, err)
+	}
+
+	return &ControlClient{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}, nil
+}
+
+// Close closes the connection
+func (c *ControlClient) Close() error {
+	return c.conn.Close()
+}
+
+// Apply applies a configuration through the server
+func (c *ControlClient) Apply(config *TrafficControlConfig) error {
+	return c.call(ControlMethodApply, config, nil)
+}
+
+// Plan returns the qdiscs, classes and filters the server would install for a configuration
+func (c *ControlClient) Plan(config *TrafficControlConfig) (*qmodels.ConfigurationView, error) {
+	var view qmodels.ConfigurationView
+	if err := c.call(ControlMethodPlan, config, &view); err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// Statistics retrieves device statistics from the server
+func (c *ControlClient) Statistics() (*qmodels.DeviceStatisticsView, error) {
+	var stats qmodels.DeviceStatisticsView
+	if err := c.call(ControlMethodStats, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Health retrieves the health of the server
+func (c *ControlClient) Health() (*ControlHealth, error) {
+	var health ControlHealth
+	if err := c.call(ControlMethodHealth, nil, &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// call sends a request and decodes the result of its response
+func (c *ControlClient) call(method string, params interface{}, result interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	request := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      c.nextID,
+		"method":  method,
+	}
+	if params != nil {
+		request["params"] = params
+	}
+
+	if err := json.NewEncoder(c.conn).Encode(request); err != nil {
+		return fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", method, err)
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *ControlError   `json:"error"`
+	}
+	if err := json.Unmarshal(line, &response); err != nil {
+		return fmt.Errorf("invalid %s response: %w", method, err)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if result != nil {
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return fmt.Errorf("invalid %s result: %w", method, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestControlServer(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+	socketPath := filepath.Join(t.TempDir(), "control.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- controller.NewControlServer().Serve(ctx, socketPath) }()
+
+	var client *ControlClient
+	require.Eventually(t, func() bool {
+		var err error
+		client, err = DialControl(socketPath)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	defer func() { _ = client.Close() }()
+
+	device, _ := tc.NewDeviceName("eth0")
+	config := &TrafficControlConfig{
+		Version:   "1.0",
+		Bandwidth: "100Mbps",
+		Classes: []TrafficClassConfig{
+			{Name: "web", Guaranteed: "30Mbps", Maximum: "60Mbps", Priority: &[]int{1}[0]},
+			{Name: "bulk", Guaranteed: "10Mbps", Maximum: "50Mbps", Priority: &[]int{4}[0]},
+		},
+	}
+
+	t.Run("health", func(t *testing.T) {
+		health, err := client.Health()
+		require.NoError(t, err)
+		assert.Equal(t, "ok", health.Status)
+		assert.Equal(t, "eth0", health.Device)
+		assert.True(t, health.LastApply.IsZero())
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		require.NoError(t, client.Apply(config))
+		assert.Len(t, mockNetlinkAdapter.GetClasses(device).Value(), 3)
+
+		// Re-applying replaces rather than accumulates the configuration
+		require.NoError(t, client.Apply(config))
+		assert.Len(t, controller.classes, 2)
+		assert.Len(t, mockNetlinkAdapter.GetClasses(device).Value(), 3)
+
+		health, err := client.Health()
+		require.NoError(t, err)
+		assert.False(t, health.LastApply.IsZero())
+		assert.Empty(t, health.LastError)
+	})
+
+	t.Run("apply_invalid_configuration", func(t *testing.T) {
+		invalid := *config
+		invalid.Bandwidth = "lots"
+
+		err := client.Apply(&invalid)
+		var controlErr *ControlError
+		require.True(t, errors.As(err, &controlErr))
+		assert.Equal(t, controlErrServer, controlErr.Code)
+
+		// The previous configuration is kept
+		assert.Len(t, controller.classes, 2)
+		health, err := client.Health()
+		require.NoError(t, err)
+		assert.NotEmpty(t, health.LastError)
+	})
+
+	t.Run("apply_other_device", func(t *testing.T) {
+		other := *config
+		other.Device = "eth1"
+
+		err := client.Apply(&other)
+		var controlErr *ControlError
+		require.True(t, errors.As(err, &controlErr))
+		assert.Equal(t, controlErrInvalidParams, controlErr.Code)
+	})
+
+	t.Run("plan", func(t *testing.T) {
+		planned := *config
+		planned.Classes = append(planned.Classes, TrafficClassConfig{Name: "backup", Guaranteed: "5Mbps", Priority: &[]int{6}[0]})
+
+		view, err := client.Plan(&planned)
+		require.NoError(t, err)
+		assert.Len(t, view.Classes, 4)
+
+		// Planning leaves the device and the controller untouched
+		assert.Len(t, controller.classes, 2)
+		assert.Len(t, mockNetlinkAdapter.GetClasses(device).Value(), 3)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		stats, err := client.Statistics()
+		require.NoError(t, err)
+		assert.Equal(t, "eth0", stats.DeviceName)
+	})
+
+	t.Run("unknown_method", func(t *testing.T) {
+		err := client.call("reboot", nil, nil)
+		var controlErr *ControlError
+		require.True(t, errors.As(err, &controlErr))
+		assert.Equal(t, controlErrMethodNotFound, controlErr.Code)
+	})
+
+	cancel()
+	select {
+	case err := <-served:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not stop after cancellation")
+	}
+}
+
+func TestControlServerServeStream(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), netlink.NewMockAdapter(), controller.logger)
+
+	requests := `{"jsonrpc":"2.0","id":1,"method":"health"}
+{"jsonrpc":"2.0","id":2,"method":"plan","params":{"version":"1.0","bandwidth":"100Mbps","classes":[{"name":"web","guaranteed":"30Mbps","priority":1}]}}
+{"jsonrpc":"2.0","id":3,"method":"schema"}
+not json
+`
+	reader, writer := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- controller.NewControlServer().ServeStream(context.Background(), strings.NewReader(requests), writer)
+		_ = writer.Close()
+	}()
+
+	var responses []map[string]json.RawMessage
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, maxRequestLineBytes)
+	for scanner.Scan() {
+		var response map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &response))
+		responses = append(responses, response)
+	}
+	require.NoError(t, <-served)
+	require.Len(t, responses, 4)
+
+	var health ControlHealth
+	require.NoError(t, json.Unmarshal(responses[0]["result"], &health))
+	assert.Equal(t, "eth0", health.Device)
+
+	var plan struct {
+		Classes []map[string]interface{} `json:"classes"`
+	}
+	require.NoError(t, json.Unmarshal(responses[1]["result"], &plan))
+	assert.Len(t, plan.Classes, 2) // web and the default class
+
+	var schema map[string]MethodSchema
+	require.NoError(t, json.Unmarshal(responses[2]["result"], &schema))
+	assert.Contains(t, schema, ControlMethodApply)
+	assert.Contains(t, schema, ControlMethodPlan)
+
+	assert.Contains(t, string(responses[3]["error"]), "-32700")
+}
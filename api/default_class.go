@@ -0,0 +1,64 @@
+package api
+
+import (
+	"strings"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+// defaultClassHandle is the HTB class receiving traffic no filter classifies
+const defaultClassHandle = "1:999"
+
+// defaultClassSpec is the default class configured with WithDefaultClass
+type defaultClassSpec struct {
+	name      string
+	bandwidth string // Absolute, or a percentage of the total resolved at Apply
+}
+
+// WithDefaultClass names the class that traffic matching no filter falls into and sets the
+// bandwidth guaranteed to it, absolute like "5mbps" or a share of the total like "10%".
+// The class may borrow up to the total. Without it, unclassified traffic is guaranteed
+// 1Mbps in an unnamed class. The guarantee counts towards the total guaranteed bandwidth.
+func (controller *TrafficController) WithDefaultClass(name, bandwidth string) *TrafficController {
+	controller.defaultClass = &defaultClassSpec{name: name, bandwidth: bandwidth}
+	return controller
+}
+
+// UnclassifiedStatistics returns the statistics of the default class, which count the
+// traffic that matched no filter
+func (controller *TrafficController) UnclassifiedStatistics() (*qmodels.ClassStatisticsView, error) {
+	stats, err := controller.GetClassStatistics(defaultClassHandle)
+	if err != nil {
+		return nil, err
+	}
+	stats.Name = controller.defaultClassLabel()
+	return stats, nil
+}
+
+// validateDefaultClass checks the class configured with WithDefaultClass
+func (controller *TrafficController) validateDefaultClass(report *ValidationReport) {
+	spec := controller.defaultClass
+	if spec == nil {
+		return
+	}
+
+	if strings.TrimSpace(spec.name) == "" {
+		controller.addError(report, "invalid_default_class", "", "default class needs a name")
+	}
+	for _, class := range controller.classes {
+		if class.name == spec.name {
+			controller.addError(report, "invalid_default_class", spec.name,
+				"default class '%s' has the name of a traffic class", spec.name)
+		}
+	}
+
+	rate, err := resolveBandwidth(spec.bandwidth, controller.totalBandwidth)
+	switch {
+	case err != nil:
+		controller.addError(report, "invalid_default_class", spec.name,
+			"default class '%s' has an invalid bandwidth: %v", spec.name, err)
+	case rate.BitsPerSecond() == 0:
+		controller.addError(report, "invalid_default_class", spec.name,
+			"default class '%s' must be guaranteed some bandwidth", spec.name)
+	}
+}
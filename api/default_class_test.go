@@ -0,0 +1,79 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func newDefaultClassController(bandwidth string) (*TrafficController, *netlink.MockAdapter) {
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(mockNetlinkAdapter)
+	controller.WithHardLimitBandwidth("100mbps").
+		WithDefaultClass("everything-else", bandwidth)
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithPriority(1).
+		ForPort(80)
+	return controller, mockNetlinkAdapter
+}
+
+func TestTrafficController_WithDefaultClass(t *testing.T) {
+	controller, mockNetlinkAdapter := newDefaultClassController("10%")
+
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	assert.Equal(t, defaultClassHandle, plan.Qdiscs[0].Parameters["default_class"])
+	require.Len(t, plan.Classes, 2)
+	assert.Equal(t, defaultClassHandle, plan.Classes[1].Handle)
+	assert.Equal(t, "everything-else", plan.Classes[1].Name)
+	assert.Equal(t, "10.0Mbps", plan.Classes[1].Rate)
+
+	require.NoError(t, controller.Apply())
+	device := tc.MustNewDeviceName("eth0")
+	mockNetlinkAdapter.SetClassStatistics(device, tc.NewHandle(1, 0x999), netlink.ClassStats{BytesSent: 4200, PacketsSent: 3})
+
+	stats, err := controller.UnclassifiedStatistics()
+	require.NoError(t, err)
+	assert.Equal(t, "everything-else", stats.Name)
+	assert.Equal(t, uint64(4200), stats.BytesSent)
+	assert.Equal(t, uint64(3), stats.PacketsSent)
+}
+
+func TestTrafficController_WithDefaultClassAbsolute(t *testing.T) {
+	controller, _ := newDefaultClassController("5mbps")
+
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	assert.Equal(t, "5.0Mbps", plan.Classes[1].Rate)
+}
+
+func TestTrafficController_WithDefaultClassInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		bandwidth string
+		code      string
+	}{
+		{"everything-else", "150%", "invalid_default_class"},
+		{"everything-else", "lots", "invalid_default_class"},
+		{"everything-else", "0%", "invalid_default_class"},
+		{"web", "10%", "invalid_default_class"},
+		{"everything-else", "80%", "total_guaranteed_exceeds_total"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.bandwidth, func(t *testing.T) {
+			controller, _ := newDefaultClassController(tt.bandwidth)
+			controller.WithDefaultClass(tt.name, tt.bandwidth)
+
+			report := controller.Validate()
+			require.False(t, report.Valid())
+			assert.Equal(t, tt.code, report.Errors[0].Code)
+			assert.Error(t, controller.Apply())
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// Objects names qdiscs, classes and filters of the interface by handle, such as "1:0",
+// "1:10" and "800:800". Filters are named by the handle the kernel reports for them.
+type Objects struct {
+	Qdiscs  []string
+	Classes []string
+	Filters []string
+}
+
+// Delete deletes the named qdiscs, classes and filters from the interface together with
+// everything attached to them, including configuration not made through this controller.
+// It returns the objects deleted, such as "class 1:10". Naming an object the interface
+// does not hold, or under the Coexistence policy one of another tool, is an error and
+// deletes nothing.
+func (controller *TrafficController) Delete(objects Objects) ([]string, error) {
+	var selection application.RemoveSelection
+	for _, group := range []struct {
+		kind    string
+		handles []string
+		into    *[]tc.Handle
+	}{
+		{"qdisc", objects.Qdiscs, &selection.Qdiscs},
+		{"class", objects.Classes, &selection.Classes},
+		{"filter", objects.Filters, &selection.Filters},
+	} {
+		for _, value := range group.handles {
+			handle, err := tc.ParseHandle(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s handle %q: %w", group.kind, value, err)
+			}
+			*group.into = append(*group.into, handle)
+		}
+	}
+
+	return controller.remove(selection)
+}
+
+// Reset deletes every qdisc, class and filter from the interface, including configuration
+// not made through this controller, so that the kernel's default qdisc takes over again.
+// Under the Coexistence policy the objects of other tools are kept. It returns the objects
+// deleted.
+func (controller *TrafficController) Reset() ([]string, error) {
+	return controller.remove(application.RemoveSelection{All: true})
+}
+
+// remove deletes the selected objects and logs the outcome
+func (controller *TrafficController) remove(selection application.RemoveSelection) ([]string, error) {
+	ctx := netlink.WithOperationTimeout(controller.ownershipContext(controller.actorContext(context.Background())), controller.operationTimeout)
+
+	if err := controller.checkPrivileges(ctx); err != nil {
+		return nil, err
+	}
+
+	result, err := controller.service.Remove(ctx, controller.deviceName, selection)
+	if err != nil {
+		controller.logger.Error("Failed to delete traffic control configuration",
+			logging.String("device", controller.deviceName),
+			logging.Error(err),
+		)
+		if result == nil {
+			return nil, err
+		}
+		return result.Deleted, err
+	}
+
+	controller.logger.Info("Deleted traffic control configuration",
+		logging.String("device", controller.deviceName),
+		logging.Int("deleted", len(result.Deleted)),
+	)
+	return result.Deleted, nil
+}
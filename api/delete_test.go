@@ -0,0 +1,68 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// shapedController applies a web class with an SFQ leaf and a port filter to a mock eth0
+func shapedController(t *testing.T) (*TrafficController, *netlink.MockAdapter) {
+	adapter := netlink.NewMockAdapter()
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(adapter)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithPriority(1).
+		WithLeafQdisc(SFQ()).
+		ForPort(80)
+	require.NoError(t, controller.Apply())
+	return controller, adapter
+}
+
+func TestTrafficController_Delete(t *testing.T) {
+	device := tc.MustNewDeviceName("eth0")
+
+	t.Run("deletes_class_with_its_leaf_and_filters", func(t *testing.T) {
+		controller, adapter := shapedController(t)
+
+		deleted, err := controller.Delete(Objects{Classes: []string{"1:11"}})
+		require.NoError(t, err)
+		assert.Contains(t, deleted, "class 1:11")
+		assert.Contains(t, deleted, "qdisc 11:")
+		assert.Empty(t, adapter.GetFilters(device).Value())
+
+		classes := adapter.GetClasses(device).Value()
+		require.Len(t, classes, 1)
+		assert.Equal(t, "1:999", classes[0].Handle.String())
+	})
+
+	t.Run("rejects_unknown_object", func(t *testing.T) {
+		controller, adapter := shapedController(t)
+
+		_, err := controller.Delete(Objects{Classes: []string{"1:11"}, Qdiscs: []string{"7:0"}})
+		assert.ErrorContains(t, err, "qdisc 7: not found")
+		assert.Len(t, adapter.GetClasses(device).Value(), 2, "nothing is deleted")
+
+		_, err = controller.Delete(Objects{Filters: []string{"not-a-handle"}})
+		assert.ErrorContains(t, err, "invalid filter handle")
+	})
+
+	t.Run("reset_deletes_everything", func(t *testing.T) {
+		controller, adapter := shapedController(t)
+
+		deleted, err := controller.Reset()
+		require.NoError(t, err)
+		assert.Equal(t, "qdisc 1:", deleted[len(deleted)-1], "the root qdisc goes last")
+		assert.Empty(t, adapter.GetQdiscs(device).Value())
+		assert.Empty(t, adapter.GetClasses(device).Value())
+		assert.Empty(t, adapter.GetFilters(device).Value())
+
+		// The device can be shaped again afterwards
+		assert.NoError(t, controller.Apply())
+	})
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// LinkInfo describes a device's link: what kind of device it is and how it is stacked on
+// other devices
+type LinkInfo struct {
+	Device string `json:"device"`
+	Index  int    `json:"index"`
+	Kind   string `json:"kind"`              // Link type, such as "device", "vlan", "bridge" or "veth"
+	Parent string `json:"parent,omitempty"`  // The device a VLAN is stacked on
+	VLANID uint16 `json:"vlan_id,omitempty"` // The VLAN ID of a VLAN device
+	Master string `json:"master,omitempty"`  // The bridge or bond the device is a port of
+	Up     bool   `json:"up"`
+	// SpeedBPS is the negotiated link speed in bits per second; zero when not reported
+	SpeedBPS uint64 `json:"speed_bps,omitempty"`
+}
+
+// LinkInfo describes the controller's device
+func (controller *TrafficController) LinkInfo() (LinkInfo, error) {
+	link, err := controller.service.GetLinkInfo(controller.deviceName)
+	if err != nil {
+		return LinkInfo{}, err
+	}
+
+	return LinkInfo{
+		Device:   link.Name.String(),
+		Index:    link.Index,
+		Kind:     link.Kind,
+		Parent:   link.Parent.String(),
+		VLANID:   link.VLANID,
+		Master:   link.Master.String(),
+		Up:       link.Up,
+		SpeedBPS: link.Speed.BitsPerSecond(),
+	}, nil
+}
+
+// BridgePorts returns the devices enslaved to the controller's device, which must be a bridge
+func (controller *TrafficController) BridgePorts() ([]string, error) {
+	return controller.service.GetBridgePorts(controller.deviceName)
+}
+
+// PortGroup returns a group of the ports of the controller's bridge, so that a template
+// can be applied to each port. Shaping on the bridge device itself only sees traffic sent
+// by the host; traffic forwarded between ports leaves through the ports' qdiscs.
+func (controller *TrafficController) PortGroup() (*Group, error) {
+	ports, err := controller.BridgePorts()
+	if err != nil {
+		return nil, err
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("bridge %s has no ports", controller.deviceName)
+	}
+
+	group := NewGroup(ports...)
+	adapter := controller.service.NetlinkAdapter()
+	for _, port := range ports {
+		group.controllers[port].WithNetlinkAdapter(adapter)
+	}
+	return group, nil
+}
+
+// NewBridgeGroup returns a group of the ports of the bridge
+func NewBridgeGroup(bridge string) (*Group, error) {
+	return NetworkInterface(bridge).PortGroup()
+}
+
+// checkVLANDevice verifies that a device named as a VLAN sub-interface exists, so that a
+// missing VLAN or parent device is reported before anything is changed
+func (controller *TrafficController) checkVLANDevice() error {
+	device, err := tc.NewDeviceName(controller.deviceName)
+	if err != nil {
+		return err
+	}
+	if _, _, ok := device.VLAN(); !ok {
+		return nil
+	}
+	return controller.service.CheckDevice(controller.deviceName)
+}
+
+// checkLinkSpeed verifies that the total bandwidth does not exceed the speed of the device's
+// link: HTB would then never hold traffic back, as the link saturates first. The guaranteed
+// rates are covered, as validation keeps their sum within the total. Devices reporting no
+// speed, such as most virtual devices, are not checked.
+func (controller *TrafficController) checkLinkSpeed() error {
+	if controller.ignoreLinkSpeed {
+		return nil
+	}
+	link, err := controller.service.GetLinkInfo(controller.deviceName)
+	if err != nil || link.Speed.BitsPerSecond() == 0 {
+		return nil
+	}
+
+	if controller.totalBandwidth.GreaterThan(link.Speed) {
+		return tcerrors.New(tcerrors.CodeBandwidthExceeded, "device "+controller.deviceName,
+			"total bandwidth (%s) exceeds the link speed of %s (%s)",
+			controller.totalBandwidth, controller.deviceName, link.Speed,
+		).WithHint("lower the total bandwidth to the link speed, or use WithIgnoreLinkSpeed if the driver reports a wrong speed")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+func newVLANTestController(device string) (*TrafficController, *netlink.MockAdapter) {
+	controller := NetworkInterface(device)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("video").
+		WithGuaranteedBandwidth("10mbps").
+		WithSoftLimitBandwidth("20mbps").
+		WithPriority(1)
+
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.WithNetlinkAdapter(mockNetlinkAdapter)
+	return controller, mockNetlinkAdapter
+}
+
+func TestLinkInfo_VLAN(t *testing.T) {
+	controller, mockNetlinkAdapter := newVLANTestController("eth0.100")
+	mockNetlinkAdapter.AddVLAN(tc.MustNewDeviceName("eth0.100"), tc.MustNewDeviceName("eth0"), 100)
+
+	link, err := controller.LinkInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "vlan", link.Kind)
+	assert.Equal(t, "eth0", link.Parent)
+	assert.Equal(t, uint16(100), link.VLANID)
+	assert.Equal(t, "", link.Master)
+	assert.True(t, link.Up)
+
+	require.NoError(t, controller.Apply())
+}
+
+func TestApply_VLANWithMissingParent(t *testing.T) {
+	controller, mockNetlinkAdapter := newVLANTestController("eth1.200")
+	mockNetlinkAdapter.RemoveLink(tc.MustNewDeviceName("eth1"))
+	mockNetlinkAdapter.RemoveLink(tc.MustNewDeviceName("eth1.200"))
+
+	err := controller.Apply()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "parent device eth1 of VLAN eth1.200 does not exist")
+	assert.ErrorIs(t, err, tcerrors.ErrDeviceNotFound)
+
+	qdiscs := mockNetlinkAdapter.GetQdiscs(tc.MustNewDeviceName("eth1.200"))
+	require.True(t, qdiscs.IsSuccess())
+	assert.Empty(t, qdiscs.Value(), "nothing is changed")
+}
+
+func TestApply_VLANMissingOnParent(t *testing.T) {
+	controller, mockNetlinkAdapter := newVLANTestController("eth1.200")
+	mockNetlinkAdapter.RemoveLink(tc.MustNewDeviceName("eth1.200"))
+
+	err := controller.Apply()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "VLAN 200 is not configured on eth1")
+}
+
+func TestPortGroup(t *testing.T) {
+	controller, mockNetlinkAdapter := newVLANTestController("br0")
+	mockNetlinkAdapter.AddBridge(tc.MustNewDeviceName("br0"),
+		tc.MustNewDeviceName("veth1"), tc.MustNewDeviceName("eth0.100"))
+
+	ports, err := controller.BridgePorts()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"veth1", "eth0.100"}, ports)
+
+	group, err := controller.PortGroup()
+	require.NoError(t, err)
+	group.WithTemplate(func(port *TrafficController) {
+		port.WithHardLimitBandwidth("50mbps")
+		port.CreateTrafficClass("bulk").
+			WithGuaranteedBandwidth("5mbps").
+			WithPriority(6)
+	})
+	require.NoError(t, group.Apply())
+
+	for _, port := range ports {
+		classes := mockNetlinkAdapter.GetClasses(tc.MustNewDeviceName(port))
+		require.True(t, classes.IsSuccess())
+		assert.NotEmpty(t, classes.Value(), "classes on %s", port)
+	}
+
+	link, err := group.Controller("veth1").LinkInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "br0", link.Master)
+
+	// Removing the bridge releases its ports
+	mockNetlinkAdapter.RemoveLink(tc.MustNewDeviceName("br0"))
+	_, err = controller.BridgePorts()
+	assert.Error(t, err)
+	link, err = group.Controller("veth1").LinkInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "", link.Master)
+}
+
+func TestPortGroup_NotABridge(t *testing.T) {
+	controller, _ := newVLANTestController("eth0")
+
+	_, err := controller.PortGroup()
+	assert.ErrorContains(t, err, "not a bridge")
+}
+
+func TestApply_TotalAboveLinkSpeed(t *testing.T) {
+	controller, mockNetlinkAdapter := newVLANTestController("eth0")
+	controller.WithHardLimitBandwidth("10gbps")
+	mockNetlinkAdapter.SetLinkSpeed(tc.MustNewDeviceName("eth0"), tc.Gbps(1))
+
+	link, err := controller.LinkInfo()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000000000), link.SpeedBPS)
+
+	err = controller.Apply()
+	require.ErrorIs(t, err, tcerrors.ErrBandwidthExceeded)
+	assert.Contains(t, err.Error(), "exceeds the link speed of eth0 (1.0Gbps)")
+	qdiscs := mockNetlinkAdapter.GetQdiscs(tc.MustNewDeviceName("eth0"))
+	require.True(t, qdiscs.IsSuccess())
+	assert.Empty(t, qdiscs.Value(), "nothing is changed")
+
+	require.NoError(t, controller.WithIgnoreLinkSpeed().Apply())
+
+	// Within the link speed, and on devices reporting no speed, the total is accepted
+	controller, mockNetlinkAdapter = newVLANTestController("eth1")
+	mockNetlinkAdapter.SetLinkSpeed(tc.MustNewDeviceName("eth1"), tc.Gbps(1))
+	require.NoError(t, controller.Apply())
+	controller, _ = newVLANTestController("veth0")
+	controller.WithHardLimitBandwidth("10gbps")
+	require.NoError(t, controller.Apply())
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+)
+
+// DryRun validates and plans the configuration like Apply, but instead of changing the
+// device returns the tc commands Apply would run, in order. Objects already installed with
+// the desired definition are left out, so an empty result means Apply would change nothing.
+// The device's current state is read to compute the difference.
+func (controller *TrafficController) DryRun() ([]string, error) {
+	controller.finalizePendingClasses()
+
+	if err := controller.validate(); err != nil {
+		return nil, err
+	}
+
+	ctx := netlink.WithOperationTimeout(controller.ownershipContext(context.Background()), controller.operationTimeout)
+	desired, err := controller.plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var commands []string
+	if controller.ingressDevice != "" {
+		commands, err = controller.service.IngressRedirectCommands(controller.ingressDevice, controller.deviceName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	changes, err := controller.service.DryRun(ctx, controller.deviceName, desired)
+	if err != nil {
+		return nil, err
+	}
+	commands = append(commands, changes...)
+
+	attachments, err := controller.service.ClsactCommands(controller.deviceName, controller.pendingBPFClassifiers(), controller.pendingHookFilters())
+	if err != nil {
+		return nil, err
+	}
+	return append(commands, attachments...), nil
+}
+
+// BatchScript returns the commands of DryRun as a script for `tc -batch`, which runs them
+// in one process and stops at the first that fails. The commands creating the IFB device
+// of an ingress redirect are not tc commands; they are listed at the top, to be run first.
+func (controller *TrafficController) BatchScript() (string, error) {
+	commands, err := controller.DryRun()
+	if err != nil {
+		return "", err
+	}
+
+	var prelude, batch []string
+	for _, command := range commands {
+		for _, line := range strings.Split(command, " && ") {
+			if args, ok := strings.CutPrefix(line, "tc "); ok {
+				batch = append(batch, args)
+			} else {
+				prelude = append(prelude, "# Run first: "+line)
+			}
+		}
+	}
+
+	lines := append(prelude, batch...)
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestTrafficController_DryRun(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithSoftLimitBandwidth("60mbps").
+		WithPriority(1).
+		ForPort(80)
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+	commands, err := controller.DryRun()
+	require.NoError(t, err)
+	require.Len(t, commands, 4)
+	assert.Equal(t, "tc qdisc add dev eth0 root handle 1: htb default 999 r2q 10", commands[0])
+	assert.Equal(t, "tc class add dev eth0 parent 1: classid 1:11 htb rate 30000000bit ceil 60000000bit prio 0 burst 240640 cburst 481280 quantum 3750", commands[1])
+	assert.Equal(t, "tc filter add dev eth0 parent 1: protocol ip prio 100 u32 match ip dport 80 0xffff flowid 1:11", commands[2])
+	assert.Equal(t, "tc class add dev eth0 parent 1: classid 1:999 htb rate 1000000bit ceil 100000000bit prio 0 burst 8000 cburst 800000 quantum 1000", commands[3])
+
+	// Nothing reaches the device or the event store
+	device := tc.MustNewDeviceName("eth0")
+	assert.Empty(t, mockNetlinkAdapter.GetQdiscs(device).Value())
+	history, err := controller.service.GetDeviceHistory(context.Background(), "eth0")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	// Once applied, the configuration needs no more commands
+	require.NoError(t, controller.Apply())
+	commands, err = controller.DryRun()
+	require.NoError(t, err)
+	assert.Empty(t, commands)
+
+	// A changed class is changed in place, leaving its filter alone
+	controller.classes[0].guaranteedBandwidth = tc.MustParseBandwidth("40mbps")
+	commands, err = controller.DryRun()
+	require.NoError(t, err)
+	require.Len(t, commands, 1)
+	assert.Equal(t, "tc class change dev eth0 parent 1: classid 1:11 htb rate 40000000bit ceil 60000000bit prio 0 burst 320852 cburst 481280 quantum 5000", commands[0])
+}
+
+func TestControlServer_DryRun(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+	server := controller.NewControlServer().WithDryRun()
+
+	response := server.handle(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"apply","params":{"version":"1.0","bandwidth":"100Mbps","classes":[{"name":"web","guaranteed":"30Mbps","priority":1}]}}`))
+	encoded, err := json.Marshal(response)
+	require.NoError(t, err)
+
+	var decoded struct {
+		Result ControlDryRun `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.False(t, decoded.Result.Applied)
+	assert.NotEmpty(t, decoded.Result.Commands)
+	assert.Empty(t, mockNetlinkAdapter.GetQdiscs(tc.MustNewDeviceName("eth0")).Value())
+	assert.Empty(t, controller.classes)
+}
+
+func TestTrafficController_BatchScript(t *testing.T) {
+	controller := NetworkInterface("eth0").ShapeIngressVia("ifb0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("downloads").
+		WithGuaranteedBandwidth("20mbps").
+		WithSoftLimitBandwidth("50mbps").
+		WithPriority(4).
+		ForPort(443)
+	controller.WithNetlinkAdapter(netlink.NewMockAdapter())
+
+	script, err := controller.BatchScript()
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(script, "\n"), "\n")
+	require.Greater(t, len(lines), 4)
+	assert.Equal(t, "# Run first: ip link add ifb0 type ifb", lines[0])
+	assert.Equal(t, "# Run first: ip link set dev ifb0 up", lines[1])
+	assert.Equal(t, "qdisc add dev eth0 clsact", lines[2])
+	for _, line := range lines[2:] {
+		assert.False(t, strings.HasPrefix(line, "tc "), line)
+		assert.NotContains(t, line, "&&")
+	}
+	assert.Contains(t, lines, "qdisc add dev ifb0 root handle 1: htb default 999 r2q 10")
+}
+
+func TestTrafficController_WithExecBackend(t *testing.T) {
+	controller := NetworkInterface("eth0").WithExecBackend("/usr/sbin/tc")
+	_, ok := controller.service.NetlinkAdapter().(*netlink.ExecAdapter)
+	assert.True(t, ok)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// CreateDRRQdisc creates a root DRR (Deficit Round Robin) qdisc with fluent interface. Each
+// round, every backlogged class may send up to its quantum of bytes, so classes share the
+// link in proportion to their quanta. Packets reach a class through filters or a socket
+// priority equal to the class handle; the qdisc drops packets that match no class.
+func (controller *TrafficController) CreateDRRQdisc(handle string) *DRRQdiscBuilder {
+	return &DRRQdiscBuilder{
+		controller: controller,
+		handle:     handle,
+	}
+}
+
+// DRRQdiscBuilder provides fluent interface for DRR qdiscs
+type DRRQdiscBuilder struct {
+	controller *TrafficController
+	handle     string
+	classes    []*DRRClassBuilder
+}
+
+// DRRClassBuilder configures a class of a DRR qdisc
+type DRRClassBuilder struct {
+	handle  string
+	name    string
+	quantum uint32
+}
+
+// AddClass adds a class to the qdisc; its handle shares the qdisc's major number
+func (b *DRRQdiscBuilder) AddClass(handle, name string) *DRRClassBuilder {
+	class := &DRRClassBuilder{handle: handle, name: name}
+	b.classes = append(b.classes, class)
+	return class
+}
+
+// WithQuantum sets the bytes the class may send per round; by default it is the device MTU
+func (c *DRRClassBuilder) WithQuantum(bytes uint32) *DRRClassBuilder {
+	c.quantum = bytes
+	return c
+}
+
+func (b *DRRQdiscBuilder) Apply() error {
+	return b.controller.inTransaction(b.controller.actorContext(context.Background()), func(ctx context.Context) error {
+		service := b.controller.service
+		if err := service.CreateDRRQdisc(ctx, b.controller.deviceName, "", b.handle); err != nil {
+			return err
+		}
+
+		for _, class := range b.classes {
+			if err := service.CreateDRRClass(ctx, b.controller.deviceName, b.handle, class.handle, class.name, class.quantum); err != nil {
+				return fmt.Errorf("failed to create DRR class %s: %w", class.name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// CreateQFQQdisc creates a root QFQ (Quick Fair Queueing) qdisc with fluent interface.
+// Classes share the link in proportion to their weights, with tighter delay guarantees
+// than DRR at a small cost per packet. As with DRR, packets reach a class through filters
+// or a socket priority equal to the class handle, and unclassified packets are dropped.
+func (controller *TrafficController) CreateQFQQdisc(handle string) *QFQQdiscBuilder {
+	return &QFQQdiscBuilder{
+		controller: controller,
+		handle:     handle,
+	}
+}
+
+// QFQQdiscBuilder provides fluent interface for QFQ qdiscs
+type QFQQdiscBuilder struct {
+	controller *TrafficController
+	handle     string
+	classes    []*QFQClassBuilder
+}
+
+// QFQClassBuilder configures a class of a QFQ qdisc
+type QFQClassBuilder struct {
+	handle string
+	name   string
+	weight uint32
+	lmax   uint32
+}
+
+// AddClass adds a class to the qdisc; its handle shares the qdisc's major number
+func (b *QFQQdiscBuilder) AddClass(handle, name string) *QFQClassBuilder {
+	class := &QFQClassBuilder{handle: handle, name: name}
+	b.classes = append(b.classes, class)
+	return class
+}
+
+// WithWeight sets the class's share of the link relative to its siblings, from 1 to 1024;
+// by default it is 1. The weights of a qdisc's classes may sum to at most 65536.
+func (c *QFQClassBuilder) WithWeight(weight uint32) *QFQClassBuilder {
+	c.weight = weight
+	return c
+}
+
+// WithMaxPacket sets the largest packet the class sends, from 512 to 65536 bytes; by
+// default it is the device MTU. Raise it for devices with segmentation offload.
+func (c *QFQClassBuilder) WithMaxPacket(bytes uint32) *QFQClassBuilder {
+	c.lmax = bytes
+	return c
+}
+
+func (b *QFQQdiscBuilder) Apply() error {
+	return b.controller.inTransaction(b.controller.actorContext(context.Background()), func(ctx context.Context) error {
+		service := b.controller.service
+		if err := service.CreateQFQQdisc(ctx, b.controller.deviceName, "", b.handle); err != nil {
+			return err
+		}
+
+		for _, class := range b.classes {
+			if err := service.CreateQFQClass(ctx, b.controller.deviceName, b.handle, class.handle, class.name, class.weight, class.lmax); err != nil {
+				return fmt.Errorf("failed to create QFQ class %s: %w", class.name, err)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestFairQueueingQdiscBuilders(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	device, _ := tc.NewDeviceName("eth0")
+
+	useMock := func() *netlink.MockAdapter {
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+		return mockNetlinkAdapter
+	}
+
+	classTypes := func(mockNetlinkAdapter *netlink.MockAdapter) map[string]entities.QdiscType {
+		types := make(map[string]entities.QdiscType)
+		for _, class := range mockNetlinkAdapter.GetClasses(device).Value() {
+			types[class.Handle.String()] = class.Type
+		}
+		return types
+	}
+
+	t.Run("applies_drr_classes", func(t *testing.T) {
+		mockNetlinkAdapter := useMock()
+
+		drr := controller.CreateDRRQdisc("1:0")
+		drr.AddClass("1:1", "bulk").WithQuantum(1500)
+		drr.AddClass("1:2", "video").WithQuantum(4500)
+		require.NoError(t, drr.Apply())
+
+		qdiscs := mockNetlinkAdapter.GetQdiscs(device).Value()
+		require.Len(t, qdiscs, 1)
+		assert.Equal(t, entities.QdiscTypeDRR, qdiscs[0].Type)
+		assert.Equal(t, map[string]entities.QdiscType{
+			"1:1": entities.QdiscTypeDRR,
+			"1:2": entities.QdiscTypeDRR,
+		}, classTypes(mockNetlinkAdapter))
+	})
+
+	t.Run("applies_qfq_classes", func(t *testing.T) {
+		mockNetlinkAdapter := useMock()
+
+		qfq := controller.CreateQFQQdisc("1:0")
+		qfq.AddClass("1:1", "bulk")
+		qfq.AddClass("1:2", "video").WithWeight(4).WithMaxPacket(9000)
+		require.NoError(t, qfq.Apply())
+
+		assert.Equal(t, map[string]entities.QdiscType{
+			"1:1": entities.QdiscTypeQFQ,
+			"1:2": entities.QdiscTypeQFQ,
+		}, classTypes(mockNetlinkAdapter))
+	})
+
+	t.Run("rolls_back_invalid_classes", func(t *testing.T) {
+		mockNetlinkAdapter := useMock()
+
+		qfq := controller.CreateQFQQdisc("1:0")
+		qfq.AddClass("1:1", "bulk").WithWeight(1)
+		qfq.AddClass("1:2", "video").WithWeight(2000)
+		err := qfq.Apply()
+		assert.ErrorContains(t, err, "QFQ weight")
+
+		assert.Empty(t, mockNetlinkAdapter.GetQdiscs(device).Value())
+		assert.Empty(t, mockNetlinkAdapter.GetClasses(device).Value())
+	})
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"github.com/rng999/traffic-control-go/internal/commands/models"
+)
+
+// FilterAction is an action run on the packets matched by a traffic class's filters. Actions
+// run in the order given to TrafficClassBuilder.WithActions; a redirect, drop or pass must
+// come last.
+type FilterAction struct {
+	spec models.FilterAction
+}
+
+// MarkPackets sets the firewall mark of matched packets, e.g. to select a policy routing table
+func MarkPackets(mark uint32) FilterAction {
+	return FilterAction{spec: models.FilterAction{Type: "mark", Mark: mark}}
+}
+
+// Police limits matched traffic to the rate with the given burst in bytes; packets above the
+// rate are dropped
+func Police(rate string, burst uint32) FilterAction {
+	return FilterAction{spec: models.FilterAction{Type: "police", Rate: rate, Burst: burst, Exceed: "drop"}}
+}
+
+// PoliceOrReclassify limits matched traffic to the rate with the given burst in bytes; packets
+// above the rate are left to lower priority filters, usually ending in the default class
+func PoliceOrReclassify(rate string, burst uint32) FilterAction {
+	return FilterAction{spec: models.FilterAction{Type: "police", Rate: rate, Burst: burst, Exceed: "reclassify"}}
+}
+
+// MirrorTo sends a copy of matched packets to the egress of another device
+func MirrorTo(device string) FilterAction {
+	return FilterAction{spec: models.FilterAction{Type: "mirror", Device: device}}
+}
+
+// RedirectTo hands matched packets to the egress of another device, e.g. a scrubbing
+// interface, instead of queueing them in the class. Redirected traffic is still reported
+// in the class statistics as "redirected_bytes" and "redirected_packets".
+func RedirectTo(device string) FilterAction {
+	return FilterAction{spec: models.FilterAction{Type: "redirect", Device: device}}
+}
+
+// Drop discards matched packets, e.g. to block traffic after mirroring it to a monitor
+func Drop() FilterAction {
+	return FilterAction{spec: models.FilterAction{Type: "drop"}}
+}
+
+// Pass ends the action chain; the packet is queued in the class without running later actions
+func Pass() FilterAction {
+	return FilterAction{spec: models.FilterAction{Type: "pass"}}
+}
+
+// SetPriority sets the priority of matched packets as a handle, e.g. "1:10"; prio and mqprio
+// qdiscs further down map it to a band
+func SetPriority(handle string) FilterAction {
+	return FilterAction{spec: models.FilterAction{Type: "priority", Handle: handle}}
+}
+
+// SetQueue sends matched packets to a transmit queue of a multiqueue device
+func SetQueue(queue uint16) FilterAction {
+	return FilterAction{spec: models.FilterAction{Type: "queue_mapping", Queue: queue}}
+}
+
+// RemarkDSCP rewrites the DSCP of matched IP packets, from 0 to 63, keeping their ECN bits
+func RemarkDSCP(dscp uint8) FilterAction {
+	return FilterAction{spec: models.FilterAction{Type: "dscp", DSCP: dscp}}
+}
+
+// filterActionSpecs returns the command form of a class's action chain
+func filterActionSpecs(actions []FilterAction) []models.FilterAction {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	specs := make([]models.FilterAction, 0, len(actions))
+	for _, action := range actions {
+		specs = append(specs, action.spec)
+	}
+	return specs
+}
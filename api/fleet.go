@@ -0,0 +1,342 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// Defaults of a RolloutPolicy
+const (
+	DefaultCanaryPercent = 10
+	DefaultMaxDropRate   = 0.05
+)
+
+// FleetHost is a host of a fleet's inventory
+type FleetHost struct {
+	Name      string  // Identifies the host in reports; the SSH destination unless SSH sets one
+	SSH       SSHHost // How the host is reached
+	Device    string  // Device to shape; the template's device when empty
+	Bandwidth string  // Total bandwidth of the device; the template's when empty
+}
+
+// RolloutPolicy stages a rollout. The canaries are configured first and the other hosts
+// in waves; after each stage the rollout waits for the soak time, then checks the health
+// of the stage's hosts. A host is degraded when its root qdisc dropped more than
+// MaxDropRate of its packets while soaking, or when HealthCheck fails.
+type RolloutPolicy struct {
+	CanaryPercent int           // Share of the hosts configured first, at least one; DefaultCanaryPercent when zero
+	WaveSize      int           // Hosts configured at once after the canaries; all remaining ones when zero
+	SoakTime      time.Duration // Wait after each stage before checking its hosts
+	MaxDropRate   float64       // Drop rate above which a host is degraded; DefaultMaxDropRate when zero
+	HealthCheck   func(host string, controller *TrafficController) error
+}
+
+// HostRolloutStatus is the outcome of a rollout on a host
+type HostRolloutStatus string
+
+// Outcomes of a rollout on a host
+const (
+	HostPending    HostRolloutStatus = "pending"     // Not reached before the rollout stopped
+	HostApplied    HostRolloutStatus = "applied"     // Configured and healthy
+	HostFailed     HostRolloutStatus = "failed"      // The configuration could not be applied
+	HostDegraded   HostRolloutStatus = "degraded"    // Configured, then failed its health check
+	HostRolledBack HostRolloutStatus = "rolled-back" // Configured, then restored when the rollout stopped
+)
+
+// HostRollout reports the rollout on a host
+type HostRollout struct {
+	Host     string
+	Stage    int // 0 for the canaries, then the waves from 1
+	Status   HostRolloutStatus
+	DropRate float64 // Share of packets dropped while soaking
+	Err      error   // Why the host failed or was degraded, or why restoring it failed
+}
+
+// RolloutReport reports a rollout, host by host in the order of the inventory
+type RolloutReport struct {
+	Hosts      []HostRollout
+	RolledBack bool // A stage failed, and every host configured so far was restored
+}
+
+// Fleet rolls a configuration out to many hosts, each reached over SSH, in stages, and
+// restores the hosts' previous configuration when a stage fails. It keeps the controller
+// of each host, so that the next rollout changes only what differs and a failed one can be
+// rolled back to the configuration of the last successful one. A fleet runs one rollout at
+// a time.
+type Fleet struct {
+	hosts       []FleetHost
+	controllers map[string]*TrafficController
+	applied     map[string]*TrafficControlConfig // Configuration of the last successful rollout, by host
+	logger      logging.Logger
+
+	connect func(host FleetHost, device string) *TrafficController
+	soak    func(ctx context.Context, d time.Duration) error
+}
+
+// NewFleet creates a fleet of the hosts of an inventory, which must have distinct names
+func NewFleet(hosts []FleetHost) (*Fleet, error) {
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("fleet has no hosts")
+	}
+	seen := make(map[string]bool, len(hosts))
+	for i := range hosts {
+		if hosts[i].Name == "" {
+			hosts[i].Name = hosts[i].SSH.Destination
+		}
+		if hosts[i].Name == "" {
+			return nil, fmt.Errorf("fleet host %d has no name", i)
+		}
+		if seen[hosts[i].Name] {
+			return nil, fmt.Errorf("fleet host %q is listed twice", hosts[i].Name)
+		}
+		seen[hosts[i].Name] = true
+	}
+
+	return &Fleet{
+		hosts:       hosts,
+		controllers: make(map[string]*TrafficController),
+		applied:     make(map[string]*TrafficControlConfig),
+		logger:      logging.WithComponent(logging.ComponentAPI),
+		connect: func(host FleetHost, device string) *TrafficController {
+			if host.SSH.Destination == "" {
+				host.SSH.Destination = host.Name
+			}
+			return NetworkInterface(device).WithSSHBackend(host.SSH)
+		},
+		soak: sleepContext,
+	}, nil
+}
+
+// Rollout applies the template to the fleet's hosts, with each host's device and bandwidth,
+// in the stages of the policy. When a host of a stage fails to apply it or is degraded, the
+// rollout stops, every host configured by it is restored to the configuration of the last
+// successful rollout, or cleared if it had none, and an error is returned with the report.
+func (f *Fleet) Rollout(ctx context.Context, template *TrafficControlConfig, policy RolloutPolicy) (*RolloutReport, error) {
+	if err := template.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	policy = policy.withDefaults()
+
+	report := &RolloutReport{Hosts: make([]HostRollout, len(f.hosts))}
+	stages := policy.stages(len(f.hosts))
+	for stage, hosts := range stages {
+		for _, i := range hosts {
+			report.Hosts[i] = HostRollout{Host: f.hosts[i].Name, Stage: stage, Status: HostPending}
+		}
+	}
+
+	var configured []int
+	for stage, hosts := range stages {
+		f.logger.Info("Rolling out stage",
+			logging.Int("stage", stage),
+			logging.Int("hosts", len(hosts)),
+		)
+		configured = append(configured, hosts...)
+		err := f.runStage(ctx, template, policy, hosts, report)
+		if err != nil {
+			f.rollback(configured, report)
+			return report, fmt.Errorf("rollout stopped at stage %d and rolled back: %w", stage, err)
+		}
+	}
+
+	for _, i := range configured {
+		f.applied[f.hosts[i].Name] = f.hostConfig(template, f.hosts[i])
+	}
+	return report, nil
+}
+
+// runStage configures the hosts of a stage at once, waits for the soak time and checks
+// their health
+func (f *Fleet) runStage(ctx context.Context, template *TrafficControlConfig, policy RolloutPolicy, hosts []int, report *RolloutReport) error {
+	configs := make([]*TrafficControlConfig, len(hosts))
+	controllers := make([]*TrafficController, len(hosts))
+	for n, i := range hosts {
+		configs[n] = f.hostConfig(template, f.hosts[i])
+		controllers[n] = f.controller(f.hosts[i], configs[n].Device)
+	}
+
+	baselines := make([]dropCounters, len(hosts))
+	f.eachHost(hosts, func(n, i int) {
+		host := &report.Hosts[i]
+		config, controller := configs[n], controllers[n]
+		if err := controller.ReplaceConfig(config); err != nil {
+			host.Status, host.Err = HostFailed, err
+			return
+		}
+		host.Status = HostApplied
+		baselines[n] = rootDropCounters(controller)
+	})
+	if err := stageError(hosts, report); err != nil {
+		return err
+	}
+
+	if err := f.soak(ctx, policy.SoakTime); err != nil {
+		return err
+	}
+
+	f.eachHost(hosts, func(n, i int) {
+		host, controller := &report.Hosts[i], controllers[n]
+		host.DropRate = rootDropCounters(controller).rateSince(baselines[n])
+		switch {
+		case host.DropRate > policy.MaxDropRate:
+			host.Status = HostDegraded
+			host.Err = fmt.Errorf("dropped %.1f%% of packets, above %.1f%%", host.DropRate*100, policy.MaxDropRate*100)
+		case policy.HealthCheck != nil:
+			if err := policy.HealthCheck(host.Host, controller); err != nil {
+				host.Status, host.Err = HostDegraded, err
+			}
+		}
+	})
+	return stageError(hosts, report)
+}
+
+// rollback restores the configured hosts to the configuration of the last successful
+// rollout, or clears those that had none. Hosts that failed to apply the configuration were
+// restored by Apply.
+func (f *Fleet) rollback(configured []int, report *RolloutReport) {
+	report.RolledBack = true
+	f.eachHost(configured, func(_, i int) {
+		host := &report.Hosts[i]
+		controller := f.controllers[host.Host]
+		if host.Status == HostFailed {
+			return
+		}
+
+		var err error
+		if previous := f.applied[host.Host]; previous != nil {
+			err = controller.ReplaceConfig(previous)
+		} else {
+			_, err = controller.Reset()
+		}
+		if err != nil {
+			host.Err = errors.Join(host.Err, fmt.Errorf("failed to roll back: %w", err))
+			f.logger.Error("Failed to roll back host", logging.String("host", host.Host), logging.Error(err))
+			return
+		}
+		if host.Status == HostApplied {
+			host.Status = HostRolledBack
+		}
+	})
+}
+
+// eachHost runs fn for the hosts at once, with the position of each in hosts and in the
+// inventory
+func (f *Fleet) eachHost(hosts []int, fn func(n, i int)) {
+	var wg sync.WaitGroup
+	for n, i := range hosts {
+		wg.Add(1)
+		go func(n, i int) {
+			defer wg.Done()
+			fn(n, i)
+		}(n, i)
+	}
+	wg.Wait()
+}
+
+// controller returns the controller of a host, created on first use
+func (f *Fleet) controller(host FleetHost, device string) *TrafficController {
+	controller, ok := f.controllers[host.Name]
+	if !ok {
+		controller = f.connect(host, device)
+		f.controllers[host.Name] = controller
+	}
+	return controller
+}
+
+// hostConfig returns the template with the host's device and bandwidth
+func (f *Fleet) hostConfig(template *TrafficControlConfig, host FleetHost) *TrafficControlConfig {
+	config := *template
+	if host.Device != "" {
+		config.Device = host.Device
+	}
+	if host.Bandwidth != "" {
+		config.Bandwidth = host.Bandwidth
+	}
+	return &config
+}
+
+// withDefaults fills in the unset fields of the policy
+func (p RolloutPolicy) withDefaults() RolloutPolicy {
+	if p.CanaryPercent <= 0 {
+		p.CanaryPercent = DefaultCanaryPercent
+	}
+	if p.MaxDropRate <= 0 {
+		p.MaxDropRate = DefaultMaxDropRate
+	}
+	return p
+}
+
+// stages splits the inventory, by position, into the canaries and the waves
+func (p RolloutPolicy) stages(hosts int) [][]int {
+	canaries := max(1, min(hosts, (hosts*p.CanaryPercent+99)/100))
+	stages := [][]int{positions(0, canaries)}
+
+	wave := p.WaveSize
+	if wave <= 0 {
+		wave = hosts
+	}
+	for start := canaries; start < hosts; start += wave {
+		stages = append(stages, positions(start, min(hosts, start+wave)))
+	}
+	return stages
+}
+
+func positions(start, end int) []int {
+	positions := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		positions = append(positions, i)
+	}
+	return positions
+}
+
+// stageError returns an error naming the hosts of the stage that failed or were degraded
+func stageError(hosts []int, report *RolloutReport) error {
+	var errs []error
+	for _, i := range hosts {
+		if host := report.Hosts[i]; host.Status == HostFailed || host.Status == HostDegraded {
+			errs = append(errs, fmt.Errorf("host %s %s: %w", host.Host, host.Status, host.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dropCounters are the packet counters of a root qdisc
+type dropCounters struct {
+	sent, dropped uint64
+}
+
+// rootDropCounters reads the counters of the controller's root qdisc; a host whose
+// statistics cannot be read counts no traffic
+func rootDropCounters(controller *TrafficController) dropCounters {
+	stats, err := controller.GetQdiscStatistics(rootQdiscHandle.String())
+	if err != nil {
+		return dropCounters{}
+	}
+	return dropCounters{sent: stats.PacketsSent, dropped: stats.PacketsDropped}
+}
+
+// rateSince returns the share of the packets since the baseline that were dropped
+func (c dropCounters) rateSince(baseline dropCounters) float64 {
+	sent, dropped := counterDelta(c.sent, baseline.sent), counterDelta(c.dropped, baseline.dropped)
+	if sent+dropped == 0 {
+		return 0
+	}
+	return float64(dropped) / float64(sent+dropped)
+}
+
+// sleepContext waits for the duration or until the context is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// newTestFleet returns a fleet of hosts changing mock adapters instead of remote hosts, and
+// a soak that lets the test change the hosts' counters while the stage soaks
+func newTestFleet(t *testing.T, names ...string) (*Fleet, map[string]*netlink.MockAdapter, *func(stage int)) {
+	var hosts []FleetHost
+	for _, name := range names {
+		hosts = append(hosts, FleetHost{Name: name})
+	}
+	fleet, err := NewFleet(hosts)
+	require.NoError(t, err)
+
+	adapters := make(map[string]*netlink.MockAdapter)
+	fleet.connect = func(host FleetHost, device string) *TrafficController {
+		adapters[host.Name] = netlink.NewMockAdapter()
+		return NetworkInterface(device).WithNetlinkAdapter(adapters[host.Name])
+	}
+	duringSoak := func(int) {}
+	stage := 0
+	fleet.soak = func(ctx context.Context, d time.Duration) error {
+		duringSoak(stage)
+		stage++
+		return nil
+	}
+	return fleet, adapters, &duringSoak
+}
+
+func fleetTemplate(t *testing.T, guaranteed string) *TrafficControlConfig {
+	config, err := ParseConfigFromYAML([]byte(fmt.Sprintf(`
+device: eth0
+bandwidth: 100mbps
+classes:
+  - name: web
+    guaranteed: %s
+    priority: 1
+`, guaranteed)))
+	require.NoError(t, err)
+	return config
+}
+
+func TestRolloutPolicy_Stages(t *testing.T) {
+	assert.Equal(t, [][]int{{0}, {1, 2, 3, 4}}, RolloutPolicy{}.withDefaults().stages(5))
+	assert.Equal(t, [][]int{{0, 1}, {2, 3}, {4}}, RolloutPolicy{CanaryPercent: 25, WaveSize: 2}.stages(5))
+	assert.Equal(t, [][]int{{0}}, RolloutPolicy{CanaryPercent: 100}.stages(1))
+}
+
+func TestFleet_Rollout(t *testing.T) {
+	fleet, adapters, _ := newTestFleet(t, "edge1", "edge2", "edge3")
+	fleet.hosts[2].Device, fleet.hosts[2].Bandwidth = "eth1", "50mbps"
+
+	report, err := fleet.Rollout(context.Background(), fleetTemplate(t, "10mbps"), RolloutPolicy{CanaryPercent: 33, WaveSize: 1})
+	require.NoError(t, err)
+	assert.False(t, report.RolledBack)
+	for stage, host := range report.Hosts {
+		assert.Equal(t, HostApplied, host.Status, host.Host)
+		assert.Equal(t, stage, host.Stage)
+	}
+
+	assert.NotEmpty(t, adapters["edge1"].GetClasses(tc.MustNewDeviceName("eth0")).Value())
+	assert.NotEmpty(t, adapters["edge3"].GetClasses(tc.MustNewDeviceName("eth1")).Value())
+}
+
+func TestFleet_RolloutRollsBackDegradedCanary(t *testing.T) {
+	fleet, adapters, duringSoak := newTestFleet(t, "edge1", "edge2")
+	ctx := context.Background()
+	device := tc.MustNewDeviceName("eth0")
+
+	_, err := fleet.Rollout(ctx, fleetTemplate(t, "10mbps"), RolloutPolicy{})
+	require.NoError(t, err)
+
+	// The canary drops a fifth of its packets under the new configuration
+	*duringSoak = func(stage int) {
+		adapters["edge1"].SetQdiscStatistics(device, tc.NewHandle(1, 0), netlink.QdiscStats{PacketsSent: 800, PacketsDropped: 200})
+	}
+	report, err := fleet.Rollout(ctx, fleetTemplate(t, "20mbps"), RolloutPolicy{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "edge1 degraded")
+	assert.True(t, report.RolledBack)
+	assert.Equal(t, HostDegraded, report.Hosts[0].Status)
+	assert.InDelta(t, 0.2, report.Hosts[0].DropRate, 0.001)
+	assert.Equal(t, HostPending, report.Hosts[1].Status)
+
+	// The canary is back on the configuration of the first rollout
+	require.NotEmpty(t, adapters["edge1"].GetClasses(device).Value())
+	assert.Equal(t, tc.MustParseBandwidth("10mbps"), fleet.controllers["edge1"].classByName("web").guaranteedBandwidth)
+}
+
+func TestFleet_RolloutHealthCheck(t *testing.T) {
+	fleet, adapters, _ := newTestFleet(t, "edge1", "edge2", "edge3")
+	device := tc.MustNewDeviceName("eth0")
+
+	policy := RolloutPolicy{
+		CanaryPercent: 1,
+		HealthCheck: func(host string, controller *TrafficController) error {
+			if host == "edge3" {
+				return fmt.Errorf("probe failed")
+			}
+			return nil
+		},
+	}
+	report, err := fleet.Rollout(context.Background(), fleetTemplate(t, "10mbps"), policy)
+	require.Error(t, err)
+	assert.Equal(t, HostRolledBack, report.Hosts[0].Status)
+	assert.Equal(t, HostRolledBack, report.Hosts[1].Status)
+	assert.Equal(t, HostDegraded, report.Hosts[2].Status)
+
+	// Hosts without an earlier rollout are cleared
+	for _, name := range []string{"edge1", "edge2", "edge3"} {
+		assert.Empty(t, adapters[name].GetQdiscs(device).Value(), name)
+	}
+}
+
+func TestNewFleet_Inventory(t *testing.T) {
+	_, err := NewFleet(nil)
+	assert.Error(t, err)
+
+	_, err = NewFleet([]FleetHost{{SSH: SSHHost{Destination: "edge1"}}, {Name: "edge1"}})
+	assert.ErrorContains(t, err, "listed twice")
+
+	_, err = NewFleet([]FleetHost{{}})
+	assert.ErrorContains(t, err, "no name")
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+// FlowerMatch is a flower filter matching packets on which all of its conditions hold, e.g.
+// api.Flower().VLAN(100).DestinationMAC("02:00:00:00:00:01").Protocol("tcp").DestinationPort(443).
+// Flower parses the headers instead of reading fixed offsets, so it handles VLAN tags and
+// IPv6, and network cards and switches can offload it to hardware.
+type FlowerMatch struct {
+	match map[string]string
+	errs  []string
+}
+
+// Flower starts an empty flower match, which matches every packet
+func Flower() *FlowerMatch {
+	return &FlowerMatch{match: make(map[string]string)}
+}
+
+// SourceMAC matches the source Ethernet address
+func (m *FlowerMatch) SourceMAC(mac string) *FlowerMatch {
+	if _, err := entities.NewMACSourceMatch(mac); err != nil {
+		return m.fail("invalid source MAC %q", mac)
+	}
+	m.match["src_mac"] = mac
+	return m
+}
+
+// DestinationMAC matches the destination Ethernet address
+func (m *FlowerMatch) DestinationMAC(mac string) *FlowerMatch {
+	if _, err := entities.NewMACDestinationMatch(mac); err != nil {
+		return m.fail("invalid destination MAC %q", mac)
+	}
+	m.match["dst_mac"] = mac
+	return m
+}
+
+// VLAN matches 802.1Q tagged frames of the VLAN (0-4095)
+func (m *FlowerMatch) VLAN(id int) *FlowerMatch {
+	if id < 0 || id > 4095 {
+		return m.fail("invalid VLAN ID %d, must be between 0 and 4095", id)
+	}
+	m.match["vlan_id"] = fmt.Sprintf("%d", id)
+	return m
+}
+
+// VLANPriority matches 802.1Q tagged frames of the priority code point (0-7)
+func (m *FlowerMatch) VLANPriority(priority int) *FlowerMatch {
+	if priority < 0 || priority > 7 {
+		return m.fail("invalid VLAN priority %d, must be between 0 and 7", priority)
+	}
+	m.match["vlan_prio"] = fmt.Sprintf("%d", priority)
+	return m
+}
+
+// FromSource matches the source IPv4 or IPv6 address or CIDR
+func (m *FlowerMatch) FromSource(cidr string) *FlowerMatch {
+	if _, err := entities.NewIPSourceMatch(cidr); err != nil {
+		return m.fail("invalid source %q", cidr)
+	}
+	m.match["src_ip"] = cidr
+	return m
+}
+
+// ToDestination matches the destination IPv4 or IPv6 address or CIDR
+func (m *FlowerMatch) ToDestination(cidr string) *FlowerMatch {
+	if _, err := entities.NewIPDestinationMatch(cidr); err != nil {
+		return m.fail("invalid destination %q", cidr)
+	}
+	m.match["dst_ip"] = cidr
+	return m
+}
+
+// Protocol matches the IP protocol: "tcp", "udp", "sctp", "icmp" or a protocol number.
+// Port matches require tcp, udp or sctp.
+func (m *FlowerMatch) Protocol(protocol string) *FlowerMatch {
+	m.match["protocol"] = protocol
+	return m
+}
+
+// SourcePort matches the source port
+func (m *FlowerMatch) SourcePort(port int) *FlowerMatch {
+	if port < 1 || port > 65535 {
+		return m.fail("invalid source port %d", port)
+	}
+	m.match["src_port"] = fmt.Sprintf("%d", port)
+	return m
+}
+
+// DestinationPort matches the destination port
+func (m *FlowerMatch) DestinationPort(port int) *FlowerMatch {
+	if port < 1 || port > 65535 {
+		return m.fail("invalid destination port %d", port)
+	}
+	m.match["dst_port"] = fmt.Sprintf("%d", port)
+	return m
+}
+
+// SourcePortRange matches source ports from min to max, inclusive
+func (m *FlowerMatch) SourcePortRange(min, max int) *FlowerMatch {
+	if min < 1 || max > 65535 || min > max {
+		return m.fail("invalid source port range %d-%d", min, max)
+	}
+	m.match["src_port_range"] = fmt.Sprintf("%d-%d", min, max)
+	return m
+}
+
+// DestinationPortRange matches destination ports from min to max, inclusive
+func (m *FlowerMatch) DestinationPortRange(min, max int) *FlowerMatch {
+	if min < 1 || max > 65535 || min > max {
+		return m.fail("invalid destination port range %d-%d", min, max)
+	}
+	m.match["dst_port_range"] = fmt.Sprintf("%d-%d", min, max)
+	return m
+}
+
+// fail records an invalid condition, reported when the configuration is validated
+func (m *FlowerMatch) fail(format string, args ...interface{}) *FlowerMatch {
+	m.errs = append(m.errs, fmt.Sprintf(format, args...))
+	return m
+}
+
+// err returns the invalid conditions of the match, if any
+func (m *FlowerMatch) err() error {
+	if m == nil || len(m.match) == 0 && len(m.errs) == 0 {
+		return fmt.Errorf("no conditions")
+	}
+	if len(m.errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(m.errs, "; "))
+	}
+
+	hasPorts := false
+	for _, key := range []string{"src_port", "dst_port", "src_port_range", "dst_port_range"} {
+		if _, ok := m.match[key]; ok {
+			hasPorts = true
+		}
+	}
+	if hasPorts {
+		switch strings.ToLower(m.match["protocol"]) {
+		case "tcp", "udp", "sctp", "6", "17", "132":
+		default:
+			return fmt.Errorf("port matches require Protocol(\"tcp\"), Protocol(\"udp\") or Protocol(\"sctp\")")
+		}
+	}
+
+	if source, ok := m.match["src_ip"]; ok {
+		if destination, ok := m.match["dst_ip"]; ok && isIPv4(source) != isIPv4(destination) {
+			return fmt.Errorf("source and destination mix IPv4 and IPv6")
+		}
+	}
+	return nil
+}
+
+// matches returns the filter match installing the flower match
+func (m *FlowerMatch) matches() map[string]string {
+	match := make(map[string]string, len(m.match))
+	for key, value := range m.match {
+		match[key] = value
+	}
+	return match
+}
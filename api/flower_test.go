@@ -0,0 +1,80 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestTrafficClassBuilder_ForFlower(t *testing.T) {
+	controller := NetworkInterface("br0")
+	controller.WithHardLimitBandwidth("1gbps")
+	controller.CreateTrafficClass("voice").
+		WithGuaranteedBandwidth("50mbps").
+		WithPriority(0).
+		ForFlower(Flower().VLAN(100).VLANPriority(5)).
+		ForFlower(Flower().SourceMAC("02:00:00:00:00:01").Protocol("udp").DestinationPortRange(16384, 32767))
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("100mbps").
+		WithPriority(2).
+		ForPort(443)
+
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+	require.NoError(t, controller.Apply())
+
+	byKind := make(map[entities.FilterKind][][]string)
+	for _, filter := range mockNetlinkAdapter.GetFilters(tc.MustNewDeviceName("br0")).Value() {
+		var matches []string
+		for _, match := range filter.Matches {
+			matches = append(matches, match.Value.(string))
+		}
+		byKind[filter.Kind] = append(byKind[filter.Kind], matches)
+	}
+
+	flower := byKind[entities.FilterKindFlower]
+	require.Len(t, flower, 2)
+	assert.ElementsMatch(t, []string{"vlan id 100", "vlan prio 5"}, flower[0])
+	assert.ElementsMatch(t, []string{"eth src 02:00:00:00:00:01", "ip protocol 17 0xff", "dport range 16384-32767"}, flower[1])
+
+	// Other filters keep using u32
+	require.Len(t, byKind[entities.FilterKindU32], 1)
+	assert.Equal(t, []string{"ip dport 443 0xffff"}, byKind[entities.FilterKindU32][0])
+}
+
+func TestTrafficClassBuilder_ForFlowerInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		match *FlowerMatch
+	}{
+		{"no_conditions", Flower()},
+		{"invalid_mac", Flower().DestinationMAC("02:00:00:00:00")},
+		{"vlan_out_of_range", Flower().VLAN(4096)},
+		{"vlan_priority_out_of_range", Flower().VLANPriority(8)},
+		{"port_without_protocol", Flower().DestinationPort(443)},
+		{"port_range_with_icmp", Flower().Protocol("icmp").SourcePortRange(1, 1024)},
+		{"mixed_address_families", Flower().FromSource("10.0.0.0/8").ToDestination("2001:db8::1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := NetworkInterface("br0")
+			controller.WithHardLimitBandwidth("100mbps")
+			controller.CreateTrafficClass("web").
+				WithGuaranteedBandwidth("10mbps").
+				WithPriority(1).
+				ForFlower(tt.match)
+
+			err := controller.Apply()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid flower match")
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+// Group applies one traffic control template to several devices, such as a set of VLAN
+// interfaces shaped alike. Each device gets its own controller, built by the template and
+// then by the device's overrides.
+type Group struct {
+	devices     []string
+	controllers map[string]*TrafficController
+	template    func(controller *TrafficController)
+	overrides   map[string][]func(controller *TrafficController)
+	built       bool
+}
+
+// NewGroup creates a group of traffic controllers for the devices
+func NewGroup(devices ...string) *Group {
+	group := &Group{
+		devices:     devices,
+		controllers: make(map[string]*TrafficController, len(devices)),
+		overrides:   make(map[string][]func(controller *TrafficController)),
+	}
+	for _, device := range devices {
+		group.controllers[device] = NetworkInterface(device)
+	}
+	return group
+}
+
+// WithTemplate sets the configuration built on every device's controller: the bandwidth,
+// classes and filters, as for a single controller
+func (g *Group) WithTemplate(template func(controller *TrafficController)) *Group {
+	g.template = template
+	return g
+}
+
+// Override adds configuration for one device, built after the template. A class created
+// with the name of a template class replaces it; the bandwidth set last wins.
+func (g *Group) Override(device string, override func(controller *TrafficController)) *Group {
+	g.overrides[device] = append(g.overrides[device], override)
+	return g
+}
+
+// Devices returns the group's devices, in the order given to NewGroup
+func (g *Group) Devices() []string {
+	return append([]string(nil), g.devices...)
+}
+
+// Controller returns the controller of a device in the group, with the template and its
+// overrides built, or nil for a device not in the group. It can be used for anything the
+// group does not do itself, such as watching the device.
+func (g *Group) Controller(device string) *TrafficController {
+	controller, ok := g.controllers[device]
+	if !ok {
+		return nil
+	}
+	g.build()
+	return controller
+}
+
+// build runs the template and overrides on every controller, once
+func (g *Group) build() {
+	if g.built {
+		return
+	}
+	g.built = true
+
+	for _, device := range g.devices {
+		controller := g.controllers[device]
+		if g.template != nil {
+			g.template(controller)
+		}
+		for _, override := range g.overrides[device] {
+			override(controller)
+		}
+		controller.finalizePendingClasses()
+		controller.classes = replaceOverriddenClasses(controller.classes)
+	}
+}
+
+// replaceOverriddenClasses keeps the last class defined with each name, in the place of
+// the first
+func replaceOverriddenClasses(classes []*TrafficClass) []*TrafficClass {
+	last := make(map[string]*TrafficClass, len(classes))
+	for _, class := range classes {
+		last[class.name] = class
+	}
+
+	result := make([]*TrafficClass, 0, len(last))
+	for _, class := range classes {
+		if replacement, ok := last[class.name]; ok {
+			result = append(result, replacement)
+			delete(last, class.name)
+		}
+	}
+	return result
+}
+
+// GroupError reports the devices of a group that failed, keyed by device
+type GroupError struct {
+	Errors map[string]error
+}
+
+// Error implements the error interface
+func (e *GroupError) Error() string {
+	devices := make([]string, 0, len(e.Errors))
+	for device := range e.Errors {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+
+	messages := make([]string, 0, len(devices))
+	for _, device := range devices {
+		messages = append(messages, fmt.Sprintf("%s: %v", device, e.Errors[device]))
+	}
+	return fmt.Sprintf("%d of the group's devices failed: %s", len(devices), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the devices' errors, for errors.Is and errors.As
+func (e *GroupError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Validate validates every device's configuration, returning the reports keyed by device
+func (g *Group) Validate() map[string]*ValidationReport {
+	g.build()
+
+	reports := make(map[string]*ValidationReport, len(g.devices))
+	for _, device := range g.devices {
+		reports[device] = g.controllers[device].Validate()
+	}
+	return reports
+}
+
+// Apply applies the configuration to every device. A device failing does not stop the
+// others; the failures are returned in a *GroupError.
+func (g *Group) Apply() error {
+	g.build()
+
+	failed := make(map[string]error)
+	for _, device := range g.devices {
+		if err := g.controllers[device].Apply(); err != nil {
+			failed[device] = err
+		}
+	}
+	if len(failed) > 0 {
+		return &GroupError{Errors: failed}
+	}
+	return nil
+}
+
+// GroupClassStatistics is a class's statistics summed over the group's devices
+type GroupClassStatistics struct {
+	Name           string `json:"name"`
+	Devices        int    `json:"devices"` // Devices the class was found on
+	BytesSent      uint64 `json:"bytes_sent"`
+	PacketsSent    uint64 `json:"packets_sent"`
+	PacketsDropped uint64 `json:"packets_dropped"`
+	Overlimits     uint64 `json:"overlimits"`
+	BacklogBytes   uint64 `json:"backlog_bytes"`
+	BacklogPackets uint64 `json:"backlog_packets"`
+	RateBPS        uint64 `json:"rate_bps"`
+}
+
+// GroupStatistics holds the statistics of every device of a group, and of each configured
+// class summed over the devices
+type GroupStatistics struct {
+	Devices map[string]*qmodels.DeviceStatisticsView `json:"devices"`
+	Classes []GroupClassStatistics                   `json:"classes"` // In the order first seen
+	Link    qmodels.LinkStatisticsView               `json:"link"`    // Summed over the devices
+}
+
+// GetStatistics returns the statistics of every device and their sums. Devices whose
+// statistics cannot be read are reported in a *GroupError alongside the rest.
+func (g *Group) GetStatistics() (*GroupStatistics, error) {
+	g.build()
+
+	stats := &GroupStatistics{
+		Devices: make(map[string]*qmodels.DeviceStatisticsView, len(g.devices)),
+		Classes: make([]GroupClassStatistics, 0),
+	}
+	classIndex := make(map[string]int)
+	failed := make(map[string]error)
+
+	for _, device := range g.devices {
+		controller := g.controllers[device]
+		view, err := controller.GetStatistics()
+		if err != nil {
+			failed[device] = err
+			continue
+		}
+		stats.Devices[device] = view
+
+		for _, class := range controller.classes {
+			classStats, err := controller.GetClassStatistics(classHandle(class))
+			if err != nil {
+				failed[device] = err
+				break
+			}
+			i, ok := classIndex[class.name]
+			if !ok {
+				i = len(stats.Classes)
+				classIndex[class.name] = i
+				stats.Classes = append(stats.Classes, GroupClassStatistics{Name: class.name})
+			}
+			sum := &stats.Classes[i]
+			sum.Devices++
+			sum.BytesSent += classStats.BytesSent
+			sum.PacketsSent += classStats.PacketsSent
+			sum.PacketsDropped += classStats.PacketsDropped
+			sum.Overlimits += classStats.Overlimits
+			sum.BacklogBytes += classStats.BacklogBytes
+			sum.BacklogPackets += classStats.BacklogPackets
+			sum.RateBPS += classStats.RateBPS
+		}
+
+		link := &stats.Link
+		link.RxBytes += view.LinkStats.RxBytes
+		link.TxBytes += view.LinkStats.TxBytes
+		link.RxPackets += view.LinkStats.RxPackets
+		link.TxPackets += view.LinkStats.TxPackets
+		link.RxErrors += view.LinkStats.RxErrors
+		link.TxErrors += view.LinkStats.TxErrors
+		link.RxDropped += view.LinkStats.RxDropped
+		link.TxDropped += view.LinkStats.TxDropped
+	}
+
+	if len(failed) > 0 {
+		return stats, &GroupError{Errors: failed}
+	}
+	return stats, nil
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func newTestGroup(devices ...string) (*Group, *netlink.MockAdapter) {
+	group := NewGroup(devices...).WithTemplate(func(controller *TrafficController) {
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("video").
+			WithGuaranteedBandwidth("10mbps").
+			WithSoftLimitBandwidth("20mbps").
+			WithPriority(1)
+		controller.CreateTrafficClass("bulk").
+			WithGuaranteedBandwidth("5mbps").
+			WithSoftLimitBandwidth("50mbps").
+			WithPriority(6)
+	})
+
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	for _, device := range devices {
+		group.controllers[device].WithNetlinkAdapter(mockNetlinkAdapter)
+	}
+	return group, mockNetlinkAdapter
+}
+
+func TestGroup_AppliesTemplateToEveryDevice(t *testing.T) {
+	group, mockNetlinkAdapter := newTestGroup("eth0", "eth1")
+	require.NoError(t, group.Apply())
+
+	for _, name := range []string{"eth0", "eth1"} {
+		device, _ := tc.NewDeviceName(name)
+		classes := mockNetlinkAdapter.GetClasses(device)
+		require.True(t, classes.IsSuccess())
+		assert.Len(t, classes.Value(), 3, "two template classes and the default class on %s", name)
+	}
+	assert.Equal(t, []string{"eth0", "eth1"}, group.Devices())
+	assert.Nil(t, group.Controller("eth2"))
+}
+
+func TestGroup_OverrideReplacesTemplateClass(t *testing.T) {
+	group, _ := newTestGroup("eth0", "eth1")
+	group.Override("eth1", func(controller *TrafficController) {
+		controller.CreateTrafficClass("video").
+			WithGuaranteedBandwidth("30mbps").
+			WithSoftLimitBandwidth("60mbps").
+			WithPriority(1)
+	})
+
+	eth0 := group.Controller("eth0")
+	eth1 := group.Controller("eth1")
+	require.Len(t, eth0.classes, 2)
+	require.Len(t, eth1.classes, 2)
+
+	assert.Equal(t, "video", eth1.classes[0].name)
+	assert.Equal(t, uint64(30_000_000), eth1.classes[0].guaranteedBandwidth.BitsPerSecond())
+	assert.Equal(t, uint64(10_000_000), eth0.classes[0].guaranteedBandwidth.BitsPerSecond())
+	assert.Equal(t, "bulk", eth1.classes[1].name)
+
+	require.NoError(t, group.Apply())
+}
+
+func TestGroup_ApplyReportsFailingDevices(t *testing.T) {
+	group, mockNetlinkAdapter := newTestGroup("eth0", "eth1")
+	group.Override("eth0", func(controller *TrafficController) {
+		controller.WithHardLimitBandwidth("10mbps")
+	})
+
+	err := group.Apply()
+	require.Error(t, err)
+
+	var groupErr *GroupError
+	require.True(t, errors.As(err, &groupErr))
+	assert.Contains(t, groupErr.Errors, "eth0")
+	assert.NotContains(t, groupErr.Errors, "eth1")
+	assert.Contains(t, err.Error(), "eth0:")
+
+	device, _ := tc.NewDeviceName("eth1")
+	classes := mockNetlinkAdapter.GetClasses(device)
+	require.True(t, classes.IsSuccess())
+	assert.NotEmpty(t, classes.Value(), "the other devices are still applied")
+}
+
+func TestGroup_AggregatesStatistics(t *testing.T) {
+	group, mockNetlinkAdapter := newTestGroup("eth0", "eth1")
+	require.NoError(t, group.Apply())
+
+	handle := tc.NewHandle(1, 0x11)
+	for i, name := range []string{"eth0", "eth1"} {
+		device, _ := tc.NewDeviceName(name)
+		mockNetlinkAdapter.SetClassStatistics(device, handle, netlink.ClassStats{
+			BytesSent:      uint64(1000 * (i + 1)),
+			PacketsSent:    uint64(10 * (i + 1)),
+			PacketsDropped: 5,
+		})
+	}
+
+	stats, err := group.GetStatistics()
+	require.NoError(t, err)
+	assert.Len(t, stats.Devices, 2)
+
+	var video *GroupClassStatistics
+	for i := range stats.Classes {
+		if stats.Classes[i].Name == "video" {
+			video = &stats.Classes[i]
+		}
+	}
+	require.NotNil(t, video, "classes: %+v", stats.Classes)
+	assert.Equal(t, 2, video.Devices)
+	assert.Equal(t, uint64(3000), video.BytesSent)
+	assert.Equal(t, uint64(30), video.PacketsSent)
+	assert.Equal(t, uint64(10), video.PacketsDropped)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// WithHandle pins the HTB class handle of the traffic class, e.g. WithHandle("1:20"),
+// instead of having one assigned from its priority. The leaf qdisc of the class takes the
+// mirrored handle (20:). Validation fails when the handle collides with another class or
+// with the default class (1:999).
+func (b *TrafficClassBuilder) WithHandle(handle string) *TrafficClassBuilder {
+	b.class.pinnedHandle = handle
+	return b
+}
+
+// assignHandles gives every class a handle and its leaf qdisc the mirrored one. Pinned
+// handles are reserved first; the other classes then take the handle their priority derives
+// (1:10-1:17) when it is free, and the next free handle otherwise, so that classes sharing a
+// priority no longer collide.
+func (controller *TrafficController) assignHandles(report *ValidationReport) {
+	allocator := entities.NewHandleAllocator()
+	allocator.MarkQdisc(tc.NewHandle(1, 0), "the root qdisc")
+	allocator.MarkClass(tc.MustParseHandle(defaultClassHandle), "the default class")
+
+	for _, class := range controller.classes {
+		class.handle = tc.Handle{}
+		if class.pinnedHandle == "" {
+			continue
+		}
+		handle, err := tc.ParseHandle(class.pinnedHandle)
+		if err != nil || handle.Major() != 1 || handle.Minor() == 0 {
+			controller.addError(report, "invalid_handle", class.name,
+				"class '%s' has an invalid handle %q: classes are children of the root qdisc, e.g. \"1:20\"",
+				class.name, class.pinnedHandle)
+			continue
+		}
+		owner := fmt.Sprintf("class '%s'", class.name)
+		if err := allocator.ReserveClass(handle, owner); err != nil {
+			controller.addError(report, "handle_conflict", class.name, "%v", err)
+			continue
+		}
+		if err := allocator.ReserveQdisc(tc.NewHandle(handle.Minor(), 0), owner); err != nil {
+			controller.addError(report, "handle_conflict", class.name, "%v", err)
+			continue
+		}
+		class.handle = handle
+	}
+
+	// The first class of each priority gets its derived handle before any class sharing a
+	// priority takes the next free one
+	var shared []*TrafficClass
+	claimed := make(map[uint8]bool)
+	for _, class := range controller.classes {
+		if class.pinnedHandle != "" || class.priority == nil {
+			continue
+		}
+		if claimed[*class.priority] {
+			shared = append(shared, class)
+			continue
+		}
+		claimed[*class.priority] = true
+		controller.allocateHandle(report, allocator, class)
+	}
+	for _, class := range shared {
+		controller.allocateHandle(report, allocator, class)
+	}
+}
+
+// allocateHandle assigns a class the free handle closest above the one its priority derives
+func (controller *TrafficController) allocateHandle(report *ValidationReport, allocator *entities.HandleAllocator, class *TrafficClass) {
+	preferred := tc.MustParseHandle(fmt.Sprintf("1:%d", int(*class.priority)+10))
+	handle, _, err := allocator.AllocateClassWithLeaf(1, preferred.Minor(), fmt.Sprintf("class '%s'", class.name))
+	if err != nil {
+		controller.addError(report, "handle_conflict", class.name, "%v", err)
+		return
+	}
+	class.handle = handle
+}
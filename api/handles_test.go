@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// appliedClassHandles applies the configuration and returns the class handles by class name
+func appliedClassHandles(t *testing.T, controller *TrafficController) map[string]string {
+	t.Helper()
+	require.NoError(t, controller.Apply())
+
+	handles := make(map[string]string)
+	for _, class := range controller.classes {
+		handles[class.name] = classHandle(class)
+	}
+	return handles
+}
+
+func TestAssignHandles_SharedPriority(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("10mbps").WithPriority(1).ForPort(80)
+	controller.CreateTrafficClass("api").WithGuaranteedBandwidth("10mbps").WithPriority(1).ForPort(8080)
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").WithPriority(2).ForPort(873)
+
+	// The class sharing a priority takes the first handle left free by the others
+	handles := appliedClassHandles(t, controller)
+	assert.Equal(t, map[string]string{"web": "1:11", "api": "1:13", "bulk": "1:12"}, handles)
+
+	stats, err := controller.GetRealtimeStatistics()
+	require.NoError(t, err)
+	var applied []string
+	for _, class := range stats.ClassStats {
+		applied = append(applied, class.Handle)
+	}
+	assert.ElementsMatch(t, []string{"1:11", "1:12", "1:13", "1:999"}, applied)
+}
+
+func TestAssignHandles_Pinned(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("10mbps").WithPriority(1).ForPort(80)
+	controller.CreateTrafficClass("ssh").WithGuaranteedBandwidth("10mbps").WithPriority(0).
+		WithHandle("1:11").WithLeafQdisc(SFQ()).ForPort(22)
+
+	// The pin wins over the handle web's priority derives
+	handles := appliedClassHandles(t, controller)
+	assert.Equal(t, map[string]string{"web": "1:12", "ssh": "1:11"}, handles)
+
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	var leaves []string
+	for _, qdisc := range plan.Qdiscs {
+		leaves = append(leaves, qdisc.Handle)
+	}
+	assert.Contains(t, leaves, "11:")
+}
+
+func TestAssignHandles_PinConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle string
+		code   string
+	}{
+		{"default class", "1:999", "handle_conflict"},
+		{"other major", "2:10", "invalid_handle"},
+		{"no minor", "1:", "invalid_handle"},
+		{"malformed", "web", "invalid_handle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+			controller.WithHardLimitBandwidth("100mbps")
+			controller.CreateTrafficClass("web").WithGuaranteedBandwidth("10mbps").WithPriority(1).WithHandle(tt.handle)
+
+			report := controller.Validate()
+			require.False(t, report.Valid())
+			assert.Equal(t, tt.code, report.Errors[0].Code)
+			assert.Equal(t, "web", report.Errors[0].Class)
+		})
+	}
+
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("10mbps").WithPriority(1).WithHandle("1:20")
+	controller.CreateTrafficClass("ssh").WithGuaranteedBandwidth("10mbps").WithPriority(0).WithHandle("1:20")
+
+	err := controller.Apply()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, tcerrors.ErrHandleConflict))
+	assert.Contains(t, err.Error(), "class handle 1:20 of class 'ssh' is already used by class 'web'")
+	assert.Contains(t, tcerrors.HintOf(err), "WithHandle")
+}
+
+func TestAssignHandles_FromConfig(t *testing.T) {
+	config, err := ParseConfigFromYAML([]byte(`
+device: eth0
+bandwidth: 100mbps
+classes:
+  - name: web
+    guaranteed: 10mbps
+    priority: 1
+    handle: "1:30"
+`))
+	require.NoError(t, err)
+
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	plan, err := controller.PlanConfig(config)
+	require.NoError(t, err)
+	assert.Equal(t, "1:30", plan.Classes[0].Handle)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// maxClassDepth is the deepest level, counting from 0 below the root qdisc, a class may be
+// nested at: HTB builds trees of at most eight levels
+const maxClassDepth = 7
+
+// WithParent nests the class under the traffic class of the name, so that the class shares
+// the bandwidth of its parent rather than the interface's, e.g. department -> team ->
+// service:
+//
+//	controller.CreateTrafficClass("engineering").WithGuaranteedBandwidth("600mbps").WithPriority(2)
+//	controller.CreateTrafficClass("platform").WithParent("engineering").WithGuaranteedBandwidth("400mbps").WithPriority(2)
+//
+// The guarantees of the children must fit in the guarantee of the parent, as the
+// oversubscription policy decides, and their soft limits in its soft limit. A parent only
+// lends bandwidth to its children: it takes no filters, leaf qdisc or low-latency tuning.
+func (b *TrafficClassBuilder) WithParent(name string) *TrafficClassBuilder {
+	b.class.parentName = name
+	return b
+}
+
+// CreateChildClass creates a traffic class nested under the traffic class of the name, as
+// CreateTrafficClass(name).WithParent(parent)
+func (controller *TrafficController) CreateChildClass(parent, name string) *TrafficClassBuilder {
+	return controller.CreateTrafficClass(name).WithParent(parent)
+}
+
+// parentOf returns the class the class is nested under, nil for a class of the root qdisc
+// or one whose parent does not exist
+func (controller *TrafficController) parentOf(class *TrafficClass) *TrafficClass {
+	if class.parentName == "" {
+		return nil
+	}
+	return controller.classByName(class.parentName)
+}
+
+// childrenOf returns the classes nested directly under the parent, or with nil the classes
+// of the root qdisc
+func (controller *TrafficController) childrenOf(parent *TrafficClass) []*TrafficClass {
+	parentName := ""
+	if parent != nil {
+		parentName = parent.name
+	}
+	var children []*TrafficClass
+	for _, class := range controller.classes {
+		if class.parentName == parentName {
+			children = append(children, class)
+		}
+	}
+	return children
+}
+
+// hasChildren reports whether classes are nested under the class
+func (controller *TrafficController) hasChildren(class *TrafficClass) bool {
+	return len(controller.childrenOf(class)) > 0
+}
+
+// classesParentsFirst returns the classes ordered so that every class follows its parent,
+// keeping their order otherwise, and the classes that cannot be ordered: those under a
+// missing parent or in a cycle. Without nesting the order is that of definition.
+func (controller *TrafficController) classesParentsFirst() (ordered, stranded []*TrafficClass) {
+	placed := make(map[string]bool, len(controller.classes))
+	remaining := controller.classes
+	for len(remaining) > 0 {
+		var next []*TrafficClass
+		for _, class := range remaining {
+			if class.parentName == "" || placed[class.parentName] {
+				ordered = append(ordered, class)
+				continue
+			}
+			next = append(next, class)
+		}
+		if len(next) == len(remaining) {
+			return ordered, next
+		}
+		for _, class := range ordered {
+			placed[class.name] = true
+		}
+		remaining = next
+	}
+	return ordered, nil
+}
+
+// validateHierarchy checks that every parent exists and the nesting forms a tree HTB can
+// build, and that parents only lend bandwidth
+func (controller *TrafficController) validateHierarchy(report *ValidationReport) {
+	for _, class := range controller.classes {
+		if class.parentName != "" && controller.classByName(class.parentName) == nil {
+			controller.addError(report, "unknown_parent", class.name,
+				"class '%s' is nested under '%s', which is not a traffic class", class.name, class.parentName)
+		}
+	}
+
+	ordered, stranded := controller.classesParentsFirst()
+	for _, class := range stranded {
+		if controller.classByName(class.parentName) != nil {
+			controller.addError(report, "class_cycle", class.name,
+				"class '%s' is nested under '%s', which is nested under it", class.name, class.parentName)
+		}
+	}
+
+	hierarchy := entities.NewClassHierarchy(maxClassDepth)
+	device, _ := tc.NewDeviceName(controller.deviceName)
+	for _, class := range ordered {
+		parent := tc.NewHandle(1, 0)
+		if p := controller.parentOf(class); p != nil {
+			parent = p.handle
+		}
+		if err := hierarchy.AddClass(entities.NewClass(device, class.handle, parent, class.name, 0)); err != nil {
+			controller.addError(report, "hierarchy_too_deep", class.name,
+				"class '%s' cannot be nested under '%s': %v", class.name, class.parentName, err)
+		}
+	}
+
+	for _, parent := range ordered {
+		children := controller.childrenOf(parent)
+		if len(children) == 0 {
+			continue
+		}
+		if len(parent.filters) > 0 {
+			controller.addError(report, "filters_on_parent_class", parent.name,
+				"class '%s' has filters but classes are nested under it; HTB only queues packets in leaf classes\n"+
+					"Suggestion: Move the filters to a class nested under '%s'", parent.name, parent.name)
+		}
+		if parent.leafQdisc != nil || parent.lowLatency {
+			controller.addError(report, "leaf_qdisc_on_parent_class", parent.name,
+				"class '%s' has a leaf qdisc or low-latency tuning but classes are nested under it\n"+
+					"Suggestion: Set them on the classes nested under '%s'", parent.name, parent.name)
+		}
+
+		ceiling := parent.maxBandwidth
+		if ceiling.BitsPerSecond() == 0 {
+			ceiling = parent.guaranteedBandwidth
+		}
+		for _, child := range children {
+			if child.maxBandwidth.GreaterThan(ceiling) {
+				controller.addError(report, "max_exceeds_parent", child.name,
+					"class '%s' has max bandwidth (%s) higher than the max bandwidth of its parent '%s' (%s)",
+					child.name, child.maxBandwidth, parent.name, ceiling)
+			}
+		}
+	}
+}
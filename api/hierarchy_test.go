@@ -0,0 +1,206 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+func TestClassHierarchy(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("1gbps")
+	// Defined children first: classes are created parents first
+	controller.CreateChildClass("platform", "ci").WithGuaranteedBandwidth("100mbps").WithSoftLimitBandwidth("400mbps").
+		WithPriority(3).ForPort(8080)
+	controller.CreateTrafficClass("engineering").WithGuaranteedBandwidth("600mbps").WithSoftLimitBandwidth("900mbps").WithPriority(2)
+	controller.CreateTrafficClass("platform").WithParent("engineering").WithGuaranteedBandwidth("400mbps").
+		WithSoftLimitBandwidth("800mbps").WithPriority(2)
+	controller.CreateChildClass("platform", "registry").WithGuaranteedBandwidth("300mbps").WithSoftLimitBandwidth("800mbps").
+		WithPriority(4).ForPort(5000)
+	controller.CreateChildClass("engineering", "wiki").WithGuaranteedBandwidth("200mbps").WithSoftLimitBandwidth("400mbps").
+		WithPriority(5).ForPort(80)
+	controller.CreateTrafficClass("guests").WithGuaranteedBandwidth("100mbps").WithSoftLimitBandwidth("200mbps").
+		WithPriority(6).ForPort(8000)
+
+	report := controller.Validate()
+	require.Empty(t, report.Errors)
+	assert.Nil(t, findIssue(report.Warnings, "no_filters"), "parents take no traffic")
+
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	handles := make(map[string]string)
+	var order []string
+	for _, class := range plan.Classes {
+		handles[class.Name] = class.Handle
+		order = append(order, class.Name)
+	}
+	parents := make(map[string]string)
+	for _, class := range plan.Classes {
+		parents[class.Name] = class.Parent
+	}
+	assert.Equal(t, "1:", parents["engineering"])
+	assert.Equal(t, handles["engineering"], parents["platform"])
+	assert.Equal(t, handles["engineering"], parents["wiki"])
+	assert.Equal(t, handles["platform"], parents["ci"])
+	assert.Equal(t, handles["platform"], parents["registry"])
+	assert.Less(t, indexOf(order, "platform"), indexOf(order, "ci"))
+
+	// Only the leaves classify traffic
+	flows := make(map[string]bool)
+	for _, filter := range plan.Filters {
+		flows[filter.FlowID] = true
+	}
+	assert.False(t, flows[handles["engineering"]])
+	assert.False(t, flows[handles["platform"]])
+	assert.True(t, flows[handles["ci"]])
+
+	require.NoError(t, controller.Apply())
+}
+
+// indexOf returns the index of the name, or -1
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestClassHierarchy_Validation(t *testing.T) {
+	newController := func() *TrafficController {
+		controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+		controller.WithHardLimitBandwidth("100mbps")
+		return controller
+	}
+
+	t.Run("unknown_parent", func(t *testing.T) {
+		controller := newController()
+		controller.CreateChildClass("engineering", "ci").WithGuaranteedBandwidth("10mbps").WithPriority(1)
+
+		report := controller.Validate()
+		require.NotNil(t, findIssue(report.Errors, "unknown_parent"))
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		controller := newController()
+		controller.CreateChildClass("b", "a").WithGuaranteedBandwidth("10mbps").WithPriority(1)
+		controller.CreateChildClass("a", "b").WithGuaranteedBandwidth("10mbps").WithPriority(2)
+
+		report := controller.Validate()
+		require.NotNil(t, findIssue(report.Errors, "class_cycle"))
+	})
+
+	t.Run("too_deep", func(t *testing.T) {
+		controller := newController()
+		parent := ""
+		for _, name := range []string{"l0", "l1", "l2", "l3", "l4", "l5", "l6", "l7", "l8"} {
+			controller.CreateTrafficClass(name).WithParent(parent).WithGuaranteedBandwidth("10mbps").WithPriority(1)
+			parent = name
+		}
+
+		report := controller.Validate()
+		issue := findIssue(report.Errors, "hierarchy_too_deep")
+		require.NotNil(t, issue)
+		assert.Equal(t, "l8", issue.Class)
+	})
+
+	t.Run("parent_only_lends", func(t *testing.T) {
+		controller := newController()
+		controller.CreateTrafficClass("engineering").WithGuaranteedBandwidth("50mbps").WithSoftLimitBandwidth("60mbps").
+			WithPriority(1).ForPort(22).WithLowLatency()
+		controller.CreateChildClass("engineering", "ci").WithGuaranteedBandwidth("10mbps").WithSoftLimitBandwidth("80mbps").
+			WithPriority(2).ForPort(8080)
+
+		report := controller.Validate()
+		assert.NotNil(t, findIssue(report.Errors, "filters_on_parent_class"))
+		assert.NotNil(t, findIssue(report.Errors, "leaf_qdisc_on_parent_class"))
+		assert.NotNil(t, findIssue(report.Errors, "max_exceeds_parent"))
+		assert.True(t, errors.Is(controller.Apply(), tcerrors.ErrInvalidConfiguration))
+	})
+
+	t.Run("children_exceed_parent", func(t *testing.T) {
+		controller := newController()
+		controller.CreateTrafficClass("engineering").WithGuaranteedBandwidth("50mbps").WithSoftLimitBandwidth("90mbps").WithPriority(1)
+		controller.CreateChildClass("engineering", "ci").WithGuaranteedBandwidth("40mbps").WithSoftLimitBandwidth("90mbps").
+			WithPriority(2).ForPort(8080)
+		controller.CreateChildClass("engineering", "wiki").WithGuaranteedBandwidth("20mbps").WithSoftLimitBandwidth("90mbps").
+			WithPriority(3).ForPort(80)
+
+		report := controller.Validate()
+		issue := findIssue(report.Errors, "children_guaranteed_exceeds_parent")
+		require.NotNil(t, issue)
+		assert.Equal(t, "engineering", issue.Class)
+		assert.Nil(t, findIssue(report.Errors, "total_guaranteed_exceeds_total"), "children share the guarantee of their parent")
+		assert.True(t, errors.Is(controller.Apply(), tcerrors.ErrBandwidthExceeded))
+	})
+}
+
+func TestClassHierarchy_ScaleGuarantees(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter()).WithOversubscriptionPolicy(ScaleGuarantees)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("engineering").WithGuaranteedBandwidth("99mbps").WithSoftLimitBandwidth("100mbps").WithPriority(1)
+	controller.CreateTrafficClass("guests").WithGuaranteedBandwidth("99mbps").WithSoftLimitBandwidth("100mbps").WithPriority(2).ForPort(8000)
+	controller.CreateChildClass("engineering", "ci").WithGuaranteedBandwidth("66mbps").WithSoftLimitBandwidth("100mbps").
+		WithPriority(3).ForPort(8080)
+	controller.CreateChildClass("engineering", "wiki").WithGuaranteedBandwidth("33mbps").WithSoftLimitBandwidth("100mbps").
+		WithPriority(4).ForPort(80)
+
+	require.NoError(t, controller.Apply())
+	guaranteed := make(map[string]uint64)
+	for _, class := range controller.classes {
+		guaranteed[class.name] = class.guaranteedBandwidth.BitsPerSecond()
+	}
+	// The top level is halved to fit, then the children fit the halved parent
+	assert.Equal(t, uint64(49_500_000), guaranteed["engineering"])
+	assert.Equal(t, uint64(33_000_000), guaranteed["ci"])
+	assert.Equal(t, uint64(16_500_000), guaranteed["wiki"])
+}
+
+func TestClassHierarchy_Config(t *testing.T) {
+	config, err := ParseConfigFromYAML([]byte(`
+device: eth0
+bandwidth: 100mbps
+classes:
+  - name: engineering
+    guaranteed: 60mbps
+    maximum: 90mbps
+    priority: 2
+    children:
+      - name: ci
+        guaranteed: 40mbps
+        maximum: 90mbps
+        priority: 3
+      - name: wiki
+        guaranteed: 20mbps
+        maximum: 40mbps
+        priority: 4
+  - name: release
+    parent: engineering.ci
+    guaranteed: 10mbps
+    maximum: 20mbps
+    priority: 5
+  - name: guests
+    guaranteed: 30mbps
+    maximum: 50mbps
+    priority: 6
+rules:
+  - name: wiki
+    match:
+      dest_port: [80]
+    target: engineering.wiki
+    priority: 1
+`))
+	require.NoError(t, err)
+
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	require.NoError(t, controller.ApplyConfig(config))
+	assert.Equal(t, "engineering", controller.classByName("engineering.ci").parentName)
+	assert.Equal(t, "engineering.ci", controller.classByName("release").parentName)
+	assert.True(t, controller.Validate().Valid())
+}
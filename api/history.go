@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/statstore"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// PersistHistory records the controller's configuration history in a SQLite database at
+// path instead of in memory. A process restarted with the same database reconciles against
+// the configuration it applied before, leaving unchanged objects in place. The samples of a
+// StatisticsRecorder are kept in the same database. It must be called before Apply and
+// before recording statistics; after CloseHistory the controller must not be applied again.
+func (controller *TrafficController) PersistHistory(path string) error {
+	store, err := eventstore.NewSQLiteEventStore(path)
+	if err != nil {
+		return fmt.Errorf("failed to open configuration history: %w", err)
+	}
+
+	statistics, err := statstore.NewSQLiteStore(path)
+	if err != nil {
+		_ = store.Close()
+		return fmt.Errorf("failed to open statistics history: %w", err)
+	}
+
+	if err := controller.CloseHistory(); err != nil {
+		controller.logger.Warn("Failed to close previous configuration history", logging.Error(err))
+	}
+	controller.history = store
+	controller.statistics = statistics
+	controller.service = controller.service.WithEventStore(&eventstore.SQLiteEventStoreWrapper{SQLiteEventStore: store})
+
+	controller.logger.Info("Persisting configuration history",
+		logging.String("path", path),
+	)
+	return nil
+}
+
+// CloseHistory closes the database opened by PersistHistory
+func (controller *TrafficController) CloseHistory() error {
+	if controller.history == nil {
+		return nil
+	}
+	err := errors.Join(controller.history.Close(), controller.statistics.Close())
+	controller.history = nil
+	controller.statistics = statstore.NewMemoryStore()
+	return err
+}
+
+// Restore re-applies the configuration last applied to the interface, as recorded in the
+// history opened by PersistHistory. Kernel qdiscs do not survive a reboot; a boot hook
+// restoring from the history reinstalls them without the program that configured them.
+// Objects still installed as recorded are left in place. The controller's own configuration
+// is not used, and is not rebuilt: applying it afterwards replaces the restored one.
+func (controller *TrafficController) Restore() (AppliedConfig, error) {
+	if controller.history == nil {
+		return AppliedConfig{}, fmt.Errorf("restoring needs the configuration history: call PersistHistory first")
+	}
+
+	ctx := netlink.WithOperationTimeout(controller.ownershipContext(controller.actorContext(context.Background())), controller.operationTimeout)
+	if err := controller.checkPrivileges(ctx); err != nil {
+		return AppliedConfig{}, err
+	}
+
+	var result *application.ReconcileResult
+	err := controller.inTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = controller.service.Restore(ctx, controller.deviceName)
+		return err
+	})
+	if err != nil {
+		controller.logger.Error("Failed to restore traffic control configuration",
+			logging.String("device", controller.deviceName),
+			logging.Error(err),
+		)
+		return AppliedConfig{}, err
+	}
+
+	controller.logger.Info("Restored traffic control configuration",
+		logging.String("device", controller.deviceName),
+		logging.Int("added", len(result.Added)),
+		logging.Int("unchanged", len(result.Unchanged)),
+	)
+	return AppliedConfig{
+		Device:    controller.deviceName,
+		Added:     result.Added,
+		Deleted:   result.Deleted,
+		Modified:  result.Modified,
+		Unchanged: result.Unchanged,
+	}, nil
+}
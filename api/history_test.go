@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+func TestTrafficController_PersistHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	ctx := context.Background()
+
+	newController := func() *TrafficController {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("web").
+			WithGuaranteedBandwidth("30mbps").
+			WithSoftLimitBandwidth("60mbps").
+			WithPriority(1).
+			ForPort(80)
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+		require.NoError(t, controller.PersistHistory(path))
+		return controller
+	}
+
+	first := newController()
+	require.NoError(t, first.Apply())
+	history, err := first.service.GetDeviceHistory(ctx, "eth0")
+	require.NoError(t, err)
+	applied := len(history)
+	require.NotZero(t, applied)
+	require.NoError(t, first.CloseHistory())
+
+	// Counters of the live class show whether it is recreated
+	device := tc.MustNewDeviceName("eth0")
+	handle := tc.MustParseHandle(classHandle(first.classes[0]))
+	mockNetlinkAdapter.SetClassStatistics(device, handle, netlink.ClassStats{BytesSent: 1000})
+
+	// A restarted controller finds the configuration it applied and changes nothing
+	second := newController()
+	defer func() { _ = second.CloseHistory() }()
+	require.NoError(t, second.Apply())
+	history, err = second.service.GetDeviceHistory(ctx, "eth0")
+	require.NoError(t, err)
+	assert.Len(t, history, applied)
+
+	classes := mockNetlinkAdapter.GetClasses(device).Value()
+	for _, class := range classes {
+		if class.Handle == handle {
+			assert.Equal(t, uint64(1000), class.Statistics.BytesSent)
+		}
+	}
+}
+
+func TestTrafficController_PersistHistoryInvalidPath(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	err := controller.PersistHistory(filepath.Join(t.TempDir(), "missing", "history.db"))
+	assert.ErrorContains(t, err, "failed to open configuration history")
+	assert.NoError(t, controller.CloseHistory())
+}
+
+func TestTrafficController_Restore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	device := tc.MustNewDeviceName("eth0")
+
+	// boot returns a controller on a host whose kernel configuration is empty, as after a
+	// reboot, recording its history at path
+	boot := func(adapter *netlink.MockAdapter) *TrafficController {
+		controller := NetworkInterface("eth0")
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), adapter, controller.logger)
+		require.NoError(t, controller.PersistHistory(path))
+		t.Cleanup(func() { _ = controller.CloseHistory() })
+		return controller
+	}
+
+	_, err := NetworkInterface("eth0").Restore()
+	assert.ErrorContains(t, err, "PersistHistory")
+
+	_, err = boot(netlink.NewMockAdapter()).Restore()
+	assert.True(t, errors.Is(err, tcerrors.ErrNotFound), "nothing was applied yet: %v", err)
+
+	first := boot(netlink.NewMockAdapter())
+	first.WithHardLimitBandwidth("100mbps")
+	first.CreateTrafficClass("web").WithGuaranteedBandwidth("30mbps").WithPriority(1).ForPort(80)
+	require.NoError(t, first.Apply())
+	// A class changed in place is recorded after its filter
+	require.NoError(t, first.UpdateTrafficClass("web").SetMaxBandwidth("80mbps").Apply())
+	want := first.service.NetlinkAdapter().GetClasses(device).Value()
+	require.NoError(t, first.CloseHistory())
+
+	adapter := netlink.NewMockAdapter()
+	restored, err := boot(adapter).Restore()
+	require.NoError(t, err)
+	assert.NotEmpty(t, restored.Added)
+	assert.Empty(t, restored.Deleted, "missing objects are only restored")
+	assert.ElementsMatch(t, want, adapter.GetClasses(device).Value())
+	assert.Len(t, adapter.GetFilters(device).Value(), 1)
+
+	again, err := boot(adapter).Restore()
+	require.NoError(t, err)
+	assert.False(t, again.Changed(), "an installed configuration is left in place")
+}
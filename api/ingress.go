@@ -0,0 +1,45 @@
+package api
+
+import (
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// ShapeIngressVia shapes the traffic the device receives instead of the traffic it sends.
+// Apply creates the IFB device if needed, loading the ifb kernel module, and redirects the
+// device's ingress to it, where the configured classes shape it on egress. From this call on,
+// statistics, configuration reads and repairs refer to the IFB device.
+func (controller *TrafficController) ShapeIngressVia(ifb string) *TrafficController {
+	if controller.ingressDevice == "" {
+		controller.ingressDevice = controller.deviceName
+	}
+	controller.deviceName = ifb
+
+	controller.logger.Info("Shaping ingress traffic through IFB device",
+		logging.String("device", controller.ingressDevice),
+		logging.String("ifb", ifb),
+	)
+	return controller
+}
+
+// RemoveIngressShaping stops redirecting the device's ingress and deletes the IFB device
+// together with its classes and filters. It does nothing unless ShapeIngressVia was called.
+func (controller *TrafficController) RemoveIngressShaping() error {
+	if controller.ingressDevice == "" {
+		return nil
+	}
+
+	if err := controller.service.DeleteIngressRedirect(controller.ingressDevice, controller.deviceName); err != nil {
+		controller.logger.Error("Failed to remove ingress shaping",
+			logging.String("device", controller.ingressDevice),
+			logging.String("ifb", controller.deviceName),
+			logging.Error(err),
+		)
+		return err
+	}
+
+	controller.logger.Info("Removed ingress shaping",
+		logging.String("device", controller.ingressDevice),
+		logging.String("ifb", controller.deviceName),
+	)
+	return nil
+}
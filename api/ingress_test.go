@@ -0,0 +1,70 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestTrafficController_ShapeIngressVia(t *testing.T) {
+	controller := NetworkInterface("eth0").ShapeIngressVia("ifb0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("downloads").
+		WithGuaranteedBandwidth("20mbps").
+		WithSoftLimitBandwidth("50mbps").
+		WithPriority(4).
+		ForPort(443)
+
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+	require.NoError(t, controller.Apply())
+
+	device := tc.MustNewDeviceName("eth0")
+	ifb := tc.MustNewDeviceName("ifb0")
+	redirect, ok := mockNetlinkAdapter.IngressRedirect(device)
+	require.True(t, ok)
+	assert.Equal(t, ifb, redirect)
+
+	// The hierarchy is built on the IFB device, not the device itself
+	assert.Empty(t, mockNetlinkAdapter.GetQdiscs(device).Value())
+	assert.NotEmpty(t, mockNetlinkAdapter.GetQdiscs(ifb).Value())
+	assert.NotEmpty(t, mockNetlinkAdapter.GetClasses(ifb).Value())
+
+	// Applying again keeps the redirect
+	require.NoError(t, controller.Apply())
+	_, ok = mockNetlinkAdapter.IngressRedirect(device)
+	assert.True(t, ok)
+
+	require.NoError(t, controller.RemoveIngressShaping())
+	_, ok = mockNetlinkAdapter.IngressRedirect(device)
+	assert.False(t, ok)
+	assert.Empty(t, mockNetlinkAdapter.GetQdiscs(ifb).Value())
+
+	// Applying after the teardown shapes the ingress again
+	require.NoError(t, controller.Apply())
+	_, ok = mockNetlinkAdapter.IngressRedirect(device)
+	assert.True(t, ok)
+	assert.NotEmpty(t, mockNetlinkAdapter.GetClasses(ifb).Value())
+}
+
+func TestTrafficController_ShapeIngressViaSelf(t *testing.T) {
+	controller := NetworkInterface("eth0").ShapeIngressVia("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), netlink.NewMockAdapter(), controller.logger)
+
+	err := controller.Apply()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "itself")
+}
+
+func TestTrafficController_RemoveIngressShapingWithoutIngress(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	assert.NoError(t, controller.RemoveIngressShaping())
+}
@@ -0,0 +1,413 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/commands/models"
+)
+
+// LeafQdisc is a queueing discipline attached under a traffic class with
+// TrafficClassBuilder.WithLeafQdisc. Without one, the kernel picks the class's leaf queue.
+type LeafQdisc interface {
+	// create attaches the qdisc with the given handle to the class
+	create(ctx context.Context, service *application.TrafficControlService, device, classID, handle string) error
+}
+
+// SFQQdisc configures a Stochastic Fairness Queueing leaf qdisc, which shares a class's
+// bandwidth fairly between its flows
+type SFQQdisc struct {
+	perturb uint32
+	quantum uint32
+	limit   uint32
+}
+
+// SFQ creates an SFQ leaf qdisc with kernel defaults
+func SFQ() *SFQQdisc {
+	return &SFQQdisc{
+		perturb: 0,    // no hash perturbation
+		quantum: 1514, // one full Ethernet frame
+		limit:   127,  // kernel default packet limit
+	}
+}
+
+// Perturb re-seeds the flow hash every given number of seconds, so that colliding flows
+// do not keep sharing a queue
+func (q *SFQQdisc) Perturb(seconds uint32) *SFQQdisc {
+	q.perturb = seconds
+	return q
+}
+
+// Quantum sets the bytes a flow may send per round
+func (q *SFQQdisc) Quantum(bytes uint32) *SFQQdisc {
+	q.quantum = bytes
+	return q
+}
+
+// Limit sets the queue limit in packets
+func (q *SFQQdisc) Limit(packets uint32) *SFQQdisc {
+	q.limit = packets
+	return q
+}
+
+func (q *SFQQdisc) create(ctx context.Context, service *application.TrafficControlService, device, classID, handle string) error {
+	return service.CreateLeafSFQQdisc(ctx, device, classID, handle, q.perturb, q.quantum, q.limit)
+}
+
+// FQQdisc configures a Fair Queue leaf qdisc, which schedules flows fairly and paces
+// each flow at the rate its socket requests
+type FQQdisc struct {
+	limit          uint32
+	flowLimit      uint32
+	quantum        uint32
+	initialQuantum uint32
+	maxRate        string
+	pacing         bool
+}
+
+// FQ creates an FQ leaf qdisc with kernel defaults
+func FQ() *FQQdisc {
+	return &FQQdisc{
+		limit:          10000, // kernel default packet limit
+		flowLimit:      100,   // kernel default per-flow limit
+		quantum:        3028,  // two full Ethernet frames
+		initialQuantum: 15140, // ten full Ethernet frames
+		pacing:         true,
+	}
+}
+
+// Limit sets the queue limit in packets
+func (q *FQQdisc) Limit(packets uint32) *FQQdisc {
+	q.limit = packets
+	return q
+}
+
+// FlowLimit sets the per-flow queue limit in packets
+func (q *FQQdisc) FlowLimit(packets uint32) *FQQdisc {
+	q.flowLimit = packets
+	return q
+}
+
+// Quantum sets the bytes a flow may send per round
+func (q *FQQdisc) Quantum(bytes uint32) *FQQdisc {
+	q.quantum = bytes
+	return q
+}
+
+// InitialQuantum sets the bytes a new flow may send in its first round
+func (q *FQQdisc) InitialQuantum(bytes uint32) *FQQdisc {
+	q.initialQuantum = bytes
+	return q
+}
+
+// MaxRate caps the rate of every flow, e.g. "10Mbps"
+func (q *FQQdisc) MaxRate(bandwidth string) *FQQdisc {
+	q.maxRate = bandwidth
+	return q
+}
+
+// Pacing enables or disables pacing flows at their socket rate
+func (q *FQQdisc) Pacing(enabled bool) *FQQdisc {
+	q.pacing = enabled
+	return q
+}
+
+func (q *FQQdisc) create(ctx context.Context, service *application.TrafficControlService, device, classID, handle string) error {
+	return service.CreateLeafFQQdisc(ctx, device, classID, handle, q.limit, q.flowLimit, q.quantum,
+		q.initialQuantum, q.maxRate, q.pacing)
+}
+
+// NETEMQdisc configures a Network Emulator qdisc, which delays, drops, duplicates,
+// corrupts or reorders packets to emulate a wide area link
+type NETEMQdisc struct {
+	params application.NETEMParameters
+}
+
+// NETEM creates a NETEM leaf qdisc that emulates a perfect link until configured
+func NETEM() *NETEMQdisc {
+	return &NETEMQdisc{
+		params: application.NETEMParameters{
+			Limit: 1000, // kernel default packet limit
+		},
+	}
+}
+
+// Delay delays every packet by the given duration
+func (q *NETEMQdisc) Delay(delay time.Duration) *NETEMQdisc {
+	q.params.Delay = delay
+	return q
+}
+
+// Jitter varies the delay randomly by up to the given duration
+func (q *NETEMQdisc) Jitter(jitter time.Duration) *NETEMQdisc {
+	q.params.Jitter = jitter
+	return q
+}
+
+// Loss drops the given percentage of packets
+func (q *NETEMQdisc) Loss(percent float32) *NETEMQdisc {
+	q.params.Loss = percent
+	return q
+}
+
+// Duplicate duplicates the given percentage of packets
+func (q *NETEMQdisc) Duplicate(percent float32) *NETEMQdisc {
+	q.params.Duplicate = percent
+	return q
+}
+
+// Corrupt flips a random bit in the given percentage of packets
+func (q *NETEMQdisc) Corrupt(percent float32) *NETEMQdisc {
+	q.params.Corrupt = percent
+	return q
+}
+
+// Reorder sends the given percentage of packets immediately, ahead of delayed ones.
+// With a non-zero gap, every gap-th packet is sent immediately instead. Reordering
+// requires a delay.
+func (q *NETEMQdisc) Reorder(percent float32, gap uint32) *NETEMQdisc {
+	q.params.Reorder = percent
+	q.params.Gap = gap
+	return q
+}
+
+// Limit sets the queue limit in packets
+func (q *NETEMQdisc) Limit(packets uint32) *NETEMQdisc {
+	q.params.Limit = packets
+	return q
+}
+
+func (q *NETEMQdisc) create(ctx context.Context, service *application.TrafficControlService, device, classID, handle string) error {
+	return service.CreateNETEMQdisc(ctx, device, classID, handle, q.params)
+}
+
+// REDQdisc configures a Random Early Detection qdisc, which drops or ECN marks packets
+// with a probability that grows with the average queue size, before the queue fills up
+type REDQdisc struct {
+	params application.REDParameters
+}
+
+// RED creates a RED qdisc that starts marking when the average queue exceeds min bytes
+// and marks with the full probability at max bytes. The limit defaults to four times max.
+func RED(min, max uint32) *REDQdisc {
+	return &REDQdisc{
+		params: application.REDParameters{
+			Limit:       4 * max,
+			Min:         min,
+			Max:         max,
+			Avpkt:       1000,     // typical average packet size
+			Bandwidth:   "10mbit", // tc default
+			Probability: 0.02,     // tc default
+		},
+	}
+}
+
+// Limit sets the hard queue limit in bytes
+func (q *REDQdisc) Limit(bytes uint32) *REDQdisc {
+	q.params.Limit = bytes
+	return q
+}
+
+// Avpkt sets the average packet size in bytes
+func (q *REDQdisc) Avpkt(bytes uint32) *REDQdisc {
+	q.params.Avpkt = bytes
+	return q
+}
+
+// Burst sets how many packets may arrive at once; zero derives it from the thresholds
+func (q *REDQdisc) Burst(packets uint32) *REDQdisc {
+	q.params.Burst = packets
+	return q
+}
+
+// Bandwidth sets the link rate, e.g. "1gbit", used to age the average while the queue is idle
+func (q *REDQdisc) Bandwidth(bandwidth string) *REDQdisc {
+	q.params.Bandwidth = bandwidth
+	return q
+}
+
+// Probability sets the marking probability at the max threshold, between 0 and 1
+func (q *REDQdisc) Probability(probability float64) *REDQdisc {
+	q.params.Probability = probability
+	return q
+}
+
+// ECN marks ECN-capable packets instead of dropping them
+func (q *REDQdisc) ECN(enabled bool) *REDQdisc {
+	q.params.ECN = enabled
+	return q
+}
+
+// HardDrop drops rather than marks packets once the average exceeds max; requires ECN
+func (q *REDQdisc) HardDrop(enabled bool) *REDQdisc {
+	q.params.HardDrop = enabled
+	return q
+}
+
+// Adaptive lets the kernel tune the probability to keep the average between the thresholds
+func (q *REDQdisc) Adaptive(enabled bool) *REDQdisc {
+	q.params.Adaptive = enabled
+	return q
+}
+
+func (q *REDQdisc) create(ctx context.Context, service *application.TrafficControlService, device, classID, handle string) error {
+	return service.CreateREDQdisc(ctx, device, classID, handle, q.params)
+}
+
+// GREDQdisc configures a Generalized RED qdisc: several RED virtual queues with their own
+// thresholds, selected by the packet's tc_index (e.g. set by dsmark)
+type GREDQdisc struct {
+	params application.GREDParameters
+}
+
+// GRED creates a GRED qdisc with the given number of virtual queues, at most 16. Packets
+// whose tc_index selects no configured queue use virtual queue 0 until Default is set.
+func GRED(queues uint32) *GREDQdisc {
+	return &GREDQdisc{
+		params: application.GREDParameters{
+			Queues: queues,
+		},
+	}
+}
+
+// Default selects the virtual queue of packets whose tc_index selects none
+func (q *GREDQdisc) Default(dp uint32) *GREDQdisc {
+	q.params.Default = dp
+	return q
+}
+
+// GRIO enables RIO mode, where the average of a virtual queue includes the backlog of
+// queues with higher priority (lower priority values)
+func (q *GREDQdisc) GRIO(enabled bool) *GREDQdisc {
+	q.params.GRIO = enabled
+	return q
+}
+
+// ECN marks ECN-capable packets instead of dropping them in every virtual queue
+func (q *GREDQdisc) ECN(enabled bool) *GREDQdisc {
+	q.params.ECN = enabled
+	return q
+}
+
+// HardDrop drops rather than marks packets once the average exceeds max; requires ECN
+func (q *GREDQdisc) HardDrop(enabled bool) *GREDQdisc {
+	q.params.HardDrop = enabled
+	return q
+}
+
+// VirtualQueue configures virtual queue dp with the thresholds of a RED qdisc; the priority
+// only matters in GRIO mode. The ECN, hard drop and adaptive settings of the RED qdisc are
+// ignored, as GRED applies its own to all virtual queues.
+func (q *GREDQdisc) VirtualQueue(dp uint32, priority uint8, red *REDQdisc) *GREDQdisc {
+	q.params.VirtualQueues = append(q.params.VirtualQueues, models.GREDVirtualQueue{
+		DP:          dp,
+		Priority:    priority,
+		Limit:       red.params.Limit,
+		Min:         red.params.Min,
+		Max:         red.params.Max,
+		Avpkt:       red.params.Avpkt,
+		Burst:       red.params.Burst,
+		Bandwidth:   red.params.Bandwidth,
+		Probability: red.params.Probability,
+	})
+	return q
+}
+
+func (q *GREDQdisc) create(ctx context.Context, service *application.TrafficControlService, device, classID, handle string) error {
+	return service.CreateGREDQdisc(ctx, device, classID, handle, q.params)
+}
+
+// CODELQdisc configures a Controlled Delay qdisc, which drops or marks packets when the
+// time they spend queued stays above a target
+type CODELQdisc struct {
+	params application.CODELParameters
+}
+
+// CODEL creates a CoDel qdisc with kernel defaults
+func CODEL() *CODELQdisc {
+	return &CODELQdisc{
+		params: application.CODELParameters{
+			Limit:    1000, // kernel default packet limit
+			Target:   5 * time.Millisecond,
+			Interval: 100 * time.Millisecond,
+		},
+	}
+}
+
+// Limit sets the queue limit in packets
+func (q *CODELQdisc) Limit(packets uint32) *CODELQdisc {
+	q.params.Limit = packets
+	return q
+}
+
+// Target sets the acceptable standing queue delay
+func (q *CODELQdisc) Target(target time.Duration) *CODELQdisc {
+	q.params.Target = target
+	return q
+}
+
+// Interval sets the window over which the queue delay must fall below the target; it
+// should be about the worst case round trip time of the traffic
+func (q *CODELQdisc) Interval(interval time.Duration) *CODELQdisc {
+	q.params.Interval = interval
+	return q
+}
+
+// CEThreshold ECN marks packets queued longer than the threshold, independently of the
+// drop logic; zero disables it
+func (q *CODELQdisc) CEThreshold(threshold time.Duration) *CODELQdisc {
+	q.params.CEThreshold = threshold
+	return q
+}
+
+// ECN marks ECN-capable packets instead of dropping them
+func (q *CODELQdisc) ECN(enabled bool) *CODELQdisc {
+	q.params.ECN = enabled
+	return q
+}
+
+func (q *CODELQdisc) create(ctx context.Context, service *application.TrafficControlService, device, classID, handle string) error {
+	return service.CreateCODELQdisc(ctx, device, classID, handle, q.params)
+}
+
+// ETSQdisc configures an Enhanced Transmission Selection qdisc (IEEE 802.1Qaz). Strict
+// bands are served first in order; the remaining bands share what is left in proportion
+// to their quanta.
+type ETSQdisc struct {
+	params application.ETSParameters
+}
+
+// ETS creates an ETS qdisc, which needs at least one band, strict or with a quantum
+func ETS() *ETSQdisc {
+	return &ETSQdisc{}
+}
+
+// Bands sets the number of bands; by default it is the strict bands plus the quanta
+func (q *ETSQdisc) Bands(bands uint8) *ETSQdisc {
+	q.params.Bands = bands
+	return q
+}
+
+// Strict sets the number of strict priority bands, which take the lowest band numbers
+func (q *ETSQdisc) Strict(bands uint8) *ETSQdisc {
+	q.params.Strict = bands
+	return q
+}
+
+// Quanta sets the bytes each bandwidth-sharing band may send per round, in band order
+func (q *ETSQdisc) Quanta(bytes ...uint32) *ETSQdisc {
+	q.params.Quanta = bytes
+	return q
+}
+
+// Priomap maps packet priorities to bands: the i-th band receives priority i. Unlisted
+// priorities go to the last band.
+func (q *ETSQdisc) Priomap(bands ...uint8) *ETSQdisc {
+	q.params.Priomap = bands
+	return q
+}
+
+func (q *ETSQdisc) create(ctx context.Context, service *application.TrafficControlService, device, classID, handle string) error {
+	return service.CreateETSQdisc(ctx, device, classID, handle, q.params)
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// DefaultLinkReapplyDelay is the default time a LinkWatcher waits after a device comes up
+// before it re-applies the configuration
+const DefaultLinkReapplyDelay = time.Second
+
+// LinkEvent reports a managed device going down or coming up
+type LinkEvent struct {
+	Device  string
+	Index   int  // Interface index, new when the device was recreated
+	Up      bool // False when the link went down or the device was removed
+	Removed bool // The device was deleted
+}
+
+// linkState is the last known state of a watched device
+type linkState struct {
+	index int
+	up    bool
+}
+
+// LinkWatcher re-applies the controller's configuration when its device comes back up or
+// is recreated, as happens with VPN tun/tap devices and bonds, and runs the registered
+// hooks on every transition. A controller shaping ingress through an IFB device watches
+// both devices.
+type LinkWatcher struct {
+	controller *TrafficController
+	devices    []string
+	delay      time.Duration
+	logger     logging.Logger
+	reapply    bool
+
+	mu     sync.Mutex
+	onUp   []func(LinkEvent)
+	onDown []func(LinkEvent)
+
+	reapplies atomic.Uint64
+}
+
+// NewLinkWatcher creates a link watcher for the controller's devices, as configured when it
+// is called. The configuration is re-applied delay after a device comes up, so that a
+// flapping link triggers a single apply. A non-positive delay selects DefaultLinkReapplyDelay.
+func (controller *TrafficController) NewLinkWatcher(delay time.Duration) *LinkWatcher {
+	if delay <= 0 {
+		delay = DefaultLinkReapplyDelay
+	}
+
+	devices := []string{controller.deviceName}
+	if controller.ingressDevice != "" {
+		devices = append(devices, controller.ingressDevice)
+	}
+
+	return &LinkWatcher{
+		controller: controller,
+		devices:    devices,
+		delay:      delay,
+		logger:     controller.logger,
+		reapply:    true,
+	}
+}
+
+// OnLinkUp registers a hook run from the watcher's goroutine when a device comes up or is
+// recreated, before the configuration is re-applied
+func (w *LinkWatcher) OnLinkUp(hook func(LinkEvent)) *LinkWatcher {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onUp = append(w.onUp, hook)
+	return w
+}
+
+// OnLinkDown registers a hook run from the watcher's goroutine when a device goes down or
+// is removed
+func (w *LinkWatcher) OnLinkDown(hook func(LinkEvent)) *LinkWatcher {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onDown = append(w.onDown, hook)
+	return w
+}
+
+// WithoutReapply leaves re-applying the configuration to the hooks, for callers that
+// serialize their use of the controller themselves
+func (w *LinkWatcher) WithoutReapply() *LinkWatcher {
+	w.reapply = false
+	return w
+}
+
+// Run watches the devices until the context is cancelled. The devices need not exist yet.
+func (w *LinkWatcher) Run(ctx context.Context) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan LinkEvent)
+	stopped := make(chan struct{}, len(w.devices))
+	for _, device := range w.devices {
+		links, err := w.controller.service.WatchLinks(watchCtx, device)
+		if err != nil {
+			return err
+		}
+		go func(device string) {
+			defer func() { stopped <- struct{}{} }()
+			for link := range links {
+				event := LinkEvent{Device: device, Index: link.Index, Up: link.Up, Removed: link.Removed}
+				select {
+				case events <- event:
+				case <-watchCtx.Done():
+					return
+				}
+			}
+		}(device)
+	}
+
+	w.logger.Info("Watching device links",
+		logging.String("device", w.devices[0]),
+		logging.String("delay", w.delay.String()),
+	)
+
+	// The timer is armed by the first device coming up and not pushed back by later ones
+	var timer *time.Timer
+	var reapply <-chan time.Time
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	states := make(map[string]*linkState)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-stopped:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errors.New("link watch stopped unexpectedly")
+
+		case event := <-events:
+			if !w.transition(states, event) || !event.Up || !w.reapply || reapply != nil {
+				continue
+			}
+			timer = time.NewTimer(w.delay)
+			reapply = timer.C
+
+		case <-reapply:
+			reapply = nil
+			w.reapplyConfiguration()
+		}
+	}
+}
+
+// Reapplies returns the number of times the configuration was re-applied
+func (w *LinkWatcher) Reapplies() uint64 {
+	return w.reapplies.Load()
+}
+
+// transition records the device's new state and runs the hooks if the device went down,
+// came up or was recreated, reporting whether it did. The first state seen for a device
+// counts as a transition.
+func (w *LinkWatcher) transition(states map[string]*linkState, event LinkEvent) bool {
+	previous, known := states[event.Device]
+	if known && previous.up == event.Up && (!event.Up || previous.index == event.Index) {
+		return false
+	}
+	states[event.Device] = &linkState{index: event.Index, up: event.Up}
+
+	w.mu.Lock()
+	hooks := w.onDown
+	if event.Up {
+		hooks = w.onUp
+	}
+	hooks = append([]func(LinkEvent){}, hooks...)
+	w.mu.Unlock()
+
+	if event.Up {
+		w.logger.Info("Device link up",
+			logging.String("device", event.Device),
+			logging.Int("index", event.Index),
+		)
+	} else {
+		w.logger.Warn("Device link down",
+			logging.String("device", event.Device),
+			logging.Bool("removed", event.Removed),
+		)
+	}
+
+	for _, hook := range hooks {
+		hook(event)
+	}
+	return true
+}
+
+// reapplyConfiguration applies the configuration again, restoring what a recreated device
+// lost; objects still installed are left alone
+func (w *LinkWatcher) reapplyConfiguration() {
+	if err := w.controller.Apply(); err != nil {
+		w.logger.Error("Failed to re-apply traffic control configuration",
+			logging.String("device", w.devices[0]),
+			logging.Error(err),
+		)
+		return
+	}
+
+	w.reapplies.Add(1)
+	w.logger.Info("Traffic control configuration re-applied",
+		logging.String("device", w.devices[0]),
+		logging.Int("reapplies", int(w.reapplies.Load())),
+	)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestLinkWatcher_ReappliesRecreatedDevice(t *testing.T) {
+	controller, mockNetlinkAdapter := newPressureTestController(t)
+	device, _ := tc.NewDeviceName("eth0")
+
+	var mu sync.Mutex
+	var seen []LinkEvent
+	record := func(event LinkEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, event)
+	}
+	watcher := controller.NewLinkWatcher(10 * time.Millisecond).OnLinkUp(record).OnLinkDown(record)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- watcher.Run(ctx) }()
+
+	// Run subscribes asynchronously; keep bouncing the device until the first re-apply
+	require.Eventually(t, func() bool {
+		if len(mockNetlinkAdapter.GetQdiscs(device).Value()) > 0 && watcher.Reapplies() == 0 {
+			mockNetlinkAdapter.RemoveLink(device)
+			mockNetlinkAdapter.SetLinkState(device, true)
+		}
+		return watcher.Reapplies() == 1
+	}, 2*time.Second, 20*time.Millisecond)
+
+	assert.NotEmpty(t, mockNetlinkAdapter.GetQdiscs(device).Value())
+	assert.NotEmpty(t, mockNetlinkAdapter.GetClasses(device).Value())
+
+	mu.Lock()
+	require.GreaterOrEqual(t, len(seen), 2)
+	assert.True(t, seen[0].Removed)
+	assert.False(t, seen[0].Up)
+	assert.True(t, seen[1].Up)
+	assert.Equal(t, "eth0", seen[1].Device)
+	assert.NotEqual(t, seen[0].Index, seen[1].Index, "a recreated device has a new index")
+	mu.Unlock()
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestLinkWatcher_Transitions(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	watcher := controller.NewLinkWatcher(0).WithoutReapply()
+	assert.Equal(t, DefaultLinkReapplyDelay, watcher.delay)
+	assert.False(t, watcher.reapply)
+
+	var ups, downs int
+	watcher.OnLinkUp(func(LinkEvent) { ups++ }).OnLinkDown(func(LinkEvent) { downs++ })
+
+	states := make(map[string]*linkState)
+	assert.True(t, watcher.transition(states, LinkEvent{Device: "eth0", Index: 2, Up: true}))
+	assert.False(t, watcher.transition(states, LinkEvent{Device: "eth0", Index: 2, Up: true}), "unrelated link change")
+	assert.True(t, watcher.transition(states, LinkEvent{Device: "eth0", Index: 2}))
+	assert.False(t, watcher.transition(states, LinkEvent{Device: "eth0", Index: 2, Removed: true}))
+	assert.True(t, watcher.transition(states, LinkEvent{Device: "eth0", Index: 5, Up: true}))
+	assert.True(t, watcher.transition(states, LinkEvent{Device: "eth0", Index: 6, Up: true}), "recreated while up")
+	assert.Equal(t, 3, ups)
+	assert.Equal(t, 1, downs)
+}
@@ -0,0 +1,446 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// DefaultMetricsInterval is the default polling interval of a MetricsExporter
+const DefaultMetricsInterval = 15 * time.Second
+
+// metricsContentType is the Prometheus text exposition format served on /metrics
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// MetricsExporter polls the interface's qdisc, class, filter and link statistics at a fixed
+// interval and serves the latest sample in the Prometheus text format. Scrapes are answered
+// from memory, so scrape frequency does not add netlink load.
+type MetricsExporter struct {
+	controller *TrafficController
+	interval   time.Duration
+	logger     logging.Logger
+
+	mu        sync.RWMutex
+	stats     *qmodels.DeviceStatisticsView
+	sampledAt time.Time
+	lastErr   error
+}
+
+// NewMetricsExporter creates a metrics exporter for the controller's interface. A
+// non-positive interval selects DefaultMetricsInterval.
+func (controller *TrafficController) NewMetricsExporter(interval time.Duration) *MetricsExporter {
+	if interval <= 0 {
+		interval = DefaultMetricsInterval
+	}
+
+	return &MetricsExporter{
+		controller: controller,
+		interval:   interval,
+		logger:     controller.logger,
+	}
+}
+
+// Run polls statistics until the context is cancelled
+func (e *MetricsExporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	e.sample(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			e.sample(now)
+		}
+	}
+}
+
+// ListenAndServe polls statistics and serves them on /metrics at the address until the
+// context is cancelled
+func (e *MetricsExporter) ListenAndServe(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return e.Serve(ctx, listener)
+}
+
+// Serve polls statistics and serves them on /metrics of the listener until the context is
+// cancelled
+func (e *MetricsExporter) Serve(ctx context.Context, listener net.Listener) error {
+	e.logger.Info("Serving traffic control metrics",
+		logging.String("address", listener.Addr().String()),
+		logging.String("interval", e.interval.String()),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		_ = e.Run(ctx)
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
+
+// ServeHTTP writes the latest sample in the Prometheus text format
+func (e *MetricsExporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	_ = e.WriteMetrics(w)
+}
+
+// WriteMetrics writes the latest sample in the Prometheus text format
+func (e *MetricsExporter) WriteMetrics(w io.Writer) error {
+	e.mu.RLock()
+	stats, sampledAt, lastErr := e.stats, e.sampledAt, e.lastErr
+	e.mu.RUnlock()
+
+	m := newMetricWriter(w)
+	device := e.controller.deviceName
+
+	success := 0.0
+	if lastErr == nil && stats != nil {
+		success = 1
+	}
+	m.gauge("tc_scrape_success", "Whether the last statistics poll succeeded", success, "device", device)
+	if !sampledAt.IsZero() {
+		m.gauge("tc_scrape_timestamp_seconds", "Time of the last statistics poll", float64(sampledAt.UnixNano())/1e9, "device", device)
+	}
+	if stats == nil {
+		return m.err
+	}
+
+	writeQdiscMetrics(m, device, stats.QdiscStats)
+	writeClassMetrics(m, device, stats.ClassStats, e.classNames())
+	writeLeafQdiscMetrics(m, device, stats.ClassStats, e.classNames())
+	writeFilterMetrics(m, device, stats.FilterStats)
+	writeLinkMetrics(m, device, stats.LinkStats)
+	return m.err
+}
+
+// sample refreshes the statistics; a failed poll keeps the previous sample
+func (e *MetricsExporter) sample(now time.Time) {
+	stats, err := e.controller.GetRealtimeStatistics()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastErr = err
+	if err != nil {
+		e.logger.Debug("Failed to poll statistics", logging.Error(err))
+		return
+	}
+	e.stats = stats
+	e.sampledAt = now
+}
+
+// classNames maps the handles of the configured traffic classes, and of a named default
+// class, to their names
+func (e *MetricsExporter) classNames() map[string]string {
+	names := make(map[string]string, len(e.controller.classes)+1)
+	for _, class := range e.controller.classes {
+		names[classHandle(class)] = class.name
+	}
+	if e.controller.defaultClass != nil {
+		names[defaultClassHandle] = e.controller.defaultClass.name
+	}
+	return names
+}
+
+// writeQdiscMetrics writes the counters and queue gauges of every qdisc
+func writeQdiscMetrics(m *metricWriter, device string, qdiscs []qmodels.QdiscStatisticsView) {
+	families := []struct {
+		name, help string
+		counter    bool
+		value      func(qmodels.QdiscStatisticsView) float64
+	}{
+		{"tc_qdisc_sent_bytes_total", "Bytes sent by the qdisc", true, func(q qmodels.QdiscStatisticsView) float64 { return float64(q.BytesSent) }},
+		{"tc_qdisc_sent_packets_total", "Packets sent by the qdisc", true, func(q qmodels.QdiscStatisticsView) float64 { return float64(q.PacketsSent) }},
+		{"tc_qdisc_dropped_packets_total", "Packets dropped by the qdisc", true, func(q qmodels.QdiscStatisticsView) float64 { return float64(q.PacketsDropped) }},
+		{"tc_qdisc_overlimits_total", "Times the qdisc was over its limit", true, func(q qmodels.QdiscStatisticsView) float64 { return float64(q.Overlimits) }},
+		{"tc_qdisc_requeues_total", "Packets requeued by the qdisc", true, func(q qmodels.QdiscStatisticsView) float64 { return float64(q.Requeues) }},
+		{"tc_qdisc_backlog_bytes", "Bytes queued in the qdisc", false, func(q qmodels.QdiscStatisticsView) float64 { return float64(q.Backlog) }},
+		{"tc_qdisc_queue_length", "Packets queued in the qdisc", false, func(q qmodels.QdiscStatisticsView) float64 { return float64(q.QueueLength) }},
+	}
+
+	for _, family := range families {
+		m.header(family.name, family.help, family.counter)
+		for _, qdisc := range qdiscs {
+			m.sample(family.name, family.value(qdisc), "device", device, "handle", qdisc.Handle, "kind", qdisc.Type)
+		}
+	}
+}
+
+// writeClassMetrics writes the counters, backlog and rate of every class
+func writeClassMetrics(m *metricWriter, device string, classes []qmodels.ClassStatisticsView, names map[string]string) {
+	families := []struct {
+		name, help string
+		counter    bool
+		value      func(qmodels.ClassStatisticsView) float64
+	}{
+		{"tc_class_sent_bytes_total", "Bytes sent by the class", true, func(c qmodels.ClassStatisticsView) float64 { return float64(c.BytesSent) }},
+		{"tc_class_sent_packets_total", "Packets sent by the class", true, func(c qmodels.ClassStatisticsView) float64 { return float64(c.PacketsSent) }},
+		{"tc_class_dropped_packets_total", "Packets dropped by the class", true, func(c qmodels.ClassStatisticsView) float64 { return float64(c.PacketsDropped) }},
+		{"tc_class_overlimits_total", "Times the class was over its rate", true, func(c qmodels.ClassStatisticsView) float64 { return float64(c.Overlimits) }},
+		{"tc_class_backlog_bytes", "Bytes queued in the class", false, func(c qmodels.ClassStatisticsView) float64 { return float64(c.BacklogBytes) }},
+		{"tc_class_backlog_packets", "Packets queued in the class", false, func(c qmodels.ClassStatisticsView) float64 { return float64(c.BacklogPackets) }},
+		{"tc_class_rate_bytes_per_second", "Rate of the class estimated by the kernel", false, func(c qmodels.ClassStatisticsView) float64 { return float64(c.RateBPS) }},
+	}
+
+	for _, family := range families {
+		m.header(family.name, family.help, family.counter)
+		for _, class := range classes {
+			name := class.Name
+			if configured, ok := names[class.Handle]; ok {
+				name = configured
+			}
+			m.sample(family.name, family.value(class), "device", device, "handle", class.Handle, "parent", class.Parent, "class", name)
+		}
+	}
+}
+
+// writeLeafQdiscMetrics writes the counters of the qdiscs attached under classes, labelled
+// with the class they queue for, and the fq_codel, codel and sfq counters when reported.
+// Families whose statistics a leaf does not report get no series for it.
+func writeLeafQdiscMetrics(m *metricWriter, device string, classes []qmodels.ClassStatisticsView, names map[string]string) {
+	families := []struct {
+		name, help string
+		counter    bool
+		value      func(qmodels.QdiscStatisticsView) (float64, bool)
+	}{
+		{"tc_leaf_qdisc_sent_packets_total", "Packets sent by the qdisc under the class", true, func(q qmodels.QdiscStatisticsView) (float64, bool) { return float64(q.PacketsSent), true }},
+		{"tc_leaf_qdisc_dropped_packets_total", "Packets dropped by the qdisc under the class", true, func(q qmodels.QdiscStatisticsView) (float64, bool) { return float64(q.PacketsDropped), true }},
+		{"tc_leaf_qdisc_backlog_bytes", "Bytes queued in the qdisc under the class", false, func(q qmodels.QdiscStatisticsView) (float64, bool) { return float64(q.Backlog), true }},
+		{"tc_fq_codel_drop_overlimit_total", "Packets fq_codel dropped over its packet limit", true, func(q qmodels.QdiscStatisticsView) (float64, bool) {
+			if q.FQCodel == nil {
+				return 0, false
+			}
+			return float64(q.FQCodel.DropOverlimit), true
+		}},
+		{"tc_fq_codel_drop_overmemory_total", "Packets fq_codel dropped over its memory limit", true, func(q qmodels.QdiscStatisticsView) (float64, bool) {
+			if q.FQCodel == nil {
+				return 0, false
+			}
+			return float64(q.FQCodel.DropOvermemory), true
+		}},
+		{"tc_fq_codel_ecn_marks_total", "Packets fq_codel marked with ECN instead of dropping", true, func(q qmodels.QdiscStatisticsView) (float64, bool) {
+			if q.FQCodel == nil {
+				return 0, false
+			}
+			return float64(q.FQCodel.ECNMarks), true
+		}},
+		{"tc_fq_codel_ce_marks_total", "Packets fq_codel marked above its CE threshold", true, func(q qmodels.QdiscStatisticsView) (float64, bool) {
+			if q.FQCodel == nil {
+				return 0, false
+			}
+			return float64(q.FQCodel.CEMarks), true
+		}},
+		{"tc_fq_codel_new_flows_total", "Flows fq_codel has seen start", true, func(q qmodels.QdiscStatisticsView) (float64, bool) {
+			if q.FQCodel == nil {
+				return 0, false
+			}
+			return float64(q.FQCodel.NewFlowCount), true
+		}},
+		{"tc_fq_codel_active_flows", "Flows on the new and old flow lists of fq_codel", false, func(q qmodels.QdiscStatisticsView) (float64, bool) {
+			if q.FQCodel == nil {
+				return 0, false
+			}
+			return float64(q.FQCodel.NewFlows) + float64(q.FQCodel.OldFlows), true
+		}},
+		{"tc_fq_codel_memory_usage_bytes", "Memory used by the packets queued in fq_codel", false, func(q qmodels.QdiscStatisticsView) (float64, bool) {
+			if q.FQCodel == nil {
+				return 0, false
+			}
+			return float64(q.FQCodel.MemoryUsage), true
+		}},
+		{"tc_codel_queue_delay_seconds", "Queueing delay of the packet codel dequeued last", false, func(q qmodels.QdiscStatisticsView) (float64, bool) {
+			if q.Codel == nil {
+				return 0, false
+			}
+			return float64(q.Codel.LDelayUs) / 1e6, true
+		}},
+		{"tc_codel_dropping", "Whether codel is in its dropping state", false, func(q qmodels.QdiscStatisticsView) (float64, bool) {
+			if q.Codel == nil {
+				return 0, false
+			}
+			if q.Codel.Dropping {
+				return 1, true
+			}
+			return 0, true
+		}},
+		{"tc_codel_ecn_marks_total", "Packets codel marked with ECN instead of dropping", true, func(q qmodels.QdiscStatisticsView) (float64, bool) {
+			if q.Codel == nil {
+				return 0, false
+			}
+			return float64(q.Codel.ECNMarks), true
+		}},
+		{"tc_sfq_active_flows", "Flows holding packets in sfq", false, func(q qmodels.QdiscStatisticsView) (float64, bool) {
+			if q.SFQ == nil {
+				return 0, false
+			}
+			return float64(q.SFQ.ActiveFlows), true
+		}},
+	}
+
+	for _, family := range families {
+		m.header(family.name, family.help, family.counter)
+		for _, class := range classes {
+			if class.LeafQdisc == nil {
+				continue
+			}
+			value, ok := family.value(*class.LeafQdisc)
+			if !ok {
+				continue
+			}
+			name := class.Name
+			if configured, ok := names[class.Handle]; ok {
+				name = configured
+			}
+			m.sample(family.name, value, "device", device, "handle", class.LeafQdisc.Handle, "kind", class.LeafQdisc.Type, "parent", class.Handle, "class", name)
+		}
+	}
+}
+
+// writeFilterMetrics writes one info series per filter, as filters keep no counters of
+// their own
+func writeFilterMetrics(m *metricWriter, device string, filters []qmodels.FilterStatisticsView) {
+	m.header("tc_filter_info", "Installed filters, labelled with their target class", false)
+	for _, filter := range filters {
+		m.sample("tc_filter_info", 1,
+			"device", device,
+			"parent", filter.Parent,
+			"priority", fmt.Sprintf("%d", filter.Priority),
+			"protocol", filter.Protocol,
+			"handle", filter.Handle,
+			"flow_id", filter.FlowID,
+		)
+	}
+	m.header("tc_filter_matches", "Match conditions of the filter", false)
+	for _, filter := range filters {
+		m.sample("tc_filter_matches", float64(filter.MatchCount),
+			"device", device,
+			"parent", filter.Parent,
+			"priority", fmt.Sprintf("%d", filter.Priority),
+			"handle", filter.Handle,
+		)
+	}
+}
+
+// writeLinkMetrics writes the interface counters
+func writeLinkMetrics(m *metricWriter, device string, link qmodels.LinkStatisticsView) {
+	for _, direction := range []struct {
+		name, verb                      string
+		bytes, packets, errors, dropped uint64
+	}{
+		{"receive", "received", link.RxBytes, link.RxPackets, link.RxErrors, link.RxDropped},
+		{"transmit", "transmitted", link.TxBytes, link.TxPackets, link.TxErrors, link.TxDropped},
+	} {
+		prefix := "tc_link_" + direction.name
+		m.counter(prefix+"_bytes_total", "Bytes "+direction.verb+" by the interface", float64(direction.bytes), "device", device)
+		m.counter(prefix+"_packets_total", "Packets "+direction.verb+" by the interface", float64(direction.packets), "device", device)
+		m.counter(prefix+"_errors_total", "Packets the interface failed to "+direction.name, float64(direction.errors), "device", device)
+		m.counter(prefix+"_dropped_total", "Packets the interface dropped instead of "+direction.verb[:len(direction.verb)-2]+"ing", float64(direction.dropped), "device", device)
+	}
+}
+
+// metricWriter writes metric families in the Prometheus text exposition format
+type metricWriter struct {
+	w   io.Writer
+	err error
+}
+
+// newMetricWriter creates a writer; the first write error is kept and later writes skipped
+func newMetricWriter(w io.Writer) *metricWriter {
+	return &metricWriter{w: w}
+}
+
+// header writes the HELP and TYPE lines of a family
+func (m *metricWriter) header(name, help string, counter bool) {
+	kind := "gauge"
+	if counter {
+		kind = "counter"
+	}
+	m.printf("# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+}
+
+// sample writes one series; labels are name-value pairs
+func (m *metricWriter) sample(name string, value float64, labels ...string) {
+	m.printf("%s%s %g\n", name, formatLabels(labels), value)
+}
+
+// gauge writes a family holding a single gauge series
+func (m *metricWriter) gauge(name, help string, value float64, labels ...string) {
+	m.header(name, help, false)
+	m.sample(name, value, labels...)
+}
+
+// counter writes a family holding a single counter series
+func (m *metricWriter) counter(name, help string, value float64, labels ...string) {
+	m.header(name, help, true)
+	m.sample(name, value, labels...)
+}
+
+// printf writes unless an earlier write failed
+func (m *metricWriter) printf(format string, args ...interface{}) {
+	if m.err != nil {
+		return
+	}
+	_, m.err = fmt.Fprintf(m.w, format, args...)
+}
+
+// formatLabels renders name-value pairs as a label set, sorted by name and without empty
+// values, which Prometheus treats as absent labels anyway
+func formatLabels(labels []string) string {
+	type label struct{ name, value string }
+	var set []label
+	for i := 0; i+1 < len(labels); i += 2 {
+		if labels[i+1] != "" {
+			set = append(set, label{labels[i], labels[i+1]})
+		}
+	}
+	if len(set) == 0 {
+		return ""
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i].name < set[j].name })
+
+	parts := make([]string, 0, len(set))
+	for _, l := range set {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", l.name, escapeLabelValue(l.value)))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// escapeLabelValue escapes backslashes, double quotes and line feeds in a label value
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// escapeHelp escapes backslashes and line feeds in a HELP line
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
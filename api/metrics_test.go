@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+func TestMetricsExporter_WriteMetrics(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithPriority(1)
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), netlink.NewMockAdapter(), controller.logger)
+	require.NoError(t, controller.Apply())
+
+	exporter := controller.NewMetricsExporter(time.Second)
+	exporter.stats = &qmodels.DeviceStatisticsView{
+		DeviceName: "eth0",
+		QdiscStats: []qmodels.QdiscStatisticsView{{Handle: "1:0", Type: "htb", BytesSent: 1500, Backlog: 64}},
+		ClassStats: []qmodels.ClassStatisticsView{{
+			Handle: classHandle(controller.classes[0]), Parent: "1:1", BytesSent: 1000, Overlimits: 3,
+			LeafQdisc: &qmodels.QdiscStatisticsView{
+				Handle: "10:", Type: "fq_codel", PacketsDropped: 9,
+				FQCodel: &qmodels.FQCodelStatisticsView{ECNMarks: 4, CEMarks: 5, NewFlows: 1, OldFlows: 2},
+			},
+		}},
+		FilterStats: []qmodels.FilterStatisticsView{
+			{Parent: "1:0", Priority: 100, Protocol: "ip", Handle: "800:100", MatchCount: 2, FlowID: "1:11"},
+		},
+		LinkStats: qmodels.LinkStatisticsView{RxBytes: 42, TxDropped: 7},
+	}
+	exporter.sampledAt = time.Unix(1700000000, 0)
+
+	var out bytes.Buffer
+	require.NoError(t, exporter.WriteMetrics(&out))
+	text := out.String()
+
+	assert.Contains(t, text, "# TYPE tc_qdisc_sent_bytes_total counter\n")
+	assert.Contains(t, text, `tc_qdisc_sent_bytes_total{device="eth0",handle="1:0",kind="htb"} 1500`)
+	assert.Contains(t, text, "# TYPE tc_qdisc_backlog_bytes gauge\n")
+	assert.Contains(t, text, `tc_qdisc_backlog_bytes{device="eth0",handle="1:0",kind="htb"} 64`)
+	// Classes are labelled with their configured names
+	assert.Contains(t, text, `tc_class_overlimits_total{class="web",device="eth0",handle="`+classHandle(controller.classes[0])+`",parent="1:1"} 3`)
+	// Leaf qdiscs are labelled with the class they queue for
+	leafLabels := `{class="web",device="eth0",handle="10:",kind="fq_codel",parent="` + classHandle(controller.classes[0]) + `"}`
+	assert.Contains(t, text, `tc_leaf_qdisc_dropped_packets_total`+leafLabels+` 9`)
+	assert.Contains(t, text, `tc_fq_codel_ecn_marks_total`+leafLabels+` 4`)
+	assert.Contains(t, text, `tc_fq_codel_active_flows`+leafLabels+` 3`)
+	assert.Contains(t, text, `tc_fq_codel_ce_marks_total`+leafLabels+` 5`)
+	assert.NotContains(t, text, `tc_sfq_active_flows{`)
+	assert.NotContains(t, text, `tc_codel_dropping{`)
+	assert.Contains(t, text, `tc_filter_info{device="eth0",flow_id="1:11",handle="800:100",parent="1:0",priority="100",protocol="ip"} 1`)
+	assert.Contains(t, text, `tc_link_receive_bytes_total{device="eth0"} 42`)
+	assert.Contains(t, text, `tc_link_transmit_dropped_total{device="eth0"} 7`)
+	assert.Contains(t, text, `tc_scrape_success{device="eth0"} 1`)
+	assert.Contains(t, text, `tc_scrape_timestamp_seconds{device="eth0"} 1.7e+09`)
+}
+
+func TestMetricsExporter_ServeHTTP(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithPriority(1)
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), netlink.NewMockAdapter(), controller.logger)
+	require.NoError(t, controller.Apply())
+
+	exporter := controller.NewMetricsExporter(0)
+	assert.Equal(t, DefaultMetricsInterval, exporter.interval)
+
+	// Before the first poll only the scrape status is reported
+	recorder := httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, metricsContentType, recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), `tc_scrape_success{device="eth0"} 0`)
+
+	exporter.sample(time.Now())
+	recorder = httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), `tc_scrape_success{device="eth0"} 1`)
+	assert.Contains(t, recorder.Body.String(), "# TYPE tc_link_transmit_bytes_total counter")
+}
+
+func TestFormatLabels(t *testing.T) {
+	assert.Equal(t, "", formatLabels(nil))
+	// Sorted by name, empty values dropped, values escaped
+	assert.Equal(t, `{class="a\"b\\c\nd",device="eth0"}`, formatLabels([]string{"device", "eth0", "parent", "", "class", "a\"b\\c\nd"}))
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// OversubscriptionPolicy decides what happens when the guaranteed bandwidths of the classes
+// add up to more than they share: the total bandwidth of the interface for traffic classes,
+// or the rate of the parent for the classes of an HTB qdisc builder
+type OversubscriptionPolicy int
+
+const (
+	// RejectOversubscription fails validation, and Apply, with
+	// total_guaranteed_exceeds_total. This is the default.
+	RejectOversubscription OversubscriptionPolicy = iota
+	// WarnOnOversubscription applies the guarantees as given and reports a warning; under
+	// contention HTB then cannot honour them all
+	WarnOnOversubscription
+	// ScaleGuarantees scales the guarantees down proportionally until they fit beside the
+	// default class, keeping the ceilings, and reports a warning. The configured guarantees are kept and scaled anew
+	// whenever the configuration is validated.
+	ScaleGuarantees
+)
+
+// String returns the name of the policy, as used in configuration files
+func (policy OversubscriptionPolicy) String() string {
+	switch policy {
+	case WarnOnOversubscription:
+		return "warn"
+	case ScaleGuarantees:
+		return "scale"
+	}
+	return "strict"
+}
+
+// ParseOversubscriptionPolicy returns the policy of the name: "strict", "warn" or "scale";
+// an empty name is strict
+func ParseOversubscriptionPolicy(name string) (OversubscriptionPolicy, error) {
+	switch name {
+	case "", "strict":
+		return RejectOversubscription, nil
+	case "warn":
+		return WarnOnOversubscription, nil
+	case "scale":
+		return ScaleGuarantees, nil
+	}
+	return RejectOversubscription, fmt.Errorf("unknown oversubscription policy %q (expected strict, warn or scale)", name)
+}
+
+// WithOversubscriptionPolicy sets what the controller does when guarantees add up to more
+// than the bandwidth they share, e.g. WithOversubscriptionPolicy(api.ScaleGuarantees) for
+// tenant guarantees sold against a link that may shrink
+func (controller *TrafficController) WithOversubscriptionPolicy(policy OversubscriptionPolicy) *TrafficController {
+	controller.oversubscription = policy
+	return controller
+}
+
+// unscaleGuarantees restores the configured guarantees of the classes ScaleGuarantees
+// scaled down at the last validation
+func (controller *TrafficController) unscaleGuarantees() {
+	for _, class := range controller.classes {
+		if class.scaledFrom.BitsPerSecond() > 0 {
+			class.guaranteedBandwidth = class.scaledFrom
+			class.scaledFrom = tc.Bandwidth{}
+		}
+	}
+}
+
+// checkOversubscription applies the oversubscription policy to the guarantees of the
+// classes of the root qdisc and the default class, then to those of the classes nested under
+// each parent, parents first, so that the children of a scaled class fit its scaled
+// guarantee. It reports whether guarantees still exceed what they share, as the warn policy
+// leaves them.
+func (controller *TrafficController) checkOversubscription(report *ValidationReport) bool {
+	var reserved tc.Bandwidth
+	if controller.defaultClass != nil {
+		reserved = controller.defaultClassRate()
+	}
+	oversubscribed := controller.fitGuarantees(report, nil, controller.totalBandwidth, reserved)
+
+	ordered, _ := controller.classesParentsFirst()
+	for _, parent := range ordered {
+		if controller.fitGuarantees(report, parent, parent.guaranteedBandwidth, tc.Bandwidth{}) {
+			oversubscribed = true
+		}
+	}
+	return oversubscribed
+}
+
+// fitGuarantees applies the oversubscription policy to the guarantees of the classes nested
+// under the parent, or with nil those of the root qdisc, which share the capacity with the
+// reserved bandwidth. It reports whether they still exceed the capacity.
+func (controller *TrafficController) fitGuarantees(report *ValidationReport, parent *TrafficClass, capacity, reserved tc.Bandwidth) bool {
+	children := controller.childrenOf(parent)
+	if len(children) == 0 {
+		return false
+	}
+	var classesGuaranteed tc.Bandwidth
+	for _, class := range children {
+		classesGuaranteed = classesGuaranteed.Add(class.guaranteedBandwidth)
+	}
+	totalGuaranteed := classesGuaranteed.Add(reserved)
+	if !totalGuaranteed.GreaterThan(capacity) {
+		return false
+	}
+
+	code, className := "total_guaranteed_exceeds_total", ""
+	subject, shared := "total guaranteed bandwidth", fmt.Sprintf("interface bandwidth (%s)", capacity)
+	if parent != nil {
+		code, className = "children_guaranteed_exceeds_parent", parent.name
+		subject = fmt.Sprintf("guaranteed bandwidth of the classes under '%s'", parent.name)
+		shared = fmt.Sprintf("its guaranteed bandwidth (%s)", capacity)
+	}
+
+	switch controller.oversubscription {
+	case WarnOnOversubscription:
+		addWarning(report, code, className,
+			"%s (%s) exceeds %s; the guarantees cannot all be met under contention",
+			subject, totalGuaranteed, shared)
+		return true
+
+	case ScaleGuarantees:
+		// The default class keeps its rate, which contention validation counts even when
+		// unnamed
+		if parent == nil {
+			reserved = controller.defaultClassRate()
+		}
+		if reserved.LessThan(capacity) && classesGuaranteed.BitsPerSecond() > 0 {
+			available := tc.Bps(capacity.BitsPerSecond() - reserved.BitsPerSecond())
+			for _, class := range children {
+				configured := class.guaranteedBandwidth
+				class.guaranteedBandwidth = scaleBandwidth(configured, available, classesGuaranteed)
+				if configured.BitsPerSecond() > 0 && class.scaledFrom.BitsPerSecond() == 0 {
+					class.scaledFrom = configured
+				}
+			}
+			addWarning(report, "guarantees_scaled", className,
+				"%s (%s) exceeds %s; guarantees were scaled to %.1f%%",
+				subject, totalGuaranteed, shared,
+				float64(available.BitsPerSecond())/float64(classesGuaranteed.BitsPerSecond())*100)
+			return false
+		}
+	}
+
+	controller.addError(report, code, className,
+		"%s (%s) exceeds %s\n"+
+			"Suggestion: Reduce guaranteed bandwidths, increase total bandwidth or choose another oversubscription policy",
+		subject,
+		totalGuaranteed,
+		shared,
+	)
+	return false
+}
+
+// scaleBandwidth returns the bandwidth scaled by available over total, rounded down so that
+// the scaled bandwidths never add up to more than available
+func scaleBandwidth(bandwidth, available, total tc.Bandwidth) tc.Bandwidth {
+	return tc.Bps(uint64(float64(bandwidth.BitsPerSecond()) * float64(available.BitsPerSecond()) / float64(total.BitsPerSecond())))
+}
+
+// checkOversubscription applies the controller's oversubscription policy to the rates of
+// the classes sharing each parent: the interface total, when set, for the children of the
+// qdisc, and the rate of the parent class for the others. Parents are checked before their
+// children, so that the children of a scaled class fit its scaled rate.
+func (b *HTBQdiscBuilder) checkOversubscription() error {
+	rates := make([]tc.Bandwidth, len(b.classes))
+	for i, class := range b.classes {
+		rate, err := tc.ParseBandwidth(class.rate)
+		if err != nil {
+			return nil // The service rejects the rate
+		}
+		rates[i] = rate
+	}
+
+	parents := []string{b.handle}
+	for _, class := range b.classes {
+		parents = append(parents, class.handle)
+	}
+	for _, parent := range parents {
+		var capacity tc.Bandwidth
+		if sameHandle(parent, b.handle) {
+			capacity = b.controller.totalBandwidth
+		} else {
+			for i, class := range b.classes {
+				if class.handle == parent {
+					capacity = rates[i]
+				}
+			}
+		}
+		if capacity.BitsPerSecond() == 0 {
+			continue
+		}
+
+		var children []int
+		var guaranteed tc.Bandwidth
+		for i, class := range b.classes {
+			if sameHandle(class.parent, parent) {
+				children = append(children, i)
+				guaranteed = guaranteed.Add(rates[i])
+			}
+		}
+		if !guaranteed.GreaterThan(capacity) {
+			continue
+		}
+
+		switch b.controller.oversubscription {
+		case WarnOnOversubscription:
+			b.controller.logger.Warn("HTB class rates exceed their parent's",
+				logging.String("parent", parent),
+				logging.String("total_rate", guaranteed.String()),
+				logging.String("parent_rate", capacity.String()),
+			)
+		case ScaleGuarantees:
+			for _, i := range children {
+				rates[i] = scaleBandwidth(rates[i], capacity, guaranteed)
+				b.classes[i].rate = fmt.Sprintf("%dbps", rates[i].BitsPerSecond())
+			}
+		default:
+			return tcerrors.New(tcerrors.CodeBandwidthExceeded, "parent "+parent,
+				"class rates under %s add up to %s, more than its %s", parent, guaranteed, capacity).
+				WithHint("reduce the class rates or choose another oversubscription policy")
+		}
+	}
+	return nil
+}
+
+// sameHandle reports whether two handles are the same, however they are written ("1:" and
+// "1:0"); handles that do not parse are compared as written
+func sameHandle(a, b string) bool {
+	ha, errA := tc.ParseHandle(a)
+	hb, errB := tc.ParseHandle(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return ha == hb
+}
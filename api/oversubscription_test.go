@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// oversubscribedController returns a controller guaranteeing 120mbps of a 100mbps interface
+func oversubscribedController(policy OversubscriptionPolicy) *TrafficController {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter()).WithOversubscriptionPolicy(policy)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("tenant-a").WithGuaranteedBandwidth("60mbps").WithSoftLimitBandwidth("100mbps").WithPriority(1)
+	controller.CreateTrafficClass("tenant-b").WithGuaranteedBandwidth("30%").WithSoftLimitBandwidth("100mbps").WithPriority(2)
+	controller.CreateTrafficClass("tenant-c").WithGuaranteedBandwidth("30mbps").WithSoftLimitBandwidth("100mbps").WithPriority(3)
+	return controller
+}
+
+func TestOversubscriptionPolicy(t *testing.T) {
+	t.Run("strict_rejects", func(t *testing.T) {
+		controller := oversubscribedController(RejectOversubscription)
+
+		report := controller.Validate()
+		require.Len(t, report.Errors, 1)
+		assert.Equal(t, "total_guaranteed_exceeds_total", report.Errors[0].Code)
+		assert.True(t, errors.Is(controller.Apply(), tcerrors.ErrBandwidthExceeded))
+	})
+
+	t.Run("warn_applies_as_given", func(t *testing.T) {
+		controller := oversubscribedController(WarnOnOversubscription)
+
+		report := controller.Validate()
+		assert.Empty(t, report.Errors)
+		warning := findIssue(report.Warnings, "total_guaranteed_exceeds_total")
+		require.NotNil(t, warning)
+		require.NoError(t, controller.Apply())
+		assert.Equal(t, tc.MustParseBandwidth("60mbps"), controller.classes[0].guaranteedBandwidth)
+	})
+
+	t.Run("scale_fits_guarantees", func(t *testing.T) {
+		controller := oversubscribedController(ScaleGuarantees)
+
+		report := controller.Validate()
+		assert.Empty(t, report.Errors)
+		warning := findIssue(report.Warnings, "guarantees_scaled")
+		require.NotNil(t, warning)
+		assert.Contains(t, warning.Message, "82.5%")
+		require.NoError(t, controller.Apply())
+
+		guaranteed := func() []uint64 {
+			var rates []uint64
+			for _, class := range controller.classes {
+				rates = append(rates, class.guaranteedBandwidth.BitsPerSecond())
+			}
+			return rates
+		}
+		// 99mbps of guarantees and the 1mbps default class
+		assert.Equal(t, []uint64{49_500_000, 24_750_000, 24_750_000}, guaranteed())
+		assert.Equal(t, tc.MustParseBandwidth("100mbps"), controller.classes[0].maxBandwidth, "ceilings are kept")
+
+		// The configured guarantees are scaled anew, here not at all
+		controller.WithHardLimitBandwidth("200mbps")
+		require.True(t, controller.Validate().Valid())
+		assert.Equal(t, []uint64{60_000_000, 60_000_000, 30_000_000}, guaranteed())
+	})
+
+	t.Run("scale_leaves_default_class", func(t *testing.T) {
+		controller := oversubscribedController(ScaleGuarantees).WithDefaultClass("unclassified", "10mbps")
+
+		require.NoError(t, controller.Apply())
+		assert.Equal(t, uint64(45_000_000), controller.classes[0].guaranteedBandwidth.BitsPerSecond())
+	})
+}
+
+// findIssue returns the first issue of the code, or nil
+func findIssue(issues []ValidationIssue, code string) *ValidationIssue {
+	for i := range issues {
+		if issues[i].Code == code {
+			return &issues[i]
+		}
+	}
+	return nil
+}
+
+func TestOversubscriptionPolicy_Config(t *testing.T) {
+	config, err := ParseConfigFromYAML([]byte(`
+device: eth0
+bandwidth: 100mbps
+oversubscription: scale
+classes:
+  - name: tenant-a
+    guaranteed: 80mbps
+    maximum: 100mbps
+    priority: 1
+  - name: tenant-b
+    guaranteed: 80mbps
+    maximum: 100mbps
+    priority: 2
+`))
+	require.NoError(t, err)
+
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	require.NoError(t, controller.ApplyConfig(config))
+	assert.Equal(t, ScaleGuarantees, controller.oversubscription)
+	assert.Equal(t, uint64(49_500_000), controller.classes[0].guaranteedBandwidth.BitsPerSecond())
+
+	config.Oversubscription = "lenient"
+	report := ValidateConfig(config)
+	require.Len(t, report.Errors, 1)
+	assert.Contains(t, report.Errors[0].Message, `unknown oversubscription policy "lenient"`)
+}
+
+func TestHTBQdiscBuilder_Oversubscription(t *testing.T) {
+	build := func(policy OversubscriptionPolicy) (*HTBQdiscBuilder, error) {
+		controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter()).WithOversubscriptionPolicy(policy)
+		controller.WithHardLimitBandwidth("100mbps")
+		builder := controller.CreateHTBQdisc("1:", "1:30").
+			AddClass("1:", "1:1", "tenants", "80mbps", "100mbps").
+			AddClass("1:0", "1:2", "infra", "40mbps", "100mbps").
+			AddClass("1:1", "1:10", "tenant-a", "60mbps", "80mbps").
+			AddClass("1:1", "1:11", "tenant-b", "40mbps", "80mbps")
+		return builder, builder.Apply()
+	}
+
+	_, err := build(RejectOversubscription)
+	assert.True(t, errors.Is(err, tcerrors.ErrBandwidthExceeded))
+
+	builder, err := build(WarnOnOversubscription)
+	require.NoError(t, err)
+	assert.Equal(t, "80mbps", builder.classes[0].rate)
+
+	// 1: is 120mbps of 100mbps, then 1:1 is 100mbps of its scaled 66.6mbps
+	builder, err = build(ScaleGuarantees)
+	require.NoError(t, err)
+	var rates []string
+	for _, class := range builder.classes {
+		rates = append(rates, class.rate)
+	}
+	assert.Equal(t, []string{"66666666bps", "33333333bps", "39999999bps", "26666666bps"}, rates)
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"context"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+// Plan validates the configuration and returns it fully resolved, without touching the
+// device: the handles generated for classes, the rates, bursts, cburst and quantum the
+// library computes, the HTB priority each class is scheduled with, the band of each packet
+// priority in PRIO qdiscs and the priority of each filter. Under the Coexistence policy it
+// also lists the objects of other tools on the device, which Apply leaves in place.
+func (controller *TrafficController) Plan() (*qmodels.PlanView, error) {
+	controller.finalizePendingClasses()
+
+	if err := controller.validate(); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	planner, err := controller.planner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := planner.PlanView(ctx, controller.deviceName)
+	if err != nil || controller.ownership != Coexistence {
+		return view, err
+	}
+
+	if view.Foreign, err = controller.service.ForeignObjects(ctx, controller.deviceName); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
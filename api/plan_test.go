@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestTrafficController_Plan(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithSoftLimitBandwidth("60mbps").
+		WithPriority(1).
+		ForPort(80)
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+
+	require.Len(t, plan.Qdiscs, 1)
+	assert.Equal(t, "htb", plan.Qdiscs[0].Type)
+	assert.Equal(t, "1:999", plan.Qdiscs[0].Parameters["default_class"])
+
+	require.Len(t, plan.Classes, 2)
+	web := plan.Classes[0]
+	assert.Equal(t, "web", web.Name)
+	assert.Equal(t, "1:11", web.Handle)
+	assert.Equal(t, "30.0Mbps", web.Rate)
+	assert.Equal(t, 1, web.Priority)
+	// The class priority is not passed to HTB, which serves all classes at prio 0
+	assert.Equal(t, uint32(0), web.HTBPrio)
+	// 64ms at the rate and ceil plus 4 bytes of overhead per 1500-byte packet, and the
+	// bytes sent at the rate in a millisecond
+	assert.Equal(t, uint32(240640), web.Burst)
+	assert.Equal(t, uint32(481280), web.Cburst)
+	assert.Equal(t, uint32(3750), web.Quantum)
+	assert.Equal(t, "1:999", plan.Classes[1].Handle)
+
+	require.Len(t, plan.Filters, 1)
+	assert.Equal(t, uint16(100), plan.Filters[0].Priority)
+	assert.Equal(t, "1:11", plan.Filters[0].FlowID)
+	assert.Equal(t, "web", plan.Filters[0].Class)
+	assert.Equal(t, []string{"ip dport 80 0xffff"}, plan.Filters[0].Matches)
+
+	// Planning leaves the device untouched
+	assert.Empty(t, mockNetlinkAdapter.GetQdiscs(tc.MustNewDeviceName("eth0")).Value())
+
+	_, err = json.Marshal(plan)
+	assert.NoError(t, err)
+}
+
+func TestTrafficController_PlanInvalid(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("30mbps").WithPriority(1)
+
+	_, err := controller.Plan()
+	assert.Error(t, err)
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// DefaultPressureInterval is the default sampling interval of a PressureMonitor
+const DefaultPressureInterval = 250 * time.Millisecond
+
+// ClassPressure reports how close a traffic class is to its ceiling, so that
+// applications can shed load before the kernel starts dropping their packets
+type ClassPressure struct {
+	Class       string    `json:"class"`
+	Handle      string    `json:"handle"`
+	RateBPS     uint64    `json:"rate_bps"`
+	CeilBPS     uint64    `json:"ceil_bps"`
+	Utilization float64   `json:"utilization"` // Percentage of the ceiling in use
+	Drops       uint64    `json:"drops"`       // Packets dropped since the previous sample
+	Overlimits  uint64    `json:"overlimits"`  // Overlimit events since the previous sample
+	Backlog     uint64    `json:"backlog"`     // Bytes queued in the class
+	SampledAt   time.Time `json:"sampled_at"`
+}
+
+// Above reports whether the class uses more than the given percentage of its ceiling
+func (p ClassPressure) Above(percent float64) bool {
+	return p.Utilization > percent
+}
+
+// Dropping reports whether the class dropped traffic since the previous sample
+func (p ClassPressure) Dropping() bool {
+	return p.Drops > 0
+}
+
+// PressureMonitor samples class statistics at a fixed interval and keeps the latest
+// pressure of every class, so queries are answered from memory without touching netlink
+type PressureMonitor struct {
+	controller *TrafficController
+	interval   time.Duration
+	logger     logging.Logger
+
+	mu       sync.RWMutex
+	pressure map[string]ClassPressure
+	previous map[string]*qmodels.ClassStatisticsView
+	watches  map[string][]*classWatch // class -> threshold watches
+}
+
+// NewPressureMonitor creates a pressure monitor for the controller's traffic classes.
+// A non-positive interval selects DefaultPressureInterval.
+func (controller *TrafficController) NewPressureMonitor(interval time.Duration) *PressureMonitor {
+	if interval <= 0 {
+		interval = DefaultPressureInterval
+	}
+
+	return &PressureMonitor{
+		controller: controller,
+		interval:   interval,
+		logger:     controller.logger,
+		pressure:   make(map[string]ClassPressure),
+		previous:   make(map[string]*qmodels.ClassStatisticsView),
+		watches:    make(map[string][]*classWatch),
+	}
+}
+
+// Run samples class statistics until the context is cancelled
+func (m *PressureMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	m.sample(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			m.sample(now)
+		}
+	}
+}
+
+// Pressure returns the latest pressure of the named traffic class
+func (m *PressureMonitor) Pressure(class string) (ClassPressure, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	pressure, ok := m.pressure[class]
+	return pressure, ok
+}
+
+// sampledClass is what a sample needs of a configured class, read under the controller's lock
+type sampledClass struct {
+	name, handle string
+	ceilBPS      uint64
+}
+
+// sample refreshes the pressure of every configured traffic class
+func (m *PressureMonitor) sample(now time.Time) {
+	// The device and classes are copied under the controller's lock, as an apply from
+	// another goroutine may replace them, and released before reading the statistics
+	m.controller.mu.Lock()
+	device := m.controller.deviceName
+	classes := make([]sampledClass, 0, len(m.controller.classes))
+	for _, class := range m.controller.classes {
+		classes = append(classes, sampledClass{class.name, classHandle(class), class.maxBandwidth.BitsPerSecond()})
+	}
+	m.controller.mu.Unlock()
+
+	for _, class := range classes {
+		handle := class.handle
+
+		stats, err := m.controller.service.GetClassStatistics(context.Background(), device, handle)
+		if err != nil {
+			m.logger.Debug("Failed to sample class statistics",
+				logging.String("class_name", class.name),
+				logging.String("class_id", handle),
+				logging.Error(err),
+			)
+			continue
+		}
+
+		m.mu.Lock()
+		previous := m.previous[class.name]
+		last, hasLast := m.pressure[class.name]
+
+		pressure := ClassPressure{
+			Class:     class.name,
+			Handle:    handle,
+			RateBPS:   stats.RateBPS,
+			CeilBPS:   class.ceilBPS,
+			Backlog:   stats.BacklogBytes,
+			SampledAt: now,
+		}
+		if previous != nil && hasLast {
+			// Derive the rate from byte counters; the kernel rate estimator is often disabled
+			if elapsed := now.Sub(last.SampledAt).Seconds(); elapsed > 0 && stats.BytesSent >= previous.BytesSent {
+				pressure.RateBPS = uint64(float64(stats.BytesSent-previous.BytesSent) * 8 / elapsed)
+			}
+			pressure.Drops = counterDelta(stats.PacketsDropped, previous.PacketsDropped)
+			pressure.Overlimits = counterDelta(stats.Overlimits, previous.Overlimits)
+		}
+		if pressure.CeilBPS > 0 {
+			pressure.Utilization = float64(pressure.RateBPS) / float64(pressure.CeilBPS) * 100
+		}
+
+		m.pressure[class.name] = pressure
+		m.previous[class.name] = stats
+		crossings := m.evaluateWatches(pressure)
+		m.mu.Unlock()
+
+		// Callbacks run unlocked so that they can query the monitor
+		for _, crossing := range crossings {
+			crossing.callback(crossing.event)
+		}
+	}
+}
+
+// counterDelta returns the increase of a kernel counter, treating a reset as no increase
+func counterDelta(current, previous uint64) uint64 {
+	if current < previous {
+		return 0
+	}
+	return current - previous
+}
+
+// pressureResponse is the reply written to unix socket clients
+type pressureResponse struct {
+	*ClassPressure
+	AboveThreshold bool   `json:"above_threshold"`
+	Dropping       bool   `json:"dropping"`
+	Error          string `json:"error,omitempty"`
+}
+
+// ServeUnix answers pressure queries on a unix socket until the context is cancelled.
+// Each request is a single line "<class> [threshold-percent]" and each reply a single
+// JSON line; the threshold defaults to 100 (the class is at its ceiling).
+func (m *PressureMonitor) ServeUnix(ctx context.Context, socketPath string) error {
+	m.logger.Info("Serving class pressure",
+		logging.String("socket", socketPath),
+		logging.String("interval", m.interval.String()),
+	)
+
+	return serveUnix(ctx, socketPath, func(_ context.Context, request []byte) interface{} {
+		return m.answer(string(request))
+	})
+}
+
+// answer builds the reply to a single request line
+func (m *PressureMonitor) answer(request string) pressureResponse {
+	fields := strings.Fields(request)
+	if len(fields) == 0 || len(fields) > 2 {
+		return pressureResponse{Error: "expected request \"<class> [threshold-percent]\""}
+	}
+
+	threshold := 100.0
+	if len(fields) == 2 {
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || value < 0 {
+			return pressureResponse{Error: fmt.Sprintf("invalid threshold %q", fields[1])}
+		}
+		threshold = value
+	}
+
+	pressure, ok := m.Pressure(fields[0])
+	if !ok {
+		return pressureResponse{Error: fmt.Sprintf("no pressure sample for class %q", fields[0])}
+	}
+
+	return pressureResponse{
+		ClassPressure:  &pressure,
+		AboveThreshold: pressure.Above(threshold),
+		Dropping:       pressure.Dropping(),
+	}
+}
@@ -0,0 +1,65 @@
+//go:build linux
+// +build linux
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	nl "github.com/vishvananda/netlink"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// TestPressureMonitor_KernelDrops feeds the monitor the statistics as the netlink backend
+// maps them from the kernel, so that drop thresholds are exercised with the counters the
+// kernel actually reports and not only with ones set on the mock
+func TestPressureMonitor_KernelDrops(t *testing.T) {
+	controller, mockNetlinkAdapter := newPressureTestController(t)
+	device, _ := tc.NewDeviceName("eth0")
+	handle := tc.NewHandle(1, 0x11)
+	monitor := controller.NewPressureMonitor(time.Hour)
+
+	var events []ThresholdEvent
+	stop, err := monitor.WatchClass("video", ClassThresholds{Drops: 10}, func(event ThresholdEvent) {
+		events = append(events, event)
+	})
+	require.NoError(t, err)
+	defer stop()
+
+	start := time.Now()
+	sampleAt := func(second int, bytesSent uint64, drops, overlimits uint32) {
+		stats := netlink.ClassStatsFromKernel(&nl.ClassStatistics{
+			Basic: &nl.GnetStatsBasic{Bytes: bytesSent},
+			Queue: &nl.GnetStatsQueue{Drops: drops, Overlimits: overlimits},
+		})
+		mockNetlinkAdapter.SetClassStatistics(device, handle, stats)
+		monitor.sample(start.Add(time.Duration(second) * time.Second))
+	}
+
+	sampleAt(0, 0, 0, 0)
+	assert.Empty(t, events)
+
+	sampleAt(1, 1000000, 25, 300)
+	pressure, ok := monitor.Pressure("video")
+	require.True(t, ok)
+	assert.True(t, pressure.Dropping())
+	assert.Equal(t, uint64(25), pressure.Drops)
+	assert.Equal(t, uint64(300), pressure.Overlimits)
+	require.Len(t, events, 1)
+	assert.Equal(t, MetricDrops, events[0].Metric)
+	assert.True(t, events[0].Exceeded)
+	assert.Equal(t, uint64(25), events[0].Value)
+
+	// The counters stop growing once the class is no longer dropping
+	sampleAt(2, 2000000, 25, 300)
+	pressure, _ = monitor.Pressure("video")
+	assert.False(t, pressure.Dropping())
+	require.Len(t, events, 2)
+	assert.Equal(t, MetricDrops, events[1].Metric)
+	assert.False(t, events[1].Exceeded)
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func newPressureTestController(t *testing.T) (*TrafficController, *netlink.MockAdapter) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("video").
+		WithGuaranteedBandwidth("10mbps").
+		WithSoftLimitBandwidth("20mbps").
+		WithPriority(1)
+
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+	require.NoError(t, controller.Apply())
+
+	return controller, mockNetlinkAdapter
+}
+
+func TestPressureMonitor_Sample(t *testing.T) {
+	controller, mockNetlinkAdapter := newPressureTestController(t)
+	device, _ := tc.NewDeviceName("eth0")
+	handle := tc.NewHandle(1, 0x11)
+
+	monitor := controller.NewPressureMonitor(0)
+	assert.Equal(t, DefaultPressureInterval, monitor.interval)
+
+	start := time.Now()
+	mockNetlinkAdapter.SetClassStatistics(device, handle, netlink.ClassStats{BytesSent: 1000000})
+	monitor.sample(start)
+
+	pressure, ok := monitor.Pressure("video")
+	require.True(t, ok)
+	assert.Equal(t, "1:11", pressure.Handle)
+	assert.Equal(t, uint64(20000000), pressure.CeilBPS)
+	assert.False(t, pressure.Dropping())
+
+	// 2.25MB in one second is 18Mbps, 90% of the 20Mbps ceiling
+	mockNetlinkAdapter.SetClassStatistics(device, handle, netlink.ClassStats{
+		BytesSent:      3250000,
+		PacketsDropped: 1500,
+		Overlimits:     12,
+	})
+	monitor.sample(start.Add(time.Second))
+
+	pressure, ok = monitor.Pressure("video")
+	require.True(t, ok)
+	assert.Equal(t, uint64(18000000), pressure.RateBPS)
+	assert.InDelta(t, 90.0, pressure.Utilization, 0.001)
+	assert.True(t, pressure.Above(80))
+	assert.False(t, pressure.Above(95))
+	assert.Equal(t, uint64(1500), pressure.Drops)
+	assert.Equal(t, uint64(12), pressure.Overlimits)
+	assert.True(t, pressure.Dropping())
+
+	_, ok = monitor.Pressure("unknown")
+	assert.False(t, ok)
+}
+
+func TestPressureMonitor_ServeUnix(t *testing.T) {
+	controller, mockNetlinkAdapter := newPressureTestController(t)
+	device, _ := tc.NewDeviceName("eth0")
+	handle := tc.NewHandle(1, 0x11)
+
+	monitor := controller.NewPressureMonitor(time.Hour)
+	start := time.Now()
+	mockNetlinkAdapter.SetClassStatistics(device, handle, netlink.ClassStats{})
+	monitor.sample(start)
+	mockNetlinkAdapter.SetClassStatistics(device, handle, netlink.ClassStats{BytesSent: 2000000, PacketsDropped: 64})
+	monitor.sample(start.Add(time.Second))
+
+	socketPath := filepath.Join(t.TempDir(), "pressure.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- monitor.ServeUnix(ctx, socketPath) }()
+
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		var err error
+		conn, err = net.Dial("unix", socketPath)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	defer func() { _ = conn.Close() }()
+
+	reader := bufio.NewReader(conn)
+	query := func(request string) map[string]interface{} {
+		_, err := conn.Write([]byte(request + "\n"))
+		require.NoError(t, err)
+		line, err := reader.ReadBytes('\n')
+		require.NoError(t, err)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &response))
+		return response
+	}
+
+	t.Run("above_threshold", func(t *testing.T) {
+		response := query("video 50")
+		assert.Equal(t, "video", response["class"])
+		assert.Equal(t, true, response["above_threshold"])
+		assert.Equal(t, true, response["dropping"])
+		assert.NotContains(t, response, "error")
+	})
+
+	t.Run("below_default_threshold", func(t *testing.T) {
+		response := query("video")
+		assert.Equal(t, false, response["above_threshold"])
+	})
+
+	t.Run("unknown_class", func(t *testing.T) {
+		response := query("unknown")
+		assert.Contains(t, response["error"], "unknown")
+		assert.NotContains(t, response, "class")
+	})
+
+	t.Run("invalid_threshold", func(t *testing.T) {
+		response := query("video high")
+		assert.Contains(t, response["error"], "invalid threshold")
+	})
+
+	cancel()
+	select {
+	case err := <-served:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("ServeUnix did not stop after cancellation")
+	}
+}
+
+func TestPressureMonitor_ConcurrentReplaceConfig(t *testing.T) {
+	controller, _ := newPressureTestController(t)
+	monitor := controller.NewPressureMonitor(time.Hour)
+	config, err := ParseConfigFromYAML([]byte(`
+device: eth0
+bandwidth: 100mbps
+classes:
+  - name: video
+    guaranteed: 10mbps
+    maximum: 20mbps
+    priority: 1
+`))
+	require.NoError(t, err)
+
+	// The monitor samples while the configuration is replaced; run with -race
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for second := 0; second < 10; second++ {
+			monitor.sample(start.Add(time.Duration(second) * time.Second))
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, controller.ReplaceConfig(config))
+	}
+	<-done
+
+	pressure, ok := monitor.Pressure("video")
+	require.True(t, ok)
+	assert.Equal(t, uint64(20000000), pressure.CeilBPS)
+}
+
+func TestPressureMonitor_WatchClass(t *testing.T) {
+	controller, mockNetlinkAdapter := newPressureTestController(t)
+	device, _ := tc.NewDeviceName("eth0")
+	handle := tc.NewHandle(1, 0x11)
+	monitor := controller.NewPressureMonitor(time.Hour)
+
+	var events []ThresholdEvent
+	stop, err := monitor.WatchClass("video", ClassThresholds{RateBPS: 10000000, Backlog: 50000}, func(event ThresholdEvent) {
+		events = append(events, event)
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	sampleAt := func(second int, bytesSent, backlog uint64) {
+		mockNetlinkAdapter.SetClassStatistics(device, handle, netlink.ClassStats{BytesSent: bytesSent, BacklogBytes: backlog})
+		monitor.sample(start.Add(time.Duration(second) * time.Second))
+	}
+
+	sampleAt(0, 0, 0)
+	assert.Empty(t, events)
+
+	// 12Mbps crosses the 10Mbps threshold
+	sampleAt(1, 1500000, 0)
+	require.Len(t, events, 1)
+	assert.Equal(t, MetricRate, events[0].Metric)
+	assert.True(t, events[0].Exceeded)
+	assert.Equal(t, uint64(12000000), events[0].Value)
+
+	// 9.6Mbps is below the threshold but within the 10% hysteresis
+	sampleAt(2, 2700000, 60000)
+	require.Len(t, events, 2)
+	assert.Equal(t, MetricBacklog, events[1].Metric)
+	assert.True(t, events[1].Exceeded)
+
+	// 4Mbps clears the rate threshold
+	sampleAt(3, 3200000, 60000)
+	require.Len(t, events, 3)
+	assert.Equal(t, MetricRate, events[2].Metric)
+	assert.False(t, events[2].Exceeded)
+
+	stop()
+	sampleAt(4, 3200000, 0)
+	assert.Len(t, events, 3)
+
+	t.Run("rejects_invalid_watches", func(t *testing.T) {
+		callback := func(ThresholdEvent) {}
+		_, err := monitor.WatchClass("unknown", ClassThresholds{RateBPS: 1}, callback)
+		assert.Error(t, err)
+		_, err = monitor.WatchClass("video", ClassThresholds{}, callback)
+		assert.Error(t, err)
+		_, err = monitor.WatchClass("video", ClassThresholds{RateBPS: 1, Hysteresis: 100}, callback)
+		assert.Error(t, err)
+		_, err = monitor.WatchClass("video", ClassThresholds{RateBPS: 1}, nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"context"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// CheckPrivileges checks that the process may change the interface's traffic control
+// configuration. Changes need CAP_NET_ADMIN, which root usually holds but a service or
+// container may have dropped; without it the error is a tcerrors.ErrPermissionDenied whose
+// hint tells how to grant it in the process's situation. Apply, ReplaceConfig, Delete and
+// Reset run this check first. Statistics, ReadCurrentConfiguration and DryRun only read,
+// and need no privilege.
+func (controller *TrafficController) CheckPrivileges() error {
+	return controller.service.CheckPrivileges(context.Background())
+}
+
+// checkPrivileges runs the privilege check before a change and logs a refusal
+func (controller *TrafficController) checkPrivileges(ctx context.Context) error {
+	if err := controller.service.CheckPrivileges(ctx); err != nil {
+		controller.logger.Error("Missing privileges to change traffic control",
+			logging.String("device", controller.deviceName),
+			logging.Error(err),
+		)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+func TestTrafficController_CheckPrivileges(t *testing.T) {
+	device := tc.MustNewDeviceName("eth0")
+
+	t.Run("refuses_changes_before_touching_the_device", func(t *testing.T) {
+		adapter := netlink.NewMockAdapter()
+		adapter.SetPrivileges(netlink.Privileges{Known: true, EffectiveUID: 1000})
+		controller := NetworkInterface("eth0").WithNetlinkAdapter(adapter)
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("web").WithGuaranteedBandwidth("30mbps").WithPriority(1)
+
+		err := controller.Apply()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, tcerrors.ErrPermissionDenied))
+		assert.Contains(t, tcerrors.HintOf(err), "setcap cap_net_admin+ep")
+		assert.Empty(t, adapter.GetQdiscs(device).Value())
+
+		_, err = controller.Reset()
+		assert.True(t, errors.Is(err, tcerrors.ErrPermissionDenied))
+
+		commands, err := controller.DryRun()
+		require.NoError(t, err, "dry runs only read")
+		assert.NotEmpty(t, commands)
+	})
+
+	t.Run("reads_statistics_unprivileged", func(t *testing.T) {
+		_, adapter := shapedController(t)
+		adapter.SetClassStatistics(device, tc.NewHandle(1, 0x11), netlink.ClassStats{BytesSent: 1500, PacketsSent: 1})
+		adapter.SetPrivileges(netlink.Privileges{Known: true, ProcSysReadOnly: true})
+
+		// A separate monitor, without the history of the process that configured the device
+		monitor := NetworkInterface("eth0").WithNetlinkAdapter(adapter)
+		assert.Contains(t, tcerrors.HintOf(monitor.CheckPrivileges()), "--cap-add=NET_ADMIN")
+
+		stats, err := monitor.GetStatistics()
+		require.NoError(t, err)
+		var web bool
+		for _, class := range stats.ClassStats {
+			if class.Handle == "1:11" {
+				web = true
+				assert.Equal(t, uint64(1500), class.BytesSent)
+			}
+		}
+		assert.True(t, web, "classes: %+v", stats.ClassStats)
+	})
+
+	t.Run("allows_changes_with_net_admin", func(t *testing.T) {
+		adapter := netlink.NewMockAdapter()
+		adapter.SetPrivileges(netlink.Privileges{Known: true, NetAdmin: true, ProcSysReadOnly: true})
+		assert.NoError(t, NetworkInterface("eth0").WithNetlinkAdapter(adapter).CheckPrivileges())
+	})
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// The configuration history records every class under the name it was created with, so it
+// doubles as the registry resolving class names to handles. Kept with PersistHistory, the
+// registry survives restarts: a new process resolves the names of the classes applied by the
+// previous one before it has configured anything.
+
+// ClassHandleByName returns the handle of the class applied under the name, e.g. "1:11"
+func (controller *TrafficController) ClassHandleByName(name string) (string, error) {
+	class, err := controller.GetClassByName(name)
+	if err != nil {
+		return "", err
+	}
+	return class.Handle, nil
+}
+
+// GetClassByName returns the class applied under the name, as recorded in the configuration
+// history. It fails with tcerrors.ErrNotFound when no class of that name was applied.
+func (controller *TrafficController) GetClassByName(name string) (*qmodels.ClassView, error) {
+	config, err := controller.service.GetConfiguration(context.Background(), controller.deviceName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range config.Classes {
+		if config.Classes[i].Name == name {
+			return &config.Classes[i], nil
+		}
+	}
+	return nil, tcerrors.New(tcerrors.CodeNotFound, "class "+name,
+		"no class named '%s' was applied to %s", name, controller.deviceName,
+	).WithHint("apply the configuration first, or open its history with PersistHistory after a restart")
+}
+
+// GetClassStatisticsByName returns the statistics of the class applied under the name
+func (controller *TrafficController) GetClassStatisticsByName(name string) (*qmodels.ClassStatisticsView, error) {
+	handle, err := controller.ClassHandleByName(name)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := controller.GetClassStatistics(handle)
+	if err != nil {
+		return nil, err
+	}
+	stats.Name = name
+	return stats, nil
+}
+
+// UpdateClassByName changes the configured class of that name through its builder and applies
+// the configuration, so that only the class is replaced on the device:
+//
+//	controller.UpdateClassByName("web", func(class *api.TrafficClassBuilder) {
+//		class.WithSoftLimitBandwidth("80mbps")
+//	})
+//
+// The class is left as it was when applying fails. A restarted process declares its
+// configuration again before updating a class of it.
+func (controller *TrafficController) UpdateClassByName(name string, update func(*TrafficClassBuilder)) error {
+	controller.finalizePendingClasses()
+	for _, class := range controller.classes {
+		if class.name == name {
+			previous := *class
+			update(&TrafficClassBuilder{controller: controller, class: class, finalized: true})
+			if err := controller.Apply(); err != nil {
+				*class = previous
+				return err
+			}
+			return nil
+		}
+	}
+
+	err := tcerrors.New(tcerrors.CodeNotFound, "class "+name,
+		"class '%s' is not configured on %s", name, controller.deviceName)
+	if handle, lookupErr := controller.ClassHandleByName(name); lookupErr == nil {
+		err.Message += fmt.Sprintf(", although it was applied as %s", handle)
+		err.Hint = "declare the configuration the class was applied with before updating it"
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+func TestClassRegistry_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	mock := netlink.NewMockAdapter()
+
+	first := NetworkInterface("eth0").WithNetlinkAdapter(mock)
+	require.NoError(t, first.PersistHistory(path))
+	first.WithHardLimitBandwidth("100mbps")
+	first.CreateTrafficClass("work-laptop").WithGuaranteedBandwidth("30mbps").WithPriority(1).ForPort(443)
+	first.CreateTrafficClass("backup").WithGuaranteedBandwidth("10mbps").WithPriority(1).ForPort(873)
+	require.NoError(t, first.Apply())
+	require.NoError(t, first.CloseHistory())
+
+	// A new process with nothing configured resolves the names through the history
+	second := NetworkInterface("eth0").WithNetlinkAdapter(mock)
+	require.NoError(t, second.PersistHistory(path))
+	defer second.CloseHistory()
+
+	handle, err := second.ClassHandleByName("backup")
+	require.NoError(t, err)
+	assert.Equal(t, "1:12", handle)
+
+	class, err := second.GetClassByName("work-laptop")
+	require.NoError(t, err)
+	assert.Equal(t, "1:11", class.Handle)
+
+	device, _ := tc.NewDeviceName("eth0")
+	mock.SetClassStatistics(device, tc.NewHandle(1, 0x11), netlink.ClassStats{BytesSent: 4096})
+	stats, err := second.GetClassStatisticsByName("work-laptop")
+	require.NoError(t, err)
+	assert.Equal(t, "work-laptop", stats.Name)
+	assert.Equal(t, uint64(4096), stats.BytesSent)
+
+	_, err = second.GetClassByName("gaming")
+	assert.True(t, errors.Is(err, tcerrors.ErrNotFound))
+
+	// Updating needs the configuration, which the new process has not declared
+	err = second.UpdateClassByName("backup", func(class *TrafficClassBuilder) {})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, tcerrors.ErrNotFound))
+	assert.Contains(t, err.Error(), "although it was applied as 1:12")
+}
+
+func TestUpdateClassByName(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("30mbps").WithPriority(1).ForPort(80)
+	controller.CreateTrafficClass("ssh").WithGuaranteedBandwidth("10mbps").WithPriority(0).ForPort(22)
+	require.NoError(t, controller.Apply())
+
+	err := controller.UpdateClassByName("web", func(class *TrafficClassBuilder) {
+		class.WithSoftLimitBandwidth("80mbps")
+	})
+	require.NoError(t, err)
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	assert.Equal(t, "80.0Mbps", plan.Classes[0].Ceil)
+
+	// An update that does not validate leaves the class as it was
+	err = controller.UpdateClassByName("web", func(class *TrafficClassBuilder) {
+		class.WithGuaranteedBandwidth("200mbps")
+	})
+	require.Error(t, err)
+	assert.Equal(t, "30.0Mbps", controller.classes[0].guaranteedBandwidth.String())
+	assert.NoError(t, controller.Validate().Err())
+
+	err = controller.UpdateClassByName("gaming", func(class *TrafficClassBuilder) {})
+	assert.True(t, errors.Is(err, tcerrors.ErrNotFound))
+}
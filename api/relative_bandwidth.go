@@ -0,0 +1,76 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// relativeToGuaranteed is the suffix of a ceiling given as a multiple of the guarantee,
+// e.g. "2x guaranteed"
+const relativeToGuaranteed = "x guaranteed"
+
+// isRelativeBandwidth reports whether a bandwidth is given as a share of the total, like
+// "30%", or as a multiple of the class guarantee, like "2x guaranteed"
+func isRelativeBandwidth(spec string) bool {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	return strings.HasSuffix(spec, "%") || strings.HasSuffix(spec, relativeToGuaranteed)
+}
+
+// resolveBandwidth parses an absolute bandwidth, or a percentage such as "10%" of the total
+func resolveBandwidth(spec string, total tc.Bandwidth) (tc.Bandwidth, error) {
+	spec = strings.TrimSpace(spec)
+	if percent, ok := strings.CutSuffix(spec, "%"); ok {
+		value, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+		if err != nil || value < 0 || value > 100 {
+			return tc.Bandwidth{}, fmt.Errorf("percentage %q must be between 0%% and 100%%", spec)
+		}
+		return total.Percentage(value), nil
+	}
+	return tc.ParseBandwidth(spec)
+}
+
+// resolveCeiling parses a class ceiling: absolute, a percentage of the total, or a multiple
+// of the class guarantee such as "2x guaranteed"
+func resolveCeiling(spec string, total, guaranteed tc.Bandwidth) (tc.Bandwidth, error) {
+	lower := strings.ToLower(strings.TrimSpace(spec))
+	factor, ok := strings.CutSuffix(lower, relativeToGuaranteed)
+	if !ok {
+		return resolveBandwidth(spec, total)
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(factor), 64)
+	if err != nil || value < 1 {
+		return tc.Bandwidth{}, fmt.Errorf("%q must be at least 1x the guaranteed bandwidth", spec)
+	}
+	return guaranteed.MultiplyBy(value), nil
+}
+
+// resolveRelativeBandwidths computes the bandwidths of classes given relative to the total
+// or to their guarantee. It runs on every validation, so that the classes follow a total
+// changed between two applies.
+func (controller *TrafficController) resolveRelativeBandwidths(report *ValidationReport) {
+	for _, class := range controller.classes {
+		if class.guaranteedSpec != "" {
+			guaranteed, err := resolveBandwidth(class.guaranteedSpec, controller.totalBandwidth)
+			if err != nil {
+				controller.addError(report, "invalid_bandwidth", class.name,
+					"class '%s' has an invalid guaranteed bandwidth: %v", class.name, err)
+				continue
+			}
+			class.guaranteedBandwidth = guaranteed
+		}
+
+		if class.maxSpec != "" {
+			ceiling, err := resolveCeiling(class.maxSpec, controller.totalBandwidth, class.guaranteedBandwidth)
+			if err != nil {
+				controller.addError(report, "invalid_bandwidth", class.name,
+					"class '%s' has an invalid max bandwidth: %v", class.name, err)
+				continue
+			}
+			class.maxBandwidth = ceiling
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+)
+
+func TestTrafficController_RelativeBandwidths(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30%").
+		WithSoftLimitBandwidth("2x guaranteed").
+		WithPriority(1).
+		ForPort(80)
+	controller.CreateTrafficClass("bulk").
+		WithGuaranteedBandwidth("10mbps").
+		WithSoftLimitBandwidth("50%").
+		WithPriority(6).
+		ForPort(8080)
+
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	require.Len(t, plan.Classes, 3)
+	assert.Equal(t, "30.0Mbps", plan.Classes[0].Rate)
+	assert.Equal(t, "60.0Mbps", plan.Classes[0].Ceil)
+	assert.Equal(t, "10.0Mbps", plan.Classes[1].Rate)
+	assert.Equal(t, "50.0Mbps", plan.Classes[1].Ceil)
+
+	// Changing the total recomputes the relative bandwidths
+	controller.WithHardLimitBandwidth("1gbps")
+	plan, err = controller.Plan()
+	require.NoError(t, err)
+	assert.Equal(t, "300.0Mbps", plan.Classes[0].Rate)
+	assert.Equal(t, "600.0Mbps", plan.Classes[0].Ceil)
+	assert.Equal(t, "500.0Mbps", plan.Classes[1].Ceil)
+}
+
+func TestTrafficController_RelativeBandwidthsInvalid(t *testing.T) {
+	tests := []struct {
+		guaranteed, max string
+		code            string
+	}{
+		{"120%", "", "invalid_bandwidth"},
+		{"2x guaranteed", "", "invalid_bandwidth"},
+		{"10mbps", "0.5x guaranteed", "invalid_bandwidth"},
+		{"60%", "2x guaranteed", "max_exceeds_total"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.guaranteed+" "+tt.max, func(t *testing.T) {
+			controller := NetworkInterface("eth0")
+			controller.WithHardLimitBandwidth("100mbps")
+			builder := controller.CreateTrafficClass("web").
+				WithGuaranteedBandwidth(tt.guaranteed).
+				WithPriority(1)
+			if tt.max != "" {
+				builder.WithSoftLimitBandwidth(tt.max)
+			}
+
+			report := controller.Validate()
+			require.False(t, report.Valid())
+			assert.Equal(t, tt.code, report.Errors[0].Code)
+		})
+	}
+}
+
+func TestPlanConfig_RelativeBandwidths(t *testing.T) {
+	config, err := ParseConfigFromYAML([]byte(`
+device: eth0
+bandwidth: 200mbps
+defaults:
+  burst_ratio: 1.5
+classes:
+  - name: web
+    guaranteed: 20%
+    priority: 1
+`))
+	require.NoError(t, err)
+
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	plan, err := controller.PlanConfig(config)
+	require.NoError(t, err)
+	assert.Equal(t, "40.0Mbps", plan.Classes[0].Rate)
+	assert.Equal(t, "60.0Mbps", plan.Classes[0].Ceil, "the burst ratio applies to the resolved guarantee")
+}
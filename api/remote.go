@@ -0,0 +1,35 @@
+package api
+
+import (
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// SSHHost is a remote host whose traffic control a controller manages over SSH, running tc
+// there; nothing needs to be installed on the host besides iproute2. The host is reached
+// with the local ssh client and must accept a key.
+type SSHHost struct {
+	Destination  string   // [user@]host, or a Host of ssh_config
+	Port         int      // Zero for the port of ssh_config or 22
+	IdentityFile string   // Private key; empty for those of ssh_config and the agent
+	Options      []string // Further ssh_config options, such as "StrictHostKeyChecking=yes"
+	TCPath       string   // tc on the host; /sbin/tc when empty
+}
+
+// WithSSHBackend manages the device of the same name on the remote host instead of a local
+// one, with the tc backend run over SSH (see WithExecBackend). Statistics are read over the
+// same connection. It must be called before Apply.
+func (controller *TrafficController) WithSSHBackend(host SSHHost) *TrafficController {
+	controller.logger.Info("Using SSH backend",
+		logging.String("device", controller.deviceName),
+		logging.String("host", host.Destination),
+	)
+
+	runner := netlink.SSHRunner{
+		Destination:  host.Destination,
+		Port:         host.Port,
+		IdentityFile: host.IdentityFile,
+		Options:      host.Options,
+	}
+	return controller.WithNetlinkAdapter(netlink.NewExecAdapterWithRunner(runner, host.TCPath))
+}
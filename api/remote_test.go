@@ -0,0 +1,15 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+)
+
+func TestTrafficController_WithSSHBackend(t *testing.T) {
+	controller := NetworkInterface("eth0").WithSSHBackend(SSHHost{Destination: "admin@edge1"})
+	_, ok := controller.service.NetlinkAdapter().(*netlink.ExecAdapter)
+	assert.True(t, ok)
+}
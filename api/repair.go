@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// DefaultRepairDelay is the default time a RepairWatcher waits after the root qdisc is
+// deleted before it re-applies the configuration
+const DefaultRepairDelay = time.Second
+
+// maxRepairRetryDelay bounds the delay between the attempts of a failing repair, which
+// doubles from the repair delay
+const maxRepairRetryDelay = time.Minute
+
+// rootQdiscHandle is the handle of the root qdisc created by Apply
+var rootQdiscHandle = tc.NewHandle(1, 0)
+
+// RepairWatcher re-applies the controller's configuration when the root qdisc is deleted
+// from under it, e.g. by a network manager resetting the device
+type RepairWatcher struct {
+	controller *TrafficController
+	delay      time.Duration
+	logger     logging.Logger
+
+	repairs atomic.Uint64
+}
+
+// NewRepairWatcher creates a repair watcher for the controller's device. The configuration
+// is re-applied at most delay after the deletion is seen, so that a burst of deletions while
+// the device is reset triggers a single repair. A failed repair is retried after twice the
+// previous delay, up to a minute, until it succeeds. A non-positive delay selects
+// DefaultRepairDelay.
+func (controller *TrafficController) NewRepairWatcher(delay time.Duration) *RepairWatcher {
+	if delay <= 0 {
+		delay = DefaultRepairDelay
+	}
+
+	return &RepairWatcher{
+		controller: controller,
+		delay:      delay,
+		logger:     controller.logger,
+	}
+}
+
+// Run watches the device until the context is cancelled
+func (w *RepairWatcher) Run(ctx context.Context) error {
+	deletions, err := w.controller.service.WatchQdiscDeletions(ctx, w.controller.deviceName)
+	if err != nil {
+		return err
+	}
+
+	w.logger.Info("Watching for root qdisc deletion",
+		logging.String("device", w.controller.deviceName),
+		logging.String("delay", w.delay.String()),
+	)
+
+	// The timer is armed by the first deletion and not pushed back by later ones, which
+	// keeps the repair within the delay however long the deletions go on. A failed repair
+	// re-arms it with a longer delay.
+	var timer *time.Timer
+	var repair <-chan time.Time
+	retryDelay := w.delay
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case deletion, ok := <-deletions:
+			if !ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return errors.New("qdisc deletion watch stopped unexpectedly")
+			}
+			if !deletion.Root || deletion.Handle != rootQdiscHandle || repair != nil {
+				continue
+			}
+			w.logger.Warn("Root qdisc deleted, scheduling repair",
+				logging.String("device", w.controller.deviceName),
+				logging.String("handle", deletion.Handle.String()),
+			)
+			timer = time.NewTimer(w.delay)
+			repair = timer.C
+
+		case <-repair:
+			repair = nil
+			if w.repair(ctx) {
+				retryDelay = w.delay
+				continue
+			}
+			retryDelay = min(2*retryDelay, maxRepairRetryDelay)
+			w.logger.Warn("Scheduling another repair attempt",
+				logging.String("device", w.controller.deviceName),
+				logging.String("delay", retryDelay.String()),
+			)
+			timer = time.NewTimer(retryDelay)
+			repair = timer.C
+		}
+	}
+}
+
+// Repairs returns the number of times the configuration was re-applied
+func (w *RepairWatcher) Repairs() uint64 {
+	return w.repairs.Load()
+}
+
+// repair re-applies the configuration if the root qdisc is still missing, and reports
+// whether the device needs no further repair
+func (w *RepairWatcher) repair(ctx context.Context) bool {
+	// Hold the controller's lock from the check to the apply, so that an Apply from another
+	// goroutine cannot restore the device in between
+	w.controller.mu.Lock()
+	defer w.controller.mu.Unlock()
+
+	config, err := w.controller.service.ReadCurrentConfiguration(ctx, w.controller.deviceName)
+	if err != nil {
+		w.logger.Error("Failed to read device configuration for repair",
+			logging.String("device", w.controller.deviceName),
+			logging.Error(err),
+		)
+		return false
+	}
+	for _, qdisc := range config.Qdiscs {
+		if qdisc.Handle == rootQdiscHandle.String() {
+			w.logger.Info("Root qdisc restored before repair",
+				logging.String("device", w.controller.deviceName),
+			)
+			return true
+		}
+	}
+
+	if _, err := w.controller.apply(); err != nil {
+		w.logger.Error("Failed to repair traffic control configuration",
+			logging.String("device", w.controller.deviceName),
+			logging.Error(err),
+		)
+		return false
+	}
+
+	w.repairs.Add(1)
+	w.logger.Info("Traffic control configuration repaired",
+		logging.String("device", w.controller.deviceName),
+		logging.Int("repairs", int(w.repairs.Load())),
+	)
+	return true
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestRepairWatcher_ReappliesDeletedRootQdisc(t *testing.T) {
+	controller, mockNetlinkAdapter := newPressureTestController(t)
+	device, _ := tc.NewDeviceName("eth0")
+	require.NotEmpty(t, mockNetlinkAdapter.GetQdiscs(device).Value())
+
+	watcher := controller.NewRepairWatcher(10 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- watcher.Run(ctx) }()
+
+	// Run subscribes asynchronously; keep deleting until the first repair is seen. A deletion
+	// racing the repair triggers another one.
+	require.Eventually(t, func() bool {
+		if len(mockNetlinkAdapter.GetQdiscs(device).Value()) > 0 && watcher.Repairs() == 0 {
+			mockNetlinkAdapter.RemoveRootQdisc(device)
+		}
+		return watcher.Repairs() >= 1
+	}, 2*time.Second, 20*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return len(mockNetlinkAdapter.GetQdiscs(device).Value()) > 0 && len(mockNetlinkAdapter.GetClasses(device).Value()) > 0
+	}, 2*time.Second, 20*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestRepairWatcher_RetriesFailedRepair(t *testing.T) {
+	controller, mockNetlinkAdapter := newPressureTestController(t)
+	device, _ := tc.NewDeviceName("eth0")
+
+	// The first repair fails to add the root qdisc, so no deletion follows that would
+	// trigger another; the retry succeeds
+	adapter := &failingQdiscAdapter{MockAdapter: mockNetlinkAdapter, failures: 1}
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), adapter, controller.logger)
+
+	watcher := controller.NewRepairWatcher(10 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- watcher.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		if len(mockNetlinkAdapter.GetQdiscs(device).Value()) > 0 && watcher.Repairs() == 0 {
+			mockNetlinkAdapter.RemoveRootQdisc(device)
+		}
+		return watcher.Repairs() >= 1
+	}, 2*time.Second, 20*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return len(mockNetlinkAdapter.GetQdiscs(device).Value()) > 0 && len(mockNetlinkAdapter.GetClasses(device).Value()) > 0
+	}, 2*time.Second, 20*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Zero(t, adapter.failures)
+}
+
+// failingQdiscAdapter is a mock adapter that refuses its first qdisc adds
+type failingQdiscAdapter struct {
+	*netlink.MockAdapter
+	failures int
+}
+
+func (a *failingQdiscAdapter) AddQdisc(ctx context.Context, qdisc *entities.Qdisc) error {
+	if a.failures > 0 {
+		a.failures--
+		return errors.New("qdisc add refused")
+	}
+	return a.MockAdapter.AddQdisc(ctx, qdisc)
+}
+
+func TestRepairWatcher_DefaultDelay(t *testing.T) {
+	controller := NetworkInterface("eth0")
+
+	assert.Equal(t, DefaultRepairDelay, controller.NewRepairWatcher(0).delay)
+	assert.Equal(t, time.Minute, controller.NewRepairWatcher(time.Minute).delay)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/types"
+)
+
+// AppliedConfig describes what ApplyResult changed on the device. Objects are named like
+// "class 1:11" or "filter 1: prio 100 flowid 1:11".
+type AppliedConfig struct {
+	Device    string
+	Added     []string
+	Deleted   []string
+	Modified  []string // Changed in place, keeping their counters
+	Unchanged []string
+}
+
+// Changed reports whether applying modified the device
+func (c AppliedConfig) Changed() bool {
+	return len(c.Added) > 0 || len(c.Deleted) > 0 || len(c.Modified) > 0
+}
+
+// The methods below are the Result counterparts of Validate, Plan and Apply. They let
+// validation, planning and applying be chained with types.AndThen, stopping at the first
+// failure:
+//
+//	result := types.AndThen(controller.ValidateResult(), (*TrafficController).ApplyResult)
+//	result.Match(onApplied, onError)
+
+// ValidateResult succeeds with the controller when the configuration has no errors, and
+// fails with the first error otherwise
+func (controller *TrafficController) ValidateResult() types.Result[*TrafficController] {
+	if err := controller.Validate().Err(); err != nil {
+		return types.Failure[*TrafficController](err)
+	}
+	return types.Success(controller)
+}
+
+// PlanResult is Plan returning a Result
+func (controller *TrafficController) PlanResult() types.Result[*qmodels.PlanView] {
+	return types.FromValue(controller.Plan())
+}
+
+// ApplyResult is Apply returning a Result that describes the changes made
+func (controller *TrafficController) ApplyResult() types.Result[AppliedConfig] {
+	controller.mu.Lock()
+	defer controller.mu.Unlock()
+	result, err := controller.apply()
+	if err != nil {
+		return types.Failure[AppliedConfig](err)
+	}
+	return types.Success(AppliedConfig{
+		Device:    controller.deviceName,
+		Added:     result.Added,
+		Deleted:   result.Deleted,
+		Modified:  result.Modified,
+		Unchanged: result.Unchanged,
+	})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+	"github.com/rng999/traffic-control-go/pkg/types"
+)
+
+func newResultTestController(guaranteed string) *TrafficController {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth(guaranteed).
+		WithPriority(1).
+		ForPort(80)
+	return controller
+}
+
+func TestTrafficController_ApplyResult(t *testing.T) {
+	controller := newResultTestController("30mbps")
+
+	plan := types.AndThen(controller.ValidateResult(), (*TrafficController).PlanResult)
+	require.True(t, plan.IsSuccess(), plan.Error())
+	assert.Len(t, plan.Value().Classes, 2)
+
+	applied := types.AndThen(controller.ValidateResult(), (*TrafficController).ApplyResult)
+	require.True(t, applied.IsSuccess(), applied.Error())
+	assert.Equal(t, "eth0", applied.Value().Device)
+	assert.Contains(t, applied.Value().Added, "class 1:11")
+	assert.True(t, applied.Value().Changed())
+
+	// Applying again changes nothing
+	again := controller.ApplyResult()
+	require.True(t, again.IsSuccess())
+	assert.False(t, again.Value().Changed())
+	assert.Contains(t, again.Value().Unchanged, "class 1:11")
+}
+
+func TestTrafficController_ApplyResultInvalid(t *testing.T) {
+	controller := newResultTestController("300mbps")
+
+	planned := false
+	result := types.AndThen(controller.ValidateResult(), func(c *TrafficController) types.Result[*qmodels.PlanView] {
+		planned = true
+		return c.PlanResult()
+	})
+	require.True(t, result.IsFailure())
+	assert.False(t, planned, "a failed validation stops the chain")
+	assert.ErrorIs(t, result.Error(), tcerrors.ErrBandwidthExceeded)
+
+	assert.ErrorIs(t, controller.ApplyResult().Error(), tcerrors.ErrBandwidthExceeded)
+	assert.True(t, controller.PlanResult().IsFailure())
+}
+
+func TestTrafficController_ApplyResultInvalidBandwidth(t *testing.T) {
+	controller := newResultTestController("30 furlongs")
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("10mbps").WithSoftLimitBandwidth("lots").
+		WithPriority(6).ForPort(8080)
+
+	var result types.Result[AppliedConfig]
+	require.NotPanics(t, func() {
+		result = types.AndThen(controller.ValidateResult(), (*TrafficController).ApplyResult)
+	})
+	require.True(t, result.IsFailure())
+	assert.ErrorIs(t, result.Error(), tcerrors.ErrInvalidConfiguration)
+	assert.Contains(t, result.Error().Error(), `invalid guaranteed bandwidth "30 furlongs"`)
+
+	issues := 0
+	for _, issue := range controller.Validate().Errors {
+		if issue.Code == "invalid_bandwidth" {
+			issues++
+		}
+	}
+	assert.Equal(t, 2, issues, "every invalid bandwidth is reported")
+
+	total := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter()).WithHardLimitBandwidth("fast")
+	assert.ErrorIs(t, total.ApplyResult().Error(), tcerrors.ErrInvalidConfiguration)
+}
@@ -0,0 +1,281 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// classSchedule is a bandwidth a class takes while a time window is active. An empty
+// bandwidth keeps the one the class is configured with.
+type classSchedule struct {
+	window     string
+	guaranteed string
+	max        string
+}
+
+// classBandwidth is the bandwidth configuration of a class a schedule replaces
+type classBandwidth struct {
+	guaranteed, max         tc.Bandwidth
+	guaranteedSpec, maxSpec string
+}
+
+func (class *TrafficClass) bandwidth() classBandwidth {
+	guaranteed := class.guaranteedBandwidth
+	if class.scaledFrom.BitsPerSecond() > 0 {
+		guaranteed = class.scaledFrom
+	}
+	return classBandwidth{guaranteed, class.maxBandwidth, class.guaranteedSpec, class.maxSpec}
+}
+
+func (class *TrafficClass) setBandwidth(b classBandwidth) {
+	class.scaledFrom = tc.Bandwidth{}
+	class.guaranteedBandwidth, class.maxBandwidth = b.guaranteed, b.max
+	class.guaranteedSpec, class.maxSpec = b.guaranteedSpec, b.maxSpec
+}
+
+// WithSchedule gives the class another bandwidth while a time window is active, accepting
+// the values of WithGuaranteedBandwidth and WithSoftLimitBandwidth; an empty value keeps the
+// configured one. A Scheduler applies the transitions.
+//
+//	controller.CreateTrafficClass("backups").
+//		WithGuaranteedBandwidth("100mbps").WithSoftLimitBandwidth("100mbps").
+//		WithSchedule("01:00-06:00", "500mbps", "500mbps")
+//
+// The window is either a daily range "HH:MM-HH:MM", optionally followed by the days it
+// starts on ("22:00-06:00 mon-fri"), or a five-field cron expression whose matching minutes
+// form the window ("* 1-5 * * *"). A "CRON_TZ=Europe/Berlin " prefix evaluates the window in
+// that timezone instead of the scheduler's. When windows overlap, the first one wins.
+func (b *TrafficClassBuilder) WithSchedule(window, guaranteed, max string) *TrafficClassBuilder {
+	b.class.schedules = append(b.class.schedules, classSchedule{window: window, guaranteed: guaranteed, max: max})
+	return b
+}
+
+// validateSchedules checks the windows and bandwidths of the class schedules
+func (controller *TrafficController) validateSchedules(report *ValidationReport, class *TrafficClass) {
+	for _, schedule := range class.schedules {
+		if _, err := parseTimeWindow(schedule.window, time.UTC); err != nil {
+			controller.addError(report, "invalid_schedule", class.name,
+				"class '%s' has an invalid schedule window %q: %v", class.name, schedule.window, err)
+			continue
+		}
+		guaranteed := class.guaranteedBandwidth
+		if schedule.guaranteed != "" {
+			bandwidth, err := resolveBandwidth(schedule.guaranteed, controller.totalBandwidth)
+			if err != nil {
+				controller.addError(report, "invalid_schedule", class.name,
+					"class '%s' has an invalid guaranteed bandwidth for %q: %v", class.name, schedule.window, err)
+				continue
+			}
+			guaranteed = bandwidth
+		}
+		ceiling := class.maxBandwidth
+		if schedule.max != "" {
+			bandwidth, err := resolveCeiling(schedule.max, controller.totalBandwidth, guaranteed)
+			if err != nil {
+				controller.addError(report, "invalid_schedule", class.name,
+					"class '%s' has an invalid max bandwidth for %q: %v", class.name, schedule.window, err)
+				continue
+			}
+			ceiling = bandwidth
+		}
+		if ceiling.BitsPerSecond() > 0 && guaranteed.GreaterThan(ceiling) {
+			controller.addError(report, "invalid_schedule", class.name,
+				"class '%s' would have a guaranteed bandwidth (%s) higher than its max bandwidth (%s) during %q",
+				class.name, guaranteed, ceiling, schedule.window)
+		}
+	}
+}
+
+// timeWindow is a recurring set of minutes, evaluated in its location
+type timeWindow struct {
+	location *time.Location
+
+	// Daily range form: minutes of the day [start, end), wrapping past midnight when
+	// end <= start, on the days the range starts
+	daily      bool
+	start, end int
+
+	// Cron form: the allowed values of each field
+	minutes, hours, days, months, weekdays []bool
+	anyDay, anyWeekday                     bool
+}
+
+// parseTimeWindow parses a window in the daily range or cron form, evaluated in location
+// unless the spec names its own timezone
+func parseTimeWindow(spec string, location *time.Location) (*timeWindow, error) {
+	fields := strings.Fields(spec)
+	if len(fields) > 0 {
+		for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+			if name, ok := strings.CutPrefix(fields[0], prefix); ok {
+				loc, err := time.LoadLocation(name)
+				if err != nil {
+					return nil, fmt.Errorf("unknown timezone %q", name)
+				}
+				location, fields = loc, fields[1:]
+				break
+			}
+		}
+	}
+
+	window := &timeWindow{location: location}
+	switch {
+	case len(fields) == 5:
+		return window, window.parseCron(fields)
+	case len(fields) == 1 || len(fields) == 2:
+		return window, window.parseDaily(fields)
+	}
+	return nil, fmt.Errorf(`expected "HH:MM-HH:MM [weekdays]" or a cron expression "minute hour day month weekday"`)
+}
+
+func (w *timeWindow) parseDaily(fields []string) error {
+	from, to, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return fmt.Errorf("%q is not a range like 01:00-06:00", fields[0])
+	}
+	var err error
+	if w.start, err = minuteOfDay(from); err != nil {
+		return err
+	}
+	if w.end, err = minuteOfDay(to); err != nil {
+		return err
+	}
+
+	weekdays := "*"
+	if len(fields) == 2 {
+		weekdays = fields[1]
+	}
+	w.daily = true
+	w.weekdays, err = parseCronField(weekdays, 0, 7, weekdayNames)
+	foldSunday(w.weekdays)
+	return err
+}
+
+func (w *timeWindow) parseCron(fields []string) error {
+	var err error
+	if w.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return fmt.Errorf("minute: %w", err)
+	}
+	if w.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return fmt.Errorf("hour: %w", err)
+	}
+	if w.days, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return fmt.Errorf("day of month: %w", err)
+	}
+	if w.months, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return fmt.Errorf("month: %w", err)
+	}
+	if w.weekdays, err = parseCronField(fields[4], 0, 7, weekdayNames); err != nil {
+		return fmt.Errorf("weekday: %w", err)
+	}
+	foldSunday(w.weekdays)
+	w.anyDay, w.anyWeekday = fields[2] == "*", fields[4] == "*"
+	return nil
+}
+
+// contains reports whether the window is active at t
+func (w *timeWindow) contains(t time.Time) bool {
+	t = t.In(w.location)
+	if w.daily {
+		minute := t.Hour()*60 + t.Minute()
+		if w.start < w.end {
+			return minute >= w.start && minute < w.end && w.weekdays[t.Weekday()]
+		}
+		// The range wraps past midnight; its early hours belong to the day before
+		return (minute >= w.start && w.weekdays[t.Weekday()]) ||
+			(minute < w.end && w.weekdays[t.AddDate(0, 0, -1).Weekday()])
+	}
+
+	if !w.minutes[t.Minute()] || !w.hours[t.Hour()] || !w.months[t.Month()] {
+		return false
+	}
+	// Like cron, a day matches either field when both are restricted
+	day, weekday := w.days[t.Day()], w.weekdays[t.Weekday()]
+	switch {
+	case w.anyDay && w.anyWeekday:
+		return true
+	case w.anyDay:
+		return weekday
+	case w.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
+
+var (
+	weekdayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+	monthNames   = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+)
+
+// parseCronField parses a comma-separated list of "*", values, ranges "a-b" and steps
+// "*/n" or "a-b/n" into the set of allowed values, indexed by value
+func parseCronField(field string, min, max int, names map[string]int) ([]bool, error) {
+	allowed := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rangeSpec, stepSpec, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepSpec); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepSpec)
+			}
+		}
+
+		low, high := min, max
+		if rangeSpec != "*" {
+			from, to, isRange := strings.Cut(rangeSpec, "-")
+			var err error
+			if low, err = cronValue(from, min, max, names); err != nil {
+				return nil, err
+			}
+			high = low
+			if isRange {
+				if high, err = cronValue(to, min, max, names); err != nil {
+					return nil, err
+				}
+			} else if hasStep {
+				high = max
+			}
+			if high < low {
+				return nil, fmt.Errorf("range %q ends before it starts", rangeSpec)
+			}
+		}
+		for v := low; v <= high; v += step {
+			allowed[v] = true
+		}
+	}
+	return allowed, nil
+}
+
+func cronValue(value string, min, max int, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%q is not between %d and %d", value, min, max)
+	}
+	return n, nil
+}
+
+// foldSunday makes weekday 7 an alias of 0, as cron does
+func foldSunday(weekdays []bool) {
+	if weekdays != nil && weekdays[7] {
+		weekdays[0] = true
+	}
+}
+
+// minuteOfDay parses "HH:MM"; "24:00" is the end of the day
+func minuteOfDay(value string) (int, error) {
+	hour, minute, ok := strings.Cut(value, ":")
+	h, errH := strconv.Atoi(hour)
+	m, errM := strconv.Atoi(minute)
+	if !ok || errH != nil || errM != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("%q is not a time like 06:30", value)
+	}
+	return h*60 + m, nil
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestTimeWindow_Contains(t *testing.T) {
+	// 2026-03-06 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		window string
+		at     time.Time
+		want   bool
+	}{
+		{"01:00-06:00", at(6, 1, 0), true},
+		{"01:00-06:00", at(6, 5, 59), true},
+		{"01:00-06:00", at(6, 6, 0), false},
+		{"22:00-06:00 fri", at(6, 23, 0), true},
+		{"22:00-06:00 fri", at(7, 3, 0), true}, // Saturday morning, started on Friday
+		{"22:00-06:00 fri", at(6, 3, 0), false},
+		{"00:00-24:00 sat,sun", at(8, 12, 0), true},
+		{"* 1-5 * * *", at(6, 5, 30), true},
+		{"* 1-5 * * *", at(6, 6, 0), false},
+		{"*/15 9-17 * * mon-fri", at(6, 9, 45), true},
+		{"*/15 9-17 * * mon-fri", at(6, 9, 46), false},
+		{"* * 1 * 7", at(8, 12, 0), true}, // Sunday matches although the 8th is not the 1st
+		{"* * * dec *", at(6, 12, 0), false},
+		{"CRON_TZ=Asia/Tokyo 09:00-10:00", at(6, 0, 30), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			window, err := parseTimeWindow(tt.window, time.UTC)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, window.contains(tt.at), "at %s", tt.at)
+		})
+	}
+
+	for _, invalid := range []string{"", "01:00", "25:00-26:00", "* * * *", "61 * * * *", "* 5-1 * * *", "TZ=Mars/Base 01:00-02:00"} {
+		_, err := parseTimeWindow(invalid, time.UTC)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestScheduler_AppliesTransitions(t *testing.T) {
+	mock := netlink.NewMockAdapter()
+	device := tc.MustNewDeviceName("eth0")
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(mock)
+	controller.WithHardLimitBandwidth("1gbps")
+	controller.CreateTrafficClass("backups").WithGuaranteedBandwidth("100mbps").
+		WithSoftLimitBandwidth("100mbps").WithPriority(4).ForPort(873).
+		WithSchedule("01:00-06:00", "500mbps", "500mbps")
+	require.NoError(t, controller.Apply())
+
+	var seen []ScheduleTransition
+	scheduler := controller.NewScheduler().WithLocation(time.UTC).
+		OnTransition(func(transition ScheduleTransition) { seen = append(seen, transition) })
+	night := time.Date(2026, time.March, 6, 2, 0, 0, 0, time.UTC)
+
+	transitions, err := scheduler.step(night)
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, "01:00-06:00", transitions[0].Window)
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	assert.Equal(t, "500.0Mbps", plan.Classes[0].Rate)
+	assert.Equal(t, []tc.Handle{tc.MustParseHandle("1:14")}, mock.ChangedClasses(device))
+
+	// Nothing changes within the window
+	transitions, err = scheduler.step(night.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, transitions)
+
+	// Leaving it restores the configured bandwidth
+	transitions, err = scheduler.step(night.Add(5 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Empty(t, transitions[0].Window)
+	plan, err = controller.Plan()
+	require.NoError(t, err)
+	assert.Equal(t, "100.0Mbps", plan.Classes[0].Rate)
+	assert.Equal(t, "100.0Mbps", plan.Classes[0].Ceil)
+
+	assert.Len(t, seen, 2)
+	assert.Equal(t, uint64(2), scheduler.Transitions())
+}
+
+func TestScheduler_FailedTransitionIsRetried(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("1gbps")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("600mbps").WithPriority(1).ForPort(443)
+	controller.CreateTrafficClass("backups").WithGuaranteedBandwidth("100mbps").WithPriority(4).ForPort(873).
+		WithSchedule("01:00-06:00", "500mbps", "")
+	require.NoError(t, controller.Apply())
+
+	// The scheduled guarantee oversubscribes the interface, so the class stays as it was
+	scheduler := controller.NewScheduler().WithLocation(time.UTC)
+	_, err := scheduler.step(time.Date(2026, time.March, 6, 2, 0, 0, 0, time.UTC))
+	require.Error(t, err)
+	assert.Equal(t, 0, controller.classes[1].activeSchedule)
+	assert.Equal(t, tc.MustParseBandwidth("100mbps"), controller.classes[1].guaranteedBandwidth)
+}
+
+func TestScheduler_ConcurrentApply(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("1gbps")
+	controller.CreateTrafficClass("backups").WithGuaranteedBandwidth("100mbps").
+		WithSoftLimitBandwidth("100mbps").WithPriority(4).ForPort(873).
+		WithSchedule("01:00-06:00", "500mbps", "500mbps")
+	require.NoError(t, controller.Apply())
+
+	// The scheduler's applies interleave with the caller's; run with -race
+	scheduler := controller.NewScheduler().WithLocation(time.UTC)
+	night := time.Date(2026, time.March, 6, 2, 0, 0, 0, time.UTC)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_, err := scheduler.step(night.Add(time.Duration(i%2) * 6 * time.Hour))
+			assert.NoError(t, err)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		require.NoError(t, controller.Apply())
+	}
+	<-done
+
+	assert.Equal(t, uint64(20), scheduler.Transitions())
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	assert.Equal(t, "100.0Mbps", plan.Classes[0].Rate)
+}
+
+func TestScheduler_Run(t *testing.T) {
+	config, err := ParseConfigFromYAML([]byte(`
+device: eth0
+bandwidth: 1gbps
+classes:
+  - name: backups
+    guaranteed: 100mbps
+    priority: 4
+    schedules:
+      - window: "* * * * *"
+        guaranteed: 300mbps
+        maximum: 450mbps
+`))
+	require.NoError(t, err)
+
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	require.NoError(t, controller.ApplyConfig(config))
+
+	scheduler := controller.NewScheduler()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- scheduler.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return scheduler.Transitions() == 1 }, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, tc.MustParseBandwidth("300mbps"), controller.classes[0].guaranteedBandwidth)
+}
+
+func TestWithSchedule_Validation(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("1gbps")
+	controller.CreateTrafficClass("backups").WithGuaranteedBandwidth("100mbps").WithPriority(4).
+		WithSchedule("nightly", "500mbps", "").
+		WithSchedule("01:00-06:00", "150%", "").
+		WithSchedule("sat,sun", "", "").
+		WithSoftLimitBandwidth("200mbps").
+		WithSchedule("* 1-5 * * *", "300mbps", "")
+
+	report := controller.Validate()
+	require.Len(t, report.Errors, 4)
+	assert.Equal(t, "invalid_schedule", report.Errors[0].Code)
+	assert.Contains(t, report.Errors[0].Message, `"nightly"`)
+	assert.Equal(t, "invalid_schedule", report.Errors[1].Code)
+	assert.Contains(t, report.Errors[3].Message, "higher than its max bandwidth (200.0Mbps)")
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// ScheduleTransition reports a class entering or leaving a scheduled bandwidth
+type ScheduleTransition struct {
+	Class  string
+	Window string // Window now in effect; empty when the class is back to its configured bandwidth
+	At     time.Time
+}
+
+// Scheduler applies the bandwidth schedules of the controller's classes, set with
+// WithSchedule. It checks the windows every minute and applies the configuration when a
+// class enters or leaves one; classes are changed in place, keeping their counters.
+type Scheduler struct {
+	controller *TrafficController
+	location   *time.Location
+	logger     logging.Logger
+	now        func() time.Time
+
+	mu           sync.Mutex
+	onTransition []func(ScheduleTransition)
+
+	transitions atomic.Uint64
+}
+
+// NewScheduler creates a scheduler for the controller's classes, evaluating their windows
+// in the local timezone
+func (controller *TrafficController) NewScheduler() *Scheduler {
+	return &Scheduler{
+		controller: controller,
+		location:   time.Local,
+		logger:     controller.logger,
+		now:        time.Now,
+	}
+}
+
+// WithLocation evaluates the windows that name no timezone of their own in location
+func (s *Scheduler) WithLocation(location *time.Location) *Scheduler {
+	s.location = location
+	return s
+}
+
+// OnTransition registers a hook run from the scheduler's goroutine after a transition has
+// been applied
+func (s *Scheduler) OnTransition(hook func(ScheduleTransition)) *Scheduler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onTransition = append(s.onTransition, hook)
+	return s
+}
+
+// Run applies the schedules until the context is cancelled, starting with the windows
+// active now. A transition that fails to apply is retried on the next check.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.controller.mu.Lock()
+	err := s.controller.Validate().Err()
+	s.controller.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Scheduling class bandwidths",
+		logging.String("device", s.controller.deviceName),
+		logging.String("location", s.location.String()),
+	)
+
+	for {
+		if _, err := s.step(s.now()); err != nil {
+			s.logger.Error("Failed to apply scheduled bandwidths",
+				logging.String("device", s.controller.deviceName),
+				logging.Error(err),
+			)
+		}
+
+		// Windows have minute resolution, so checking at the start of each minute suffices
+		now := s.now()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Transitions returns the number of class transitions applied
+func (s *Scheduler) Transitions() uint64 {
+	return s.transitions.Load()
+}
+
+// step moves every class to the schedule in effect at now and applies the configuration if
+// any class changed. When applying fails, the classes are moved back.
+func (s *Scheduler) step(now time.Time) ([]ScheduleTransition, error) {
+	transitions, err := s.transition(now)
+	if err != nil || len(transitions) == 0 {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	hooks := append([]func(ScheduleTransition){}, s.onTransition...)
+	s.mu.Unlock()
+	for _, transition := range transitions {
+		s.transitions.Add(1)
+		s.logger.Info("Scheduled bandwidth applied",
+			logging.String("class_name", transition.Class),
+			logging.String("window", transition.Window),
+		)
+		for _, hook := range hooks {
+			hook(transition)
+		}
+	}
+	return transitions, nil
+}
+
+// transition moves the classes and applies them while holding the controller's lock, so that
+// an Apply from another goroutine sees the classes either before or after the transition
+func (s *Scheduler) transition(now time.Time) ([]ScheduleTransition, error) {
+	controller := s.controller
+	controller.mu.Lock()
+	defer controller.mu.Unlock()
+	controller.finalizePendingClasses()
+
+	type saved struct {
+		class     *TrafficClass
+		bandwidth classBandwidth
+		active    int
+	}
+	var transitions []ScheduleTransition
+	var undo []saved
+	for _, class := range controller.classes {
+		next := 0
+		for i, schedule := range class.schedules {
+			window, err := parseTimeWindow(schedule.window, s.location)
+			if err != nil {
+				return nil, err
+			}
+			if window.contains(now) {
+				next = i + 1
+				break
+			}
+		}
+		if next == class.activeSchedule {
+			continue
+		}
+
+		undo = append(undo, saved{class, class.bandwidth(), class.activeSchedule})
+		s.enter(class, next)
+		transition := ScheduleTransition{Class: class.name, At: now}
+		if next > 0 {
+			transition.Window = class.schedules[next-1].window
+		}
+		transitions = append(transitions, transition)
+	}
+	if len(transitions) == 0 {
+		return nil, nil
+	}
+
+	if _, err := controller.apply(); err != nil {
+		for _, state := range undo {
+			state.class.setBandwidth(state.bandwidth)
+			state.class.activeSchedule = state.active
+		}
+		return nil, err
+	}
+	return transitions, nil
+}
+
+// enter switches a class to its next schedule, 0 restoring the configured bandwidth
+func (s *Scheduler) enter(class *TrafficClass, next int) {
+	if class.activeSchedule == 0 {
+		class.unscheduled = class.bandwidth()
+	} else {
+		class.setBandwidth(class.unscheduled)
+	}
+	class.activeSchedule = next
+	if next == 0 {
+		return
+	}
+
+	schedule := class.schedules[next-1]
+	builder := &TrafficClassBuilder{controller: s.controller, class: class, finalized: true}
+	if schedule.guaranteed != "" {
+		builder.WithGuaranteedBandwidth(schedule.guaranteed)
+	}
+	if schedule.max != "" {
+		builder.WithSoftLimitBandwidth(schedule.max)
+	}
+}
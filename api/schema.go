@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+// JSONSchema is a JSON Schema document describing the params or result of a control method
+type JSONSchema map[string]interface{}
+
+// MethodSchema describes the params and result of a control method
+type MethodSchema struct {
+	Params JSONSchema `json:"params,omitempty"`
+	Result JSONSchema `json:"result"`
+}
+
+// ControlSchema describes every control method, generated from the Go types exchanged, so
+// that bindings in other languages can be kept in step with the library. It is the result
+// of the "schema" method.
+func ControlSchema() map[string]MethodSchema {
+	applied := JSONSchema{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"applied": JSONSchema{"type": "boolean"},
+			// Set by servers in dry-run mode
+			"commands": JSONSchema{"type": "array", "items": JSONSchema{"type": "string"}},
+		},
+		"required":             []string{"applied"},
+		"additionalProperties": false,
+	}
+
+	return map[string]MethodSchema{
+		ControlMethodApply:  {Params: schemaOf(TrafficControlConfig{}), Result: applied},
+		ControlMethodPlan:   {Params: schemaOf(TrafficControlConfig{}), Result: schemaOf(qmodels.ConfigurationView{})},
+		ControlMethodStats:  {Result: schemaOf(qmodels.DeviceStatisticsView{})},
+		ControlMethodHealth: {Result: schemaOf(ControlHealth{})},
+		ControlMethodSchema: {Result: JSONSchema{"type": "object"}},
+	}
+}
+
+// schemaOf generates the schema of a value's type. Structs are placed in $defs and
+// referenced, which also covers recursive types such as TrafficClassConfig.
+func schemaOf(v interface{}) JSONSchema {
+	defs := make(map[string]interface{})
+	schema := typeSchema(reflect.TypeOf(v), defs)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	if len(defs) > 0 {
+		schema["$defs"] = defs
+	}
+	return schema
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// typeSchema returns the schema of a type, adding the structs it uses to defs
+func typeSchema(t reflect.Type, defs map[string]interface{}) JSONSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return JSONSchema{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return JSONSchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return JSONSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if t.PkgPath() == "time" && t.Name() == "Duration" {
+			return JSONSchema{"type": "integer", "description": "nanoseconds"}
+		}
+		return JSONSchema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return JSONSchema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return JSONSchema{"type": "number"}
+	case reflect.String:
+		return JSONSchema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return JSONSchema{"type": "string", "contentEncoding": "base64"}
+		}
+		return JSONSchema{"type": "array", "items": typeSchema(t.Elem(), defs)}
+	case reflect.Map:
+		return JSONSchema{"type": "object", "additionalProperties": typeSchema(t.Elem(), defs)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, defs)
+		}
+		name := t.Name()
+		if _, ok := defs[name]; !ok {
+			defs[name] = JSONSchema{} // Placeholder so that recursive references stop here
+			defs[name] = structSchema(t, defs)
+		}
+		return JSONSchema{"$ref": "#/$defs/" + name}
+	default:
+		// interface{} and anything else holds any JSON value
+		return JSONSchema{}
+	}
+}
+
+// structSchema returns the schema of a struct's JSON encoding: fields without omitempty
+// are always present and therefore required
+func structSchema(t reflect.Type, defs map[string]interface{}) JSONSchema {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+
+			if field.Anonymous && name == "" {
+				embedded := field.Type
+				if embedded.Kind() == reflect.Ptr {
+					embedded = embedded.Elem()
+				}
+				if embedded.Kind() == reflect.Struct {
+					addFields(embedded)
+					continue
+				}
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			properties[name] = typeSchema(field.Type, defs)
+			if !strings.Contains(options, "omitempty") {
+				required = append(required, name)
+			}
+		}
+	}
+	addFields(t)
+
+	return JSONSchema{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlSchema(t *testing.T) {
+	schema := ControlSchema()
+	require.Contains(t, schema, ControlMethodApply)
+
+	params := schema[ControlMethodApply].Params
+	assert.Equal(t, "#/$defs/TrafficControlConfig", params["$ref"])
+
+	defs := params["$defs"].(map[string]interface{})
+	config := defs["TrafficControlConfig"].(JSONSchema)
+	assert.ElementsMatch(t, []string{"version", "device", "bandwidth", "classes"}, config["required"])
+
+	// Recursive classes refer back to their own definition
+	class := defs["TrafficClassConfig"].(JSONSchema)
+	children := class["properties"].(map[string]interface{})["children"].(JSONSchema)
+	assert.Equal(t, JSONSchema{"$ref": "#/$defs/TrafficClassConfig"}, children["items"])
+
+	health := schema[ControlMethodHealth].Result["$defs"].(map[string]interface{})["ControlHealth"].(JSONSchema)
+	startedAt := health["properties"].(map[string]interface{})["started_at"]
+	assert.Equal(t, JSONSchema{"type": "string", "format": "date-time"}, startedAt)
+	assert.Nil(t, schema[ControlMethodStats].Params)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// TrafficMatrix is the bandwidth each traffic class offers, by class name, absolute like
+// "300mbps" or a share of the interface like "30%". The default class is named as in
+// contention reports; classes left out offer nothing.
+type TrafficMatrix map[string]string
+
+// SimulatedClass is the predicted outcome of a traffic matrix for one class
+type SimulatedClass struct {
+	Class      string
+	Priority   uint8
+	Offered    tc.Bandwidth
+	Guaranteed tc.Bandwidth
+	Ceiling    tc.Bandwidth
+	Throughput tc.Bandwidth // Bandwidth the class gets
+	Borrowed   tc.Bandwidth // Part of the throughput above the guarantee
+	// DropProbability is the share of the offered traffic that does not fit, 0 to 1. In the
+	// steady state the queue is full and the excess is dropped.
+	DropProbability float64
+}
+
+// SimulationReport is the outcome of simulating a traffic matrix
+type SimulationReport struct {
+	TotalBandwidth tc.Bandwidth
+	Throughput     tc.Bandwidth     // Sum over the classes
+	Classes        []SimulatedClass // In HTB service order: priority, then configuration order
+}
+
+// Class returns the outcome of the named class
+func (r *SimulationReport) Class(name string) (SimulatedClass, bool) {
+	for _, class := range r.Classes {
+		if class.Class == name {
+			return class, true
+		}
+	}
+	return SimulatedClass{}, false
+}
+
+// Dropping returns the classes that lose part of their offered traffic
+func (r *SimulationReport) Dropping() []SimulatedClass {
+	var dropping []SimulatedClass
+	for _, class := range r.Classes {
+		if class.DropProbability > 0 {
+			dropping = append(dropping, class)
+		}
+	}
+	return dropping
+}
+
+// Utilization returns the share of the interface in use, in percent
+func (r *SimulationReport) Utilization() float64 {
+	if r.TotalBandwidth.IsZero() {
+		return 0
+	}
+	return float64(r.Throughput.BitsPerSecond()) / float64(r.TotalBandwidth.BitsPerSecond()) * 100
+}
+
+// Simulate predicts the throughput, borrowing and drops of every class when the classes
+// offer the traffic of the matrix, without touching the kernel. Where SimulateContention
+// checks the worst case, Simulate answers what-if questions for capacity planning, such as
+// how a backup running during office hours affects the other classes.
+func (controller *TrafficController) Simulate(matrix TrafficMatrix) (*SimulationReport, error) {
+	controller.finalizePendingClasses()
+	if err := controller.validateReport().Err(); err != nil {
+		return nil, err
+	}
+
+	offered := make(map[string]tc.Bandwidth, len(matrix))
+	for name, spec := range matrix {
+		if !controller.hasClass(name) && name != controller.defaultClassLabel() {
+			return nil, fmt.Errorf("traffic matrix names unknown class %q", name)
+		}
+		bandwidth, err := resolveBandwidth(spec, controller.totalBandwidth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid traffic offered by class %q: %w", name, err)
+		}
+		offered[name] = bandwidth
+	}
+
+	// A class takes at most what it offers, up to its ceiling
+	allocations := controller.classAllocations(controller.defaultClassRate())
+	ceilings := make([]tc.Bandwidth, len(allocations))
+	for i := range allocations {
+		ceilings[i] = allocations[i].Ceiling
+		allocations[i].Ceiling = tc.MinBandwidth(allocations[i].Ceiling, offered[allocations[i].Class])
+	}
+	distribute(allocations, controller.totalBandwidth)
+
+	report := &SimulationReport{TotalBandwidth: controller.totalBandwidth}
+	for i, allocation := range allocations {
+		class := SimulatedClass{
+			Class:      allocation.Class,
+			Priority:   allocation.Priority,
+			Offered:    offered[allocation.Class],
+			Guaranteed: allocation.Guaranteed,
+			Ceiling:    ceilings[i],
+			Throughput: allocation.Allocated,
+			Borrowed:   allocation.Allocated.Sub(allocation.Guaranteed),
+		}
+		if !class.Offered.IsZero() {
+			class.DropProbability = float64(class.Offered.Subtract(class.Throughput).BitsPerSecond()) /
+				float64(class.Offered.BitsPerSecond())
+		}
+		report.Throughput = report.Throughput.Add(class.Throughput)
+		report.Classes = append(report.Classes, class)
+	}
+	return report, nil
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func newSimulationController() *TrafficController {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("interactive").
+		WithGuaranteedBandwidth("20mbps").
+		WithSoftLimitBandwidth("50mbps").
+		WithPriority(1)
+	controller.CreateTrafficClass("bulk").
+		WithGuaranteedBandwidth("30mbps").
+		WithSoftLimitBandwidth("100mbps").
+		WithPriority(5)
+	return controller
+}
+
+func TestTrafficController_Simulate(t *testing.T) {
+	t.Run("higher_priority_borrows_first", func(t *testing.T) {
+		report, err := newSimulationController().Simulate(TrafficMatrix{"interactive": "40mbps", "bulk": "90%"})
+		require.NoError(t, err)
+
+		interactive, ok := report.Class("interactive")
+		require.True(t, ok)
+		assert.Equal(t, tc.Mbps(40), interactive.Throughput)
+		assert.Equal(t, tc.Mbps(20), interactive.Borrowed)
+		assert.Equal(t, 0.0, interactive.DropProbability)
+
+		// Bulk keeps its guarantee and borrows what is left of the link
+		bulk, _ := report.Class("bulk")
+		assert.Equal(t, tc.Mbps(60), bulk.Throughput)
+		assert.Equal(t, tc.Mbps(30), bulk.Borrowed)
+		assert.Equal(t, tc.Mbps(100), bulk.Ceiling)
+		assert.InDelta(t, 1.0/3, bulk.DropProbability, 0.001)
+
+		assert.Equal(t, 100.0, report.Utilization())
+		dropping := report.Dropping()
+		require.Len(t, dropping, 1)
+		assert.Equal(t, "bulk", dropping[0].Class)
+	})
+
+	t.Run("idle_guarantees_are_lent", func(t *testing.T) {
+		report, err := newSimulationController().Simulate(TrafficMatrix{"bulk": "80mbps", defaultClassName: "5mbps"})
+		require.NoError(t, err)
+
+		bulk, _ := report.Class("bulk")
+		assert.Equal(t, tc.Mbps(80), bulk.Throughput)
+		unclassified, _ := report.Class(defaultClassName)
+		assert.Equal(t, tc.Mbps(5), unclassified.Throughput)
+		assert.Empty(t, report.Dropping())
+		assert.Equal(t, tc.Mbps(85), report.Throughput)
+	})
+
+	t.Run("ceiling_caps_throughput_on_an_idle_link", func(t *testing.T) {
+		report, err := newSimulationController().Simulate(TrafficMatrix{"interactive": "80mbps"})
+		require.NoError(t, err)
+
+		interactive, _ := report.Class("interactive")
+		assert.Equal(t, tc.Mbps(50), interactive.Throughput)
+		assert.Equal(t, 0.375, interactive.DropProbability)
+	})
+
+	t.Run("rejects_invalid_matrices", func(t *testing.T) {
+		_, err := newSimulationController().Simulate(TrafficMatrix{"video": "10mbps"})
+		assert.Error(t, err)
+		_, err = newSimulationController().Simulate(TrafficMatrix{"bulk": "fast"})
+		assert.Error(t, err)
+
+		invalid := NetworkInterface("eth0")
+		invalid.CreateTrafficClass("web").WithPriority(1)
+		_, err = invalid.Simulate(TrafficMatrix{})
+		assert.Error(t, err)
+	})
+}
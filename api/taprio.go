@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/commands/models"
+)
+
+// CreateTAPRIOQdisc creates a root taprio (Time Aware Priority) qdisc with fluent interface.
+// taprio implements the IEEE 802.1Qbv time-aware shaper: packet priorities map to traffic
+// classes, each traffic class owns a range of transmit queues, and a cyclic gate schedule
+// opens and closes the traffic classes. The schedule runs on CLOCK_TAI unless set otherwise.
+func (controller *TrafficController) CreateTAPRIOQdisc(handle string) *TAPRIOQdiscBuilder {
+	return &TAPRIOQdiscBuilder{
+		controller: controller,
+		handle:     handle,
+		params: application.TAPRIOParameters{
+			Clock: "CLOCK_TAI",
+		},
+	}
+}
+
+// TAPRIOQdiscBuilder provides fluent interface for taprio qdiscs
+type TAPRIOQdiscBuilder struct {
+	controller *TrafficController
+	handle     string
+	params     application.TAPRIOParameters
+}
+
+// WithTrafficClasses sets the number of traffic classes
+func (b *TAPRIOQdiscBuilder) WithTrafficClasses(count uint8) *TAPRIOQdiscBuilder {
+	b.params.NumTC = count
+	return b
+}
+
+// WithPriomap maps packet priority i to the i-th traffic class. Unlisted priorities go to
+// traffic class 0.
+func (b *TAPRIOQdiscBuilder) WithPriomap(classes ...uint8) *TAPRIOQdiscBuilder {
+	b.params.Priomap = classes
+	return b
+}
+
+// WithQueues gives the next traffic class count transmit queues starting at offset; call
+// it once per traffic class, in order
+func (b *TAPRIOQdiscBuilder) WithQueues(count, offset uint16) *TAPRIOQdiscBuilder {
+	b.params.Queues = append(b.params.Queues, models.TAPRIOQueueRange{Count: count, Offset: offset})
+	return b
+}
+
+// WithBaseTime sets when the schedule starts. A base time in the past starts the schedule
+// at the next cycle boundary after now.
+func (b *TAPRIOQdiscBuilder) WithBaseTime(baseTime time.Time) *TAPRIOQdiscBuilder {
+	b.params.BaseTime = baseTime
+	return b
+}
+
+// WithCycleTime sets the length of a schedule cycle; by default it is the sum of the
+// entry intervals
+func (b *TAPRIOQdiscBuilder) WithCycleTime(cycle time.Duration) *TAPRIOQdiscBuilder {
+	b.params.CycleTime = cycle
+	return b
+}
+
+// WithCycleTimeExtension lets the last entry of a cycle run on by up to the given duration
+// rather than leave a short entry before a new schedule takes over
+func (b *TAPRIOQdiscBuilder) WithCycleTimeExtension(extension time.Duration) *TAPRIOQdiscBuilder {
+	b.params.CycleTimeExtension = extension
+	return b
+}
+
+// WithGateEntry appends a schedule entry that opens the gates of the traffic classes
+// whose bits are set in gates for the given interval
+func (b *TAPRIOQdiscBuilder) WithGateEntry(gates uint32, interval time.Duration) *TAPRIOQdiscBuilder {
+	return b.withEntry("S", gates, interval)
+}
+
+// WithHoldEntry appends a schedule entry that sets the gates and holds preemptible
+// traffic (IEEE 802.1Qbu) on NICs with frame preemption
+func (b *TAPRIOQdiscBuilder) WithHoldEntry(gates uint32, interval time.Duration) *TAPRIOQdiscBuilder {
+	return b.withEntry("H", gates, interval)
+}
+
+// WithReleaseEntry appends a schedule entry that sets the gates and releases
+// preemptible traffic on NICs with frame preemption
+func (b *TAPRIOQdiscBuilder) WithReleaseEntry(gates uint32, interval time.Duration) *TAPRIOQdiscBuilder {
+	return b.withEntry("R", gates, interval)
+}
+
+func (b *TAPRIOQdiscBuilder) withEntry(command string, gates uint32, interval time.Duration) *TAPRIOQdiscBuilder {
+	b.params.Schedule = append(b.params.Schedule, application.TAPRIOGateEntry{
+		Command:  command,
+		Gates:    gates,
+		Interval: interval,
+	})
+	return b
+}
+
+// WithClock sets the clock the schedule runs on: CLOCK_TAI, CLOCK_REALTIME,
+// CLOCK_MONOTONIC or CLOCK_BOOTTIME
+func (b *TAPRIOQdiscBuilder) WithClock(clock string) *TAPRIOQdiscBuilder {
+	b.params.Clock = clock
+	return b
+}
+
+// WithTxTimeAssist has taprio stamp packets with transmit times for ETF qdiscs on the
+// queues, which hand them to the NIC delay ahead of time
+func (b *TAPRIOQdiscBuilder) WithTxTimeAssist(delay time.Duration) *TAPRIOQdiscBuilder {
+	b.params.TxTimeAssist = true
+	b.params.TxTimeDelay = delay
+	return b
+}
+
+// WithFullOffload runs the schedule in the NIC on its own clock
+func (b *TAPRIOQdiscBuilder) WithFullOffload() *TAPRIOQdiscBuilder {
+	b.params.FullOffload = true
+	return b
+}
+
+func (b *TAPRIOQdiscBuilder) Apply() error {
+	ctx := b.controller.actorContext(context.Background())
+	return b.controller.service.CreateTAPRIOQdisc(ctx, b.controller.deviceName, b.handle, b.params)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestTSNQdiscBuilders(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	device, _ := tc.NewDeviceName("eth0")
+
+	useMock := func() *netlink.MockAdapter {
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+		return mockNetlinkAdapter
+	}
+
+	t.Run("applies_ets_qdisc", func(t *testing.T) {
+		mockNetlinkAdapter := useMock()
+
+		err := controller.CreateETSQdisc("1:0").
+			WithStrictBands(1).
+			WithBandQuanta(3000, 1500).
+			WithPriomap(0, 1, 2, 2).
+			Apply()
+		require.NoError(t, err)
+
+		qdiscs := mockNetlinkAdapter.GetQdiscs(device).Value()
+		require.Len(t, qdiscs, 1)
+		assert.Equal(t, entities.QdiscTypeETS, qdiscs[0].Type)
+	})
+
+	t.Run("applies_taprio_qdisc", func(t *testing.T) {
+		mockNetlinkAdapter := useMock()
+
+		err := controller.CreateTAPRIOQdisc("100:0").
+			WithTrafficClasses(3).
+			WithPriomap(2, 2, 1, 0).
+			WithQueues(1, 0).
+			WithQueues(1, 1).
+			WithQueues(2, 2).
+			WithBaseTime(time.Unix(1700000000, 0)).
+			WithGateEntry(0x1, 300*time.Microsecond).
+			WithGateEntry(0x6, 700*time.Microsecond).
+			WithTxTimeAssist(200 * time.Microsecond).
+			Apply()
+		require.NoError(t, err)
+
+		qdiscs := mockNetlinkAdapter.GetQdiscs(device).Value()
+		require.Len(t, qdiscs, 1)
+		assert.Equal(t, entities.QdiscTypeTAPRIO, qdiscs[0].Type)
+	})
+
+	t.Run("attaches_ets_leaf_qdisc", func(t *testing.T) {
+		controller := NetworkInterface("eth0")
+		controller.WithHardLimitBandwidth("100mbps")
+		controller.CreateTrafficClass("video").
+			WithGuaranteedBandwidth("40mbps").
+			WithPriority(1).
+			WithLeafQdisc(ETS().Strict(1).Quanta(1500, 1500))
+
+		mockNetlinkAdapter := netlink.NewMockAdapter()
+		controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+		require.NoError(t, controller.Apply())
+
+		types := make(map[tc.Handle]entities.QdiscType)
+		for _, q := range mockNetlinkAdapter.GetQdiscs(device).Value() {
+			types[q.Handle] = q.Type
+		}
+		assert.Equal(t, entities.QdiscTypeETS, types[tc.NewHandle(0x11, 0)])
+	})
+
+	t.Run("rejects_invalid_parameters", func(t *testing.T) {
+		useMock()
+
+		err := controller.CreateETSQdisc("1:0").WithStrictBands(2).WithPriomap(0, 3).Apply()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "band 3")
+
+		err = controller.CreateTAPRIOQdisc("100:0").
+			WithTrafficClasses(2).
+			WithQueues(2, 0).
+			WithQueues(1, 1).
+			WithGateEntry(0x3, time.Millisecond).
+			Apply()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "overlap")
+
+		err = controller.CreateTAPRIOQdisc("100:0").
+			WithTrafficClasses(1).
+			WithQueues(1, 0).
+			WithGateEntry(0x1, time.Millisecond).
+			WithClock("CLOCK_WALL").
+			Apply()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "CLOCK_WALL")
+	})
+}
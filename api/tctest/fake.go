@@ -0,0 +1,486 @@
+// Package tctest provides a fake netlink adapter for unit testing code built on the api
+// package without root privileges or real network interfaces. The fake keeps the qdiscs,
+// classes and filters installed in memory, records every change made through it, and can
+// be told to fail operations the way the kernel would. RemoveRootQdisc, SetLinkState and
+// RemoveLink simulate changes made outside the library, such as a VPN recreating its device.
+//
+//	controller, fake := tctest.NewController("eth0")
+//	fake.FailOn(tctest.OpAddFilter, syscall.EPERM)
+//	err := controller.Apply() // fails with an *api.ApplyError wrapping EPERM
+package tctest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rng999/traffic-control-go/api"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/types"
+)
+
+// Op identifies a kind of change made through the adapter
+type Op string
+
+const (
+	OpAddQdisc              Op = "add qdisc"
+	OpDeleteQdisc           Op = "delete qdisc"
+	OpAddClass              Op = "add class"
+	OpDeleteClass           Op = "delete class"
+	OpAddFilter             Op = "add filter"
+	OpDeleteFilter          Op = "delete filter"
+	OpAddIngressRedirect    Op = "add ingress redirect"
+	OpDeleteIngressRedirect Op = "delete ingress redirect"
+)
+
+// Operation is a change requested through the adapter. Handles are in tc syntax; fields
+// that do not apply to the operation are empty.
+type Operation struct {
+	Op       Op
+	Device   string
+	Handle   string
+	Parent   string
+	Priority uint16 // Filter priority
+	FlowID   string // Class a filter directs packets to
+	Target   string // IFB device of an ingress redirect
+	Err      error  // Error the operation failed with, nil if it succeeded
+}
+
+// String describes the operation, e.g. "add class eth0 1:11 parent 1:"
+func (o Operation) String() string {
+	s := fmt.Sprintf("%s %s", o.Op, o.Device)
+	if o.Handle != "" {
+		s += " " + o.Handle
+	}
+	if o.Parent != "" {
+		s += " parent " + o.Parent
+	}
+	if o.Op == OpAddFilter || o.Op == OpDeleteFilter {
+		s += fmt.Sprintf(" prio %d", o.Priority)
+	}
+	if o.FlowID != "" {
+		s += " flowid " + o.FlowID
+	}
+	if o.Target != "" {
+		s += " to " + o.Target
+	}
+	return s
+}
+
+// Qdisc is a qdisc installed on the fake
+type Qdisc struct {
+	Handle string
+	Parent string // Empty for a root qdisc
+	Type   string
+}
+
+// Class is a class installed on the fake
+type Class struct {
+	Handle string
+	Parent string
+}
+
+// Filter is a filter installed on the fake
+type Filter struct {
+	Parent   string
+	Priority uint16
+	Handle   string
+	FlowID   string
+	Matches  []string
+}
+
+// ClassStats are the counters reported for a class
+type ClassStats struct {
+	BytesSent      uint64
+	PacketsSent    uint64
+	PacketsDropped uint64
+	Overlimits     uint64
+	RateBPS        uint64 // Current rate in bits per second
+	BacklogBytes   uint64
+	BacklogPackets uint64
+}
+
+// SFQStats are the statistics reported for an SFQ qdisc
+type SFQStats struct {
+	ActiveFlows uint32 // Flows currently holding packets
+	FlowBuckets uint32
+	Limit       uint32
+}
+
+// failure is an error injected for an operation
+type failure struct {
+	err  error
+	once bool
+}
+
+// Fake is an in-memory netlink adapter. It is safe for concurrent use.
+type Fake struct {
+	mock *netlink.MockAdapter // Holds the installed objects
+
+	mu         sync.Mutex
+	operations []Operation
+	failures   map[Op][]failure
+}
+
+// New creates an empty fake adapter
+func New() *Fake {
+	return &Fake{
+		mock:     netlink.NewMockAdapter(),
+		failures: make(map[Op][]failure),
+	}
+}
+
+// NewController creates a traffic controller for the device that changes the fake
+// instead of the kernel
+func NewController(device string) (*api.TrafficController, *Fake) {
+	fake := New()
+	return api.NetworkInterface(device).WithNetlinkAdapter(fake), fake
+}
+
+// FailOn makes every following operation of the kind fail with err, until Reset
+func (f *Fake) FailOn(op Op, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[op] = append(f.failures[op], failure{err: err})
+}
+
+// FailNext makes the next operation of the kind fail with err. Errors given for the same
+// kind are returned in turn, before any error given to FailOn.
+func (f *Fake) FailNext(op Op, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pending := f.failures[op]
+	i := 0
+	for i < len(pending) && pending[i].once {
+		i++
+	}
+	pending = append(pending, failure{})
+	copy(pending[i+1:], pending[i:])
+	pending[i] = failure{err: err, once: true}
+	f.failures[op] = pending
+}
+
+// Reset clears the injected errors and the recorded operations. Installed objects are kept.
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.operations = nil
+	f.failures = make(map[Op][]failure)
+}
+
+// Operations returns the operations requested so far, failed ones included, in order
+func (f *Fake) Operations() []Operation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Operation(nil), f.operations...)
+}
+
+// Qdiscs returns the qdiscs installed on the device
+func (f *Fake) Qdiscs(device string) []Qdisc {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil
+	}
+	var qdiscs []Qdisc
+	for _, info := range f.GetQdiscs(deviceName).Value() {
+		qdisc := Qdisc{Handle: info.Handle.String(), Type: info.Type.String()}
+		if info.Parent != nil {
+			qdisc.Parent = info.Parent.String()
+		}
+		qdiscs = append(qdiscs, qdisc)
+	}
+	return qdiscs
+}
+
+// Classes returns the classes installed on the device
+func (f *Fake) Classes(device string) []Class {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil
+	}
+	var classes []Class
+	for _, info := range f.GetClasses(deviceName).Value() {
+		classes = append(classes, Class{Handle: info.Handle.String(), Parent: info.Parent.String()})
+	}
+	return classes
+}
+
+// Filters returns the filters installed on the device, in the order they were added
+func (f *Fake) Filters(device string) []Filter {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil
+	}
+	var filters []Filter
+	for _, info := range f.GetFilters(deviceName).Value() {
+		filter := Filter{
+			Parent:   info.Parent.String(),
+			Priority: info.Priority,
+			Handle:   info.Handle.String(),
+			FlowID:   info.FlowID.String(),
+		}
+		for _, match := range info.Matches {
+			filter.Matches = append(filter.Matches, fmt.Sprint(match.Value))
+		}
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+// record returns the error injected for the operation, if any, and records the operation
+// with the outcome reported by do, which runs only when no error was injected
+func (f *Fake) record(operation Operation, do func() error) error {
+	f.mu.Lock()
+	var err error
+	if pending := f.failures[operation.Op]; len(pending) > 0 {
+		err = pending[0].err
+		if pending[0].once {
+			f.failures[operation.Op] = pending[1:]
+		}
+	}
+	f.mu.Unlock()
+
+	if err == nil {
+		err = do()
+	}
+
+	operation.Err = err
+	f.mu.Lock()
+	f.operations = append(f.operations, operation)
+	f.mu.Unlock()
+	return err
+}
+
+// unitResult converts an error to the result type of the adapter's delete operations
+func unitResult(err error) types.Result[netlink.Unit] {
+	if err != nil {
+		return types.Failure[netlink.Unit](err)
+	}
+	return types.Success(netlink.Unit{})
+}
+
+// AddQdisc installs a qdisc unless an error is injected
+func (f *Fake) AddQdisc(ctx context.Context, qdisc *entities.Qdisc) error {
+	operation := Operation{Op: OpAddQdisc, Device: qdisc.ID().Device().String(), Handle: qdisc.Handle().String()}
+	if qdisc.Parent() != nil {
+		operation.Parent = qdisc.Parent().String()
+	}
+	return f.record(operation, func() error {
+		return f.mock.AddQdisc(ctx, qdisc)
+	})
+}
+
+// DeleteQdisc removes a qdisc unless an error is injected
+func (f *Fake) DeleteQdisc(device tc.DeviceName, handle tc.Handle) types.Result[netlink.Unit] {
+	operation := Operation{Op: OpDeleteQdisc, Device: device.String(), Handle: handle.String()}
+	return unitResult(f.record(operation, func() error {
+		return f.mock.DeleteQdisc(device, handle).Error()
+	}))
+}
+
+// AddClass installs a class unless an error is injected
+func (f *Fake) AddClass(ctx context.Context, class interface{}) error {
+	operation := Operation{Op: OpAddClass}
+	switch c := class.(type) {
+	case *entities.Class:
+		operation.Device = c.ID().Device().String()
+		operation.Handle = c.Handle().String()
+		operation.Parent = c.Parent().String()
+	case *entities.HTBClass:
+		operation.Device = c.ID().Device().String()
+		operation.Handle = c.Handle().String()
+		operation.Parent = c.Parent().String()
+	case *entities.DRRClass:
+		operation.Device = c.ID().Device().String()
+		operation.Handle = c.Handle().String()
+		operation.Parent = c.Parent().String()
+	case *entities.QFQClass:
+		operation.Device = c.ID().Device().String()
+		operation.Handle = c.Handle().String()
+		operation.Parent = c.Parent().String()
+	}
+	return f.record(operation, func() error {
+		return f.mock.AddClass(ctx, class)
+	})
+}
+
+// DeleteClass removes a class unless an error is injected
+func (f *Fake) DeleteClass(device tc.DeviceName, handle tc.Handle) types.Result[netlink.Unit] {
+	operation := Operation{Op: OpDeleteClass, Device: device.String(), Handle: handle.String()}
+	return unitResult(f.record(operation, func() error {
+		return f.mock.DeleteClass(device, handle).Error()
+	}))
+}
+
+// AddFilter installs a filter unless an error is injected
+func (f *Fake) AddFilter(ctx context.Context, filter *entities.Filter) error {
+	operation := Operation{
+		Op:       OpAddFilter,
+		Device:   filter.ID().Device().String(),
+		Handle:   filter.ID().Handle().String(),
+		Parent:   filter.ID().Parent().String(),
+		Priority: filter.ID().Priority(),
+		FlowID:   filter.FlowID().String(),
+	}
+	return f.record(operation, func() error {
+		return f.mock.AddFilter(ctx, filter)
+	})
+}
+
+// DeleteFilter removes a filter unless an error is injected
+func (f *Fake) DeleteFilter(device tc.DeviceName, parent tc.Handle, priority uint16, handle tc.Handle) types.Result[netlink.Unit] {
+	operation := Operation{
+		Op:       OpDeleteFilter,
+		Device:   device.String(),
+		Handle:   handle.String(),
+		Parent:   parent.String(),
+		Priority: priority,
+	}
+	return unitResult(f.record(operation, func() error {
+		return f.mock.DeleteFilter(device, parent, priority, handle).Error()
+	}))
+}
+
+// AddIngressRedirect redirects the device's ingress unless an error is injected
+func (f *Fake) AddIngressRedirect(ctx context.Context, device, ifb tc.DeviceName) error {
+	operation := Operation{Op: OpAddIngressRedirect, Device: device.String(), Target: ifb.String()}
+	return f.record(operation, func() error {
+		return f.mock.AddIngressRedirect(ctx, device, ifb)
+	})
+}
+
+// DeleteIngressRedirect removes an ingress redirect unless an error is injected
+func (f *Fake) DeleteIngressRedirect(device, ifb tc.DeviceName) types.Result[netlink.Unit] {
+	operation := Operation{Op: OpDeleteIngressRedirect, Device: device.String(), Target: ifb.String()}
+	return unitResult(f.record(operation, func() error {
+		return f.mock.DeleteIngressRedirect(device, ifb).Error()
+	}))
+}
+
+// SetClassStatistics sets the counters reported for an installed class
+func (f *Fake) SetClassStatistics(device tc.DeviceName, handle tc.Handle, stats ClassStats) {
+	f.mock.SetClassStatistics(device, handle, netlink.ClassStats(stats))
+}
+
+// SetSFQStatistics sets the statistics reported for an SFQ qdisc
+func (f *Fake) SetSFQStatistics(device tc.DeviceName, handle tc.Handle, stats SFQStats) {
+	f.mock.SetSFQStatistics(device, handle, &netlink.SFQQdiscStats{
+		ActiveFlows: stats.ActiveFlows,
+		FlowBuckets: stats.FlowBuckets,
+		Limit:       stats.Limit,
+	})
+}
+
+// RemoveRootQdisc removes the device's root qdisc with all classes and filters, as another
+// process such as NetworkManager would
+func (f *Fake) RemoveRootQdisc(device tc.DeviceName) {
+	f.mock.RemoveRootQdisc(device)
+}
+
+// SetLinkState brings the device's link up or down. A device removed with RemoveLink is
+// created again, with a new index.
+func (f *Fake) SetLinkState(device tc.DeviceName, up bool) {
+	f.mock.SetLinkState(device, up)
+}
+
+// RemoveLink deletes the device, taking its qdiscs, classes and filters with it, as
+// restarting a VPN does with its tun device
+func (f *Fake) RemoveLink(device tc.DeviceName) {
+	f.mock.RemoveLink(device)
+}
+
+// The remaining adapter operations are served by the mock without being recorded
+
+// GetQdiscs returns the qdiscs installed on the device
+func (f *Fake) GetQdiscs(device tc.DeviceName) types.Result[[]netlink.QdiscInfo] {
+	return f.mock.GetQdiscs(device)
+}
+
+// ChangeClass changes an installed class in place
+func (f *Fake) ChangeClass(ctx context.Context, class interface{}) error {
+	return f.mock.ChangeClass(ctx, class)
+}
+
+// GetClasses returns the classes installed on the device
+func (f *Fake) GetClasses(device tc.DeviceName) types.Result[[]netlink.ClassInfo] {
+	return f.mock.GetClasses(device)
+}
+
+// GetFilters returns the filters installed on the device
+func (f *Fake) GetFilters(device tc.DeviceName) types.Result[[]netlink.FilterInfo] {
+	return f.mock.GetFilters(device)
+}
+
+// GetDetailedQdiscStats returns the statistics of a qdisc
+func (f *Fake) GetDetailedQdiscStats(device tc.DeviceName, handle tc.Handle) types.Result[netlink.DetailedQdiscStats] {
+	return f.mock.GetDetailedQdiscStats(device, handle)
+}
+
+// GetDetailedClassStats returns the statistics of a class
+func (f *Fake) GetDetailedClassStats(device tc.DeviceName, handle tc.Handle) types.Result[netlink.DetailedClassStats] {
+	return f.mock.GetDetailedClassStats(device, handle)
+}
+
+// GetLinkStats returns the statistics of the device's link
+func (f *Fake) GetLinkStats(device tc.DeviceName) types.Result[netlink.LinkStats] {
+	return f.mock.GetLinkStats(device)
+}
+
+// WatchQdiscDeletions reports qdiscs removed from the device until the context is cancelled
+func (f *Fake) WatchQdiscDeletions(ctx context.Context, device tc.DeviceName) (<-chan netlink.QdiscDeletion, error) {
+	return f.mock.WatchQdiscDeletions(ctx, device)
+}
+
+// WatchLinks reports changes of the device's link until the context is cancelled
+func (f *Fake) WatchLinks(ctx context.Context, device tc.DeviceName) (<-chan netlink.LinkEvent, error) {
+	return f.mock.WatchLinks(ctx, device)
+}
+
+// GetLinkInfo returns the device's link
+func (f *Fake) GetLinkInfo(device tc.DeviceName) types.Result[netlink.LinkInfo] {
+	return f.mock.GetLinkInfo(device)
+}
+
+// GetBridgePorts returns the ports of a bridge
+func (f *Fake) GetBridgePorts(bridge tc.DeviceName) types.Result[[]tc.DeviceName] {
+	return f.mock.GetBridgePorts(bridge)
+}
+
+// EnsureClsactQdisc installs the device's clsact qdisc if it is missing
+func (f *Fake) EnsureClsactQdisc(ctx context.Context, device tc.DeviceName, hook netlink.ClsactHook) (bool, error) {
+	return f.mock.EnsureClsactQdisc(ctx, device, hook)
+}
+
+// DeleteClsactQdisc removes the device's clsact qdisc
+func (f *Fake) DeleteClsactQdisc(device tc.DeviceName) types.Result[netlink.Unit] {
+	return f.mock.DeleteClsactQdisc(device)
+}
+
+// AttachBPFClassifier attaches a BPF classifier to a clsact hook
+func (f *Fake) AttachBPFClassifier(ctx context.Context, device tc.DeviceName, classifier netlink.BPFClassifier) error {
+	return f.mock.AttachBPFClassifier(ctx, device, classifier)
+}
+
+// DetachBPFClassifier detaches a BPF classifier from a clsact hook
+func (f *Fake) DetachBPFClassifier(device tc.DeviceName, hook netlink.ClsactHook, priority uint16) types.Result[netlink.Unit] {
+	return f.mock.DetachBPFClassifier(device, hook, priority)
+}
+
+// UpdateBPFMap sets an entry of a pinned BPF map
+func (f *Fake) UpdateBPFMap(path string, key, value []byte) error {
+	return f.mock.UpdateBPFMap(path, key, value)
+}
+
+// DeleteBPFMapEntry removes an entry of a pinned BPF map
+func (f *Fake) DeleteBPFMapEntry(path string, key []byte) types.Result[netlink.Unit] {
+	return f.mock.DeleteBPFMapEntry(path, key)
+}
+
+// CheckPrivileges succeeds, the fake needs no privilege
+func (f *Fake) CheckPrivileges(ctx context.Context) error {
+	return f.mock.CheckPrivileges(ctx)
+}
+
+var _ netlink.Adapter = (*Fake)(nil)
@@ -0,0 +1,82 @@
+package tctest
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func webController() (*Fake, func() error) {
+	controller, fake := NewController("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithSoftLimitBandwidth("60mbps").
+		WithPriority(1).
+		ForPort(80)
+	return fake, controller.Apply
+}
+
+func TestFake_RecordsOperations(t *testing.T) {
+	fake, apply := webController()
+	require.NoError(t, apply())
+
+	var ops []string
+	for _, operation := range fake.Operations() {
+		assert.NoError(t, operation.Err)
+		ops = append(ops, operation.String())
+	}
+	assert.Equal(t, []string{
+		"add qdisc eth0 1:",
+		"add class eth0 1:11 parent 1:",
+		"add filter eth0 800:64 parent 1: prio 100 flowid 1:11",
+		"add class eth0 1:999 parent 1:",
+	}, ops)
+
+	require.Len(t, fake.Qdiscs("eth0"), 1)
+	assert.Equal(t, Qdisc{Handle: "1:", Type: "htb"}, fake.Qdiscs("eth0")[0])
+	assert.Len(t, fake.Classes("eth0"), 2)
+	require.Len(t, fake.Filters("eth0"), 1)
+	assert.Equal(t, "1:11", fake.Filters("eth0")[0].FlowID)
+	assert.Empty(t, fake.Qdiscs("eth1"))
+}
+
+func TestFake_FailOn(t *testing.T) {
+	fake, apply := webController()
+	fake.FailOn(OpAddFilter, syscall.EPERM)
+
+	err := apply()
+	require.Error(t, err)
+	assert.ErrorIs(t, err, syscall.EPERM)
+
+	operations := fake.Operations()
+	failed := operations[2]
+	assert.Equal(t, OpAddFilter, failed.Op)
+	assert.ErrorIs(t, failed.Err, syscall.EPERM)
+	// The failed Apply is rolled back
+	assert.Empty(t, fake.Filters("eth0"))
+	assert.Empty(t, fake.Classes("eth0"))
+	assert.Empty(t, fake.Qdiscs("eth0"))
+}
+
+func TestFake_FailNext(t *testing.T) {
+	fake := New()
+	fake.FailOn(OpAddQdisc, syscall.EBUSY)
+	fake.FailNext(OpAddQdisc, syscall.EEXIST)
+	fake.FailNext(OpAddQdisc, syscall.ENOENT)
+
+	controller, _ := NewController("eth0")
+	controller.WithNetlinkAdapter(fake).WithHardLimitBandwidth("10mbps")
+	controller.CreateTrafficClass("bulk").WithGuaranteedBandwidth("1mbps").WithPriority(4).ForPort(22)
+
+	for _, want := range []error{syscall.EEXIST, syscall.ENOENT, syscall.EBUSY, syscall.EBUSY} {
+		assert.ErrorIs(t, controller.Apply(), want)
+	}
+
+	fake.Reset()
+	assert.Empty(t, fake.Operations())
+	require.NoError(t, controller.Apply())
+	assert.Len(t, fake.Classes("eth0"), 2)
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// templateVariable matches a ${name} reference to a template variable
+var templateVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_-]*)\}`)
+
+// TemplateVars are the variables a class template is instantiated with, such as the VLAN or
+// IP block of one tenant
+type TemplateVars map[string]string
+
+// Expand replaces the ${name} references in s with the values of the variables. References
+// to undefined variables are kept, so that validation rejects the value holding them.
+func (vars TemplateVars) Expand(s string) string {
+	return templateVariable.ReplaceAllStringFunc(s, func(reference string) string {
+		if value, ok := vars[templateVariable.FindStringSubmatch(reference)[1]]; ok {
+			return value
+		}
+		return reference
+	})
+}
+
+// ClassTemplate builds a traffic class from the variables of one instance
+type ClassTemplate func(class *TrafficClassBuilder, vars TemplateVars)
+
+// DefineClassTemplate registers a class template under the name, replacing any template of
+// the name, for CreateClassFromTemplate to instantiate:
+//
+//	controller.DefineClassTemplate("standard-tenant", func(class *api.TrafficClassBuilder, vars api.TemplateVars) {
+//		class.WithGuaranteedBandwidth(vars["guaranteed"]).WithSoftLimitBandwidth("1gbps").
+//			WithPriority(4).ForSource(vars["subnet"])
+//	})
+func (controller *TrafficController) DefineClassTemplate(name string, template ClassTemplate) *TrafficController {
+	if controller.classTemplates == nil {
+		controller.classTemplates = make(map[string]ClassTemplate)
+	}
+	controller.classTemplates[name] = template
+	return controller
+}
+
+// CreateClassFromTemplate creates a traffic class built by the template from the variables.
+// The name may reference them, e.g. "tenant-${vlan}". The builder returned can adjust the
+// class further; a template that is not defined fails validation.
+//
+//	controller.CreateClassFromTemplate("standard-tenant", "tenant-${vlan}",
+//		api.TemplateVars{"vlan": "100", "subnet": "10.0.100.0/24", "guaranteed": "50mbps"})
+func (controller *TrafficController) CreateClassFromTemplate(template, name string, vars TemplateVars) *TrafficClassBuilder {
+	builder := controller.CreateTrafficClass(vars.Expand(name))
+	builder.class.template = template
+	if build, ok := controller.classTemplates[template]; ok {
+		build(builder, vars)
+	}
+	return builder
+}
+
+// validateTemplate checks that the template the class was created from is defined
+func (controller *TrafficController) validateTemplate(report *ValidationReport, class *TrafficClass) {
+	if class.template == "" {
+		return
+	}
+	if _, ok := controller.classTemplates[class.template]; !ok {
+		controller.addError(report, "unknown_template", class.name,
+			"class '%s' is created from template '%s', which is not defined\n"+
+				"Suggestion: Define it with DefineClassTemplate() before applying",
+			class.name, class.template)
+	}
+}
+
+// ClassTemplateConfig is a class of the configuration whose values may reference
+// ${variables}, instantiated by ClassInstanceConfig. A value that is a single reference
+// takes the type of the variable's value, so that "priority: ${priority}" is a number.
+type ClassTemplateConfig struct {
+	Name      string                 `yaml:"name" json:"name"`
+	Variables map[string]string      `yaml:"variables,omitempty" json:"variables,omitempty"` // Default values of variables
+	Class     map[string]interface{} `yaml:"class" json:"class"`                             // A class as in classes, children included
+	Match     map[string]interface{} `yaml:"match,omitempty" json:"match,omitempty"`         // Filters of the class as in rules
+}
+
+// ClassInstanceConfig creates a class from a template with the variables
+type ClassInstanceConfig struct {
+	Template string            `yaml:"template" json:"template"`
+	Vars     map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`
+}
+
+// expandTemplates returns the classes and rules of the configuration with the classes and
+// rules of its template instances added
+func (c *TrafficControlConfig) expandTemplates() ([]TrafficClassConfig, []TrafficRuleConfig, error) {
+	templates := make(map[string]*ClassTemplateConfig, len(c.Templates))
+	for i := range c.Templates {
+		template := &c.Templates[i]
+		if template.Name == "" {
+			return nil, nil, fmt.Errorf("template name is required")
+		}
+		if templates[template.Name] != nil {
+			return nil, nil, fmt.Errorf("duplicate template name: %s", template.Name)
+		}
+		templates[template.Name] = template
+	}
+
+	if len(c.Instances) == 0 {
+		return c.Classes, c.Rules, nil
+	}
+	classes := append([]TrafficClassConfig(nil), c.Classes...)
+	rules := append([]TrafficRuleConfig(nil), c.Rules...)
+	for i, instance := range c.Instances {
+		template := templates[instance.Template]
+		if template == nil {
+			return nil, nil, fmt.Errorf("instance %d: template '%s' not found", i+1, instance.Template)
+		}
+		class, rule, err := template.instantiate(instance.Vars)
+		if err != nil {
+			return nil, nil, fmt.Errorf("instance %d of template %s: %w", i+1, template.Name, err)
+		}
+		classes = append(classes, class)
+		if rule != nil {
+			rules = append(rules, *rule)
+		}
+	}
+	return classes, rules, nil
+}
+
+// instantiate expands the template with the variables over its defaults, returning the
+// class and the rule of its match, nil without one
+func (t *ClassTemplateConfig) instantiate(instanceVars map[string]string) (TrafficClassConfig, *TrafficRuleConfig, error) {
+	vars := make(TemplateVars, len(t.Variables)+len(instanceVars))
+	for name, value := range t.Variables {
+		vars[name] = value
+	}
+	for name, value := range instanceVars {
+		vars[name] = value
+	}
+
+	var class TrafficClassConfig
+	if err := expandTemplateValue(t.Class, vars, &class); err != nil {
+		return class, nil, fmt.Errorf("class: %w", err)
+	}
+	if class.Name == "" {
+		return class, nil, fmt.Errorf("class name is required")
+	}
+	if len(t.Match) == 0 {
+		return class, nil, nil
+	}
+	rule := &TrafficRuleConfig{Name: class.Name, Target: class.Name}
+	if err := expandTemplateValue(t.Match, vars, &rule.Match); err != nil {
+		return class, nil, fmt.Errorf("match: %w", err)
+	}
+	return class, rule, nil
+}
+
+// expandTemplateValue expands the variables in the template value and decodes it into out
+func expandTemplateValue(value interface{}, vars TemplateVars, out interface{}) error {
+	expanded, err := expandValue(value, vars)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(expanded)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(out)
+}
+
+// expandValue returns a copy of a decoded YAML or JSON value with the variables expanded in
+// its strings. A string that is a single reference takes the type of the variable's value.
+func expandValue(value interface{}, vars TemplateVars) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		for _, reference := range templateVariable.FindAllStringSubmatch(value, -1) {
+			if _, ok := vars[reference[1]]; !ok {
+				return nil, fmt.Errorf("undefined variable %q", reference[1])
+			}
+		}
+		if reference := templateVariable.FindStringSubmatch(value); reference != nil && reference[0] == value {
+			return scalarValue(vars[reference[1]]), nil
+		}
+		return vars.Expand(value), nil
+	case map[string]interface{}:
+		expanded := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			item, err := expandValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			expanded[key] = item
+		}
+		return expanded, nil
+	case []interface{}:
+		expanded := make([]interface{}, len(value))
+		for i, item := range value {
+			item, err := expandValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			expanded[i] = item
+		}
+		return expanded, nil
+	default:
+		return value, nil
+	}
+}
+
+// scalarValue returns the number or boolean a variable's value spells, or the value itself
+func scalarValue(value string) interface{} {
+	var scalar interface{}
+	if err := yaml.Unmarshal([]byte(value), &scalar); err != nil {
+		return value
+	}
+	switch scalar.(type) {
+	case int, float64, bool:
+		return scalar
+	}
+	return value
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+)
+
+func TestTemplateVars_Expand(t *testing.T) {
+	vars := TemplateVars{"vlan": "100", "tenant": "acme"}
+
+	assert.Equal(t, "acme-100", vars.Expand("${tenant}-${vlan}"))
+	assert.Equal(t, "${subnet}", vars.Expand("${subnet}"), "undefined references are kept")
+	assert.Equal(t, "$vlan", vars.Expand("$vlan"))
+}
+
+func TestClassTemplate(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.DefineClassTemplate("standard-tenant", func(class *TrafficClassBuilder, vars TemplateVars) {
+		class.WithGuaranteedBandwidth(vars["guaranteed"]).WithSoftLimitBandwidth("100mbps").
+			WithPriority(4).ForSource(vars["subnet"])
+	})
+	tenants := []TemplateVars{
+		{"vlan": "100", "subnet": "10.0.100.0/24", "guaranteed": "40mbps"},
+		{"vlan": "200", "subnet": "10.0.200.0/24", "guaranteed": "20mbps"},
+	}
+	for _, vars := range tenants {
+		controller.CreateClassFromTemplate("standard-tenant", "tenant-${vlan}", vars)
+	}
+	// An instance adjusted after its template
+	controller.CreateClassFromTemplate("standard-tenant", "tenant-${vlan}",
+		TemplateVars{"vlan": "300", "subnet": "10.0.44.0/24", "guaranteed": "30mbps"}).WithPriority(2)
+
+	report := controller.Validate()
+	require.Empty(t, report.Errors)
+
+	classes := plannedClasses(t, controller)
+	require.Len(t, classes, 4, "three tenants and the default class")
+	assert.Equal(t, "40.0Mbps", classes["tenant-100"].Rate)
+	assert.Equal(t, "20.0Mbps", classes["tenant-200"].Rate)
+	assert.Equal(t, 4, classes["tenant-200"].Priority)
+	assert.Equal(t, 2, classes["tenant-300"].Priority)
+	assert.Equal(t, "10.0.200.0/24", controller.classByName("tenant-200").filters[0].value)
+
+	require.NoError(t, controller.Apply())
+}
+
+func TestClassTemplate_Unknown(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateClassFromTemplate("standard-tenant", "tenant-a", nil).WithGuaranteedBandwidth("10mbps").WithPriority(1)
+
+	report := controller.Validate()
+	issue := findIssue(report.Errors, "unknown_template")
+	require.NotNil(t, issue)
+	assert.Equal(t, "tenant-a", issue.Class)
+}
+
+func TestClassTemplate_Config(t *testing.T) {
+	config, err := ParseConfigFromYAML([]byte(`
+device: eth0
+bandwidth: 100mbps
+classes:
+  - name: tenants
+    guaranteed: 90mbps
+    maximum: 100mbps
+    priority: 3
+templates:
+  - name: standard-tenant
+    variables:
+      guaranteed: 20mbps
+      priority: "4"
+    class:
+      name: tenant-${vlan}
+      parent: tenants
+      guaranteed: ${guaranteed}
+      maximum: 100mbps
+      priority: ${priority}
+    match:
+      source_ip: ${subnet}
+      dest_port: ["${port}", 443]
+instances:
+  - template: standard-tenant
+    vars: {vlan: "100", subnet: 10.0.100.0/24, port: "80"}
+  - template: standard-tenant
+    vars: {vlan: "200", subnet: 10.0.200.0/24, port: "8080", guaranteed: 50mbps, priority: "2"}
+`))
+	require.NoError(t, err)
+
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	require.NoError(t, controller.ApplyConfig(config))
+	require.True(t, controller.Validate().Valid())
+
+	classes := plannedClasses(t, controller)
+	assert.Equal(t, "20.0Mbps", classes["tenant-100"].Rate, "the default of the variable")
+	assert.Equal(t, 4, classes["tenant-100"].Priority)
+	assert.Equal(t, "50.0Mbps", classes["tenant-200"].Rate)
+	assert.Equal(t, 2, classes["tenant-200"].Priority)
+	assert.Equal(t, classes["tenants"].Handle, classes["tenant-200"].Parent)
+
+	var values []interface{}
+	for _, filter := range controller.classByName("tenant-200").filters {
+		values = append(values, filter.value)
+	}
+	assert.Equal(t, []interface{}{"10.0.200.0/24", 8080, 443}, values)
+}
+
+func TestClassTemplate_ConfigErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates string
+		instances string
+		want      string
+	}{
+		{
+			name: "unknown template",
+			templates: `
+  - name: standard-tenant
+    class: {name: tenant, guaranteed: 10mbps, priority: 1}`,
+			instances: `
+  - template: premium-tenant`,
+			want: "template 'premium-tenant' not found",
+		},
+		{
+			name: "undefined variable",
+			templates: `
+  - name: standard-tenant
+    class: {name: "tenant-${vlan}", guaranteed: 10mbps, priority: 1}`,
+			instances: `
+  - template: standard-tenant`,
+			want: `undefined variable "vlan"`,
+		},
+		{
+			name: "unknown field",
+			templates: `
+  - name: standard-tenant
+    class: {name: tenant, guaranteed: 10mbps, priority: 1, burst: 10kb}`,
+			instances: `
+  - template: standard-tenant`,
+			want: `unknown field "burst"`,
+		},
+		{
+			name: "duplicate instance",
+			templates: `
+  - name: standard-tenant
+    class: {name: tenant, guaranteed: 10mbps, priority: 1}`,
+			instances: `
+  - template: standard-tenant
+  - template: standard-tenant`,
+			want: "duplicate class name: tenant",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfigFromYAML([]byte("device: eth0\nbandwidth: 100mbps\ntemplates:" + tt.templates +
+				"\ninstances:" + tt.instances + "\n"))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+// WithProfile selects the tuning profile the burst, cburst and quantum of the class are
+// derived with: "default", "low-latency" or "bulk-throughput". Low-latency bursts 8ms of
+// traffic and sends one frame per round, so that the class adds little delay to the others;
+// bulk-throughput bursts 250ms of traffic and sends 4ms per round, so that fast links do not
+// idle between timer ticks. Validation fails for other names.
+func (b *TrafficClassBuilder) WithProfile(name string) *TrafficClassBuilder {
+	b.class.profile = name
+	return b
+}
+
+// validateProfile checks the tuning profile of the class
+func (controller *TrafficController) validateProfile(report *ValidationReport, class *TrafficClass) {
+	if _, err := entities.ParseTuningProfile(class.profile); err != nil {
+		controller.addError(report, "invalid_profile", class.name,
+			"class '%s' has an unknown tuning profile %q\n"+
+				"Suggestion: Use one of %s",
+			class.name, class.profile, strings.Join(entities.TuningProfileNames(), ", "))
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+func TestWithProfile(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("30mbps").WithSoftLimitBandwidth("60mbps").WithPriority(1)
+	controller.CreateTrafficClass("voice").WithGuaranteedBandwidth("30mbps").WithSoftLimitBandwidth("60mbps").WithPriority(2).
+		WithProfile("low-latency")
+	controller.CreateTrafficClass("backups").WithGuaranteedBandwidth("30mbps").WithSoftLimitBandwidth("60mbps").WithPriority(3).
+		WithProfile("bulk-throughput")
+
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	classes := make(map[string]qmodels.PlannedClassView)
+	for _, class := range plan.Classes {
+		classes[class.Name] = class
+	}
+
+	// 64ms, 8ms and 250ms of the rate and ceil, plus 4 bytes of overhead per 1500-byte packet
+	assert.Equal(t, [3]uint32{240640, 481280, 3750}, plannedTuning(classes["web"]))
+	assert.Equal(t, [3]uint32{30080, 60160, 1514}, plannedTuning(classes["voice"]))
+	assert.Equal(t, [3]uint32{940000, 1880000, 15000}, plannedTuning(classes["backups"]))
+
+	require.NoError(t, controller.Apply())
+}
+
+// plannedTuning returns the burst, cburst and quantum of a planned class
+func plannedTuning(class qmodels.PlannedClassView) [3]uint32 {
+	return [3]uint32{class.Burst, class.Cburst, class.Quantum}
+}
+
+func TestWithProfile_Validation(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("30mbps").WithPriority(1).WithProfile("turbo")
+
+	report := controller.Validate()
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, "invalid_profile", report.Errors[0].Code)
+	assert.Contains(t, report.Errors[0].Message, "bulk-throughput, default, low-latency")
+}
+
+func TestWithProfile_Config(t *testing.T) {
+	config, err := ParseConfigFromYAML([]byte(`
+device: eth0
+bandwidth: 1gbps
+classes:
+  - name: backups
+    guaranteed: 100mbps
+    priority: 4
+    profile: bulk-throughput
+`))
+	require.NoError(t, err)
+
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	require.NoError(t, controller.ApplyConfig(config))
+	require.Len(t, controller.classes, 1)
+	assert.Equal(t, "bulk-throughput", controller.classes[0].profile)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+// TCP flags for U32Match.TCPFlags
+const (
+	TCPFlagFIN = entities.TCPFlagFIN
+	TCPFlagSYN = entities.TCPFlagSYN
+	TCPFlagRST = entities.TCPFlagRST
+	TCPFlagPSH = entities.TCPFlagPSH
+	TCPFlagACK = entities.TCPFlagACK
+	TCPFlagURG = entities.TCPFlagURG
+	TCPFlagECE = entities.TCPFlagECE
+	TCPFlagCWR = entities.TCPFlagCWR
+)
+
+// U32Match is a u32 filter matching packets on which all of its conditions hold, e.g.
+// api.U32().FromSource("10.0.0.0/8").Protocol("tcp").DestinationPort(443).DSCP(46).
+// Offsets past the IP header assume IPv4 without options.
+type U32Match struct {
+	match     map[string]string
+	minLength int
+	maxLength int
+	hasLength bool
+	errs      []string
+}
+
+// U32 starts an empty u32 match, which matches every packet
+func U32() *U32Match {
+	return &U32Match{match: make(map[string]string)}
+}
+
+// FromSource matches the source IPv4 address or CIDR
+func (m *U32Match) FromSource(cidr string) *U32Match {
+	if _, err := entities.NewIPSourceMatch(cidr); err != nil || !isIPv4(cidr) {
+		return m.fail("invalid IPv4 source %q", cidr)
+	}
+	m.match["src_ip"] = cidr
+	return m
+}
+
+// ToDestination matches the destination IPv4 address or CIDR
+func (m *U32Match) ToDestination(cidr string) *U32Match {
+	if _, err := entities.NewIPDestinationMatch(cidr); err != nil || !isIPv4(cidr) {
+		return m.fail("invalid IPv4 destination %q", cidr)
+	}
+	m.match["dst_ip"] = cidr
+	return m
+}
+
+// Protocol matches the IP protocol: "tcp", "udp", "icmp" or a protocol number
+func (m *U32Match) Protocol(protocol string) *U32Match {
+	m.match["protocol"] = protocol
+	return m
+}
+
+// SourcePort matches the TCP or UDP source port
+func (m *U32Match) SourcePort(port int) *U32Match {
+	if port < 0 || port > 65535 {
+		return m.fail("invalid source port %d", port)
+	}
+	m.match["src_port"] = fmt.Sprintf("%d", port)
+	return m
+}
+
+// DestinationPort matches the TCP or UDP destination port
+func (m *U32Match) DestinationPort(port int) *U32Match {
+	if port < 0 || port > 65535 {
+		return m.fail("invalid destination port %d", port)
+	}
+	m.match["dst_port"] = fmt.Sprintf("%d", port)
+	return m
+}
+
+// TOS matches the TOS bits selected by the mask
+func (m *U32Match) TOS(tos, mask uint8) *U32Match {
+	m.match["tos"] = fmt.Sprintf("0x%02x/0x%02x", tos, mask)
+	return m
+}
+
+// DSCP matches the DiffServ code point (0-63), e.g. 46 for expedited forwarding
+func (m *U32Match) DSCP(dscp uint8) *U32Match {
+	if dscp > 63 {
+		return m.fail("invalid DSCP %d, must be between 0 and 63", dscp)
+	}
+	m.match["dscp"] = fmt.Sprintf("%d", dscp)
+	return m
+}
+
+// TCPFlags matches TCP segments whose flags selected by the mask equal the given flags, e.g.
+// TCPFlags(api.TCPFlagSYN, api.TCPFlagSYN|api.TCPFlagACK) for connection attempts
+func (m *U32Match) TCPFlags(flags, mask uint8) *U32Match {
+	m.match["tcp_flags"] = fmt.Sprintf("0x%02x/0x%02x", flags, mask)
+	return m
+}
+
+// PacketLength matches IP packets from min to max bytes long, inclusive. Ranges that are not
+// a power-of-two block are installed as several filters.
+func (m *U32Match) PacketLength(min, max int) *U32Match {
+	if min < 0 || max > 65535 || min > max {
+		return m.fail("invalid packet length range %d-%d", min, max)
+	}
+	m.minLength, m.maxLength, m.hasLength = min, max, true
+	return m
+}
+
+// Raw matches the 32-bit word at the offset from the start of the IP header; the offset must
+// be a multiple of 4
+func (m *U32Match) Raw(offset int, value, mask uint32) *U32Match {
+	if offset%4 != 0 || offset < -65536 || offset > 65535 {
+		return m.fail("invalid raw match offset %d, must be a multiple of 4", offset)
+	}
+	m.match[fmt.Sprintf("raw@%d", offset)] = fmt.Sprintf("0x%08x/0x%08x", value, mask)
+	return m
+}
+
+// fail records an invalid condition, reported when the configuration is validated
+func (m *U32Match) fail(format string, args ...interface{}) *U32Match {
+	m.errs = append(m.errs, fmt.Sprintf(format, args...))
+	return m
+}
+
+// err returns the invalid conditions of the match, if any
+func (m *U32Match) err() error {
+	if m == nil || len(m.match) == 0 && !m.hasLength && len(m.errs) == 0 {
+		return fmt.Errorf("no conditions")
+	}
+	if len(m.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(m.errs, "; "))
+}
+
+// matches returns the filter matches installing the u32 match, one per packet length block
+func (m *U32Match) matches() []map[string]string {
+	if !m.hasLength {
+		return []map[string]string{m.copyMatch()}
+	}
+
+	blocks, err := entities.NewPacketLengthMatches(uint16(m.minLength), uint16(m.maxLength))
+	if err != nil {
+		return nil
+	}
+	matches := make([]map[string]string, 0, len(blocks))
+	for _, block := range blocks {
+		match := m.copyMatch()
+		match["len"] = fmt.Sprintf("0x%04x/0x%04x", block.Length(), block.Mask())
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// copyMatch returns a copy of the match conditions
+func (m *U32Match) copyMatch() map[string]string {
+	match := make(map[string]string, len(m.match)+1)
+	for key, value := range m.match {
+		match[key] = value
+	}
+	return match
+}
+
+// isIPv4 reports whether an address or CIDR is IPv4
+func isIPv4(cidr string) bool {
+	address, _, _ := strings.Cut(cidr, "/")
+	ip := net.ParseIP(address)
+	return ip != nil && ip.To4() != nil
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/application"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestTrafficClassBuilder_ForU32(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("voice").
+		WithGuaranteedBandwidth("10mbps").
+		WithPriority(0).
+		ForU32(U32().
+			FromSource("10.1.0.0/16").
+			Protocol("udp").
+			DSCP(46).
+			PacketLength(0, 300))
+	controller.CreateTrafficClass("handshakes").
+		WithGuaranteedBandwidth("5mbps").
+		WithPriority(1).
+		ForU32(U32().TCPFlags(TCPFlagSYN, TCPFlagSYN|TCPFlagACK).Raw(8, 0x40000000, 0xff000000))
+
+	mockNetlinkAdapter := netlink.NewMockAdapter()
+	controller.service = application.NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), mockNetlinkAdapter, controller.logger)
+	require.NoError(t, controller.Apply())
+
+	filters := mockNetlinkAdapter.GetFilters(tc.MustNewDeviceName("eth0")).Value()
+	byFlow := make(map[string][][]string)
+	for _, filter := range filters {
+		var matches []string
+		for _, match := range filter.Matches {
+			matches = append(matches, match.Value.(string))
+		}
+		byFlow[filter.FlowID.String()] = append(byFlow[filter.FlowID.String()], matches)
+	}
+
+	// 0-300 bytes is covered by the blocks 0-255, 256-287, 288-295, 296-299 and 300
+	voice := byFlow["1:10"]
+	require.Len(t, voice, 5)
+	for _, matches := range voice {
+		assert.Contains(t, matches, "ip src 10.1.0.0/16")
+		assert.Contains(t, matches, "ip protocol 17 0xff")
+		assert.Contains(t, matches, "ip tos 0xb8 0xfc")
+	}
+	assert.Contains(t, voice[0], "ip len 0x0000 0xff00")
+
+	handshakes := byFlow["1:11"]
+	require.Len(t, handshakes, 1)
+	assert.ElementsMatch(t, []string{"tcp flags 0x02 0x12", "u32 0x40000000 0xff000000 at 8"}, handshakes[0])
+
+	// Applying the same configuration again changes nothing
+	require.NoError(t, controller.Apply())
+	assert.Len(t, mockNetlinkAdapter.GetFilters(tc.MustNewDeviceName("eth0")).Value(), len(filters))
+}
+
+func TestTrafficClassBuilder_ForU32Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		match *U32Match
+	}{
+		{"no_conditions", U32()},
+		{"ipv6_source", U32().FromSource("2001:db8::/32")},
+		{"dscp_out_of_range", U32().DSCP(64)},
+		{"inverted_length", U32().PacketLength(100, 10)},
+		{"unaligned_raw_offset", U32().Raw(6, 0, 0xffff)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := NetworkInterface("eth0")
+			controller.WithHardLimitBandwidth("100mbps")
+			controller.CreateTrafficClass("web").
+				WithGuaranteedBandwidth("10mbps").
+				WithPriority(1).
+				ForU32(tt.match)
+
+			err := controller.Apply()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid u32 match")
+		})
+	}
+}
+
+func TestU32Match_TCPFlagConstants(t *testing.T) {
+	assert.Equal(t, entities.TCPFlagSYN, TCPFlagSYN)
+	assert.Equal(t, uint8(0x12), TCPFlagSYN|TCPFlagACK)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// lineHandler answers a single request line with a value that is written back as one JSON line
+type lineHandler func(ctx context.Context, request []byte) interface{}
+
+// serveUnix answers line-oriented requests on a unix socket until the context is cancelled
+func serveUnix(ctx context.Context, socketPath string, handle lineHandler) error {
+	// Remove a stale socket left behind by a previous process
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket %s: %w", socketPath, err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveConn(ctx, conn, handle)
+		}()
+	}
+}
+
+// serveConn answers requests on a single client connection
+func serveConn(ctx context.Context, conn net.Conn, handle lineHandler) {
+	defer func() { _ = conn.Close() }()
+
+	// Unblock the read on cancellation, without outliving the connection
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	_ = serveLines(ctx, conn, conn, handle)
+}
+
+// serveLines answers the request lines read from r, writing each reply to w, until r is
+// exhausted or a write fails
+func serveLines(ctx context.Context, r io.Reader, w io.Writer, handle lineHandler) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRequestLineBytes)
+	encoder := json.NewEncoder(w)
+	for scanner.Scan() {
+		if err := encoder.Encode(handle(ctx, scanner.Bytes())); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// maxRequestLineBytes bounds a single request line; apply requests carry a full configuration
+const maxRequestLineBytes = 1024 * 1024
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeUnix_ClosedConnectionsReleaseGoroutines(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serveUnix(ctx, socketPath, func(context.Context, []byte) interface{} { return "ok" })
+	}()
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("unix", socketPath)
+		if err == nil {
+			_ = conn.Close()
+		}
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	dial := func() net.Conn {
+		conn, err := net.Dial("unix", socketPath)
+		require.NoError(t, err)
+		return conn
+	}
+	time.Sleep(50 * time.Millisecond)
+	baseline := runtime.NumGoroutine()
+
+	for i := 0; i < 20; i++ {
+		_ = dial().Close()
+	}
+	// Polled by hand, assert.Eventually running its condition on goroutines of its own
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), baseline,
+		"goroutines of closed connections must not wait for the server to stop")
+
+	// An open connection is still closed when the server stops
+	conn := dial()
+	defer func() { _ = conn.Close() }()
+	cancel()
+	require.ErrorIs(t, <-served, context.Canceled)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err := conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.False(t, isTimeout(err), "the server closes the connection")
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
@@ -0,0 +1,40 @@
+package api
+
+// TrafficClassUpdate collects changes to a configured traffic class and applies them in
+// place: the kernel class is changed rather than deleted and recreated, so it keeps its
+// counters and its queued packets, and traffic keeps flowing while it is reconfigured.
+//
+//	controller.UpdateTrafficClass("web-traffic").SetMaxBandwidth("800Mbps").Apply()
+type TrafficClassUpdate struct {
+	controller *TrafficController
+	name       string
+	changes    []func(*TrafficClassBuilder)
+}
+
+// UpdateTrafficClass starts an update of the traffic class configured under the name
+func (controller *TrafficController) UpdateTrafficClass(name string) *TrafficClassUpdate {
+	return &TrafficClassUpdate{controller: controller, name: name}
+}
+
+// SetGuaranteedBandwidth changes the guaranteed bandwidth, accepting the values of
+// WithGuaranteedBandwidth
+func (u *TrafficClassUpdate) SetGuaranteedBandwidth(bandwidth string) *TrafficClassUpdate {
+	u.changes = append(u.changes, func(b *TrafficClassBuilder) { b.WithGuaranteedBandwidth(bandwidth) })
+	return u
+}
+
+// SetMaxBandwidth changes the bandwidth limit, accepting the values of WithSoftLimitBandwidth
+func (u *TrafficClassUpdate) SetMaxBandwidth(bandwidth string) *TrafficClassUpdate {
+	u.changes = append(u.changes, func(b *TrafficClassBuilder) { b.WithSoftLimitBandwidth(bandwidth) })
+	return u
+}
+
+// Apply applies the changes. The class is left as it was when applying fails, and
+// tcerrors.ErrNotFound is returned when no class of that name is configured.
+func (u *TrafficClassUpdate) Apply() error {
+	return u.controller.UpdateClassByName(u.name, func(b *TrafficClassBuilder) {
+		for _, change := range u.changes {
+			change(b)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// failingChangeAdapter fails every in-place class change
+type failingChangeAdapter struct {
+	*netlink.MockAdapter
+}
+
+func (a *failingChangeAdapter) ChangeClass(ctx context.Context, class interface{}) error {
+	return errors.New("change rejected")
+}
+
+func TestUpdateTrafficClass_ChangesInPlace(t *testing.T) {
+	mock := netlink.NewMockAdapter()
+	device := tc.MustNewDeviceName("eth0")
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(mock)
+	controller.WithHardLimitBandwidth("1gbps")
+	controller.CreateTrafficClass("web-traffic").WithGuaranteedBandwidth("100mbps").
+		WithSoftLimitBandwidth("200mbps").WithPriority(1).ForPort(80)
+	require.NoError(t, controller.Apply())
+	mock.SetClassStatistics(device, tc.MustParseHandle("1:11"), netlink.ClassStats{BytesSent: 4096, PacketsSent: 3})
+
+	commands, err := controller.DryRun()
+	require.NoError(t, err)
+	assert.Empty(t, commands)
+
+	require.NoError(t, controller.UpdateTrafficClass("web-traffic").SetMaxBandwidth("800Mbps").Apply())
+
+	// The class is changed rather than recreated, so its counters carry on
+	assert.Equal(t, []tc.Handle{tc.MustParseHandle("1:11")}, mock.ChangedClasses(device))
+	stats, err := controller.GetClassStatisticsByName("web-traffic")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4096), stats.BytesSent)
+
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	assert.Equal(t, "800.0Mbps", plan.Classes[0].Ceil)
+
+	// Applying again finds nothing left to change
+	result := controller.ApplyResult()
+	require.True(t, result.IsSuccess())
+	assert.False(t, result.Value().Changed())
+}
+
+func TestUpdateTrafficClass_FailureKeepsClass(t *testing.T) {
+	adapter := &failingChangeAdapter{MockAdapter: netlink.NewMockAdapter()}
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(adapter)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("30mbps").
+		WithSoftLimitBandwidth("60mbps").WithPriority(1).ForPort(80)
+	require.NoError(t, controller.Apply())
+
+	err := controller.UpdateTrafficClass("web").SetGuaranteedBandwidth("40mbps").Apply()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "change rejected")
+
+	// The configuration and the recorded history still describe the class as applied
+	plan, err := controller.Plan()
+	require.NoError(t, err)
+	assert.Equal(t, "30.0Mbps", plan.Classes[0].Rate)
+	commands, err := controller.DryRun()
+	require.NoError(t, err)
+	assert.Empty(t, commands)
+
+	err = controller.UpdateTrafficClass("video").SetMaxBandwidth("10mbps").Apply()
+	assert.True(t, errors.Is(err, tcerrors.ErrNotFound))
+}
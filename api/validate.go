@@ -0,0 +1,392 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// ValidationSeverity tells whether a validation issue stops Apply
+type ValidationSeverity string
+
+const (
+	// ValidationError is an issue that makes Apply fail
+	ValidationError ValidationSeverity = "error"
+	// ValidationWarning is an issue Apply accepts but that is likely a mistake
+	ValidationWarning ValidationSeverity = "warning"
+)
+
+// ValidationIssue is a problem found in a configuration
+type ValidationIssue struct {
+	Severity ValidationSeverity `json:"severity"`
+	Code     string             `json:"code"` // Stable identifier, e.g. "missing_priority"
+	Class    string             `json:"class,omitempty"`
+	Message  string             `json:"message"`
+}
+
+// ValidationReport lists the issues found in a configuration
+type ValidationReport struct {
+	Errors   []ValidationIssue `json:"errors"`
+	Warnings []ValidationIssue `json:"warnings"`
+}
+
+// Valid reports whether the configuration has no errors; warnings are allowed
+func (r *ValidationReport) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// bandwidthIssues are the issue codes reported as tcerrors.ErrBandwidthExceeded
+var bandwidthIssues = map[string]bool{
+	"total_guaranteed_exceeds_total":     true,
+	"children_guaranteed_exceeds_parent": true,
+	"max_exceeds_parent":                 true,
+	"max_exceeds_total":                  true,
+	"guaranteed_exceeds_max":             true,
+	"low_latency_guarantee_too_large":    true,
+	"starved_under_contention":           true,
+}
+
+// Err returns the first error, or nil when the configuration is valid. The error is a
+// *tcerrors.Error of code bandwidth_exceeded, handle_conflict or invalid_configuration.
+func (r *ValidationReport) Err() error {
+	if r.Valid() {
+		return nil
+	}
+
+	issue := r.Errors[0]
+	code := tcerrors.CodeInvalidConfiguration
+	hint := ""
+	switch {
+	case bandwidthIssues[issue.Code]:
+		code = tcerrors.CodeBandwidthExceeded
+	case issue.Code == "handle_conflict":
+		code = tcerrors.CodeHandleConflict
+		hint = "pin the class to another handle with WithHandle, or leave it unpinned to have one assigned"
+	}
+	entity := ""
+	if issue.Class != "" {
+		entity = "class " + issue.Class
+	}
+	err := tcerrors.New(code, entity, "%s", issue.Message)
+	if hint != "" {
+		err = err.WithHint(hint)
+	}
+	return err
+}
+
+// Validate checks the configuration without touching the system and returns every error
+// and warning found, where Apply stops at the first error. It needs no privileges, so
+// configurations can be checked in CI.
+func (controller *TrafficController) Validate() *ValidationReport {
+	controller.finalizePendingClasses()
+	return controller.validateReport()
+}
+
+// validate checks if the configuration is valid
+func (controller *TrafficController) validate() error {
+	return controller.validateReport().Err()
+}
+
+// addError records an error and logs it like the warnings Apply has always logged
+func (controller *TrafficController) addError(report *ValidationReport, code, class, format string, args ...interface{}) {
+	controller.logger.Warn("Configuration validation failed",
+		logging.String("class_name", class),
+		logging.String("validation_error", code),
+	)
+	report.Errors = append(report.Errors, ValidationIssue{
+		Severity: ValidationError, Code: code, Class: class, Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func addWarning(report *ValidationReport, code, class, format string, args ...interface{}) {
+	report.Warnings = append(report.Warnings, ValidationIssue{
+		Severity: ValidationWarning, Code: code, Class: class, Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validateReport runs every check on the finalized classes
+func (controller *TrafficController) validateReport() *ValidationReport {
+	controller.logger.Debug("Starting configuration validation",
+		logging.String("operation", logging.OperationValidation),
+		logging.Int("class_count", len(controller.classes)),
+	)
+
+	report := &ValidationReport{Errors: []ValidationIssue{}, Warnings: []ValidationIssue{}}
+	controller.unscaleGuarantees()
+
+	// Every other check compares against the total bandwidth
+	if controller.bandwidthErr != nil {
+		controller.addError(report, "invalid_bandwidth", "", "%v", controller.bandwidthErr)
+		return report
+	}
+	if controller.totalBandwidth.BitsPerSecond() == 0 {
+		controller.addError(report, "missing_total_bandwidth", "",
+			"total bandwidth not set. Use WithHardLimitBandwidth() to specify the interface bandwidth")
+		return report
+	}
+	controller.resolveRelativeBandwidths(report)
+
+	// Check if all classes have priority set
+	names := make(map[string]bool)
+	for _, class := range controller.classes {
+		for _, err := range class.bandwidthErrs {
+			controller.addError(report, "invalid_bandwidth", class.name, "class '%s': %v", class.name, err)
+		}
+		if class.priority == nil {
+			controller.addError(report, "missing_priority", class.name,
+				"class '%s' does not have a priority set\n"+
+					"Priority is required for all traffic classes. Use WithPriority(0-7) to set it",
+				class.name,
+			)
+		}
+		if names[class.name] {
+			controller.addError(report, "duplicate_name", class.name,
+				"class name '%s' is used more than once\n"+
+					"Suggestion: Give every traffic class a unique name",
+				class.name,
+			)
+		}
+		names[class.name] = true
+	}
+	controller.assignHandles(report)
+	controller.validateHierarchy(report)
+
+	// Check if guaranteed bandwidth sum doesn't exceed total, or make it fit
+	oversubscribed := controller.checkOversubscription(report)
+	var totalGuaranteed tc.Bandwidth
+	for _, class := range controller.classes {
+		if class.parentName == "" {
+			totalGuaranteed = totalGuaranteed.Add(class.guaranteedBandwidth)
+		}
+		controller.validateClass(report, class)
+		controller.validateSchedules(report, class)
+		controller.validateProfile(report, class)
+		controller.validateBorrowing(report, class)
+		controller.validateTemplate(report, class)
+	}
+	controller.validateDefaultClass(report)
+	controller.validateBPFClassifiers(report)
+	controller.validateHookFilters(report)
+	if controller.defaultClass != nil {
+		totalGuaranteed = totalGuaranteed.Add(controller.defaultClassRate())
+	}
+
+	// Guarantees that only hold while the link is idle fail under load. The simulation
+	// needs every priority, and oversubscription accepted with a warning starves by design.
+	if report.Valid() && !oversubscribed {
+		if err := controller.validateContention(); err != nil {
+			controller.addError(report, "starved_under_contention", "", "%s", err.Error())
+		}
+	}
+
+	controller.logger.Debug("Configuration validation completed",
+		logging.String("total_guaranteed", totalGuaranteed.String()),
+		logging.String("total_bandwidth", controller.totalBandwidth.String()),
+		logging.Int("errors", len(report.Errors)),
+		logging.Int("warnings", len(report.Warnings)),
+	)
+
+	return report
+}
+
+// validateClass checks a single class
+func (controller *TrafficController) validateClass(report *ValidationReport, class *TrafficClass) {
+	controller.logger.Debug("Validating traffic class",
+		logging.String("class_name", class.name),
+		logging.String("guaranteed_bandwidth", class.guaranteedBandwidth.String()),
+		logging.String("max_bandwidth", class.maxBandwidth.String()),
+	)
+
+	// Low-latency classes only work while their traffic stays sparse
+	if class.lowLatency && class.guaranteedBandwidth.GreaterThan(controller.totalBandwidth.Percentage(lowLatencyMaxSharePct)) {
+		controller.addError(report, "low_latency_guarantee_too_large", class.name,
+			"low-latency class '%s' guarantees %s, more than %.0f%% of total bandwidth (%s)\n"+
+				"Suggestion: Keep latency-sensitive guarantees small or drop WithLowLatency() for bulk traffic",
+			class.name,
+			class.guaranteedBandwidth,
+			lowLatencyMaxSharePct,
+			controller.totalBandwidth,
+		)
+	}
+
+	// Low-latency classes bring their own leaf qdisc
+	if class.lowLatency && class.leafQdisc != nil {
+		controller.addError(report, "low_latency_leaf_qdisc", class.name,
+			"low-latency class '%s' also sets a leaf qdisc\n"+
+				"Suggestion: WithLowLatency() attaches an FQ_CODEL leaf qdisc; drop either it or WithLeafQdisc()",
+			class.name,
+		)
+	}
+
+	// u32 and flower matches report invalid conditions only now, as the fluent API cannot fail
+	for _, filter := range class.filters {
+		if flower, ok := filter.value.(*FlowerMatch); ok {
+			if err := flower.err(); err != nil {
+				controller.addError(report, "invalid_flower_match", class.name,
+					"class '%s' has an invalid flower match: %v\n"+
+						"Suggestion: Add at least one valid condition to api.Flower(), and a protocol before any port",
+					class.name,
+					err,
+				)
+			}
+			continue
+		}
+
+		u32, ok := filter.value.(*U32Match)
+		if !ok {
+			continue
+		}
+		if err := u32.err(); err != nil {
+			controller.addError(report, "invalid_u32_match", class.name,
+				"class '%s' has an invalid u32 match: %v\n"+
+					"Suggestion: Add at least one valid condition to api.U32()",
+				class.name,
+				err,
+			)
+		}
+	}
+
+	for _, filter := range class.filters {
+		if filter.filterType != DSCPFilter {
+			continue
+		}
+		name, _ := filter.value.(string)
+		if _, err := entities.ParseDSCP(name); err != nil {
+			controller.addError(report, "invalid_dscp", class.name,
+				"class '%s' has an invalid DSCP filter: %v\n"+
+					"Suggestion: Use a DSCP from 0 to 63 or a diffserv class such as EF, AF41 or CS1",
+				class.name,
+				err,
+			)
+		}
+	}
+	for _, action := range class.actions {
+		if action.spec.Type == "dscp" && action.spec.DSCP > 63 {
+			controller.addError(report, "invalid_dscp_remark", class.name,
+				"class '%s' remarks packets with DSCP %d\n"+
+					"Suggestion: DSCPs range from 0 to 63, e.g. 46 for Expedited Forwarding",
+				class.name,
+				action.spec.DSCP,
+			)
+		}
+	}
+
+	// Cgroup filters are BPF classifiers, which cannot carry the class's actions
+	for _, filter := range class.filters {
+		if filter.filterType != CgroupFilter {
+			continue
+		}
+		path, _ := filter.value.(string)
+		if err := validateCgroupPath(path); err != nil {
+			controller.addError(report, "invalid_cgroup", class.name,
+				"class '%s' has an invalid cgroup filter: %v\n"+
+					"Suggestion: Pass the absolute path of an existing cgroup directory, e.g. /sys/fs/cgroup/myapp.slice",
+				class.name,
+				err,
+			)
+		}
+		if len(class.actions) > 0 {
+			controller.addError(report, "cgroup_with_actions", class.name,
+				"class '%s' combines a cgroup filter with actions\n"+
+					"Suggestion: Move WithActions() to a class matched by other filters",
+				class.name,
+			)
+		}
+	}
+
+	// A redirect hands the packet to another device and a drop or pass ends the chain, so
+	// no action can follow them
+	for i, action := range class.actions {
+		if action.spec.Type == "priority" {
+			if _, err := tc.ParseHandle(action.spec.Handle); err != nil {
+				controller.addError(report, "invalid_priority_action", class.name,
+					"class '%s' sets an invalid packet priority %q\n"+
+						"Suggestion: Pass a handle to SetPriority(), e.g. \"1:10\"",
+					class.name,
+					action.spec.Handle,
+				)
+			}
+		}
+		if (action.spec.Type == "drop" || action.spec.Type == "pass") && i != len(class.actions)-1 {
+			controller.addError(report, "verdict_not_last", class.name,
+				"class '%s' has a %s before the end of its action chain\n"+
+					"Suggestion: Move Drop() or Pass() to the end of WithActions(); actions after it never run",
+				class.name,
+				action.spec.Type,
+			)
+		}
+		if action.spec.Type == "redirect" && i != len(class.actions)-1 {
+			controller.addError(report, "redirect_not_last", class.name,
+				"class '%s' redirects to %s before the end of its action chain\n"+
+					"Suggestion: Move RedirectTo() to the end of WithActions(); actions after it never run",
+				class.name,
+				action.spec.Device,
+			)
+		}
+	}
+
+	// Check if max bandwidth exceeds total
+	if class.maxBandwidth.GreaterThan(controller.totalBandwidth) {
+		controller.addError(report, "max_exceeds_total", class.name,
+			"class '%s' has max bandwidth (%s) higher than total bandwidth (%s)\n"+
+				"Suggestion: Either reduce the max bandwidth or increase the total bandwidth",
+			class.name,
+			class.maxBandwidth,
+			controller.totalBandwidth,
+		)
+	}
+
+	// Check if guaranteed > max
+	if class.guaranteedBandwidth.GreaterThan(class.maxBandwidth) && class.maxBandwidth.BitsPerSecond() > 0 {
+		controller.addError(report, "guaranteed_exceeds_max", class.name,
+			"class '%s' has guaranteed bandwidth (%s) higher than max bandwidth (%s)\n"+
+				"Suggestion: Set max bandwidth higher than or equal to guaranteed bandwidth",
+			class.name,
+			class.guaranteedBandwidth,
+			class.maxBandwidth,
+		)
+	}
+
+	// Without a ceiling HTB caps the class at its guarantee
+	if class.maxBandwidth.BitsPerSecond() == 0 && !class.borrowingDisabled {
+		addWarning(report, "missing_max_bandwidth", class.name,
+			"class '%s' has no max bandwidth, so it cannot borrow beyond its guarantee (%s)\n"+
+				"Suggestion: Use WithSoftLimitBandwidth() to let the class use idle bandwidth",
+			class.name,
+			class.guaranteedBandwidth,
+		)
+	}
+
+	// A leaf without filters gets a catch-all filter, see applyConfiguration; parents take
+	// no traffic
+	if len(class.filters) == 0 && !controller.hasChildren(class) {
+		addWarning(report, "no_filters", class.name,
+			"class '%s' has no filters, so it only receives the IP traffic no other filter matches, in place of the default class\n"+
+				"Suggestion: Add a filter such as ForPort(), ForDestination() or ForProtocols() if the class is meant for specific traffic",
+			class.name,
+		)
+	}
+}
+
+// ValidateConfig checks a structured configuration without touching the system, as
+// ApplyConfig would before applying it
+func ValidateConfig(config *TrafficControlConfig) *ValidationReport {
+	report := &ValidationReport{Errors: []ValidationIssue{}, Warnings: []ValidationIssue{}}
+	invalid := func(message string) *ValidationReport {
+		report.Errors = append(report.Errors, ValidationIssue{Severity: ValidationError, Code: "invalid_config", Message: message})
+		return report
+	}
+
+	if err := config.Validate(); err != nil {
+		return invalid(err.Error())
+	}
+
+	controller := NetworkInterface(config.Device)
+	if err := controller.configure(config); err != nil {
+		return invalid(err.Error())
+	}
+	return controller.Validate()
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+func TestTrafficController_Validate(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithSoftLimitBandwidth("20mbps").
+		WithPriority(1).
+		ForPort(80)
+	controller.CreateTrafficClass("bulk").
+		WithGuaranteedBandwidth("80mbps").
+		ForPort(8080)
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("1mbps").
+		WithSoftLimitBandwidth("10mbps").
+		WithPriority(4)
+
+	report := controller.Validate()
+	assert.False(t, report.Valid())
+
+	codes := func(issues []ValidationIssue) []string {
+		result := make([]string, 0, len(issues))
+		for _, issue := range issues {
+			result = append(result, issue.Code+" "+issue.Class)
+		}
+		return result
+	}
+	// Every error is reported, not only the first
+	assert.ElementsMatch(t, []string{
+		"missing_priority bulk",
+		"duplicate_name web",
+		"guaranteed_exceeds_max web",
+		"total_guaranteed_exceeds_total ",
+	}, codes(report.Errors))
+	assert.ElementsMatch(t, []string{
+		"missing_max_bandwidth bulk",
+		"no_filters web",
+	}, codes(report.Warnings))
+	// The leaf gets the catch-all filter, so it does receive traffic
+	assert.Contains(t, findIssue(report.Warnings, "no_filters").Message, "only receives the IP traffic no other filter matches")
+
+	// Apply fails with the first error
+	require.Error(t, controller.Apply())
+	assert.Contains(t, report.Err().Error(), "class 'bulk' does not have a priority set")
+	assert.ErrorIs(t, report.Err(), tcerrors.ErrInvalidConfiguration)
+}
+
+func TestTrafficController_ValidateBandwidthError(t *testing.T) {
+	controller := NetworkInterface("eth0").WithNetlinkAdapter(netlink.NewMockAdapter())
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithSoftLimitBandwidth("200mbps").
+		WithPriority(1).
+		ForPort(80)
+
+	err := controller.Apply()
+	require.ErrorIs(t, err, tcerrors.ErrBandwidthExceeded)
+
+	var tcErr *tcerrors.Error
+	require.ErrorAs(t, err, &tcErr)
+	assert.Equal(t, "class web", tcErr.Entity)
+	assert.NotEmpty(t, tcErr.Hint)
+}
+
+func TestTrafficController_ValidateValid(t *testing.T) {
+	controller := NetworkInterface("eth0")
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithSoftLimitBandwidth("60mbps").
+		WithPriority(1).
+		ForPort(80)
+
+	report := controller.Validate()
+	assert.True(t, report.Valid())
+	assert.NoError(t, report.Err())
+	assert.Empty(t, report.Warnings)
+}
+
+func TestValidateConfig(t *testing.T) {
+	config := &TrafficControlConfig{
+		Version:   "1.0",
+		Device:    "eth0",
+		Bandwidth: "100Mbps",
+		Classes: []TrafficClassConfig{
+			{Name: "web", Guaranteed: "30Mbps", Maximum: "200Mbps", Priority: &[]int{1}[0]},
+		},
+		Rules: []TrafficRuleConfig{
+			{Name: "http", Match: MatchConfig{DestPort: []int{80}}, Target: "web"},
+		},
+	}
+
+	report := ValidateConfig(config)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, "max_exceeds_total", report.Errors[0].Code)
+
+	config.Classes[0].Maximum = "not a bandwidth"
+	report = ValidateConfig(config)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, "invalid_bandwidth", report.Errors[0].Code)
+
+	config.Classes[0].Priority = nil
+	report = ValidateConfig(config)
+	require.Len(t, report.Errors, 1)
+	assert.Contains(t, report.Errors[0].Message, "priority is required")
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// DefaultThresholdHysteresis is the percentage below its threshold a metric must fall
+// before a crossing clears, so that a class hovering at a threshold does not flap
+const DefaultThresholdHysteresis = 10.0
+
+// ThresholdMetric names a class metric watched by WatchClass
+type ThresholdMetric string
+
+// Metrics that can be watched
+const (
+	MetricRate    ThresholdMetric = "rate"    // Bits per second sent by the class
+	MetricDrops   ThresholdMetric = "drops"   // Packets dropped since the previous sample
+	MetricBacklog ThresholdMetric = "backlog" // Bytes queued in the class
+)
+
+// ClassThresholds are the limits watched by WatchClass; a zero limit is not watched
+type ClassThresholds struct {
+	RateBPS    uint64
+	Drops      uint64
+	Backlog    uint64
+	Hysteresis float64 // Percentage, zero selects DefaultThresholdHysteresis
+}
+
+// ThresholdEvent reports a class crossing one of its thresholds
+type ThresholdEvent struct {
+	Class     string
+	Metric    ThresholdMetric
+	Exceeded  bool // True when the metric rose above the threshold, false when it fell back
+	Value     uint64
+	Threshold uint64
+	Pressure  ClassPressure // The sample that caused the crossing
+}
+
+// classWatch is a threshold watch registered on a PressureMonitor
+type classWatch struct {
+	thresholds ClassThresholds
+	callback   func(ThresholdEvent)
+	exceeded   map[ThresholdMetric]bool
+}
+
+// thresholdCrossing is a callback invocation collected while the monitor is locked
+type thresholdCrossing struct {
+	callback func(ThresholdEvent)
+	event    ThresholdEvent
+}
+
+// WatchClass invokes the callback from the monitor's sampling goroutine whenever the named
+// class rises above one of the thresholds, and again once it has fallen back below it by
+// the hysteresis. It is a lightweight alternative to a full alerting setup for embedding
+// applications. The returned function removes the watch.
+func (m *PressureMonitor) WatchClass(class string, thresholds ClassThresholds, callback func(ThresholdEvent)) (func(), error) {
+	if callback == nil {
+		return nil, fmt.Errorf("callback is required")
+	}
+	if thresholds.RateBPS == 0 && thresholds.Drops == 0 && thresholds.Backlog == 0 {
+		return nil, fmt.Errorf("at least one threshold is required")
+	}
+	if thresholds.Hysteresis < 0 || thresholds.Hysteresis >= 100 {
+		return nil, fmt.Errorf("hysteresis must be between 0 and 100 percent, got %g", thresholds.Hysteresis)
+	}
+	if thresholds.Hysteresis == 0 {
+		thresholds.Hysteresis = DefaultThresholdHysteresis
+	}
+
+	m.controller.mu.Lock()
+	m.controller.finalizePendingClasses()
+	known := m.controller.hasClass(class)
+	m.controller.mu.Unlock()
+	if !known {
+		return nil, fmt.Errorf("unknown traffic class %q", class)
+	}
+
+	watch := &classWatch{
+		thresholds: thresholds,
+		callback:   callback,
+		exceeded:   make(map[ThresholdMetric]bool),
+	}
+
+	m.mu.Lock()
+	m.watches[class] = append(m.watches[class], watch)
+	m.mu.Unlock()
+
+	m.logger.Info("Watching traffic class thresholds",
+		logging.String("class_name", class),
+		logging.Float64("hysteresis", thresholds.Hysteresis),
+	)
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		watches := m.watches[class]
+		for i, w := range watches {
+			if w == watch {
+				m.watches[class] = append(watches[:i:i], watches[i+1:]...)
+				break
+			}
+		}
+	}, nil
+}
+
+// evaluateWatches updates the watches of the sampled class and returns the crossings to
+// report; the caller must hold the lock
+func (m *PressureMonitor) evaluateWatches(pressure ClassPressure) []thresholdCrossing {
+	var crossings []thresholdCrossing
+	for _, watch := range m.watches[pressure.Class] {
+		for _, metric := range []struct {
+			name      ThresholdMetric
+			value     uint64
+			threshold uint64
+		}{
+			{MetricRate, pressure.RateBPS, watch.thresholds.RateBPS},
+			{MetricDrops, pressure.Drops, watch.thresholds.Drops},
+			{MetricBacklog, pressure.Backlog, watch.thresholds.Backlog},
+		} {
+			if metric.threshold == 0 {
+				continue
+			}
+
+			exceeded := watch.exceeded[metric.name]
+			switch {
+			case !exceeded && metric.value > metric.threshold:
+				exceeded = true
+			case exceeded && float64(metric.value) < float64(metric.threshold)*(1-watch.thresholds.Hysteresis/100):
+				exceeded = false
+			default:
+				continue
+			}
+
+			watch.exceeded[metric.name] = exceeded
+			crossings = append(crossings, thresholdCrossing{
+				callback: watch.callback,
+				event: ThresholdEvent{
+					Class:     pressure.Class,
+					Metric:    metric.name,
+					Exceeded:  exceeded,
+					Value:     metric.value,
+					Threshold: metric.threshold,
+					Pressure:  pressure,
+				},
+			})
+		}
+	}
+	return crossings
+}
+
+// hasClass reports whether a traffic class with the name is configured
+func (controller *TrafficController) hasClass(name string) bool {
+	return controller.classByName(name) != nil
+}
+
+// classByName returns the configured traffic class with the name, nil when there is none
+func (controller *TrafficController) classByName(name string) *TrafficClass {
+	for _, class := range controller.classes {
+		if class.name == name {
+			return class
+		}
+	}
+	return nil
+}
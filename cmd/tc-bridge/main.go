@@ -0,0 +1,106 @@
+// Command tc-bridge lets programs in other languages drive the traffic control library
+// through a child process. It reads JSON-RPC 2.0 requests from stdin, one per line, and
+// writes one response per line to stdout, offering the control methods "apply", "plan",
+// "stats", "health" and "schema". Logs go to stderr so that stdout carries only responses.
+// With -dry-run, apply requests change nothing and are answered with the tc commands
+// applying the configuration would run. -validate checks a YAML or JSON configuration file,
+// prints the errors and warnings found and exits with status 1 on errors; it needs no root.
+//
+// Usage:
+//
+//	tc-bridge -device eth0
+//	tc-bridge -device eth0 -dry-run
+//	tc-bridge -schema
+//	tc-bridge -validate config.yaml
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/rng999/traffic-control-go/api"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+func main() {
+	device := flag.String("device", "", "network interface to manage")
+	schema := flag.Bool("schema", false, "print the JSON Schema of every method and exit")
+	validate := flag.String("validate", "", "validate a YAML or JSON configuration file and exit")
+	dryRun := flag.Bool("dry-run", false, "answer apply requests with the equivalent tc commands instead of applying")
+	flag.Parse()
+
+	if *schema {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(api.ControlSchema()); err != nil {
+			fmt.Fprintf(os.Stderr, "tc-bridge: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	config := logging.LoadConfigFromEnv()
+	config.OutputPaths = []string{"stderr"}
+	if err := logging.Initialize(config); err != nil {
+		fmt.Fprintf(os.Stderr, "tc-bridge: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *validate != "" {
+		os.Exit(validateConfig(*validate, *device))
+	}
+
+	if *device == "" {
+		fmt.Fprintln(os.Stderr, "tc-bridge: -device is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := api.NetworkInterface(*device).NewControlServer()
+	if *dryRun {
+		server.WithDryRun()
+	}
+	if err := server.ServeStream(ctx, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "tc-bridge: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// validateConfig prints the validation report of a configuration file and returns the exit
+// status: 0 when the configuration is valid, 1 when it has errors
+func validateConfig(path, device string) int {
+	load := api.LoadConfigFromYAML
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		load = api.LoadConfigFromJSON
+	}
+	config, err := load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tc-bridge: %v\n", err)
+		return 1
+	}
+	if device != "" {
+		config.Device = device
+	}
+
+	report := api.ValidateConfig(config)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "tc-bridge: %v\n", err)
+		return 1
+	}
+	if !report.Valid() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/api"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// NetConf is the network configuration the runtime passes to the plugin
+type NetConf struct {
+	CNIVersion string `json:"cniVersion"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+
+	// Class hierarchies shaping traffic to and from the pod; Device is ignored
+	Ingress *api.TrafficControlConfig `json:"ingress,omitempty"`
+	Egress  *api.TrafficControlConfig `json:"egress,omitempty"`
+
+	RuntimeConfig struct {
+		Bandwidth *BandwidthEntry `json:"bandwidth,omitempty"`
+	} `json:"runtimeConfig,omitempty"`
+
+	RawPrevResult json.RawMessage `json:"prevResult,omitempty"`
+	PrevResult    *Result         `json:"-"`
+}
+
+// BandwidthEntry holds the rates the runtime derives from the pod's bandwidth annotations,
+// in bits per second. Bursts are accepted for compatibility but HTB computes its own.
+type BandwidthEntry struct {
+	IngressRate  uint64 `json:"ingressRate"`
+	IngressBurst uint64 `json:"ingressBurst"`
+	EgressRate   uint64 `json:"egressRate"`
+	EgressBurst  uint64 `json:"egressBurst"`
+}
+
+// Result is the part of a previous plugin's result the plugin reads
+type Result struct {
+	Interfaces []Interface `json:"interfaces"`
+}
+
+// Interface is an interface created by a previous plugin; Sandbox is empty on the host
+type Interface struct {
+	Name    string `json:"name"`
+	Sandbox string `json:"sandbox,omitempty"`
+}
+
+// parseNetConf decodes and checks a network configuration
+func parseNetConf(data []byte) (*NetConf, error) {
+	conf := &NetConf{}
+	if err := json.Unmarshal(data, conf); err != nil {
+		return nil, &pluginError{Code: errInvalidConfig, Msg: "failed to parse network configuration", Details: err.Error()}
+	}
+
+	supported := false
+	for _, version := range supportedVersions {
+		supported = supported || version == conf.CNIVersion
+	}
+	if !supported {
+		return nil, &pluginError{Code: errIncompatibleVersion, Msg: "unsupported CNI version", Details: conf.CNIVersion}
+	}
+
+	if len(conf.RawPrevResult) > 0 {
+		conf.PrevResult = &Result{}
+		if err := json.Unmarshal(conf.RawPrevResult, conf.PrevResult); err != nil {
+			return nil, conf.error(errInvalidConfig, "failed to parse prevResult", err)
+		}
+	}
+	return conf, nil
+}
+
+// error returns a plugin error reported in the configuration's CNI version
+func (conf *NetConf) error(code int, msg string, err error) *pluginError {
+	return &pluginError{CNIVersion: conf.CNIVersion, Code: code, Msg: msg, Details: err.Error()}
+}
+
+// rates returns the rates from the runtime, zero for a direction not limited
+func (conf *NetConf) rates() (ingress, egress uint64) {
+	if bandwidth := conf.RuntimeConfig.Bandwidth; bandwidth != nil {
+		return bandwidth.IngressRate, bandwidth.EgressRate
+	}
+	return 0, 0
+}
+
+// newController creates the traffic controller for a device, replaced in tests
+var newController = api.NetworkInterface
+
+// shaper shapes one direction of the pod's traffic on the egress of a device
+type shaper struct {
+	device   string                    // Device whose egress is shaped
+	redirect string                    // Device whose ingress is redirected to it, if any
+	rate     uint64                    // Total rate in bits per second
+	config   *api.TrafficControlConfig // Class hierarchy, nil to only limit the rate
+}
+
+// shapers returns the shapers the configuration asks for: traffic to the pod is shaped on
+// the host side of its veth pair and traffic from the pod on the IFB device
+func (conf *NetConf) shapers(host, ifb string) ([]*shaper, error) {
+	ingressRate, egressRate := conf.rates()
+
+	var shapers []*shaper
+	for _, s := range []*shaper{
+		{device: host, rate: ingressRate, config: conf.Ingress},
+		{device: ifb, redirect: host, rate: egressRate, config: conf.Egress},
+	} {
+		if s.rate == 0 && s.config == nil {
+			continue
+		}
+
+		if s.config != nil {
+			config := *s.config
+			config.Device = s.device
+			if s.rate > 0 {
+				config.Bandwidth = fmt.Sprintf("%dbit", s.rate)
+			}
+			if err := config.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid configuration for %s: %w", s.device, err)
+			}
+			if _, err := tc.ParseBandwidth(config.Bandwidth); err != nil {
+				return nil, fmt.Errorf("invalid configuration for %s: %w", s.device, err)
+			}
+			s.config = &config
+		}
+		shapers = append(shapers, s)
+	}
+	return shapers, nil
+}
+
+// controller returns a traffic controller for the shaped device, redirecting ingress to it
+// when asked
+func (s *shaper) controller(redirect bool) *api.TrafficController {
+	if !redirect || s.redirect == "" {
+		return newController(s.device)
+	}
+	return newController(s.redirect).ShapeIngressVia(s.device)
+}
+
+// apply installs the shaping, replacing whatever the device had
+func (s *shaper) apply() error {
+	controller := s.controller(true)
+	if s.config != nil {
+		return controller.ApplyConfig(s.config)
+	}
+	return controller.WithHardLimitBandwidth(fmt.Sprintf("%dbit", s.rate)).Apply()
+}
+
+// missing returns the planned qdiscs, classes and filters the device does not have
+func (s *shaper) missing() ([]string, error) {
+	controller := s.controller(false)
+	var plan *qmodels.PlanView
+	var err error
+	if s.config != nil {
+		plan, err = controller.PlanConfig(s.config)
+	} else {
+		plan, err = controller.WithHardLimitBandwidth(fmt.Sprintf("%dbit", s.rate)).Plan()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := controller.ReadCurrentConfiguration()
+	if err != nil {
+		return nil, err
+	}
+	installed := make(map[string]bool)
+	for _, qdisc := range current.Qdiscs {
+		installed["qdisc "+qdisc.Handle] = true
+	}
+	for _, class := range current.Classes {
+		installed["class "+class.Handle] = true
+	}
+	for _, filter := range current.Filters {
+		installed["filter to "+filter.FlowID] = true
+	}
+
+	var missing []string
+	want := func(key string) {
+		if !installed[key] {
+			missing = append(missing, key)
+		}
+	}
+	for _, qdisc := range plan.Qdiscs {
+		want("qdisc " + qdisc.Handle)
+	}
+	for _, class := range plan.Classes {
+		want("class " + class.Handle)
+	}
+	for _, filter := range plan.Filters {
+		want("filter to " + filter.FlowID)
+	}
+	return missing, nil
+}
@@ -0,0 +1,137 @@
+// Command tc-cni is a chained CNI plugin shaping the traffic of a pod with the traffic
+// control library. It replaces the upstream bandwidth plugin: it reads the rates the
+// runtime derives from the kubernetes.io/ingress-bandwidth and egress-bandwidth annotations,
+// and can add a full HTB class hierarchy for each direction from its network configuration.
+//
+// Traffic to the pod is shaped on egress of the host side of its veth pair; traffic from
+// the pod is redirected to an IFB device and shaped there. ADD applies the configuration,
+// DEL removes the IFB device and CHECK verifies that the planned qdiscs, classes and filters
+// are still installed. The plugin must follow an interface-creating plugin such as ptp or
+// bridge in the chain.
+//
+// Network configuration:
+//
+//	{
+//	  "type": "tc-cni",
+//	  "capabilities": {"bandwidth": true},
+//	  "ingress": {"bandwidth": "100mbit", "classes": [...], "rules": [...]},
+//	  "egress": {"classes": [...], "rules": [...]}
+//	}
+//
+// "ingress" and "egress" take the classes and rules of a tc-bridge configuration file. A
+// rate from the runtime replaces the bandwidth of the direction.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// CNI versions the plugin accepts and reports
+var supportedVersions = []string{"0.3.0", "0.3.1", "0.4.0", "1.0.0", "1.1.0"}
+
+// CNI error codes, from the specification
+const (
+	errIncompatibleVersion = 1
+	errInvalidConfig       = 7
+	errTryAgainLater       = 11
+	errInternal            = 999
+)
+
+// pluginError is the error result the specification asks plugins to print on failure
+type pluginError struct {
+	CNIVersion string `json:"cniVersion"`
+	Code       int    `json:"code"`
+	Msg        string `json:"msg"`
+	Details    string `json:"details,omitempty"`
+}
+
+func (e *pluginError) Error() string {
+	if e.Details == "" {
+		return e.Msg
+	}
+	return e.Msg + ": " + e.Details
+}
+
+func main() {
+	// Stdout carries the result, so logs go to stderr
+	config := logging.LoadConfigFromEnv()
+	config.OutputPaths = []string{"stderr"}
+	if err := logging.Initialize(config); err != nil {
+		fmt.Fprintf(os.Stderr, "tc-cni: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := run(os.Getenv("CNI_COMMAND"), os.Stdin, os.Stdout); err != nil {
+		perr, ok := err.(*pluginError)
+		if !ok {
+			perr = &pluginError{Code: errInternal, Msg: err.Error()}
+		}
+		if perr.CNIVersion == "" {
+			perr.CNIVersion = supportedVersions[len(supportedVersions)-1]
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(perr)
+		os.Exit(1)
+	}
+}
+
+// run executes a CNI command with the network configuration read from stdin
+func run(command string, stdin io.Reader, stdout io.Writer) error {
+	if command == "VERSION" {
+		return json.NewEncoder(stdout).Encode(map[string]interface{}{
+			"cniVersion":        supportedVersions[len(supportedVersions)-1],
+			"supportedVersions": supportedVersions,
+		})
+	}
+
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return &pluginError{Code: errInvalidConfig, Msg: "failed to read network configuration", Details: err.Error()}
+	}
+	conf, err := parseNetConf(data)
+	if err != nil {
+		return err
+	}
+	args := argsFromEnv()
+
+	switch command {
+	case "ADD":
+		if err := add(conf, args); err != nil {
+			return conf.error(errInternal, "failed to shape pod traffic", err)
+		}
+		// A chained plugin passes on the result of the plugins before it
+		_, err := stdout.Write(conf.RawPrevResult)
+		return err
+	case "DEL":
+		if err := del(conf, args); err != nil {
+			return conf.error(errTryAgainLater, "failed to remove pod traffic shaping", err)
+		}
+		return nil
+	case "CHECK":
+		if err := check(conf, args); err != nil {
+			return conf.error(errInternal, "pod traffic shaping check failed", err)
+		}
+		return nil
+	default:
+		return conf.error(errInvalidConfig, "unknown CNI_COMMAND", fmt.Errorf("%q", command))
+	}
+}
+
+// cniArgs are the parameters the runtime passes in the environment
+type cniArgs struct {
+	ContainerID string
+	Netns       string
+	IfName      string
+}
+
+func argsFromEnv() cniArgs {
+	return cniArgs{
+		ContainerID: os.Getenv("CNI_CONTAINERID"),
+		Netns:       os.Getenv("CNI_NETNS"),
+		IfName:      os.Getenv("CNI_IFNAME"),
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+// add shapes the traffic of the pod
+func add(conf *NetConf, args cniArgs) error {
+	if conf.PrevResult == nil {
+		return errors.New("must be called as a chained plugin after the plugin creating the interface")
+	}
+
+	host, err := hostInterface(conf.PrevResult, args)
+	if err != nil {
+		return err
+	}
+	shapers, err := conf.shapers(host, ifbName(args.ContainerID))
+	if err != nil {
+		return err
+	}
+
+	for _, s := range shapers {
+		if err := s.apply(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// del removes the IFB device shaping traffic from the pod. The shaping of traffic to the
+// pod goes with the host side of the veth pair, which the plugins creating it delete.
+func del(conf *NetConf, args cniArgs) error {
+	ifb := ifbName(args.ContainerID)
+
+	// The host interface may be gone already; the IFB device is removed regardless
+	host := ifb
+	if conf.PrevResult != nil {
+		if name, err := hostInterface(conf.PrevResult, args); err == nil {
+			host = name
+		}
+	}
+	return newController(host).ShapeIngressVia(ifb).RemoveIngressShaping()
+}
+
+// check verifies that the devices are shaped as configured
+func check(conf *NetConf, args cniArgs) error {
+	if conf.PrevResult == nil {
+		return errors.New("must be called as a chained plugin after the plugin creating the interface")
+	}
+
+	host, err := hostInterface(conf.PrevResult, args)
+	if err != nil {
+		return err
+	}
+	shapers, err := conf.shapers(host, ifbName(args.ContainerID))
+	if err != nil {
+		return err
+	}
+
+	for _, s := range shapers {
+		missing, err := s.missing()
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("%s is missing %s", s.device, strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+// ifbName returns the name of the IFB device shaping traffic from a container, unique per
+// container and short enough for an interface name
+func ifbName(containerID string) string {
+	sum := sha1.Sum([]byte(containerID))
+	return "tcc" + hex.EncodeToString(sum[:])[:12]
+}
+
+// hostInterface returns the host side of the veth pair whose other end is the container's
+// interface. Without access to the container's network namespace, a single host interface
+// in the result is taken to be it.
+func hostInterface(result *Result, args cniArgs) (string, error) {
+	var candidates []string
+	for _, iface := range result.Interfaces {
+		if iface.Sandbox == "" {
+			candidates = append(candidates, iface.Name)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", errors.New("prevResult has no host interface")
+	}
+
+	peerIndex, err := containerPeerIndex(args.Netns, args.IfName)
+	if err != nil {
+		if len(candidates) == 1 {
+			return candidates[0], nil
+		}
+		return "", fmt.Errorf("failed to find the host side of %s: %w", args.IfName, err)
+	}
+
+	for _, name := range candidates {
+		if link, err := netlink.LinkByName(name); err == nil && link.Attrs().Index == peerIndex {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no host interface in prevResult is the peer of %s", args.IfName)
+}
+
+// containerPeerIndex returns the host index of the peer of the container's veth interface
+func containerPeerIndex(netnsPath, ifName string) (int, error) {
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open network namespace %s: %w", netnsPath, err)
+	}
+	defer ns.Close()
+
+	handle, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return 0, err
+	}
+	defer handle.Close()
+
+	link, err := handle.LinkByName(ifName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find %s in the container: %w", ifName, err)
+	}
+	if link.Type() != "veth" || link.Attrs().ParentIndex == 0 {
+		return 0, fmt.Errorf("%s is not a veth interface", ifName)
+	}
+	return link.Attrs().ParentIndex, nil
+}
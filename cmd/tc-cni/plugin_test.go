@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/api"
+	"github.com/rng999/traffic-control-go/api/tctest"
+)
+
+const testConf = `{
+  "cniVersion": "1.0.0",
+  "name": "pods",
+  "type": "tc-cni",
+  "egress": {
+    "classes": [
+      {"name": "interactive", "guaranteed": "2mbit", "maximum": "10mbit", "priority": 1},
+      {"name": "bulk", "guaranteed": "1mbit", "maximum": "10mbit", "priority": 5}
+    ],
+    "rules": [{"name": "ssh", "match": {"dest_port": [22]}, "target": "interactive"}]
+  },
+  "runtimeConfig": {"bandwidth": {"ingressRate": 500000, "ingressBurst": 1000, "egressRate": 10000000, "egressBurst": 1000}},
+  "prevResult": {
+    "cniVersion": "1.0.0",
+    "interfaces": [{"name": "veth1234"}, {"name": "eth0", "sandbox": "/var/run/netns/pod"}]
+  }
+}`
+
+// withFake makes the plugin's controllers change the fake instead of the kernel
+func withFake(t *testing.T) *tctest.Fake {
+	fake := tctest.New()
+	newController = func(device string) *api.TrafficController {
+		return api.NetworkInterface(device).WithNetlinkAdapter(fake)
+	}
+	t.Cleanup(func() { newController = api.NetworkInterface })
+	return fake
+}
+
+func TestShapers(t *testing.T) {
+	fake := withFake(t)
+	conf, err := parseNetConf([]byte(testConf))
+	require.NoError(t, err)
+
+	host, err := hostInterface(conf.PrevResult, cniArgs{Netns: "/nonexistent", IfName: "eth0"})
+	require.NoError(t, err)
+	assert.Equal(t, "veth1234", host)
+
+	ifb := ifbName("container-1")
+	assert.LessOrEqual(t, len(ifb), 15)
+	shapers, err := conf.shapers(host, ifb)
+	require.NoError(t, err)
+	require.Len(t, shapers, 2)
+	assert.Nil(t, shapers[0].config)
+	assert.Equal(t, "10000000bit", shapers[1].config.Bandwidth)
+
+	for _, s := range shapers {
+		require.NoError(t, s.apply())
+	}
+
+	// The annotation rate is below the default class guarantee, which is lowered to it
+	assert.Len(t, fake.Classes(host), 1)
+	assert.Len(t, fake.Classes(ifb), 3)
+	assert.Len(t, fake.Filters(ifb), 2) // ssh, and the catch-all of the class without rules
+	assert.Contains(t, fake.Operations(), tctest.Operation{Op: tctest.OpAddIngressRedirect, Device: host, Target: ifb})
+}
+
+func TestRun(t *testing.T) {
+	fake := withFake(t)
+
+	var out bytes.Buffer
+	require.NoError(t, run("VERSION", strings.NewReader(""), &out))
+	assert.Contains(t, out.String(), `"supportedVersions"`)
+
+	out.Reset()
+	require.NoError(t, run("ADD", strings.NewReader(testConf), &out))
+	var result Result
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Len(t, result.Interfaces, 2)
+	require.NoError(t, run("CHECK", strings.NewReader(testConf), &out))
+
+	// Deleting removes the IFB device; the host interface's qdiscs are left to go with it
+	require.NoError(t, run("DEL", strings.NewReader(testConf), &out))
+	assert.Equal(t, tctest.OpDeleteIngressRedirect, fake.Operations()[len(fake.Operations())-1].Op)
+	err := run("CHECK", strings.NewReader(testConf), &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is missing qdisc 1:")
+
+	err = run("ADD", strings.NewReader(`{"cniVersion": "1.0.0", "type": "tc-cni"}`), &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "chained plugin")
+
+	err = run("ADD", strings.NewReader(`{"cniVersion": "0.1.0", "type": "tc-cni"}`), &out)
+	var perr *pluginError
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, errIncompatibleVersion, perr.Code)
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rng999/traffic-control-go/api"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// newController creates the traffic controller for a device, replaced in tests
+var newController = api.NetworkInterface
+
+// daemon keeps a device shaped as its configuration file says
+type daemon struct {
+	path    string
+	history string
+	socket  string // Control socket, empty when not served
+	states  string // Directory of the traffic-control command's state files, empty when none
+	resync  time.Duration
+	logger  logging.Logger
+
+	controller *api.TrafficController
+	device     string                    // Device of the controller
+	config     *api.TrafficControlConfig // Last configuration read, applied or not
+	applied    bool                      // The configuration was applied at least once
+
+	ctx         context.Context    // Context of run, nil when not running
+	stopLinks   context.CancelFunc // Stops watching the controller's device links
+	linkUp      chan struct{}      // A watched device came up or was recreated
+	stopControl context.CancelFunc // Stops serving the control socket
+	controlDone chan struct{}      // Closed once the control socket is no longer served
+	applies     chan controlApply  // Configurations applied through the control socket
+}
+
+// controlApply is a configuration applied through the control socket, with the channel
+// receiving the result of applying it
+type controlApply struct {
+	config *api.TrafficControlConfig
+	done   chan error
+}
+
+func newDaemon(path, history, socket string, resync time.Duration) *daemon {
+	return &daemon{
+		path:    path,
+		history: history,
+		socket:  socket,
+		resync:  resync,
+		logger:  logging.WithComponent("tcd"),
+		linkUp:  make(chan struct{}, 1),
+		applies: make(chan controlApply),
+	}
+}
+
+// run applies the configuration and keeps the device shaped until the context is cancelled.
+// Failures to read or apply the configuration are logged and retried rather than fatal, so
+// that a device missing at boot is shaped once it appears. The device is reconciled as soon
+// as it comes back up or is recreated. A signal on reload re-reads the file like a change does.
+// Configurations applied through the control socket are applied from the same loop.
+func (d *daemon) run(ctx context.Context, reload <-chan os.Signal) error {
+	changes, err := watchFile(ctx, d.path)
+	if err != nil {
+		return err
+	}
+	d.ctx = ctx
+
+	d.load()
+
+	var tick <-chan time.Time
+	if d.resync > 0 {
+		ticker := time.NewTicker(d.resync)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changes:
+			d.logger.Info("Configuration file changed", logging.String("path", d.path))
+			d.load()
+		case <-reload:
+			d.logger.Info("Reloading configuration", logging.String("path", d.path))
+			d.load()
+		case request := <-d.applies:
+			request.done <- d.applyControl(request.config)
+		case <-d.linkUp:
+			d.reconcile()
+		case <-tick:
+			d.reconcile()
+		}
+	}
+}
+
+// load reads the configuration file and applies it. An invalid file leaves the configuration
+// applied before in place.
+func (d *daemon) load() {
+	config, err := loadConfig(d.path)
+	if err != nil {
+		d.logger.Error("Failed to load configuration, keeping the current one",
+			logging.String("path", d.path),
+			logging.Error(err),
+		)
+		return
+	}
+	d.config = config
+	_ = d.apply()
+}
+
+// applyControl applies a configuration received on the control socket. It replaces the
+// file's until the file changes or is reloaded; when it fails the current one is kept.
+func (d *daemon) applyControl(config *api.TrafficControlConfig) error {
+	previous := d.config
+	d.config = config
+	if err := d.apply(); err != nil {
+		d.config = previous
+		return err
+	}
+	return nil
+}
+
+// apply applies the configuration last read, on a new controller if the device changed
+func (d *daemon) apply() error {
+	if d.controller == nil || d.config.Device != d.device {
+		controller, err := d.newController(d.config.Device)
+		if err != nil {
+			d.logger.Error("Failed to create traffic controller",
+				logging.String("device", d.config.Device),
+				logging.Error(err),
+			)
+			return err
+		}
+		d.close()
+		d.controller, d.device, d.applied = controller, d.config.Device, false
+		d.watchLinks()
+		d.serveControl()
+	}
+
+	if err := d.controller.ReplaceConfig(d.config); err != nil {
+		d.logger.Error("Failed to apply configuration",
+			logging.String("device", d.config.Device),
+			logging.Error(err),
+		)
+		return err
+	}
+	d.applied = true
+	d.forgetApplied()
+	d.logger.Info("Configuration applied",
+		logging.String("device", d.config.Device),
+		logging.Int("classes", len(d.config.Classes)),
+	)
+	return nil
+}
+
+// forgetApplied removes the hash the traffic-control command recorded for the device when it
+// last applied a configuration directly: the daemon's configuration replaced it, so applying
+// it again must not be taken as unchanged
+func (d *daemon) forgetApplied() {
+	if d.states == "" {
+		return
+	}
+	if err := os.Remove(filepath.Join(d.states, d.device+".sha256")); err != nil && !os.IsNotExist(err) {
+		d.logger.Warn("Failed to remove the state file of the device",
+			logging.String("device", d.device),
+			logging.Error(err),
+		)
+	}
+}
+
+// reconcile restores the applied configuration on the device, or retries applying the
+// configuration when it never was
+func (d *daemon) reconcile() {
+	if d.config == nil {
+		d.load()
+		return
+	}
+	if !d.applied {
+		_ = d.apply()
+		return
+	}
+
+	if err := d.controller.Apply(); err != nil {
+		d.logger.Warn("Failed to reconcile device",
+			logging.String("device", d.device),
+			logging.Error(err),
+		)
+	}
+}
+
+// newController creates the controller for a device, recording its history if configured
+func (d *daemon) newController(device string) (*api.TrafficController, error) {
+	controller := newController(device)
+	if d.history != "" {
+		if err := controller.PersistHistory(d.history); err != nil {
+			return nil, err
+		}
+	}
+	return controller, nil
+}
+
+// watchLinks watches the current controller's device links while the daemon runs, handing
+// re-applying to the daemon's loop so that the controller is used from one goroutine
+func (d *daemon) watchLinks() {
+	if d.ctx == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	d.stopLinks = cancel
+	watcher := d.controller.NewLinkWatcher(0).WithoutReapply().OnLinkUp(func(api.LinkEvent) {
+		select {
+		case d.linkUp <- struct{}{}:
+		default: // A reconciliation is already pending
+		}
+	})
+	go func(device string) {
+		if err := watcher.Run(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Warn("Stopped watching device links, relying on periodic reconciliation",
+				logging.String("device", device),
+				logging.Error(err),
+			)
+		}
+	}(d.device)
+}
+
+// serveControl serves the current controller on the control socket while the daemon runs,
+// so that the traffic-control command reads its statistics and applies through the daemon
+func (d *daemon) serveControl() {
+	if d.ctx == nil || d.socket == "" {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	done := make(chan struct{})
+	d.stopControl, d.controlDone = cancel, done
+	server := d.controller.NewControlServer().OnApply(func(config *api.TrafficControlConfig) error {
+		return d.requestApply(ctx, config)
+	})
+	go func(device string) {
+		defer close(done)
+		if err := server.Serve(ctx, d.socket); err != nil && ctx.Err() == nil {
+			d.logger.Warn("Stopped serving the control socket",
+				logging.String("device", device),
+				logging.String("socket", d.socket),
+				logging.Error(err),
+			)
+		}
+	}(d.device)
+}
+
+// requestApply hands a configuration received on the control socket to the daemon's loop and
+// waits for the result of applying it
+func (d *daemon) requestApply(ctx context.Context, config *api.TrafficControlConfig) error {
+	request := controlApply{config: config, done: make(chan error, 1)}
+	select {
+	case d.applies <- request:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-request.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close stops watching the current controller's device and serving it on the control socket,
+// and releases its history database
+func (d *daemon) close() {
+	if d.stopLinks != nil {
+		d.stopLinks()
+		d.stopLinks = nil
+	}
+	if d.stopControl != nil {
+		// Wait for the listener to close, as closing it removes the socket the next
+		// controller may already be served on
+		d.stopControl()
+		<-d.controlDone
+		d.stopControl, d.controlDone = nil, nil
+	}
+	if d.controller == nil {
+		return
+	}
+	if err := d.controller.CloseHistory(); err != nil {
+		d.logger.Warn("Failed to close configuration history", logging.Error(err))
+	}
+}
+
+// loadConfig reads a JSON configuration file, or a YAML one for any other extension. The
+// path comes from the operator, so unlike the api loaders any directory is accepted.
+func loadConfig(path string) (*api.TrafficControlConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return api.ParseConfigFromJSON(data)
+	}
+	return api.ParseConfigFromYAML(data)
+}
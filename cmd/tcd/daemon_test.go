@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/api"
+	"github.com/rng999/traffic-control-go/api/tctest"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+const testConfig = `version: "1.0"
+device: eth0
+bandwidth: 100mbit
+classes:
+  - name: web
+    guaranteed: 30mbit
+    maximum: 60mbit
+    priority: 1
+rules:
+  - name: http
+    match:
+      dest_port: [80]
+    target: web
+`
+
+// withFake makes the daemon's controllers change the fake instead of the kernel
+func withFake(t *testing.T) *tctest.Fake {
+	fake := tctest.New()
+	newController = func(device string) *api.TrafficController {
+		return api.NetworkInterface(device).WithNetlinkAdapter(fake)
+	}
+	t.Cleanup(func() { newController = api.NetworkInterface })
+	return fake
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestDaemon_LoadAndReconcile(t *testing.T) {
+	fake := withFake(t)
+	path := filepath.Join(t.TempDir(), "eth0.yaml")
+	writeConfig(t, path, testConfig)
+
+	d := newDaemon(path, "", "", time.Minute)
+	d.load()
+	require.True(t, d.applied)
+	assert.Len(t, fake.Classes("eth0"), 2)
+
+	// Reconciling an intact device changes nothing
+	before := len(fake.Operations())
+	d.reconcile()
+	assert.Len(t, fake.Operations(), before)
+
+	// A link bounce wipes the qdiscs; reconciling restores them
+	fake.RemoveRootQdisc(tc.MustNewDeviceName("eth0"))
+	d.reconcile()
+	assert.Len(t, fake.Qdiscs("eth0"), 1)
+	assert.Len(t, fake.Classes("eth0"), 2)
+
+	// An invalid file keeps the configuration applied before
+	writeConfig(t, path, "device: eth0\nclasses: []\n")
+	d.load()
+	assert.Len(t, fake.Classes("eth0"), 2)
+
+	// A changed file is reconciled
+	writeConfig(t, path, testConfig+`  - name: ssh
+    match:
+      dest_port: [22]
+    target: web
+`)
+	d.load()
+	assert.Len(t, fake.Filters("eth0"), 2)
+}
+
+func TestDaemon_ForgetsAppliedHash(t *testing.T) {
+	withFake(t)
+	path := filepath.Join(t.TempDir(), "eth0.yaml")
+	writeConfig(t, path, testConfig)
+
+	// The traffic-control command applied a configuration directly before the daemon started
+	states := t.TempDir()
+	state := filepath.Join(states, "eth0.sha256")
+	require.NoError(t, os.WriteFile(state, []byte("0123\n"), 0o600))
+
+	d := newDaemon(path, "", "", time.Minute)
+	d.states = states
+	d.load()
+	require.True(t, d.applied)
+	assert.NoFileExists(t, state)
+}
+
+func TestDaemon_RetriesUntilApplied(t *testing.T) {
+	fake := withFake(t)
+	fake.FailNext(tctest.OpAddQdisc, os.ErrNotExist)
+	path := filepath.Join(t.TempDir(), "eth0.yaml")
+	writeConfig(t, path, testConfig)
+
+	d := newDaemon(path, "", "", time.Minute)
+	d.load()
+	assert.False(t, d.applied)
+	assert.Empty(t, fake.Qdiscs("eth0"))
+
+	d.reconcile()
+	assert.True(t, d.applied)
+	assert.Len(t, fake.Qdiscs("eth0"), 1)
+}
+
+func TestDaemon_ReconcilesWhenLinkReturns(t *testing.T) {
+	fake := withFake(t)
+	path := filepath.Join(t.TempDir(), "tun0.yaml")
+	writeConfig(t, path, strings.Replace(testConfig, "eth0", "tun0", 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := newDaemon(path, "", "", 0)
+	done := make(chan error, 1)
+	go func() { done <- d.run(ctx, nil) }()
+
+	require.Eventually(t, func() bool { return len(fake.Qdiscs("tun0")) == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	// Restarting the VPN recreates the device without its qdiscs. The daemon watches the
+	// link asynchronously; keep restarting until the device is shaped again.
+	device := tc.MustNewDeviceName("tun0")
+	qdiscsAdded := func() int {
+		added := 0
+		for _, operation := range fake.Operations() {
+			if operation.Op == tctest.OpAddQdisc && operation.Err == nil {
+				added++
+			}
+		}
+		return added
+	}
+	require.Eventually(t, func() bool {
+		if qdiscsAdded() == 1 {
+			fake.RemoveLink(device)
+			fake.SetLinkState(device, true)
+		}
+		return qdiscsAdded() == 2
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.Len(t, fake.Classes("tun0"), 2)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestDaemon_ServesControlSocket(t *testing.T) {
+	fake := withFake(t)
+	dir := t.TempDir()
+	path, socket := filepath.Join(dir, "eth0.yaml"), filepath.Join(dir, "tcd.sock")
+	writeConfig(t, path, testConfig)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := newDaemon(path, "", socket, 0)
+	done := make(chan error, 1)
+	go func() { done <- d.run(ctx, nil) }()
+
+	var client *api.ControlClient
+	require.Eventually(t, func() bool {
+		var err error
+		client, err = api.DialControl(socket)
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	defer func() { _ = client.Close() }()
+
+	health, err := client.Health()
+	require.NoError(t, err)
+	assert.Equal(t, "eth0", health.Device)
+	stats, err := client.Statistics()
+	require.NoError(t, err)
+	assert.Equal(t, "eth0", stats.DeviceName)
+
+	// An apply through the socket is reconciled by the daemon
+	config, err := api.ParseConfigFromYAML([]byte(testConfig + `  - name: ssh
+    match:
+      dest_port: [22]
+    target: web
+`))
+	require.NoError(t, err)
+	require.NoError(t, client.Apply(config))
+	assert.Len(t, fake.Filters("eth0"), 2)
+
+	// A configuration the daemon fails to apply is reported, keeping the current one
+	fake.FailNext(tctest.OpAddFilter, os.ErrPermission)
+	config.Rules = append(config.Rules, api.TrafficRuleConfig{Name: "dns", Match: api.MatchConfig{DestPort: []int{53}}, Target: "web"})
+	assert.Error(t, client.Apply(config))
+	assert.Len(t, fake.Filters("eth0"), 2)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	d.close()
+	_, err = os.Stat(socket)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eth0.yaml")
+	writeConfig(t, path, testConfig)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := watchFile(ctx, path)
+	require.NoError(t, err)
+
+	// Replace the file through a rename, as editors do
+	tmp := path + ".tmp"
+	writeConfig(t, tmp, testConfig)
+	require.NoError(t, os.Rename(tmp, path))
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not reported")
+	}
+}
@@ -0,0 +1,64 @@
+// Command tcd is a daemon keeping a device shaped as a declarative configuration file says.
+// It applies the file at start, re-applies it whenever the file changes, and reconciles the
+// device periodically, so that shaping wiped by a link bounce, a recreated interface or a
+// network manager is restored. SIGHUP reloads the file. Changes are reconciled: only what
+// differs from the configuration applied before reaches the device. The device is served on
+// a control socket, through which the traffic-control command reads statistics and applies
+// configurations while the daemon runs.
+//
+// Usage:
+//
+//	tcd -config /etc/tcd/eth0.yaml
+//	tcd -config /etc/tcd/eth0.json -resync 10s -history /var/lib/tcd/eth0.db
+//	tcd -config /etc/tcd/eth0.yaml -socket /run/tcd-eth0.sock
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rng999/traffic-control-go/api"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+)
+
+// DefaultResyncInterval is the default time between reconciliations of the device
+const DefaultResyncInterval = 30 * time.Second
+
+func main() {
+	configPath := flag.String("config", "", "YAML or JSON configuration file to apply and watch")
+	resync := flag.Duration("resync", DefaultResyncInterval, "interval between reconciliations of the device, 0 to disable")
+	history := flag.String("history", "", "SQLite database recording the configuration history across restarts")
+	socket := flag.String("socket", api.DefaultControlSocket, "control socket serving the traffic-control command, empty to disable")
+	states := flag.String("state-dir", "/run/traffic-control", "state directory of the traffic-control command, whose hash of the device's configuration is removed on apply")
+	flag.Parse()
+
+	if *configPath == "" {
+		fmt.Fprintln(os.Stderr, "tcd: -config is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := logging.Initialize(logging.LoadConfigFromEnv()); err != nil {
+		fmt.Fprintf(os.Stderr, "tcd: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	d := newDaemon(*configPath, *history, *socket, *resync)
+	d.states = *states
+	defer d.close()
+	if err := d.run(ctx, reload); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "tcd: %v\n", err)
+		os.Exit(1)
+	}
+}
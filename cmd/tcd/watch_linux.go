@@ -0,0 +1,86 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchSettle is how long the watcher waits for a burst of events to end, as editors
+// write a file in several steps
+const watchSettle = 200 * time.Millisecond
+
+// watchFile reports changes to the file until the context is cancelled. It watches the
+// file's directory with inotify, so that files replaced by a rename, as editors and
+// configuration management tools do, are followed.
+func watchFile(ctx context.Context, path string) (<-chan struct{}, error) {
+	dir, name := filepath.Split(filepath.Clean(path))
+	if dir == "" {
+		dir = "."
+	}
+
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+	const mask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_CREATE | unix.IN_DELETE
+	if _, err := unix.InotifyAddWatch(fd, dir, mask); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer unix.Close(fd)
+
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		var settle <-chan time.Time
+		for ctx.Err() == nil {
+			select {
+			case <-settle:
+				settle = nil
+				select {
+				case changes <- struct{}{}:
+				default: // A change is already pending
+				}
+			default:
+			}
+
+			// Poll so that cancellation and the settle timer are noticed
+			fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+			if _, err := unix.Poll(fds, 100); err != nil && !errors.Is(err, unix.EINTR) {
+				return
+			}
+			n, err := unix.Read(fd, buf)
+			if err != nil {
+				continue
+			}
+			if eventsNamed(buf[:n], name) {
+				settle = time.After(watchSettle)
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// eventsNamed reports whether any inotify event in buf concerns the named file
+func eventsNamed(buf []byte, name string) bool {
+	for offset := 0; offset+unix.SizeofInotifyEvent <= len(buf); {
+		event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+		offset += unix.SizeofInotifyEvent + int(event.Len)
+		if strings.TrimRight(string(nameBytes), "\x00") == name {
+			return true
+		}
+	}
+	return false
+}
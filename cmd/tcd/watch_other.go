@@ -0,0 +1,49 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// watchPollInterval is how often the file is checked where inotify is not available
+const watchPollInterval = 2 * time.Second
+
+// watchFile reports changes to the file until the context is cancelled, by polling its
+// modification time and size
+func watchFile(ctx context.Context, path string) (<-chan struct{}, error) {
+	stamp := func() (time.Time, int64) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return info.ModTime(), info.Size()
+	}
+	modified, size := stamp()
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m, s := stamp()
+				if m.Equal(modified) && s == size {
+					continue
+				}
+				modified, size = m, s
+				select {
+				case changes <- struct{}{}:
+				default: // A change is already pending
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rng999/traffic-control-go/api"
+)
+
+// runApply applies the configuration file named by the arguments to its device, through the
+// tcd managing the device when one runs so that the daemon reconciles it from then on.
+// Applied directly, the hash of the configuration is recorded in a state file, and a
+// configuration with the hash recorded is reported unchanged without reading the device:
+// running the command from cron costs nothing until the file changes.
+func runApply(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("apply", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	socket := flags.String("socket", controlSocket, "control socket of a running tcd, empty to always apply directly")
+	states := flags.String("state-dir", stateDir, "directory of the hashes of the configurations applied, empty to always apply")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: traffic-control apply [flags] <config.yaml|config.json>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return errUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+	path := flags.Arg(0)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	if client := dialDaemon(*socket, config.Device); client != nil {
+		defer func() { _ = client.Close() }()
+		forgetApplied(*states, config.Device)
+		if err := client.Apply(config); err != nil {
+			return fmt.Errorf("tcd failed to apply %s: %w", path, err)
+		}
+		fmt.Fprintf(stdout, "applied %s to %s through tcd\n", path, config.Device)
+		return nil
+	}
+
+	// The daemon's configuration changes without this command, so the state is only kept
+	// for configurations applied directly
+	state := stateFile(*states, config.Device)
+	hash, err := configHash(config)
+	if err != nil {
+		return err
+	}
+	if state != "" && appliedHash(state) == hash {
+		fmt.Fprintln(stdout, "unchanged")
+		return nil
+	}
+
+	if err := newController(config.Device).ApplyConfig(config); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "applied %s to %s\n", path, config.Device)
+
+	if state != "" {
+		if err := recordHash(state, hash); err != nil {
+			fmt.Fprintf(stderr, "traffic-control: %v; the next apply will not detect an unchanged configuration\n", err)
+		}
+	}
+	return nil
+}
+
+// loadConfig reads a JSON configuration file, or a YAML one for any other extension. The
+// path comes from the operator, so unlike the api loaders any directory is accepted.
+func loadConfig(path string) (*api.TrafficControlConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return api.ParseConfigFromJSON(data)
+	}
+	return api.ParseConfigFromYAML(data)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/api"
+	"github.com/rng999/traffic-control-go/api/tctest"
+)
+
+const webConfig = `version: "1.0"
+device: eth0
+bandwidth: 100mbit
+classes:
+  - name: web
+    guaranteed: 30mbit
+    priority: 1
+rules:
+  - name: http
+    match:
+      dest_port: [80]
+    target: web
+`
+
+func writeWebConfig(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "eth0.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(webConfig), 0o600))
+	return path
+}
+
+func TestApply_Directly(t *testing.T) {
+	fake := withFake(t)
+	path := writeWebConfig(t)
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"apply", path}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Equal(t, "applied "+path+" to eth0\n", stdout.String())
+	assert.Len(t, fake.Classes("eth0"), 2)
+	assert.Len(t, fake.Filters("eth0"), 1)
+
+	assert.Equal(t, 2, run([]string{"apply"}, strings.NewReader(""), &stdout, &stderr))
+	assert.Equal(t, 1, run([]string{"apply", filepath.Join(t.TempDir(), "missing.yaml")}, strings.NewReader(""), &stdout, &stderr))
+}
+
+func TestApply_Unchanged(t *testing.T) {
+	fake := withFake(t)
+	path := writeWebConfig(t)
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"apply", path}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	operations := len(fake.Operations())
+
+	// The same configuration, reformatted, is detected from the state file alone
+	require.NoError(t, os.WriteFile(path, []byte("# web shaping\n"+webConfig), 0o600))
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"apply", path}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Equal(t, "unchanged\n", stdout.String())
+	assert.Len(t, fake.Operations(), operations)
+
+	// Without a state directory the device is reconciled every time
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"apply", "-state-dir", "", path}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Equal(t, "applied "+path+" to eth0\n", stdout.String())
+
+	// A changed configuration is applied and recorded
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(webConfig, "30mbit", "40mbit", 1)), 0o600))
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"apply", path}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Equal(t, "applied "+path+" to eth0\n", stdout.String())
+	assert.Greater(t, len(fake.Operations()), operations)
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"apply", path}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Equal(t, "unchanged\n", stdout.String())
+
+	// Resetting the device forgets the configuration applied
+	require.Equal(t, 0, run([]string{"reset", "-force", "eth0"}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"apply", path}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Equal(t, "applied "+path+" to eth0\n", stdout.String())
+	assert.Len(t, fake.Classes("eth0"), 2)
+
+	// So does resetting it with the state directory the configuration was applied with
+	states := t.TempDir()
+	require.Equal(t, 0, run([]string{"apply", "-state-dir", states, path}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	require.Equal(t, 0, run([]string{"reset", "-force", "-state-dir", states, "eth0"}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"apply", "-state-dir", states, path}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Equal(t, "applied "+path+" to eth0\n", stdout.String())
+
+	// A failed apply records nothing
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(webConfig, "[80]", "[8080]", 1)), 0o600))
+	fake.FailNext(tctest.OpAddFilter, os.ErrPermission)
+	assert.Equal(t, 1, run([]string{"apply", path}, strings.NewReader(""), &stdout, &stderr))
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"apply", path}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Equal(t, "applied "+path+" to eth0\n", stdout.String())
+}
+
+func TestApply_ThroughDaemon(t *testing.T) {
+	local := withFake(t)
+	path := writeWebConfig(t)
+
+	// A daemon shapes eth0 of another fake and serves it on the socket
+	daemonFake := tctest.New()
+	socket := filepath.Join(t.TempDir(), "tcd.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := api.NetworkInterface("eth0").WithNetlinkAdapter(daemonFake).NewControlServer()
+	go func() { _ = server.Serve(ctx, socket) }()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(socket)
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The configuration applied directly before the daemon started is no longer on the device
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"apply", "-socket", "", path}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	require.FileExists(t, stateFile(stateDir, "eth0"))
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"apply", "-socket", socket, path}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Equal(t, "applied "+path+" to eth0 through tcd\n", stdout.String())
+	assert.Len(t, daemonFake.Classes("eth0"), 2)
+	assert.NoFileExists(t, stateFile(stateDir, "eth0"))
+
+	// Statistics come from the daemon as well
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"monitor", "-socket", socket, "-count", "1", "eth0"}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "1:11")
+
+	// The daemon shapes eth0 only; eth1 is applied directly
+	stdout.Reset()
+	eth1 := filepath.Join(t.TempDir(), "eth1.yaml")
+	require.NoError(t, os.WriteFile(eth1, []byte(strings.Replace(webConfig, "eth0", "eth1", 1)), 0o600))
+	require.Equal(t, 0, run([]string{"apply", "-socket", socket, eth1}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Equal(t, "applied "+eth1+" to eth1\n", stdout.String())
+	assert.Len(t, local.Classes("eth1"), 2)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/rng999/traffic-control-go/api"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+// controlSocket is the default control socket of a running tcd, replaced in tests
+var controlSocket = api.DefaultControlSocket
+
+// dialDaemon connects to the tcd serving the control socket when it manages the device, and
+// returns nil when the command should talk to the kernel itself: no daemon runs, or it
+// shapes another device
+func dialDaemon(socket, device string) *api.ControlClient {
+	if socket == "" {
+		return nil
+	}
+	client, err := api.DialControl(socket)
+	if err != nil {
+		return nil
+	}
+	if health, err := client.Health(); err != nil || health.Device != device {
+		_ = client.Close()
+		return nil
+	}
+	return client
+}
+
+// statisticsReader returns the function reading the device statistics, from the daemon
+// managing the device when one runs, and the function releasing its connection
+func statisticsReader(socket, device string) (func() (*qmodels.DeviceStatisticsView, error), func()) {
+	if client := dialDaemon(socket, device); client != nil {
+		return client.Statistics, func() { _ = client.Close() }
+	}
+	return newController(device).GetRealtimeStatistics, func() {}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rng999/traffic-control-go/api"
+)
+
+// errAborted reports a deletion the user declined at the confirmation prompt
+var errAborted = errors.New("aborted")
+
+// handleList collects the values of a flag given several times
+type handleList []string
+
+func (l *handleList) String() string { return strings.Join(*l, ",") }
+
+func (l *handleList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// runDelete deletes the qdiscs, classes and filters named by the flags
+func runDelete(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var objects api.Objects
+	flags := flag.NewFlagSet("delete", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Var((*handleList)(&objects.Qdiscs), "qdisc", "handle of a qdisc to delete, repeatable")
+	flags.Var((*handleList)(&objects.Classes), "class", "handle of a class to delete, repeatable")
+	flags.Var((*handleList)(&objects.Filters), "filter", "handle of a filter to delete, repeatable")
+	force := flags.Bool("force", false, "delete without asking for confirmation")
+	states := stateDirFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: traffic-control delete [-qdisc handle] [-class handle] [-filter handle] [-force] [-state-dir dir] <device>")
+		flags.PrintDefaults()
+	}
+	device, err := parseDeviceArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(objects.Qdiscs)+len(objects.Classes)+len(objects.Filters) == 0 {
+		fmt.Fprintln(stderr, "traffic-control: delete needs at least one -qdisc, -class or -filter")
+		flags.Usage()
+		return errUsage
+	}
+
+	var names []string
+	for _, group := range []struct {
+		kind    string
+		handles []string
+	}{{"qdisc", objects.Qdiscs}, {"class", objects.Classes}, {"filter", objects.Filters}} {
+		for _, handle := range group.handles {
+			names = append(names, group.kind+" "+handle)
+		}
+	}
+	question := fmt.Sprintf("Delete %s and everything attached to them from %s?", strings.Join(names, ", "), device)
+	if !*force && !confirm(stdin, stdout, question) {
+		return errAborted
+	}
+
+	forgetApplied(*states, device)
+	deleted, err := newController(device).Delete(objects)
+	printDeleted(stdout, deleted)
+	return err
+}
+
+// runReset deletes every qdisc, class and filter of the device
+func runReset(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("reset", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	force := flags.Bool("force", false, "reset without asking for confirmation")
+	states := stateDirFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: traffic-control reset [-force] [-state-dir dir] <device>")
+		flags.PrintDefaults()
+	}
+	device, err := parseDeviceArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	question := fmt.Sprintf("Delete all qdiscs, classes and filters from %s?", device)
+	if !*force && !confirm(stdin, stdout, question) {
+		return errAborted
+	}
+
+	forgetApplied(*states, device)
+	deleted, err := newController(device).Reset()
+	printDeleted(stdout, deleted)
+	return err
+}
+
+// confirm asks a yes/no question and reports whether it was answered yes
+func confirm(stdin io.Reader, stdout io.Writer, question string) bool {
+	fmt.Fprintf(stdout, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// printDeleted lists the objects deleted
+func printDeleted(w io.Writer, deleted []string) {
+	for _, object := range deleted {
+		fmt.Fprintf(w, "deleted %s\n", object)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/api/tctest"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+func TestDelete_AsksForConfirmation(t *testing.T) {
+	fake := withFake(t)
+	applyWeb(t, fake)
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, run([]string{"delete", "-class", "1:11", "eth0"}, strings.NewReader("n\n"), &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "Delete class 1:11 and everything attached to them from eth0? [y/N]")
+	assert.Contains(t, stderr.String(), "aborted")
+	assert.Len(t, fake.Classes("eth0"), 2)
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"delete", "-class", "1:11", "eth0"}, strings.NewReader("y\n"), &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "deleted class 1:11\n")
+	assert.Contains(t, stdout.String(), "deleted qdisc 11:\n")
+	assert.Len(t, fake.Classes("eth0"), 1)
+	assert.Empty(t, fake.Filters("eth0"))
+}
+
+func TestDelete_Force(t *testing.T) {
+	fake := withFake(t)
+	applyWeb(t, fake)
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"delete", "-force", "-qdisc", "11:", "-class", "1:999", "eth0"}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.NotContains(t, stdout.String(), "[y/N]")
+	assert.Equal(t, "deleted qdisc 11:\ndeleted class 1:999\n", stdout.String())
+
+	// Unknown objects fail without deleting anything
+	assert.Equal(t, 1, run([]string{"delete", "-force", "-class", "1:42", "eth0"}, strings.NewReader(""), &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "class 1:42 not found")
+
+	// At least one object is needed
+	assert.Equal(t, 2, run([]string{"delete", "-force", "eth0"}, strings.NewReader(""), &stdout, &stderr))
+}
+
+func TestReset(t *testing.T) {
+	fake := withFake(t)
+	applyWeb(t, fake)
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"reset", "eth0"}, strings.NewReader("yes\n"), &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "Delete all qdiscs, classes and filters from eth0? [y/N]")
+	assert.Contains(t, stdout.String(), "deleted qdisc 1:\n")
+	assert.Empty(t, fake.Qdiscs("eth0"))
+	assert.Empty(t, fake.Classes("eth0"))
+}
+
+func TestDelete_PrintsHint(t *testing.T) {
+	fake := withFake(t)
+	applyWeb(t, fake)
+	fake.FailOn(tctest.OpDeleteFilter, tcerrors.New(tcerrors.CodePermissionDenied, "filter 800::800", "failed to delete filter"))
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, run([]string{"reset", "-force", "eth0"}, strings.NewReader(""), &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "failed to delete filter")
+	assert.Contains(t, stderr.String(), "\nhint: run as root or grant the process CAP_NET_ADMIN\n")
+}
@@ -0,0 +1,133 @@
+// Command traffic-control inspects and clears the traffic control configuration of a
+// device. The show subcommand reads the qdiscs, classes and filters installed in the kernel,
+// including those not created by this library, and prints them as a tree with the rates,
+// ceilings and counters of each node. monitor samples the class counters every interval and
+// redraws their rates, drops and backlog with a sparkline of the recent rates. delete removes
+// single objects together with everything attached to them, and reset removes all of them;
+// both ask for confirmation unless -force is given. restore re-applies the configuration last
+// applied with a configuration history, such as tcd's -history, for a boot hook to reinstall
+// the shaping a reboot removed. apply applies a configuration file, and prints "unchanged"
+// without touching the device when the file's configuration is the one it applied last.
+//
+// When tcd manages the device, apply, show and monitor go through its control socket:
+// configurations are applied by the daemon, which keeps reconciling them, and statistics
+// come from the daemon. Without a daemon they talk to the kernel directly.
+//
+// Usage:
+//
+//	traffic-control apply /etc/tcd/eth0.yaml
+//	traffic-control show eth0
+//	traffic-control show -stats=false eth0
+//	traffic-control monitor -interval 2s eth0
+//	traffic-control delete -class 1:10 -filter 800:800 eth0
+//	traffic-control reset -force eth0
+//	traffic-control restore -history /var/lib/tcd/eth0.db eth0
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rng999/traffic-control-go/api"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// newController creates the traffic controller for a device, replaced in tests
+var newController = api.NetworkInterface
+
+var (
+	// errUsage reports invalid arguments whose message the flag set already printed
+	errUsage = errors.New("invalid usage")
+	// errHelp reports that the help of a subcommand was asked for and printed
+	errHelp = errors.New("help requested")
+)
+
+func main() {
+	config := logging.LoadConfigFromEnv()
+	config.OutputPaths = []string{"stderr"}
+	if err := logging.Initialize(config); err != nil {
+		fmt.Fprintf(os.Stderr, "traffic-control: %v\n", err)
+		os.Exit(1)
+	}
+
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes a subcommand and returns the exit status: 2 for usage errors, 1 when the
+// subcommand fails
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "apply":
+		err = runApply(args[1:], stdout, stderr)
+	case "show":
+		err = runShow(args[1:], stdout, stderr)
+	case "monitor":
+		err = runMonitor(args[1:], stdout, stderr)
+	case "delete":
+		err = runDelete(args[1:], stdin, stdout, stderr)
+	case "reset":
+		err = runReset(args[1:], stdin, stdout, stderr)
+	case "restore":
+		err = runRestore(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "traffic-control: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	switch {
+	case err == errHelp:
+		return 0
+	case err == errUsage:
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "traffic-control: %v\n", err)
+		if hint := tcerrors.HintOf(err); hint != "" {
+			fmt.Fprintf(stderr, "hint: %s\n", hint)
+		}
+		return 1
+	}
+	return 0
+}
+
+// parseDeviceArgs parses the flags of a subcommand taking a single device argument
+func parseDeviceArgs(flags *flag.FlagSet, args []string) (string, error) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return "", errHelp
+		}
+		return "", errUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return "", errUsage
+	}
+	return flags.Arg(0), nil
+}
+
+// usage prints the available subcommands
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: traffic-control <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintln(w, "  apply <config>    apply a configuration file, through tcd when it manages the device")
+	fmt.Fprintln(w, "  show <device>     print the installed qdiscs, classes and filters as a tree")
+	fmt.Fprintln(w, "  monitor <device>  redraw the rate, drops and backlog of every class as they change")
+	fmt.Fprintln(w, "  delete <device>   delete qdiscs, classes or filters with what is attached to them")
+	fmt.Fprintln(w, "  reset <device>    delete all qdiscs, classes and filters")
+	fmt.Fprintln(w, "  restore <device>  re-apply the configuration recorded in a history, as after a reboot")
+}
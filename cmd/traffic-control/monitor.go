@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// clearScreen moves the cursor home and clears the terminal before a frame is drawn
+const clearScreen = "\x1b[H\x1b[2J"
+
+// sparkLevels are the bars of a sparkline from the lowest to the highest value
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// runMonitor samples the class counters of the device every interval and redraws their
+// rates, drops and backlog until interrupted or, with -count, after that many samples
+func runMonitor(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("monitor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	interval := flags.Duration("interval", time.Second, "time between samples")
+	history := flags.Int("history", 30, "number of samples drawn in the sparkline of each class")
+	count := flags.Int("count", 0, "stop after this many samples, 0 runs until interrupted")
+	socket := flags.String("socket", controlSocket, "control socket of a running tcd to read the statistics from")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: traffic-control monitor [flags] <device>")
+		flags.PrintDefaults()
+	}
+	device, err := parseDeviceArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if *interval <= 0 || *history < 1 || *count < 0 {
+		fmt.Fprintln(stderr, "traffic-control: -interval and -history must be positive and -count not negative")
+		return errUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	statistics, closeStatistics := statisticsReader(*socket, device)
+	defer closeStatistics()
+	monitor := newClassMonitor(*history)
+	redraw := isTerminal(stdout)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for samples := 0; ; {
+		stats, err := statistics()
+		if err != nil {
+			return fmt.Errorf("failed to read the statistics of %s: %w", device, err)
+		}
+		monitor.update(stats, time.Now())
+
+		var frame strings.Builder
+		if redraw {
+			frame.WriteString(clearScreen)
+		} else if samples > 0 {
+			frame.WriteString("\n")
+		}
+		monitor.render(&frame, device, *interval)
+		if _, err := io.WriteString(stdout, frame.String()); err != nil {
+			return err
+		}
+
+		samples++
+		if *count > 0 && samples >= *count {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// classMonitor turns successive samples of the class counters into per-interval rates
+type classMonitor struct {
+	size    int
+	at      time.Time
+	classes map[string]*classSeries
+	order   []string
+}
+
+// classSeries is the state of one class across samples
+type classSeries struct {
+	name      string
+	bytesSent uint64
+	dropped   uint64
+	backlog   uint64
+	rate      uint64   // Bits per second over the last interval
+	drops     uint64   // Dropped during the last interval
+	rates     []uint64 // Rates of the last intervals, oldest first
+}
+
+func newClassMonitor(size int) *classMonitor {
+	return &classMonitor{size: size, classes: make(map[string]*classSeries)}
+}
+
+// update records a sample taken at the given time. Classes missing from the sample are
+// forgotten, and a counter that went backwards is taken as the class being recreated.
+func (m *classMonitor) update(stats *qmodels.DeviceStatisticsView, at time.Time) {
+	elapsed := at.Sub(m.at).Seconds()
+	classes := make(map[string]*classSeries, len(stats.ClassStats))
+	m.order = m.order[:0]
+
+	for _, class := range stats.ClassStats {
+		series, seen := m.classes[class.Handle]
+		if !seen {
+			series = &classSeries{}
+		}
+		if seen && elapsed > 0 {
+			sent := counterDelta(series.bytesSent, class.BytesSent)
+			series.rate = uint64(float64(sent*8) / elapsed)
+			series.drops = counterDelta(series.dropped, class.PacketsDropped)
+			series.rates = append(series.rates, series.rate)
+			if len(series.rates) > m.size {
+				series.rates = series.rates[len(series.rates)-m.size:]
+			}
+		}
+		series.name = class.Name
+		series.bytesSent = class.BytesSent
+		series.dropped = class.PacketsDropped
+		series.backlog = class.BacklogBytes
+
+		classes[class.Handle] = series
+		m.order = append(m.order, class.Handle)
+	}
+
+	sort.Slice(m.order, func(i, j int) bool { return handleLess(m.order[i], m.order[j]) })
+	m.classes = classes
+	m.at = at
+}
+
+// render writes a frame listing every class with its rate, drops, backlog and sparkline
+func (m *classMonitor) render(w io.Writer, device string, interval time.Duration) {
+	fmt.Fprintf(w, "%s  every %s  (Ctrl-C to quit)\n\n", device, interval)
+	if len(m.order) == 0 {
+		fmt.Fprintf(w, "no classes on %s\n", device)
+		return
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CLASS\tRATE\tDROPS\tBACKLOG\tHISTORY")
+	for _, handle := range m.order {
+		series := m.classes[handle]
+		label := handle
+		if series.name != "" && series.name != handle {
+			label += " " + series.name
+		}
+		rate, drops := "-", "-"
+		if len(series.rates) > 0 {
+			rate = tc.Bps(series.rate).HumanReadable()
+			drops = fmt.Sprintf("%d", series.drops)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", label, rate, drops, formatBytes(series.backlog), sparkline(series.rates))
+	}
+	table.Flush()
+}
+
+// counterDelta returns how much a counter grew, or its new value when it was reset
+func counterDelta(previous, current uint64) uint64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+// sparkline draws the values as bars scaled to the largest of them
+func sparkline(values []uint64) string {
+	var peak uint64
+	for _, value := range values {
+		if value > peak {
+			peak = value
+		}
+	}
+
+	var b strings.Builder
+	for _, value := range values {
+		level := 0
+		if peak > 0 {
+			level = int(value * uint64(len(sparkLevels)-1) / peak)
+		}
+		b.WriteRune(sparkLevels[level])
+	}
+	return b.String()
+}
+
+// isTerminal reports whether the writer is a terminal that frames can be redrawn on
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
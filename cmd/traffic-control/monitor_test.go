@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+func TestClassMonitor_ComputesRates(t *testing.T) {
+	monitor := newClassMonitor(3)
+	start := time.Unix(1700000000, 0)
+	sample := func(at time.Duration, sent, dropped, backlog uint64) {
+		monitor.update(&qmodels.DeviceStatisticsView{ClassStats: []qmodels.ClassStatisticsView{
+			{Handle: "1:999"},
+			{Handle: "1:11", Name: "web", BytesSent: sent, PacketsDropped: dropped, BacklogBytes: backlog},
+		}}, start.Add(at))
+	}
+
+	sample(0, 1000, 0, 0)
+	var frame bytes.Buffer
+	monitor.render(&frame, "eth0", time.Second)
+	lines := strings.Split(strings.TrimRight(frame.String(), "\n"), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, "eth0  every 1s  (Ctrl-C to quit)", lines[0])
+	assert.Equal(t, "CLASS     RATE  DROPS  BACKLOG  HISTORY", strings.TrimRight(lines[2], " "))
+	assert.Equal(t, "1:11 web  -     -      0B", strings.TrimRight(lines[3], " "), "no rate before the second sample")
+
+	sample(time.Second, 126000, 2, 1500)
+	sample(3*time.Second, 376000, 5, 0)
+	sample(4*time.Second, 1000, 5, 0) // Counters reset with the class recreated
+	frame.Reset()
+	monitor.render(&frame, "eth0", time.Second)
+	lines = strings.Split(strings.TrimRight(frame.String(), "\n"), "\n")
+	assert.Equal(t, "1:11 web  8.0Kbps  0      0B       ██▁", lines[3])
+	assert.Equal(t, "1:999     0bps     0      0B       ▁▁▁", lines[4])
+
+	series := monitor.classes["1:11"]
+	assert.Equal(t, []uint64{1000000, 1000000, 8000}, series.rates)
+}
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, "", sparkline(nil))
+	assert.Equal(t, "▁▁", sparkline([]uint64{0, 0}))
+	assert.Equal(t, "▁▄█", sparkline([]uint64{0, 50, 100}))
+}
+
+func TestMonitor_SamplesDevice(t *testing.T) {
+	fake := withFake(t)
+	applyWeb(t, fake)
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"monitor", "-interval", "1ms", "-count", "2", "eth0"}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	frames := strings.Split(stdout.String(), "\n\neth0  every 1ms")
+	require.Len(t, frames, 2, "frames written to a pipe are separated by blank lines")
+	assert.NotContains(t, stdout.String(), clearScreen)
+	assert.Contains(t, frames[1], "1:11")
+	assert.Contains(t, frames[1], "1:999")
+
+	assert.Equal(t, 2, run([]string{"monitor", "-interval", "0s", "eth0"}, strings.NewReader(""), &stdout, &stderr))
+}
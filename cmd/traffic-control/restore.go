@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// runRestore re-applies the configuration last applied to the device, as recorded in a
+// configuration history, for a boot hook to reinstall the shaping a reboot removed
+func runRestore(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	history := flags.String("history", "", "SQLite configuration history the configuration was applied with")
+	states := stateDirFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: traffic-control restore -history path [-state-dir dir] <device>")
+		flags.PrintDefaults()
+	}
+	device, err := parseDeviceArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if *history == "" {
+		fmt.Fprintln(stderr, "traffic-control: restore needs -history")
+		flags.Usage()
+		return errUsage
+	}
+
+	controller := newController(device)
+	if err := controller.PersistHistory(*history); err != nil {
+		return err
+	}
+	defer func() { _ = controller.CloseHistory() }()
+
+	forgetApplied(*states, device)
+	restored, err := controller.Restore()
+	if err != nil {
+		return err
+	}
+	for _, object := range restored.Added {
+		fmt.Fprintf(stdout, "restored %s\n", object)
+	}
+	for _, object := range restored.Deleted {
+		fmt.Fprintf(stdout, "deleted %s\n", object)
+	}
+	if !restored.Changed() {
+		fmt.Fprintf(stdout, "%s is already configured as recorded\n", device)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/api"
+	"github.com/rng999/traffic-control-go/api/tctest"
+)
+
+func TestRestore(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "eth0.db")
+
+	controller := api.NetworkInterface("eth0").WithNetlinkAdapter(tctest.New())
+	require.NoError(t, controller.PersistHistory(history))
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").WithGuaranteedBandwidth("30mbps").WithPriority(1).ForPort(80)
+	require.NoError(t, controller.Apply())
+	require.NoError(t, controller.CloseHistory())
+
+	// The host rebooted: its kernel configuration is empty. A hash left in the state
+	// directory does not describe what restore installs.
+	fake := withFake(t)
+	states := t.TempDir()
+	require.NoError(t, recordHash(stateFile(states, "eth0"), "0123"))
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"restore", "-history", history, "-state-dir", states, "eth0"}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "restored class 1:11\n")
+	assert.NoFileExists(t, stateFile(states, "eth0"))
+	assert.Len(t, fake.Classes("eth0"), 2)
+	assert.Len(t, fake.Filters("eth0"), 1)
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"restore", "-history", history, "eth0"}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Equal(t, "eth0 is already configured as recorded\n", stdout.String())
+
+	assert.Equal(t, 2, run([]string{"restore", "eth0"}, strings.NewReader(""), &stdout, &stderr))
+	assert.Equal(t, 1, run([]string{"restore", "-history", history, "eth1"}, strings.NewReader(""), &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "no configuration of eth1 is recorded")
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+// runShow prints the tree of the device named by the arguments
+func runShow(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("show", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	withStats := flags.Bool("stats", true, "include the sent, dropped and overlimit counters of every node")
+	socket := flags.String("socket", controlSocket, "control socket of a running tcd to read the counters from")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: traffic-control show [flags] <device>")
+		flags.PrintDefaults()
+	}
+	device, err := parseDeviceArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	controller := newController(device)
+	config, err := controller.ReadCurrentConfiguration()
+	if err != nil {
+		return fmt.Errorf("failed to read the configuration of %s: %w", device, err)
+	}
+
+	// Rates and ceilings come with the detailed class statistics, as the kernel state
+	// holds only the class hierarchy
+	classes := make(map[string]qmodels.ClassStatisticsView, len(config.Classes))
+	for _, class := range config.Classes {
+		if stats, err := controller.GetClassStatistics(class.Handle); err == nil {
+			classes[class.Handle] = *stats
+		}
+	}
+
+	var stats *qmodels.DeviceStatisticsView
+	if *withStats {
+		statistics, closeStatistics := statisticsReader(*socket, device)
+		stats, err = statistics()
+		closeStatistics()
+		if err != nil {
+			return fmt.Errorf("failed to read the statistics of %s: %w", device, err)
+		}
+	}
+
+	return renderTree(stdout, config, classes, stats)
+}
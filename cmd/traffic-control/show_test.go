@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/api"
+	"github.com/rng999/traffic-control-go/api/tctest"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// withFake makes the command's controllers read the fake instead of the kernel, and keeps
+// the commands from finding a daemon or state files on the host
+func withFake(t *testing.T) *tctest.Fake {
+	fake := tctest.New()
+	newController = func(device string) *api.TrafficController {
+		return api.NetworkInterface(device).WithNetlinkAdapter(fake)
+	}
+	controlSocket, stateDir = filepath.Join(t.TempDir(), "none.sock"), t.TempDir()
+	t.Cleanup(func() {
+		newController, controlSocket, stateDir = api.NetworkInterface, api.DefaultControlSocket, "/run/traffic-control"
+	})
+	return fake
+}
+
+// applyWeb shapes eth0 of the fake with a web class queueing into SFQ
+func applyWeb(t *testing.T, fake *tctest.Fake) {
+	controller := api.NetworkInterface("eth0").WithNetlinkAdapter(fake)
+	controller.WithHardLimitBandwidth("100mbps")
+	controller.CreateTrafficClass("web").
+		WithGuaranteedBandwidth("30mbps").
+		WithPriority(1).
+		WithLeafQdisc(api.SFQ()).
+		ForPort(80)
+	require.NoError(t, controller.Apply())
+}
+
+func TestShow_RendersTree(t *testing.T) {
+	fake := withFake(t)
+	applyWeb(t, fake)
+
+	device := tc.MustNewDeviceName("eth0")
+	fake.SetClassStatistics(device, tc.NewHandle(1, 0x11), tctest.ClassStats{BytesSent: 1500000, PacketsSent: 1000, PacketsDropped: 4})
+	fake.SetSFQStatistics(device, tc.NewHandle(0x11, 0), tctest.SFQStats{ActiveFlows: 3})
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"show", "eth0"}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	lines := strings.Split(strings.TrimRight(stdout.String(), "\n"), "\n")
+
+	require.Len(t, lines, 6)
+	assert.Equal(t, "eth0", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "└── qdisc htb 1:  [sent"), lines[1])
+	assert.Equal(t, "    ├── class 1:11 rate 8.0Mbps ceil 16.0Mbps  [sent 1.5MB 1000 pkts, dropped 4, overlimits 0]", lines[2])
+	assert.Contains(t, lines[3], "│   └── qdisc sfq 11:")
+	assert.Contains(t, lines[3], "active flows 3")
+	assert.True(t, strings.HasPrefix(lines[4], "    ├── class 1:999"), lines[4])
+	assert.Equal(t, "    └── filter u32 prio 100 ip -> 1:11 match ip dport 80 0xffff", lines[5])
+}
+
+func TestShow_WithoutStatistics(t *testing.T) {
+	fake := withFake(t)
+	applyWeb(t, fake)
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"show", "-stats=false", "eth0"}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "class 1:11 rate 8.0Mbps ceil 16.0Mbps\n")
+	assert.NotContains(t, stdout.String(), "[sent")
+}
+
+func TestShow_EmptyDevice(t *testing.T) {
+	withFake(t)
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"show", "eth1"}, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	assert.Equal(t, "eth1\n└── (no qdiscs)\n", stdout.String())
+}
+
+func TestRun_UsageErrors(t *testing.T) {
+	withFake(t)
+
+	for _, args := range [][]string{nil, {"frobnicate"}, {"show"}, {"show", "-bogus", "eth0"}, {"show", "eth0", "eth1"}} {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, run(args, strings.NewReader(""), &stdout, &stderr), "args %q", args)
+		assert.NotEmpty(t, stderr.String(), "args %q", args)
+	}
+}
+
+func TestQdiscCounters_Codel(t *testing.T) {
+	counters := qdiscCounters(qmodels.QdiscStatisticsView{
+		Type:  "codel",
+		Codel: &qmodels.CodelStatisticsView{LDelayUs: 7500, Dropping: true, Count: 4},
+	})
+	assert.Contains(t, counters, ", delay 7.5ms, dropping (4 drops)]")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rng999/traffic-control-go/api"
+)
+
+// stateDir is the default directory of the hashes of the configurations last applied,
+// replaced in tests. It lives under /run so that a reboot, which removes the shaping, also
+// forgets what was applied.
+var stateDir = "/run/traffic-control"
+
+// stateFile returns the path of the state file of a device, empty when no state is kept
+func stateFile(dir, device string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, device+".sha256")
+}
+
+// configHash returns the hash of a configuration. It is taken over the parsed configuration,
+// so that reformatting or commenting the file does not change it.
+func configHash(config *api.TrafficControlConfig) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// appliedHash returns the hash recorded in the state file, empty when there is none
+func appliedHash(path string) string {
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// forgetApplied removes the state file of a device about to be changed other than by applying
+// the configuration directly, so that applying it again is not taken as unchanged
+func forgetApplied(dir, device string) {
+	if path := stateFile(dir, device); path != "" {
+		_ = os.Remove(path)
+	}
+}
+
+// stateDirFlag defines the -state-dir flag of a command changing the device
+func stateDirFlag(flags *flag.FlagSet) *string {
+	return flags.String("state-dir", stateDir, "directory of the hashes of the configurations applied, forgotten when the device changes")
+}
+
+// recordHash records the hash of the configuration applied, replacing the state file at once
+// so that an interrupted write never leaves a partial hash behind
+func recordHash(path, hash string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	defer func() { _ = os.Remove(temp.Name()) }()
+	if _, err := temp.WriteString(hash + "\n"); err != nil {
+		_ = temp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// treeNode is a qdisc, class or filter with the nodes attached under it
+type treeNode struct {
+	label    string
+	children []*treeNode
+}
+
+// deviceTree indexes a configuration and its statistics by parent handle
+type deviceTree struct {
+	config      *qmodels.ConfigurationView
+	qdiscStats  map[string]qmodels.QdiscStatisticsView
+	classStats  map[string]qmodels.ClassStatisticsView
+	classByID   map[string]bool
+	qdiscByID   map[string]bool
+	withCounter bool
+}
+
+// renderTree writes the qdisc, class and filter hierarchy of a device, taking the rates of
+// classes from their detailed statistics. Without device statistics the counters are left
+// out.
+func renderTree(w io.Writer, config *qmodels.ConfigurationView, classes map[string]qmodels.ClassStatisticsView, stats *qmodels.DeviceStatisticsView) error {
+	tree := newDeviceTree(config, classes, stats)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", config.DeviceName)
+	roots := tree.roots()
+	if len(roots) == 0 {
+		b.WriteString("└── (no qdiscs)\n")
+	}
+	for i, root := range roots {
+		writeNode(&b, root, "", i == len(roots)-1)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// newDeviceTree indexes the configuration and statistics
+func newDeviceTree(config *qmodels.ConfigurationView, classes map[string]qmodels.ClassStatisticsView, stats *qmodels.DeviceStatisticsView) *deviceTree {
+	tree := &deviceTree{
+		config:      config,
+		qdiscStats:  make(map[string]qmodels.QdiscStatisticsView),
+		classStats:  make(map[string]qmodels.ClassStatisticsView),
+		classByID:   make(map[string]bool),
+		qdiscByID:   make(map[string]bool),
+		withCounter: stats != nil,
+	}
+	for _, class := range config.Classes {
+		tree.classByID[class.Handle] = true
+	}
+	for _, qdisc := range config.Qdiscs {
+		tree.qdiscByID[qdisc.Handle] = true
+	}
+
+	if stats != nil {
+		for _, qdisc := range stats.QdiscStats {
+			tree.qdiscStats[qdisc.Handle] = qdisc
+		}
+		for _, class := range stats.ClassStats {
+			tree.classStats[class.Handle] = class
+		}
+	}
+	for handle, class := range classes {
+		tree.classStats[handle] = class
+		// Leaf qdiscs carry the fq_codel and sfq details the qdisc list lacks
+		if stats != nil && class.LeafQdisc != nil {
+			tree.qdiscStats[class.LeafQdisc.Handle] = *class.LeafQdisc
+		}
+	}
+	return tree
+}
+
+// roots returns the qdiscs attached to the device itself rather than under a class
+func (t *deviceTree) roots() []*treeNode {
+	var qdiscs []qmodels.QdiscView
+	for _, qdisc := range t.config.Qdiscs {
+		if !t.classByID[qdisc.Parent] && !t.qdiscByID[qdisc.Parent] {
+			qdiscs = append(qdiscs, qdisc)
+		}
+	}
+	sort.Slice(qdiscs, func(i, j int) bool { return handleLess(qdiscs[i].Handle, qdiscs[j].Handle) })
+
+	nodes := make([]*treeNode, 0, len(qdiscs))
+	for _, qdisc := range qdiscs {
+		nodes = append(nodes, t.qdiscNode(qdisc))
+	}
+	return nodes
+}
+
+// children returns the classes, leaf qdiscs and filters attached under a handle
+func (t *deviceTree) children(parent string) []*treeNode {
+	var classes []qmodels.ClassView
+	for _, class := range t.config.Classes {
+		if class.Parent == parent && class.Handle != parent {
+			classes = append(classes, class)
+		}
+	}
+	sort.Slice(classes, func(i, j int) bool { return handleLess(classes[i].Handle, classes[j].Handle) })
+
+	var qdiscs []qmodels.QdiscView
+	for _, qdisc := range t.config.Qdiscs {
+		if qdisc.Parent == parent && t.classByID[parent] {
+			qdiscs = append(qdiscs, qdisc)
+		}
+	}
+	sort.Slice(qdiscs, func(i, j int) bool { return handleLess(qdiscs[i].Handle, qdiscs[j].Handle) })
+
+	var filters []qmodels.FilterView
+	for _, filter := range t.config.Filters {
+		if filter.Parent == parent {
+			filters = append(filters, filter)
+		}
+	}
+	sort.SliceStable(filters, func(i, j int) bool { return filters[i].Priority < filters[j].Priority })
+
+	nodes := make([]*treeNode, 0, len(classes)+len(qdiscs)+len(filters))
+	for _, qdisc := range qdiscs {
+		nodes = append(nodes, t.qdiscNode(qdisc))
+	}
+	for _, class := range classes {
+		nodes = append(nodes, t.classNode(class))
+	}
+	for _, filter := range filters {
+		nodes = append(nodes, &treeNode{label: filterLabel(filter)})
+	}
+	return nodes
+}
+
+// qdiscNode returns a qdisc with its classes and filters
+func (t *deviceTree) qdiscNode(qdisc qmodels.QdiscView) *treeNode {
+	label := fmt.Sprintf("qdisc %s %s", qdisc.Type, qdisc.Handle)
+	if qdisc.DefaultClass != "" {
+		label += " default " + qdisc.DefaultClass
+	}
+	if t.withCounter {
+		label += "  " + qdiscCounters(t.qdiscStats[qdisc.Handle])
+	}
+	return &treeNode{label: label, children: t.children(qdisc.Handle)}
+}
+
+// classNode returns a class with its child classes, leaf qdisc and filters
+func (t *deviceTree) classNode(class qmodels.ClassView) *treeNode {
+	label := "class "
+	if class.Type != "" {
+		label += class.Type + " "
+	}
+	label += class.Handle
+	if class.Name != "" {
+		label += fmt.Sprintf(" %q", class.Name)
+	}
+	stats := t.classStats[class.Handle]
+	if rate := firstNonEmpty(class.GuaranteedBandwidth, class.Rate, htbBandwidth(stats, "htb_rate")); rate != "" {
+		label += " rate " + rate
+	}
+	if ceil := firstNonEmpty(class.MaxBandwidth, class.Ceil, htbBandwidth(stats, "htb_ceil")); ceil != "" {
+		label += " ceil " + ceil
+	}
+	if t.withCounter {
+		label += "  " + classCounters(stats)
+	}
+	return &treeNode{label: label, children: t.children(class.Handle)}
+}
+
+// filterLabel describes a filter and the class it sends traffic to
+func filterLabel(filter qmodels.FilterView) string {
+	label := "filter"
+	if filter.Kind != "" {
+		label += " " + filter.Kind
+	}
+	label += fmt.Sprintf(" prio %d", filter.Priority)
+	if filter.Protocol != "" {
+		label += " " + filter.Protocol
+	}
+	if filter.FlowID != "" {
+		label += " -> " + filter.FlowID
+	}
+
+	keys := make([]string, 0, len(filter.Matches))
+	for key := range filter.Matches {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		label += " match " + filter.Matches[key]
+	}
+	for _, action := range filter.Actions {
+		label += " action " + action
+	}
+	return label
+}
+
+// qdiscCounters formats the counters of a qdisc, with the fq_codel, codel and sfq details
+// of leaf qdiscs when reported
+func qdiscCounters(stats qmodels.QdiscStatisticsView) string {
+	counters := fmt.Sprintf("[sent %s %d pkts, dropped %d, overlimits %d, requeues %d",
+		formatBytes(stats.BytesSent), stats.PacketsSent, stats.PacketsDropped, stats.Overlimits, stats.Requeues)
+	if stats.Backlog > 0 {
+		counters += ", backlog " + formatBytes(uint64(stats.Backlog))
+	}
+	if stats.FQCodel != nil {
+		counters += fmt.Sprintf(", overlimit drops %d, ecn marks %d, flows %d",
+			stats.FQCodel.DropOverlimit, stats.FQCodel.ECNMarks, stats.FQCodel.NewFlows+stats.FQCodel.OldFlows)
+	}
+	if stats.Codel != nil {
+		counters += fmt.Sprintf(", delay %s", time.Duration(stats.Codel.LDelayUs)*time.Microsecond)
+		if stats.Codel.Dropping {
+			counters += fmt.Sprintf(", dropping (%d drops)", stats.Codel.Count)
+		}
+	}
+	if stats.SFQ != nil {
+		counters += fmt.Sprintf(", active flows %d", stats.SFQ.ActiveFlows)
+	}
+	return counters + "]"
+}
+
+// classCounters formats the counters and current rate of a class
+func classCounters(stats qmodels.ClassStatisticsView) string {
+	counters := fmt.Sprintf("[sent %s %d pkts, dropped %d, overlimits %d",
+		formatBytes(stats.BytesSent), stats.PacketsSent, stats.PacketsDropped, stats.Overlimits)
+	if stats.BacklogBytes > 0 {
+		counters += ", backlog " + formatBytes(stats.BacklogBytes)
+	}
+	if stats.RateBPS > 0 {
+		counters += ", rate " + tc.Bps(stats.RateBPS).HumanReadable()
+	}
+	return counters + "]"
+}
+
+// htbBandwidth formats an HTB rate of the detailed class statistics, which the kernel
+// reports in bytes per second, or returns "" when the class is not an HTB class
+func htbBandwidth(stats qmodels.ClassStatisticsView, key string) string {
+	bytesPerSecond, ok := stats.DetailedStats[key].(uint64)
+	if !ok || bytesPerSecond == 0 {
+		return ""
+	}
+	return tc.Bps(bytesPerSecond * 8).HumanReadable()
+}
+
+// writeNode writes a node and its children, drawing the branches of the tree
+func writeNode(b *strings.Builder, node *treeNode, indent string, last bool) {
+	branch, childIndent := "├── ", indent+"│   "
+	if last {
+		branch, childIndent = "└── ", indent+"    "
+	}
+	fmt.Fprintf(b, "%s%s%s\n", indent, branch, node.label)
+	for i, child := range node.children {
+		writeNode(b, child, childIndent, i == len(node.children)-1)
+	}
+}
+
+// formatBytes formats a byte count with a decimal unit
+func formatBytes(bytes uint64) string {
+	switch {
+	case bytes >= 1000*1000*1000:
+		return fmt.Sprintf("%.1fGB", float64(bytes)/1e9)
+	case bytes >= 1000*1000:
+		return fmt.Sprintf("%.1fMB", float64(bytes)/1e6)
+	case bytes >= 1000:
+		return fmt.Sprintf("%.1fKB", float64(bytes)/1e3)
+	default:
+		return fmt.Sprintf("%dB", bytes)
+	}
+}
+
+// handleLess orders handles numerically by major then minor, falling back to the text of
+// handles that do not parse
+func handleLess(a, b string) bool {
+	ha, errA := tc.ParseHandle(a)
+	hb, errB := tc.ParseHandle(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	if ha.Major() != hb.Major() {
+		return ha.Major() < hb.Major()
+	}
+	return ha.Minor() < hb.Minor()
+}
+
+// firstNonEmpty returns the first of the values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
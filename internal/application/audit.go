@@ -0,0 +1,81 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+// AuditLog returns the configuration changes recorded for a device since the given time,
+// oldest first. A zero time returns all of them.
+func (s *TrafficControlService) AuditLog(ctx context.Context, device string, since time.Time) ([]qmodels.AuditEntryView, error) {
+	history, err := s.GetDeviceHistory(ctx, device)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]qmodels.AuditEntryView, 0, len(history))
+	for _, event := range history {
+		if event.Timestamp().Before(since) {
+			continue
+		}
+		entry, err := auditEntry(device, event)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// auditEntry describes the change recorded by an event. The object and action follow
+// from the event type, the details are the event's fields.
+func auditEntry(device string, event events.DomainEvent) (qmodels.AuditEntryView, error) {
+	eventType := event.EventType()
+	entry := qmodels.AuditEntryView{
+		Time:    event.Timestamp(),
+		Device:  device,
+		Version: event.EventVersion(),
+		Event:   eventType,
+		Action:  "modified",
+	}
+	if e, ok := event.(interface{ Actor() string }); ok {
+		entry.Actor = e.Actor()
+	}
+
+	switch {
+	case strings.Contains(eventType, "Qdisc"):
+		entry.Object = "qdisc"
+	case strings.Contains(eventType, "Class"):
+		entry.Object = "class"
+	case strings.Contains(eventType, "Filter"):
+		entry.Object = "filter"
+	}
+	switch {
+	case strings.Contains(eventType, "Created"):
+		entry.Action = "added"
+	case strings.Contains(eventType, "Deleted"):
+		entry.Action = "deleted"
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return entry, fmt.Errorf("failed to describe %s event: %w", eventType, err)
+	}
+	if err := json.Unmarshal(data, &entry.Details); err != nil {
+		return entry, fmt.Errorf("failed to describe %s event: %w", eventType, err)
+	}
+	if handle, ok := entry.Details["Handle"].(string); ok {
+		entry.Handle = handle
+	}
+	// The device is reported once for the entry
+	delete(entry.Details, "DeviceName")
+
+	return entry, nil
+}
@@ -0,0 +1,137 @@
+package application
+
+import (
+	"context"
+	"sort"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// ownedRootMajor is the major number of the root qdisc the library installs. Its objects
+// are that qdisc and everything attached below it, so that they are told apart from those
+// of other tools even when the event store holding their records was lost.
+const ownedRootMajor = 1
+
+// coexistenceKey is the context key of WithCoexistence
+type coexistenceKey struct{}
+
+// WithCoexistence returns a context in which Reconcile, DryRun and Remove share the device
+// with other tools, such as systemd-networkd or Docker: qdiscs, classes and filters that are
+// neither recorded nor attached to the library's root qdisc are theirs and left in place.
+func WithCoexistence(ctx context.Context) context.Context {
+	return context.WithValue(ctx, coexistenceKey{}, true)
+}
+
+// coexisting reports whether the context was made by WithCoexistence
+func coexisting(ctx context.Context) bool {
+	shared, _ := ctx.Value(coexistenceKey{}).(bool)
+	return shared
+}
+
+// foreignObjects returns the keys of the live objects of other tools: those not recorded
+// and not attached, directly or through their parents, to the library's root qdisc
+func foreignObjects(current map[string]*tcObject) map[string]bool {
+	byHandle := make(map[tc.Handle]*tcObject)
+	for _, object := range current {
+		if object.kind != kindFilter {
+			byHandle[object.handle] = object
+		}
+	}
+
+	owned := func(object *tcObject) bool {
+		if object.created != nil || object.kind != kindFilter && object.handle.Major() == ownedRootMajor {
+			return true
+		}
+		// A class's parent is a class or qdisc of the same major, so walking the parents
+		// reaches the qdisc the object hangs from; the bound guards against cycles
+		for parent, steps := object.parent, 0; parent != nil && steps <= len(current); steps++ {
+			if parent.Major() == ownedRootMajor {
+				return true
+			}
+			next, ok := byHandle[*parent]
+			if !ok {
+				return false
+			}
+			if next.created != nil {
+				return true
+			}
+			parent = next.parent
+		}
+		return false
+	}
+
+	foreign := make(map[string]bool)
+	for key, object := range current {
+		if !owned(object) {
+			foreign[key] = true
+		}
+	}
+	return foreign
+}
+
+// setAsideForeignObjects removes the objects of other tools from the live objects, so that
+// reconciliation neither deletes nor keeps them, and fails if the desired state needs their
+// place: their handle, or the root for a root qdisc
+func setAsideForeignObjects(device string, current, desired map[string]*tcObject) error {
+	foreign := foreignObjects(current)
+
+	for key := range desired {
+		if foreign[key] {
+			return coexistenceConflict(device, key, key)
+		}
+	}
+	for key := range foreign {
+		if object := current[key]; object.kind == kindQdisc && object.parent == nil {
+			for desiredKey, want := range desired {
+				if want.kind == kindQdisc && want.parent == nil {
+					return coexistenceConflict(device, key, desiredKey)
+				}
+			}
+		}
+	}
+
+	for key := range foreign {
+		delete(current, key)
+	}
+	return nil
+}
+
+// coexistenceConflict is the error of a desired object taking the place of an object of
+// another tool
+func coexistenceConflict(device, foreign, desired string) error {
+	return tcerrors.New(tcerrors.CodeHandleConflict, foreign,
+		"%s on device %s belongs to another tool and is left in place, so %s cannot be installed", foreign, device, desired).
+		WithHint("remove it from the other tool's configuration, or apply with exclusive ownership to replace it")
+}
+
+// foreignSelected is the error of selecting an object of another tool for removal
+func foreignSelected(device, foreign string) error {
+	return tcerrors.New(tcerrors.CodeInvalidConfiguration, foreign,
+		"%s on device %s belongs to another tool and is left in place", foreign, device).
+		WithHint("delete it through the tool that installed it, or with exclusive ownership")
+}
+
+// ForeignObjects lists the qdiscs, classes and filters on the device that belong to other
+// tools, which Reconcile leaves in place in a context made by WithCoexistence. The kernel's
+// default qdiscs and the clsact qdisc with its filters are not listed; the clsact qdisc is
+// shared rather than owned.
+func (s *TrafficControlService) ForeignObjects(ctx context.Context, device string) ([]qmodels.ForeignObjectView, error) {
+	recorded, err := s.GetDeviceHistory(ctx, device)
+	if err != nil {
+		return nil, err
+	}
+	current := s.liveObjects(ctx, device, recordedObjects(recorded))
+
+	views := make([]qmodels.ForeignObjectView, 0)
+	for key := range foreignObjects(current) {
+		view := qmodels.ForeignObjectView{Object: key}
+		if parent := current[key].parent; parent != nil {
+			view.Parent = parent.String()
+		}
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Object < views[j].Object })
+	return views, nil
+}
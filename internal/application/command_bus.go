@@ -146,6 +146,28 @@ func (gcb *CommandBus) publishCommandEvents(ctx context.Context, commandType str
 		return gcb.service.eventBus.Publish(ctx, "QdiscCreated", nil)
 	case "CreateFQCODELQdiscCommand":
 		return gcb.service.eventBus.Publish(ctx, "QdiscCreated", nil)
+	case "CreateSFQQdiscCommand":
+		return gcb.service.eventBus.Publish(ctx, "QdiscCreated", nil)
+	case "CreateFQQdiscCommand":
+		return gcb.service.eventBus.Publish(ctx, "QdiscCreated", nil)
+	case "CreateNETEMQdiscCommand":
+		return gcb.service.eventBus.Publish(ctx, "QdiscCreated", nil)
+	case "CreateREDQdiscCommand":
+		return gcb.service.eventBus.Publish(ctx, "QdiscCreated", nil)
+	case "CreateGREDQdiscCommand":
+		return gcb.service.eventBus.Publish(ctx, "QdiscCreated", nil)
+	case "CreateCODELQdiscCommand":
+		return gcb.service.eventBus.Publish(ctx, "QdiscCreated", nil)
+	case "CreateETSQdiscCommand":
+		return gcb.service.eventBus.Publish(ctx, "QdiscCreated", nil)
+	case "CreateTAPRIOQdiscCommand":
+		return gcb.service.eventBus.Publish(ctx, "QdiscCreated", nil)
+	case "CreateCAKEQdiscCommand":
+		return gcb.service.eventBus.Publish(ctx, "QdiscCreated", nil)
+	case "CreateDRRQdiscCommand", "CreateQFQQdiscCommand":
+		return gcb.service.eventBus.Publish(ctx, "QdiscCreated", nil)
+	case "CreateDRRClassCommand", "CreateQFQClassCommand":
+		return gcb.service.eventBus.Publish(ctx, "ClassCreated", nil)
 	}
 
 	return nil
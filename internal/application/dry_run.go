@@ -0,0 +1,298 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// DryRun plans the changes Reconcile would make to bring a device to the desired state and
+// returns them as the equivalent tc commands, in the order Reconcile would make them. The
+// device's kernel state is read but nothing is changed and no event is recorded.
+func (s *TrafficControlService) DryRun(ctx context.Context, device string, desired []events.DomainEvent) ([]string, error) {
+	plan, err := s.planReconcile(ctx, device, desired)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := make([]string, 0, len(plan.removals)+len(plan.changes)+len(plan.additions))
+	for _, object := range plan.removals {
+		commands = append(commands, deleteCommand(device, object))
+	}
+	for _, change := range plan.changes {
+		commands = append(commands, htbClassCommand("change", advancedHTBClassFromEvent(change.current)))
+	}
+	for i, event := range plan.additions {
+		command, err := createCommand(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", plan.additionKeys[i], err)
+		}
+		commands = append(commands, command)
+	}
+
+	return commands, nil
+}
+
+// IngressRedirectCommands returns the commands CreateIngressRedirect is equivalent to
+func (s *TrafficControlService) IngressRedirectCommands(device, ifb string) ([]string, error) {
+	deviceName, ifbName, err := parseIngressDevices(device, ifb)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{
+		fmt.Sprintf("ip link add %s type ifb", ifbName),
+		fmt.Sprintf("ip link set dev %s up", ifbName),
+		fmt.Sprintf("tc qdisc add dev %s clsact", deviceName),
+		fmt.Sprintf("tc filter add dev %s ingress protocol all prio 1 matchall action mirred egress redirect dev %s", deviceName, ifbName),
+	}, nil
+}
+
+// ClsactCommands returns the commands adding the clsact qdisc and attaching the BPF
+// classifiers and hook filters to its hooks
+func (s *TrafficControlService) ClsactCommands(device string, classifiers []netlink.BPFClassifier, filters []HookFilter) ([]string, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+	if len(classifiers) == 0 && len(filters) == 0 {
+		return nil, nil
+	}
+
+	commands := []string{fmt.Sprintf("tc qdisc add dev %s clsact", deviceName)}
+	for _, classifier := range classifiers {
+		program := "pinned " + classifier.Pinned
+		if classifier.Pinned == "" {
+			program = "obj " + classifier.Object
+			if classifier.Section != "" {
+				program += " sec " + classifier.Section
+			}
+		}
+		commands = append(commands, fmt.Sprintf("tc filter replace dev %s %s prio %d handle 1 bpf da %s",
+			deviceName, classifier.Hook, classifier.Priority, program))
+	}
+	for _, filter := range filters {
+		entity, err := buildHookFilter(device, filter)
+		if err != nil {
+			return nil, err
+		}
+		selector, err := netlink.FilterSelector(entity.Kind(), entity.Matches())
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, fmt.Sprintf("tc filter replace dev %s %s protocol %s prio %d %s%s",
+			deviceName, filter.Hook, netlink.ProtocolArg(entity.Protocol()), filter.Priority, selector,
+			netlink.ActionArgs(entity.Actions(), entity.Protocol())))
+	}
+	return commands, nil
+}
+
+// deleteCommand returns the tc command deleting an object. Filters are deleted by priority,
+// which the planner assigns to one filter each.
+func deleteCommand(device string, object *tcObject) string {
+	switch object.kind {
+	case kindFilter:
+		return fmt.Sprintf("tc filter del dev %s parent %s prio %d", device, object.parent, object.priority)
+	case kindClass:
+		return fmt.Sprintf("tc class del dev %s classid %s", device, object.handle)
+	default:
+		return fmt.Sprintf("tc qdisc del dev %s %s handle %s", device, netlink.ParentArg(object.parent), object.handle)
+	}
+}
+
+// createCommand returns the tc command creating the object of a creation event
+func createCommand(event events.DomainEvent) (string, error) {
+	switch e := event.(type) {
+	case *events.QdiscCreatedEvent:
+		return qdiscCommand(e.DeviceName, e.Parent, e.Handle, e.QdiscType.String()), nil
+	case *events.HTBQdiscCreatedEvent:
+		args := fmt.Sprintf("htb default %x", e.DefaultClass.Minor())
+		if e.R2Q > 0 {
+			args += fmt.Sprintf(" r2q %d", e.R2Q)
+		}
+		return qdiscCommand(e.DeviceName, nil, e.Handle, args), nil
+	case *events.TBFQdiscCreatedEvent:
+		return qdiscCommand(e.DeviceName, nil, e.Handle, fmt.Sprintf("tbf rate %s burst %d limit %d",
+			netlink.RateArg(e.Rate), e.Burst, e.Limit)), nil
+	case *events.PRIOQdiscCreatedEvent:
+		priomap := make([]string, len(e.Priomap))
+		for i, band := range e.Priomap {
+			priomap[i] = fmt.Sprint(band)
+		}
+		return qdiscCommand(e.DeviceName, nil, e.Handle, fmt.Sprintf("prio bands %d priomap %s",
+			e.Bands, strings.Join(priomap, " "))), nil
+	case *events.FQCODELQdiscCreatedEvent:
+		return qdiscCommand(e.DeviceName, e.Parent, e.Handle, fmt.Sprintf("fq_codel limit %d flows %d target %dus interval %dus quantum %d %s",
+			e.Limit, e.Flows, e.Target, e.Interval, e.Quantum, flagArg(e.ECN, "ecn", "noecn"))), nil
+	case *events.SFQQdiscCreatedEvent:
+		return qdiscCommand(e.DeviceName, e.Parent, e.Handle, fmt.Sprintf("sfq perturb %d quantum %d limit %d",
+			e.Perturb, e.Quantum, e.Limit)), nil
+	case *events.FQQdiscCreatedEvent:
+		args := fmt.Sprintf("fq limit %d flow_limit %d quantum %d initial_quantum %d",
+			e.Limit, e.FlowLimit, e.Quantum, e.InitialQuantum)
+		if e.MaxRate.BitsPerSecond() > 0 {
+			args += " maxrate " + netlink.RateArg(e.MaxRate)
+		}
+		return qdiscCommand(e.DeviceName, e.Parent, e.Handle, args+" "+flagArg(e.Pacing, "pacing", "nopacing")), nil
+	case *events.NETEMQdiscCreatedEvent:
+		args := fmt.Sprintf("netem limit %d delay %dus %dus", e.Limit, e.Delay, e.Jitter)
+		if e.Loss > 0 {
+			args += fmt.Sprintf(" loss %g%%", e.Loss)
+		}
+		if e.Duplicate > 0 {
+			args += fmt.Sprintf(" duplicate %g%%", e.Duplicate)
+		}
+		if e.Corrupt > 0 {
+			args += fmt.Sprintf(" corrupt %g%%", e.Corrupt)
+		}
+		if e.Reorder > 0 {
+			args += fmt.Sprintf(" reorder %g%% gap %d", e.Reorder, e.Gap)
+		}
+		return qdiscCommand(e.DeviceName, e.Parent, e.Handle, args), nil
+	case *events.REDQdiscCreatedEvent:
+		args := "red " + netlink.REDArgs(entities.REDThresholds{
+			Limit: e.Limit, Min: e.Min, Max: e.Max, Avpkt: e.Avpkt, Burst: e.Burst,
+			Bandwidth: e.Bandwidth, Probability: e.Probability,
+		})
+		if e.ECN {
+			args += " ecn"
+		}
+		if e.HardDrop {
+			args += " harddrop"
+		}
+		if e.Adaptive {
+			args += " adaptive"
+		}
+		return qdiscCommand(e.DeviceName, e.Parent, e.Handle, args), nil
+	case *events.GREDQdiscCreatedEvent:
+		return gredCommand(e), nil
+	case *events.CODELQdiscCreatedEvent:
+		args := fmt.Sprintf("codel limit %d target %dus interval %dus", e.Limit, e.Target, e.Interval)
+		if e.CEThreshold > 0 {
+			args += fmt.Sprintf(" ce_threshold %dus", e.CEThreshold)
+		}
+		return qdiscCommand(e.DeviceName, e.Parent, e.Handle, args+" "+flagArg(e.ECN, "ecn", "noecn")), nil
+	case *events.ETSQdiscCreatedEvent:
+		return qdiscCommand(e.DeviceName, e.Parent, e.Handle, netlink.ETSArgs(e.Parameters())), nil
+	case *events.TAPRIOQdiscCreatedEvent:
+		return qdiscCommand(e.DeviceName, nil, e.Handle, netlink.TAPRIOArgs(e.Parameters())), nil
+	case *events.CAKEQdiscCreatedEvent:
+		return qdiscCommand(e.DeviceName, nil, e.Handle, fmt.Sprintf("cake bandwidth %s rtt %dus %s %s %s %s",
+			netlink.RateArg(e.Bandwidth), e.RTT, e.Diffserv, flagArg(e.NAT, "nat", "nonat"),
+			flagArg(e.Wash, "wash", "nowash"), e.AckFilter)), nil
+	case *events.ClassCreatedEvent:
+		return fmt.Sprintf("tc class add dev %s parent %s classid %s", e.DeviceName, e.Parent, e.Handle), nil
+	case *events.HTBClassCreatedEvent, *events.HTBClassCreatedEventWithAdvancedParameters:
+		class, _, _ := resolvedHTBClass(event)
+		return htbClassCommand("add", class), nil
+	case *events.DRRClassCreatedEvent:
+		args := "drr"
+		if e.Quantum > 0 {
+			args += fmt.Sprintf(" quantum %d", e.Quantum)
+		}
+		return classCommand(e.DeviceName, e.Parent, e.Handle, args), nil
+	case *events.QFQClassCreatedEvent:
+		args := "qfq"
+		if e.Weight > 0 {
+			args += fmt.Sprintf(" weight %d", e.Weight)
+		}
+		if e.Lmax > 0 {
+			args += fmt.Sprintf(" maxpkt %d", e.Lmax)
+		}
+		return classCommand(e.DeviceName, e.Parent, e.Handle, args), nil
+	case *events.FilterCreatedEvent:
+		return filterCommand(e)
+	}
+	return "", fmt.Errorf("no tc command for %s events", event.EventType())
+}
+
+// qdiscCommand returns the tc command adding a qdisc
+func qdiscCommand(device tc.DeviceName, parent *tc.Handle, handle tc.Handle, args string) string {
+	return fmt.Sprintf("tc qdisc add dev %s %s handle %s %s", device, netlink.ParentArg(parent), handle, args)
+}
+
+// classCommand returns the tc command adding a class
+func classCommand(device tc.DeviceName, parent tc.Handle, handle tc.Handle, args string) string {
+	return fmt.Sprintf("tc class add dev %s parent %s classid %s %s", device, parent, handle, args)
+}
+
+// htbClassCommand returns the tc command adding or changing an HTB class with the
+// parameters the netlink adapter installs
+func htbClassCommand(verb string, class *entities.HTBClass) string {
+	return fmt.Sprintf("tc class %s dev %s parent %s classid %s htb rate %s ceil %s prio %d burst %d cburst %d quantum %d",
+		verb, class.ID().Device(), class.Parent(), class.Handle(), netlink.RateArg(class.Rate()), netlink.RateArg(class.Ceil()),
+		class.HTBPrio(), class.Burst(), class.Cburst(), htbQuantum(class))
+}
+
+// gredCommand returns the tc commands setting up a GRED qdisc and each of its virtual
+// queues, joined into one shell line
+func gredCommand(e *events.GREDQdiscCreatedEvent) string {
+	setup := fmt.Sprintf("gred setup vqs %d default %d", e.Queues, e.Default)
+	if e.GRIO {
+		setup += " grio"
+	}
+	commands := []string{qdiscCommand(e.DeviceName, e.Parent, e.Handle, setup)}
+
+	for _, vq := range e.VirtualQueues {
+		command := fmt.Sprintf("tc qdisc change dev %s %s handle %s gred %s DP %d",
+			e.DeviceName, netlink.ParentArg(e.Parent), e.Handle, netlink.REDArgs(vq.REDThresholds), vq.DP)
+		if e.GRIO {
+			command += fmt.Sprintf(" prio %d", vq.Priority)
+		}
+		if e.ECN {
+			command += " ecn"
+		}
+		if e.HardDrop {
+			command += " harddrop"
+		}
+		commands = append(commands, command)
+	}
+	return strings.Join(commands, " && ")
+}
+
+// filterCommand returns the tc command adding a filter
+func filterCommand(e *events.FilterCreatedEvent) (string, error) {
+	prefix := fmt.Sprintf("tc filter add dev %s parent %s protocol %s prio %d",
+		e.DeviceName, e.Parent, netlink.ProtocolArg(e.Protocol), e.Priority)
+
+	matches := make([]entities.Match, 0, len(e.Matches))
+	for _, data := range e.Matches {
+		match, err := convertMatchData(data)
+		if err != nil {
+			return "", err
+		}
+		matches = append(matches, match)
+	}
+
+	if e.Kind == entities.FilterKindCgroup {
+		// The cgroup classifier is a BPF program the controller loads, which tc cannot
+		// express; the command is shown as a comment for reference
+		paths := make([]string, 0, len(matches))
+		for _, match := range matches {
+			paths = append(paths, match.String())
+		}
+		return fmt.Sprintf("# %s bpf classid %s (%s)", prefix, e.FlowID, strings.Join(paths, ", ")), nil
+	}
+	selector, err := netlink.FilterSelector(e.Kind, matches)
+	if err != nil {
+		return "", err
+	}
+
+	actions := make([]entities.Action, 0, len(e.Actions))
+	for _, data := range e.Actions {
+		actions = append(actions, data.Action())
+	}
+	return fmt.Sprintf("%s %s flowid %s%s", prefix, selector, e.FlowID, netlink.ActionArgs(actions, e.Protocol)), nil
+}
+
+func flagArg(set bool, on, off string) string {
+	if set {
+		return on
+	}
+	return off
+}
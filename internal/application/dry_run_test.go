@@ -0,0 +1,127 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestCreateCommand(t *testing.T) {
+	device := tc.MustNewDeviceName("eth0")
+	parent := tc.MustParseHandle("1:10")
+
+	tests := []struct {
+		name     string
+		event    events.DomainEvent
+		expected string
+	}{
+		{
+			name: "TBF qdisc",
+			event: &events.TBFQdiscCreatedEvent{
+				DeviceName: device, Handle: tc.MustParseHandle("1:"),
+				Rate: tc.MustParseBandwidth("10mbit"), Burst: 32000, Limit: 10000,
+			},
+			expected: "tc qdisc add dev eth0 root handle 1: tbf rate 10000000bit burst 32000 limit 10000",
+		},
+		{
+			name: "leaf netem qdisc",
+			event: &events.NETEMQdiscCreatedEvent{
+				DeviceName: device, Handle: tc.MustParseHandle("10:"), Parent: &parent,
+				Delay: 100000, Jitter: 10000, Loss: 1.5, Limit: 1000,
+			},
+			expected: "tc qdisc add dev eth0 parent 1:10 handle 10: netem limit 1000 delay 100000us 10000us loss 1.5%",
+		},
+		{
+			name: "leaf ets qdisc",
+			event: &events.ETSQdiscCreatedEvent{
+				DeviceName: device, Handle: tc.MustParseHandle("10:"), Parent: &parent,
+				Bands: 3, Strict: 1, Quanta: []uint32{1500, 3000}, Priomap: []uint8{0, 1, 2},
+			},
+			expected: "tc qdisc add dev eth0 parent 1:10 handle 10: ets bands 3 strict 1 quanta 1500 3000 priomap 0 1 2",
+		},
+		{
+			name: "taprio qdisc",
+			event: &events.TAPRIOQdiscCreatedEvent{
+				DeviceName: device, Handle: tc.MustParseHandle("100:"),
+				NumTC: 2, Priomap: []uint8{0, 1},
+				Queues:   []entities.TAPRIOQueueRange{{Count: 1, Offset: 0}, {Count: 1, Offset: 1}},
+				BaseTime: 1000, Clock: entities.TAPRIOClockTAI,
+				Schedule: []entities.TAPRIOScheduleEntry{
+					{Command: entities.TAPRIOSetGates, GateMask: 0x1, Interval: 300000},
+					{Command: entities.TAPRIOSetGates, GateMask: 0x2, Interval: 700000},
+				},
+			},
+			expected: "tc qdisc add dev eth0 root handle 100: taprio num_tc 2 map 0 1 queues 1@0 1@1 base-time 1000" +
+				" sched-entry S 01 300000 sched-entry S 02 700000 clockid CLOCK_TAI",
+		},
+		{
+			name: "drr qdisc",
+			event: &events.QdiscCreatedEvent{
+				DeviceName: device, Handle: tc.MustParseHandle("1:"), QdiscType: entities.QdiscTypeDRR,
+			},
+			expected: "tc qdisc add dev eth0 root handle 1: drr",
+		},
+		{
+			name: "drr class",
+			event: &events.DRRClassCreatedEvent{
+				DeviceName: device, Parent: tc.MustParseHandle("1:"), Handle: tc.MustParseHandle("1:2"), Quantum: 4500,
+			},
+			expected: "tc class add dev eth0 parent 1: classid 1:2 drr quantum 4500",
+		},
+		{
+			name: "qfq class",
+			event: &events.QFQClassCreatedEvent{
+				DeviceName: device, Parent: tc.MustParseHandle("1:"), Handle: tc.MustParseHandle("1:2"), Weight: 4, Lmax: 9000,
+			},
+			expected: "tc class add dev eth0 parent 1: classid 1:2 qfq weight 4 maxpkt 9000",
+		},
+		{
+			name: "flower filter with actions",
+			event: &events.FilterCreatedEvent{
+				DeviceName: device, Parent: tc.MustParseHandle("1:"), Priority: 110,
+				Kind: entities.FilterKindFlower, FlowID: parent, Protocol: entities.ProtocolIP,
+				Matches: []events.MatchData{
+					{Type: entities.MatchTypeProtocol, Value: "ip protocol 17 0xff"},
+					{Type: entities.MatchTypePortRange, Value: "dport range 5000-5100"},
+				},
+				Actions: []events.ActionData{
+					{Type: entities.ActionTypeDSCP, DSCP: 46},
+					{Type: entities.ActionTypePolice, Rate: tc.MustParseBandwidth("1mbit"), Burst: 10000},
+				},
+			},
+			expected: "tc filter add dev eth0 parent 1: protocol ip prio 110 flower ip_proto udp dst_port 5000-5100 flowid 1:10" +
+				" action pedit ex munge ip dsfield set 0xb8 retain 0xfc pipe action csum ip" +
+				" action police rate 1000000bit burst 10000 conform-exceed drop/pipe",
+		},
+		{
+			name: "u32 filter with TCP flags",
+			event: &events.FilterCreatedEvent{
+				DeviceName: device, Parent: tc.MustParseHandle("1:"), Priority: 100,
+				FlowID: parent, Protocol: entities.ProtocolIP,
+				Matches: []events.MatchData{{Type: entities.MatchTypeTCPFlags, Value: "tcp flags 0x02 0x12"}},
+			},
+			expected: "tc filter add dev eth0 parent 1: protocol ip prio 100 u32 match u8 0x02 0x12 at nexthdr+13 flowid 1:10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, err := createCommand(tt.event)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, command)
+		})
+	}
+
+	t.Run("flower filter matching a mark", func(t *testing.T) {
+		_, err := createCommand(&events.FilterCreatedEvent{
+			DeviceName: device, Kind: entities.FilterKindFlower,
+			Matches: []events.MatchData{{Type: entities.MatchTypeMark, Value: "mark 0x1 0xffffffff"}},
+		})
+		assert.Error(t, err)
+	})
+}
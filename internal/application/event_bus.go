@@ -2,6 +2,7 @@ package application
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -132,7 +133,7 @@ func (eb *EventBus) Publish(ctx context.Context, eventType string, event interfa
 	}
 
 	if len(errs) > 0 {
-		return fmt.Errorf("event handling failed with %d errors: %v", len(errs), errs)
+		return fmt.Errorf("event handling failed with %d errors: %w", len(errs), errors.Join(errs...))
 	}
 
 	eb.logger.Debug("Event published successfully", logging.String("type", eventType))
@@ -19,17 +19,39 @@ func (s *TrafficControlService) handleQdiscCreated(ctx context.Context, event in
 	var handle tc.Handle
 	var qdiscType entities.QdiscType
 	var defaultClass string
+	var parent *tc.Handle
 
 	switch e := event.(type) {
 	case *events.QdiscCreatedEvent:
 		device = e.DeviceName
 		handle = e.Handle
 		qdiscType = e.QdiscType
+		parent = e.Parent
 	case *events.HTBQdiscCreatedEvent:
 		device = e.DeviceName
 		handle = e.Handle
 		qdiscType = entities.QdiscTypeHTB
 		defaultClass = e.DefaultClass.String()
+	case *events.FQCODELQdiscCreatedEvent:
+		return s.applyFQCODELQdisc(ctx, e)
+	case *events.SFQQdiscCreatedEvent:
+		return s.applySFQQdisc(ctx, e)
+	case *events.FQQdiscCreatedEvent:
+		return s.applyFQQdisc(ctx, e)
+	case *events.NETEMQdiscCreatedEvent:
+		return s.applyNETEMQdisc(ctx, e)
+	case *events.REDQdiscCreatedEvent:
+		return s.applyREDQdisc(ctx, e)
+	case *events.GREDQdiscCreatedEvent:
+		return s.applyGREDQdisc(ctx, e)
+	case *events.CODELQdiscCreatedEvent:
+		return s.applyCODELQdisc(ctx, e)
+	case *events.ETSQdiscCreatedEvent:
+		return s.applyETSQdisc(ctx, e)
+	case *events.TAPRIOQdiscCreatedEvent:
+		return s.applyTAPRIOQdisc(ctx, e)
+	case *events.CAKEQdiscCreatedEvent:
+		return s.applyCAKEQdisc(ctx, e)
 	default:
 		// Not a qdisc event we handle
 		return nil
@@ -50,7 +72,14 @@ func (s *TrafficControlService) handleQdiscCreated(ctx context.Context, event in
 			return fmt.Errorf("invalid default class handle: %w", err)
 		}
 		qdisc := entities.NewHTBQdisc(device, handle, defaultHandle)
-		return s.netlinkAdapter.AddQdisc(ctx, qdisc.Qdisc)
+		return s.journal.AddQdisc(ctx, qdisc.Qdisc)
+	case entities.QdiscTypeDRR, entities.QdiscTypeQFQ:
+		// The qdiscs have no options; their classes carry the quanta and weights
+		qdisc := entities.NewQdisc(device, handle, qdiscType)
+		if parent != nil {
+			qdisc.SetParent(*parent)
+		}
+		return s.journal.AddQdisc(ctx, qdisc)
 	case entities.QdiscTypeTBF:
 		// TBF needs rate from event - skip for now
 		s.logger.Warn("TBF qdisc netlink application not implemented")
@@ -63,6 +92,221 @@ func (s *TrafficControlService) handleQdiscCreated(ctx context.Context, event in
 	}
 }
 
+// applyFQCODELQdisc applies a FQ_CODEL qdisc (root or leaf) to netlink
+func (s *TrafficControlService) applyFQCODELQdisc(ctx context.Context, e *events.FQCODELQdiscCreatedEvent) error {
+	s.logger.Info("Applying FQ_CODEL qdisc to netlink",
+		logging.String("device", e.DeviceName.String()),
+		logging.String("handle", e.Handle.String()),
+		logging.Int("target_us", int(e.Target)),
+	)
+
+	qdisc := entities.NewQdisc(e.DeviceName, e.Handle, entities.QdiscTypeFQCODEL)
+	if e.Parent != nil {
+		qdisc.SetParent(*e.Parent)
+	}
+	qdisc.SetParameter("limit", e.Limit)
+	qdisc.SetParameter("flows", e.Flows)
+	qdisc.SetParameter("target", e.Target)
+	qdisc.SetParameter("interval", e.Interval)
+	qdisc.SetParameter("quantum", e.Quantum)
+	qdisc.SetParameter("ecn", e.ECN)
+
+	return s.journal.AddQdisc(ctx, qdisc)
+}
+
+// applySFQQdisc applies an SFQ qdisc (root or leaf) to netlink
+func (s *TrafficControlService) applySFQQdisc(ctx context.Context, e *events.SFQQdiscCreatedEvent) error {
+	s.logger.Info("Applying SFQ qdisc to netlink",
+		logging.String("device", e.DeviceName.String()),
+		logging.String("handle", e.Handle.String()),
+		logging.Int("perturb_s", int(e.Perturb)),
+	)
+
+	qdisc := entities.NewQdisc(e.DeviceName, e.Handle, entities.QdiscTypeSFQ)
+	if e.Parent != nil {
+		qdisc.SetParent(*e.Parent)
+	}
+	qdisc.SetParameter("perturb", e.Perturb)
+	qdisc.SetParameter("quantum", e.Quantum)
+	qdisc.SetParameter("limit", e.Limit)
+
+	return s.journal.AddQdisc(ctx, qdisc)
+}
+
+// applyFQQdisc applies an FQ qdisc (root or leaf) to netlink
+func (s *TrafficControlService) applyFQQdisc(ctx context.Context, e *events.FQQdiscCreatedEvent) error {
+	s.logger.Info("Applying FQ qdisc to netlink",
+		logging.String("device", e.DeviceName.String()),
+		logging.String("handle", e.Handle.String()),
+		logging.String("max_rate", e.MaxRate.String()),
+	)
+
+	qdisc := entities.NewQdisc(e.DeviceName, e.Handle, entities.QdiscTypeFQ)
+	if e.Parent != nil {
+		qdisc.SetParent(*e.Parent)
+	}
+	qdisc.SetParameter("limit", e.Limit)
+	qdisc.SetParameter("flow_limit", e.FlowLimit)
+	qdisc.SetParameter("quantum", e.Quantum)
+	qdisc.SetParameter("initial_quantum", e.InitialQuantum)
+	qdisc.SetParameter("max_rate", e.MaxRate)
+	qdisc.SetParameter("pacing", e.Pacing)
+
+	return s.journal.AddQdisc(ctx, qdisc)
+}
+
+// applyNETEMQdisc applies a NETEM qdisc (root or leaf) to netlink
+func (s *TrafficControlService) applyNETEMQdisc(ctx context.Context, e *events.NETEMQdiscCreatedEvent) error {
+	s.logger.Info("Applying NETEM qdisc to netlink",
+		logging.String("device", e.DeviceName.String()),
+		logging.String("handle", e.Handle.String()),
+		logging.Int("delay_us", int(e.Delay)),
+		logging.Float64("loss_percent", float64(e.Loss)),
+	)
+
+	qdisc := entities.NewQdisc(e.DeviceName, e.Handle, entities.QdiscTypeNETEM)
+	if e.Parent != nil {
+		qdisc.SetParent(*e.Parent)
+	}
+	qdisc.SetParameter("delay", e.Delay)
+	qdisc.SetParameter("jitter", e.Jitter)
+	qdisc.SetParameter("loss", e.Loss)
+	qdisc.SetParameter("duplicate", e.Duplicate)
+	qdisc.SetParameter("corrupt", e.Corrupt)
+	qdisc.SetParameter("reorder", e.Reorder)
+	qdisc.SetParameter("gap", e.Gap)
+	qdisc.SetParameter("limit", e.Limit)
+
+	return s.journal.AddQdisc(ctx, qdisc)
+}
+
+// applyREDQdisc applies a RED qdisc (root or leaf) to netlink
+func (s *TrafficControlService) applyREDQdisc(ctx context.Context, e *events.REDQdiscCreatedEvent) error {
+	s.logger.Info("Applying RED qdisc to netlink",
+		logging.String("device", e.DeviceName.String()),
+		logging.String("handle", e.Handle.String()),
+		logging.Int("min_bytes", int(e.Min)),
+		logging.Int("max_bytes", int(e.Max)),
+	)
+
+	qdisc := entities.NewQdisc(e.DeviceName, e.Handle, entities.QdiscTypeRED)
+	if e.Parent != nil {
+		qdisc.SetParent(*e.Parent)
+	}
+	qdisc.SetParameter("red", e.Parameters())
+
+	return s.journal.AddQdisc(ctx, qdisc)
+}
+
+// applyGREDQdisc applies a GRED qdisc (root or leaf) to netlink
+func (s *TrafficControlService) applyGREDQdisc(ctx context.Context, e *events.GREDQdiscCreatedEvent) error {
+	s.logger.Info("Applying GRED qdisc to netlink",
+		logging.String("device", e.DeviceName.String()),
+		logging.String("handle", e.Handle.String()),
+		logging.Int("queues", int(e.Queues)),
+		logging.Int("virtual_queues", len(e.VirtualQueues)),
+	)
+
+	qdisc := entities.NewQdisc(e.DeviceName, e.Handle, entities.QdiscTypeGRED)
+	if e.Parent != nil {
+		qdisc.SetParent(*e.Parent)
+	}
+	qdisc.SetParameter("gred", e.Parameters())
+
+	return s.journal.AddQdisc(ctx, qdisc)
+}
+
+// applyCODELQdisc applies a CoDel qdisc (root or leaf) to netlink
+func (s *TrafficControlService) applyCODELQdisc(ctx context.Context, e *events.CODELQdiscCreatedEvent) error {
+	s.logger.Info("Applying CoDel qdisc to netlink",
+		logging.String("device", e.DeviceName.String()),
+		logging.String("handle", e.Handle.String()),
+		logging.Int("target_us", int(e.Target)),
+		logging.Int("interval_us", int(e.Interval)),
+	)
+
+	qdisc := entities.NewQdisc(e.DeviceName, e.Handle, entities.QdiscTypeCODEL)
+	if e.Parent != nil {
+		qdisc.SetParent(*e.Parent)
+	}
+	qdisc.SetParameter("limit", e.Limit)
+	qdisc.SetParameter("target", e.Target)
+	qdisc.SetParameter("interval", e.Interval)
+	qdisc.SetParameter("ce_threshold", e.CEThreshold)
+	qdisc.SetParameter("ecn", e.ECN)
+
+	return s.journal.AddQdisc(ctx, qdisc)
+}
+
+// applyETSQdisc applies an ETS qdisc (root or leaf) to netlink
+func (s *TrafficControlService) applyETSQdisc(ctx context.Context, e *events.ETSQdiscCreatedEvent) error {
+	s.logger.Info("Applying ETS qdisc to netlink",
+		logging.String("device", e.DeviceName.String()),
+		logging.String("handle", e.Handle.String()),
+		logging.Int("bands", int(e.Bands)),
+		logging.Int("strict", int(e.Strict)),
+	)
+
+	qdisc := entities.NewQdisc(e.DeviceName, e.Handle, entities.QdiscTypeETS)
+	if e.Parent != nil {
+		qdisc.SetParent(*e.Parent)
+	}
+	qdisc.SetParameter("ets", e.Parameters())
+
+	return s.journal.AddQdisc(ctx, qdisc)
+}
+
+// applyTAPRIOQdisc applies a taprio qdisc to netlink
+func (s *TrafficControlService) applyTAPRIOQdisc(ctx context.Context, e *events.TAPRIOQdiscCreatedEvent) error {
+	s.logger.Info("Applying taprio qdisc to netlink",
+		logging.String("device", e.DeviceName.String()),
+		logging.String("handle", e.Handle.String()),
+		logging.Int("traffic_classes", int(e.NumTC)),
+		logging.Int("schedule_entries", len(e.Schedule)),
+	)
+
+	qdisc := entities.NewQdisc(e.DeviceName, e.Handle, entities.QdiscTypeTAPRIO)
+	qdisc.SetParameter("taprio", e.Parameters())
+
+	return s.journal.AddQdisc(ctx, qdisc)
+}
+
+// applyCAKEQdisc applies a CAKE qdisc to netlink
+func (s *TrafficControlService) applyCAKEQdisc(ctx context.Context, e *events.CAKEQdiscCreatedEvent) error {
+	s.logger.Info("Applying CAKE qdisc to netlink",
+		logging.String("device", e.DeviceName.String()),
+		logging.String("handle", e.Handle.String()),
+		logging.String("bandwidth", e.Bandwidth.String()),
+		logging.String("diffserv", e.Diffserv.String()),
+	)
+
+	qdisc := entities.NewQdisc(e.DeviceName, e.Handle, entities.QdiscTypeCAKE)
+	qdisc.SetParameter("bandwidth", e.Bandwidth)
+	qdisc.SetParameter("rtt", e.RTT)
+	qdisc.SetParameter("diffserv", uint32(e.Diffserv))
+	qdisc.SetParameter("nat", e.NAT)
+	qdisc.SetParameter("wash", e.Wash)
+	qdisc.SetParameter("ack_filter", uint32(e.AckFilter))
+
+	return s.journal.AddQdisc(ctx, qdisc)
+}
+
+// restoreObject re-creates an object deleted during a transaction from its creation event,
+// when the transaction is rolled back
+func (s *TrafficControlService) restoreObject(ctx context.Context, created interface{}) error {
+	switch created.(type) {
+	case *events.FilterCreatedEvent:
+		return s.handleFilterCreated(ctx, created)
+	case *events.HTBClassCreatedEvent, *events.HTBClassCreatedEventWithAdvancedParameters,
+		*events.DRRClassCreatedEvent, *events.QFQClassCreatedEvent:
+		return s.handleClassCreated(ctx, created)
+	case *events.ClassCreatedEvent:
+		return fmt.Errorf("the class has no parameters recorded to re-create it from")
+	default:
+		return s.handleQdiscCreated(ctx, created)
+	}
+}
+
 // handleClassCreated handles ClassCreated events and applies them to netlink
 func (s *TrafficControlService) handleClassCreated(ctx context.Context, event interface{}) error {
 	switch e := event.(type) {
@@ -83,35 +327,7 @@ func (s *TrafficControlService) handleClassCreated(ctx context.Context, event in
 			logging.String("ceil", e.Ceil.String()),
 		)
 
-		// Create HTB class with proper parameters
-		// Note: HTB events don't have priority in the event, using default priority
-		priority := entities.Priority(4) // Normal priority
-		class := entities.NewHTBClass(e.DeviceName, e.Handle, e.Parent, e.Name, priority)
-
-		// Set rate and ceil bandwidth
-		class.SetRate(e.Rate)
-
-		// If ceil is 0 or not set, default to rate (HTB requirement)
-		if e.Ceil.BitsPerSecond() == 0 {
-			class.SetCeil(e.Rate)
-		} else {
-			class.SetCeil(e.Ceil)
-		}
-
-		// Set burst values if provided, otherwise calculate them
-		if e.Burst > 0 {
-			class.SetBurst(e.Burst)
-		} else {
-			class.SetBurst(class.CalculateBurst())
-		}
-
-		if e.Cburst > 0 {
-			class.SetCburst(e.Cburst)
-		} else {
-			class.SetCburst(class.CalculateCburst())
-		}
-
-		return s.netlinkAdapter.AddClass(ctx, class)
+		return s.journal.AddClass(ctx, htbClassFromEvent(e))
 
 	case *events.HTBClassCreatedEventWithAdvancedParameters:
 		s.logger.Info("Applying HTB class with comprehensive parameters to netlink",
@@ -123,58 +339,33 @@ func (s *TrafficControlService) handleClassCreated(ctx context.Context, event in
 			logging.Int("priority", int(e.Priority)),
 		)
 
-		// Create HTB class with proper priority from event
-		class := entities.NewHTBClass(e.DeviceName, e.Handle, e.Parent, e.Name, e.Priority)
-
-		// Set rate and ceil bandwidth
-		class.SetRate(e.Rate)
-
-		// If ceil is 0 or not set, default to rate (HTB requirement)
-		if e.Ceil.BitsPerSecond() == 0 {
-			class.SetCeil(e.Rate)
-		} else {
-			class.SetCeil(e.Ceil)
-		}
-
-		// Set WP2 parameters from event
-		if e.Burst > 0 {
-			class.SetBurst(e.Burst)
-		}
-		if e.Cburst > 0 {
-			class.SetCburst(e.Cburst)
-		}
+		return s.journal.AddClass(ctx, advancedHTBClassFromEvent(e))
 
-		// Set enhanced parameters from event
-		if e.Quantum > 0 {
-			class.SetQuantum(e.Quantum)
-		}
-		if e.Overhead > 0 {
-			class.SetOverhead(e.Overhead)
-		}
-		if e.MPU > 0 {
-			class.SetMPU(e.MPU)
-		}
-		if e.MTU > 0 {
-			class.SetMTU(e.MTU)
-		}
-		if e.HTBPrio > 0 {
-			class.SetHTBPrio(e.HTBPrio)
-		}
+	case *events.DRRClassCreatedEvent:
+		s.logger.Info("Applying DRR class to netlink",
+			logging.String("device", e.DeviceName.String()),
+			logging.String("parent", e.Parent.String()),
+			logging.String("handle", e.Handle.String()),
+			logging.Int("quantum", int(e.Quantum)),
+		)
 
-		// Apply default parameters if requested
-		if e.UseDefaults {
-			class.ApplyDefaultParameters()
-		}
+		class := entities.NewDRRClass(e.DeviceName, e.Handle, e.Parent, e.Name)
+		class.SetQuantum(e.Quantum)
+		return s.journal.AddClass(ctx, class)
 
-		// Calculate burst and cburst using enhanced calculation if not already set
-		if class.Burst() == 0 {
-			class.SetBurst(class.CalculateEnhancedBurst())
-		}
-		if class.Cburst() == 0 {
-			class.SetCburst(class.CalculateEnhancedCburst())
-		}
+	case *events.QFQClassCreatedEvent:
+		s.logger.Info("Applying QFQ class to netlink",
+			logging.String("device", e.DeviceName.String()),
+			logging.String("parent", e.Parent.String()),
+			logging.String("handle", e.Handle.String()),
+			logging.Int("weight", int(e.Weight)),
+			logging.Int("lmax", int(e.Lmax)),
+		)
 
-		return s.netlinkAdapter.AddClass(ctx, class)
+		class := entities.NewQFQClass(e.DeviceName, e.Handle, e.Parent, e.Name)
+		class.SetWeight(e.Weight)
+		class.SetLmax(e.Lmax)
+		return s.journal.AddClass(ctx, class)
 
 	default:
 		// Not a class event we handle
@@ -201,6 +392,8 @@ func (s *TrafficControlService) handleFilterCreated(ctx context.Context, event i
 	// Create filter entity directly from event data (no string parsing needed)
 	filter := entities.NewFilter(e.DeviceName, e.Parent, e.Priority, e.Handle)
 
+	filter.SetKind(e.Kind)
+
 	// Set flow ID
 	filter.SetFlowID(e.FlowID)
 
@@ -234,8 +427,123 @@ func (s *TrafficControlService) handleFilterCreated(ctx context.Context, event i
 		filter.AddMatch(match)
 	}
 
+	for _, actionData := range e.Actions {
+		filter.AddAction(actionData.Action())
+	}
+
 	s.logger.Info("Adding filter via netlink adapter")
-	return s.netlinkAdapter.AddFilter(ctx, filter)
+	return s.journal.AddFilter(ctx, filter)
+}
+
+// htbClassFromEvent builds the HTB class an HTBClassCreatedEvent installs, calculating the
+// bursts the event leaves out
+func htbClassFromEvent(e *events.HTBClassCreatedEvent) *entities.HTBClass {
+	// Create HTB class with proper parameters
+	// Note: HTB events don't have priority in the event, using default priority
+	priority := entities.Priority(4) // Normal priority
+	class := entities.NewHTBClass(e.DeviceName, e.Handle, e.Parent, e.Name, priority)
+
+	// Set rate and ceil bandwidth
+	class.SetRate(e.Rate)
+
+	// If ceil is 0 or not set, default to rate (HTB requirement)
+	if e.Ceil.BitsPerSecond() == 0 {
+		class.SetCeil(e.Rate)
+	} else {
+		class.SetCeil(e.Ceil)
+	}
+
+	// Set burst values if provided, otherwise calculate them
+	if e.Burst > 0 {
+		class.SetBurst(e.Burst)
+	} else {
+		class.SetBurst(class.CalculateBurst())
+	}
+
+	if e.Cburst > 0 {
+		class.SetCburst(e.Cburst)
+	} else {
+		class.SetCburst(class.CalculateCburst())
+	}
+
+	return class
+}
+
+// handleClassChanged handles HTBClassChanged events by changing the class in place
+func (s *TrafficControlService) handleClassChanged(ctx context.Context, event interface{}) error {
+	e, ok := event.(*events.HTBClassChangedEvent)
+	if !ok {
+		return fmt.Errorf("unexpected event type for class change: %T", event)
+	}
+
+	s.logger.Info("Changing HTB class in place",
+		logging.String("device", e.DeviceName.String()),
+		logging.String("handle", e.Handle.String()),
+		logging.String("rate", e.Current.Rate.String()),
+		logging.String("ceil", e.Current.Ceil.String()),
+	)
+
+	return s.journal.ChangeClassFrom(ctx, advancedHTBClassFromEvent(e.Previous), advancedHTBClassFromEvent(e.Current))
+}
+
+// advancedHTBClassFromEvent builds the HTB class an HTBClassCreatedEventWithAdvancedParameters
+// installs, filling in the defaults for the parameters the event leaves out
+func advancedHTBClassFromEvent(e *events.HTBClassCreatedEventWithAdvancedParameters) *entities.HTBClass {
+	// Create HTB class with proper priority from event
+	class := entities.NewHTBClass(e.DeviceName, e.Handle, e.Parent, e.Name, e.Priority)
+
+	// Set rate and ceil bandwidth
+	class.SetRate(e.Rate)
+
+	// If ceil is 0 or not set, default to rate (HTB requirement)
+	if e.Ceil.BitsPerSecond() == 0 {
+		class.SetCeil(e.Rate)
+	} else {
+		class.SetCeil(e.Ceil)
+	}
+
+	// Set WP2 parameters from event
+	if e.Burst > 0 {
+		class.SetBurst(e.Burst)
+	}
+	if e.Cburst > 0 {
+		class.SetCburst(e.Cburst)
+	}
+
+	// Set enhanced parameters from event
+	if e.Quantum > 0 {
+		class.SetQuantum(e.Quantum)
+	}
+	if e.Overhead > 0 {
+		class.SetOverhead(e.Overhead)
+	}
+	if e.MPU > 0 {
+		class.SetMPU(e.MPU)
+	}
+	if e.MTU > 0 {
+		class.SetMTU(e.MTU)
+	}
+	if e.HTBPrio > 0 {
+		class.SetHTBPrio(e.HTBPrio)
+	}
+	if e.Profile != "" {
+		class.SetTuningProfile(e.Profile)
+	}
+
+	// Apply default parameters if requested
+	if e.UseDefaults {
+		class.ApplyDefaultParameters()
+	}
+
+	// Calculate burst and cburst using enhanced calculation if not already set
+	if class.Burst() == 0 {
+		class.SetBurst(class.CalculateEnhancedBurst())
+	}
+	if class.Cburst() == 0 {
+		class.SetCburst(class.CalculateEnhancedCburst())
+	}
+
+	return class
 }
 
 // convertMatchData converts event match data back to entities.Match objects
@@ -283,6 +591,14 @@ func convertMatchData(matchData events.MatchData) (entities.Match, error) {
 			return nil, fmt.Errorf("invalid mark match value: %w", err)
 		}
 		return entities.NewMarkMatch(mark), nil
+	case entities.MatchTypeTOS, entities.MatchTypeDSCP, entities.MatchTypeTCPFlags,
+		entities.MatchTypePacketLength, entities.MatchTypeRaw:
+		return entities.ParseHeaderMatch(matchData.Type, matchData.Value)
+	case entities.MatchTypeMACSource, entities.MatchTypeMACDestination, entities.MatchTypeVLANID,
+		entities.MatchTypeVLANPriority, entities.MatchTypePortRange:
+		return entities.ParseFlowerMatch(matchData.Type, matchData.Value)
+	case entities.MatchTypeCgroup:
+		return entities.ParseCgroupMatch(matchData.Value)
 	default:
 		return nil, fmt.Errorf("unsupported match type: %v", matchData.Type)
 	}
@@ -0,0 +1,167 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/rng999/traffic-control-go/internal/domain/aggregates"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// defaultAdoptedClassPriority is used for DRR and QFQ classes read from the kernel, which
+// does not report the priority the class was created with
+const defaultAdoptedClassPriority = entities.Priority(4)
+
+// ReadKernelState reconstructs a traffic control aggregate from the qdiscs, classes and
+// filters currently installed on a device. The aggregate is built in memory only: nothing
+// is saved to the event store and no netlink changes are made. HTB qdiscs and classes keep
+// their default class and rates; other qdiscs are read without their parameters.
+func (s *TrafficControlService) ReadKernelState(ctx context.Context, device string) (*aggregates.TrafficControlAggregate, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+
+	qdiscsResult := s.netlinkAdapter.GetQdiscs(deviceName)
+	if qdiscsResult.IsFailure() {
+		return nil, fmt.Errorf("failed to read qdiscs: %w", qdiscsResult.Error())
+	}
+	classesResult := s.netlinkAdapter.GetClasses(deviceName)
+	if classesResult.IsFailure() {
+		return nil, fmt.Errorf("failed to read classes: %w", classesResult.Error())
+	}
+	filtersResult := s.netlinkAdapter.GetFilters(deviceName)
+	if filtersResult.IsFailure() {
+		return nil, fmt.Errorf("failed to read filters: %w", filtersResult.Error())
+	}
+
+	qdiscs := qdiscsResult.Value()
+	classes := classesResult.Value()
+	filters := filtersResult.Value()
+
+	// Kernel dumps are unordered; sort for a deterministic event history
+	sort.Slice(qdiscs, func(i, j int) bool { return qdiscs[i].Handle.ToUint32() < qdiscs[j].Handle.ToUint32() })
+	sort.Slice(classes, func(i, j int) bool { return classes[i].Handle.ToUint32() < classes[j].Handle.ToUint32() })
+	sort.SliceStable(filters, func(i, j int) bool { return filters[i].Priority < filters[j].Priority })
+
+	aggregateID := aggregates.NewTrafficControlAggregate(deviceName).GetID()
+	history := make([]events.DomainEvent, 0, len(qdiscs)+len(classes)+len(filters))
+
+	for _, qdisc := range qdiscs {
+		if qdisc.Type == entities.QdiscTypeHTB && qdisc.Parent == nil {
+			history = append(history, events.NewHTBQdiscCreatedEvent(aggregateID, len(history)+1, deviceName,
+				qdisc.Handle, qdisc.DefaultClass))
+			continue
+		}
+		history = append(history, events.NewQdiscCreatedEvent(aggregateID, len(history)+1, deviceName,
+			qdisc.Handle, qdisc.Type, qdisc.Parent))
+	}
+
+	for _, class := range classes {
+		if class.Type == entities.QdiscTypeHTB {
+			history = append(history, events.NewHTBClassCreatedEvent(aggregateID, len(history)+1, deviceName,
+				class.Handle, class.Parent, "", class.Rate, class.Ceil))
+			continue
+		}
+		history = append(history, events.NewClassCreatedEvent(aggregateID, len(history)+1, deviceName,
+			class.Handle, class.Parent, "", defaultAdoptedClassPriority))
+	}
+
+	for _, filter := range filters {
+		event := events.NewFilterCreatedEvent(aggregateID, len(history)+1, deviceName,
+			filter.Parent, filter.Priority, filter.Handle, filter.FlowID)
+		event.Kind = filter.Kind
+		event.Protocol = filter.Protocol
+		for _, match := range filter.Matches {
+			event.AddMatch(match.Type, fmt.Sprint(match.Value))
+		}
+		for _, action := range filter.Actions {
+			event.AddAction(action)
+		}
+		history = append(history, event)
+	}
+
+	s.logger.Info("Read kernel traffic control state",
+		logging.String("device", device),
+		logging.Int("qdiscs", len(qdiscs)),
+		logging.Int("classes", len(classes)),
+		logging.Int("filters", len(filters)),
+	)
+
+	return aggregates.FromEvents(deviceName, history), nil
+}
+
+// sameKernelDefinition reports whether an object read back from the kernel matches the
+// desired definition in everything the kernel reports: the type, parent and default class
+// of qdiscs, the rates of HTB classes, and the classification and actions of filters. Qdiscs
+// created with parameters and DRR and QFQ classes never match, the kernel state not telling
+// whether their parameters are the desired ones.
+func sameKernelDefinition(kernel, desired events.DomainEvent) bool {
+	switch k := kernel.(type) {
+	case *events.HTBQdiscCreatedEvent:
+		d, ok := desired.(*events.HTBQdiscCreatedEvent)
+		return ok && d.Handle == k.Handle && d.DefaultClass == k.DefaultClass
+	case *events.QdiscCreatedEvent:
+		d, ok := desired.(*events.QdiscCreatedEvent)
+		return ok && d.Handle == k.Handle && d.QdiscType == k.QdiscType && reflect.DeepEqual(d.Parent, k.Parent)
+	case *events.HTBClassCreatedEvent:
+		// Compared with the class the desired event installs, which fills in the ceil
+		var class *entities.HTBClass
+		switch d := desired.(type) {
+		case *events.HTBClassCreatedEvent:
+			class = htbClassFromEvent(d)
+		case *events.HTBClassCreatedEventWithAdvancedParameters:
+			class = advancedHTBClassFromEvent(d)
+		default:
+			return false
+		}
+		// The kernel keeps the rates in bytes per second
+		return class.Handle() == k.Handle && class.Parent() == k.Parent &&
+			class.Rate().BitsPerSecond()/8 == k.Rate.BitsPerSecond()/8 &&
+			class.Ceil().BitsPerSecond()/8 == k.Ceil.BitsPerSecond()/8
+	case *events.FilterCreatedEvent:
+		d, ok := desired.(*events.FilterCreatedEvent)
+		if !ok {
+			return false
+		}
+		// The kernel assigns filter handles
+		read := *k
+		read.Handle = d.Handle
+		return sameDefinition(&read, d)
+	}
+	return false
+}
+
+// ReadCurrentConfiguration returns the configuration currently installed in the kernel
+func (s *TrafficControlService) ReadCurrentConfiguration(ctx context.Context, device string) (*qmodels.ConfigurationView, error) {
+	aggregate, err := s.ReadKernelState(ctx, device)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &qmodels.ConfigurationView{
+		DeviceName: aggregate.DeviceName().String(),
+		Qdiscs:     make([]qmodels.QdiscView, 0),
+		Classes:    make([]qmodels.ClassView, 0),
+		Filters:    make([]qmodels.FilterView, 0),
+		Version:    aggregate.Version(),
+	}
+
+	for _, qdisc := range aggregate.GetQdiscs() {
+		view.Qdiscs = append(view.Qdiscs, qmodels.NewQdiscView(aggregate.DeviceName(), qdisc))
+	}
+	for _, class := range aggregate.GetClasses() {
+		view.Classes = append(view.Classes, qmodels.NewClassView(aggregate.DeviceName(), class))
+	}
+	for _, filter := range aggregate.GetFilters() {
+		view.Filters = append(view.Filters, qmodels.NewFilterView(aggregate.DeviceName(), filter))
+	}
+
+	return view, nil
+}
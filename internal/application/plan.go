@@ -0,0 +1,262 @@
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/internal/domain/aggregates"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// PlanView resolves the configuration recorded for a device into the values installed for
+// it: the handles and priorities assigned, and the HTB bursts and quantum computed when
+// they are not given. It is meant for a planning service, whose records are the plan.
+func (s *TrafficControlService) PlanView(ctx context.Context, device string) (*qmodels.PlanView, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+
+	aggregate := aggregates.NewTrafficControlAggregate(deviceName)
+	if err := s.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return nil, fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	view := &qmodels.PlanView{
+		DeviceName: device,
+		Qdiscs:     make([]qmodels.PlannedQdiscView, 0),
+		Classes:    make([]qmodels.PlannedClassView, 0),
+		Filters:    make([]qmodels.PlannedFilterView, 0),
+	}
+
+	// The snapshot holds the creation events of the objects that exist, in creation order
+	_, created := aggregate.Snapshot()
+	classNames := make(map[tc.Handle]string)
+	var flowIDs []tc.Handle
+	for _, event := range created {
+		switch e := event.(type) {
+		case *events.FilterCreatedEvent:
+			filter, err := plannedFilter(e)
+			if err != nil {
+				return nil, err
+			}
+			view.Filters = append(view.Filters, filter)
+			flowIDs = append(flowIDs, e.FlowID)
+		case *events.ClassCreatedEvent:
+			classNames[e.Handle] = e.Name
+			view.Classes = append(view.Classes, qmodels.PlannedClassView{
+				Name:     e.Name,
+				Handle:   e.Handle.String(),
+				Parent:   e.Parent.String(),
+				Priority: int(e.Priority),
+			})
+		case *events.DRRClassCreatedEvent:
+			classNames[e.Handle] = e.Name
+			view.Classes = append(view.Classes, qmodels.PlannedClassView{
+				Name:    e.Name,
+				Handle:  e.Handle.String(),
+				Parent:  e.Parent.String(),
+				Quantum: e.Quantum,
+			})
+		case *events.QFQClassCreatedEvent:
+			classNames[e.Handle] = e.Name
+			view.Classes = append(view.Classes, qmodels.PlannedClassView{
+				Name:   e.Name,
+				Handle: e.Handle.String(),
+				Parent: e.Parent.String(),
+				Weight: e.Weight,
+				Lmax:   e.Lmax,
+			})
+		default:
+			if class, priority, ok := resolvedHTBClass(event); ok {
+				classNames[class.Handle()] = class.Name()
+				view.Classes = append(view.Classes, plannedHTBClass(class, priority))
+			} else if object, ok := objectFromEvent(event); ok && object.kind == kindQdisc {
+				view.Qdiscs = append(view.Qdiscs, plannedQdisc(event, object))
+			}
+		}
+	}
+
+	for i := range view.Filters {
+		view.Filters[i].Class = classNames[flowIDs[i]]
+	}
+
+	return view, nil
+}
+
+// resolvedHTBClass returns the HTB class an HTB class creation event installs, with the
+// parameters the event handler computes, and the priority the class was configured with
+func resolvedHTBClass(event events.DomainEvent) (*entities.HTBClass, int, bool) {
+	switch e := event.(type) {
+	case *events.HTBClassCreatedEvent:
+		return htbClassFromEvent(e), e.Priority, true
+	case *events.HTBClassCreatedEventWithAdvancedParameters:
+		return advancedHTBClassFromEvent(e), int(e.Priority), true
+	}
+	return nil, 0, false
+}
+
+// htbQuantum returns the quantum the netlink adapter installs for an HTB class
+func htbQuantum(class *entities.HTBClass) uint32 {
+	if class.Quantum() > 0 {
+		return class.Quantum()
+	}
+	return class.CalculateQuantum()
+}
+
+func plannedHTBClass(class *entities.HTBClass, priority int) qmodels.PlannedClassView {
+	return qmodels.PlannedClassView{
+		Name:     class.Name(),
+		Handle:   class.Handle().String(),
+		Parent:   class.Parent().String(),
+		Rate:     class.Rate().HumanReadable(),
+		Ceil:     class.Ceil().HumanReadable(),
+		Priority: priority,
+		HTBPrio:  class.HTBPrio(),
+		Burst:    class.Burst(),
+		Cburst:   class.Cburst(),
+		Quantum:  htbQuantum(class),
+	}
+}
+
+// plannedQdisc describes a qdisc creation event. Times are in microseconds and sizes in
+// bytes or packets, as in the events.
+func plannedQdisc(event events.DomainEvent, object *tcObject) qmodels.PlannedQdiscView {
+	view := qmodels.PlannedQdiscView{
+		Handle:     object.handle.String(),
+		Parameters: make(map[string]interface{}),
+	}
+	if object.parent != nil {
+		view.Parent = object.parent.String()
+	}
+
+	switch e := event.(type) {
+	case *events.QdiscCreatedEvent:
+		view.Type = e.QdiscType.String()
+	case *events.HTBQdiscCreatedEvent:
+		view.Type = "htb"
+		view.Parameters["default_class"] = e.DefaultClass.String()
+		view.Parameters["r2q"] = e.R2Q
+	case *events.TBFQdiscCreatedEvent:
+		view.Type = "tbf"
+		view.Parameters["rate"] = e.Rate.HumanReadable()
+		view.Parameters["buffer"] = e.Buffer
+		view.Parameters["burst"] = e.Burst
+		view.Parameters["limit"] = e.Limit
+	case *events.PRIOQdiscCreatedEvent:
+		view.Type = "prio"
+		view.Parameters["bands"] = e.Bands
+		// Band of each packet priority, as numbers rather than the bytes JSON would encode
+		priomap := make([]int, len(e.Priomap))
+		for i, band := range e.Priomap {
+			priomap[i] = int(band)
+		}
+		view.Parameters["priomap"] = priomap
+	case *events.FQCODELQdiscCreatedEvent:
+		view.Type = "fq_codel"
+		view.Parameters["limit"] = e.Limit
+		view.Parameters["flows"] = e.Flows
+		view.Parameters["target"] = e.Target
+		view.Parameters["interval"] = e.Interval
+		view.Parameters["quantum"] = e.Quantum
+		view.Parameters["ecn"] = e.ECN
+	case *events.SFQQdiscCreatedEvent:
+		view.Type = "sfq"
+		view.Parameters["perturb"] = e.Perturb
+		view.Parameters["quantum"] = e.Quantum
+		view.Parameters["limit"] = e.Limit
+	case *events.FQQdiscCreatedEvent:
+		view.Type = "fq"
+		view.Parameters["limit"] = e.Limit
+		view.Parameters["flow_limit"] = e.FlowLimit
+		view.Parameters["quantum"] = e.Quantum
+		view.Parameters["initial_quantum"] = e.InitialQuantum
+		view.Parameters["max_rate"] = e.MaxRate.HumanReadable()
+		view.Parameters["pacing"] = e.Pacing
+	case *events.NETEMQdiscCreatedEvent:
+		view.Type = "netem"
+		view.Parameters["delay"] = e.Delay
+		view.Parameters["jitter"] = e.Jitter
+		view.Parameters["loss"] = e.Loss
+		view.Parameters["duplicate"] = e.Duplicate
+		view.Parameters["corrupt"] = e.Corrupt
+		view.Parameters["reorder"] = e.Reorder
+		view.Parameters["gap"] = e.Gap
+		view.Parameters["limit"] = e.Limit
+	case *events.REDQdiscCreatedEvent:
+		view.Type = "red"
+		view.Parameters["limit"] = e.Limit
+		view.Parameters["min"] = e.Min
+		view.Parameters["max"] = e.Max
+		view.Parameters["avpkt"] = e.Avpkt
+		view.Parameters["burst"] = e.Burst
+		view.Parameters["bandwidth"] = e.Bandwidth.HumanReadable()
+		view.Parameters["probability"] = e.Probability
+		view.Parameters["ecn"] = e.ECN
+	case *events.GREDQdiscCreatedEvent:
+		view.Type = "gred"
+		view.Parameters["queues"] = e.Queues
+		view.Parameters["default"] = e.Default
+		view.Parameters["grio"] = e.GRIO
+		view.Parameters["virtual_queues"] = len(e.VirtualQueues)
+	case *events.CODELQdiscCreatedEvent:
+		view.Type = "codel"
+		view.Parameters["limit"] = e.Limit
+		view.Parameters["target"] = e.Target
+		view.Parameters["interval"] = e.Interval
+		view.Parameters["ce_threshold"] = e.CEThreshold
+		view.Parameters["ecn"] = e.ECN
+	case *events.ETSQdiscCreatedEvent:
+		view.Type = "ets"
+		view.Parameters["bands"] = e.Bands
+		view.Parameters["strict"] = e.Strict
+		view.Parameters["quanta"] = e.Quanta
+		view.Parameters["priomap"] = e.Priomap
+	case *events.TAPRIOQdiscCreatedEvent:
+		view.Type = "taprio"
+		view.Parameters["num_tc"] = e.NumTC
+		view.Parameters["priomap"] = e.Priomap
+		view.Parameters["base_time"] = e.BaseTime
+		view.Parameters["cycle_time"] = e.CycleTime
+		view.Parameters["clockid"] = e.Clock.String()
+		view.Parameters["schedule_entries"] = len(e.Schedule)
+		view.Parameters["full_offload"] = e.FullOffload
+	case *events.CAKEQdiscCreatedEvent:
+		view.Type = "cake"
+		view.Parameters["bandwidth"] = e.Bandwidth.HumanReadable()
+		view.Parameters["rtt"] = e.RTT
+		view.Parameters["diffserv"] = e.Diffserv.String()
+		view.Parameters["nat"] = e.NAT
+		view.Parameters["wash"] = e.Wash
+		view.Parameters["ack_filter"] = e.AckFilter.String()
+	}
+
+	return view
+}
+
+// plannedFilter describes a filter creation event, with its matches in tc syntax
+func plannedFilter(e *events.FilterCreatedEvent) (qmodels.PlannedFilterView, error) {
+	view := qmodels.PlannedFilterView{
+		Parent:   e.Parent.String(),
+		Priority: e.Priority,
+		Kind:     e.Kind.String(),
+		Protocol: netlink.ProtocolArg(e.Protocol),
+		FlowID:   e.FlowID.String(),
+		Matches:  make([]string, 0, len(e.Matches)),
+	}
+	for _, data := range e.Matches {
+		match, err := convertMatchData(data)
+		if err != nil {
+			return view, err
+		}
+		view.Matches = append(view.Matches, match.String())
+	}
+	for _, data := range e.Actions {
+		view.Actions = append(view.Actions, data.Action().String())
+	}
+	return view, nil
+}
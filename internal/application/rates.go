@@ -0,0 +1,132 @@
+package application
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
+)
+
+// minRateInterval is the shortest time between samples a rate is computed over; samples
+// taken sooner, as by a device query followed by a class query, reuse the last rate
+const minRateInterval = 100 * time.Millisecond
+
+// CounterRates are the bytes and packets per second of a qdisc or class between two samples
+type CounterRates struct {
+	BytesPerSecond   float64 `json:"bytes_per_second"`
+	PacketsPerSecond float64 `json:"packets_per_second"`
+}
+
+// rateSample is the last counters sampled of an object and the rate computed with them
+type rateSample struct {
+	bytes   uint64
+	packets uint64
+	at      time.Time
+	rates   *CounterRates
+}
+
+// RateCalculator turns the monotonic counters of successive statistics into rates, keeping
+// the previous sample of every qdisc and class. It is safe for concurrent use.
+type RateCalculator struct {
+	mu      sync.Mutex
+	samples map[string]rateSample
+}
+
+// NewRateCalculator creates a rate calculator without samples
+func NewRateCalculator() *RateCalculator {
+	return &RateCalculator{samples: make(map[string]rateSample)}
+}
+
+// Rates records the counters of the object sampled at the time and returns its rates since
+// the previous sample. It returns nil for the first sample of an object and after its
+// counters were reset, as when the object is recreated; the sample is the new baseline.
+func (c *RateCalculator) Rates(key string, bytes, packets uint64, at time.Time) *CounterRates {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous, ok := c.samples[key]
+	if ok && at.Sub(previous.at) < minRateInterval {
+		return previous.rates
+	}
+
+	sample := rateSample{bytes: bytes, packets: packets, at: at}
+	if ok {
+		bytesDelta, bytesOK := counterIncrease(bytes, previous.bytes)
+		packetsDelta, packetsOK := counterIncrease(packets, previous.packets)
+		if bytesOK && packetsOK {
+			seconds := at.Sub(previous.at).Seconds()
+			sample.rates = &CounterRates{
+				BytesPerSecond:   float64(bytesDelta) / seconds,
+				PacketsPerSecond: float64(packetsDelta) / seconds,
+			}
+		}
+	}
+	c.samples[key] = sample
+	return sample.rates
+}
+
+// counterIncrease returns how much a counter grew between samples. A counter that went
+// backwards from the upper half of the 32-bit range wrapped, as the kernel's 32-bit packet
+// counters do; one that went backwards otherwise was reset, and its increase is unknown.
+func counterIncrease(current, previous uint64) (uint64, bool) {
+	if current >= previous {
+		return current - previous, true
+	}
+	if previous >= 1<<31 && previous <= math.MaxUint32 && current < 1<<31 {
+		return current + (math.MaxUint32 - previous) + 1, true
+	}
+	return 0, false
+}
+
+// observe fills in the rates of the statistics' qdiscs and classes
+func (c *RateCalculator) observe(stats *DeviceStatistics) {
+	for i := range stats.QdiscStats {
+		c.observeQdisc(stats.DeviceName, &stats.QdiscStats[i], stats.Timestamp)
+	}
+	for i := range stats.ClassStats {
+		class := &stats.ClassStats[i]
+		class.Rates = c.Rates(rateKey(stats.DeviceName, "class", class.Handle), class.Stats.BytesSent, class.Stats.PacketsSent, stats.Timestamp)
+		if class.LeafQdisc != nil {
+			c.observeQdisc(stats.DeviceName, class.LeafQdisc, stats.Timestamp)
+		}
+	}
+}
+
+func (c *RateCalculator) observeQdisc(device string, qdisc *QdiscStatistics, at time.Time) {
+	qdisc.Rates = c.Rates(rateKey(device, "qdisc", qdisc.Handle), qdisc.Stats.BytesSent, qdisc.Stats.PacketsSent, at)
+}
+
+// observeView fills in the rates of the qdiscs and classes of a statistics view
+func (c *RateCalculator) observeView(device string, view *qmodels.DeviceStatisticsView, at time.Time) {
+	for i := range view.QdiscStats {
+		c.observeQdiscView(device, &view.QdiscStats[i], at)
+	}
+	for i := range view.ClassStats {
+		c.observeClassView(device, &view.ClassStats[i], at)
+	}
+}
+
+func (c *RateCalculator) observeQdiscView(device string, qdisc *qmodels.QdiscStatisticsView, at time.Time) {
+	qdisc.Rates = rateView(c.Rates(rateKey(device, "qdisc", qdisc.Handle), qdisc.BytesSent, qdisc.PacketsSent, at))
+}
+
+func (c *RateCalculator) observeClassView(device string, class *qmodels.ClassStatisticsView, at time.Time) {
+	class.Rates = rateView(c.Rates(rateKey(device, "class", class.Handle), class.BytesSent, class.PacketsSent, at))
+	if class.LeafQdisc != nil {
+		c.observeQdiscView(device, class.LeafQdisc, at)
+	}
+}
+
+// rateKey identifies the samples of a qdisc or class
+func rateKey(device, kind, handle string) string {
+	return device + " " + kind + " " + handle
+}
+
+// rateView converts rates to their view
+func rateView(rates *CounterRates) *qmodels.CounterRatesView {
+	if rates == nil {
+		return nil
+	}
+	return &qmodels.CounterRatesView{BytesPerSecond: rates.BytesPerSecond, PacketsPerSecond: rates.PacketsPerSecond}
+}
@@ -0,0 +1,74 @@
+package application
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestRateCalculator(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("computes_rates_between_samples", func(t *testing.T) {
+		rates := NewRateCalculator()
+		assert.Nil(t, rates.Rates("eth0 class 1:10", 1000, 10, start), "nothing to compare the first sample with")
+
+		rate := rates.Rates("eth0 class 1:10", 21000, 30, start.Add(2*time.Second))
+		require.NotNil(t, rate)
+		assert.Equal(t, CounterRates{BytesPerSecond: 10000, PacketsPerSecond: 10}, *rate)
+
+		assert.Same(t, rate, rates.Rates("eth0 class 1:10", 21000, 30, start.Add(2*time.Second+time.Millisecond)),
+			"a sample right after another keeps its rate")
+		assert.Nil(t, rates.Rates("eth0 class 1:20", 21000, 30, start.Add(2*time.Second)), "objects are sampled apart")
+	})
+
+	t.Run("handles_32_bit_wrap", func(t *testing.T) {
+		rates := NewRateCalculator()
+		rates.Rates("eth0 qdisc 1:", 5000, math.MaxUint32-9, start)
+
+		rate := rates.Rates("eth0 qdisc 1:", 6000, 10, start.Add(time.Second))
+		require.NotNil(t, rate)
+		assert.Equal(t, float64(20), rate.PacketsPerSecond)
+	})
+
+	t.Run("restarts_after_reset", func(t *testing.T) {
+		rates := NewRateCalculator()
+		rates.Rates("eth0 class 1:10", 1<<40, 1000, start)
+
+		assert.Nil(t, rates.Rates("eth0 class 1:10", 500, 5, start.Add(time.Second)), "the class was recreated")
+		rate := rates.Rates("eth0 class 1:10", 1500, 15, start.Add(2*time.Second))
+		require.NotNil(t, rate)
+		assert.Equal(t, CounterRates{BytesPerSecond: 1000, PacketsPerSecond: 10}, *rate)
+	})
+}
+
+func TestTrafficControlService_StatisticsRates(t *testing.T) {
+	adapter := netlink.NewMockAdapter()
+	device := tc.MustNewDeviceName("eth0")
+	handle := tc.NewHandle(1, 0)
+	require.NoError(t, adapter.AddQdisc(context.Background(), entities.NewHTBQdisc(device, handle, tc.NewHandle(1, 999)).Qdisc))
+	service := NewTrafficControlService(eventstore.NewMemoryEventStoreWithContext(), adapter, logging.WithComponent("test"))
+
+	first, err := service.GetRealtimeStatistics(context.Background(), "eth0")
+	require.NoError(t, err)
+	require.Len(t, first.QdiscStats, 1)
+	assert.Nil(t, first.QdiscStats[0].Rates)
+
+	adapter.SetQdiscStatistics(device, handle, netlink.QdiscStats{BytesSent: 100000, PacketsSent: 100})
+	time.Sleep(2 * minRateInterval)
+	second, err := service.GetRealtimeStatistics(context.Background(), "eth0")
+	require.NoError(t, err)
+	require.NotNil(t, second.QdiscStats[0].Rates)
+	assert.Greater(t, second.QdiscStats[0].Rates.BytesPerSecond, float64(0))
+	assert.Greater(t, second.QdiscStats[0].Rates.PacketsPerSecond, float64(0))
+}
@@ -0,0 +1,733 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/rng999/traffic-control-go/internal/domain/aggregates"
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// objectKind identifies the kind of a traffic control object during reconciliation
+type objectKind int
+
+const (
+	kindQdisc objectKind = iota
+	kindClass
+	kindFilter
+)
+
+// tcObject is a qdisc, class or filter as seen by the reconciler
+type tcObject struct {
+	kind         objectKind
+	key          string
+	handle       tc.Handle
+	parent       *tc.Handle
+	priority     uint16             // Filters only
+	flowID       tc.Handle          // Filters only
+	created      events.DomainEvent // Creation event; nil for objects not created through the event store
+	kernel       events.DomainEvent // Definition read back from the kernel, for objects not created through the event store
+	inKernel     bool
+	kernelHandle tc.Handle // Filters only: handle reported by the kernel
+}
+
+// ReconcileResult summarises the changes made by Reconcile
+type ReconcileResult struct {
+	Added     []string
+	Deleted   []string
+	Modified  []string // Changed in place, keeping their counters
+	Unchanged []string
+	// NotAttempted lists the objects left untouched because reconciliation stopped early,
+	// after a failed change or when its deadline passed
+	NotAttempted []string
+}
+
+// Changed reports whether reconciliation modified the device
+func (r *ReconcileResult) Changed() bool {
+	return len(r.Added) > 0 || len(r.Deleted) > 0 || len(r.Modified) > 0
+}
+
+// qdiscKey, classKey and filterKey identify objects independently of the event store.
+// Filters are keyed by their target class because the kernel assigns filter handles.
+func qdiscKey(handle tc.Handle) string { return "qdisc " + handle.String() }
+func classKey(handle tc.Handle) string { return "class " + handle.String() }
+func filterKey(parent tc.Handle, priority uint16, flowID tc.Handle) string {
+	return fmt.Sprintf("filter %s prio %d flowid %s", parent, priority, flowID)
+}
+
+// objectFromEvent describes the object created by a creation event
+func objectFromEvent(event events.DomainEvent) (*tcObject, bool) {
+	switch e := event.(type) {
+	case *events.QdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, parent: e.Parent, created: event}, true
+	case *events.HTBQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, created: event}, true
+	case *events.TBFQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, created: event}, true
+	case *events.PRIOQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, created: event}, true
+	case *events.FQCODELQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, parent: e.Parent, created: event}, true
+	case *events.SFQQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, parent: e.Parent, created: event}, true
+	case *events.FQQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, parent: e.Parent, created: event}, true
+	case *events.NETEMQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, parent: e.Parent, created: event}, true
+	case *events.REDQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, parent: e.Parent, created: event}, true
+	case *events.GREDQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, parent: e.Parent, created: event}, true
+	case *events.CODELQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, parent: e.Parent, created: event}, true
+	case *events.ETSQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, parent: e.Parent, created: event}, true
+	case *events.TAPRIOQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, created: event}, true
+	case *events.CAKEQdiscCreatedEvent:
+		return &tcObject{kind: kindQdisc, key: qdiscKey(e.Handle), handle: e.Handle, created: event}, true
+	case *events.ClassCreatedEvent:
+		parent := e.Parent
+		return &tcObject{kind: kindClass, key: classKey(e.Handle), handle: e.Handle, parent: &parent, created: event}, true
+	case *events.HTBClassCreatedEvent:
+		parent := e.Parent
+		return &tcObject{kind: kindClass, key: classKey(e.Handle), handle: e.Handle, parent: &parent, created: event}, true
+	case *events.HTBClassCreatedEventWithAdvancedParameters:
+		parent := e.Parent
+		return &tcObject{kind: kindClass, key: classKey(e.Handle), handle: e.Handle, parent: &parent, created: event}, true
+	case *events.DRRClassCreatedEvent:
+		parent := e.Parent
+		return &tcObject{kind: kindClass, key: classKey(e.Handle), handle: e.Handle, parent: &parent, created: event}, true
+	case *events.QFQClassCreatedEvent:
+		parent := e.Parent
+		return &tcObject{kind: kindClass, key: classKey(e.Handle), handle: e.Handle, parent: &parent, created: event}, true
+	case *events.FilterCreatedEvent:
+		parent := e.Parent
+		return &tcObject{
+			kind:         kindFilter,
+			key:          filterKey(e.Parent, e.Priority, e.FlowID),
+			handle:       e.Handle,
+			parent:       &parent,
+			priority:     e.Priority,
+			flowID:       e.FlowID,
+			created:      event,
+			kernelHandle: e.Handle,
+		}, true
+	}
+	return nil, false
+}
+
+// recordedObjects folds an event history into the objects that currently exist in it
+func recordedObjects(history []events.DomainEvent) map[string]*tcObject {
+	objects := make(map[string]*tcObject)
+
+	for _, event := range history {
+		if object, ok := objectFromEvent(event); ok {
+			objects[object.key] = object
+			continue
+		}
+
+		switch e := event.(type) {
+		case *events.QdiscDeletedEvent:
+			delete(objects, qdiscKey(e.Handle))
+		case *events.ClassDeletedEvent:
+			delete(objects, classKey(e.Handle))
+		case *events.FilterDeletedEvent:
+			for key, object := range objects {
+				if object.kind == kindFilter && *object.parent == e.Parent &&
+					object.priority == e.Priority && object.handle == e.Handle {
+					delete(objects, key)
+				}
+			}
+		}
+	}
+
+	return objects
+}
+
+// sameDefinition reports whether two creation events describe the same object, ignoring
+// event metadata such as aggregate version and timestamp
+func sameDefinition(a, b events.DomainEvent) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		return false
+	}
+	va, vb = reflect.Indirect(va), reflect.Indirect(vb)
+
+	for i := 0; i < va.NumField(); i++ {
+		field := va.Type().Field(i)
+		if field.Anonymous && field.Type == reflect.TypeOf(events.BaseEvent{}) {
+			continue
+		}
+
+		fa, fb := va.Field(i), vb.Field(i)
+		if matches, ok := fa.Interface().([]events.MatchData); ok {
+			// Match order follows map iteration when the filter was created
+			if !sameMatches(matches, fb.Interface().([]events.MatchData)) {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sameMatches compares filter matches regardless of order
+func sameMatches(a, b []events.MatchData) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	counts := make(map[events.MatchData]int, len(a))
+	for _, match := range a {
+		counts[match]++
+	}
+	for _, match := range b {
+		counts[match]--
+		if counts[match] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// GetDeviceHistory returns the events recorded for a device
+func (s *TrafficControlService) GetDeviceHistory(ctx context.Context, device string) ([]events.DomainEvent, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+
+	history, err := s.eventStore.GetEvents(aggregates.NewTrafficControlAggregate(deviceName).GetID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events: %w", err)
+	}
+
+	return history, nil
+}
+
+// liveObjects returns the objects currently installed on the device, annotated with their
+// recorded creation events, or with their definition as read back from the kernel for
+// objects installed outside the event store. When the kernel state cannot be read, the
+// recorded state is assumed to be live.
+func (s *TrafficControlService) liveObjects(ctx context.Context, device string, recorded map[string]*tcObject) map[string]*tcObject {
+	live := make(map[string]*tcObject)
+
+	kernel, err := s.ReadKernelState(ctx, device)
+	if err != nil {
+		s.logger.Warn("Failed to read kernel state, reconciling against recorded state",
+			logging.String("device", device),
+			logging.Error(err),
+		)
+		for key, object := range recorded {
+			object.inKernel = true
+			live[key] = object
+		}
+		return live
+	}
+	_, read := kernel.Snapshot()
+	kernelObjects := recordedObjects(read)
+
+	add := func(object *tcObject) {
+		if _, duplicate := live[object.key]; duplicate {
+			// Several kernel filters for the same target; only one can be ours
+			object.key = fmt.Sprintf("%s handle %s", object.key, object.kernelHandle)
+		}
+		if rec, ok := recorded[object.key]; ok {
+			object.created = rec.created
+			if object.kind == kindFilter {
+				object.handle = rec.handle
+			}
+		} else if read, ok := kernelObjects[object.key]; ok {
+			object.kernel = read.created
+		}
+		object.inKernel = true
+		live[object.key] = object
+	}
+
+	// Handles with major 0 belong to the kernel's default setup and are left alone, as is the
+	// ingress or clsact qdisc at major ffff, which is not part of the tree
+	for handle, qdisc := range kernel.GetQdiscs() {
+		if handle.Major() != 0 && handle.Major() != 0xffff {
+			add(&tcObject{kind: kindQdisc, key: qdiscKey(handle), handle: handle, parent: qdisc.Parent()})
+		}
+	}
+	for handle, class := range kernel.GetClasses() {
+		if handle.Major() != 0 {
+			parent := class.Parent()
+			add(&tcObject{kind: kindClass, key: classKey(handle), handle: handle, parent: &parent})
+		}
+	}
+	for _, filter := range kernel.GetFilters() {
+		// Filters without a target class are u32 hash table nodes, and filters on the hooks
+		// of the clsact qdisc are not part of the tree either
+		if filter.FlowID().Major() == 0 && filter.FlowID().Minor() == 0 || filter.ID().Parent().Major() == 0xffff {
+			continue
+		}
+		parent := filter.ID().Parent()
+		add(&tcObject{
+			kind:         kindFilter,
+			key:          filterKey(parent, filter.Priority(), filter.FlowID()),
+			handle:       filter.Handle(),
+			parent:       &parent,
+			priority:     filter.Priority(),
+			flowID:       filter.FlowID(),
+			kernelHandle: filter.Handle(),
+		})
+	}
+
+	// Recorded objects missing from the kernel are stale and must be recreated
+	for key, object := range recorded {
+		if _, ok := live[key]; !ok {
+			object.inKernel = false
+			live[key] = object
+		}
+	}
+
+	return live
+}
+
+// reconcilePlan is the set of changes that brings a device to its desired state
+type reconcilePlan struct {
+	aggregate    *aggregates.TrafficControlAggregate
+	removals     []*tcObject          // Objects to delete, in deletion order
+	additions    []events.DomainEvent // Creation events of the objects to add, in planned order
+	additionKeys []string
+	changes      []classChange // HTB classes changed in place
+	changeKeys   []string
+	adoptions    []events.DomainEvent // Desired events of the objects installed outside the event store with their definition
+	unchanged    []string
+}
+
+// classChange is an HTB class whose parameters change while its handle and parent stay
+type classChange struct {
+	previous, current *events.HTBClassCreatedEventWithAdvancedParameters
+}
+
+// changeableInPlace reports whether the recorded object can be changed into the desired one
+// without deleting it, returning the recorded class definition when it can
+func changeableInPlace(recorded, desired events.DomainEvent) (*events.HTBClassCreatedEventWithAdvancedParameters, bool) {
+	previous, ok := recorded.(*events.HTBClassCreatedEventWithAdvancedParameters)
+	if !ok {
+		return nil, false
+	}
+	current, ok := desired.(*events.HTBClassCreatedEventWithAdvancedParameters)
+	if !ok || previous.Handle != current.Handle || previous.Parent != current.Parent {
+		return nil, false
+	}
+	return previous, true
+}
+
+// planReconcile computes the changes Reconcile makes without making them. The desired
+// state is given as the creation events produced by applying the configuration to an empty
+// planning copy of the device.
+func (s *TrafficControlService) planReconcile(ctx context.Context, device string, desired []events.DomainEvent) (*reconcilePlan, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+
+	aggregate := aggregates.NewTrafficControlAggregate(deviceName)
+	if err := s.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return nil, fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// The aggregate's snapshot holds the creation events of the objects it records
+	_, recorded := aggregate.Snapshot()
+
+	desiredObjects := make(map[string]*tcObject)
+	for _, event := range desired {
+		if object, ok := objectFromEvent(event); ok {
+			desiredObjects[object.key] = object
+		}
+	}
+
+	current := s.liveObjects(ctx, device, recordedObjects(recorded))
+	if coexisting(ctx) {
+		if err := setAsideForeignObjects(device, current, desiredObjects); err != nil {
+			return nil, err
+		}
+	}
+
+	// An object is kept only if it is live and recorded with the desired definition, or
+	// installed outside the event store with a definition the kernel reports as the desired
+	// one, in which case it is adopted. An HTB class whose parameters changed is changed in
+	// place instead, keeping its counters.
+	remove := make(map[string]bool)
+	adopt := make(map[string]bool)
+	changeable := make(map[string]*events.HTBClassCreatedEventWithAdvancedParameters)
+	for key, object := range current {
+		want, ok := desiredObjects[key]
+		if !ok || !object.inKernel {
+			remove[key] = true
+			continue
+		}
+		if object.created == nil {
+			if object.kernel != nil && sameKernelDefinition(object.kernel, want.created) {
+				adopt[key] = true
+			} else {
+				remove[key] = true
+			}
+			continue
+		}
+		if sameDefinition(object.created, want.created) {
+			continue
+		}
+		if previous, ok := changeableInPlace(object.created, want.created); ok {
+			changeable[key] = previous
+			continue
+		}
+		remove[key] = true
+	}
+
+	// The desired objects that are missing, in the order they were planned. A changeable
+	// class attached to a removed object is recreated with it.
+	plan := &reconcilePlan{aggregate: aggregate, removals: orderRemovals(current, remove)}
+	for _, event := range desired {
+		object, ok := objectFromEvent(event)
+		if !ok {
+			continue
+		}
+		if _, exists := current[object.key]; exists && !remove[object.key] {
+			if previous, ok := changeable[object.key]; ok {
+				plan.changes = append(plan.changes, classChange{
+					previous: previous,
+					current:  event.(*events.HTBClassCreatedEventWithAdvancedParameters),
+				})
+				plan.changeKeys = append(plan.changeKeys, object.key)
+				continue
+			}
+			if adopt[object.key] {
+				plan.adoptions = append(plan.adoptions, event)
+			}
+			plan.unchanged = append(plan.unchanged, object.key)
+			continue
+		}
+		plan.additions = append(plan.additions, event)
+		plan.additionKeys = append(plan.additionKeys, object.key)
+	}
+
+	return plan, nil
+}
+
+// orderRemovals extends the objects marked for removal with everything attached to them
+// and returns them in deletion order: filters first, then qdiscs and classes from the
+// leaves up
+func orderRemovals(current map[string]*tcObject, remove map[string]bool) []*tcObject {
+	byHandle := make(map[tc.Handle]*tcObject)
+	for _, object := range current {
+		if object.kind != kindFilter {
+			byHandle[object.handle] = object
+		}
+	}
+
+	// Removing an object removes everything attached to it
+	for changed := true; changed; {
+		changed = false
+		for key, object := range current {
+			if remove[key] {
+				continue
+			}
+			dependsOnRemoved := object.parent != nil && byHandle[*object.parent] != nil && remove[byHandle[*object.parent].key]
+			if object.kind == kindFilter && byHandle[object.flowID] != nil && remove[byHandle[object.flowID].key] {
+				dependsOnRemoved = true
+			}
+			if dependsOnRemoved {
+				remove[key] = true
+				changed = true
+			}
+		}
+	}
+
+	// Delete filters first, then qdiscs and classes from the leaves up
+	depth := func(object *tcObject) int {
+		d := 0
+		for parent := object.parent; parent != nil && d < len(current); d++ {
+			next, ok := byHandle[*parent]
+			if !ok {
+				break
+			}
+			parent = next.parent
+		}
+		return d
+	}
+	removals := make([]*tcObject, 0, len(remove))
+	for key := range remove {
+		removals = append(removals, current[key])
+	}
+	sort.Slice(removals, func(i, j int) bool {
+		if (removals[i].kind == kindFilter) != (removals[j].kind == kindFilter) {
+			return removals[i].kind == kindFilter
+		}
+		if di, dj := depth(removals[i]), depth(removals[j]); di != dj {
+			return di > dj
+		}
+		return removals[i].key < removals[j].key
+	})
+	return removals
+}
+
+// Reconcile brings a device to the desired state with the minimal set of netlink changes.
+// The desired state is given as the creation events produced by applying the configuration
+// to an empty planning copy of the device. Objects whose definition is unchanged are left
+// untouched, and those installed outside the event store are recorded as they are when the
+// kernel reports the desired definition; HTB classes whose parameters changed are changed
+// in place; other changed objects are deleted and recreated together with their dependants;
+// objects that are no longer desired are deleted.
+func (s *TrafficControlService) Reconcile(ctx context.Context, device string, desired []events.DomainEvent) (*ReconcileResult, error) {
+	plan, err := s.planReconcile(ctx, device, desired)
+	if err != nil {
+		return nil, err
+	}
+	aggregate, removals := plan.aggregate, plan.removals
+	changes, changeKeys := plan.changes, plan.changeKeys
+	additions, additionKeys := plan.additions, plan.additionKeys
+	result := &ReconcileResult{Unchanged: plan.unchanged}
+
+	// Adopted objects are recorded first, as the additions may hang from them
+	if len(plan.adoptions) > 0 {
+		for _, event := range plan.adoptions {
+			if err := aggregate.Record(event); err != nil {
+				return nil, fmt.Errorf("failed to adopt the installed objects: %w", err)
+			}
+		}
+		if err := s.eventStore.SaveAggregate(withoutPublishing(ctx), aggregate); err != nil {
+			return nil, fmt.Errorf("failed to adopt the installed objects: %w", err)
+		}
+	}
+
+	// stop records what was not attempted and keeps the deletions already made to the
+	// kernel in the event store, even after the deadline passed
+	stop := func(err error, removed, changed, added int) (*ReconcileResult, error) {
+		skipped := make(map[string]bool)
+		for _, object := range removals[removed:] {
+			skipped[object.key] = true
+			result.NotAttempted = append(result.NotAttempted, object.key)
+		}
+		result.NotAttempted = append(result.NotAttempted, changeKeys[changed:]...)
+		for _, key := range additionKeys[added:] {
+			// A changed object is listed once, whether its deletion or re-creation was skipped
+			if !skipped[key] {
+				result.NotAttempted = append(result.NotAttempted, key)
+			}
+		}
+		if saveErr := s.eventStore.SaveAggregate(context.WithoutCancel(ctx), aggregate); saveErr != nil {
+			err = fmt.Errorf("%w (failed to save aggregate: %v)", err, saveErr)
+		}
+		return result, err
+	}
+
+	for i, object := range removals {
+		if err := ctx.Err(); err != nil {
+			return stop(fmt.Errorf("reconciliation stopped before deleting %s: %w", object.key, err), i, 0, 0)
+		}
+		if err := s.removeObject(ctx, aggregate, object); err != nil {
+			return stop(err, i+1, 0, 0)
+		}
+		result.Deleted = append(result.Deleted, object.key)
+	}
+	if err := s.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return result, fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	// Changed classes are changed in place, so that they keep their counters and queued packets
+	for i, change := range changes {
+		key := changeKeys[i]
+		if err := ctx.Err(); err != nil {
+			return stop(fmt.Errorf("reconciliation stopped before changing %s: %w", key, err), len(removals), i, 0)
+		}
+		if err := aggregate.ChangeHTBClass(change.previous, change.current); err != nil {
+			return stop(fmt.Errorf("failed to record the change of %s: %w", key, err), len(removals), i+1, 0)
+		}
+		if err := s.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+			return stop(fmt.Errorf("failed to change %s: %w", key, err), len(removals), i+1, 0)
+		}
+		result.Modified = append(result.Modified, key)
+	}
+
+	// Objects are added one at a time, so that the deadline is checked between them and
+	// a failure names the object that failed
+	for i, event := range additions {
+		key := additionKeys[i]
+		if err := ctx.Err(); err != nil {
+			return stop(fmt.Errorf("reconciliation stopped before adding %s: %w", key, err), len(removals), len(changes), i)
+		}
+		if err := aggregate.Record(event); err != nil {
+			return stop(fmt.Errorf("failed to record %s: %w", key, err), len(removals), len(changes), i+1)
+		}
+		if err := s.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+			return stop(fmt.Errorf("failed to add %s: %w", key, err), len(removals), len(changes), i+1)
+		}
+		result.Added = append(result.Added, key)
+	}
+
+	s.logger.Info("Reconciled traffic control configuration",
+		logging.String("device", device),
+		logging.Int("added", len(result.Added)),
+		logging.Int("deleted", len(result.Deleted)),
+		logging.Int("modified", len(result.Modified)),
+		logging.Int("unchanged", len(result.Unchanged)),
+	)
+
+	return result, nil
+}
+
+// RemoveSelection names the objects Remove deletes. Filters are named by the handle the
+// kernel reports for them.
+type RemoveSelection struct {
+	Qdiscs  []tc.Handle
+	Classes []tc.Handle
+	Filters []tc.Handle
+	// All selects every object on the device except the kernel's default setup
+	All bool
+}
+
+// Remove deletes the selected objects from the device together with everything attached to
+// them, including objects not created through the event store. Deleted lists the objects
+// removed; when a deletion fails, NotAttempted lists the ones left in place. Selecting an
+// object the device does not hold is an error and deletes nothing. In a context made by
+// WithCoexistence, All selects only the library's objects, and selecting an object of
+// another tool is an error as well.
+func (s *TrafficControlService) Remove(ctx context.Context, device string, selection RemoveSelection) (*ReconcileResult, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+
+	aggregate := aggregates.NewTrafficControlAggregate(deviceName)
+	if err := s.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return nil, fmt.Errorf("failed to load aggregate: %w", err)
+	}
+	_, recorded := aggregate.Snapshot()
+	current := s.liveObjects(ctx, device, recordedObjects(recorded))
+	var foreign map[string]bool
+	if coexisting(ctx) {
+		foreign = foreignObjects(current)
+	}
+
+	remove := make(map[string]bool)
+	if selection.All {
+		for key := range current {
+			if !foreign[key] {
+				remove[key] = true
+			}
+		}
+	}
+	for _, handle := range selection.Qdiscs {
+		if _, ok := current[qdiscKey(handle)]; !ok {
+			return nil, fmt.Errorf("qdisc %s not found on device %s", handle, device)
+		}
+		if foreign[qdiscKey(handle)] {
+			return nil, foreignSelected(device, qdiscKey(handle))
+		}
+		remove[qdiscKey(handle)] = true
+	}
+	for _, handle := range selection.Classes {
+		if _, ok := current[classKey(handle)]; !ok {
+			return nil, fmt.Errorf("class %s not found on device %s", handle, device)
+		}
+		if foreign[classKey(handle)] {
+			return nil, foreignSelected(device, classKey(handle))
+		}
+		remove[classKey(handle)] = true
+	}
+	for _, handle := range selection.Filters {
+		found := false
+		for key, object := range current {
+			if object.kind == kindFilter && object.kernelHandle == handle {
+				if foreign[key] {
+					return nil, foreignSelected(device, key)
+				}
+				remove[key] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("filter %s not found on device %s", handle, device)
+		}
+	}
+
+	// Objects of other tools are not deleted with what they are attached to either
+	for key := range foreign {
+		delete(current, key)
+	}
+
+	removals := orderRemovals(current, remove)
+	result := &ReconcileResult{}
+	for i, object := range removals {
+		if err := s.removeObject(ctx, aggregate, object); err != nil {
+			for _, skipped := range removals[i+1:] {
+				result.NotAttempted = append(result.NotAttempted, skipped.key)
+			}
+			if saveErr := s.eventStore.SaveAggregate(context.WithoutCancel(ctx), aggregate); saveErr != nil {
+				err = fmt.Errorf("%w (failed to save aggregate: %v)", err, saveErr)
+			}
+			return result, err
+		}
+		if object.inKernel {
+			result.Deleted = append(result.Deleted, object.key)
+		}
+	}
+	if err := s.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return result, fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	s.logger.Info("Removed traffic control configuration",
+		logging.String("device", device),
+		logging.Int("deleted", len(result.Deleted)),
+	)
+
+	return result, nil
+}
+
+// removeObject deletes an object from the kernel and from the aggregate. The deletion is
+// journaled with the object's creation event, so that a rollback re-creates it.
+func (s *TrafficControlService) removeObject(ctx context.Context, aggregate *aggregates.TrafficControlAggregate, object *tcObject) error {
+	device := aggregate.DeviceName()
+
+	if object.inKernel {
+		var created interface{}
+		if object.created != nil {
+			created = object.created
+		}
+		var err error
+		switch object.kind {
+		case kindFilter:
+			err = s.journal.DeleteFilterCreatedBy(ctx, device, *object.parent, object.priority, object.kernelHandle, created)
+		case kindClass:
+			err = s.journal.DeleteClassCreatedBy(ctx, device, object.handle, created)
+		case kindQdisc:
+			err = s.journal.DeleteQdiscCreatedBy(ctx, device, object.handle, created)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %w", object.key, err)
+		}
+	}
+
+	if object.created == nil {
+		return nil
+	}
+
+	var err error
+	switch object.kind {
+	case kindFilter:
+		err = aggregate.DeleteFilter(*object.parent, object.priority, object.handle)
+	case kindClass:
+		err = aggregate.DeleteClass(object.handle)
+	case kindQdisc:
+		err = aggregate.DeleteQdisc(object.handle)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record deletion of %s: %w", object.key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/rng999/traffic-control-go/internal/domain/aggregates"
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// Restore reinstalls the qdiscs, classes and filters the event store records for the device,
+// as after a reboot, which empties the kernel's configuration but not a persisted history.
+// Objects still installed with their recorded definition are left in place, and objects not
+// recorded are deleted, as Reconcile does.
+func (s *TrafficControlService) Restore(ctx context.Context, device string) (*ReconcileResult, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+
+	aggregate := aggregates.NewTrafficControlAggregate(deviceName)
+	if err := s.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return nil, fmt.Errorf("failed to load aggregate: %w", err)
+	}
+	_, recorded := aggregate.Snapshot()
+	if len(recorded) == 0 {
+		return nil, tcerrors.New(tcerrors.CodeNotFound, "device "+device, "no configuration of %s is recorded", device).
+			WithHint("restore from the history the configuration was applied with")
+	}
+
+	result, err := s.Reconcile(ctx, device, restoreOrder(recorded))
+	if result != nil {
+		// Recorded objects missing from the kernel are dropped from the record before they
+		// are created again; they are reported as restored only
+		added := make(map[string]bool, len(result.Added))
+		for _, key := range result.Added {
+			added[key] = true
+		}
+		deleted := result.Deleted[:0]
+		for _, key := range result.Deleted {
+			if !added[key] {
+				deleted = append(deleted, key)
+			}
+		}
+		result.Deleted = deleted
+	}
+	return result, err
+}
+
+// restoreOrder orders the recorded creation events so that every object is created after
+// the one it is attached to: a class changed in place is recorded after the objects created
+// since, which may include its own children
+func restoreOrder(recorded []events.DomainEvent) []events.DomainEvent {
+	objects := make([]*tcObject, len(recorded))
+	byHandle := make(map[tc.Handle]*tcObject)
+	for i, event := range recorded {
+		objects[i], _ = objectFromEvent(event)
+		if objects[i] != nil && objects[i].kind != kindFilter {
+			byHandle[objects[i].handle] = objects[i]
+		}
+	}
+
+	depth := func(object *tcObject) int {
+		if object == nil || object.kind == kindFilter {
+			return len(objects) // Filters go last, once their classes exist
+		}
+		d := 0
+		for parent := object.parent; parent != nil && d < len(objects); d++ {
+			next, ok := byHandle[*parent]
+			if !ok {
+				break
+			}
+			parent = next.parent
+		}
+		return d
+	}
+
+	order := make([]int, len(recorded))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return depth(objects[order[a]]) < depth(objects[order[b]])
+	})
+
+	ordered := make([]events.DomainEvent, len(recorded))
+	for i, index := range order {
+		ordered[i] = recorded[index]
+	}
+	return ordered
+}
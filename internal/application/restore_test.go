@@ -0,0 +1,25 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestRestoreOrder(t *testing.T) {
+	device := tc.MustNewDeviceName("eth0")
+	root, parent, child := tc.NewHandle(1, 0), tc.NewHandle(1, 1), tc.NewHandle(1, 0x10)
+	rate := tc.MustParseBandwidth("10mbps")
+
+	qdisc := events.NewHTBQdiscCreatedEvent("tc:eth0", 1, device, root, child)
+	filter := events.NewFilterCreatedEvent("tc:eth0", 2, device, root, 100, tc.NewHandle(0x800, 0x800), child)
+	childClass := events.NewHTBClassCreatedEvent("tc:eth0", 3, device, child, parent, "web", rate, rate)
+	// The parent was changed in place, so it is recorded last
+	parentClass := events.NewHTBClassCreatedEvent("tc:eth0", 4, device, parent, root, "all", rate, rate)
+
+	ordered := restoreOrder([]events.DomainEvent{qdisc, filter, childClass, parentClass})
+	assert.Equal(t, []events.DomainEvent{qdisc, parentClass, childClass, filter}, ordered)
+}
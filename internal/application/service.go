@@ -2,11 +2,14 @@ package application
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	chandlers "github.com/rng999/traffic-control-go/internal/commands/handlers"
 	"github.com/rng999/traffic-control-go/internal/commands/models"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
 	"github.com/rng999/traffic-control-go/internal/domain/events"
 	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
 	"github.com/rng999/traffic-control-go/internal/infrastructure/netlink"
@@ -15,6 +18,7 @@ import (
 	qmodels "github.com/rng999/traffic-control-go/internal/queries/models"
 	"github.com/rng999/traffic-control-go/pkg/logging"
 	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
 )
 
 // TrafficControlService is the main application service that coordinates
@@ -22,6 +26,7 @@ import (
 type TrafficControlService struct {
 	eventStore        eventstore.EventStoreWithContext
 	netlinkAdapter    netlink.Adapter
+	journal           *netlink.JournalingAdapter
 	commandBus        *CommandBus
 	queryBus          *QueryBus
 	eventBus          *EventBus
@@ -50,14 +55,21 @@ func NewTrafficControlService(
 	}
 	projectionManager := projections.NewManager(baseEventStore)
 
+	// Journal netlink changes so a failed Apply can be rolled back
+	journal := netlink.NewJournalingAdapter(netlinkAdapter)
+
 	service := &TrafficControlService{
 		eventStore:        eventStore,
 		netlinkAdapter:    netlinkAdapter,
+		journal:           journal,
 		projectionManager: projectionManager,
 		readModelStore:    readModelStore,
 		logger:            logger,
 	}
 
+	// Deleted objects are re-created by the event handlers that created them
+	journal.SetRestorer(service.restoreObject)
+
 	// Initialize statistics service
 	service.statisticsService = NewStatisticsService(netlinkAdapter, readModelStore)
 
@@ -67,8 +79,8 @@ func NewTrafficControlService(
 	service.eventBus = NewEventBus(service)
 
 	// Setup event publishing from event store to event bus
-	if wrapper, ok := eventStore.(*eventstore.MemoryEventStoreWrapper); ok {
-		wrapper.SetEventPublisher(service.publishEvent)
+	if publishing, ok := eventStore.(eventstore.PublishingEventStore); ok {
+		publishing.SetEventPublisher(service.publishEvent)
 	}
 
 	// Register handlers
@@ -80,6 +92,18 @@ func NewTrafficControlService(
 	return service
 }
 
+// WithEventStore returns a service recording its events in the given store, using the
+// same netlink adapter and logger
+func (s *TrafficControlService) WithEventStore(eventStore eventstore.EventStoreWithContext) *TrafficControlService {
+	return NewTrafficControlService(eventStore, s.netlinkAdapter, s.logger)
+}
+
+// WithNetlinkAdapter returns a service changing devices through the given adapter, using
+// the same event store and logger
+func (s *TrafficControlService) WithNetlinkAdapter(netlinkAdapter netlink.Adapter) *TrafficControlService {
+	return NewTrafficControlService(s.eventStore, netlinkAdapter, s.logger)
+}
+
 // registerHandlers registers all command and query handlers
 func (s *TrafficControlService) registerHandlers() {
 	// Legacy command handlers removed - now using type-safe generic handlers only
@@ -91,6 +115,19 @@ func (s *TrafficControlService) registerHandlers() {
 	RegisterHandlerFor[*models.CreateTBFQdiscCommand](s.commandBus, chandlers.NewCreateTBFQdiscHandler(s.eventStore))
 	RegisterHandlerFor[*models.CreatePRIOQdiscCommand](s.commandBus, chandlers.NewCreatePRIOQdiscHandler(s.eventStore))
 	RegisterHandlerFor[*models.CreateFQCODELQdiscCommand](s.commandBus, chandlers.NewCreateFQCODELQdiscHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateSFQQdiscCommand](s.commandBus, chandlers.NewCreateSFQQdiscHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateFQQdiscCommand](s.commandBus, chandlers.NewCreateFQQdiscHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateNETEMQdiscCommand](s.commandBus, chandlers.NewCreateNETEMQdiscHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateREDQdiscCommand](s.commandBus, chandlers.NewCreateREDQdiscHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateGREDQdiscCommand](s.commandBus, chandlers.NewCreateGREDQdiscHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateCODELQdiscCommand](s.commandBus, chandlers.NewCreateCODELQdiscHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateETSQdiscCommand](s.commandBus, chandlers.NewCreateETSQdiscHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateTAPRIOQdiscCommand](s.commandBus, chandlers.NewCreateTAPRIOQdiscHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateCAKEQdiscCommand](s.commandBus, chandlers.NewCreateCAKEQdiscHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateDRRQdiscCommand](s.commandBus, chandlers.NewCreateDRRQdiscHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateDRRClassCommand](s.commandBus, chandlers.NewCreateDRRClassHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateQFQQdiscCommand](s.commandBus, chandlers.NewCreateQFQQdiscHandler(s.eventStore))
+	RegisterHandlerFor[*models.CreateQFQClassCommand](s.commandBus, chandlers.NewCreateQFQClassHandler(s.eventStore))
 
 	// Register query handlers with event store access for aggregate reconstruction
 	if baseEventStore, ok := s.eventStore.(eventstore.EventStore); ok {
@@ -114,6 +151,7 @@ func (s *TrafficControlService) registerHandlers() {
 	s.eventBus.Subscribe("HTBQdiscCreated", s.handleQdiscCreated)
 	s.eventBus.Subscribe("ClassCreated", s.handleClassCreated)
 	s.eventBus.Subscribe("HTBClassCreated", s.handleClassCreated)
+	s.eventBus.Subscribe("HTBClassChanged", s.handleClassChanged)
 	s.eventBus.Subscribe("FilterCreated", s.handleFilterCreated)
 
 	// Register event handlers for projections
@@ -198,6 +236,396 @@ func (s *TrafficControlService) CreateFQCODELQdisc(ctx context.Context, device s
 	return nil
 }
 
+// CreateLeafFQCODELQdisc attaches a FQ_CODEL qdisc to an existing class
+func (s *TrafficControlService) CreateLeafFQCODELQdisc(ctx context.Context, device string, parent string, handle string, limit, flows, target, interval, quantum uint32, ecn bool) error {
+	cmd := &models.CreateFQCODELQdiscCommand{
+		DeviceName: device,
+		Handle:     handle,
+		Limit:      limit,
+		Flows:      flows,
+		Target:     target,
+		Interval:   interval,
+		Quantum:    quantum,
+		ECN:        ecn,
+		Parent:     parent,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create leaf FQ_CODEL qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// CreateSFQQdisc creates a new SFQ qdisc
+func (s *TrafficControlService) CreateSFQQdisc(ctx context.Context, device string, handle string, perturb, quantum, limit uint32) error {
+	cmd := &models.CreateSFQQdiscCommand{
+		DeviceName: device,
+		Handle:     handle,
+		Perturb:    perturb,
+		Quantum:    quantum,
+		Limit:      limit,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create SFQ qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// CreateLeafSFQQdisc attaches an SFQ qdisc to an existing class
+func (s *TrafficControlService) CreateLeafSFQQdisc(ctx context.Context, device string, parent string, handle string, perturb, quantum, limit uint32) error {
+	cmd := &models.CreateSFQQdiscCommand{
+		DeviceName: device,
+		Handle:     handle,
+		Perturb:    perturb,
+		Quantum:    quantum,
+		Limit:      limit,
+		Parent:     parent,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create leaf SFQ qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// CreateFQQdisc creates a new FQ qdisc
+func (s *TrafficControlService) CreateFQQdisc(ctx context.Context, device string, handle string, limit, flowLimit, quantum, initialQuantum uint32, maxRate string, pacing bool) error {
+	cmd := &models.CreateFQQdiscCommand{
+		DeviceName:     device,
+		Handle:         handle,
+		Limit:          limit,
+		FlowLimit:      flowLimit,
+		Quantum:        quantum,
+		InitialQuantum: initialQuantum,
+		MaxRate:        maxRate,
+		Pacing:         pacing,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create FQ qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// CreateLeafFQQdisc attaches an FQ qdisc to an existing class
+func (s *TrafficControlService) CreateLeafFQQdisc(ctx context.Context, device string, parent string, handle string, limit, flowLimit, quantum, initialQuantum uint32, maxRate string, pacing bool) error {
+	cmd := &models.CreateFQQdiscCommand{
+		DeviceName:     device,
+		Handle:         handle,
+		Limit:          limit,
+		FlowLimit:      flowLimit,
+		Quantum:        quantum,
+		InitialQuantum: initialQuantum,
+		MaxRate:        maxRate,
+		Pacing:         pacing,
+		Parent:         parent,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create leaf FQ qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// NETEMParameters holds the network conditions emulated by a NETEM qdisc
+type NETEMParameters struct {
+	Delay     time.Duration
+	Jitter    time.Duration
+	Loss      float32 // percent
+	Duplicate float32 // percent
+	Corrupt   float32 // percent
+	Reorder   float32 // percent
+	Gap       uint32
+	Limit     uint32
+}
+
+// CreateNETEMQdisc creates a new NETEM qdisc; an empty parent makes it the root qdisc,
+// otherwise it is attached to the parent class
+func (s *TrafficControlService) CreateNETEMQdisc(ctx context.Context, device string, parent string, handle string, params NETEMParameters) error {
+	delay, err := durationMicros(params.Delay)
+	if err != nil {
+		return fmt.Errorf("invalid delay: %w", err)
+	}
+	jitter, err := durationMicros(params.Jitter)
+	if err != nil {
+		return fmt.Errorf("invalid jitter: %w", err)
+	}
+
+	cmd := &models.CreateNETEMQdiscCommand{
+		DeviceName: device,
+		Handle:     handle,
+		Delay:      delay,
+		Jitter:     jitter,
+		Loss:       params.Loss,
+		Duplicate:  params.Duplicate,
+		Corrupt:    params.Corrupt,
+		Reorder:    params.Reorder,
+		Gap:        params.Gap,
+		Limit:      params.Limit,
+		Parent:     parent,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create NETEM qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// durationMicros converts a duration to the microseconds used by qdisc parameters
+func durationMicros(d time.Duration) (uint32, error) {
+	if d < 0 || d.Microseconds() > math.MaxUint32 {
+		return 0, fmt.Errorf("%v is out of range", d)
+	}
+	return uint32(d.Microseconds()), nil // #nosec G115 - range checked above
+}
+
+// REDParameters holds the thresholds and flags of a RED qdisc
+type REDParameters struct {
+	Limit       uint32 // bytes
+	Min         uint32 // bytes
+	Max         uint32 // bytes
+	Avpkt       uint32 // bytes
+	Burst       uint32 // packets, 0 derives it from the thresholds
+	Bandwidth   string
+	Probability float64
+	ECN         bool
+	HardDrop    bool
+	Adaptive    bool
+}
+
+// CreateREDQdisc creates a new RED qdisc; an empty parent makes it the root qdisc,
+// otherwise it is attached to the parent class
+func (s *TrafficControlService) CreateREDQdisc(ctx context.Context, device string, parent string, handle string, params REDParameters) error {
+	cmd := &models.CreateREDQdiscCommand{
+		DeviceName:  device,
+		Handle:      handle,
+		Limit:       params.Limit,
+		Min:         params.Min,
+		Max:         params.Max,
+		Avpkt:       params.Avpkt,
+		Burst:       params.Burst,
+		Bandwidth:   params.Bandwidth,
+		Probability: params.Probability,
+		ECN:         params.ECN,
+		HardDrop:    params.HardDrop,
+		Adaptive:    params.Adaptive,
+		Parent:      parent,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create RED qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// GREDParameters holds the virtual queues of a GRED qdisc
+type GREDParameters struct {
+	Queues        uint32
+	Default       uint32
+	GRIO          bool
+	ECN           bool
+	HardDrop      bool
+	VirtualQueues []models.GREDVirtualQueue
+}
+
+// CreateGREDQdisc creates a new GRED qdisc; an empty parent makes it the root qdisc,
+// otherwise it is attached to the parent class
+func (s *TrafficControlService) CreateGREDQdisc(ctx context.Context, device string, parent string, handle string, params GREDParameters) error {
+	cmd := &models.CreateGREDQdiscCommand{
+		DeviceName:    device,
+		Handle:        handle,
+		Queues:        params.Queues,
+		Default:       params.Default,
+		GRIO:          params.GRIO,
+		ECN:           params.ECN,
+		HardDrop:      params.HardDrop,
+		VirtualQueues: params.VirtualQueues,
+		Parent:        parent,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create GRED qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// CODELParameters holds the settings of a CoDel qdisc
+type CODELParameters struct {
+	Limit       uint32 // packets
+	Target      time.Duration
+	Interval    time.Duration
+	CEThreshold time.Duration // zero disables CE marking
+	ECN         bool
+}
+
+// CreateCODELQdisc creates a new CoDel qdisc; an empty parent makes it the root qdisc,
+// otherwise it is attached to the parent class
+func (s *TrafficControlService) CreateCODELQdisc(ctx context.Context, device string, parent string, handle string, params CODELParameters) error {
+	target, err := durationMicros(params.Target)
+	if err != nil {
+		return fmt.Errorf("invalid target: %w", err)
+	}
+	interval, err := durationMicros(params.Interval)
+	if err != nil {
+		return fmt.Errorf("invalid interval: %w", err)
+	}
+	ceThreshold, err := durationMicros(params.CEThreshold)
+	if err != nil {
+		return fmt.Errorf("invalid ce threshold: %w", err)
+	}
+
+	cmd := &models.CreateCODELQdiscCommand{
+		DeviceName:  device,
+		Handle:      handle,
+		Limit:       params.Limit,
+		Target:      target,
+		Interval:    interval,
+		CEThreshold: ceThreshold,
+		ECN:         params.ECN,
+		Parent:      parent,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create CoDel qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// ETSParameters holds the bands of an ETS qdisc
+type ETSParameters struct {
+	Bands   uint8    // 0 derives the bands from the strict bands and quanta
+	Strict  uint8    // strict priority bands, served first
+	Quanta  []uint32 // bytes, one per bandwidth-sharing band
+	Priomap []uint8  // band of each packet priority
+}
+
+// CreateETSQdisc creates a new ETS qdisc; an empty parent makes it the root qdisc,
+// otherwise it is attached to the parent class
+func (s *TrafficControlService) CreateETSQdisc(ctx context.Context, device string, parent string, handle string, params ETSParameters) error {
+	cmd := &models.CreateETSQdiscCommand{
+		DeviceName: device,
+		Handle:     handle,
+		Bands:      params.Bands,
+		Strict:     params.Strict,
+		Quanta:     params.Quanta,
+		Priomap:    params.Priomap,
+		Parent:     parent,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create ETS qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// TAPRIOGateEntry is one interval of a taprio gate schedule
+type TAPRIOGateEntry struct {
+	Command  string // "S" sets the gates, "H" and "R" also hold and release preemptible traffic
+	Gates    uint32 // bit n opens the gate of traffic class n
+	Interval time.Duration
+}
+
+// TAPRIOParameters holds the traffic classes and gate schedule of a taprio qdisc
+type TAPRIOParameters struct {
+	NumTC              uint8
+	Priomap            []uint8 // traffic class of each packet priority
+	Queues             []models.TAPRIOQueueRange
+	BaseTime           time.Time
+	CycleTime          time.Duration // zero for the sum of the intervals
+	CycleTimeExtension time.Duration
+	Clock              string // such as "CLOCK_TAI"; ignored with full offload
+	Schedule           []TAPRIOGateEntry
+	TxTimeAssist       bool
+	FullOffload        bool
+	TxTimeDelay        time.Duration
+}
+
+// CreateTAPRIOQdisc creates a new taprio qdisc, which is always the root qdisc
+func (s *TrafficControlService) CreateTAPRIOQdisc(ctx context.Context, device string, handle string, params TAPRIOParameters) error {
+	var baseTime int64
+	if !params.BaseTime.IsZero() {
+		baseTime = params.BaseTime.UnixNano()
+	}
+	txTimeDelay, err := durationNanos(params.TxTimeDelay)
+	if err != nil {
+		return fmt.Errorf("invalid txtime delay: %w", err)
+	}
+
+	schedule := make([]models.TAPRIOScheduleEntry, 0, len(params.Schedule))
+	for i, entry := range params.Schedule {
+		interval, err := durationNanos(entry.Interval)
+		if err != nil {
+			return fmt.Errorf("invalid interval of schedule entry %d: %w", i, err)
+		}
+		schedule = append(schedule, models.TAPRIOScheduleEntry{
+			Command:  entry.Command,
+			GateMask: entry.Gates,
+			Interval: interval,
+		})
+	}
+
+	cmd := &models.CreateTAPRIOQdiscCommand{
+		DeviceName:         device,
+		Handle:             handle,
+		NumTC:              params.NumTC,
+		Priomap:            params.Priomap,
+		Queues:             params.Queues,
+		BaseTime:           baseTime,
+		CycleTime:          params.CycleTime.Nanoseconds(),
+		CycleTimeExtension: params.CycleTimeExtension.Nanoseconds(),
+		Clock:              params.Clock,
+		Schedule:           schedule,
+		TxTimeAssist:       params.TxTimeAssist,
+		FullOffload:        params.FullOffload,
+		TxTimeDelay:        txTimeDelay,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create taprio qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// durationNanos converts a duration to the nanoseconds the kernel takes as a 32-bit value
+func durationNanos(d time.Duration) (uint32, error) {
+	if d < 0 || d.Nanoseconds() > math.MaxUint32 {
+		return 0, fmt.Errorf("%v is out of range", d)
+	}
+	return uint32(d.Nanoseconds()), nil // #nosec G115 - range checked above
+}
+
+// CreateCAKEQdisc creates a new CAKE qdisc
+func (s *TrafficControlService) CreateCAKEQdisc(ctx context.Context, device string, handle string, bandwidth string, rtt uint32, diffserv string, nat, wash bool, ackFilter string) error {
+	cmd := &models.CreateCAKEQdiscCommand{
+		DeviceName: device,
+		Handle:     handle,
+		Bandwidth:  bandwidth,
+		RTT:        rtt,
+		Diffserv:   diffserv,
+		NAT:        nat,
+		Wash:       wash,
+		AckFilter:  ackFilter,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create CAKE qdisc: %w", err)
+	}
+
+	return nil
+}
+
 // CreateHTBClass creates a new HTB class
 func (s *TrafficControlService) CreateHTBClass(ctx context.Context, device string, parent string, classID string, rate string, ceil string) error {
 	cmd := &models.CreateHTBClassCommand{
@@ -235,6 +663,109 @@ func (s *TrafficControlService) CreateHTBClassWithAdvancedParameters(ctx context
 	return nil
 }
 
+// CreateDRRQdisc creates a new DRR (Deficit Round Robin) qdisc; an empty parent makes it
+// the root qdisc, otherwise it is attached to the parent class
+func (s *TrafficControlService) CreateDRRQdisc(ctx context.Context, device string, parent string, handle string) error {
+	cmd := &models.CreateDRRQdiscCommand{
+		DeviceName: device,
+		Handle:     handle,
+		Parent:     parent,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create DRR qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// CreateDRRClass creates a new DRR class, which may send up to quantum bytes each round;
+// a zero quantum uses the device MTU
+func (s *TrafficControlService) CreateDRRClass(ctx context.Context, device string, parent string, classID string, name string, quantum uint32) error {
+	cmd := &models.CreateDRRClassCommand{
+		DeviceName: device,
+		Parent:     parent,
+		ClassID:    classID,
+		Name:       name,
+		Quantum:    quantum,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create DRR class: %w", err)
+	}
+
+	return nil
+}
+
+// CreateQFQQdisc creates a new QFQ (Quick Fair Queueing) qdisc; an empty parent makes it
+// the root qdisc, otherwise it is attached to the parent class
+func (s *TrafficControlService) CreateQFQQdisc(ctx context.Context, device string, parent string, handle string) error {
+	cmd := &models.CreateQFQQdiscCommand{
+		DeviceName: device,
+		Handle:     handle,
+		Parent:     parent,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create QFQ qdisc: %w", err)
+	}
+
+	return nil
+}
+
+// CreateQFQClass creates a new QFQ class with the given weight and maximum packet size;
+// zero values use the kernel defaults of weight 1 and the device MTU
+func (s *TrafficControlService) CreateQFQClass(ctx context.Context, device string, parent string, classID string, name string, weight, lmax uint32) error {
+	cmd := &models.CreateQFQClassCommand{
+		DeviceName: device,
+		Parent:     parent,
+		ClassID:    classID,
+		Name:       name,
+		Weight:     weight,
+		Lmax:       lmax,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create QFQ class: %w", err)
+	}
+
+	return nil
+}
+
+// HTBClassParameters carries optional HTB tuning parameters; zero values are calculated automatically
+type HTBClassParameters struct {
+	Burst   uint32
+	Cburst  uint32
+	Quantum uint32
+	HTBPrio uint32
+	Profile string // Tuning profile deriving the parameters left at zero; empty is the default
+}
+
+// CreateHTBClassWithParameters creates a new HTB class with explicit tuning parameters
+func (s *TrafficControlService) CreateHTBClassWithParameters(ctx context.Context, device string, parent string, classID string, name string, rate string, ceil string, priority uint8, params HTBClassParameters) error {
+	cmd := &models.CreateHTBClassCommand{
+		DeviceName:  device,
+		Parent:      parent,
+		ClassID:     classID,
+		Name:        name,
+		Rate:        rate,
+		Ceil:        ceil,
+		Priority:    int(priority),
+		Burst:       params.Burst,
+		Cburst:      params.Cburst,
+		Quantum:     params.Quantum,
+		HTBPrio:     params.HTBPrio,
+		Profile:     params.Profile,
+		UseDefaults: true, // Fill in anything not given explicitly
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create HTB class with parameters: %w", err)
+	}
+
+	return nil
+}
+
 // CreateFilter creates a new filter
 func (s *TrafficControlService) CreateFilter(ctx context.Context, device string, parent string, priority uint16, protocol string, flowID string, match map[string]string) error {
 	cmd := &models.CreateFilterCommand{
@@ -253,6 +784,47 @@ func (s *TrafficControlService) CreateFilter(ctx context.Context, device string,
 	return nil
 }
 
+// CreateFilterWithActions creates a new filter that runs the given action chain on the
+// packets it matches
+func (s *TrafficControlService) CreateFilterWithActions(ctx context.Context, device string, parent string, priority uint16, protocol string, flowID string, match map[string]string, actions []models.FilterAction) error {
+	cmd := &models.CreateFilterCommand{
+		DeviceName: device,
+		Parent:     parent,
+		Priority:   priority,
+		Protocol:   protocol,
+		FlowID:     flowID,
+		Match:      match,
+		Actions:    actions,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create filter: %w", err)
+	}
+
+	return nil
+}
+
+// CreateFilterOfKind creates a new filter installed with the classifier, "u32" or "flower",
+// that runs the given action chain on the packets it matches
+func (s *TrafficControlService) CreateFilterOfKind(ctx context.Context, kind string, device string, parent string, priority uint16, protocol string, flowID string, match map[string]string, actions []models.FilterAction) error {
+	cmd := &models.CreateFilterCommand{
+		DeviceName: device,
+		Parent:     parent,
+		Priority:   priority,
+		Protocol:   protocol,
+		Kind:       kind,
+		FlowID:     flowID,
+		Match:      match,
+		Actions:    actions,
+	}
+
+	if err := s.commandBus.ExecuteCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to create filter: %w", err)
+	}
+
+	return nil
+}
+
 // GetConfiguration retrieves the current traffic control configuration
 func (s *TrafficControlService) GetConfiguration(ctx context.Context, device string) (*qmodels.ConfigurationView, error) {
 	deviceName, err := tc.NewDevice(device)
@@ -302,6 +874,7 @@ func (s *TrafficControlService) GetDeviceStatistics(ctx context.Context, device
 		return nil, fmt.Errorf("unexpected result type: %T", result)
 	}
 
+	s.statisticsService.rates.observeView(device, &stats, time.Now())
 	return &stats, nil
 }
 
@@ -329,6 +902,7 @@ func (s *TrafficControlService) GetQdiscStatistics(ctx context.Context, device s
 		return nil, fmt.Errorf("unexpected result type: %T", result)
 	}
 
+	s.statisticsService.rates.observeQdiscView(device, &stats, time.Now())
 	return &stats, nil
 }
 
@@ -356,6 +930,7 @@ func (s *TrafficControlService) GetClassStatistics(ctx context.Context, device s
 		return nil, fmt.Errorf("unexpected result type: %T", result)
 	}
 
+	s.statisticsService.rates.observeClassView(device, &stats, time.Now())
 	return &stats, nil
 }
 
@@ -378,6 +953,7 @@ func (s *TrafficControlService) GetRealtimeStatistics(ctx context.Context, devic
 		return nil, fmt.Errorf("unexpected result type: %T", result)
 	}
 
+	s.statisticsService.rates.observeView(device, &stats, time.Now())
 	return &stats, nil
 }
 
@@ -390,6 +966,312 @@ func (s *TrafficControlService) MonitorStatistics(ctx context.Context, device st
 	})
 }
 
+// WatchQdiscDeletions reports qdiscs deleted from the device, including deletions made
+// outside this library, until the context is cancelled
+func (s *TrafficControlService) WatchQdiscDeletions(ctx context.Context, device string) (<-chan netlink.QdiscDeletion, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+
+	return s.netlinkAdapter.WatchQdiscDeletions(ctx, deviceName)
+}
+
+// WatchLinks reports changes of the device's link state, following the device by name
+// across deletion and re-creation, until the context is cancelled
+func (s *TrafficControlService) WatchLinks(ctx context.Context, device string) (<-chan netlink.LinkEvent, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+
+	return s.netlinkAdapter.WatchLinks(ctx, deviceName)
+}
+
+// CheckDevice verifies that the device exists. For a missing VLAN sub-interface named like
+// eth0.100, the error tells whether the parent device is missing too.
+func (s *TrafficControlService) CheckDevice(device string) error {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	result := s.netlinkAdapter.GetLinkInfo(deviceName)
+	if result.IsSuccess() {
+		return nil
+	}
+
+	parent, id, ok := deviceName.VLAN()
+	if !ok {
+		return result.Error()
+	}
+	if parentResult := s.netlinkAdapter.GetLinkInfo(parent); parentResult.IsFailure() {
+		return fmt.Errorf("parent device %s of VLAN %s does not exist: %w", parent, deviceName, parentResult.Error())
+	}
+	return fmt.Errorf("VLAN %d is not configured on %s: %w", id, parent, result.Error())
+}
+
+// GetLinkInfo describes the device's link: its kind, and the devices it is stacked on
+func (s *TrafficControlService) GetLinkInfo(device string) (netlink.LinkInfo, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return netlink.LinkInfo{}, fmt.Errorf("invalid device name: %w", err)
+	}
+
+	result := s.netlinkAdapter.GetLinkInfo(deviceName)
+	if result.IsFailure() {
+		return netlink.LinkInfo{}, result.Error()
+	}
+	return result.Value(), nil
+}
+
+// GetBridgePorts returns the names of the devices enslaved to the bridge
+func (s *TrafficControlService) GetBridgePorts(bridge string) ([]string, error) {
+	bridgeName, err := tc.NewDeviceName(bridge)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+
+	result := s.netlinkAdapter.GetBridgePorts(bridgeName)
+	if result.IsFailure() {
+		return nil, result.Error()
+	}
+
+	ports := make([]string, 0, len(result.Value()))
+	for _, port := range result.Value() {
+		ports = append(ports, port.String())
+	}
+	return ports, nil
+}
+
+// CheckPrivileges checks that the process may change traffic control, so that a change the
+// kernel would refuse fails before it starts, with guidance, rather than part way through
+func (s *TrafficControlService) CheckPrivileges(ctx context.Context) error {
+	return s.netlinkAdapter.CheckPrivileges(ctx)
+}
+
+// NetlinkAdapter returns the adapter the service changes devices through
+func (s *TrafficControlService) NetlinkAdapter() netlink.Adapter {
+	return s.netlinkAdapter
+}
+
+// CreateIngressRedirect redirects traffic received on the device to the IFB device, which is
+// created if missing, so that qdiscs on the IFB device shape the device's ingress
+func (s *TrafficControlService) CreateIngressRedirect(ctx context.Context, device, ifb string) error {
+	deviceName, ifbName, err := parseIngressDevices(device, ifb)
+	if err != nil {
+		return err
+	}
+
+	return netlink.RunOperation(ctx, func() error {
+		return s.netlinkAdapter.AddIngressRedirect(ctx, deviceName, ifbName)
+	})
+}
+
+// DeleteIngressRedirect stops redirecting the device's ingress and deletes the IFB device,
+// together with the qdiscs shaping it
+func (s *TrafficControlService) DeleteIngressRedirect(device, ifb string) error {
+	deviceName, ifbName, err := parseIngressDevices(device, ifb)
+	if err != nil {
+		return err
+	}
+
+	if result := s.netlinkAdapter.DeleteIngressRedirect(deviceName, ifbName); result.IsFailure() {
+		return result.Error()
+	}
+	return nil
+}
+
+// parseIngressDevices validates the device whose ingress is shaped and its IFB device
+func parseIngressDevices(device, ifb string) (tc.DeviceName, tc.DeviceName, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return tc.DeviceName{}, tc.DeviceName{}, fmt.Errorf("invalid device name: %w", err)
+	}
+	ifbName, err := tc.NewDeviceName(ifb)
+	if err != nil {
+		return tc.DeviceName{}, tc.DeviceName{}, fmt.Errorf("invalid IFB device name: %w", err)
+	}
+	if deviceName.Equals(ifbName) {
+		return tc.DeviceName{}, tc.DeviceName{}, fmt.Errorf("cannot redirect ingress of %s to itself", device)
+	}
+	return deviceName, ifbName, nil
+}
+
+// AttachBPFClassifier loads a BPF program and attaches it in direct-action mode to a clsact
+// hook of the device, adding the clsact qdisc if missing
+func (s *TrafficControlService) AttachBPFClassifier(ctx context.Context, device string, classifier netlink.BPFClassifier) error {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	return netlink.RunOperation(ctx, func() error {
+		return s.netlinkAdapter.AttachBPFClassifier(ctx, deviceName, classifier)
+	})
+}
+
+// DetachBPFClassifier removes the BPF classifier with the priority from a clsact hook of
+// the device
+func (s *TrafficControlService) DetachBPFClassifier(device string, hook netlink.ClsactHook, priority uint16) error {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	if result := s.netlinkAdapter.DetachBPFClassifier(deviceName, hook, priority); result.IsFailure() {
+		return result.Error()
+	}
+	return nil
+}
+
+// UpdateBPFMap sets an entry of the BPF map pinned at path
+func (s *TrafficControlService) UpdateBPFMap(path string, key, value []byte) error {
+	return s.netlinkAdapter.UpdateBPFMap(path, key, value)
+}
+
+// DeleteBPFMapEntry removes an entry from the BPF map pinned at path
+func (s *TrafficControlService) DeleteBPFMapEntry(path string, key []byte) error {
+	if result := s.netlinkAdapter.DeleteBPFMapEntry(path, key); result.IsFailure() {
+		return result.Error()
+	}
+	return nil
+}
+
+// HookFilter is a filter on a hook of the clsact qdisc. It belongs to no class and runs its
+// actions on the packets it matches, before any qdisc for ingress and before the root qdisc
+// for egress.
+type HookFilter struct {
+	Hook     netlink.ClsactHook
+	Kind     string // Classifier, "u32" or "flower"; u32 when empty
+	Priority uint16
+	Match    map[string]string // Conditions in CreateFilterCommand form
+	Actions  []models.FilterAction
+}
+
+// EnsureClsactQdisc adds the clsact qdisc to the device unless it has one, and reports
+// whether it was added
+func (s *TrafficControlService) EnsureClsactQdisc(ctx context.Context, device string, hook netlink.ClsactHook) (bool, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return false, fmt.Errorf("invalid device name: %w", err)
+	}
+
+	var created bool
+	err = netlink.RunOperation(ctx, func() error {
+		created, err = s.netlinkAdapter.EnsureClsactQdisc(ctx, deviceName, hook)
+		return err
+	})
+	return created, err
+}
+
+// DeleteClsactQdisc deletes the device's clsact qdisc unless filters remain on its hooks
+func (s *TrafficControlService) DeleteClsactQdisc(device string) error {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	if result := s.netlinkAdapter.DeleteClsactQdisc(deviceName); result.IsFailure() {
+		return result.Error()
+	}
+	return nil
+}
+
+// AttachHookFilter adds the filter to its hook of the device's clsact qdisc, replacing the
+// filters at its priority. The qdisc must exist; see EnsureClsactQdisc.
+func (s *TrafficControlService) AttachHookFilter(ctx context.Context, device string, filter HookFilter) error {
+	entity, err := buildHookFilter(device, filter)
+	if err != nil {
+		return err
+	}
+	if err := s.DetachHookFilter(device, filter.Hook, filter.Priority); err != nil {
+		return err
+	}
+
+	return netlink.RunOperation(ctx, func() error {
+		return s.netlinkAdapter.AddFilter(ctx, entity)
+	})
+}
+
+// DetachHookFilter removes the filters at the priority from a hook of the device's clsact
+// qdisc; there being none is not an error
+func (s *TrafficControlService) DetachHookFilter(device string, hook netlink.ClsactHook, priority uint16) error {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+	parent, err := hook.Parent()
+	if err != nil {
+		return err
+	}
+
+	result := s.netlinkAdapter.DeleteFilter(deviceName, parent, priority, tc.Handle{})
+	if result.IsFailure() && !errors.Is(result.Error(), tcerrors.ErrNotFound) {
+		return result.Error()
+	}
+	return nil
+}
+
+// CheckHookFilter reports why the filter cannot be attached to the device, if it cannot
+func (s *TrafficControlService) CheckHookFilter(device string, filter HookFilter) error {
+	_, err := buildHookFilter(device, filter)
+	return err
+}
+
+// buildHookFilter converts a hook filter to the filter entity the adapter installs,
+// applying the rules the aggregate applies to class filters
+func buildHookFilter(device string, filter HookFilter) (*entities.Filter, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+	parent, err := filter.Hook.Parent()
+	if err != nil {
+		return nil, err
+	}
+	kind, err := entities.ParseFilterKind(filter.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if kind == entities.FilterKindCgroup {
+		return nil, fmt.Errorf("cgroup filters select a class and cannot be attached to a clsact hook")
+	}
+	matches, err := chandlers.ParseFilterMatches(filter.Match)
+	if err != nil {
+		return nil, err
+	}
+	if err := entities.ValidateFilterMatches(kind, matches); err != nil {
+		return nil, fmt.Errorf("invalid filter matches: %w", err)
+	}
+	actions, err := chandlers.ParseFilterActions(filter.Actions)
+	if err != nil {
+		return nil, err
+	}
+	if len(actions) == 0 {
+		return nil, fmt.Errorf("a filter on a clsact hook needs at least one action")
+	}
+	if err := entities.ValidateActionChain(actions); err != nil {
+		return nil, fmt.Errorf("invalid action chain: %w", err)
+	}
+	for _, action := range actions {
+		// Packets sent back to the device's own egress would pass the hook again
+		if mirred, ok := action.(*entities.MirredAction); ok && filter.Hook == netlink.HookEgress && mirred.Target().Equals(deviceName) {
+			return nil, fmt.Errorf("cannot %s packets of %s to itself", mirred.Type(), deviceName)
+		}
+	}
+
+	entity := entities.NewFilter(deviceName, parent, filter.Priority, tc.NewHandle(0x800, filter.Priority))
+	entity.SetKind(kind)
+	for _, match := range matches {
+		entity.AddMatch(match)
+	}
+	for _, action := range actions {
+		entity.AddAction(action)
+	}
+	return entity, nil
+}
+
 // 削除: tc.ParseHandle()を直接使用するため不要
 
 // convertApplicationStatsToView converts application model to view model
@@ -414,29 +1296,8 @@ func convertApplicationStatsToView(stats *DeviceStatistics) qmodels.DeviceStatis
 
 	// Convert qdisc statistics
 	for _, qdisc := range stats.QdiscStats {
-		qdiscView := qmodels.QdiscStatisticsView{
-			Handle:        qdisc.Handle,
-			Type:          qdisc.Type,
-			BytesSent:     qdisc.Stats.BytesSent,
-			PacketsSent:   qdisc.Stats.PacketsSent,
-			BytesDropped:  qdisc.Stats.BytesDropped,
-			Overlimits:    qdisc.Stats.Overlimits,
-			Requeues:      qdisc.Stats.Requeues,
-			DetailedStats: make(map[string]interface{}),
-		}
-
-		if qdisc.DetailedStats != nil {
-			qdiscView.Backlog = qdisc.DetailedStats.Backlog
-			qdiscView.QueueLength = qdisc.DetailedStats.QueueLength
-			qdiscView.DetailedStats["backlog_bytes"] = qdisc.DetailedStats.BacklogBytes
-			qdiscView.DetailedStats["bytes_per_second"] = qdisc.DetailedStats.BytesPerSecond
-			qdiscView.DetailedStats["packets_per_second"] = qdisc.DetailedStats.PacketsPerSecond
-			if qdisc.DetailedStats.HTBStats != nil {
-				qdiscView.DetailedStats["htb_direct_packets"] = qdisc.DetailedStats.HTBStats.DirectPackets
-				qdiscView.DetailedStats["htb_version"] = qdisc.DetailedStats.HTBStats.Version
-			}
-		}
-
+		qdiscView := qhandlers.NewQdiscStatisticsView(qdisc.Handle, qdisc.Type, qdisc.Stats, qdisc.DetailedStats)
+		qdiscView.Rates = rateView(qdisc.Rates)
 		view.QdiscStats = append(view.QdiscStats, qdiscView)
 	}
 
@@ -448,12 +1309,13 @@ func convertApplicationStatsToView(stats *DeviceStatistics) qmodels.DeviceStatis
 			Name:           class.Name,
 			BytesSent:      class.Stats.BytesSent,
 			PacketsSent:    class.Stats.PacketsSent,
-			BytesDropped:   class.Stats.BytesDropped,
+			PacketsDropped: class.Stats.PacketsDropped,
 			Overlimits:     class.Stats.Overlimits,
 			BacklogBytes:   class.Stats.BacklogBytes,
 			BacklogPackets: class.Stats.BacklogPackets,
 			RateBPS:        class.Stats.RateBPS,
 			DetailedStats:  make(map[string]interface{}),
+			Rates:          rateView(class.Rates),
 		}
 
 		if class.DetailedStats != nil && class.DetailedStats.HTBStats != nil {
@@ -466,6 +1328,15 @@ func convertApplicationStatsToView(stats *DeviceStatistics) qmodels.DeviceStatis
 			classView.DetailedStats["htb_ceil"] = class.DetailedStats.HTBStats.Ceil
 			classView.DetailedStats["htb_level"] = class.DetailedStats.HTBStats.Level
 		}
+		if class.DetailedStats != nil && class.DetailedStats.RedirectedPackets > 0 {
+			classView.DetailedStats["redirected_bytes"] = class.DetailedStats.RedirectedBytes
+			classView.DetailedStats["redirected_packets"] = class.DetailedStats.RedirectedPackets
+		}
+		if class.LeafQdisc != nil {
+			leafView := qhandlers.NewQdiscStatisticsView(class.LeafQdisc.Handle, class.LeafQdisc.Type, class.LeafQdisc.Stats, class.LeafQdisc.DetailedStats)
+			leafView.Rates = rateView(class.LeafQdisc.Rates)
+			classView.LeafQdisc = &leafView
+		}
 
 		view.ClassStats = append(view.ClassStats, classView)
 	}
@@ -484,8 +1355,13 @@ func convertApplicationStatsToView(stats *DeviceStatistics) qmodels.DeviceStatis
 	return view
 }
 
-// publishEvent publishes an event to the event bus
+// publishEvent publishes an event to the event bus, unless the context records events the
+// kernel already holds
 func (s *TrafficControlService) publishEvent(ctx context.Context, event interface{}) error {
+	if unpublished(ctx) {
+		return nil
+	}
+
 	// Determine event type from the event itself
 	eventType := ""
 	switch event.(type) {
@@ -493,12 +1369,38 @@ func (s *TrafficControlService) publishEvent(ctx context.Context, event interfac
 		eventType = "QdiscCreated"
 	case *events.HTBQdiscCreatedEvent:
 		eventType = "HTBQdiscCreated"
+	case *events.FQCODELQdiscCreatedEvent:
+		eventType = "QdiscCreated"
+	case *events.SFQQdiscCreatedEvent:
+		eventType = "QdiscCreated"
+	case *events.FQQdiscCreatedEvent:
+		eventType = "QdiscCreated"
+	case *events.NETEMQdiscCreatedEvent:
+		eventType = "QdiscCreated"
+	case *events.REDQdiscCreatedEvent:
+		eventType = "QdiscCreated"
+	case *events.GREDQdiscCreatedEvent:
+		eventType = "QdiscCreated"
+	case *events.CODELQdiscCreatedEvent:
+		eventType = "QdiscCreated"
+	case *events.ETSQdiscCreatedEvent:
+		eventType = "QdiscCreated"
+	case *events.TAPRIOQdiscCreatedEvent:
+		eventType = "QdiscCreated"
+	case *events.CAKEQdiscCreatedEvent:
+		eventType = "QdiscCreated"
 	case *events.ClassCreatedEvent:
 		eventType = "ClassCreated"
 	case *events.HTBClassCreatedEvent:
 		eventType = "HTBClassCreated"
 	case *events.HTBClassCreatedEventWithAdvancedParameters:
 		eventType = "HTBClassCreated"
+	case *events.HTBClassChangedEvent:
+		eventType = "HTBClassChanged"
+	case *events.DRRClassCreatedEvent:
+		eventType = "ClassCreated"
+	case *events.QFQClassCreatedEvent:
+		eventType = "ClassCreated"
 	case *events.FilterCreatedEvent:
 		eventType = "FilterCreated"
 	default:
@@ -501,6 +501,7 @@ func TestConvertApplicationStatsToView(t *testing.T) {
 		assert.Equal(t, uint32(50), qdisc.QueueLength)
 		assert.Equal(t, uint32(25), qdisc.DetailedStats["htb_direct_packets"])
 		assert.Equal(t, uint32(3), qdisc.DetailedStats["htb_version"])
+		assert.Equal(t, &qmodels.HTBQdiscStatisticsView{DirectPackets: 25, Version: 3}, qdisc.HTB)
 
 		require.Len(t, view.ClassStats, 1)
 		class := view.ClassStats[0]
@@ -15,6 +15,7 @@ import (
 type StatisticsService struct {
 	netlinkAdapter netlink.Adapter
 	readModelStore projections.ReadModelStore
+	rates          *RateCalculator
 	logger         logging.Logger
 }
 
@@ -23,6 +24,7 @@ func NewStatisticsService(netlinkAdapter netlink.Adapter, readModelStore project
 	return &StatisticsService{
 		netlinkAdapter: netlinkAdapter,
 		readModelStore: readModelStore,
+		rates:          NewRateCalculator(),
 		logger:         logging.WithComponent("application.statistics"),
 	}
 }
@@ -43,6 +45,7 @@ type QdiscStatistics struct {
 	Type          string                      `json:"type"`
 	Stats         netlink.QdiscStats          `json:"stats"`
 	DetailedStats *netlink.DetailedQdiscStats `json:"detailed_stats,omitempty"`
+	Rates         *CounterRates               `json:"rates,omitempty"` // Since the previous sample; nil on the first
 }
 
 // ClassStatistics represents class statistics with metadata
@@ -52,6 +55,8 @@ type ClassStatistics struct {
 	Name          string                      `json:"name"`
 	Stats         netlink.ClassStats          `json:"stats"`
 	DetailedStats *netlink.DetailedClassStats `json:"detailed_stats,omitempty"`
+	LeafQdisc     *QdiscStatistics            `json:"leaf_qdisc,omitempty"`
+	Rates         *CounterRates               `json:"rates,omitempty"` // Since the previous sample; nil on the first
 }
 
 // FilterStatistics represents filter statistics with metadata
@@ -144,7 +149,7 @@ func (s *StatisticsService) GetDeviceStatistics(ctx context.Context, deviceName
 
 	// Get class statistics
 	for _, class := range readModel.Classes {
-		_, err := tc.ParseHandle(class.Handle)
+		handle, err := tc.ParseHandle(class.Handle)
 		if err != nil {
 			s.logger.Warn("Invalid class handle",
 				logging.String("handle", class.Handle),
@@ -175,6 +180,8 @@ func (s *StatisticsService) GetDeviceStatistics(ctx context.Context, deviceName
 					//     }
 					// }
 
+					classStat.LeafQdisc = s.leafQdiscStatistics(device, handle)
+
 					stats.ClassStats = append(stats.ClassStats, classStat)
 					break
 				}
@@ -199,6 +206,7 @@ func (s *StatisticsService) GetDeviceStatistics(ctx context.Context, deviceName
 		logging.Int("classes", len(stats.ClassStats)),
 		logging.Int("filters", len(stats.FilterStats)))
 
+	s.rates.observe(stats)
 	return stats, nil
 }
 
@@ -235,17 +243,47 @@ func (s *StatisticsService) GetRealtimeStatistics(ctx context.Context, deviceNam
 	if classResult.IsSuccess() {
 		for _, info := range classResult.Value() {
 			classStat := ClassStatistics{
-				Handle: info.Handle.String(),
-				Parent: info.Parent.String(),
-				Stats:  info.Statistics,
+				Handle:    info.Handle.String(),
+				Parent:    info.Parent.String(),
+				Stats:     info.Statistics,
+				LeafQdisc: s.leafQdiscStatistics(device, info.Handle),
 			}
 			stats.ClassStats = append(stats.ClassStats, classStat)
 		}
 	}
 
+	s.rates.observe(stats)
 	return stats, nil
 }
 
+// leafQdiscStatistics returns the statistics of the qdisc attached under a class, or nil
+// when the class has none
+func (s *StatisticsService) leafQdiscStatistics(device tc.DeviceName, class tc.Handle) *QdiscStatistics {
+	qdiscs := s.netlinkAdapter.GetQdiscs(device)
+	if !qdiscs.IsSuccess() {
+		return nil
+	}
+
+	for _, info := range qdiscs.Value() {
+		if info.Parent == nil || !info.Parent.Equals(class) {
+			continue
+		}
+
+		leaf := &QdiscStatistics{
+			Handle: info.Handle.String(),
+			Type:   info.Type.String(),
+			Stats:  info.Statistics,
+		}
+		if detailedResult := s.netlinkAdapter.GetDetailedQdiscStats(device, info.Handle); detailedResult.IsSuccess() {
+			detailedStats := detailedResult.Value()
+			leaf.DetailedStats = &detailedStats
+		}
+		return leaf
+	}
+
+	return nil
+}
+
 // MonitorStatistics continuously monitors statistics
 func (s *StatisticsService) MonitorStatistics(ctx context.Context, deviceName string, interval time.Duration, callback func(*DeviceStatistics)) error {
 	ticker := time.NewTicker(interval)
@@ -0,0 +1,202 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/rng999/traffic-control-go/internal/domain/aggregates"
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// Transaction groups the changes made to a device so they can be undone together.
+// Rollback removes every netlink object created since BeginTransaction, restores the classes
+// changed in place, re-creates the objects deleted, and records compensating events so the
+// domain model matches the kernel again. Objects deleted that the event store holds no
+// creation event for, such as those of other tools, cannot be re-created.
+type Transaction struct {
+	service      *TrafficControlService
+	device       tc.DeviceName
+	startVersion int
+	done         bool
+}
+
+// BeginTransaction starts a transaction for the given device
+func (s *TrafficControlService) BeginTransaction(ctx context.Context, device string) (*Transaction, error) {
+	deviceName, err := tc.NewDeviceName(device)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+
+	aggregate := aggregates.NewTrafficControlAggregate(deviceName)
+	if err := s.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return nil, fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	if err := s.journal.Begin(deviceName); err != nil {
+		return nil, err
+	}
+
+	return &Transaction{
+		service:      s,
+		device:       deviceName,
+		startVersion: aggregate.GetVersion(),
+	}, nil
+}
+
+// Commit keeps all changes made during the transaction
+func (t *Transaction) Commit() error {
+	if t.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	t.done = true
+
+	t.service.journal.Commit(t.device)
+	return nil
+}
+
+// Rollback undoes all changes made during the transaction
+func (t *Transaction) Rollback(ctx context.Context) error {
+	if t.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	t.done = true
+
+	logger := t.service.logger
+	logger.Warn("Rolling back traffic control changes",
+		logging.String("device", t.device.String()),
+		logging.Int("netlink_operations", len(t.service.journal.Operations(t.device))),
+	)
+
+	netlinkErr := t.service.journal.Rollback(t.device)
+	domainErr := t.compensate(ctx)
+
+	return errors.Join(netlinkErr, domainErr)
+}
+
+// unpublishedKey marks the context of events recorded without being published, because the
+// kernel already holds what they describe: the compensating events of a rollback, which the
+// netlink journal has applied, and the objects adopted from the kernel by Reconcile
+type unpublishedKey struct{}
+
+// withoutPublishing returns a context recording events without publishing them
+func withoutPublishing(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unpublishedKey{}, true)
+}
+
+// unpublished reports whether the context records events without publishing them
+func unpublished(ctx context.Context) bool {
+	marked, _ := ctx.Value(unpublishedKey{}).(bool)
+	return marked
+}
+
+// compensate appends delete events for everything created after the transaction began,
+// change events restoring the classes changed since, and the creation events of the objects
+// deleted since
+func (t *Transaction) compensate(ctx context.Context) error {
+	aggregate := aggregates.NewTrafficControlAggregate(t.device)
+	if err := t.service.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	history, err := t.service.eventStore.GetEvents(aggregate.GetID())
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+	created, err := t.service.eventStore.GetEventsFromVersion(aggregate.GetID(), t.startVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load transaction events: %w", err)
+	}
+	before := recordedObjects(history[:len(history)-len(created)])
+	restore := func(key string) error {
+		object, ok := before[key]
+		if !ok {
+			// Created during the transaction as well
+			return nil
+		}
+		return aggregate.Record(copyEvent(object.created))
+	}
+
+	// Undo in reverse order so filters go before classes and classes before qdiscs
+	for i := len(created) - 1; i >= 0; i-- {
+		var err error
+		switch e := created[i].(type) {
+		case *events.FilterCreatedEvent:
+			err = aggregate.DeleteFilter(e.Parent, e.Priority, e.Handle)
+		case *events.HTBClassCreatedEvent:
+			err = aggregate.DeleteClass(e.Handle)
+		case *events.HTBClassCreatedEventWithAdvancedParameters:
+			err = aggregate.DeleteClass(e.Handle)
+		case *events.HTBClassChangedEvent:
+			err = aggregate.ChangeHTBClass(e.Current, e.Previous)
+		case *events.DRRClassCreatedEvent:
+			err = aggregate.DeleteClass(e.Handle)
+		case *events.QFQClassCreatedEvent:
+			err = aggregate.DeleteClass(e.Handle)
+		case *events.QdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.HTBQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.TBFQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.PRIOQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.FQCODELQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.SFQQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.FQQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.NETEMQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.REDQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.GREDQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.CODELQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.ETSQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.TAPRIOQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.CAKEQdiscCreatedEvent:
+			err = aggregate.DeleteQdisc(e.Handle)
+		case *events.FilterDeletedEvent:
+			for key, object := range before {
+				if object.kind == kindFilter && *object.parent == e.Parent &&
+					object.priority == e.Priority && object.handle == e.Handle {
+					err = restore(key)
+				}
+			}
+		case *events.ClassDeletedEvent:
+			err = restore(classKey(e.Handle))
+		case *events.QdiscDeletedEvent:
+			err = restore(qdiscKey(e.Handle))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to compensate event %s: %w", created[i].EventType(), err)
+		}
+	}
+
+	// The netlink rollback has put the kernel back already
+	if err := t.service.eventStore.SaveAggregate(withoutPublishing(ctx), aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// copyEvent returns a copy of an event, so that recording it again leaves the stored one as
+// it was
+func copyEvent(event events.DomainEvent) events.DomainEvent {
+	value := reflect.ValueOf(event)
+	if value.Kind() != reflect.Ptr {
+		return event
+	}
+	copied := reflect.New(value.Elem().Type())
+	copied.Elem().Set(value.Elem())
+	return copied.Interface().(events.DomainEvent)
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/internal/commands/models"
+	"github.com/rng999/traffic-control-go/internal/domain/aggregates"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// CreateDRRQdiscHandler handles CreateDRRQdiscCommand with type safety
+type CreateDRRQdiscHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateDRRQdiscHandler creates a new type-safe DRR qdisc handler
+func NewCreateDRRQdiscHandler(eventStore eventstore.EventStoreWithContext) *CreateDRRQdiscHandler {
+	return &CreateDRRQdiscHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateDRRQdiscCommand with compile-time type safety
+func (h *CreateDRRQdiscHandler) HandleTyped(ctx context.Context, command *models.CreateDRRQdiscCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handles
+	handle, err := tc.ParseHandle(command.Handle)
+	if err != nil {
+		return fmt.Errorf("invalid handle format: %w", err)
+	}
+	parent, err := parseParentHandle(command.Parent)
+	if err != nil {
+		return err
+	}
+
+	// Execute business logic
+	if err := aggregate.AddDRRQdisc(parent, handle); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// CreateDRRClassHandler handles CreateDRRClassCommand with type safety
+type CreateDRRClassHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateDRRClassHandler creates a new type-safe DRR class handler
+func NewCreateDRRClassHandler(eventStore eventstore.EventStoreWithContext) *CreateDRRClassHandler {
+	return &CreateDRRClassHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateDRRClassCommand with compile-time type safety
+func (h *CreateDRRClassHandler) HandleTyped(ctx context.Context, command *models.CreateDRRClassCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handles
+	parentHandle, err := tc.ParseHandle(command.Parent)
+	if err != nil {
+		return fmt.Errorf("invalid parent handle: %w", err)
+	}
+	classHandle, err := tc.ParseHandle(command.ClassID)
+	if err != nil {
+		return fmt.Errorf("invalid class handle: %w", err)
+	}
+
+	className := command.Name
+	if className == "" {
+		className = command.ClassID
+	}
+
+	// Execute business logic
+	if err := aggregate.AddDRRClass(parentHandle, classHandle, className, command.Quantum); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// CreateQFQQdiscHandler handles CreateQFQQdiscCommand with type safety
+type CreateQFQQdiscHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateQFQQdiscHandler creates a new type-safe QFQ qdisc handler
+func NewCreateQFQQdiscHandler(eventStore eventstore.EventStoreWithContext) *CreateQFQQdiscHandler {
+	return &CreateQFQQdiscHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateQFQQdiscCommand with compile-time type safety
+func (h *CreateQFQQdiscHandler) HandleTyped(ctx context.Context, command *models.CreateQFQQdiscCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handles
+	handle, err := tc.ParseHandle(command.Handle)
+	if err != nil {
+		return fmt.Errorf("invalid handle format: %w", err)
+	}
+	parent, err := parseParentHandle(command.Parent)
+	if err != nil {
+		return err
+	}
+
+	// Execute business logic
+	if err := aggregate.AddQFQQdisc(parent, handle); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// CreateQFQClassHandler handles CreateQFQClassCommand with type safety
+type CreateQFQClassHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateQFQClassHandler creates a new type-safe QFQ class handler
+func NewCreateQFQClassHandler(eventStore eventstore.EventStoreWithContext) *CreateQFQClassHandler {
+	return &CreateQFQClassHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateQFQClassCommand with compile-time type safety
+func (h *CreateQFQClassHandler) HandleTyped(ctx context.Context, command *models.CreateQFQClassCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handles
+	parentHandle, err := tc.ParseHandle(command.Parent)
+	if err != nil {
+		return fmt.Errorf("invalid parent handle: %w", err)
+	}
+	classHandle, err := tc.ParseHandle(command.ClassID)
+	if err != nil {
+		return fmt.Errorf("invalid class handle: %w", err)
+	}
+
+	className := command.Name
+	if className == "" {
+		className = command.ClassID
+	}
+
+	// Execute business logic
+	if err := aggregate.AddQFQClass(parentHandle, classHandle, className, command.Weight, command.Lmax); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
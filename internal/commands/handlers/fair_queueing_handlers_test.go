@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/commands/models"
+	"github.com/rng999/traffic-control-go/internal/domain/aggregates"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestCreateQFQClassHandler(t *testing.T) {
+	store := eventstore.NewMemoryEventStoreWithContext()
+	ctx := context.Background()
+
+	require.NoError(t, NewCreateQFQQdiscHandler(store).HandleTyped(ctx, &models.CreateQFQQdiscCommand{
+		DeviceName: "eth0",
+		Handle:     "1:0",
+	}))
+
+	classHandler := NewCreateQFQClassHandler(store)
+	require.NoError(t, classHandler.HandleTyped(ctx, &models.CreateQFQClassCommand{
+		DeviceName: "eth0",
+		Parent:     "1:0",
+		ClassID:    "1:1",
+		Name:       "video",
+		Weight:     4,
+		Lmax:       9000,
+	}))
+
+	// A DRR class cannot join a QFQ qdisc
+	err := NewCreateDRRClassHandler(store).HandleTyped(ctx, &models.CreateDRRClassCommand{
+		DeviceName: "eth0",
+		Parent:     "1:0",
+		ClassID:    "1:2",
+		Quantum:    1500,
+	})
+	assert.ErrorContains(t, err, "must be drr type")
+
+	deviceName, _ := tc.NewDeviceName("eth0")
+	aggregate := aggregates.NewTrafficControlAggregate(deviceName)
+	require.NoError(t, store.Load(ctx, aggregate.GetID(), aggregate))
+
+	assert.Equal(t, entities.QdiscTypeQFQ, aggregate.GetQdiscs()[tc.NewHandle(1, 0)].Type())
+	class, exists := aggregate.GetClasses()[tc.NewHandle(1, 1)]
+	require.True(t, exists)
+	assert.Equal(t, "video", class.Name())
+}
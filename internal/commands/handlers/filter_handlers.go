@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/rng999/traffic-control-go/internal/commands/models"
 	"github.com/rng999/traffic-control-go/internal/domain/aggregates"
@@ -161,6 +162,8 @@ func getProtocolNumber(protocol string) uint8 {
 		return 17
 	case "icmp", "ICMP":
 		return 1
+	case "sctp", "SCTP":
+		return 132
 	default:
 		// Try to parse as number
 		if num, err := strconv.ParseUint(protocol, 10, 8); err == nil {
@@ -168,4 +171,213 @@ func getProtocolNumber(protocol string) uint8 {
 		}
 		return 0
 	}
-}
\ No newline at end of file
+}
+
+// ParseFilterMatches converts the match map of a CreateFilterCommand to filter matches.
+// Malformed addresses and ports are skipped; other malformed matches are errors.
+func ParseFilterMatches(conditions map[string]string) ([]entities.Match, error) {
+	matches := make([]entities.Match, 0, len(conditions))
+	for key, value := range conditions {
+		switch key {
+		case "src_ip":
+			if match, err := entities.NewIPSourceMatch(value); err == nil {
+				matches = append(matches, match)
+			}
+		case "dst_ip":
+			if match, err := entities.NewIPDestinationMatch(value); err == nil {
+				matches = append(matches, match)
+			}
+		case "src_port":
+			if port, err := strconv.ParseUint(value, 10, 16); err == nil {
+				match := entities.NewPortSourceMatch(uint16(port))
+				matches = append(matches, match)
+			}
+		case "dst_port":
+			if port, err := strconv.ParseUint(value, 10, 16); err == nil {
+				match := entities.NewPortDestinationMatch(uint16(port))
+				matches = append(matches, match)
+			}
+		case "cgroup":
+			match, err := entities.NewCgroupMatch(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cgroup match: %w", err)
+			}
+			matches = append(matches, match)
+		default:
+			match, err := parseHeaderMatch(key, value)
+			if match == nil && err == nil {
+				match, err = parseFlowerMatch(key, value)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s match: %w", key, err)
+			}
+			if match != nil {
+				matches = append(matches, match)
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+// parseHeaderMatch converts a protocol, tos, dscp, tcp_flags, len or raw@<offset> match of
+// a CreateFilterCommand; masked values are written "<value>/<mask>", the mask defaulting
+// to all bits. Unknown keys yield no match.
+func parseHeaderMatch(key, value string) (entities.Match, error) {
+	switch {
+	case key == "protocol":
+		protocol := getProtocolNumber(value)
+		if protocol == 0 {
+			return nil, fmt.Errorf("unknown protocol %q", value)
+		}
+		return entities.NewProtocolMatch(entities.TransportProtocol(protocol)), nil
+	case key == "tos":
+		tos, mask, err := parseMaskedValue(value, 8)
+		if err != nil {
+			return nil, err
+		}
+		return entities.NewTOSMaskMatch(uint8(tos), uint8(mask)), nil
+	case key == "dscp":
+		dscp, err := strconv.ParseUint(value, 0, 8)
+		if err != nil || dscp > 63 {
+			return nil, fmt.Errorf("DSCP must be between 0 and 63, got %q", value)
+		}
+		return entities.NewDSCPMatch(uint8(dscp)), nil
+	case key == "tcp_flags":
+		flags, mask, err := parseMaskedValue(value, 8)
+		if err != nil {
+			return nil, err
+		}
+		return entities.NewTCPFlagsMatch(uint8(flags), uint8(mask)), nil
+	case key == "len":
+		length, mask, err := parseMaskedValue(value, 16)
+		if err != nil {
+			return nil, err
+		}
+		return entities.NewPacketLengthMatch(uint16(length), uint16(mask)), nil
+	case strings.HasPrefix(key, "raw@"):
+		offset, err := strconv.ParseInt(strings.TrimPrefix(key, "raw@"), 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset in %q", key)
+		}
+		word, mask, err := parseMaskedValue(value, 32)
+		if err != nil {
+			return nil, err
+		}
+		return entities.NewRawMatch(int32(offset), uint32(word), uint32(mask))
+	default:
+		return nil, nil
+	}
+}
+
+// parseFlowerMatch converts a src_mac, dst_mac, vlan_id, vlan_prio, src_port_range or
+// dst_port_range match of a CreateFilterCommand; ranges are written "<min>-<max>". Unknown
+// keys yield no match.
+func parseFlowerMatch(key, value string) (entities.Match, error) {
+	switch key {
+	case "src_mac":
+		return entities.NewMACSourceMatch(value)
+	case "dst_mac":
+		return entities.NewMACDestinationMatch(value)
+	case "vlan_id":
+		id, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid VLAN ID %q", value)
+		}
+		return entities.NewVLANIDMatch(uint16(id))
+	case "vlan_prio":
+		priority, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid VLAN priority %q", value)
+		}
+		return entities.NewVLANPriorityMatch(uint8(priority))
+	case "src_port_range", "dst_port_range":
+		minPart, maxPart, _ := strings.Cut(value, "-")
+		start, err := strconv.ParseUint(minPart, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port range %q", value)
+		}
+		end, err := strconv.ParseUint(maxPart, 10, 16)
+		if err != nil || start == 0 || start > end {
+			return nil, fmt.Errorf("invalid port range %q", value)
+		}
+		if key == "src_port_range" {
+			return entities.NewPortSourceRangeMatch(uint16(start), uint16(end)), nil
+		}
+		return entities.NewPortDestinationRangeMatch(uint16(start), uint16(end)), nil
+	default:
+		return nil, nil
+	}
+}
+
+// parseMaskedValue parses "<value>[/<mask>]" with values of the given bit size
+func parseMaskedValue(s string, bitSize int) (uint64, uint64, error) {
+	valuePart, maskPart, masked := strings.Cut(s, "/")
+	value, err := strconv.ParseUint(valuePart, 0, bitSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid value %q", valuePart)
+	}
+	mask := uint64(1)<<bitSize - 1
+	if masked {
+		if mask, err = strconv.ParseUint(maskPart, 0, bitSize); err != nil {
+			return 0, 0, fmt.Errorf("invalid mask %q", maskPart)
+		}
+	}
+	return value, mask, nil
+}
+
+// ParseFilterActions converts command action descriptions to filter actions
+func ParseFilterActions(specs []models.FilterAction) ([]entities.Action, error) {
+	actions := make([]entities.Action, 0, len(specs))
+	for i, spec := range specs {
+		switch spec.Type {
+		case "mark":
+			mask := spec.Mask
+			if mask == 0 {
+				mask = 0xFFFFFFFF
+			}
+			actions = append(actions, entities.NewMarkAction(spec.Mark, mask))
+		case "police":
+			rate, err := tc.ParseBandwidth(spec.Rate)
+			if err != nil {
+				return nil, fmt.Errorf("invalid police rate in action %d: %w", i+1, err)
+			}
+			exceed, err := entities.ParsePoliceExceedAction(spec.Exceed)
+			if err != nil {
+				return nil, fmt.Errorf("invalid action %d: %w", i+1, err)
+			}
+			actions = append(actions, entities.NewPoliceAction(rate, spec.Burst, exceed))
+		case "mirror", "redirect":
+			target, err := tc.NewDeviceName(spec.Device)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s target in action %d: %w", spec.Type, i+1, err)
+			}
+			if spec.Type == "mirror" {
+				actions = append(actions, entities.NewMirrorAction(target))
+			} else {
+				actions = append(actions, entities.NewRedirectAction(target))
+			}
+		case "dscp":
+			if spec.DSCP > 63 {
+				return nil, fmt.Errorf("invalid DSCP in action %d: must be between 0 and 63, got %d", i+1, spec.DSCP)
+			}
+			actions = append(actions, entities.NewDSCPRemarkAction(spec.DSCP))
+		case "drop":
+			actions = append(actions, entities.NewDropAction())
+		case "pass":
+			actions = append(actions, entities.NewPassAction())
+		case "priority":
+			priority, err := tc.ParseHandle(spec.Handle)
+			if err != nil {
+				return nil, fmt.Errorf("invalid priority in action %d: %w", i+1, err)
+			}
+			actions = append(actions, entities.NewPriorityAction(priority))
+		case "queue_mapping":
+			actions = append(actions, entities.NewQueueMappingAction(spec.Queue))
+		default:
+			return nil, fmt.Errorf("unknown filter action type %q", spec.Type)
+		}
+	}
+
+	return actions, nil
+}
@@ -3,7 +3,6 @@ package handlers
 import (
 	"context"
 	"fmt"
-	"strconv"
 
 	"github.com/rng999/traffic-control-go/internal/commands/models"
 	"github.com/rng999/traffic-control-go/internal/domain/aggregates"
@@ -127,6 +126,11 @@ func (h *CreateHTBClassHandler) HandleTyped(ctx context.Context, command *models
 		priority = entities.Priority(4) // Default to normal priority
 	}
 
+	profile, err := entities.ParseTuningProfile(command.Profile)
+	if err != nil {
+		return fmt.Errorf("invalid tuning profile: %w", err)
+	}
+
 	// Create HTB class with comprehensive parameters
 	if err := aggregate.AddHTBClassWithAdvancedParameters(
 		parentHandle,
@@ -145,6 +149,7 @@ func (h *CreateHTBClassHandler) HandleTyped(ctx context.Context, command *models
 		command.MTU,
 		command.HTBPrio,
 		command.UseDefaults,
+		profile,
 	); err != nil {
 		return err
 	}
@@ -197,41 +202,33 @@ func (h *CreateFilterHandler) HandleTyped(ctx context.Context, command *models.C
 		return fmt.Errorf("invalid flow ID handle: %w", err)
 	}
 
+	kind, err := entities.ParseFilterKind(command.Kind)
+	if err != nil {
+		return err
+	}
+
 	// Create a handle for the filter (using priority as a simple approach)
 	filterHandle := tc.NewHandle(0x800, uint16(command.Priority))
 
-	// Convert map matches to entities.Match
-	matches := make([]entities.Match, 0, len(command.Match))
-	for key, value := range command.Match {
-		switch key {
-		case "src_ip":
-			if match, err := entities.NewIPSourceMatch(value); err == nil {
-				matches = append(matches, match)
-			}
-		case "dst_ip":
-			if match, err := entities.NewIPDestinationMatch(value); err == nil {
-				matches = append(matches, match)
-			}
-		case "src_port":
-			if port, err := strconv.ParseUint(value, 10, 16); err == nil {
-				match := entities.NewPortSourceMatch(uint16(port))
-				matches = append(matches, match)
-			}
-		case "dst_port":
-			if port, err := strconv.ParseUint(value, 10, 16); err == nil {
-				match := entities.NewPortDestinationMatch(uint16(port))
-				matches = append(matches, match)
-			}
-		}
+	matches, err := ParseFilterMatches(command.Match)
+	if err != nil {
+		return err
+	}
+
+	actions, err := ParseFilterActions(command.Actions)
+	if err != nil {
+		return err
 	}
 
 	// Execute business logic
-	if err := aggregate.AddFilter(
+	if err := aggregate.AddFilterOfKind(
+		kind,
 		parentHandle,
 		command.Priority,
 		filterHandle,
 		flowHandle,
 		matches,
+		actions,
 	); err != nil {
 		return err
 	}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/rng999/traffic-control-go/internal/commands/models"
 	"github.com/rng999/traffic-control-go/internal/domain/aggregates"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
 	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
 	"github.com/rng999/traffic-control-go/pkg/tc"
 )
@@ -139,7 +140,15 @@ func (h *CreateFQCODELQdiscHandler) HandleTyped(ctx context.Context, command *mo
 	}
 
 	// Execute business logic
-	if err := aggregate.AddFQCODELQdisc(handle, command.Limit, command.Flows, command.Target, command.Interval, command.Quantum, command.ECN); err != nil {
+	if command.Parent != "" {
+		parentHandle, err := tc.ParseHandle(command.Parent)
+		if err != nil {
+			return fmt.Errorf("invalid parent handle: %w", err)
+		}
+		if err := aggregate.AddLeafFQCODELQdisc(parentHandle, handle, command.Limit, command.Flows, command.Target, command.Interval, command.Quantum, command.ECN); err != nil {
+			return err
+		}
+	} else if err := aggregate.AddFQCODELQdisc(handle, command.Limit, command.Flows, command.Target, command.Interval, command.Quantum, command.ECN); err != nil {
 		return err
 	}
 
@@ -150,3 +159,579 @@ func (h *CreateFQCODELQdiscHandler) HandleTyped(ctx context.Context, command *mo
 
 	return nil
 }
+
+// CreateCAKEQdiscHandler handles CreateCAKEQdiscCommand with type safety
+type CreateCAKEQdiscHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateCAKEQdiscHandler creates a new type-safe CAKE handler
+func NewCreateCAKEQdiscHandler(eventStore eventstore.EventStoreWithContext) *CreateCAKEQdiscHandler {
+	return &CreateCAKEQdiscHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateCAKEQdiscCommand with compile-time type safety
+func (h *CreateCAKEQdiscHandler) HandleTyped(ctx context.Context, command *models.CreateCAKEQdiscCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handle
+	handle, err := tc.ParseHandle(command.Handle)
+	if err != nil {
+		return fmt.Errorf("invalid handle format: %w", err)
+	}
+
+	// Parse bandwidth; an empty bandwidth leaves the qdisc unshaped
+	var bandwidth tc.Bandwidth
+	if command.Bandwidth != "" {
+		bandwidth, err = tc.ParseBandwidth(command.Bandwidth)
+		if err != nil {
+			return fmt.Errorf("invalid bandwidth: %w", err)
+		}
+	}
+
+	diffserv, err := entities.ParseCAKEDiffservMode(command.Diffserv)
+	if err != nil {
+		return err
+	}
+	ackFilter, err := entities.ParseCAKEAckFilter(command.AckFilter)
+	if err != nil {
+		return err
+	}
+
+	// Execute business logic
+	if err := aggregate.AddCAKEQdisc(handle, bandwidth, command.RTT, diffserv, command.NAT, command.Wash, ackFilter); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// CreateSFQQdiscHandler handles CreateSFQQdiscCommand with type safety
+type CreateSFQQdiscHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateSFQQdiscHandler creates a new type-safe SFQ handler
+func NewCreateSFQQdiscHandler(eventStore eventstore.EventStoreWithContext) *CreateSFQQdiscHandler {
+	return &CreateSFQQdiscHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateSFQQdiscCommand with compile-time type safety
+func (h *CreateSFQQdiscHandler) HandleTyped(ctx context.Context, command *models.CreateSFQQdiscCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handles
+	handle, err := tc.ParseHandle(command.Handle)
+	if err != nil {
+		return fmt.Errorf("invalid handle format: %w", err)
+	}
+	parent, err := parseParentHandle(command.Parent)
+	if err != nil {
+		return err
+	}
+
+	// Execute business logic
+	if err := aggregate.AddSFQQdisc(parent, handle, command.Perturb, command.Quantum, command.Limit); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// CreateFQQdiscHandler handles CreateFQQdiscCommand with type safety
+type CreateFQQdiscHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateFQQdiscHandler creates a new type-safe FQ handler
+func NewCreateFQQdiscHandler(eventStore eventstore.EventStoreWithContext) *CreateFQQdiscHandler {
+	return &CreateFQQdiscHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateFQQdiscCommand with compile-time type safety
+func (h *CreateFQQdiscHandler) HandleTyped(ctx context.Context, command *models.CreateFQQdiscCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handles
+	handle, err := tc.ParseHandle(command.Handle)
+	if err != nil {
+		return fmt.Errorf("invalid handle format: %w", err)
+	}
+	parent, err := parseParentHandle(command.Parent)
+	if err != nil {
+		return err
+	}
+
+	// Parse the per-flow rate cap; an empty rate leaves flows uncapped
+	var maxRate tc.Bandwidth
+	if command.MaxRate != "" {
+		maxRate, err = tc.ParseBandwidth(command.MaxRate)
+		if err != nil {
+			return fmt.Errorf("invalid max rate: %w", err)
+		}
+	}
+
+	// Execute business logic
+	if err := aggregate.AddFQQdisc(parent, handle, command.Limit, command.FlowLimit, command.Quantum,
+		command.InitialQuantum, maxRate, command.Pacing); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// CreateNETEMQdiscHandler handles CreateNETEMQdiscCommand with type safety
+type CreateNETEMQdiscHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateNETEMQdiscHandler creates a new type-safe NETEM handler
+func NewCreateNETEMQdiscHandler(eventStore eventstore.EventStoreWithContext) *CreateNETEMQdiscHandler {
+	return &CreateNETEMQdiscHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateNETEMQdiscCommand with compile-time type safety
+func (h *CreateNETEMQdiscHandler) HandleTyped(ctx context.Context, command *models.CreateNETEMQdiscCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handles
+	handle, err := tc.ParseHandle(command.Handle)
+	if err != nil {
+		return fmt.Errorf("invalid handle format: %w", err)
+	}
+	parent, err := parseParentHandle(command.Parent)
+	if err != nil {
+		return err
+	}
+
+	// Execute business logic
+	params := entities.NETEMParameters{
+		Delay:     command.Delay,
+		Jitter:    command.Jitter,
+		Loss:      command.Loss,
+		Duplicate: command.Duplicate,
+		Corrupt:   command.Corrupt,
+		Reorder:   command.Reorder,
+		Gap:       command.Gap,
+		Limit:     command.Limit,
+	}
+	if err := aggregate.AddNETEMQdisc(parent, handle, params); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// CreateREDQdiscHandler handles CreateREDQdiscCommand with type safety
+type CreateREDQdiscHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateREDQdiscHandler creates a new type-safe RED handler
+func NewCreateREDQdiscHandler(eventStore eventstore.EventStoreWithContext) *CreateREDQdiscHandler {
+	return &CreateREDQdiscHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateREDQdiscCommand with compile-time type safety
+func (h *CreateREDQdiscHandler) HandleTyped(ctx context.Context, command *models.CreateREDQdiscCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handles
+	handle, err := tc.ParseHandle(command.Handle)
+	if err != nil {
+		return fmt.Errorf("invalid handle format: %w", err)
+	}
+	parent, err := parseParentHandle(command.Parent)
+	if err != nil {
+		return err
+	}
+
+	bandwidth, err := tc.ParseBandwidth(command.Bandwidth)
+	if err != nil {
+		return fmt.Errorf("invalid bandwidth: %w", err)
+	}
+
+	// Execute business logic
+	params := entities.REDParameters{
+		REDThresholds: entities.REDThresholds{
+			Limit:       command.Limit,
+			Min:         command.Min,
+			Max:         command.Max,
+			Avpkt:       command.Avpkt,
+			Burst:       command.Burst,
+			Bandwidth:   bandwidth,
+			Probability: command.Probability,
+		},
+		ECN:      command.ECN,
+		HardDrop: command.HardDrop,
+		Adaptive: command.Adaptive,
+	}
+	if err := aggregate.AddREDQdisc(parent, handle, params); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// CreateGREDQdiscHandler handles CreateGREDQdiscCommand with type safety
+type CreateGREDQdiscHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateGREDQdiscHandler creates a new type-safe GRED handler
+func NewCreateGREDQdiscHandler(eventStore eventstore.EventStoreWithContext) *CreateGREDQdiscHandler {
+	return &CreateGREDQdiscHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateGREDQdiscCommand with compile-time type safety
+func (h *CreateGREDQdiscHandler) HandleTyped(ctx context.Context, command *models.CreateGREDQdiscCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handles
+	handle, err := tc.ParseHandle(command.Handle)
+	if err != nil {
+		return fmt.Errorf("invalid handle format: %w", err)
+	}
+	parent, err := parseParentHandle(command.Parent)
+	if err != nil {
+		return err
+	}
+
+	// Execute business logic
+	params := entities.GREDParameters{
+		Queues:        command.Queues,
+		Default:       command.Default,
+		GRIO:          command.GRIO,
+		ECN:           command.ECN,
+		HardDrop:      command.HardDrop,
+		VirtualQueues: make([]entities.GREDVirtualQueue, 0, len(command.VirtualQueues)),
+	}
+	for _, vq := range command.VirtualQueues {
+		bandwidth, err := tc.ParseBandwidth(vq.Bandwidth)
+		if err != nil {
+			return fmt.Errorf("invalid bandwidth of virtual queue %d: %w", vq.DP, err)
+		}
+		params.VirtualQueues = append(params.VirtualQueues, entities.GREDVirtualQueue{
+			REDThresholds: entities.REDThresholds{
+				Limit:       vq.Limit,
+				Min:         vq.Min,
+				Max:         vq.Max,
+				Avpkt:       vq.Avpkt,
+				Burst:       vq.Burst,
+				Bandwidth:   bandwidth,
+				Probability: vq.Probability,
+			},
+			DP:       vq.DP,
+			Priority: vq.Priority,
+		})
+	}
+	if err := aggregate.AddGREDQdisc(parent, handle, params); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// CreateCODELQdiscHandler handles CreateCODELQdiscCommand with type safety
+type CreateCODELQdiscHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateCODELQdiscHandler creates a new type-safe CoDel handler
+func NewCreateCODELQdiscHandler(eventStore eventstore.EventStoreWithContext) *CreateCODELQdiscHandler {
+	return &CreateCODELQdiscHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateCODELQdiscCommand with compile-time type safety
+func (h *CreateCODELQdiscHandler) HandleTyped(ctx context.Context, command *models.CreateCODELQdiscCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handles
+	handle, err := tc.ParseHandle(command.Handle)
+	if err != nil {
+		return fmt.Errorf("invalid handle format: %w", err)
+	}
+	parent, err := parseParentHandle(command.Parent)
+	if err != nil {
+		return err
+	}
+
+	// Execute business logic
+	params := entities.CODELParameters{
+		Limit:       command.Limit,
+		Target:      command.Target,
+		Interval:    command.Interval,
+		CEThreshold: command.CEThreshold,
+		ECN:         command.ECN,
+	}
+	if err := aggregate.AddCODELQdisc(parent, handle, params); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// CreateETSQdiscHandler handles CreateETSQdiscCommand with type safety
+type CreateETSQdiscHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateETSQdiscHandler creates a new type-safe ETS handler
+func NewCreateETSQdiscHandler(eventStore eventstore.EventStoreWithContext) *CreateETSQdiscHandler {
+	return &CreateETSQdiscHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateETSQdiscCommand with compile-time type safety
+func (h *CreateETSQdiscHandler) HandleTyped(ctx context.Context, command *models.CreateETSQdiscCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handles
+	handle, err := tc.ParseHandle(command.Handle)
+	if err != nil {
+		return fmt.Errorf("invalid handle format: %w", err)
+	}
+	parent, err := parseParentHandle(command.Parent)
+	if err != nil {
+		return err
+	}
+
+	// Execute business logic
+	params := entities.ETSParameters{
+		Bands:   command.Bands,
+		Strict:  command.Strict,
+		Quanta:  command.Quanta,
+		Priomap: command.Priomap,
+	}
+	if err := aggregate.AddETSQdisc(parent, handle, params); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// CreateTAPRIOQdiscHandler handles CreateTAPRIOQdiscCommand with type safety
+type CreateTAPRIOQdiscHandler struct {
+	eventStore eventstore.EventStoreWithContext
+}
+
+// NewCreateTAPRIOQdiscHandler creates a new type-safe taprio handler
+func NewCreateTAPRIOQdiscHandler(eventStore eventstore.EventStoreWithContext) *CreateTAPRIOQdiscHandler {
+	return &CreateTAPRIOQdiscHandler{
+		eventStore: eventStore,
+	}
+}
+
+// HandleTyped processes the CreateTAPRIOQdiscCommand with compile-time type safety
+func (h *CreateTAPRIOQdiscHandler) HandleTyped(ctx context.Context, command *models.CreateTAPRIOQdiscCommand) error {
+	// Create device value object
+	device, err := tc.NewDeviceName(command.DeviceName)
+	if err != nil {
+		return fmt.Errorf("invalid device name: %w", err)
+	}
+
+	// Load aggregate
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	if err := h.eventStore.Load(ctx, aggregate.GetID(), aggregate); err != nil {
+		return fmt.Errorf("failed to load aggregate: %w", err)
+	}
+
+	// Parse handle
+	handle, err := tc.ParseHandle(command.Handle)
+	if err != nil {
+		return fmt.Errorf("invalid handle format: %w", err)
+	}
+
+	// Parse the clock and schedule; a fully offloaded schedule runs on the NIC's clock
+	params := entities.TAPRIOParameters{
+		NumTC:              command.NumTC,
+		Priomap:            command.Priomap,
+		Queues:             make([]entities.TAPRIOQueueRange, 0, len(command.Queues)),
+		BaseTime:           command.BaseTime,
+		CycleTime:          command.CycleTime,
+		CycleTimeExtension: command.CycleTimeExtension,
+		Schedule:           make([]entities.TAPRIOScheduleEntry, 0, len(command.Schedule)),
+		TxTimeAssist:       command.TxTimeAssist,
+		FullOffload:        command.FullOffload,
+		TxTimeDelay:        command.TxTimeDelay,
+	}
+	if !command.FullOffload {
+		if params.Clock, err = entities.ParseTAPRIOClock(command.Clock); err != nil {
+			return err
+		}
+	}
+	for _, queues := range command.Queues {
+		params.Queues = append(params.Queues, entities.TAPRIOQueueRange{Count: queues.Count, Offset: queues.Offset})
+	}
+	for i, entry := range command.Schedule {
+		gateCommand, err := entities.ParseTAPRIOGateCommand(entry.Command)
+		if err != nil {
+			return fmt.Errorf("schedule entry %d: %w", i, err)
+		}
+		params.Schedule = append(params.Schedule, entities.TAPRIOScheduleEntry{
+			Command:  gateCommand,
+			GateMask: entry.GateMask,
+			Interval: entry.Interval,
+		})
+	}
+
+	// Execute business logic
+	if err := aggregate.AddTAPRIOQdisc(handle, params); err != nil {
+		return err
+	}
+
+	// Save aggregate
+	if err := h.eventStore.SaveAggregate(ctx, aggregate); err != nil {
+		return fmt.Errorf("failed to save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// parseParentHandle parses the parent class of a leaf qdisc; an empty parent means a root qdisc
+func parseParentHandle(parent string) (*tc.Handle, error) {
+	if parent == "" {
+		return nil, nil
+	}
+
+	handle, err := tc.ParseHandle(parent)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parent handle: %w", err)
+	}
+	return &handle, nil
+}
@@ -227,3 +227,51 @@ func TestCreateFQCODELQdiscHandler_InvalidHandle(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid handle format")
 }
+
+func TestCreateTAPRIOQdiscHandler(t *testing.T) {
+	store := eventstore.NewMemoryEventStoreWithContext()
+	handler := NewCreateTAPRIOQdiscHandler(store)
+	ctx := context.Background()
+
+	cmd := &models.CreateTAPRIOQdiscCommand{
+		DeviceName: "eth0",
+		Handle:     "100:0",
+		NumTC:      2,
+		Priomap:    []uint8{1, 1, 0, 1},
+		Queues:     []models.TAPRIOQueueRange{{Count: 1, Offset: 0}, {Count: 1, Offset: 1}},
+		BaseTime:   1000000000,
+		Clock:      "CLOCK_TAI",
+		Schedule: []models.TAPRIOScheduleEntry{
+			{Command: "S", GateMask: 0x1, Interval: 200000},
+			{Command: "S", GateMask: 0x2, Interval: 800000},
+		},
+	}
+	require.NoError(t, handler.HandleTyped(ctx, cmd))
+
+	aggregate := aggregates.NewTrafficControlAggregate(tc.MustNewDeviceName("eth0"))
+	require.NoError(t, store.Load(ctx, aggregate.GetID(), aggregate))
+	_, exists := aggregate.GetQdiscs()[tc.NewHandle(0x100, 0)]
+	assert.True(t, exists)
+
+	t.Run("rejects an unknown clock", func(t *testing.T) {
+		bad := *cmd
+		bad.DeviceName = "eth1"
+		bad.Clock = "CLOCK_LOCAL"
+		assert.ErrorContains(t, handler.HandleTyped(ctx, &bad), "unknown taprio clock")
+	})
+
+	t.Run("rejects an unknown gate command", func(t *testing.T) {
+		bad := *cmd
+		bad.DeviceName = "eth2"
+		bad.Schedule = []models.TAPRIOScheduleEntry{{Command: "X", GateMask: 0x1, Interval: 1000}}
+		assert.ErrorContains(t, handler.HandleTyped(ctx, &bad), "schedule entry 0")
+	})
+
+	t.Run("full offload needs no clock", func(t *testing.T) {
+		offload := *cmd
+		offload.DeviceName = "eth3"
+		offload.Clock = ""
+		offload.FullOffload = true
+		assert.NoError(t, handler.HandleTyped(ctx, &offload))
+	})
+}
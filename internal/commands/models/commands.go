@@ -44,6 +44,153 @@ type CreateFQCODELQdiscCommand struct {
 	Interval   uint32 // microseconds
 	Quantum    uint32
 	ECN        bool
+	Parent     string // Parent class handle for leaf qdiscs (empty for root)
+}
+
+// CreateSFQQdiscCommand creates an SFQ qdisc
+type CreateSFQQdiscCommand struct {
+	DeviceName string
+	Handle     string
+	Perturb    uint32 // seconds, 0 disables perturbation
+	Quantum    uint32
+	Limit      uint32
+	Parent     string // Parent class handle for leaf qdiscs (empty for root)
+}
+
+// CreateFQQdiscCommand creates an FQ qdisc
+type CreateFQQdiscCommand struct {
+	DeviceName     string
+	Handle         string
+	Limit          uint32
+	FlowLimit      uint32
+	Quantum        uint32
+	InitialQuantum uint32
+	MaxRate        string // per-flow rate cap like "10Mbps", empty for unlimited
+	Pacing         bool
+	Parent         string // Parent class handle for leaf qdiscs (empty for root)
+}
+
+// CreateNETEMQdiscCommand creates a NETEM qdisc
+type CreateNETEMQdiscCommand struct {
+	DeviceName string
+	Handle     string
+	Delay      uint32  // microseconds
+	Jitter     uint32  // microseconds
+	Loss       float32 // percent
+	Duplicate  float32 // percent
+	Corrupt    float32 // percent
+	Reorder    float32 // percent
+	Gap        uint32
+	Limit      uint32
+	Parent     string // Parent class handle for leaf qdiscs (empty for root)
+}
+
+// CreateREDQdiscCommand creates a RED qdisc
+type CreateREDQdiscCommand struct {
+	DeviceName  string
+	Handle      string
+	Limit       uint32 // bytes
+	Min         uint32 // bytes
+	Max         uint32 // bytes
+	Avpkt       uint32 // bytes
+	Burst       uint32 // packets, 0 derives it from the thresholds
+	Bandwidth   string
+	Probability float64
+	ECN         bool
+	HardDrop    bool
+	Adaptive    bool
+	Parent      string // Parent class handle for leaf qdiscs (empty for root)
+}
+
+// CreateGREDQdiscCommand creates a GRED qdisc
+type CreateGREDQdiscCommand struct {
+	DeviceName    string
+	Handle        string
+	Queues        uint32
+	Default       uint32
+	GRIO          bool
+	ECN           bool
+	HardDrop      bool
+	VirtualQueues []GREDVirtualQueue
+	Parent        string // Parent class handle for leaf qdiscs (empty for root)
+}
+
+// GREDVirtualQueue describes one virtual queue of a GRED qdisc
+type GREDVirtualQueue struct {
+	DP          uint32
+	Priority    uint8
+	Limit       uint32 // bytes
+	Min         uint32 // bytes
+	Max         uint32 // bytes
+	Avpkt       uint32 // bytes
+	Burst       uint32 // packets, 0 derives it from the thresholds
+	Bandwidth   string
+	Probability float64
+}
+
+// CreateCODELQdiscCommand creates a CoDel qdisc
+type CreateCODELQdiscCommand struct {
+	DeviceName  string
+	Handle      string
+	Limit       uint32 // packets
+	Target      uint32 // microseconds
+	Interval    uint32 // microseconds
+	CEThreshold uint32 // microseconds
+	ECN         bool
+	Parent      string // Parent class handle for leaf qdiscs (empty for root)
+}
+
+// CreateETSQdiscCommand creates an ETS qdisc
+type CreateETSQdiscCommand struct {
+	DeviceName string
+	Handle     string
+	Bands      uint8    // 0 derives the bands from the strict bands and quanta
+	Strict     uint8    // strict priority bands
+	Quanta     []uint32 // bytes, one per bandwidth-sharing band
+	Priomap    []uint8  // band of each packet priority
+	Parent     string   // Parent class handle for leaf qdiscs (empty for root)
+}
+
+// CreateTAPRIOQdiscCommand creates a taprio qdisc
+type CreateTAPRIOQdiscCommand struct {
+	DeviceName         string
+	Handle             string
+	NumTC              uint8
+	Priomap            []uint8 // traffic class of each packet priority
+	Queues             []TAPRIOQueueRange
+	BaseTime           int64  // nanoseconds
+	CycleTime          int64  // nanoseconds, 0 for the sum of the intervals
+	CycleTimeExtension int64  // nanoseconds
+	Clock              string // "CLOCK_TAI", "CLOCK_REALTIME", "CLOCK_MONOTONIC" or "CLOCK_BOOTTIME"
+	Schedule           []TAPRIOScheduleEntry
+	TxTimeAssist       bool
+	FullOffload        bool
+	TxTimeDelay        uint32 // nanoseconds
+}
+
+// TAPRIOQueueRange assigns a traffic class count transmit queues from offset
+type TAPRIOQueueRange struct {
+	Count  uint16
+	Offset uint16
+}
+
+// TAPRIOScheduleEntry is one interval of a taprio gate schedule
+type TAPRIOScheduleEntry struct {
+	Command  string // "S", "H" or "R", as in tc(8)
+	GateMask uint32
+	Interval uint32 // nanoseconds
+}
+
+// CreateCAKEQdiscCommand creates a CAKE qdisc
+type CreateCAKEQdiscCommand struct {
+	DeviceName string
+	Handle     string
+	Bandwidth  string // bandwidth string like "100Mbps", empty for unlimited
+	RTT        uint32 // microseconds
+	Diffserv   string // "diffserv3", "diffserv4", "diffserv8", "besteffort" or "precedence"
+	NAT        bool
+	Wash       bool
+	AckFilter  string // "no-ack-filter", "ack-filter" or "ack-filter-aggressive"
 }
 
 // CreateHTBClassCommand creates an HTB class
@@ -65,6 +212,40 @@ type CreateHTBClassCommand struct {
 	MTU         uint32 // Maximum transmission unit (bytes)
 	HTBPrio     uint32 // Internal HTB priority (0-7)
 	UseDefaults bool   // Apply default parameters automatically
+	Profile     string // Tuning profile deriving the burst, cburst and quantum left out ("" = default)
+}
+
+// CreateDRRQdiscCommand creates a DRR qdisc
+type CreateDRRQdiscCommand struct {
+	DeviceName string
+	Handle     string
+	Parent     string // Parent class handle for leaf qdiscs (empty for root)
+}
+
+// CreateQFQQdiscCommand creates a QFQ qdisc
+type CreateQFQQdiscCommand struct {
+	DeviceName string
+	Handle     string
+	Parent     string // Parent class handle for leaf qdiscs (empty for root)
+}
+
+// CreateDRRClassCommand creates a DRR class
+type CreateDRRClassCommand struct {
+	DeviceName string
+	Parent     string
+	ClassID    string
+	Name       string
+	Quantum    uint32 // bytes per round (0 = device MTU)
+}
+
+// CreateQFQClassCommand creates a QFQ class
+type CreateQFQClassCommand struct {
+	DeviceName string
+	Parent     string
+	ClassID    string
+	Name       string
+	Weight     uint32 // share relative to the sibling classes (0 = 1)
+	Lmax       uint32 // largest packet in bytes (0 = device MTU)
 }
 
 // CreateFilterCommand creates a filter
@@ -73,8 +254,24 @@ type CreateFilterCommand struct {
 	Parent     string
 	Priority   uint16
 	Protocol   string
+	Kind       string // Classifier: "u32" (default), "flower" or "cgroup"
 	FlowID     string
-	Match      map[string]string
+	Match      map[string]string // All must hold: src_ip, dst_ip, src_port, dst_port, protocol; u32: tos, dscp, tcp_flags, len, raw@<offset>; flower: src_mac, dst_mac, vlan_id, vlan_prio, src_port_range, dst_port_range; cgroup: cgroup
+	Actions    []FilterAction    // Run in order on matched packets
+}
+
+// FilterAction describes one action of a filter's action chain
+type FilterAction struct {
+	Type   string // "mark", "police", "mirror", "redirect", "dscp", "drop", "pass", "priority" or "queue_mapping"
+	Mark   uint32 // mark: value to set
+	Mask   uint32 // mark: bits to change (0 = all)
+	Rate   string // police: rate, e.g. "10Mbps"
+	Burst  uint32 // police: burst in bytes
+	Exceed string // police: "drop" (default) or "reclassify"
+	Device string // mirror, redirect: target device
+	DSCP   uint8  // dscp: value to set, 0-63
+	Handle string // priority: value to set, as a handle, e.g. "1:10"
+	Queue  uint16 // queue_mapping: transmit queue
 }
 
 // CreateAdvancedFilterCommand creates an advanced filter with enhanced capabilities
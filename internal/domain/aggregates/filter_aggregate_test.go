@@ -443,4 +443,57 @@ func mustNewIPSourceMatch(cidr string) *entities.IPMatch {
 func mustNewIPDestinationMatch(cidr string) *entities.IPMatch {
 	match, _ := entities.NewIPDestinationMatch(cidr)
 	return match
-}
\ No newline at end of file
+}
+
+func TestAddFilterWithActions(t *testing.T) {
+	device, _ := tc.NewDeviceName("eth0")
+	scrub, _ := tc.NewDeviceName("scrub0")
+	parentHandle, _ := tc.ParseHandle("1:")
+	classHandle, _ := tc.ParseHandle("1:10")
+	filterHandle, _ := tc.ParseHandle("800:100")
+
+	newAggregate := func() *TrafficControlAggregate {
+		agg := NewTrafficControlAggregate(device)
+		require.NoError(t, agg.AddHTBQdisc(parentHandle, classHandle))
+		require.NoError(t, agg.AddHTBClass(parentHandle, classHandle, "scrubbed", tc.Mbps(10), tc.Mbps(20)))
+		return agg
+	}
+
+	t.Run("replays action chain in order", func(t *testing.T) {
+		agg := newAggregate()
+		actions := []entities.Action{
+			entities.NewMarkAction(0x10, 0xFFFFFFFF),
+			entities.NewPoliceAction(tc.Mbps(5), 32000, entities.PoliceExceedReclassify),
+			entities.NewRedirectAction(scrub),
+		}
+		require.NoError(t, agg.AddFilterWithActions(parentHandle, 100, filterHandle, classHandle, nil, actions))
+
+		replayed := NewTrafficControlAggregate(device)
+		replayed.LoadFromHistory(agg.GetUncommittedEvents())
+
+		filters := replayed.GetFilters()
+		require.Len(t, filters, 1)
+		require.Len(t, filters[0].Actions(), 3)
+		for i, action := range filters[0].Actions() {
+			assert.Equal(t, actions[i].String(), action.String())
+		}
+	})
+
+	t.Run("rejects redirect before other actions", func(t *testing.T) {
+		agg := newAggregate()
+		actions := []entities.Action{entities.NewRedirectAction(scrub), entities.NewMarkAction(0x10, 0xFFFFFFFF)}
+
+		err := agg.AddFilterWithActions(parentHandle, 100, filterHandle, classHandle, nil, actions)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "last action")
+	})
+
+	t.Run("rejects redirect to own device", func(t *testing.T) {
+		agg := newAggregate()
+		actions := []entities.Action{entities.NewRedirectAction(device)}
+
+		err := agg.AddFilterWithActions(parentHandle, 100, filterHandle, classHandle, nil, actions)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "to itself")
+	})
+}
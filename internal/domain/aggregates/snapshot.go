@@ -0,0 +1,120 @@
+package aggregates
+
+import (
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// Snapshot returns the aggregate's version and the creation events of its current qdiscs,
+// classes and filters. Applying them in order rebuilds the aggregate's state, so a snapshot
+// replaces the history up to its version.
+func (ag *TrafficControlAggregate) Snapshot() (int, []events.DomainEvent) {
+	state := make([]events.DomainEvent, len(ag.created))
+	copy(state, ag.created)
+	return ag.version, state
+}
+
+// LoadFromSnapshot rebuilds the aggregate from a snapshot taken at version. Events recorded
+// after the snapshot are then applied with LoadFromHistory.
+func (ag *TrafficControlAggregate) LoadFromSnapshot(version int, state []events.DomainEvent) {
+	for _, event := range state {
+		ag.ApplyEvent(event)
+	}
+	ag.version = version
+	ag.changes = make([]events.DomainEvent, 0)
+}
+
+// trackCreated keeps the creation events of the current objects up to date. Creating an
+// object replaces the event of an earlier object with the same identity.
+func (ag *TrafficControlAggregate) trackCreated(event events.DomainEvent) {
+	var removed string
+	switch e := event.(type) {
+	case *events.QdiscDeletedEvent:
+		removed = qdiscIdentity(e.Handle)
+	case *events.ClassDeletedEvent:
+		removed = classIdentity(e.Handle)
+	case *events.FilterDeletedEvent:
+		removed = filterIdentity(e.Parent, e.Priority, e.Handle)
+	case *events.HTBClassChangedEvent:
+		// The class is now created by its new definition
+		ag.removeCreated(classIdentity(e.Handle))
+		ag.created = append(ag.created, e.Definition())
+		return
+	default:
+		identity, ok := createdIdentity(event)
+		if !ok {
+			return
+		}
+		ag.removeCreated(identity)
+		ag.created = append(ag.created, event)
+		return
+	}
+	ag.removeCreated(removed)
+}
+
+// removeCreated drops the creation event of the object with the given identity
+func (ag *TrafficControlAggregate) removeCreated(identity string) {
+	kept := ag.created[:0:0]
+	for _, event := range ag.created {
+		if id, _ := createdIdentity(event); id != identity {
+			kept = append(kept, event)
+		}
+	}
+	ag.created = kept
+}
+
+// createdIdentity identifies the object created by a creation event as the aggregate's
+// state does: qdiscs and classes by handle, filters by parent, priority and handle
+func createdIdentity(event events.DomainEvent) (string, bool) {
+	switch e := event.(type) {
+	case *events.QdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.HTBQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.TBFQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.PRIOQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.FQCODELQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.SFQQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.FQQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.NETEMQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.REDQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.GREDQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.CODELQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.ETSQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.TAPRIOQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.CAKEQdiscCreatedEvent:
+		return qdiscIdentity(e.Handle), true
+	case *events.ClassCreatedEvent:
+		return classIdentity(e.Handle), true
+	case *events.HTBClassCreatedEvent:
+		return classIdentity(e.Handle), true
+	case *events.HTBClassCreatedEventWithAdvancedParameters:
+		return classIdentity(e.Handle), true
+	case *events.DRRClassCreatedEvent:
+		return classIdentity(e.Handle), true
+	case *events.QFQClassCreatedEvent:
+		return classIdentity(e.Handle), true
+	case *events.FilterCreatedEvent:
+		return filterIdentity(e.Parent, e.Priority, e.Handle), true
+	}
+	return "", false
+}
+
+func qdiscIdentity(handle tc.Handle) string { return "qdisc " + handle.String() }
+func classIdentity(handle tc.Handle) string { return "class " + handle.String() }
+func filterIdentity(parent tc.Handle, priority uint16, handle tc.Handle) string {
+	return fmt.Sprintf("filter %s prio %d handle %s", parent, priority, handle)
+}
@@ -20,6 +20,10 @@ type TrafficControlAggregate struct {
 	classes map[tc.Handle]*entities.Class
 	filters []*entities.Filter
 
+	// Creation events of the current qdiscs, classes and filters, in the order they were
+	// applied; the aggregate's snapshot
+	created []events.DomainEvent
+
 	// Event sourcing
 	version int
 	changes []events.DomainEvent
@@ -135,6 +139,7 @@ func (ag *TrafficControlAggregate) withEvent(event events.DomainEvent) *TrafficC
 		qdiscs:     make(map[tc.Handle]*entities.Qdisc),
 		classes:    make(map[tc.Handle]*entities.Class),
 		filters:    make([]*entities.Filter, len(ag.filters)),
+		created:    make([]events.DomainEvent, len(ag.created)),
 		version:    ag.version + 1,
 		changes:    make([]events.DomainEvent, len(ag.changes)+1),
 	}
@@ -149,8 +154,9 @@ func (ag *TrafficControlAggregate) withEvent(event events.DomainEvent) *TrafficC
 		newAggregate.classes[k] = v
 	}
 
-	// Copy filters
+	// Copy filters and their creation events
 	copy(newAggregate.filters, ag.filters)
+	copy(newAggregate.created, ag.created)
 
 	// Copy existing changes and add new event
 	copy(newAggregate.changes, ag.changes)
@@ -275,31 +281,58 @@ func (ag *TrafficControlAggregate) AddFQCODELQdisc(handle tc.Handle, limit, flow
 		return fmt.Errorf("root qdisc handle must have minor = 0, got %s", handle)
 	}
 
-	// Business rule: Limit must be positive
-	if limit == 0 {
-		return fmt.Errorf("limit must be positive, got %d", limit)
+	if err := validateFQCODELParameters(limit, flows, target, interval); err != nil {
+		return err
 	}
 
-	// Business rule: Flows must be positive and power of 2
-	if flows == 0 || (flows&(flows-1)) != 0 {
-		return fmt.Errorf("flows must be positive and power of 2, got %d", flows)
+	// Create and apply event
+	event := events.NewFQCODELQdiscCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		handle,
+		limit,
+		flows,
+		target,
+		interval,
+		quantum,
+		ecn,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// AddLeafFQCODELQdisc attaches a FQ_CODEL qdisc to an existing class
+func (ag *TrafficControlAggregate) AddLeafFQCODELQdisc(parent tc.Handle, handle tc.Handle, limit, flows, target, interval, quantum uint32, ecn bool) error {
+	// Business rule: Parent class must exist
+	if _, exists := ag.classes[parent]; !exists {
+		return fmt.Errorf("parent class %s does not exist", parent)
 	}
 
-	// Business rule: Target must be positive
-	if target == 0 {
-		return fmt.Errorf("target must be positive, got %d microseconds", target)
+	// Business rule: Check if qdisc already exists
+	if _, exists := ag.qdiscs[handle]; exists {
+		return fmt.Errorf("qdisc with handle %s already exists", handle)
 	}
 
-	// Business rule: Interval must be positive and >= target
-	if interval == 0 || interval < target {
-		return fmt.Errorf("interval must be positive and >= target (%d), got %d microseconds", target, interval)
+	// Business rule: Qdisc handle must have minor = 0
+	if !handle.IsRoot() {
+		return fmt.Errorf("qdisc handle must have minor = 0, got %s", handle)
+	}
+
+	if err := validateFQCODELParameters(limit, flows, target, interval); err != nil {
+		return err
 	}
 
 	// Create and apply event
-	event := events.NewFQCODELQdiscCreatedEvent(
+	event := events.NewLeafFQCODELQdiscCreatedEvent(
 		ag.id,
 		ag.version+1,
 		ag.deviceName,
+		parent,
 		handle,
 		limit,
 		flows,
@@ -316,6 +349,381 @@ func (ag *TrafficControlAggregate) AddFQCODELQdisc(handle tc.Handle, limit, flow
 	return nil
 }
 
+// AddSFQQdisc adds an SFQ qdisc; a nil parent makes it the root qdisc, otherwise it is
+// attached to the parent class
+func (ag *TrafficControlAggregate) AddSFQQdisc(parent *tc.Handle, handle tc.Handle, perturb, quantum, limit uint32) error {
+	if err := ag.validateQdiscPlacement(parent, handle); err != nil {
+		return err
+	}
+
+	// Business rule: Quantum and limit must be positive
+	if quantum == 0 {
+		return fmt.Errorf("quantum must be positive, got %d", quantum)
+	}
+	if limit == 0 {
+		return fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	// Create and apply event
+	event := events.NewSFQQdiscCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		parent,
+		handle,
+		perturb,
+		quantum,
+		limit,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// AddFQQdisc adds an FQ qdisc; a nil parent makes it the root qdisc, otherwise it is
+// attached to the parent class
+func (ag *TrafficControlAggregate) AddFQQdisc(parent *tc.Handle, handle tc.Handle, limit, flowLimit, quantum, initialQuantum uint32, maxRate tc.Bandwidth, pacing bool) error {
+	if err := ag.validateQdiscPlacement(parent, handle); err != nil {
+		return err
+	}
+
+	// Business rule: Limits and quantum must be positive
+	if limit == 0 {
+		return fmt.Errorf("limit must be positive, got %d", limit)
+	}
+	if flowLimit == 0 || flowLimit > limit {
+		return fmt.Errorf("flow limit must be positive and <= limit (%d), got %d", limit, flowLimit)
+	}
+	if quantum == 0 {
+		return fmt.Errorf("quantum must be positive, got %d", quantum)
+	}
+
+	// Create and apply event
+	event := events.NewFQQdiscCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		parent,
+		handle,
+		limit,
+		flowLimit,
+		quantum,
+		initialQuantum,
+		maxRate,
+		pacing,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// AddNETEMQdisc adds a NETEM qdisc; a nil parent makes it the root qdisc, otherwise it is
+// attached to the parent class
+func (ag *TrafficControlAggregate) AddNETEMQdisc(parent *tc.Handle, handle tc.Handle, params entities.NETEMParameters) error {
+	if err := ag.validateQdiscPlacement(parent, handle); err != nil {
+		return err
+	}
+
+	// Business rule: Emulated conditions must be possible
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	// Create and apply event
+	event := events.NewNETEMQdiscCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		parent,
+		handle,
+		params,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// AddREDQdisc adds a RED qdisc; a nil parent makes it the root qdisc, otherwise it is
+// attached to the parent class
+func (ag *TrafficControlAggregate) AddREDQdisc(parent *tc.Handle, handle tc.Handle, params entities.REDParameters) error {
+	if err := ag.validateQdiscPlacement(parent, handle); err != nil {
+		return err
+	}
+
+	// Business rule: Thresholds must describe a manageable queue
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	// Create and apply event
+	event := events.NewREDQdiscCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		parent,
+		handle,
+		params,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// AddGREDQdisc adds a GRED qdisc; a nil parent makes it the root qdisc, otherwise it is
+// attached to the parent class
+func (ag *TrafficControlAggregate) AddGREDQdisc(parent *tc.Handle, handle tc.Handle, params entities.GREDParameters) error {
+	if err := ag.validateQdiscPlacement(parent, handle); err != nil {
+		return err
+	}
+
+	// Business rule: Virtual queues must be consistent
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	// Create and apply event
+	event := events.NewGREDQdiscCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		parent,
+		handle,
+		params,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// AddCODELQdisc adds a CoDel qdisc; a nil parent makes it the root qdisc, otherwise it is
+// attached to the parent class
+func (ag *TrafficControlAggregate) AddCODELQdisc(parent *tc.Handle, handle tc.Handle, params entities.CODELParameters) error {
+	if err := ag.validateQdiscPlacement(parent, handle); err != nil {
+		return err
+	}
+
+	// Business rule: Delays must be consistent
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	// Create and apply event
+	event := events.NewCODELQdiscCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		parent,
+		handle,
+		params,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// AddETSQdisc adds an ETS qdisc; a nil parent makes it the root qdisc, otherwise it is
+// attached to the parent class
+func (ag *TrafficControlAggregate) AddETSQdisc(parent *tc.Handle, handle tc.Handle, params entities.ETSParameters) error {
+	if err := ag.validateQdiscPlacement(parent, handle); err != nil {
+		return err
+	}
+
+	// Business rule: Bands must be schedulable
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	// Create and apply event
+	event := events.NewETSQdiscCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		parent,
+		handle,
+		params,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// AddTAPRIOQdisc adds a taprio qdisc, which can only be the root qdisc as it owns the
+// device's transmit queues
+func (ag *TrafficControlAggregate) AddTAPRIOQdisc(handle tc.Handle, params entities.TAPRIOParameters) error {
+	if err := ag.validateQdiscPlacement(nil, handle); err != nil {
+		return err
+	}
+
+	// Business rule: The gate schedule must be runnable
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	// Create and apply event
+	event := events.NewTAPRIOQdiscCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		handle,
+		params,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// AddDRRQdisc adds a DRR (Deficit Round Robin) qdisc; a nil parent makes it the root
+// qdisc, otherwise it is attached to the parent class
+func (ag *TrafficControlAggregate) AddDRRQdisc(parent *tc.Handle, handle tc.Handle) error {
+	return ag.addFairQueueingQdisc(parent, handle, entities.QdiscTypeDRR)
+}
+
+// AddQFQQdisc adds a QFQ (Quick Fair Queueing) qdisc; a nil parent makes it the root
+// qdisc, otherwise it is attached to the parent class
+func (ag *TrafficControlAggregate) AddQFQQdisc(parent *tc.Handle, handle tc.Handle) error {
+	return ag.addFairQueueingQdisc(parent, handle, entities.QdiscTypeQFQ)
+}
+
+// addFairQueueingQdisc adds a classful qdisc whose behaviour lies entirely in its classes
+func (ag *TrafficControlAggregate) addFairQueueingQdisc(parent *tc.Handle, handle tc.Handle, qdiscType entities.QdiscType) error {
+	if err := ag.validateQdiscPlacement(parent, handle); err != nil {
+		return err
+	}
+
+	// Create and apply event
+	event := events.NewQdiscCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		handle,
+		qdiscType,
+		parent,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// validateQdiscPlacement checks the business rules for adding a root (nil parent) or leaf qdisc
+func (ag *TrafficControlAggregate) validateQdiscPlacement(parent *tc.Handle, handle tc.Handle) error {
+	// Business rule: Parent class must exist
+	if parent != nil {
+		if _, exists := ag.classes[*parent]; !exists {
+			return fmt.Errorf("parent class %s does not exist", *parent)
+		}
+	}
+
+	// Business rule: Check if qdisc already exists
+	if _, exists := ag.qdiscs[handle]; exists {
+		return fmt.Errorf("qdisc with handle %s already exists", handle)
+	}
+
+	// Business rule: Qdisc handle must have minor = 0
+	if !handle.IsRoot() {
+		return fmt.Errorf("qdisc handle must have minor = 0, got %s", handle)
+	}
+
+	return nil
+}
+
+// AddCAKEQdisc adds a CAKE qdisc
+func (ag *TrafficControlAggregate) AddCAKEQdisc(handle tc.Handle, bandwidth tc.Bandwidth, rtt uint32, diffserv entities.CAKEDiffservMode, nat, wash bool, ackFilter entities.CAKEAckFilter) error {
+	// Business rule: Check if qdisc already exists
+	if _, exists := ag.qdiscs[handle]; exists {
+		return fmt.Errorf("qdisc with handle %s already exists", handle)
+	}
+
+	// Business rule: Root qdisc must have minor = 0
+	if !handle.IsRoot() {
+		return fmt.Errorf("root qdisc handle must have minor = 0, got %s", handle)
+	}
+
+	// Business rule: RTT must be positive
+	if rtt == 0 {
+		return fmt.Errorf("rtt must be positive, got %d microseconds", rtt)
+	}
+
+	// Business rule: Modes must be ones the kernel knows
+	if diffserv < entities.CAKEDiffserv3 || diffserv > entities.CAKEPrecedence {
+		return fmt.Errorf("invalid CAKE diffserv mode %d", diffserv)
+	}
+	if ackFilter < entities.CAKEAckFilterNone || ackFilter > entities.CAKEAckFilterAggressive {
+		return fmt.Errorf("invalid CAKE ACK filter mode %d", ackFilter)
+	}
+
+	// Create and apply event
+	event := events.NewCAKEQdiscCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		handle,
+		bandwidth,
+		rtt,
+		diffserv,
+		nat,
+		wash,
+		ackFilter,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// validateFQCODELParameters checks the FQ_CODEL business rules shared by root and leaf qdiscs
+func validateFQCODELParameters(limit, flows, target, interval uint32) error {
+	// Business rule: Limit must be positive
+	if limit == 0 {
+		return fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	// Business rule: Flows must be positive and power of 2
+	if flows == 0 || (flows&(flows-1)) != 0 {
+		return fmt.Errorf("flows must be positive and power of 2, got %d", flows)
+	}
+
+	// Business rule: Target must be positive
+	if target == 0 {
+		return fmt.Errorf("target must be positive, got %d microseconds", target)
+	}
+
+	// Business rule: Interval must be positive and >= target
+	if interval == 0 || interval < target {
+		return fmt.Errorf("interval must be positive and >= target (%d), got %d microseconds", target, interval)
+	}
+
+	return nil
+}
+
 // AddHTBClass adds an HTB class
 func (ag *TrafficControlAggregate) AddHTBClass(parent tc.Handle, classHandle tc.Handle, name string, rate tc.Bandwidth, ceil tc.Bandwidth) error {
 	// Business rule: Parent qdisc must exist
@@ -379,6 +787,7 @@ func (ag *TrafficControlAggregate) AddHTBClassWithAdvancedParameters(
 	mtu uint32,
 	htbPrio uint32,
 	useDefaults bool,
+	profile entities.TuningProfile,
 ) error {
 	// Business rule: Parent qdisc must exist
 	parentQdisc, parentExists := ag.qdiscs[parent]
@@ -430,6 +839,7 @@ func (ag *TrafficControlAggregate) AddHTBClassWithAdvancedParameters(
 		mtu,
 		htbPrio,
 		useDefaults,
+		profile,
 	)
 
 	ag.ApplyEvent(event)
@@ -439,6 +849,113 @@ func (ag *TrafficControlAggregate) AddHTBClassWithAdvancedParameters(
 	return nil
 }
 
+// AddDRRClass adds a DRR class, which may send up to quantum bytes each round; a zero
+// quantum uses the device MTU
+func (ag *TrafficControlAggregate) AddDRRClass(parent tc.Handle, classHandle tc.Handle, name string, quantum uint32) error {
+	if err := ag.validateFairQueueingClass(parent, classHandle, entities.QdiscTypeDRR); err != nil {
+		return err
+	}
+
+	// Create and apply event
+	event := events.NewDRRClassCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		classHandle,
+		parent,
+		name,
+		quantum,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// AddQFQClass adds a QFQ class with the given weight and maximum packet size; zero values
+// use the kernel defaults of weight 1 and the device MTU
+func (ag *TrafficControlAggregate) AddQFQClass(parent tc.Handle, classHandle tc.Handle, name string, weight, lmax uint32) error {
+	if err := ag.validateFairQueueingClass(parent, classHandle, entities.QdiscTypeQFQ); err != nil {
+		return err
+	}
+
+	// Business rule: Weight and maximum packet size within the kernel's limits
+	if weight > entities.QFQMaxWeight {
+		return fmt.Errorf("QFQ weight must be between 1 and %d, got %d", entities.QFQMaxWeight, weight)
+	}
+	if lmax != 0 && (lmax < entities.QFQMinLmax || lmax > entities.QFQMaxLmax) {
+		return fmt.Errorf("QFQ maximum packet size must be between %d and %d bytes, got %d",
+			entities.QFQMinLmax, entities.QFQMaxLmax, lmax)
+	}
+
+	// Business rule: The weights of a qdisc's classes are bounded
+	class := entities.NewQFQClass(ag.deviceName, classHandle, parent, name)
+	class.SetWeight(weight)
+	total := ag.qfqWeightSum(parent) + class.EffectiveWeight()
+	if total > entities.QFQMaxWeightSum {
+		return fmt.Errorf("QFQ class weights under %s would sum to %d, above the limit of %d",
+			parent, total, entities.QFQMaxWeightSum)
+	}
+
+	// Create and apply event
+	event := events.NewQFQClassCreatedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		classHandle,
+		parent,
+		name,
+		weight,
+		lmax,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// validateFairQueueingClass checks the business rules for adding a class to a DRR or QFQ
+// qdisc, whose classes all hang directly off the qdisc
+func (ag *TrafficControlAggregate) validateFairQueueingClass(parent tc.Handle, classHandle tc.Handle, qdiscType entities.QdiscType) error {
+	// Business rule: Parent must be a qdisc of the class's kind
+	parentQdisc, exists := ag.qdiscs[parent]
+	if !exists {
+		return fmt.Errorf("parent qdisc %s does not exist", parent)
+	}
+	if parentQdisc.Type() != qdiscType {
+		return fmt.Errorf("parent qdisc must be %s type, got %s", qdiscType, parentQdisc.Type())
+	}
+
+	// Business rule: Class handle must not already exist
+	if _, exists := ag.classes[classHandle]; exists {
+		return fmt.Errorf("class with handle %s already exists", classHandle)
+	}
+
+	// Business rule: Class handle must share the qdisc's major number
+	if classHandle.Major() != parent.Major() || classHandle.IsRoot() {
+		return fmt.Errorf("class handle %s must be %x:<minor> with a non-zero minor", classHandle, parent.Major())
+	}
+
+	return nil
+}
+
+// qfqWeightSum returns the sum of the weights of the QFQ classes under a qdisc
+func (ag *TrafficControlAggregate) qfqWeightSum(parent tc.Handle) uint32 {
+	var sum uint32
+	for _, event := range ag.created {
+		if e, ok := event.(*events.QFQClassCreatedEvent); ok && e.Parent == parent {
+			class := entities.NewQFQClass(e.DeviceName, e.Handle, e.Parent, e.Name)
+			class.SetWeight(e.Weight)
+			sum += class.EffectiveWeight()
+		}
+	}
+	return sum
+}
+
 // WithHTBClass returns a new aggregate with an HTB class added (immutable)
 func (ag *TrafficControlAggregate) WithHTBClass(parent tc.Handle, classHandle tc.Handle, name string, rate tc.Bandwidth, ceil tc.Bandwidth) types.Result[*TrafficControlAggregate] {
 	// Business rule: Parent qdisc must exist
@@ -483,6 +1000,16 @@ func (ag *TrafficControlAggregate) WithHTBClass(parent tc.Handle, classHandle tc
 
 // AddFilter adds a filter
 func (ag *TrafficControlAggregate) AddFilter(parent tc.Handle, priority uint16, handle tc.Handle, flowID tc.Handle, matches []entities.Match) error {
+	return ag.AddFilterWithActions(parent, priority, handle, flowID, matches, nil)
+}
+
+// AddFilterWithActions adds a filter that runs an action chain on the packets it matches
+func (ag *TrafficControlAggregate) AddFilterWithActions(parent tc.Handle, priority uint16, handle tc.Handle, flowID tc.Handle, matches []entities.Match, actions []entities.Action) error {
+	return ag.AddFilterOfKind(entities.FilterKindU32, parent, priority, handle, flowID, matches, actions)
+}
+
+// AddFilterOfKind adds a filter installed with the given classifier
+func (ag *TrafficControlAggregate) AddFilterOfKind(kind entities.FilterKind, parent tc.Handle, priority uint16, handle tc.Handle, flowID tc.Handle, matches []entities.Match, actions []entities.Action) error {
 	// Business rule: Parent must exist (either qdisc or class)
 	_, qdiscExists := ag.qdiscs[parent]
 	_, classExists := ag.classes[parent]
@@ -495,6 +1022,23 @@ func (ag *TrafficControlAggregate) AddFilter(parent tc.Handle, priority uint16,
 		return fmt.Errorf("target class %s does not exist", flowID)
 	}
 
+	// Business rule: The classifier must be able to express every match
+	if err := entities.ValidateFilterMatches(kind, matches); err != nil {
+		return fmt.Errorf("invalid filter matches: %w", err)
+	}
+
+	// Business rule: Actions must run in a meaningful order
+	if err := entities.ValidateActionChain(actions); err != nil {
+		return fmt.Errorf("invalid action chain: %w", err)
+	}
+
+	// Business rule: Packets cannot be sent back to the device's own egress
+	for _, action := range actions {
+		if mirred, ok := action.(*entities.MirredAction); ok && mirred.Target().Equals(ag.deviceName) {
+			return fmt.Errorf("cannot %s packets of %s to itself", mirred.Type(), ag.deviceName)
+		}
+	}
+
 	// Create event
 	event := events.NewFilterCreatedEvent(
 		ag.id,
@@ -505,11 +1049,15 @@ func (ag *TrafficControlAggregate) AddFilter(parent tc.Handle, priority uint16,
 		handle,
 		flowID,
 	)
+	event.Kind = kind
 
 	// Add matches to event
 	for _, match := range matches {
 		event.AddMatch(match.Type(), match.String())
 	}
+	for _, action := range actions {
+		event.AddAction(action)
+	}
 
 	ag.ApplyEvent(event)
 	ag.changes = append(ag.changes, event)
@@ -550,6 +1098,82 @@ func (ag *TrafficControlAggregate) DeleteFilter(parent tc.Handle, priority uint1
 	return nil
 }
 
+// DeleteQdisc removes a qdisc from the configuration
+func (ag *TrafficControlAggregate) DeleteQdisc(handle tc.Handle) error {
+	// Business rule: Qdisc must exist
+	if _, exists := ag.qdiscs[handle]; !exists {
+		return fmt.Errorf("qdisc with handle %s not found", handle)
+	}
+
+	event := events.NewQdiscDeletedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		handle,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// DeleteClass removes a class from the configuration
+func (ag *TrafficControlAggregate) DeleteClass(handle tc.Handle) error {
+	// Business rule: Class must exist
+	if _, exists := ag.classes[handle]; !exists {
+		return fmt.Errorf("class with handle %s not found", handle)
+	}
+
+	event := events.NewClassDeletedEvent(
+		ag.id,
+		ag.version+1,
+		ag.deviceName,
+		handle,
+	)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// ChangeHTBClass changes an existing HTB class in place from the previous to the current
+// definition, keeping the class and what is attached to it
+func (ag *TrafficControlAggregate) ChangeHTBClass(previous, current *events.HTBClassCreatedEventWithAdvancedParameters) error {
+	// Business rule: Class must exist
+	if _, exists := ag.classes[current.Handle]; !exists {
+		return fmt.Errorf("class with handle %s not found", current.Handle)
+	}
+
+	event := events.NewHTBClassChangedEvent(ag.id, ag.version+1, previous, current)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
+// Record records a creation event that was produced against another instance of this
+// aggregate, such as a planning copy, as the next change of this aggregate
+func (ag *TrafficControlAggregate) Record(event events.DomainEvent) error {
+	restampable, ok := event.(events.Restampable)
+	if !ok {
+		return fmt.Errorf("event %s cannot be recorded", event.EventType())
+	}
+
+	restampable.Restamp(ag.id, ag.version+1)
+
+	ag.ApplyEvent(event)
+	ag.changes = append(ag.changes, event)
+	ag.version++
+
+	return nil
+}
+
 // GetUncommittedEvents returns uncommitted events
 func (ag *TrafficControlAggregate) GetUncommittedEvents() []events.DomainEvent {
 	return ag.changes
@@ -577,7 +1201,16 @@ func (ag *TrafficControlAggregate) GetVersion() int {
 
 // ApplyEvent applies a domain event to update aggregate state
 func (ag *TrafficControlAggregate) ApplyEvent(event events.DomainEvent) {
+	ag.trackCreated(event)
+
 	switch e := event.(type) {
+	case *events.QdiscCreatedEvent:
+		qdisc := entities.NewQdisc(e.DeviceName, e.Handle, e.QdiscType)
+		if e.Parent != nil {
+			qdisc.SetParent(*e.Parent)
+		}
+		ag.qdiscs[e.Handle] = qdisc
+
 	case *events.HTBQdiscCreatedEvent:
 		qdisc := entities.NewHTBQdisc(e.DeviceName, e.Handle, e.DefaultClass)
 		ag.qdiscs[e.Handle] = qdisc.Qdisc
@@ -602,8 +1235,85 @@ func (ag *TrafficControlAggregate) ApplyEvent(event events.DomainEvent) {
 		qdisc.SetInterval(e.Interval)
 		qdisc.SetQuantum(e.Quantum)
 		qdisc.SetECN(e.ECN)
+		if e.Parent != nil {
+			qdisc.SetParent(*e.Parent)
+		}
+		ag.qdiscs[e.Handle] = qdisc.Qdisc
+
+	case *events.SFQQdiscCreatedEvent:
+		qdisc := entities.NewSFQQdisc(e.DeviceName, e.Handle)
+		qdisc.SetPerturb(e.Perturb)
+		qdisc.SetQuantum(e.Quantum)
+		qdisc.SetLimit(e.Limit)
+		if e.Parent != nil {
+			qdisc.SetParent(*e.Parent)
+		}
+		ag.qdiscs[e.Handle] = qdisc.Qdisc
+
+	case *events.FQQdiscCreatedEvent:
+		qdisc := entities.NewFQQdisc(e.DeviceName, e.Handle)
+		qdisc.SetLimit(e.Limit)
+		qdisc.SetFlowLimit(e.FlowLimit)
+		qdisc.SetQuantum(e.Quantum)
+		qdisc.SetInitialQuantum(e.InitialQuantum)
+		qdisc.SetMaxRate(e.MaxRate)
+		qdisc.SetPacing(e.Pacing)
+		if e.Parent != nil {
+			qdisc.SetParent(*e.Parent)
+		}
+		ag.qdiscs[e.Handle] = qdisc.Qdisc
+
+	case *events.NETEMQdiscCreatedEvent:
+		qdisc := entities.NewNETEMQdisc(e.DeviceName, e.Handle, e.Parameters())
+		if e.Parent != nil {
+			qdisc.SetParent(*e.Parent)
+		}
+		ag.qdiscs[e.Handle] = qdisc.Qdisc
+
+	case *events.REDQdiscCreatedEvent:
+		qdisc := entities.NewREDQdisc(e.DeviceName, e.Handle, e.Parameters())
+		if e.Parent != nil {
+			qdisc.SetParent(*e.Parent)
+		}
+		ag.qdiscs[e.Handle] = qdisc.Qdisc
+
+	case *events.GREDQdiscCreatedEvent:
+		qdisc := entities.NewGREDQdisc(e.DeviceName, e.Handle, e.Parameters())
+		if e.Parent != nil {
+			qdisc.SetParent(*e.Parent)
+		}
+		ag.qdiscs[e.Handle] = qdisc.Qdisc
+
+	case *events.CODELQdiscCreatedEvent:
+		qdisc := entities.NewCODELQdisc(e.DeviceName, e.Handle, e.Parameters())
+		if e.Parent != nil {
+			qdisc.SetParent(*e.Parent)
+		}
+		ag.qdiscs[e.Handle] = qdisc.Qdisc
+
+	case *events.ETSQdiscCreatedEvent:
+		qdisc := entities.NewETSQdisc(e.DeviceName, e.Handle, e.Parameters())
+		if e.Parent != nil {
+			qdisc.SetParent(*e.Parent)
+		}
+		ag.qdiscs[e.Handle] = qdisc.Qdisc
+
+	case *events.TAPRIOQdiscCreatedEvent:
+		qdisc := entities.NewTAPRIOQdisc(e.DeviceName, e.Handle, e.Parameters())
+		ag.qdiscs[e.Handle] = qdisc.Qdisc
+
+	case *events.CAKEQdiscCreatedEvent:
+		qdisc := entities.NewCAKEQdisc(e.DeviceName, e.Handle, e.Bandwidth)
+		qdisc.SetRTT(e.RTT)
+		qdisc.SetDiffserv(e.Diffserv)
+		qdisc.SetNAT(e.NAT)
+		qdisc.SetWash(e.Wash)
+		qdisc.SetAckFilter(e.AckFilter)
 		ag.qdiscs[e.Handle] = qdisc.Qdisc
 
+	case *events.ClassCreatedEvent:
+		ag.classes[e.Handle] = entities.NewClass(e.DeviceName, e.Handle, e.Parent, e.Name, e.Priority)
+
 	case *events.HTBClassCreatedEvent:
 		// Use a default priority of 4 for event reconstruction
 		class := entities.NewHTBClass(e.DeviceName, e.Handle, e.Parent, e.Name, entities.Priority(4))
@@ -612,37 +1322,25 @@ func (ag *TrafficControlAggregate) ApplyEvent(event events.DomainEvent) {
 		ag.classes[e.Handle] = class.Class
 
 	case *events.HTBClassCreatedEventWithAdvancedParameters:
-		// Create HTB class with advanced parameters
-		class := entities.NewHTBClass(e.DeviceName, e.Handle, e.Parent, e.Name, e.Priority)
-		class.SetRate(e.Rate)
-		class.SetCeil(e.Ceil)
+		ag.applyHTBClass(e)
 
-		// Set advanced parameters
-		if e.Quantum > 0 {
-			class.SetQuantum(e.Quantum)
-		}
-		if e.Overhead > 0 {
-			class.SetOverhead(e.Overhead)
-		}
-		if e.MPU > 0 {
-			class.SetMPU(e.MPU)
-		}
-		if e.MTU > 0 {
-			class.SetMTU(e.MTU)
-		}
-		if e.HTBPrio > 0 {
-			class.SetHTBPrio(e.HTBPrio)
-		}
+	case *events.HTBClassChangedEvent:
+		ag.applyHTBClass(e.Current)
 
-		// Apply default parameters if requested
-		if e.UseDefaults {
-			class.ApplyDefaultParameters()
-		}
+	case *events.DRRClassCreatedEvent:
+		class := entities.NewDRRClass(e.DeviceName, e.Handle, e.Parent, e.Name)
+		class.SetQuantum(e.Quantum)
+		ag.classes[e.Handle] = class.Class
 
+	case *events.QFQClassCreatedEvent:
+		class := entities.NewQFQClass(e.DeviceName, e.Handle, e.Parent, e.Name)
+		class.SetWeight(e.Weight)
+		class.SetLmax(e.Lmax)
 		ag.classes[e.Handle] = class.Class
 
 	case *events.FilterCreatedEvent:
 		filter := entities.NewFilter(e.DeviceName, e.Parent, e.Priority, e.Handle)
+		filter.SetKind(e.Kind)
 		filter.SetFlowID(e.FlowID)
 		filter.SetProtocol(e.Protocol)
 
@@ -691,8 +1389,28 @@ func (ag *TrafficControlAggregate) ApplyEvent(event events.DomainEvent) {
 					match := entities.NewProtocolMatch(entities.TransportProtocol(protocol))
 					filter.AddMatch(match)
 				}
+			case entities.MatchTypeTOS, entities.MatchTypeDSCP, entities.MatchTypeTCPFlags,
+				entities.MatchTypePacketLength, entities.MatchTypeRaw:
+				// Format: "ip tos 0x10 0xff", "tcp flags 0x02 0x12", "u32 0x00000001 0x000000ff at 8", ...
+				if match, err := entities.ParseHeaderMatch(matchData.Type, matchData.Value); err == nil {
+					filter.AddMatch(match)
+				}
+			case entities.MatchTypeMACSource, entities.MatchTypeMACDestination, entities.MatchTypeVLANID,
+				entities.MatchTypeVLANPriority, entities.MatchTypePortRange:
+				// Format: "eth src 02:00:00:00:00:01", "vlan id 10", "dport range 8000-8080", ...
+				if match, err := entities.ParseFlowerMatch(matchData.Type, matchData.Value); err == nil {
+					filter.AddMatch(match)
+				}
+			case entities.MatchTypeCgroup:
+				// Format: "cgroup /sys/fs/cgroup/myapp.slice"
+				if match, err := entities.ParseCgroupMatch(matchData.Value); err == nil {
+					filter.AddMatch(match)
+				}
 			}
 		}
+		for _, actionData := range e.Actions {
+			filter.AddAction(actionData.Action())
+		}
 
 		ag.filters = append(ag.filters, filter)
 
@@ -714,6 +1432,40 @@ func (ag *TrafficControlAggregate) ApplyEvent(event events.DomainEvent) {
 	}
 }
 
+// applyHTBClass sets the class created, or changed, by an advanced HTB class definition
+func (ag *TrafficControlAggregate) applyHTBClass(e *events.HTBClassCreatedEventWithAdvancedParameters) {
+	class := entities.NewHTBClass(e.DeviceName, e.Handle, e.Parent, e.Name, e.Priority)
+	class.SetRate(e.Rate)
+	class.SetCeil(e.Ceil)
+
+	// Set advanced parameters
+	if e.Quantum > 0 {
+		class.SetQuantum(e.Quantum)
+	}
+	if e.Overhead > 0 {
+		class.SetOverhead(e.Overhead)
+	}
+	if e.MPU > 0 {
+		class.SetMPU(e.MPU)
+	}
+	if e.MTU > 0 {
+		class.SetMTU(e.MTU)
+	}
+	if e.HTBPrio > 0 {
+		class.SetHTBPrio(e.HTBPrio)
+	}
+	if e.Profile != "" {
+		class.SetTuningProfile(e.Profile)
+	}
+
+	// Apply default parameters if requested
+	if e.UseDefaults {
+		class.ApplyDefaultParameters()
+	}
+
+	ag.classes[e.Handle] = class.Class
+}
+
 // GetUncommittedChanges returns events that haven't been persisted
 func (ag *TrafficControlAggregate) GetUncommittedChanges() []events.DomainEvent {
 	return ag.changes
@@ -3,9 +3,12 @@ package aggregates
 import (
 	"testing"
 
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/domain/events"
 	"github.com/rng999/traffic-control-go/pkg/tc"
 	"github.com/rng999/traffic-control-go/pkg/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTrafficControlAggregate_Immutability(t *testing.T) {
@@ -182,3 +185,459 @@ func TestTrafficControlAggregate_BusinessRuleValidation(t *testing.T) {
 		assert.Contains(t, result.Error().Error(), "cannot be less than rate")
 	})
 }
+
+func TestTrafficControlAggregate_AddLeafFQCODELQdisc(t *testing.T) {
+	deviceName, _ := tc.NewDeviceName("eth0")
+	rootHandle := tc.NewHandle(1, 0)
+	classHandle := tc.NewHandle(1, 10)
+	leafHandle := tc.NewHandle(0x10, 0)
+
+	t.Run("attaches to existing class", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+		assert.NoError(t, aggregate.AddHTBQdisc(rootHandle, tc.NewHandle(1, 999)))
+		assert.NoError(t, aggregate.AddHTBClass(rootHandle, classHandle, "voip", tc.Mbps(1), tc.Mbps(5)))
+
+		err := aggregate.AddLeafFQCODELQdisc(classHandle, leafHandle, 1000, 1024, 1000, 20000, 300, true)
+		assert.NoError(t, err)
+
+		leaf, exists := aggregate.GetQdiscs()[leafHandle]
+		assert.True(t, exists)
+		assert.Equal(t, classHandle, *leaf.Parent())
+	})
+
+	t.Run("rejects missing parent class", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+		assert.NoError(t, aggregate.AddHTBQdisc(rootHandle, tc.NewHandle(1, 999)))
+
+		err := aggregate.AddLeafFQCODELQdisc(classHandle, leafHandle, 1000, 1024, 1000, 20000, 300, true)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "parent class")
+	})
+
+	t.Run("rejects interval below target", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+		assert.NoError(t, aggregate.AddHTBQdisc(rootHandle, tc.NewHandle(1, 999)))
+		assert.NoError(t, aggregate.AddHTBClass(rootHandle, classHandle, "voip", tc.Mbps(1), tc.Mbps(5)))
+
+		err := aggregate.AddLeafFQCODELQdisc(classHandle, leafHandle, 1000, 1024, 5000, 1000, 300, true)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "interval")
+	})
+}
+
+func TestTrafficControlAggregate_AddCAKEQdisc(t *testing.T) {
+	deviceName, _ := tc.NewDeviceName("eth0")
+	handle := tc.NewHandle(1, 0)
+
+	t.Run("adds root qdisc", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+
+		err := aggregate.AddCAKEQdisc(handle, tc.Mbps(100), 50000, entities.CAKEDiffserv4, true, false, entities.CAKEAckFilterEnabled)
+		assert.NoError(t, err)
+
+		qdisc, exists := aggregate.GetQdiscs()[handle]
+		assert.True(t, exists)
+		assert.Equal(t, entities.QdiscTypeCAKE, qdisc.Type())
+		assert.Len(t, aggregate.GetUncommittedEvents(), 1)
+	})
+
+	t.Run("rejects zero rtt", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+
+		err := aggregate.AddCAKEQdisc(handle, tc.Mbps(100), 0, entities.CAKEDiffserv3, false, false, entities.CAKEAckFilterNone)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "rtt")
+	})
+
+	t.Run("rejects unknown diffserv mode", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+
+		err := aggregate.AddCAKEQdisc(handle, tc.Mbps(100), 50000, entities.CAKEDiffservMode(9), false, false, entities.CAKEAckFilterNone)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "diffserv")
+	})
+}
+
+func TestTrafficControlAggregate_AddSFQQdisc(t *testing.T) {
+	deviceName, _ := tc.NewDeviceName("eth0")
+	rootHandle := tc.NewHandle(1, 0)
+	classHandle := tc.NewHandle(1, 10)
+	leafHandle := tc.NewHandle(0x10, 0)
+
+	t.Run("adds root qdisc", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+
+		assert.NoError(t, aggregate.AddSFQQdisc(nil, rootHandle, 10, 1514, 127))
+
+		qdisc, exists := aggregate.GetQdiscs()[rootHandle]
+		assert.True(t, exists)
+		assert.Equal(t, entities.QdiscTypeSFQ, qdisc.Type())
+		assert.Nil(t, qdisc.Parent())
+	})
+
+	t.Run("attaches to existing class", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+		assert.NoError(t, aggregate.AddHTBQdisc(rootHandle, tc.NewHandle(1, 999)))
+		assert.NoError(t, aggregate.AddHTBClass(rootHandle, classHandle, "web", tc.Mbps(1), tc.Mbps(5)))
+
+		assert.NoError(t, aggregate.AddSFQQdisc(&classHandle, leafHandle, 10, 1514, 127))
+
+		leaf, exists := aggregate.GetQdiscs()[leafHandle]
+		assert.True(t, exists)
+		assert.Equal(t, classHandle, *leaf.Parent())
+	})
+
+	t.Run("rejects missing parent class", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+
+		err := aggregate.AddSFQQdisc(&classHandle, leafHandle, 10, 1514, 127)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "parent class")
+	})
+
+	t.Run("rejects zero quantum", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+
+		err := aggregate.AddSFQQdisc(nil, rootHandle, 10, 0, 127)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "quantum")
+	})
+}
+
+func TestTrafficControlAggregate_AddNETEMQdisc(t *testing.T) {
+	deviceName, _ := tc.NewDeviceName("eth0")
+	rootHandle := tc.NewHandle(1, 0)
+
+	t.Run("adds root qdisc", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+		params := entities.NETEMParameters{Delay: 50000, Jitter: 5000, Loss: 1, Limit: 1000}
+
+		assert.NoError(t, aggregate.AddNETEMQdisc(nil, rootHandle, params))
+
+		qdisc, exists := aggregate.GetQdiscs()[rootHandle]
+		assert.True(t, exists)
+		assert.Equal(t, entities.QdiscTypeNETEM, qdisc.Type())
+		assert.Len(t, aggregate.GetUncommittedEvents(), 1)
+	})
+
+	t.Run("rejects impossible conditions", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			params entities.NETEMParameters
+			errMsg string
+		}{
+			{"loss above 100", entities.NETEMParameters{Loss: 101, Limit: 1000}, "loss"},
+			{"reorder without delay", entities.NETEMParameters{Reorder: 10, Limit: 1000}, "delay"},
+			{"gap without reorder", entities.NETEMParameters{Delay: 1000, Gap: 5, Limit: 1000}, "reorder"},
+			{"zero limit", entities.NETEMParameters{Delay: 1000}, "limit"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				aggregate := NewTrafficControlAggregate(deviceName)
+				err := aggregate.AddNETEMQdisc(nil, rootHandle, tt.params)
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			})
+		}
+	})
+}
+
+func TestTrafficControlAggregate_AddREDQdisc(t *testing.T) {
+	deviceName, _ := tc.NewDeviceName("eth0")
+	rootHandle := tc.NewHandle(1, 0)
+	thresholds := entities.REDThresholds{
+		Limit:       400000,
+		Min:         30000,
+		Max:         90000,
+		Avpkt:       1000,
+		Bandwidth:   tc.MustParseBandwidth("10mbit"),
+		Probability: 0.02,
+	}
+
+	t.Run("adds root qdisc", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+		params := entities.REDParameters{REDThresholds: thresholds, ECN: true, Adaptive: true}
+
+		assert.NoError(t, aggregate.AddREDQdisc(nil, rootHandle, params))
+
+		qdisc, exists := aggregate.GetQdiscs()[rootHandle]
+		assert.True(t, exists)
+		assert.Equal(t, entities.QdiscTypeRED, qdisc.Type())
+		assert.Len(t, aggregate.GetUncommittedEvents(), 1)
+	})
+
+	t.Run("rejects unmanageable thresholds", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			modify func(*entities.REDParameters)
+			errMsg string
+		}{
+			{"max below min", func(p *entities.REDParameters) { p.Max = p.Min }, "max threshold"},
+			{"limit below max", func(p *entities.REDParameters) { p.Limit = p.Max - 1 }, "limit"},
+			{"zero probability", func(p *entities.REDParameters) { p.Probability = 0 }, "probability"},
+			{"probability above 1", func(p *entities.REDParameters) { p.Probability = 1.5 }, "probability"},
+			{"burst too small", func(p *entities.REDParameters) { p.Burst = 10 }, "burst"},
+			{"harddrop without ecn", func(p *entities.REDParameters) { p.HardDrop = true }, "ecn"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				params := entities.REDParameters{REDThresholds: thresholds}
+				tt.modify(&params)
+
+				aggregate := NewTrafficControlAggregate(deviceName)
+				err := aggregate.AddREDQdisc(nil, rootHandle, params)
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			})
+		}
+	})
+
+	t.Run("derives burst from thresholds", func(t *testing.T) {
+		assert.Equal(t, uint32(50), thresholds.EffectiveBurst())
+	})
+
+	t.Run("adds GRED virtual queues", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+		params := entities.GREDParameters{
+			Queues:  4,
+			Default: 1,
+			GRIO:    true,
+			VirtualQueues: []entities.GREDVirtualQueue{
+				{REDThresholds: thresholds, DP: 0, Priority: 1},
+				{REDThresholds: thresholds, DP: 1, Priority: 2},
+			},
+		}
+
+		assert.NoError(t, aggregate.AddGREDQdisc(nil, rootHandle, params))
+		qdisc := aggregate.GetQdiscs()[rootHandle]
+		assert.Equal(t, entities.QdiscTypeGRED, qdisc.Type())
+
+		event := aggregate.GetUncommittedEvents()[0].(*events.GREDQdiscCreatedEvent)
+		assert.Equal(t, params, event.Parameters())
+	})
+
+	t.Run("rejects inconsistent GRED virtual queues", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			params entities.GREDParameters
+			errMsg string
+		}{
+			{"too many queues", entities.GREDParameters{Queues: 17}, "queues"},
+			{"default out of range", entities.GREDParameters{Queues: 2, Default: 2}, "default"},
+			{"no virtual queues", entities.GREDParameters{Queues: 2}, "at least one"},
+			{"dp out of range", entities.GREDParameters{Queues: 2, VirtualQueues: []entities.GREDVirtualQueue{
+				{REDThresholds: thresholds, DP: 2},
+			}}, "virtual queue 2"},
+			{"duplicate dp", entities.GREDParameters{Queues: 2, VirtualQueues: []entities.GREDVirtualQueue{
+				{REDThresholds: thresholds, DP: 1},
+				{REDThresholds: thresholds, DP: 1},
+			}}, "more than once"},
+			{"invalid thresholds", entities.GREDParameters{Queues: 2, VirtualQueues: []entities.GREDVirtualQueue{
+				{DP: 0},
+			}}, "virtual queue 0"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				aggregate := NewTrafficControlAggregate(deviceName)
+				err := aggregate.AddGREDQdisc(nil, rootHandle, tt.params)
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			})
+		}
+	})
+}
+
+func TestTrafficControlAggregate_AddCODELQdisc(t *testing.T) {
+	deviceName, _ := tc.NewDeviceName("eth0")
+	rootHandle := tc.NewHandle(1, 0)
+
+	aggregate := NewTrafficControlAggregate(deviceName)
+	params := entities.CODELParameters{Limit: 1000, Target: 5000, Interval: 100000, CEThreshold: 2000, ECN: true}
+	require.NoError(t, aggregate.AddCODELQdisc(nil, rootHandle, params))
+	assert.Equal(t, entities.QdiscTypeCODEL, aggregate.GetQdiscs()[rootHandle].Type())
+
+	err := NewTrafficControlAggregate(deviceName).AddCODELQdisc(nil, rootHandle, entities.CODELParameters{Limit: 1000, Target: 5000, Interval: 1000})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "interval")
+}
+
+func TestTrafficControlAggregate_AddETSQdisc(t *testing.T) {
+	deviceName, _ := tc.NewDeviceName("eth0")
+	rootHandle := tc.NewHandle(1, 0)
+
+	t.Run("derives the bands", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+		params := entities.ETSParameters{Strict: 2, Quanta: []uint32{4500, 3000, 1500}, Priomap: []uint8{4, 4, 3, 2, 1, 0, 0, 0}}
+		require.NoError(t, aggregate.AddETSQdisc(nil, rootHandle, params))
+		assert.Equal(t, entities.QdiscTypeETS, aggregate.GetQdiscs()[rootHandle].Type())
+
+		event, ok := aggregate.GetUncommittedEvents()[0].(*events.ETSQdiscCreatedEvent)
+		require.True(t, ok)
+		assert.Equal(t, uint8(5), event.Bands)
+	})
+
+	t.Run("rejects a priority mapped beyond the bands", func(t *testing.T) {
+		params := entities.ETSParameters{Bands: 3, Strict: 1, Priomap: []uint8{0, 3}}
+		err := NewTrafficControlAggregate(deviceName).AddETSQdisc(nil, rootHandle, params)
+		assert.ErrorContains(t, err, "priority 1 maps to band 3")
+	})
+
+	t.Run("rejects quanta that do not fit", func(t *testing.T) {
+		params := entities.ETSParameters{Bands: 2, Strict: 1, Quanta: []uint32{1500, 1500}}
+		err := NewTrafficControlAggregate(deviceName).AddETSQdisc(nil, rootHandle, params)
+		assert.ErrorContains(t, err, "do not fit")
+	})
+}
+
+func TestTrafficControlAggregate_AddTAPRIOQdisc(t *testing.T) {
+	deviceName, _ := tc.NewDeviceName("eth0")
+	rootHandle := tc.NewHandle(0x100, 0)
+	valid := entities.TAPRIOParameters{
+		NumTC:   3,
+		Priomap: []uint8{2, 2, 1, 0, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2},
+		Queues:  []entities.TAPRIOQueueRange{{Count: 1, Offset: 0}, {Count: 1, Offset: 1}, {Count: 2, Offset: 2}},
+		Clock:   entities.TAPRIOClockTAI,
+		Schedule: []entities.TAPRIOScheduleEntry{
+			{Command: entities.TAPRIOSetGates, GateMask: 0x1, Interval: 300000},
+			{Command: entities.TAPRIOSetGates, GateMask: 0x2, Interval: 300000},
+			{Command: entities.TAPRIOSetGates, GateMask: 0x4, Interval: 400000},
+		},
+	}
+
+	aggregate := NewTrafficControlAggregate(deviceName)
+	require.NoError(t, aggregate.AddTAPRIOQdisc(rootHandle, valid))
+	assert.Equal(t, entities.QdiscTypeTAPRIO, aggregate.GetQdiscs()[rootHandle].Type())
+	assert.Equal(t, int64(1000000), valid.ScheduleDuration())
+
+	tests := []struct {
+		name   string
+		modify func(p *entities.TAPRIOParameters)
+		errMsg string
+	}{
+		{"missing queue range", func(p *entities.TAPRIOParameters) { p.Queues = p.Queues[:2] }, "needs a queue range"},
+		{"overlapping queues", func(p *entities.TAPRIOParameters) { p.Queues[2].Offset = 1 }, "overlap"},
+		{"gate beyond the classes", func(p *entities.TAPRIOParameters) { p.Schedule[0].GateMask = 0x8 }, "beyond the 3 traffic classes"},
+		{"empty interval", func(p *entities.TAPRIOParameters) { p.Schedule[1].Interval = 0 }, "positive interval"},
+		{"no schedule", func(p *entities.TAPRIOParameters) { p.Schedule = nil }, "schedule entry is required"},
+		{"txtime delay without assist", func(p *entities.TAPRIOParameters) { p.TxTimeDelay = 1000 }, "requires txtime assist"},
+		{"extension beyond the cycle", func(p *entities.TAPRIOParameters) { p.CycleTimeExtension = 2000000 }, "exceeds the cycle time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := valid
+			params.Queues = append([]entities.TAPRIOQueueRange(nil), valid.Queues...)
+			params.Schedule = append([]entities.TAPRIOScheduleEntry(nil), valid.Schedule...)
+			tt.modify(&params)
+
+			err := NewTrafficControlAggregate(deviceName).AddTAPRIOQdisc(rootHandle, params)
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestTrafficControlAggregate_FairQueueingClasses(t *testing.T) {
+	deviceName, _ := tc.NewDeviceName("eth0")
+	rootHandle := tc.NewHandle(1, 0)
+
+	t.Run("adds DRR classes", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+		require.NoError(t, aggregate.AddDRRQdisc(nil, rootHandle))
+		require.NoError(t, aggregate.AddDRRClass(rootHandle, tc.NewHandle(1, 1), "bulk", 1500))
+		require.NoError(t, aggregate.AddDRRClass(rootHandle, tc.NewHandle(1, 2), "video", 4500))
+
+		assert.Equal(t, entities.QdiscTypeDRR, aggregate.GetQdiscs()[rootHandle].Type())
+		assert.Len(t, aggregate.GetClasses(), 2)
+
+		err := aggregate.AddQFQClass(rootHandle, tc.NewHandle(1, 3), "wrong", 1, 0)
+		assert.ErrorContains(t, err, "must be qfq type")
+	})
+
+	t.Run("bounds QFQ weights", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+		require.NoError(t, aggregate.AddQFQQdisc(nil, rootHandle))
+
+		err := aggregate.AddQFQClass(rootHandle, tc.NewHandle(1, 1), "heavy", entities.QFQMaxWeight+1, 0)
+		assert.ErrorContains(t, err, "weight")
+		err = aggregate.AddQFQClass(rootHandle, tc.NewHandle(1, 1), "tiny", 1, 100)
+		assert.ErrorContains(t, err, "maximum packet size")
+
+		for minor := uint16(1); minor <= 64; minor++ {
+			require.NoError(t, aggregate.AddQFQClass(rootHandle, tc.NewHandle(1, minor), "", entities.QFQMaxWeight, 9000))
+		}
+		err = aggregate.AddQFQClass(rootHandle, tc.NewHandle(1, 65), "", 0, 0)
+		assert.ErrorContains(t, err, "would sum to 65537")
+	})
+
+	t.Run("requires the qdisc's major number", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+		require.NoError(t, aggregate.AddQFQQdisc(nil, rootHandle))
+		err := aggregate.AddQFQClass(rootHandle, tc.NewHandle(2, 1), "", 1, 0)
+		assert.ErrorContains(t, err, "must be 1:<minor>")
+	})
+}
+
+func TestTrafficControlAggregate_AddFQQdisc(t *testing.T) {
+	deviceName, _ := tc.NewDeviceName("eth0")
+	rootHandle := tc.NewHandle(1, 0)
+	classHandle := tc.NewHandle(1, 10)
+	leafHandle := tc.NewHandle(0x10, 0)
+
+	t.Run("attaches to existing class", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+		assert.NoError(t, aggregate.AddHTBQdisc(rootHandle, tc.NewHandle(1, 999)))
+		assert.NoError(t, aggregate.AddHTBClass(rootHandle, classHandle, "uploads", tc.Mbps(1), tc.Mbps(5)))
+
+		assert.NoError(t, aggregate.AddFQQdisc(&classHandle, leafHandle, 10000, 100, 3028, 15140, tc.Mbps(2), true))
+
+		leaf, exists := aggregate.GetQdiscs()[leafHandle]
+		assert.True(t, exists)
+		assert.Equal(t, entities.QdiscTypeFQ, leaf.Type())
+		assert.Equal(t, classHandle, *leaf.Parent())
+	})
+
+	t.Run("rejects flow limit above limit", func(t *testing.T) {
+		aggregate := NewTrafficControlAggregate(deviceName)
+
+		err := aggregate.AddFQQdisc(nil, rootHandle, 100, 1000, 3028, 15140, tc.Bandwidth{}, true)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "flow limit")
+	})
+}
+
+func TestTrafficControlAggregate_Snapshot(t *testing.T) {
+	deviceName, _ := tc.NewDeviceName("eth0")
+	root := tc.NewHandle(1, 0)
+	web := tc.NewHandle(1, 10)
+	bulk := tc.NewHandle(1, 20)
+
+	aggregate := NewTrafficControlAggregate(deviceName)
+	require.NoError(t, aggregate.AddHTBQdisc(root, tc.NewHandle(1, 999)))
+	require.NoError(t, aggregate.AddHTBClass(root, web, "web", tc.Mbps(10), tc.Mbps(20)))
+	require.NoError(t, aggregate.AddHTBClass(root, bulk, "bulk", tc.Mbps(5), tc.Mbps(10)))
+	require.NoError(t, aggregate.AddFilter(root, 100, tc.NewHandle(0x800, 1), web, []entities.Match{entities.NewPortDestinationMatch(80)}))
+	require.NoError(t, aggregate.DeleteFilter(root, 100, tc.NewHandle(0x800, 1)))
+	require.NoError(t, aggregate.DeleteClass(bulk))
+
+	version, state := aggregate.Snapshot()
+	assert.Equal(t, 6, version)
+	// Deleted objects leave the snapshot
+	require.Len(t, state, 2)
+	assert.Equal(t, "HTBQdiscCreated", state[0].EventType())
+	assert.Equal(t, "HTBClassCreated", state[1].EventType())
+
+	restored := NewTrafficControlAggregate(deviceName)
+	restored.LoadFromSnapshot(version, state)
+	assert.Equal(t, version, restored.Version())
+	assert.Empty(t, restored.GetUncommittedEvents())
+	assert.Len(t, restored.GetQdiscs(), 1)
+	assert.Len(t, restored.GetClasses(), 1)
+	assert.Contains(t, restored.GetClasses(), web)
+	assert.Empty(t, restored.GetFilters())
+
+	// Events after the snapshot continue from its version
+	require.NoError(t, restored.AddHTBClass(root, bulk, "bulk", tc.Mbps(5), tc.Mbps(10)))
+	assert.Equal(t, version+1, restored.GetUncommittedEvents()[0].EventVersion())
+}
@@ -0,0 +1,47 @@
+package entities
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// CgroupMatch matches packets sent by sockets of the processes in a cgroup, given by the
+// path of the cgroup directory, e.g. /sys/fs/cgroup/myapp.slice or, on cgroup v1 hosts,
+// /sys/fs/cgroup/net_cls/myapp. It classifies traffic by application, which addresses and
+// ports cannot express.
+type CgroupMatch struct {
+	path string
+}
+
+// NewCgroupMatch creates a cgroup match; the path must be absolute
+func NewCgroupMatch(path string) (*CgroupMatch, error) {
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("cgroup path must be absolute: %s", path)
+	}
+	return &CgroupMatch{path: filepath.Clean(path)}, nil
+}
+
+// Type returns the match type
+func (m *CgroupMatch) Type() MatchType {
+	return MatchTypeCgroup
+}
+
+// String returns the string representation
+func (m *CgroupMatch) String() string {
+	return "cgroup " + m.path
+}
+
+// Path returns the path of the cgroup directory
+func (m *CgroupMatch) Path() string {
+	return m.path
+}
+
+// ParseCgroupMatch reconstructs a cgroup match from its string representation
+func ParseCgroupMatch(value string) (*CgroupMatch, error) {
+	path, ok := strings.CutPrefix(value, "cgroup ")
+	if !ok {
+		return nil, fmt.Errorf("invalid cgroup match format: %s", value)
+	}
+	return NewCgroupMatch(path)
+}
@@ -148,6 +148,7 @@ type HTBClass struct {
 	mpu      uint32 // Minimum packet unit (bytes)
 	mtu      uint32 // Maximum transmission unit (bytes)
 	prio     uint32 // Internal HTB priority (0-7)
+	profile  TuningProfile
 }
 
 // NewHTBClass creates a new HTB class
@@ -701,7 +702,7 @@ func (ch *ClassHierarchy) CalculateBandwidthDistribution(parentHandle tc.Handle,
 	if len(children) == 0 {
 		return &BandwidthDistribution{
 			TotalRate:             parentRate,
-			AllocatedRate:         tc.MustParseBandwidth("0bps"),
+			AllocatedRate:         tc.Bandwidth{},
 			AvailableRate:         parentRate,
 			ChildAllocations:      make(map[tc.Handle]tc.Bandwidth),
 			OversubscriptionRatio: 0.0,
@@ -714,14 +715,10 @@ func (ch *ClassHierarchy) CalculateBandwidthDistribution(parentHandle tc.Handle,
 	// Calculate total demand per priority group
 	for i := range priorityGroups {
 		group := &priorityGroups[i]
-		totalDemand := tc.MustParseBandwidth("0bps")
+		var totalDemand tc.Bandwidth
 		for _, childHandle := range group.Classes {
 			if htbClass := ch.getHTBClass(childHandle); htbClass != nil {
-				rate := htbClass.Rate()
-				if rate.BitsPerSecond() > 0 {
-					totalDemand = tc.MustParseBandwidth(fmt.Sprintf("%dbps",
-						totalDemand.BitsPerSecond()+rate.BitsPerSecond()))
-				}
+				totalDemand = totalDemand.Add(htbClass.Rate())
 			}
 		}
 		group.TotalDemand = totalDemand
@@ -730,30 +727,28 @@ func (ch *ClassHierarchy) CalculateBandwidthDistribution(parentHandle tc.Handle,
 	// Allocate bandwidth by priority (higher priority first)
 	allocations := make(map[tc.Handle]tc.Bandwidth)
 	remainingRate := parentRate
-	totalAllocated := tc.MustParseBandwidth("0bps")
+	var totalAllocated tc.Bandwidth
 
 	for _, group := range priorityGroups {
-		if remainingRate.BitsPerSecond() <= 0 {
+		if remainingRate.IsZero() {
 			break // No more bandwidth available
 		}
 
-		if group.TotalDemand.BitsPerSecond() == 0 {
+		if group.TotalDemand.IsZero() {
 			continue // No demand in this priority group
 		}
 
 		// Distribute available bandwidth among classes in this priority group
 		availableForGroup := remainingRate
-		if group.TotalDemand.BitsPerSecond() <= availableForGroup.BitsPerSecond() {
+		if group.TotalDemand.LessOrEqual(availableForGroup) {
 			// Enough bandwidth for full allocation
 			for _, childHandle := range group.Classes {
 				if htbClass := ch.getHTBClass(childHandle); htbClass != nil {
 					rate := htbClass.Rate()
-					if rate.BitsPerSecond() > 0 {
+					if !rate.IsZero() {
 						allocations[childHandle] = rate
-						totalAllocated = tc.MustParseBandwidth(fmt.Sprintf("%dbps",
-							totalAllocated.BitsPerSecond()+rate.BitsPerSecond()))
-						remainingRate = tc.MustParseBandwidth(fmt.Sprintf("%dbps",
-							remainingRate.BitsPerSecond()-rate.BitsPerSecond()))
+						totalAllocated = totalAllocated.Add(rate)
+						remainingRate = remainingRate.Sub(rate)
 					}
 				}
 			}
@@ -762,17 +757,15 @@ func (ch *ClassHierarchy) CalculateBandwidthDistribution(parentHandle tc.Handle,
 			for _, childHandle := range group.Classes {
 				if htbClass := ch.getHTBClass(childHandle); htbClass != nil {
 					rate := htbClass.Rate()
-					if rate.BitsPerSecond() > 0 && group.TotalDemand.BitsPerSecond() > 0 {
+					if !rate.IsZero() {
 						proportion := float64(rate.BitsPerSecond()) / float64(group.TotalDemand.BitsPerSecond())
-						allocated := uint64(float64(availableForGroup.BitsPerSecond()) * proportion)
-						allocatedBandwidth := tc.MustParseBandwidth(fmt.Sprintf("%dbps", allocated))
-						allocations[childHandle] = allocatedBandwidth
-						totalAllocated = tc.MustParseBandwidth(fmt.Sprintf("%dbps",
-							totalAllocated.BitsPerSecond()+allocated))
+						allocated := availableForGroup.MulScalar(proportion)
+						allocations[childHandle] = allocated
+						totalAllocated = totalAllocated.Add(allocated)
 					}
 				}
 			}
-			remainingRate = tc.MustParseBandwidth("0bps") // All available bandwidth used
+			remainingRate = tc.Bandwidth{} // All available bandwidth used
 		}
 	}
 
@@ -785,7 +778,7 @@ func (ch *ClassHierarchy) CalculateBandwidthDistribution(parentHandle tc.Handle,
 	return &BandwidthDistribution{
 		TotalRate:             parentRate,
 		AllocatedRate:         totalAllocated,
-		AvailableRate:         tc.MustParseBandwidth(fmt.Sprintf("%dbps", parentRate.BitsPerSecond()-totalAllocated.BitsPerSecond())),
+		AvailableRate:         parentRate.Sub(totalAllocated),
 		ChildAllocations:      allocations,
 		OversubscriptionRatio: oversubscriptionRatio,
 	}, nil
@@ -976,16 +969,29 @@ func (h *HTBClass) HTBPrio() uint32 {
 	return h.prio
 }
 
-// CalculateQuantum calculates appropriate quantum based on rate
+// SetTuningProfile sets the profile the burst, cburst and quantum are derived with
+func (h *HTBClass) SetTuningProfile(profile TuningProfile) {
+	h.profile = profile
+}
+
+// TuningProfile returns the profile the burst, cburst and quantum are derived with
+func (h *HTBClass) TuningProfile() TuningProfile {
+	if h.profile == "" {
+		return TuningProfileDefault
+	}
+	return h.profile
+}
+
+// CalculateQuantum calculates appropriate quantum based on rate and the tuning profile
 func (h *HTBClass) CalculateQuantum() uint32 {
-	// Quantum calculation: rate_bps / 8 / HZ
-	// Standard Linux HZ is typically 1000, so quantum = rate_bytes_per_second / 1000
-	// Minimum quantum is typically 1000 bytes, maximum is 60000 bytes
-	const (
-		MinQuantum = 1000  // Minimum quantum (1KB)
-		MaxQuantum = 60000 // Maximum quantum (60KB)
-		HZ         = 1000  // Linux timer frequency
-	)
+	// Quantum calculation: rate_bps / 8 / divisor, within the profile's bounds. The default
+	// divisor is HZ, typically 1000, so quantum = rate_bytes_per_second / 1000 within
+	// 1000-60000 bytes. Profiles without a divisor send one frame per round.
+	parameters := h.TuningProfile().parameters()
+	if parameters.quantumDivisor == 0 {
+		return h.frameSize()
+	}
+	MinQuantum, MaxQuantum := parameters.minQuantum, parameters.maxQuantum
 
 	if h.rate.BitsPerSecond() == 0 {
 		return MinQuantum
@@ -993,7 +999,7 @@ func (h *HTBClass) CalculateQuantum() uint32 {
 
 	bytesPerSecond := h.rate.BitsPerSecond() / 8
 	// Prevent integer overflow in conversion
-	quantumCalc := bytesPerSecond / HZ
+	quantumCalc := bytesPerSecond / parameters.quantumDivisor
 	var quantum uint32
 	if quantumCalc > 0xFFFFFFFF {
 		quantum = 0xFFFFFFFF
@@ -1012,19 +1018,28 @@ func (h *HTBClass) CalculateQuantum() uint32 {
 	return quantum
 }
 
+// frameSize returns the size of a full frame: the MTU, 1500 unless set, and its header
+func (h *HTBClass) frameSize() uint32 {
+	if h.mtu > 0 {
+		return h.mtu + ethernetHeaderBytes
+	}
+	return 1500 + ethernetHeaderBytes
+}
+
 // CalculateEnhancedBurst calculates burst with MTU and overhead considerations
 func (h *HTBClass) CalculateEnhancedBurst() uint32 {
-	// Enhanced burst calculation considering MTU, overhead, and timer resolution
-	const TimerResolutionMS = 64 // Linux timer resolution in milliseconds
+	// Enhanced burst calculation considering MTU, overhead, and the traffic the tuning
+	// profile bursts: 64ms by default, the timer resolution HTB is commonly built with
+	parameters := h.TuningProfile().parameters()
 
 	if h.rate.BitsPerSecond() == 0 {
-		return 1600 // Default minimum burst
+		return parameters.minBurst // Default minimum burst
 	}
 
-	// Calculate burst for timer resolution period
+	// Calculate burst for the profile's period
 	bytesPerSecond := h.rate.BitsPerSecond() / 8
 	// Prevent integer overflow in conversion
-	burstCalc := bytesPerSecond * TimerResolutionMS / 1000
+	burstCalc := bytesPerSecond * parameters.burstWindowMS / 1000
 	var burstBytes uint32
 	if burstCalc > 0xFFFFFFFF {
 		burstBytes = 0xFFFFFFFF
@@ -1050,9 +1065,9 @@ func (h *HTBClass) CalculateEnhancedBurst() uint32 {
 	}
 
 	// Ensure minimum burst considering MTU
-	minBurst := uint32(1600) // Default minimum
+	minBurst := parameters.minBurst // Default minimum
 	if h.mtu > 0 {
-		minBurst = h.mtu * 2 // At least 2 MTU-sized packets
+		minBurst = h.mtu * parameters.minBurstMTUs // At least that many MTU-sized packets
 	}
 
 	if burstBytes < minBurst {
@@ -1115,3 +1130,82 @@ func (h *HTBClass) ApplyDefaultParameters() {
 		h.cburst = h.CalculateEnhancedCburst()
 	}
 }
+
+// fairQueueingClassPriority is the priority reported by DRR and QFQ classes, which serve
+// their classes by weight rather than priority
+const fairQueueingClassPriority = Priority(4)
+
+// DRRClass represents a Deficit Round Robin class, which may send up to its quantum of
+// bytes each round
+type DRRClass struct {
+	*Class
+	quantum uint32 // bytes per round, 0 for the device MTU
+}
+
+// NewDRRClass creates a new DRR class
+func NewDRRClass(device tc.DeviceName, handle tc.Handle, parent tc.Handle, name string) *DRRClass {
+	return &DRRClass{
+		Class: NewClass(device, handle, parent, name, fairQueueingClassPriority),
+	}
+}
+
+// SetQuantum sets the bytes the class may send per round
+func (d *DRRClass) SetQuantum(quantum uint32) {
+	d.quantum = quantum
+}
+
+// Quantum returns the bytes the class may send per round
+func (d *DRRClass) Quantum() uint32 {
+	return d.quantum
+}
+
+// QFQ limits from net/sched/sch_qfq.c
+const (
+	QFQMaxWeight    = 1 << 10 // largest weight of a class
+	QFQMaxWeightSum = 1 << 16 // largest sum of the weights of a qdisc's classes
+	QFQMinLmax      = 512     // smallest maximum packet size
+	QFQMaxLmax      = 1 << 16 // largest maximum packet size
+)
+
+// QFQClass represents a Quick Fair Queueing class, which receives a share of the link
+// proportional to its weight
+type QFQClass struct {
+	*Class
+	weight uint32 // 0 for the kernel default of 1
+	lmax   uint32 // largest packet in bytes, 0 for the device MTU
+}
+
+// NewQFQClass creates a new QFQ class
+func NewQFQClass(device tc.DeviceName, handle tc.Handle, parent tc.Handle, name string) *QFQClass {
+	return &QFQClass{
+		Class: NewClass(device, handle, parent, name, fairQueueingClassPriority),
+	}
+}
+
+// SetWeight sets the class's share of the link relative to its siblings
+func (q *QFQClass) SetWeight(weight uint32) {
+	q.weight = weight
+}
+
+// Weight returns the class's share of the link relative to its siblings
+func (q *QFQClass) Weight() uint32 {
+	return q.weight
+}
+
+// SetLmax sets the largest packet the class sends, in bytes
+func (q *QFQClass) SetLmax(lmax uint32) {
+	q.lmax = lmax
+}
+
+// Lmax returns the largest packet the class sends, in bytes
+func (q *QFQClass) Lmax() uint32 {
+	return q.lmax
+}
+
+// EffectiveWeight returns the weight the kernel uses
+func (q *QFQClass) EffectiveWeight() uint32 {
+	if q.weight == 0 {
+		return 1
+	}
+	return q.weight
+}
@@ -3,6 +3,8 @@ package entities
 import (
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 
 	"github.com/rng999/traffic-control-go/pkg/tc"
 )
@@ -53,9 +55,11 @@ func (id FilterID) Handle() tc.Handle {
 // Filter represents a packet classification filter
 type Filter struct {
 	id       FilterID
+	kind     FilterKind
 	flowID   tc.Handle // Target class
 	protocol Protocol
 	matches  []Match
+	actions  []Action
 }
 
 // Protocol represents network protocol
@@ -96,6 +100,16 @@ func (f *Filter) Handle() tc.Handle {
 	return f.id.handle
 }
 
+// SetKind sets the classifier installing the filter
+func (f *Filter) SetKind(kind FilterKind) {
+	f.kind = kind
+}
+
+// Kind returns the classifier installing the filter
+func (f *Filter) Kind() FilterKind {
+	return f.kind
+}
+
 // SetFlowID sets the target class handle
 func (f *Filter) SetFlowID(flowID tc.Handle) {
 	f.flowID = flowID
@@ -126,6 +140,16 @@ func (f *Filter) Matches() []Match {
 	return f.matches
 }
 
+// AddAction appends an action to the filter's action chain
+func (f *Filter) AddAction(action Action) {
+	f.actions = append(f.actions, action)
+}
+
+// Actions returns the filter's action chain in execution order
+func (f *Filter) Actions() []Action {
+	return f.actions
+}
+
 // Match represents a filter matching condition
 type Match interface {
 	Type() MatchType
@@ -146,6 +170,14 @@ const (
 	MatchTypeTOS
 	MatchTypeDSCP
 	MatchTypeFlowID
+	MatchTypeTCPFlags
+	MatchTypePacketLength
+	MatchTypeRaw
+	MatchTypeMACSource
+	MatchTypeMACDestination
+	MatchTypeVLANID
+	MatchTypeVLANPriority
+	MatchTypeCgroup
 )
 
 // IPMatch represents an IP address match
@@ -262,6 +294,7 @@ const (
 	TransportProtocolTCP  TransportProtocol = 6
 	TransportProtocolUDP  TransportProtocol = 17
 	TransportProtocolICMP TransportProtocol = 1
+	TransportProtocolSCTP TransportProtocol = 132
 )
 
 // NewProtocolMatch creates a protocol match
@@ -316,6 +349,7 @@ func (m *MarkMatch) Mark() uint32 {
 // PortRangeMatch represents a port range match
 type PortRangeMatch struct {
 	matchType MatchType
+	source    bool
 	startPort uint16
 	endPort   uint16
 }
@@ -324,6 +358,7 @@ type PortRangeMatch struct {
 func NewPortSourceRangeMatch(startPort, endPort uint16) *PortRangeMatch {
 	return &PortRangeMatch{
 		matchType: MatchTypePortRange,
+		source:    true,
 		startPort: startPort,
 		endPort:   endPort,
 	}
@@ -345,7 +380,16 @@ func (m *PortRangeMatch) Type() MatchType {
 
 // String returns the string representation
 func (m *PortRangeMatch) String() string {
-	return fmt.Sprintf("port range %d-%d", m.startPort, m.endPort)
+	prefix := "dport"
+	if m.source {
+		prefix = "sport"
+	}
+	return fmt.Sprintf("%s range %d-%d", prefix, m.startPort, m.endPort)
+}
+
+// IsSource reports whether the range matches source rather than destination ports
+func (m *PortRangeMatch) IsSource() bool {
+	return m.source
 }
 
 // StartPort returns the start port
@@ -366,9 +410,14 @@ type TOSMatch struct {
 
 // NewTOSMatch creates a TOS match
 func NewTOSMatch(tos uint8) *TOSMatch {
+	return NewTOSMaskMatch(tos, 0xFF)
+}
+
+// NewTOSMaskMatch creates a match on the TOS bits selected by the mask
+func NewTOSMaskMatch(tos, mask uint8) *TOSMatch {
 	return &TOSMatch{
-		tos:  tos,
-		mask: 0xFF,
+		tos:  tos & mask,
+		mask: mask,
 	}
 }
 
@@ -387,6 +436,11 @@ func (m *TOSMatch) TOS() uint8 {
 	return m.tos
 }
 
+// Mask returns the TOS bits compared
+func (m *TOSMatch) Mask() uint8 {
+	return m.mask
+}
+
 // DSCPMatch represents a DSCP (Differentiated Services Code Point) match
 type DSCPMatch struct {
 	dscp uint8
@@ -414,6 +468,30 @@ func (m *DSCPMatch) DSCP() uint8 {
 	return m.dscp
 }
 
+// dscpNames are the DSCPs of the standard diffserv per-hop behaviours (RFC 2474, 2597,
+// 3246, 5865 and 8622)
+var dscpNames = map[string]uint8{
+	"BE": 0, "DF": 0, "LE": 1,
+	"CS0": 0, "CS1": 8, "CS2": 16, "CS3": 24, "CS4": 32, "CS5": 40, "CS6": 48, "CS7": 56,
+	"AF11": 10, "AF12": 12, "AF13": 14,
+	"AF21": 18, "AF22": 20, "AF23": 22,
+	"AF31": 26, "AF32": 28, "AF33": 30,
+	"AF41": 34, "AF42": 36, "AF43": 38,
+	"VA": 44, "EF": 46,
+}
+
+// ParseDSCP parses a diffserv class name such as "EF" or "AF41", or a DSCP from 0 to 63
+func ParseDSCP(value string) (uint8, error) {
+	if dscp, ok := dscpNames[strings.ToUpper(value)]; ok {
+		return dscp, nil
+	}
+	dscp, err := strconv.ParseUint(value, 0, 8)
+	if err != nil || dscp > 63 {
+		return 0, fmt.Errorf("unknown DSCP %q, must be a class name such as EF or AF41, or between 0 and 63", value)
+	}
+	return uint8(dscp), nil
+}
+
 // FlowIDMatch represents a flow-based match using hash tables
 type FlowIDMatch struct {
 	keys []string
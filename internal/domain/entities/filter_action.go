@@ -0,0 +1,348 @@
+package entities
+
+import (
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// ActionType represents the type of a filter action
+type ActionType int
+
+const (
+	ActionTypeMark         ActionType = iota // Set the packet's firewall mark
+	ActionTypePolice                         // Rate limit matched packets
+	ActionTypeMirror                         // Copy packets to another device's egress
+	ActionTypeRedirect                       // Hand packets to another device's egress
+	ActionTypeDSCP                           // Rewrite the DSCP of the IP header
+	ActionTypeDrop                           // Drop the packet
+	ActionTypePass                           // End the chain, leaving the packet to the filter's class
+	ActionTypePriority                       // Set the packet's priority
+	ActionTypeQueueMapping                   // Select the device transmit queue
+)
+
+// String returns the string representation of the action type
+func (t ActionType) String() string {
+	switch t {
+	case ActionTypeMark:
+		return "mark"
+	case ActionTypePolice:
+		return "police"
+	case ActionTypeMirror:
+		return "mirror"
+	case ActionTypeRedirect:
+		return "redirect"
+	case ActionTypeDSCP:
+		return "dscp"
+	case ActionTypeDrop:
+		return "drop"
+	case ActionTypePass:
+		return "pass"
+	case ActionTypePriority:
+		return "priority"
+	case ActionTypeQueueMapping:
+		return "queue_mapping"
+	default:
+		return "unknown"
+	}
+}
+
+// Action is one step of the action chain run, in order, on the packets a filter matches
+type Action interface {
+	Type() ActionType
+	String() string
+}
+
+// MarkAction sets the firewall mark of matched packets, e.g. to select a routing table
+type MarkAction struct {
+	mark uint32
+	mask uint32
+}
+
+// NewMarkAction creates a mark action; only the bits in the mask are changed
+func NewMarkAction(mark, mask uint32) *MarkAction {
+	return &MarkAction{
+		mark: mark,
+		mask: mask,
+	}
+}
+
+// Type returns the action type
+func (a *MarkAction) Type() ActionType {
+	return ActionTypeMark
+}
+
+// String returns the string representation
+func (a *MarkAction) String() string {
+	return fmt.Sprintf("skbedit mark 0x%x/0x%x", a.mark, a.mask)
+}
+
+// Mark returns the mark value
+func (a *MarkAction) Mark() uint32 {
+	return a.mark
+}
+
+// Mask returns the mark mask
+func (a *MarkAction) Mask() uint32 {
+	return a.mask
+}
+
+// PoliceExceedAction represents what a police action does with packets above its rate
+type PoliceExceedAction int
+
+const (
+	PoliceExceedDrop       PoliceExceedAction = iota // Drop the packet
+	PoliceExceedReclassify                           // Let later filters classify the packet
+)
+
+// String returns the string representation of the exceed action
+func (a PoliceExceedAction) String() string {
+	switch a {
+	case PoliceExceedDrop:
+		return "drop"
+	case PoliceExceedReclassify:
+		return "reclassify"
+	default:
+		return "unknown"
+	}
+}
+
+// ParsePoliceExceedAction parses an exceed action name
+func ParsePoliceExceedAction(name string) (PoliceExceedAction, error) {
+	switch name {
+	case "drop", "":
+		return PoliceExceedDrop, nil
+	case "reclassify":
+		return PoliceExceedReclassify, nil
+	default:
+		return 0, fmt.Errorf("unknown police exceed action %q", name)
+	}
+}
+
+// PoliceAction limits matched packets to a rate; packets within the rate continue down the chain
+type PoliceAction struct {
+	rate   tc.Bandwidth
+	burst  uint32
+	exceed PoliceExceedAction
+}
+
+// NewPoliceAction creates a police action with the given rate and burst in bytes
+func NewPoliceAction(rate tc.Bandwidth, burst uint32, exceed PoliceExceedAction) *PoliceAction {
+	return &PoliceAction{
+		rate:   rate,
+		burst:  burst,
+		exceed: exceed,
+	}
+}
+
+// Type returns the action type
+func (a *PoliceAction) Type() ActionType {
+	return ActionTypePolice
+}
+
+// String returns the string representation
+func (a *PoliceAction) String() string {
+	return fmt.Sprintf("police rate %s burst %d conform-exceed pipe/%s", a.rate, a.burst, a.exceed)
+}
+
+// Rate returns the policed rate
+func (a *PoliceAction) Rate() tc.Bandwidth {
+	return a.rate
+}
+
+// Burst returns the burst size in bytes
+func (a *PoliceAction) Burst() uint32 {
+	return a.burst
+}
+
+// Exceed returns the action for packets above the rate
+func (a *PoliceAction) Exceed() PoliceExceedAction {
+	return a.exceed
+}
+
+// MirredAction mirrors or redirects matched packets to the egress of another device
+type MirredAction struct {
+	actionType ActionType
+	target     tc.DeviceName
+}
+
+// NewMirrorAction creates an action that copies matched packets to the target device
+func NewMirrorAction(target tc.DeviceName) *MirredAction {
+	return &MirredAction{
+		actionType: ActionTypeMirror,
+		target:     target,
+	}
+}
+
+// NewRedirectAction creates an action that hands matched packets to the target device;
+// the packets leave this device's queues
+func NewRedirectAction(target tc.DeviceName) *MirredAction {
+	return &MirredAction{
+		actionType: ActionTypeRedirect,
+		target:     target,
+	}
+}
+
+// Type returns the action type
+func (a *MirredAction) Type() ActionType {
+	return a.actionType
+}
+
+// String returns the string representation
+func (a *MirredAction) String() string {
+	return fmt.Sprintf("mirred egress %s dev %s", a.actionType, a.target)
+}
+
+// Target returns the target device
+func (a *MirredAction) Target() tc.DeviceName {
+	return a.target
+}
+
+// DSCPRemarkAction rewrites the DSCP of matched IP packets, keeping the ECN bits, e.g. to
+// mark traffic for the diffserv class it was given here
+type DSCPRemarkAction struct {
+	dscp uint8
+}
+
+// NewDSCPRemarkAction creates an action setting the DSCP, from 0 to 63
+func NewDSCPRemarkAction(dscp uint8) *DSCPRemarkAction {
+	return &DSCPRemarkAction{dscp: dscp}
+}
+
+// Type returns the action type
+func (a *DSCPRemarkAction) Type() ActionType {
+	return ActionTypeDSCP
+}
+
+// String returns the string representation
+func (a *DSCPRemarkAction) String() string {
+	return fmt.Sprintf("pedit ex munge ip dsfield set %d", a.dscp)
+}
+
+// DSCP returns the DSCP set
+func (a *DSCPRemarkAction) DSCP() uint8 {
+	return a.dscp
+}
+
+// GactAction ends the action chain with a verdict: drop the packet, or pass it to the
+// filter's class
+type GactAction struct {
+	actionType ActionType
+}
+
+// NewDropAction creates an action dropping matched packets
+func NewDropAction() *GactAction {
+	return &GactAction{actionType: ActionTypeDrop}
+}
+
+// NewPassAction creates an action ending the chain; later actions are skipped and the
+// packet is queued in the filter's class
+func NewPassAction() *GactAction {
+	return &GactAction{actionType: ActionTypePass}
+}
+
+// Type returns the action type
+func (a *GactAction) Type() ActionType {
+	return a.actionType
+}
+
+// String returns the string representation
+func (a *GactAction) String() string {
+	return fmt.Sprintf("gact %s", a.actionType)
+}
+
+// PriorityAction sets the priority of matched packets, which prio and mqprio qdiscs map to
+// bands and which selects a class of classful qdiscs when it holds a class handle
+type PriorityAction struct {
+	priority tc.Handle
+}
+
+// NewPriorityAction creates an action setting the packet priority
+func NewPriorityAction(priority tc.Handle) *PriorityAction {
+	return &PriorityAction{priority: priority}
+}
+
+// Type returns the action type
+func (a *PriorityAction) Type() ActionType {
+	return ActionTypePriority
+}
+
+// String returns the string representation
+func (a *PriorityAction) String() string {
+	return fmt.Sprintf("skbedit priority %s", a.priority)
+}
+
+// Priority returns the priority set
+func (a *PriorityAction) Priority() tc.Handle {
+	return a.priority
+}
+
+// QueueMappingAction sends matched packets to a transmit queue of a multiqueue device
+type QueueMappingAction struct {
+	queue uint16
+}
+
+// NewQueueMappingAction creates an action selecting the transmit queue
+func NewQueueMappingAction(queue uint16) *QueueMappingAction {
+	return &QueueMappingAction{queue: queue}
+}
+
+// Type returns the action type
+func (a *QueueMappingAction) Type() ActionType {
+	return ActionTypeQueueMapping
+}
+
+// String returns the string representation
+func (a *QueueMappingAction) String() string {
+	return fmt.Sprintf("skbedit queue_mapping %d", a.queue)
+}
+
+// Queue returns the transmit queue
+func (a *QueueMappingAction) Queue() uint16 {
+	return a.queue
+}
+
+// ValidateActionChain checks that the actions of a filter can run in the given order:
+// a redirect, drop or pass ends the chain, so it must come last, and a packet is policed at
+// most once
+func ValidateActionChain(actions []Action) error {
+	policeCount := 0
+	mirrored := make(map[tc.DeviceName]bool)
+
+	for i, action := range actions {
+		switch a := action.(type) {
+		case *MarkAction, *PriorityAction, *QueueMappingAction:
+		case *GactAction:
+			if i != len(actions)-1 {
+				return fmt.Errorf("%s must be the last action, found %d after it", a.actionType, len(actions)-1-i)
+			}
+		case *DSCPRemarkAction:
+			if a.dscp > 63 {
+				return fmt.Errorf("DSCP must be between 0 and 63, got %d", a.dscp)
+			}
+		case *PoliceAction:
+			policeCount++
+			if policeCount > 1 {
+				return fmt.Errorf("at most one police action is allowed per filter")
+			}
+			if a.rate.BitsPerSecond() == 0 {
+				return fmt.Errorf("police action requires a rate")
+			}
+			if a.burst == 0 {
+				return fmt.Errorf("police action requires a burst")
+			}
+		case *MirredAction:
+			if a.actionType == ActionTypeRedirect && i != len(actions)-1 {
+				return fmt.Errorf("redirect to %s must be the last action, found %d after it", a.target, len(actions)-1-i)
+			}
+			if mirrored[a.target] {
+				return fmt.Errorf("packets are sent to %s more than once", a.target)
+			}
+			mirrored[a.target] = true
+		default:
+			return fmt.Errorf("unsupported filter action %v", action)
+		}
+	}
+
+	return nil
+}
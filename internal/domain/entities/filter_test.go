@@ -163,3 +163,65 @@ func TestProtocolMatch_Creation(t *testing.T) {
 		assert.Contains(t, match.String(), "17") // UDP is protocol 17
 	})
 }
+
+func TestValidateActionChain(t *testing.T) {
+	scrub, _ := tc.NewDeviceName("scrub0")
+	monitor, _ := tc.NewDeviceName("mon0")
+	police := NewPoliceAction(tc.Mbps(10), 64000, PoliceExceedDrop)
+
+	tests := []struct {
+		name    string
+		actions []Action
+		errMsg  string
+	}{
+		{"empty chain", nil, ""},
+		{"mark, police, redirect", []Action{NewMarkAction(0x10, 0xFFFFFFFF), police, NewRedirectAction(scrub)}, ""},
+		{"mirror before redirect", []Action{NewMirrorAction(monitor), NewRedirectAction(scrub)}, ""},
+		{"redirect before mark", []Action{NewRedirectAction(scrub), NewMarkAction(0x10, 0xFFFFFFFF)}, "must be the last action"},
+		{"two police actions", []Action{police, police}, "at most one police"},
+		{"police without burst", []Action{NewPoliceAction(tc.Mbps(10), 0, PoliceExceedDrop)}, "burst"},
+		{"mirror and redirect to same device", []Action{NewMirrorAction(scrub), NewRedirectAction(scrub)}, "more than once"},
+		{"dscp remark before redirect", []Action{NewDSCPRemarkAction(46), NewRedirectAction(scrub)}, ""},
+		{"dscp out of range", []Action{NewDSCPRemarkAction(64)}, "between 0 and 63"},
+		{"mirror then drop", []Action{NewMirrorAction(monitor), NewDropAction()}, ""},
+		{"priority and queue", []Action{NewPriorityAction(tc.NewHandle(1, 10)), NewQueueMappingAction(2), NewPassAction()}, ""},
+		{"drop before mark", []Action{NewDropAction(), NewMarkAction(0x10, 0xFFFFFFFF)}, "drop must be the last action"},
+		{"pass before police", []Action{NewPassAction(), police}, "pass must be the last action"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateActionChain(tt.actions)
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestParseDSCP(t *testing.T) {
+	tests := []struct {
+		value string
+		dscp  uint8
+	}{
+		{"EF", 46},
+		{"af41", 34},
+		{"CS1", 8},
+		{"BE", 0},
+		{"26", 26},
+		{"0x2e", 46},
+	}
+	for _, tt := range tests {
+		dscp, err := ParseDSCP(tt.value)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.dscp, dscp, tt.value)
+	}
+
+	for _, value := range []string{"AF44", "64", "-1", ""} {
+		_, err := ParseDSCP(value)
+		assert.Error(t, err, value)
+	}
+}
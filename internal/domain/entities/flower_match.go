@@ -0,0 +1,239 @@
+package entities
+
+import (
+	"fmt"
+	"net"
+)
+
+// FilterKind is the classifier a filter is installed with
+type FilterKind int
+
+const (
+	// FilterKindU32 matches header words at fixed offsets
+	FilterKindU32 FilterKind = iota
+	// FilterKindFlower matches parsed flow keys and can be offloaded to switch hardware
+	FilterKindFlower
+	// FilterKindCgroup matches the cgroup of the socket that sent the packet
+	FilterKindCgroup
+)
+
+// ParseFilterKind parses "u32", "flower" or "cgroup"; an empty kind is u32
+func ParseFilterKind(kind string) (FilterKind, error) {
+	switch kind {
+	case "", "u32":
+		return FilterKindU32, nil
+	case "flower":
+		return FilterKindFlower, nil
+	case "cgroup":
+		return FilterKindCgroup, nil
+	default:
+		return FilterKindU32, fmt.Errorf("unknown filter kind %q, must be u32, flower or cgroup", kind)
+	}
+}
+
+// String returns the classifier name
+func (k FilterKind) String() string {
+	switch k {
+	case FilterKindFlower:
+		return "flower"
+	case FilterKindCgroup:
+		return "cgroup"
+	default:
+		return "u32"
+	}
+}
+
+// Supports reports whether the classifier can express a match type. Only flower parses
+// Ethernet and VLAN headers and port ranges; only u32 matches arbitrary header words.
+// Cgroup filters match the originating cgroup and nothing else.
+func (k FilterKind) Supports(matchType MatchType) bool {
+	if k == FilterKindCgroup || matchType == MatchTypeCgroup {
+		return k == FilterKindCgroup && matchType == MatchTypeCgroup
+	}
+
+	switch matchType {
+	case MatchTypeIPSource, MatchTypeIPDestination, MatchTypePortSource, MatchTypePortDestination,
+		MatchTypeProtocol:
+		return true
+	case MatchTypeMACSource, MatchTypeMACDestination, MatchTypeVLANID, MatchTypeVLANPriority,
+		MatchTypePortRange:
+		return k == FilterKindFlower
+	default:
+		return k == FilterKindU32
+	}
+}
+
+// ValidateFilterMatches checks that the classifier can install the matches. Flower only
+// parses ports once the transport protocol is known, so port matches need a TCP, UDP or
+// SCTP protocol match.
+func ValidateFilterMatches(kind FilterKind, matches []Match) error {
+	hasPorts := false
+	var protocol *ProtocolMatch
+	for _, match := range matches {
+		if !kind.Supports(match.Type()) {
+			return fmt.Errorf("%s filters cannot match %s", kind, match)
+		}
+		switch m := match.(type) {
+		case *PortMatch, *PortRangeMatch:
+			hasPorts = true
+		case *ProtocolMatch:
+			protocol = m
+		}
+	}
+
+	if kind == FilterKindCgroup && len(matches) != 1 {
+		return fmt.Errorf("cgroup filters match exactly one cgroup, got %d matches", len(matches))
+	}
+
+	if kind == FilterKindFlower && hasPorts {
+		if protocol == nil {
+			return fmt.Errorf("flower port matches require a tcp, udp or sctp protocol match")
+		}
+		switch protocol.Protocol() {
+		case TransportProtocolTCP, TransportProtocolUDP, TransportProtocolSCTP:
+		default:
+			return fmt.Errorf("flower cannot match ports of protocol %d", protocol.Protocol())
+		}
+	}
+	return nil
+}
+
+// MACMatch matches the source or destination Ethernet address
+type MACMatch struct {
+	matchType MatchType
+	address   net.HardwareAddr
+}
+
+// NewMACSourceMatch creates a source MAC address match
+func NewMACSourceMatch(address string) (*MACMatch, error) {
+	return newMACMatch(MatchTypeMACSource, address)
+}
+
+// NewMACDestinationMatch creates a destination MAC address match
+func NewMACDestinationMatch(address string) (*MACMatch, error) {
+	return newMACMatch(MatchTypeMACDestination, address)
+}
+
+// newMACMatch parses an Ethernet address
+func newMACMatch(matchType MatchType, address string) (*MACMatch, error) {
+	mac, err := net.ParseMAC(address)
+	if err != nil || len(mac) != 6 {
+		return nil, fmt.Errorf("invalid MAC address: %s", address)
+	}
+	return &MACMatch{
+		matchType: matchType,
+		address:   mac,
+	}, nil
+}
+
+// Type returns the match type
+func (m *MACMatch) Type() MatchType {
+	return m.matchType
+}
+
+// String returns the string representation
+func (m *MACMatch) String() string {
+	prefix := "src"
+	if m.matchType == MatchTypeMACDestination {
+		prefix = "dst"
+	}
+	return fmt.Sprintf("eth %s %s", prefix, m.address)
+}
+
+// Address returns the Ethernet address
+func (m *MACMatch) Address() net.HardwareAddr {
+	return m.address
+}
+
+// VLANIDMatch matches the VLAN ID of 802.1Q tagged frames
+type VLANIDMatch struct {
+	id uint16
+}
+
+// NewVLANIDMatch creates a VLAN ID match; IDs range from 0 to 4095
+func NewVLANIDMatch(id uint16) (*VLANIDMatch, error) {
+	if id > 4095 {
+		return nil, fmt.Errorf("invalid VLAN ID %d, must be between 0 and 4095", id)
+	}
+	return &VLANIDMatch{id: id}, nil
+}
+
+// Type returns the match type
+func (m *VLANIDMatch) Type() MatchType {
+	return MatchTypeVLANID
+}
+
+// String returns the string representation
+func (m *VLANIDMatch) String() string {
+	return fmt.Sprintf("vlan id %d", m.id)
+}
+
+// ID returns the VLAN ID
+func (m *VLANIDMatch) ID() uint16 {
+	return m.id
+}
+
+// VLANPriorityMatch matches the priority code point of 802.1Q tagged frames
+type VLANPriorityMatch struct {
+	priority uint8
+}
+
+// NewVLANPriorityMatch creates a VLAN priority match; priorities range from 0 to 7
+func NewVLANPriorityMatch(priority uint8) (*VLANPriorityMatch, error) {
+	if priority > 7 {
+		return nil, fmt.Errorf("invalid VLAN priority %d, must be between 0 and 7", priority)
+	}
+	return &VLANPriorityMatch{priority: priority}, nil
+}
+
+// Type returns the match type
+func (m *VLANPriorityMatch) Type() MatchType {
+	return MatchTypeVLANPriority
+}
+
+// String returns the string representation
+func (m *VLANPriorityMatch) String() string {
+	return fmt.Sprintf("vlan prio %d", m.priority)
+}
+
+// Priority returns the VLAN priority
+func (m *VLANPriorityMatch) Priority() uint8 {
+	return m.priority
+}
+
+// ParseFlowerMatch reconstructs a MAC, VLAN or port range match from its string
+// representation
+func ParseFlowerMatch(matchType MatchType, value string) (Match, error) {
+	switch matchType {
+	case MatchTypeMACSource, MatchTypeMACDestination:
+		var direction, address string
+		if _, err := fmt.Sscanf(value, "eth %s %s", &direction, &address); err != nil {
+			return nil, fmt.Errorf("invalid MAC match format: %s", value)
+		}
+		return newMACMatch(matchType, address)
+	case MatchTypeVLANID:
+		var id uint16
+		if _, err := fmt.Sscanf(value, "vlan id %d", &id); err != nil {
+			return nil, fmt.Errorf("invalid VLAN ID match format: %s", value)
+		}
+		return NewVLANIDMatch(id)
+	case MatchTypeVLANPriority:
+		var priority uint8
+		if _, err := fmt.Sscanf(value, "vlan prio %d", &priority); err != nil {
+			return nil, fmt.Errorf("invalid VLAN priority match format: %s", value)
+		}
+		return NewVLANPriorityMatch(priority)
+	case MatchTypePortRange:
+		var direction string
+		var start, end uint16
+		if _, err := fmt.Sscanf(value, "%s range %d-%d", &direction, &start, &end); err != nil {
+			return nil, fmt.Errorf("invalid port range match format: %s", value)
+		}
+		if direction == "sport" {
+			return NewPortSourceRangeMatch(start, end), nil
+		}
+		return NewPortDestinationRangeMatch(start, end), nil
+	default:
+		return nil, fmt.Errorf("unsupported flower match type: %v", matchType)
+	}
+}
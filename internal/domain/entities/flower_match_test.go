@@ -0,0 +1,91 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFilterMatches(t *testing.T) {
+	mac, err := NewMACDestinationMatch("02:00:00:00:00:01")
+	require.NoError(t, err)
+	vlan, err := NewVLANIDMatch(100)
+	require.NoError(t, err)
+	raw, err := NewRawMatch(8, 0, 0xff)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		kind    FilterKind
+		matches []Match
+		wantErr string
+	}{
+		{"flower_l2", FilterKindFlower, []Match{mac, vlan}, ""},
+		{"flower_ports", FilterKindFlower, []Match{NewProtocolMatch(TransportProtocolTCP), NewPortDestinationRangeMatch(8000, 8080)}, ""},
+		{"flower_port_without_protocol", FilterKindFlower, []Match{NewPortDestinationMatch(443)}, "require a tcp, udp or sctp"},
+		{"flower_port_with_icmp", FilterKindFlower, []Match{NewProtocolMatch(TransportProtocolICMP), NewPortSourceMatch(1)}, "cannot match ports"},
+		{"flower_raw", FilterKindFlower, []Match{raw}, "flower filters cannot match"},
+		{"u32_vlan", FilterKindU32, []Match{vlan}, "u32 filters cannot match"},
+		{"u32_ports_without_protocol", FilterKindU32, []Match{NewPortDestinationMatch(443)}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFilterMatches(tt.kind, tt.matches)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestParseFlowerMatch(t *testing.T) {
+	source, err := NewMACSourceMatch("02:00:00:00:00:01")
+	require.NoError(t, err)
+	vlan, err := NewVLANIDMatch(4095)
+	require.NoError(t, err)
+	priority, err := NewVLANPriorityMatch(7)
+	require.NoError(t, err)
+
+	for _, match := range []Match{
+		source,
+		vlan,
+		priority,
+		NewPortSourceRangeMatch(1024, 2048),
+		NewPortDestinationRangeMatch(80, 443),
+	} {
+		parsed, err := ParseFlowerMatch(match.Type(), match.String())
+		require.NoError(t, err, match.String())
+		assert.Equal(t, match, parsed)
+	}
+
+	_, err = NewVLANIDMatch(4096)
+	assert.Error(t, err)
+	_, err = NewVLANPriorityMatch(8)
+	assert.Error(t, err)
+	_, err = ParseFilterKind("bpf")
+	assert.Error(t, err)
+}
+
+func TestCgroupMatch(t *testing.T) {
+	match, err := NewCgroupMatch("/sys/fs/cgroup/myapp.slice/")
+	require.NoError(t, err)
+	assert.Equal(t, "cgroup /sys/fs/cgroup/myapp.slice", match.String())
+
+	parsed, err := ParseCgroupMatch(match.String())
+	require.NoError(t, err)
+	assert.Equal(t, match, parsed)
+
+	_, err = NewCgroupMatch("myapp.slice")
+	assert.Error(t, err)
+
+	// Cgroup filters match exactly one cgroup and nothing else
+	assert.NoError(t, ValidateFilterMatches(FilterKindCgroup, []Match{match}))
+	assert.Error(t, ValidateFilterMatches(FilterKindCgroup, []Match{match, match}))
+	assert.Error(t, ValidateFilterMatches(FilterKindCgroup, []Match{NewProtocolMatch(TransportProtocolTCP)}))
+	assert.Error(t, ValidateFilterMatches(FilterKindU32, []Match{match}))
+}
@@ -0,0 +1,176 @@
+package entities
+
+import (
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// reservedQdiscMajor is the major number the kernel uses for the ingress and clsact qdiscs;
+// it is never handed out
+const reservedQdiscMajor = 0xffff
+
+// HandleAllocator hands out the qdisc, class and filter handles of a device. A handle is
+// free when it is neither in use in the kernel nor taken by an earlier allocation or pin.
+// Every handle is recorded with its owner, e.g. "class 'web'", to explain collisions.
+type HandleAllocator struct {
+	qdiscs  map[uint16]string    // Qdisc major -> owner
+	classes map[tc.Handle]string // Class handle -> owner
+	filters map[filterSlot]string
+}
+
+// filterSlot identifies a filter by its parent and priority, which the kernel keeps unique
+type filterSlot struct {
+	parent   tc.Handle
+	priority uint16
+}
+
+// NewHandleAllocator creates an allocator with every handle free
+func NewHandleAllocator() *HandleAllocator {
+	return &HandleAllocator{
+		qdiscs:  make(map[uint16]string),
+		classes: make(map[tc.Handle]string),
+		filters: make(map[filterSlot]string),
+	}
+}
+
+// MarkQdisc records a qdisc handle as in use, e.g. one read from the kernel
+func (a *HandleAllocator) MarkQdisc(handle tc.Handle, owner string) {
+	a.qdiscs[handle.Major()] = owner
+}
+
+// MarkClass records a class handle as in use
+func (a *HandleAllocator) MarkClass(handle tc.Handle, owner string) {
+	a.classes[handle] = owner
+}
+
+// MarkFilter records a filter priority under a parent as in use
+func (a *HandleAllocator) MarkFilter(parent tc.Handle, priority uint16, owner string) {
+	a.filters[filterSlot{parent, priority}] = owner
+}
+
+// ReserveQdisc pins a qdisc handle, failing when its major number is already in use
+func (a *HandleAllocator) ReserveQdisc(handle tc.Handle, owner string) error {
+	if handle.Major() == 0 || handle.Major() == reservedQdiscMajor {
+		return tcerrors.New(tcerrors.CodeInvalidConfiguration, owner,
+			"qdisc handle %s of %s is reserved by the kernel", handle, owner)
+	}
+	if used, ok := a.qdiscs[handle.Major()]; ok {
+		return conflict("qdisc", handle, owner, used)
+	}
+	a.qdiscs[handle.Major()] = owner
+	return nil
+}
+
+// ReserveClass pins a class handle, failing when it is already in use
+func (a *HandleAllocator) ReserveClass(handle tc.Handle, owner string) error {
+	if handle.Minor() == 0 {
+		return tcerrors.New(tcerrors.CodeInvalidConfiguration, owner,
+			"class handle %s of %s has no minor number", handle, owner)
+	}
+	if used, ok := a.classes[handle]; ok {
+		return conflict("class", handle, owner, used)
+	}
+	a.classes[handle] = owner
+	return nil
+}
+
+// ReserveFilter pins a filter priority under a parent, failing when it is already in use
+func (a *HandleAllocator) ReserveFilter(parent tc.Handle, priority uint16, owner string) error {
+	slot := filterSlot{parent, priority}
+	if used, ok := a.filters[slot]; ok {
+		return tcerrors.New(tcerrors.CodeHandleConflict, owner,
+			"filter priority %d under %s of %s is already used by %s", priority, parent, owner, used)
+	}
+	a.filters[slot] = owner
+	return nil
+}
+
+// AllocateQdisc assigns the preferred qdisc major number when it is free, and the next free
+// one above it otherwise
+func (a *HandleAllocator) AllocateQdisc(preferred uint16, owner string) (tc.Handle, error) {
+	major, ok := nextFree(preferred, func(major uint16) bool {
+		_, used := a.qdiscs[major]
+		return !used && major != reservedQdiscMajor
+	})
+	if !ok {
+		return tc.Handle{}, exhausted("qdisc", owner)
+	}
+	a.qdiscs[major] = owner
+	return tc.NewHandle(major, 0), nil
+}
+
+// AllocateClass assigns the class handle parent:preferred when it is free, and the next
+// free minor number under the parent otherwise
+func (a *HandleAllocator) AllocateClass(parent, preferred uint16, owner string) (tc.Handle, error) {
+	minor, ok := nextFree(preferred, func(minor uint16) bool {
+		_, used := a.classes[tc.NewHandle(parent, minor)]
+		return !used
+	})
+	if !ok {
+		return tc.Handle{}, exhausted("class", owner)
+	}
+	handle := tc.NewHandle(parent, minor)
+	a.classes[handle] = owner
+	return handle, nil
+}
+
+// AllocateClassWithLeaf assigns a class handle together with the handle of its leaf qdisc,
+// whose major number mirrors the class minor (1:11 -> 11:). The preferred minor is used when
+// both are free.
+func (a *HandleAllocator) AllocateClassWithLeaf(parent, preferred uint16, owner string) (class, leaf tc.Handle, err error) {
+	minor, ok := nextFree(preferred, func(minor uint16) bool {
+		_, classUsed := a.classes[tc.NewHandle(parent, minor)]
+		_, leafUsed := a.qdiscs[minor]
+		return !classUsed && !leafUsed && minor != reservedQdiscMajor
+	})
+	if !ok {
+		return tc.Handle{}, tc.Handle{}, exhausted("class", owner)
+	}
+	class, leaf = tc.NewHandle(parent, minor), tc.NewHandle(minor, 0)
+	a.classes[class] = owner
+	a.qdiscs[minor] = owner
+	return class, leaf, nil
+}
+
+// AllocateFilter assigns the preferred filter priority under a parent when it is free, and
+// the next free priority otherwise
+func (a *HandleAllocator) AllocateFilter(parent tc.Handle, preferred uint16, owner string) (uint16, error) {
+	priority, ok := nextFree(preferred, func(priority uint16) bool {
+		_, used := a.filters[filterSlot{parent, priority}]
+		return !used
+	})
+	if !ok {
+		return 0, exhausted("filter priority", owner)
+	}
+	a.filters[filterSlot{parent, priority}] = owner
+	return priority, nil
+}
+
+// nextFree returns the first number from start upwards, wrapping past the top and skipping
+// 0, that is free
+func nextFree(start uint16, free func(uint16) bool) (uint16, bool) {
+	if start == 0 {
+		start = 1
+	}
+	for n, i := start, 0; i < 0xffff; i++ {
+		if free(n) {
+			return n, true
+		}
+		if n++; n == 0 {
+			n = 1
+		}
+	}
+	return 0, false
+}
+
+func conflict(kind string, handle tc.Handle, owner, used string) error {
+	return tcerrors.New(tcerrors.CodeHandleConflict, owner,
+		"%s handle %s of %s is already used by %s", kind, handle, owner, used,
+	).WithHint(fmt.Sprintf("pin %s to another handle, or leave it unpinned to have one assigned", owner))
+}
+
+func exhausted(kind, owner string) error {
+	return tcerrors.New(tcerrors.CodeHandleConflict, owner, "no free %s handle left for %s", kind, owner)
+}
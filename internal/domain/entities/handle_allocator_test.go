@@ -0,0 +1,72 @@
+package entities
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+func TestHandleAllocator_AllocateClassWithLeaf(t *testing.T) {
+	allocator := NewHandleAllocator()
+	allocator.MarkQdisc(tc.NewHandle(1, 0), "root qdisc")
+
+	class, leaf, err := allocator.AllocateClassWithLeaf(1, 0x11, "class 'web'")
+	require.NoError(t, err)
+	assert.Equal(t, "1:11", class.String())
+	assert.Equal(t, "11:", leaf.String())
+
+	// The preferred handle is taken, so the next free one is assigned
+	class, leaf, err = allocator.AllocateClassWithLeaf(1, 0x11, "class 'ssh'")
+	require.NoError(t, err)
+	assert.Equal(t, "1:12", class.String())
+	assert.Equal(t, "12:", leaf.String())
+
+	// A class minor whose leaf major is used in the kernel is skipped
+	allocator.MarkQdisc(tc.NewHandle(0x13, 0), "kernel qdisc 13:")
+	class, _, err = allocator.AllocateClassWithLeaf(1, 0x13, "class 'bulk'")
+	require.NoError(t, err)
+	assert.Equal(t, "1:14", class.String())
+}
+
+func TestHandleAllocator_ReserveDetectsCollisions(t *testing.T) {
+	allocator := NewHandleAllocator()
+	allocator.MarkClass(tc.NewHandle(1, 0x999), "default class")
+
+	err := allocator.ReserveClass(tc.NewHandle(1, 0x999), "class 'web'")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, tcerrors.ErrHandleConflict))
+	assert.Equal(t, "class handle 1:999 of class 'web' is already used by default class", err.Error())
+
+	require.NoError(t, allocator.ReserveClass(tc.NewHandle(1, 0x20), "class 'web'"))
+	_, err = allocator.AllocateClass(1, 0x20, "class 'ssh'")
+	require.NoError(t, err)
+
+	assert.Error(t, allocator.ReserveClass(tc.NewHandle(1, 0), "class 'bulk'"))
+	assert.Error(t, allocator.ReserveQdisc(tc.NewHandle(0xffff, 0), "ingress"))
+
+	require.NoError(t, allocator.ReserveQdisc(tc.NewHandle(0x20, 0), "leaf of class 'web'"))
+	assert.True(t, errors.Is(allocator.ReserveQdisc(tc.NewHandle(0x20, 0), "leaf of class 'ssh'"), tcerrors.ErrHandleConflict))
+}
+
+func TestHandleAllocator_AllocateWrapsAndSkipsReserved(t *testing.T) {
+	allocator := NewHandleAllocator()
+	allocator.MarkQdisc(tc.NewHandle(0xfffe, 0), "kernel qdisc fffe:")
+
+	// 0xffff belongs to the ingress qdisc and 0 is not a handle, so allocation wraps to 1
+	handle, err := allocator.AllocateQdisc(0xfffe, "qdisc")
+	require.NoError(t, err)
+	assert.Equal(t, "1:", handle.String())
+
+	priority, err := allocator.AllocateFilter(tc.NewHandle(1, 0), 100, "filter of 'web'")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(100), priority)
+	priority, err = allocator.AllocateFilter(tc.NewHandle(1, 0), 100, "filter of 'ssh'")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(101), priority)
+	assert.Error(t, allocator.ReserveFilter(tc.NewHandle(1, 0), 101, "filter of 'bulk'"))
+}
@@ -19,6 +19,15 @@ const (
 	QdiscTypeCAKE
 	QdiscTypeCBQ
 	QdiscTypeHFSC
+	QdiscTypeFQ
+	QdiscTypeNETEM
+	QdiscTypeRED
+	QdiscTypeGRED
+	QdiscTypeCODEL
+	QdiscTypeETS
+	QdiscTypeTAPRIO
+	QdiscTypeDRR
+	QdiscTypeQFQ
 )
 
 // String returns the string representation of QdiscType
@@ -40,6 +49,24 @@ func (q QdiscType) String() string {
 		return "cbq"
 	case QdiscTypeHFSC:
 		return "hfsc"
+	case QdiscTypeFQ:
+		return "fq"
+	case QdiscTypeNETEM:
+		return "netem"
+	case QdiscTypeRED:
+		return "red"
+	case QdiscTypeGRED:
+		return "gred"
+	case QdiscTypeCODEL:
+		return "codel"
+	case QdiscTypeETS:
+		return "ets"
+	case QdiscTypeTAPRIO:
+		return "taprio"
+	case QdiscTypeDRR:
+		return "drr"
+	case QdiscTypeQFQ:
+		return "qfq"
 	default:
 		return "unknown"
 	}
@@ -139,11 +166,12 @@ type HTBQdisc struct {
 // NewHTBQdisc creates a new HTB qdisc
 func NewHTBQdisc(device tc.DeviceName, handle tc.Handle, defaultClass tc.Handle) *HTBQdisc {
 	qdisc := NewQdisc(device, handle, QdiscTypeHTB)
-	return &HTBQdisc{
-		Qdisc:        qdisc,
-		defaultClass: defaultClass,
-		r2q:          10, // default value
+	htb := &HTBQdisc{
+		Qdisc: qdisc,
+		r2q:   10, // default value
 	}
+	htb.SetDefaultClass(defaultClass)
+	return htb
 }
 
 // DefaultClass returns the default class handle
@@ -154,6 +182,8 @@ func (h *HTBQdisc) DefaultClass() tc.Handle {
 // SetDefaultClass sets the default class handle
 func (h *HTBQdisc) SetDefaultClass(handle tc.Handle) {
 	h.defaultClass = handle
+	// The adapters take the base qdisc, so the minor number travels as a parameter
+	h.SetParameter("default_class", uint32(handle.Minor()))
 }
 
 // R2Q returns the rate to quantum ratio
@@ -357,3 +387,833 @@ func (f *FQCODELQdisc) ECN() bool {
 func (f *FQCODELQdisc) SetECN(ecn bool) {
 	f.ecn = ecn
 }
+
+// SFQQdisc represents a Stochastic Fairness Queueing qdisc
+type SFQQdisc struct {
+	*Qdisc
+	perturb uint32 // hash perturbation period in seconds, 0 disables
+	quantum uint32 // bytes dequeued per flow per round
+	limit   uint32 // packet limit
+}
+
+// NewSFQQdisc creates a new SFQ qdisc
+func NewSFQQdisc(device tc.DeviceName, handle tc.Handle) *SFQQdisc {
+	qdisc := NewQdisc(device, handle, QdiscTypeSFQ)
+	return &SFQQdisc{
+		Qdisc:   qdisc,
+		perturb: 0,    // kernel default: no perturbation
+		quantum: 1514, // one full Ethernet frame
+		limit:   127,  // kernel default packet limit
+	}
+}
+
+// Perturb returns the hash perturbation period in seconds
+func (s *SFQQdisc) Perturb() uint32 {
+	return s.perturb
+}
+
+// SetPerturb sets the hash perturbation period in seconds
+func (s *SFQQdisc) SetPerturb(perturb uint32) {
+	s.perturb = perturb
+}
+
+// Quantum returns the quantum
+func (s *SFQQdisc) Quantum() uint32 {
+	return s.quantum
+}
+
+// SetQuantum sets the quantum
+func (s *SFQQdisc) SetQuantum(quantum uint32) {
+	s.quantum = quantum
+}
+
+// Limit returns the packet limit
+func (s *SFQQdisc) Limit() uint32 {
+	return s.limit
+}
+
+// SetLimit sets the packet limit
+func (s *SFQQdisc) SetLimit(limit uint32) {
+	s.limit = limit
+}
+
+// FQQdisc represents a Fair Queue (per-flow pacing) qdisc
+type FQQdisc struct {
+	*Qdisc
+	limit          uint32       // total packet limit
+	flowLimit      uint32       // per-flow packet limit
+	quantum        uint32       // credit per dequeue round in bytes
+	initialQuantum uint32       // credit of a new flow in bytes
+	maxRate        tc.Bandwidth // per-flow rate cap, zero for unlimited
+	pacing         bool         // pace flows at the rate set by their sockets
+}
+
+// NewFQQdisc creates a new FQ qdisc
+func NewFQQdisc(device tc.DeviceName, handle tc.Handle) *FQQdisc {
+	qdisc := NewQdisc(device, handle, QdiscTypeFQ)
+	return &FQQdisc{
+		Qdisc:          qdisc,
+		limit:          10000, // kernel default packet limit
+		flowLimit:      100,   // kernel default per-flow limit
+		quantum:        3028,  // two full Ethernet frames
+		initialQuantum: 15140, // ten full Ethernet frames
+		pacing:         true,  // kernel default
+	}
+}
+
+// Limit returns the total packet limit
+func (f *FQQdisc) Limit() uint32 {
+	return f.limit
+}
+
+// SetLimit sets the total packet limit
+func (f *FQQdisc) SetLimit(limit uint32) {
+	f.limit = limit
+}
+
+// FlowLimit returns the per-flow packet limit
+func (f *FQQdisc) FlowLimit() uint32 {
+	return f.flowLimit
+}
+
+// SetFlowLimit sets the per-flow packet limit
+func (f *FQQdisc) SetFlowLimit(flowLimit uint32) {
+	f.flowLimit = flowLimit
+}
+
+// Quantum returns the quantum
+func (f *FQQdisc) Quantum() uint32 {
+	return f.quantum
+}
+
+// SetQuantum sets the quantum
+func (f *FQQdisc) SetQuantum(quantum uint32) {
+	f.quantum = quantum
+}
+
+// InitialQuantum returns the initial quantum of new flows
+func (f *FQQdisc) InitialQuantum() uint32 {
+	return f.initialQuantum
+}
+
+// SetInitialQuantum sets the initial quantum of new flows
+func (f *FQQdisc) SetInitialQuantum(initialQuantum uint32) {
+	f.initialQuantum = initialQuantum
+}
+
+// MaxRate returns the per-flow rate cap
+func (f *FQQdisc) MaxRate() tc.Bandwidth {
+	return f.maxRate
+}
+
+// SetMaxRate sets the per-flow rate cap
+func (f *FQQdisc) SetMaxRate(maxRate tc.Bandwidth) {
+	f.maxRate = maxRate
+}
+
+// Pacing returns whether flow pacing is enabled
+func (f *FQQdisc) Pacing() bool {
+	return f.pacing
+}
+
+// SetPacing sets whether flow pacing is enabled
+func (f *FQQdisc) SetPacing(pacing bool) {
+	f.pacing = pacing
+}
+
+// NETEMParameters describes the network conditions emulated by a NETEM qdisc
+type NETEMParameters struct {
+	Delay     uint32  // added delay in microseconds
+	Jitter    uint32  // delay variation in microseconds
+	Loss      float32 // percentage of packets dropped
+	Duplicate float32 // percentage of packets duplicated
+	Corrupt   float32 // percentage of packets with a flipped bit
+	Reorder   float32 // percentage of packets sent immediately, ahead of delayed ones
+	Gap       uint32  // reorder every gap-th packet instead of at random
+	Limit     uint32  // packet limit
+}
+
+// Validate checks that the parameters describe conditions NETEM can emulate
+func (p NETEMParameters) Validate() error {
+	percentages := []struct {
+		name  string
+		value float32
+	}{
+		{"loss", p.Loss},
+		{"duplicate", p.Duplicate},
+		{"corrupt", p.Corrupt},
+		{"reorder", p.Reorder},
+	}
+	for _, pct := range percentages {
+		if pct.value < 0 || pct.value > 100 {
+			return fmt.Errorf("%s must be between 0 and 100 percent, got %g", pct.name, pct.value)
+		}
+	}
+
+	if p.Reorder > 0 && p.Delay == 0 {
+		return fmt.Errorf("reordering requires a delay")
+	}
+	if p.Gap > 0 && p.Reorder == 0 {
+		return fmt.Errorf("gap requires a reorder percentage")
+	}
+	if p.Limit == 0 {
+		return fmt.Errorf("limit must be positive, got %d", p.Limit)
+	}
+
+	return nil
+}
+
+// NETEMQdisc represents a network emulation qdisc
+type NETEMQdisc struct {
+	*Qdisc
+	parameters NETEMParameters
+}
+
+// NewNETEMQdisc creates a new NETEM qdisc
+func NewNETEMQdisc(device tc.DeviceName, handle tc.Handle, parameters NETEMParameters) *NETEMQdisc {
+	qdisc := NewQdisc(device, handle, QdiscTypeNETEM)
+	return &NETEMQdisc{
+		Qdisc:      qdisc,
+		parameters: parameters,
+	}
+}
+
+// NETEMParameters returns the emulated network conditions
+func (n *NETEMQdisc) NETEMParameters() NETEMParameters {
+	return n.parameters
+}
+
+// REDThresholds describes when a Random Early Detection queue starts dropping or marking
+// packets. Queue sizes are in bytes and refer to the average queue size.
+type REDThresholds struct {
+	Limit       uint32       // hard queue limit in bytes
+	Min         uint32       // average queue size where marking starts
+	Max         uint32       // average queue size where marking reaches the probability
+	Avpkt       uint32       // average packet size in bytes
+	Burst       uint32       // packets allowed to arrive at once, 0 derives it from the thresholds
+	Bandwidth   tc.Bandwidth // link rate, used to age the average while the queue is idle
+	Probability float64      // marking probability at the max threshold, between 0 and 1
+}
+
+// EffectiveBurst returns the burst, deriving it from the thresholds as tc(8) does when unset
+func (t REDThresholds) EffectiveBurst() uint32 {
+	if t.Burst != 0 || t.Avpkt == 0 {
+		return t.Burst
+	}
+	return (2*t.Min + t.Max) / (3 * t.Avpkt)
+}
+
+// Validate checks that the thresholds describe a queue RED can manage
+func (t REDThresholds) Validate() error {
+	if t.Min == 0 {
+		return fmt.Errorf("min threshold must be positive")
+	}
+	if t.Max <= t.Min {
+		return fmt.Errorf("max threshold %d must be above min threshold %d", t.Max, t.Min)
+	}
+	if t.Limit < t.Max {
+		return fmt.Errorf("limit %d must be at least the max threshold %d", t.Limit, t.Max)
+	}
+	if t.Avpkt == 0 {
+		return fmt.Errorf("average packet size must be positive")
+	}
+	if t.Bandwidth.BitsPerSecond() == 0 {
+		return fmt.Errorf("bandwidth must be positive")
+	}
+	if t.Probability <= 0 || t.Probability > 1 {
+		return fmt.Errorf("probability must be above 0 and at most 1, got %g", t.Probability)
+	}
+	// The average must be able to reach the min threshold within one burst
+	if burst := t.EffectiveBurst(); burst < t.Min/t.Avpkt {
+		return fmt.Errorf("burst %d is too small for min threshold %d, use at least %d", burst, t.Min, t.Min/t.Avpkt)
+	}
+
+	return nil
+}
+
+// REDParameters describes a Random Early Detection qdisc
+type REDParameters struct {
+	REDThresholds
+	ECN      bool // mark ECN-capable packets instead of dropping them
+	HardDrop bool // drop rather than mark above the max threshold
+	Adaptive bool // adapt the probability to keep the average between the thresholds
+}
+
+// Validate checks that the parameters describe a queue RED can manage
+func (p REDParameters) Validate() error {
+	if err := p.REDThresholds.Validate(); err != nil {
+		return err
+	}
+	if p.HardDrop && !p.ECN {
+		return fmt.Errorf("harddrop requires ecn")
+	}
+	return nil
+}
+
+// REDQdisc represents a Random Early Detection qdisc
+type REDQdisc struct {
+	*Qdisc
+	parameters REDParameters
+}
+
+// NewREDQdisc creates a new RED qdisc
+func NewREDQdisc(device tc.DeviceName, handle tc.Handle, parameters REDParameters) *REDQdisc {
+	qdisc := NewQdisc(device, handle, QdiscTypeRED)
+	return &REDQdisc{
+		Qdisc:      qdisc,
+		parameters: parameters,
+	}
+}
+
+// REDParameters returns the RED parameters
+func (r *REDQdisc) REDParameters() REDParameters {
+	return r.parameters
+}
+
+// GREDMaxVirtualQueues is the kernel's limit on the virtual queues of a GRED qdisc
+const GREDMaxVirtualQueues = 16
+
+// GREDVirtualQueue is one RED queue of a GRED qdisc; packets select it by their tc_index
+type GREDVirtualQueue struct {
+	REDThresholds
+	DP       uint32 // virtual queue number, matched against the packet's tc_index
+	Priority uint8  // priority in GRIO mode, lower values are served first
+}
+
+// GREDParameters describes a Generalized RED qdisc
+type GREDParameters struct {
+	Queues        uint32 // number of virtual queues
+	Default       uint32 // virtual queue of packets whose tc_index selects none
+	GRIO          bool   // RIO mode: averages include the backlog of higher priority queues
+	ECN           bool   // mark ECN-capable packets instead of dropping them
+	HardDrop      bool   // drop rather than mark above the max threshold
+	VirtualQueues []GREDVirtualQueue
+}
+
+// Validate checks that the parameters describe queues GRED can manage
+func (p GREDParameters) Validate() error {
+	if p.Queues == 0 || p.Queues > GREDMaxVirtualQueues {
+		return fmt.Errorf("queues must be between 1 and %d, got %d", GREDMaxVirtualQueues, p.Queues)
+	}
+	if p.Default >= p.Queues {
+		return fmt.Errorf("default virtual queue %d must be below the queue count %d", p.Default, p.Queues)
+	}
+	if p.HardDrop && !p.ECN {
+		return fmt.Errorf("harddrop requires ecn")
+	}
+	if len(p.VirtualQueues) == 0 {
+		return fmt.Errorf("at least one virtual queue is required")
+	}
+
+	configured := make(map[uint32]bool)
+	for _, vq := range p.VirtualQueues {
+		if vq.DP >= p.Queues {
+			return fmt.Errorf("virtual queue %d must be below the queue count %d", vq.DP, p.Queues)
+		}
+		if configured[vq.DP] {
+			return fmt.Errorf("virtual queue %d is configured more than once", vq.DP)
+		}
+		configured[vq.DP] = true
+
+		if err := vq.REDThresholds.Validate(); err != nil {
+			return fmt.Errorf("virtual queue %d: %w", vq.DP, err)
+		}
+	}
+
+	return nil
+}
+
+// GREDQdisc represents a Generalized RED qdisc with several virtual RED queues
+type GREDQdisc struct {
+	*Qdisc
+	parameters GREDParameters
+}
+
+// NewGREDQdisc creates a new GRED qdisc
+func NewGREDQdisc(device tc.DeviceName, handle tc.Handle, parameters GREDParameters) *GREDQdisc {
+	qdisc := NewQdisc(device, handle, QdiscTypeGRED)
+	return &GREDQdisc{
+		Qdisc:      qdisc,
+		parameters: parameters,
+	}
+}
+
+// GREDParameters returns the GRED parameters
+func (g *GREDQdisc) GREDParameters() GREDParameters {
+	return g.parameters
+}
+
+// CODELParameters describes a Controlled Delay qdisc
+type CODELParameters struct {
+	Limit       uint32 // packet limit
+	Target      uint32 // acceptable standing queue delay in microseconds
+	Interval    uint32 // window in microseconds over which the delay must fall below target
+	CEThreshold uint32 // delay in microseconds above which packets are ECN marked, 0 disables
+	ECN         bool   // mark ECN-capable packets instead of dropping them
+}
+
+// Validate checks that the parameters describe a queue CoDel can manage
+func (p CODELParameters) Validate() error {
+	if p.Limit == 0 {
+		return fmt.Errorf("limit must be positive, got %d", p.Limit)
+	}
+	if p.Target == 0 {
+		return fmt.Errorf("target must be positive")
+	}
+	if p.Interval < p.Target {
+		return fmt.Errorf("interval %dus must be at least the target %dus", p.Interval, p.Target)
+	}
+	return nil
+}
+
+// CODELQdisc represents a Controlled Delay qdisc
+type CODELQdisc struct {
+	*Qdisc
+	parameters CODELParameters
+}
+
+// NewCODELQdisc creates a new CoDel qdisc
+func NewCODELQdisc(device tc.DeviceName, handle tc.Handle, parameters CODELParameters) *CODELQdisc {
+	qdisc := NewQdisc(device, handle, QdiscTypeCODEL)
+	return &CODELQdisc{
+		Qdisc:      qdisc,
+		parameters: parameters,
+	}
+}
+
+// CODELParameters returns the CoDel parameters
+func (c *CODELQdisc) CODELParameters() CODELParameters {
+	return c.parameters
+}
+
+// ETSMaxBands is the kernel's limit on the bands of an ETS qdisc
+const ETSMaxBands = 16
+
+// PriomapSize is the number of packet priorities a priomap maps, TC_PRIO_MAX + 1
+const PriomapSize = 16
+
+// ETSParameters describes an Enhanced Transmission Selection (802.1Qaz) qdisc. The strict
+// bands come first and are served in order; the remaining bands share what is left in
+// proportion to their quanta. Each band is a class, numbered from 1.
+type ETSParameters struct {
+	Bands   uint8    // total bands, 0 derives it from the strict bands and quanta
+	Strict  uint8    // number of strict priority bands
+	Quanta  []uint32 // DRR quantum in bytes of each bandwidth-sharing band, after the strict ones
+	Priomap []uint8  // band of each packet priority; unmapped priorities use the last band
+}
+
+// EffectiveBands returns the number of bands, deriving it as tc(8) does when unset
+func (p ETSParameters) EffectiveBands() uint8 {
+	if p.Bands != 0 {
+		return p.Bands
+	}
+	return p.Strict + uint8(len(p.Quanta)) // #nosec G115 - Validate bounds the quanta
+}
+
+// Validate checks that the parameters describe bands ETS can schedule
+func (p ETSParameters) Validate() error {
+	if len(p.Quanta) > ETSMaxBands {
+		return fmt.Errorf("at most %d bands are allowed, got %d quanta", ETSMaxBands, len(p.Quanta))
+	}
+	bands := p.EffectiveBands()
+	if bands == 0 || bands > ETSMaxBands {
+		return fmt.Errorf("bands must be between 1 and %d, got %d", ETSMaxBands, bands)
+	}
+	if int(p.Strict)+len(p.Quanta) > int(bands) {
+		return fmt.Errorf("%d strict bands and %d quanta do not fit in %d bands", p.Strict, len(p.Quanta), bands)
+	}
+	for i, quantum := range p.Quanta {
+		if quantum == 0 {
+			return fmt.Errorf("quantum of band %d must be positive", int(p.Strict)+i+1)
+		}
+	}
+	if len(p.Priomap) > PriomapSize {
+		return fmt.Errorf("priomap maps at most %d priorities, got %d", PriomapSize, len(p.Priomap))
+	}
+	for priority, band := range p.Priomap {
+		if band >= bands {
+			return fmt.Errorf("priority %d maps to band %d, beyond the %d bands", priority, band, bands)
+		}
+	}
+	return nil
+}
+
+// ETSQdisc represents an Enhanced Transmission Selection qdisc
+type ETSQdisc struct {
+	*Qdisc
+	parameters ETSParameters
+}
+
+// NewETSQdisc creates a new ETS qdisc
+func NewETSQdisc(device tc.DeviceName, handle tc.Handle, parameters ETSParameters) *ETSQdisc {
+	qdisc := NewQdisc(device, handle, QdiscTypeETS)
+	return &ETSQdisc{
+		Qdisc:      qdisc,
+		parameters: parameters,
+	}
+}
+
+// ETSParameters returns the ETS parameters
+func (e *ETSQdisc) ETSParameters() ETSParameters {
+	return e.parameters
+}
+
+// TAPRIOMaxTrafficClasses is the kernel's limit on the traffic classes of a taprio qdisc
+const TAPRIOMaxTrafficClasses = 16
+
+// TAPRIOGateCommand is the operation of a taprio schedule entry. The values match the
+// kernel's TC_TAPRIO_CMD_* constants.
+type TAPRIOGateCommand uint8
+
+const (
+	TAPRIOSetGates      TAPRIOGateCommand = iota // open the entry's gates and close the others
+	TAPRIOSetAndHold                             // set the gates and hold preemptible traffic (802.1Qbu)
+	TAPRIOSetAndRelease                          // set the gates and release preemptible traffic
+)
+
+// String returns the tc(8) name of the command
+func (c TAPRIOGateCommand) String() string {
+	switch c {
+	case TAPRIOSetGates:
+		return "S"
+	case TAPRIOSetAndHold:
+		return "H"
+	case TAPRIOSetAndRelease:
+		return "R"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTAPRIOGateCommand parses a tc(8) schedule entry command: S, H or R
+func ParseTAPRIOGateCommand(s string) (TAPRIOGateCommand, error) {
+	for command := TAPRIOSetGates; command <= TAPRIOSetAndRelease; command++ {
+		if command.String() == s {
+			return command, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown taprio gate command %q", s)
+}
+
+// TAPRIOClock is the clock a taprio schedule runs on. The values match the kernel's
+// clock IDs.
+type TAPRIOClock int32
+
+const (
+	TAPRIOClockRealtime  TAPRIOClock = 0
+	TAPRIOClockMonotonic TAPRIOClock = 1
+	TAPRIOClockBoottime  TAPRIOClock = 7
+	TAPRIOClockTAI       TAPRIOClock = 11
+)
+
+// String returns the tc(8) name of the clock
+func (c TAPRIOClock) String() string {
+	switch c {
+	case TAPRIOClockRealtime:
+		return "CLOCK_REALTIME"
+	case TAPRIOClockMonotonic:
+		return "CLOCK_MONOTONIC"
+	case TAPRIOClockBoottime:
+		return "CLOCK_BOOTTIME"
+	case TAPRIOClockTAI:
+		return "CLOCK_TAI"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTAPRIOClock parses a tc(8) clock name such as CLOCK_TAI
+func ParseTAPRIOClock(s string) (TAPRIOClock, error) {
+	for _, clock := range []TAPRIOClock{TAPRIOClockRealtime, TAPRIOClockMonotonic, TAPRIOClockBoottime, TAPRIOClockTAI} {
+		if clock.String() == s {
+			return clock, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown taprio clock %q", s)
+}
+
+// TAPRIOScheduleEntry is one interval of a taprio gate control list
+type TAPRIOScheduleEntry struct {
+	Command  TAPRIOGateCommand
+	GateMask uint32 // bit n opens the gate of traffic class n
+	Interval uint32 // nanoseconds
+}
+
+// TAPRIOQueueRange assigns a traffic class a contiguous range of transmit queues
+type TAPRIOQueueRange struct {
+	Count  uint16
+	Offset uint16
+}
+
+// String returns the range in tc(8) count@offset form
+func (r TAPRIOQueueRange) String() string {
+	return fmt.Sprintf("%d@%d", r.Count, r.Offset)
+}
+
+// TAPRIOParameters describes a time-aware priority shaper (802.1Qbv). Packet priorities
+// map to traffic classes, each with its own transmit queues, and a cyclic gate schedule
+// starting at the base time decides which classes may transmit.
+type TAPRIOParameters struct {
+	NumTC              uint8              // number of traffic classes
+	Priomap            []uint8            // traffic class of each packet priority; unmapped ones use class 0
+	Queues             []TAPRIOQueueRange // transmit queues of each traffic class
+	BaseTime           int64              // schedule start in nanoseconds on the clock
+	CycleTime          int64              // nanoseconds, 0 for the sum of the intervals
+	CycleTimeExtension int64              // nanoseconds a cycle may be stretched to start the next schedule
+	Clock              TAPRIOClock        // ignored with full offload, which uses the NIC's clock
+	Schedule           []TAPRIOScheduleEntry
+	TxTimeAssist       bool   // set transmit times for ETF qdiscs on the queues
+	FullOffload        bool   // run the schedule in the NIC
+	TxTimeDelay        uint32 // nanoseconds between the transmit time and the NIC sending, with TxTimeAssist
+}
+
+// ScheduleDuration returns the sum of the schedule's intervals in nanoseconds
+func (p TAPRIOParameters) ScheduleDuration() int64 {
+	var total int64
+	for _, entry := range p.Schedule {
+		total += int64(entry.Interval)
+	}
+	return total
+}
+
+// Validate checks that the parameters describe a schedule taprio can run
+func (p TAPRIOParameters) Validate() error {
+	if p.NumTC == 0 || p.NumTC > TAPRIOMaxTrafficClasses {
+		return fmt.Errorf("traffic classes must be between 1 and %d, got %d", TAPRIOMaxTrafficClasses, p.NumTC)
+	}
+	if len(p.Priomap) > PriomapSize {
+		return fmt.Errorf("priomap maps at most %d priorities, got %d", PriomapSize, len(p.Priomap))
+	}
+	for priority, class := range p.Priomap {
+		if class >= p.NumTC {
+			return fmt.Errorf("priority %d maps to traffic class %d, beyond the %d classes", priority, class, p.NumTC)
+		}
+	}
+
+	if len(p.Queues) != int(p.NumTC) {
+		return fmt.Errorf("each of the %d traffic classes needs a queue range, got %d", p.NumTC, len(p.Queues))
+	}
+	for i, queues := range p.Queues {
+		if queues.Count == 0 {
+			return fmt.Errorf("traffic class %d has no queues", i)
+		}
+		for j := 0; j < i; j++ {
+			other := p.Queues[j]
+			if queues.Offset < other.Offset+other.Count && other.Offset < queues.Offset+queues.Count {
+				return fmt.Errorf("queues %s of traffic class %d overlap queues %s of traffic class %d", queues, i, other, j)
+			}
+		}
+	}
+
+	if len(p.Schedule) == 0 {
+		return fmt.Errorf("at least one schedule entry is required")
+	}
+	for i, entry := range p.Schedule {
+		if entry.Command > TAPRIOSetAndRelease {
+			return fmt.Errorf("schedule entry %d has unknown command %d", i, entry.Command)
+		}
+		if entry.Interval == 0 {
+			return fmt.Errorf("schedule entry %d must have a positive interval", i)
+		}
+		if entry.GateMask>>p.NumTC != 0 {
+			return fmt.Errorf("schedule entry %d opens gates %#x beyond the %d traffic classes", i, entry.GateMask, p.NumTC)
+		}
+	}
+
+	if p.BaseTime < 0 {
+		return fmt.Errorf("base time must not be negative")
+	}
+	if p.CycleTime < 0 {
+		return fmt.Errorf("cycle time must not be negative")
+	}
+	if p.CycleTimeExtension < 0 {
+		return fmt.Errorf("cycle time extension must not be negative")
+	}
+	cycle := p.CycleTime
+	if cycle == 0 {
+		cycle = p.ScheduleDuration()
+	}
+	if p.CycleTimeExtension > cycle {
+		return fmt.Errorf("cycle time extension %dns exceeds the cycle time %dns", p.CycleTimeExtension, cycle)
+	}
+
+	if p.TxTimeAssist && p.FullOffload {
+		return fmt.Errorf("txtime assist and full offload cannot be combined")
+	}
+	if p.TxTimeDelay != 0 && !p.TxTimeAssist {
+		return fmt.Errorf("txtime delay requires txtime assist")
+	}
+	return nil
+}
+
+// TAPRIOQdisc represents a time-aware priority shaper qdisc
+type TAPRIOQdisc struct {
+	*Qdisc
+	parameters TAPRIOParameters
+}
+
+// NewTAPRIOQdisc creates a new taprio qdisc
+func NewTAPRIOQdisc(device tc.DeviceName, handle tc.Handle, parameters TAPRIOParameters) *TAPRIOQdisc {
+	qdisc := NewQdisc(device, handle, QdiscTypeTAPRIO)
+	return &TAPRIOQdisc{
+		Qdisc:      qdisc,
+		parameters: parameters,
+	}
+}
+
+// TAPRIOParameters returns the taprio parameters
+func (t *TAPRIOQdisc) TAPRIOParameters() TAPRIOParameters {
+	return t.parameters
+}
+
+// CAKEDiffservMode selects how CAKE splits traffic into priority tins. The values match
+// the kernel's CAKE_DIFFSERV_* constants.
+type CAKEDiffservMode int
+
+const (
+	CAKEDiffserv3 CAKEDiffservMode = iota
+	CAKEDiffserv4
+	CAKEDiffserv8
+	CAKEBestEffort
+	CAKEPrecedence
+)
+
+// String returns the tc(8) name of the diffserv mode
+func (m CAKEDiffservMode) String() string {
+	switch m {
+	case CAKEDiffserv3:
+		return "diffserv3"
+	case CAKEDiffserv4:
+		return "diffserv4"
+	case CAKEDiffserv8:
+		return "diffserv8"
+	case CAKEBestEffort:
+		return "besteffort"
+	case CAKEPrecedence:
+		return "precedence"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseCAKEDiffservMode parses a tc(8) diffserv mode name
+func ParseCAKEDiffservMode(s string) (CAKEDiffservMode, error) {
+	for mode := CAKEDiffserv3; mode <= CAKEPrecedence; mode++ {
+		if mode.String() == s {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown CAKE diffserv mode %q", s)
+}
+
+// CAKEAckFilter selects how CAKE filters redundant TCP ACKs. The values match the
+// kernel's CAKE_ACK_* constants.
+type CAKEAckFilter int
+
+const (
+	CAKEAckFilterNone CAKEAckFilter = iota
+	CAKEAckFilterEnabled
+	CAKEAckFilterAggressive
+)
+
+// String returns the tc(8) name of the ACK filter mode
+func (f CAKEAckFilter) String() string {
+	switch f {
+	case CAKEAckFilterNone:
+		return "no-ack-filter"
+	case CAKEAckFilterEnabled:
+		return "ack-filter"
+	case CAKEAckFilterAggressive:
+		return "ack-filter-aggressive"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseCAKEAckFilter parses a tc(8) ACK filter mode name
+func ParseCAKEAckFilter(s string) (CAKEAckFilter, error) {
+	for filter := CAKEAckFilterNone; filter <= CAKEAckFilterAggressive; filter++ {
+		if filter.String() == s {
+			return filter, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown CAKE ACK filter mode %q", s)
+}
+
+// CAKEQdisc represents a Common Applications Kept Enhanced qdisc
+type CAKEQdisc struct {
+	*Qdisc
+	bandwidth tc.Bandwidth     // shaper rate, zero for unlimited
+	rtt       uint32           // expected round trip time in microseconds
+	diffserv  CAKEDiffservMode // priority tin layout
+	nat       bool             // look up flows through NAT
+	wash      bool             // clear DSCP marks after classification
+	ackFilter CAKEAckFilter    // TCP ACK filtering
+}
+
+// NewCAKEQdisc creates a new CAKE qdisc
+func NewCAKEQdisc(device tc.DeviceName, handle tc.Handle, bandwidth tc.Bandwidth) *CAKEQdisc {
+	qdisc := NewQdisc(device, handle, QdiscTypeCAKE)
+	return &CAKEQdisc{
+		Qdisc:     qdisc,
+		bandwidth: bandwidth,
+		rtt:       100000,        // 100ms, the "internet" preset
+		diffserv:  CAKEDiffserv3, // kernel default
+		ackFilter: CAKEAckFilterNone,
+	}
+}
+
+// Bandwidth returns the shaper rate
+func (c *CAKEQdisc) Bandwidth() tc.Bandwidth {
+	return c.bandwidth
+}
+
+// RTT returns the expected round trip time in microseconds
+func (c *CAKEQdisc) RTT() uint32 {
+	return c.rtt
+}
+
+// SetRTT sets the expected round trip time in microseconds
+func (c *CAKEQdisc) SetRTT(rtt uint32) {
+	c.rtt = rtt
+}
+
+// Diffserv returns the diffserv mode
+func (c *CAKEQdisc) Diffserv() CAKEDiffservMode {
+	return c.diffserv
+}
+
+// SetDiffserv sets the diffserv mode
+func (c *CAKEQdisc) SetDiffserv(mode CAKEDiffservMode) {
+	c.diffserv = mode
+}
+
+// NAT returns whether NAT lookup is enabled
+func (c *CAKEQdisc) NAT() bool {
+	return c.nat
+}
+
+// SetNAT sets whether NAT lookup is enabled
+func (c *CAKEQdisc) SetNAT(nat bool) {
+	c.nat = nat
+}
+
+// Wash returns whether DSCP washing is enabled
+func (c *CAKEQdisc) Wash() bool {
+	return c.wash
+}
+
+// SetWash sets whether DSCP washing is enabled
+func (c *CAKEQdisc) SetWash(wash bool) {
+	c.wash = wash
+}
+
+// AckFilter returns the ACK filter mode
+func (c *CAKEQdisc) AckFilter() CAKEAckFilter {
+	return c.ackFilter
+}
+
+// SetAckFilter sets the ACK filter mode
+func (c *CAKEQdisc) SetAckFilter(filter CAKEAckFilter) {
+	c.ackFilter = filter
+}
@@ -0,0 +1,73 @@
+package entities
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TuningProfile selects how an HTB class derives the burst, cburst and quantum it is not
+// given explicitly
+type TuningProfile string
+
+// Tuning profiles
+const (
+	// TuningProfileDefault bursts 64ms of traffic, the timer resolution HTB is commonly built
+	// with, and gives a quantum of a millisecond of traffic
+	TuningProfileDefault TuningProfile = "default"
+	// TuningProfileLowLatency bursts 8ms of traffic, so that a class catching up on its
+	// tokens adds little queueing delay to the others, and sends one frame per round
+	TuningProfileLowLatency TuningProfile = "low-latency"
+	// TuningProfileBulkThroughput bursts 250ms of traffic, so that timer jitter does not
+	// leave fast links idle, and sends four milliseconds of traffic per round
+	TuningProfileBulkThroughput TuningProfile = "bulk-throughput"
+)
+
+// ethernetHeaderBytes is added to the MTU to size a frame
+const ethernetHeaderBytes = 14
+
+// tuningParameters are the inputs of the burst and quantum calculations of a profile
+type tuningParameters struct {
+	burstWindowMS  uint64 // Milliseconds of traffic at the rate a burst holds
+	minBurst       uint32 // Smallest burst when the MTU is unknown, in bytes
+	minBurstMTUs   uint32 // Smallest burst when the MTU is known, in MTUs
+	quantumDivisor uint64 // The quantum is the rate in bytes per second over this; 0 for one frame
+	minQuantum     uint32
+	maxQuantum     uint32
+}
+
+var tuningProfiles = map[TuningProfile]tuningParameters{
+	TuningProfileDefault:        {burstWindowMS: 64, minBurst: 1600, minBurstMTUs: 2, quantumDivisor: 1000, minQuantum: 1000, maxQuantum: 60000},
+	TuningProfileLowLatency:     {burstWindowMS: 8, minBurst: 1600, minBurstMTUs: 2},
+	TuningProfileBulkThroughput: {burstWindowMS: 250, minBurst: 16000, minBurstMTUs: 10, quantumDivisor: 250, minQuantum: 1514, maxQuantum: 200000},
+}
+
+// ParseTuningProfile returns the tuning profile of the name; an empty name is the default
+func ParseTuningProfile(name string) (TuningProfile, error) {
+	if name == "" {
+		return TuningProfileDefault, nil
+	}
+	profile := TuningProfile(name)
+	if _, ok := tuningProfiles[profile]; !ok {
+		return "", fmt.Errorf("unknown tuning profile %q (known: %s)", name, strings.Join(TuningProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// TuningProfileNames returns the names of the tuning profiles, sorted
+func TuningProfileNames() []string {
+	names := make([]string, 0, len(tuningProfiles))
+	for profile := range tuningProfiles {
+		names = append(names, string(profile))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parameters returns the calculation inputs of the profile; unknown profiles are the default
+func (p TuningProfile) parameters() tuningParameters {
+	if parameters, ok := tuningProfiles[p]; ok {
+		return parameters
+	}
+	return tuningProfiles[TuningProfileDefault]
+}
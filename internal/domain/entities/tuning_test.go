@@ -0,0 +1,66 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestHTBClass_TuningProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  TuningProfile
+		rate     string
+		mtu      uint32
+		overhead uint32
+		burst    uint32
+		quantum  uint32
+	}{
+		// 30Mbps is 3,750,000 bytes per second; 4 bytes of overhead per 1500-byte packet
+		{name: "default", profile: TuningProfileDefault, rate: "30Mbps", mtu: 1500, overhead: 4, burst: 240640, quantum: 3750},
+		{name: "unset_is_default", rate: "30Mbps", mtu: 1500, overhead: 4, burst: 240640, quantum: 3750},
+		{name: "low_latency", profile: TuningProfileLowLatency, rate: "30Mbps", mtu: 1500, overhead: 4, burst: 30080, quantum: 1514},
+		{name: "bulk_throughput", profile: TuningProfileBulkThroughput, rate: "30Mbps", mtu: 1500, overhead: 4, burst: 940000, quantum: 15000},
+		{name: "low_latency_minimum", profile: TuningProfileLowLatency, rate: "1Mbps", burst: 1600, quantum: 1514},
+		{name: "low_latency_jumbo_frames", profile: TuningProfileLowLatency, rate: "1Mbps", mtu: 9000, burst: 18000, quantum: 9014},
+		{name: "bulk_throughput_minimum", profile: TuningProfileBulkThroughput, rate: "256Kbps", burst: 16000, quantum: 1514},
+		{name: "bulk_throughput_minimum_mtus", profile: TuningProfileBulkThroughput, rate: "256Kbps", mtu: 1500, burst: 15000, quantum: 1514},
+		{name: "default_maximum_quantum", profile: TuningProfileDefault, rate: "10Gbps", burst: 80000000, quantum: 60000},
+		{name: "bulk_throughput_maximum_quantum", profile: TuningProfileBulkThroughput, rate: "10Gbps", burst: 312500000, quantum: 200000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class := NewHTBClass(tc.MustNewDeviceName("eth0"), tc.NewHandle(1, 10), tc.NewHandle(1, 0), "test-class", Priority(1))
+			class.SetRate(tc.MustParseBandwidth(tt.rate))
+			if tt.profile != "" {
+				class.SetTuningProfile(tt.profile)
+			}
+			if tt.mtu > 0 {
+				class.SetMTU(tt.mtu)
+			}
+			if tt.overhead > 0 {
+				class.SetOverhead(tt.overhead)
+			}
+
+			assert.Equal(t, tt.burst, class.CalculateEnhancedBurst())
+			assert.Equal(t, tt.quantum, class.CalculateQuantum())
+		})
+	}
+}
+
+func TestParseTuningProfile(t *testing.T) {
+	profile, err := ParseTuningProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, TuningProfileDefault, profile)
+
+	profile, err = ParseTuningProfile("bulk-throughput")
+	require.NoError(t, err)
+	assert.Equal(t, TuningProfileBulkThroughput, profile)
+
+	_, err = ParseTuningProfile("turbo")
+	assert.ErrorContains(t, err, "bulk-throughput, default, low-latency")
+}
@@ -0,0 +1,195 @@
+package entities
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// TCP header flags matched by TCPFlagsMatch
+const (
+	TCPFlagFIN uint8 = 0x01
+	TCPFlagSYN uint8 = 0x02
+	TCPFlagRST uint8 = 0x04
+	TCPFlagPSH uint8 = 0x08
+	TCPFlagACK uint8 = 0x10
+	TCPFlagURG uint8 = 0x20
+	TCPFlagECE uint8 = 0x40
+	TCPFlagCWR uint8 = 0x80
+)
+
+// TCPFlagsMatch matches TCP segments whose flags selected by the mask equal the given flags,
+// e.g. SYN set and ACK clear for connection attempts
+type TCPFlagsMatch struct {
+	flags uint8
+	mask  uint8
+}
+
+// NewTCPFlagsMatch creates a TCP flags match
+func NewTCPFlagsMatch(flags, mask uint8) *TCPFlagsMatch {
+	return &TCPFlagsMatch{
+		flags: flags & mask,
+		mask:  mask,
+	}
+}
+
+// Type returns the match type
+func (m *TCPFlagsMatch) Type() MatchType {
+	return MatchTypeTCPFlags
+}
+
+// String returns the string representation
+func (m *TCPFlagsMatch) String() string {
+	return fmt.Sprintf("tcp flags 0x%02x 0x%02x", m.flags, m.mask)
+}
+
+// Flags returns the expected flag values
+func (m *TCPFlagsMatch) Flags() uint8 {
+	return m.flags
+}
+
+// Mask returns the flags compared
+func (m *TCPFlagsMatch) Mask() uint8 {
+	return m.mask
+}
+
+// PacketLengthMatch matches IP packets whose total length, masked, equals the value. A mask
+// of high bits selects a block of lengths, see NewPacketLengthMatches.
+type PacketLengthMatch struct {
+	length uint16
+	mask   uint16
+}
+
+// NewPacketLengthMatch creates a packet length match
+func NewPacketLengthMatch(length, mask uint16) *PacketLengthMatch {
+	return &PacketLengthMatch{
+		length: length & mask,
+		mask:   mask,
+	}
+}
+
+// NewPacketLengthMatches covers the lengths from min to max, inclusive, with the fewest
+// length matches. u32 keys of one filter must all hold, so each match needs its own filter.
+func NewPacketLengthMatches(min, max uint16) ([]*PacketLengthMatch, error) {
+	if min > max {
+		return nil, fmt.Errorf("invalid packet length range %d-%d", min, max)
+	}
+
+	var matches []*PacketLengthMatch
+	for start := uint32(min); start <= uint32(max); {
+		// Largest aligned block starting at start that does not pass max
+		size := uint32(1) << bits.TrailingZeros32(start|1<<16)
+		for start+size-1 > uint32(max) {
+			size >>= 1
+		}
+		mask := ^uint16(size - 1)
+		matches = append(matches, NewPacketLengthMatch(uint16(start), mask))
+		start += size
+	}
+	return matches, nil
+}
+
+// Type returns the match type
+func (m *PacketLengthMatch) Type() MatchType {
+	return MatchTypePacketLength
+}
+
+// String returns the string representation
+func (m *PacketLengthMatch) String() string {
+	return fmt.Sprintf("ip len 0x%04x 0x%04x", m.length, m.mask)
+}
+
+// Length returns the masked length value
+func (m *PacketLengthMatch) Length() uint16 {
+	return m.length
+}
+
+// Mask returns the length bits compared
+func (m *PacketLengthMatch) Mask() uint16 {
+	return m.mask
+}
+
+// RawMatch matches the 32-bit word at an offset from the start of the IP header, for
+// fields the other matches do not cover
+type RawMatch struct {
+	offset int32
+	value  uint32
+	mask   uint32
+}
+
+// NewRawMatch creates a raw match; the offset must be a multiple of 4, as u32 compares
+// whole words. Narrower fields are matched by masking the word.
+func NewRawMatch(offset int32, value, mask uint32) (*RawMatch, error) {
+	if offset%4 != 0 {
+		return nil, fmt.Errorf("raw match offset %d is not a multiple of 4", offset)
+	}
+	return &RawMatch{
+		offset: offset,
+		value:  value & mask,
+		mask:   mask,
+	}, nil
+}
+
+// Type returns the match type
+func (m *RawMatch) Type() MatchType {
+	return MatchTypeRaw
+}
+
+// String returns the string representation
+func (m *RawMatch) String() string {
+	return fmt.Sprintf("u32 0x%08x 0x%08x at %d", m.value, m.mask, m.offset)
+}
+
+// Offset returns the offset of the word from the start of the IP header
+func (m *RawMatch) Offset() int32 {
+	return m.offset
+}
+
+// Value returns the masked value of the word
+func (m *RawMatch) Value() uint32 {
+	return m.value
+}
+
+// Mask returns the bits of the word compared
+func (m *RawMatch) Mask() uint32 {
+	return m.mask
+}
+
+// ParseHeaderMatch reconstructs a TOS, DSCP, TCP flags, packet length or raw match from
+// its string representation
+func ParseHeaderMatch(matchType MatchType, value string) (Match, error) {
+	switch matchType {
+	case MatchTypeTOS:
+		var tos, mask uint8
+		if _, err := fmt.Sscanf(value, "ip tos 0x%x 0x%x", &tos, &mask); err != nil {
+			return nil, fmt.Errorf("invalid TOS match format: %s", value)
+		}
+		return NewTOSMaskMatch(tos, mask), nil
+	case MatchTypeDSCP:
+		var tos uint8
+		if _, err := fmt.Sscanf(value, "ip tos 0x%x 0xfc", &tos); err != nil {
+			return nil, fmt.Errorf("invalid DSCP match format: %s", value)
+		}
+		return NewDSCPMatch(tos >> 2), nil
+	case MatchTypeTCPFlags:
+		var flags, mask uint8
+		if _, err := fmt.Sscanf(value, "tcp flags 0x%x 0x%x", &flags, &mask); err != nil {
+			return nil, fmt.Errorf("invalid TCP flags match format: %s", value)
+		}
+		return NewTCPFlagsMatch(flags, mask), nil
+	case MatchTypePacketLength:
+		var length, mask uint16
+		if _, err := fmt.Sscanf(value, "ip len 0x%x 0x%x", &length, &mask); err != nil {
+			return nil, fmt.Errorf("invalid packet length match format: %s", value)
+		}
+		return NewPacketLengthMatch(length, mask), nil
+	case MatchTypeRaw:
+		var word, mask uint32
+		var offset int32
+		if _, err := fmt.Sscanf(value, "u32 0x%x 0x%x at %d", &word, &mask, &offset); err != nil {
+			return nil, fmt.Errorf("invalid raw match format: %s", value)
+		}
+		return NewRawMatch(offset, word, mask)
+	default:
+		return nil, fmt.Errorf("unsupported header match type: %v", matchType)
+	}
+}
@@ -0,0 +1,73 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPacketLengthMatches(t *testing.T) {
+	t.Run("aligned_block", func(t *testing.T) {
+		matches, err := NewPacketLengthMatches(0, 127)
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		assert.Equal(t, uint16(0), matches[0].Length())
+		assert.Equal(t, uint16(0xff80), matches[0].Mask())
+	})
+
+	t.Run("unaligned_range", func(t *testing.T) {
+		// 60-63, 64-127, 128-129
+		matches, err := NewPacketLengthMatches(60, 129)
+		require.NoError(t, err)
+		require.Len(t, matches, 3)
+		assert.Equal(t, "ip len 0x003c 0xfffc", matches[0].String())
+		assert.Equal(t, "ip len 0x0040 0xffc0", matches[1].String())
+		assert.Equal(t, "ip len 0x0080 0xfffe", matches[2].String())
+	})
+
+	t.Run("full_range", func(t *testing.T) {
+		matches, err := NewPacketLengthMatches(0, 65535)
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		assert.Equal(t, uint16(0), matches[0].Mask())
+	})
+
+	t.Run("inverted_range", func(t *testing.T) {
+		_, err := NewPacketLengthMatches(100, 10)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewRawMatch(t *testing.T) {
+	match, err := NewRawMatch(8, 0x12345678, 0x00ff0000)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x00340000), match.Value())
+
+	_, err = NewRawMatch(6, 0, 0xffff)
+	assert.Error(t, err)
+}
+
+func TestParseHeaderMatch(t *testing.T) {
+	raw, err := NewRawMatch(-4, 0x1, 0xff)
+	require.NoError(t, err)
+
+	matches := []Match{
+		NewTOSMaskMatch(0x10, 0x1e),
+		NewDSCPMatch(46),
+		NewTCPFlagsMatch(TCPFlagSYN, TCPFlagSYN|TCPFlagACK),
+		NewPacketLengthMatch(0x40, 0xffc0),
+		raw,
+	}
+
+	for _, match := range matches {
+		t.Run(match.String(), func(t *testing.T) {
+			parsed, err := ParseHeaderMatch(match.Type(), match.String())
+			require.NoError(t, err)
+			assert.Equal(t, match, parsed)
+		})
+	}
+
+	_, err = ParseHeaderMatch(MatchTypeTCPFlags, "tcp flags syn")
+	assert.Error(t, err)
+}
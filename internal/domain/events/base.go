@@ -18,6 +18,7 @@ type BaseEvent struct {
 	eventType   string
 	occurredAt  time.Time
 	version     int
+	actor       string
 }
 
 // NewBaseEvent creates a new base event
@@ -49,3 +50,25 @@ func (e BaseEvent) Timestamp() time.Time {
 func (e BaseEvent) EventVersion() int {
 	return e.version
 }
+
+// Actor returns who made the change, empty when unknown
+func (e BaseEvent) Actor() string {
+	return e.actor
+}
+
+// SetActor records who made the change. Event stores set it when the event is saved.
+func (e *BaseEvent) SetActor(actor string) {
+	e.actor = actor
+}
+
+// Restampable is implemented by events that can be reassigned to another aggregate version
+type Restampable interface {
+	Restamp(aggregateID string, version int)
+}
+
+// Restamp assigns the event to an aggregate at the given version. It is used when an
+// event produced against a planning copy of an aggregate is recorded on the real one.
+func (e *BaseEvent) Restamp(aggregateID string, version int) {
+	e.aggregateID = aggregateID
+	e.version = version
+}
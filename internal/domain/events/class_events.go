@@ -78,6 +78,8 @@ type HTBClassCreatedEventWithAdvancedParameters struct {
 	MTU         uint32
 	HTBPrio     uint32
 	UseDefaults bool
+	// Profile derives the burst, cburst and quantum left out; empty is the default profile
+	Profile     entities.TuningProfile `json:",omitempty"`
 }
 
 // NewHTBClassCreatedEventWithAdvancedParameters creates a new comprehensive HTB class event
@@ -101,6 +103,7 @@ func NewHTBClassCreatedEventWithAdvancedParameters(
 	mtu uint32,
 	htbPrio uint32,
 	useDefaults bool,
+	profile entities.TuningProfile,
 ) *HTBClassCreatedEventWithAdvancedParameters {
 	return &HTBClassCreatedEventWithAdvancedParameters{
 		BaseEvent:   NewBaseEvent(aggregateID, "HTBClassCreatedWithAdvancedParameters", version),
@@ -119,6 +122,53 @@ func NewHTBClassCreatedEventWithAdvancedParameters(
 		MTU:         mtu,
 		HTBPrio:     htbPrio,
 		UseDefaults: useDefaults,
+		Profile:     profile,
+	}
+}
+
+// DRRClassCreatedEvent is emitted when a DRR class is created
+type DRRClassCreatedEvent struct {
+	BaseEvent
+	DeviceName tc.DeviceName
+	Handle     tc.Handle
+	Parent     tc.Handle
+	Name       string
+	Quantum    uint32 // bytes per round, 0 for the device MTU
+}
+
+// NewDRRClassCreatedEvent creates a new DRRClassCreatedEvent
+func NewDRRClassCreatedEvent(aggregateID string, version int, device tc.DeviceName, handle tc.Handle, parent tc.Handle, name string, quantum uint32) *DRRClassCreatedEvent {
+	return &DRRClassCreatedEvent{
+		BaseEvent:  NewBaseEvent(aggregateID, "DRRClassCreated", version),
+		DeviceName: device,
+		Handle:     handle,
+		Parent:     parent,
+		Name:       name,
+		Quantum:    quantum,
+	}
+}
+
+// QFQClassCreatedEvent is emitted when a QFQ class is created
+type QFQClassCreatedEvent struct {
+	BaseEvent
+	DeviceName tc.DeviceName
+	Handle     tc.Handle
+	Parent     tc.Handle
+	Name       string
+	Weight     uint32 // 0 for the kernel default of 1
+	Lmax       uint32 // largest packet in bytes, 0 for the device MTU
+}
+
+// NewQFQClassCreatedEvent creates a new QFQClassCreatedEvent
+func NewQFQClassCreatedEvent(aggregateID string, version int, device tc.DeviceName, handle tc.Handle, parent tc.Handle, name string, weight, lmax uint32) *QFQClassCreatedEvent {
+	return &QFQClassCreatedEvent{
+		BaseEvent:  NewBaseEvent(aggregateID, "QFQClassCreated", version),
+		DeviceName: device,
+		Handle:     handle,
+		Parent:     parent,
+		Name:       name,
+		Weight:     weight,
+		Lmax:       lmax,
 	}
 }
 
@@ -181,3 +231,34 @@ func NewClassPriorityChangedEvent(aggregateID string, version int, device tc.Dev
 		NewPriority: newPriority,
 	}
 }
+
+// HTBClassChangedEvent is emitted when an HTB class is changed in place, keeping its
+// counters and queued packets. It carries the complete definition of the class before and
+// after the change, so that the change can be undone.
+type HTBClassChangedEvent struct {
+	BaseEvent
+	DeviceName tc.DeviceName
+	Handle     tc.Handle
+	Previous   *HTBClassCreatedEventWithAdvancedParameters
+	Current    *HTBClassCreatedEventWithAdvancedParameters
+}
+
+// NewHTBClassChangedEvent creates a new HTBClassChangedEvent
+func NewHTBClassChangedEvent(aggregateID string, version int, previous, current *HTBClassCreatedEventWithAdvancedParameters) *HTBClassChangedEvent {
+	return &HTBClassChangedEvent{
+		BaseEvent:  NewBaseEvent(aggregateID, "HTBClassChanged", version),
+		DeviceName: current.DeviceName,
+		Handle:     current.Handle,
+		Previous:   previous,
+		Current:    current,
+	}
+}
+
+// Definition returns the creation event that creates the class as it is after the change,
+// stamped like the change itself
+func (e *HTBClassChangedEvent) Definition() *HTBClassCreatedEventWithAdvancedParameters {
+	definition := *e.Current
+	definition.BaseEvent = e.BaseEvent
+	definition.eventType = "HTBClassCreatedWithAdvancedParameters"
+	return &definition
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnknownEventType is returned by Decode for event types it cannot rebuild
+var ErrUnknownEventType = errors.New("unknown event type")
+
+// eventFactories create an empty event of each recorded event type
+var eventFactories = map[string]func() DomainEvent{
+	"QdiscCreated":                          func() DomainEvent { return &QdiscCreatedEvent{} },
+	"HTBQdiscCreated":                       func() DomainEvent { return &HTBQdiscCreatedEvent{} },
+	"QdiscDeleted":                          func() DomainEvent { return &QdiscDeletedEvent{} },
+	"QdiscModified":                         func() DomainEvent { return &QdiscModifiedEvent{} },
+	"TBFQdiscCreated":                       func() DomainEvent { return &TBFQdiscCreatedEvent{} },
+	"PRIOQdiscCreated":                      func() DomainEvent { return &PRIOQdiscCreatedEvent{} },
+	"FQCODELQdiscCreated":                   func() DomainEvent { return &FQCODELQdiscCreatedEvent{} },
+	"SFQQdiscCreated":                       func() DomainEvent { return &SFQQdiscCreatedEvent{} },
+	"FQQdiscCreated":                        func() DomainEvent { return &FQQdiscCreatedEvent{} },
+	"NETEMQdiscCreated":                     func() DomainEvent { return &NETEMQdiscCreatedEvent{} },
+	"REDQdiscCreated":                       func() DomainEvent { return &REDQdiscCreatedEvent{} },
+	"GREDQdiscCreated":                      func() DomainEvent { return &GREDQdiscCreatedEvent{} },
+	"CODELQdiscCreated":                     func() DomainEvent { return &CODELQdiscCreatedEvent{} },
+	"ETSQdiscCreated":                       func() DomainEvent { return &ETSQdiscCreatedEvent{} },
+	"TAPRIOQdiscCreated":                    func() DomainEvent { return &TAPRIOQdiscCreatedEvent{} },
+	"CAKEQdiscCreated":                      func() DomainEvent { return &CAKEQdiscCreatedEvent{} },
+	"ClassCreated":                          func() DomainEvent { return &ClassCreatedEvent{} },
+	"HTBClassCreated":                       func() DomainEvent { return &HTBClassCreatedEvent{} },
+	"HTBClassCreatedWithAdvancedParameters": func() DomainEvent { return &HTBClassCreatedEventWithAdvancedParameters{} },
+	"DRRClassCreated":                       func() DomainEvent { return &DRRClassCreatedEvent{} },
+	"QFQClassCreated":                       func() DomainEvent { return &QFQClassCreatedEvent{} },
+	"ClassDeleted":                          func() DomainEvent { return &ClassDeletedEvent{} },
+	"ClassModified":                         func() DomainEvent { return &ClassModifiedEvent{} },
+	"ClassPriorityChanged":                  func() DomainEvent { return &ClassPriorityChangedEvent{} },
+	"HTBClassChanged":                       func() DomainEvent { return &HTBClassChangedEvent{} },
+	"FilterCreated":                         func() DomainEvent { return &FilterCreatedEvent{} },
+	"FilterDeleted":                         func() DomainEvent { return &FilterDeletedEvent{} },
+	"FilterModified":                        func() DomainEvent { return &FilterModifiedEvent{} },
+}
+
+// baseEventHolder is implemented by events embedding a BaseEvent
+type baseEventHolder interface {
+	baseEvent() *BaseEvent
+}
+
+func (e *BaseEvent) baseEvent() *BaseEvent {
+	return e
+}
+
+// Decode rebuilds a recorded event from the JSON encoding of its fields and the metadata
+// stored alongside it. It returns ErrUnknownEventType for types it does not know.
+func Decode(eventType string, data []byte, aggregateID string, version int, occurredAt time.Time) (DomainEvent, error) {
+	factory, ok := eventFactories[eventType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, eventType)
+	}
+
+	event := factory()
+	if err := json.Unmarshal(data, event); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event: %w", eventType, err)
+	}
+
+	*event.(baseEventHolder).baseEvent() = BaseEvent{
+		aggregateID: aggregateID,
+		eventType:   eventType,
+		occurredAt:  occurredAt.UTC(),
+		version:     version,
+	}
+	return event, nil
+}
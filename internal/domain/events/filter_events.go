@@ -12,9 +12,11 @@ type FilterCreatedEvent struct {
 	Parent     tc.Handle
 	Priority   uint16
 	Handle     tc.Handle
+	Kind       entities.FilterKind
 	FlowID     tc.Handle
 	Protocol   entities.Protocol
 	Matches    []MatchData
+	Actions    []ActionData
 }
 
 // MatchData represents serializable match data
@@ -23,6 +25,20 @@ type MatchData struct {
 	Value string
 }
 
+// ActionData represents serializable filter action data
+type ActionData struct {
+	Type   entities.ActionType
+	Mark   uint32                      // mark
+	Mask   uint32                      // mark
+	Rate   tc.Bandwidth                // police
+	Burst  uint32                      // police
+	Exceed entities.PoliceExceedAction // police
+	Target tc.DeviceName               // mirror, redirect
+	DSCP   uint8                       // dscp
+	Handle tc.Handle                   // priority
+	Queue  uint16                      // queue_mapping
+}
+
 // NewFilterCreatedEvent creates a new FilterCreatedEvent
 func NewFilterCreatedEvent(aggregateID string, version int, device tc.DeviceName, parent tc.Handle, priority uint16, handle tc.Handle, flowID tc.Handle) *FilterCreatedEvent {
 	return &FilterCreatedEvent{
@@ -45,6 +61,53 @@ func (e *FilterCreatedEvent) AddMatch(matchType entities.MatchType, value string
 	})
 }
 
+// AddAction appends an action to the event's action chain
+func (e *FilterCreatedEvent) AddAction(action entities.Action) {
+	data := ActionData{Type: action.Type()}
+	switch a := action.(type) {
+	case *entities.MarkAction:
+		data.Mark = a.Mark()
+		data.Mask = a.Mask()
+	case *entities.PoliceAction:
+		data.Rate = a.Rate()
+		data.Burst = a.Burst()
+		data.Exceed = a.Exceed()
+	case *entities.MirredAction:
+		data.Target = a.Target()
+	case *entities.DSCPRemarkAction:
+		data.DSCP = a.DSCP()
+	case *entities.PriorityAction:
+		data.Handle = a.Priority()
+	case *entities.QueueMappingAction:
+		data.Queue = a.Queue()
+	}
+	e.Actions = append(e.Actions, data)
+}
+
+// Action reconstructs the filter action
+func (d ActionData) Action() entities.Action {
+	switch d.Type {
+	case entities.ActionTypeMark:
+		return entities.NewMarkAction(d.Mark, d.Mask)
+	case entities.ActionTypePolice:
+		return entities.NewPoliceAction(d.Rate, d.Burst, d.Exceed)
+	case entities.ActionTypeMirror:
+		return entities.NewMirrorAction(d.Target)
+	case entities.ActionTypeDSCP:
+		return entities.NewDSCPRemarkAction(d.DSCP)
+	case entities.ActionTypeDrop:
+		return entities.NewDropAction()
+	case entities.ActionTypePass:
+		return entities.NewPassAction()
+	case entities.ActionTypePriority:
+		return entities.NewPriorityAction(d.Handle)
+	case entities.ActionTypeQueueMapping:
+		return entities.NewQueueMappingAction(d.Queue)
+	default:
+		return entities.NewRedirectAction(d.Target)
+	}
+}
+
 // FilterDeletedEvent is emitted when a filter is deleted
 type FilterDeletedEvent struct {
 	BaseEvent
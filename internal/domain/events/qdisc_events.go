@@ -144,6 +144,7 @@ type FQCODELQdiscCreatedEvent struct {
 	Interval   uint32
 	Quantum    uint32
 	ECN        bool
+	Parent     *tc.Handle // nil for root qdiscs, class handle for leaf qdiscs
 }
 
 // NewFQCODELQdiscCreatedEvent creates a new FQCODELQdiscCreatedEvent
@@ -160,3 +161,368 @@ func NewFQCODELQdiscCreatedEvent(aggregateID string, version int, device tc.Devi
 		ECN:        ecn,
 	}
 }
+
+// NewLeafFQCODELQdiscCreatedEvent creates a FQCODELQdiscCreatedEvent for a qdisc attached to a class
+func NewLeafFQCODELQdiscCreatedEvent(aggregateID string, version int, device tc.DeviceName, parent tc.Handle, handle tc.Handle, limit, flows, target, interval, quantum uint32, ecn bool) *FQCODELQdiscCreatedEvent {
+	event := NewFQCODELQdiscCreatedEvent(aggregateID, version, device, handle, limit, flows, target, interval, quantum, ecn)
+	event.Parent = &parent
+	return event
+}
+
+// SFQQdiscCreatedEvent is emitted when an SFQ qdisc is created
+type SFQQdiscCreatedEvent struct {
+	BaseEvent
+	DeviceName tc.DeviceName
+	Handle     tc.Handle
+	Perturb    uint32 // seconds
+	Quantum    uint32
+	Limit      uint32
+	Parent     *tc.Handle // nil for root qdiscs, class handle for leaf qdiscs
+}
+
+// NewSFQQdiscCreatedEvent creates a new SFQQdiscCreatedEvent
+func NewSFQQdiscCreatedEvent(aggregateID string, version int, device tc.DeviceName, parent *tc.Handle, handle tc.Handle, perturb, quantum, limit uint32) *SFQQdiscCreatedEvent {
+	return &SFQQdiscCreatedEvent{
+		BaseEvent:  NewBaseEvent(aggregateID, "SFQQdiscCreated", version),
+		DeviceName: device,
+		Handle:     handle,
+		Perturb:    perturb,
+		Quantum:    quantum,
+		Limit:      limit,
+		Parent:     parent,
+	}
+}
+
+// FQQdiscCreatedEvent is emitted when an FQ qdisc is created
+type FQQdiscCreatedEvent struct {
+	BaseEvent
+	DeviceName     tc.DeviceName
+	Handle         tc.Handle
+	Limit          uint32
+	FlowLimit      uint32
+	Quantum        uint32
+	InitialQuantum uint32
+	MaxRate        tc.Bandwidth
+	Pacing         bool
+	Parent         *tc.Handle // nil for root qdiscs, class handle for leaf qdiscs
+}
+
+// NewFQQdiscCreatedEvent creates a new FQQdiscCreatedEvent
+func NewFQQdiscCreatedEvent(aggregateID string, version int, device tc.DeviceName, parent *tc.Handle, handle tc.Handle, limit, flowLimit, quantum, initialQuantum uint32, maxRate tc.Bandwidth, pacing bool) *FQQdiscCreatedEvent {
+	return &FQQdiscCreatedEvent{
+		BaseEvent:      NewBaseEvent(aggregateID, "FQQdiscCreated", version),
+		DeviceName:     device,
+		Handle:         handle,
+		Limit:          limit,
+		FlowLimit:      flowLimit,
+		Quantum:        quantum,
+		InitialQuantum: initialQuantum,
+		MaxRate:        maxRate,
+		Pacing:         pacing,
+		Parent:         parent,
+	}
+}
+
+// NETEMQdiscCreatedEvent is emitted when a NETEM qdisc is created
+type NETEMQdiscCreatedEvent struct {
+	BaseEvent
+	DeviceName tc.DeviceName
+	Handle     tc.Handle
+	Delay      uint32 // microseconds
+	Jitter     uint32 // microseconds
+	Loss       float32
+	Duplicate  float32
+	Corrupt    float32
+	Reorder    float32
+	Gap        uint32
+	Limit      uint32
+	Parent     *tc.Handle // nil for root qdiscs, class handle for leaf qdiscs
+}
+
+// NewNETEMQdiscCreatedEvent creates a new NETEMQdiscCreatedEvent
+func NewNETEMQdiscCreatedEvent(aggregateID string, version int, device tc.DeviceName, parent *tc.Handle, handle tc.Handle, params entities.NETEMParameters) *NETEMQdiscCreatedEvent {
+	return &NETEMQdiscCreatedEvent{
+		BaseEvent:  NewBaseEvent(aggregateID, "NETEMQdiscCreated", version),
+		DeviceName: device,
+		Handle:     handle,
+		Delay:      params.Delay,
+		Jitter:     params.Jitter,
+		Loss:       params.Loss,
+		Duplicate:  params.Duplicate,
+		Corrupt:    params.Corrupt,
+		Reorder:    params.Reorder,
+		Gap:        params.Gap,
+		Limit:      params.Limit,
+		Parent:     parent,
+	}
+}
+
+// Parameters returns the emulated network conditions
+func (e *NETEMQdiscCreatedEvent) Parameters() entities.NETEMParameters {
+	return entities.NETEMParameters{
+		Delay:     e.Delay,
+		Jitter:    e.Jitter,
+		Loss:      e.Loss,
+		Duplicate: e.Duplicate,
+		Corrupt:   e.Corrupt,
+		Reorder:   e.Reorder,
+		Gap:       e.Gap,
+		Limit:     e.Limit,
+	}
+}
+
+// REDQdiscCreatedEvent is emitted when a RED qdisc is created
+type REDQdiscCreatedEvent struct {
+	BaseEvent
+	DeviceName  tc.DeviceName
+	Handle      tc.Handle
+	Limit       uint32 // bytes
+	Min         uint32 // bytes
+	Max         uint32 // bytes
+	Avpkt       uint32 // bytes
+	Burst       uint32 // packets
+	Bandwidth   tc.Bandwidth
+	Probability float64
+	ECN         bool
+	HardDrop    bool
+	Adaptive    bool
+	Parent      *tc.Handle // nil for root qdiscs, class handle for leaf qdiscs
+}
+
+// NewREDQdiscCreatedEvent creates a new REDQdiscCreatedEvent
+func NewREDQdiscCreatedEvent(aggregateID string, version int, device tc.DeviceName, parent *tc.Handle, handle tc.Handle, params entities.REDParameters) *REDQdiscCreatedEvent {
+	return &REDQdiscCreatedEvent{
+		BaseEvent:   NewBaseEvent(aggregateID, "REDQdiscCreated", version),
+		DeviceName:  device,
+		Handle:      handle,
+		Limit:       params.Limit,
+		Min:         params.Min,
+		Max:         params.Max,
+		Avpkt:       params.Avpkt,
+		Burst:       params.Burst,
+		Bandwidth:   params.Bandwidth,
+		Probability: params.Probability,
+		ECN:         params.ECN,
+		HardDrop:    params.HardDrop,
+		Adaptive:    params.Adaptive,
+		Parent:      parent,
+	}
+}
+
+// Parameters returns the RED parameters
+func (e *REDQdiscCreatedEvent) Parameters() entities.REDParameters {
+	return entities.REDParameters{
+		REDThresholds: entities.REDThresholds{
+			Limit:       e.Limit,
+			Min:         e.Min,
+			Max:         e.Max,
+			Avpkt:       e.Avpkt,
+			Burst:       e.Burst,
+			Bandwidth:   e.Bandwidth,
+			Probability: e.Probability,
+		},
+		ECN:      e.ECN,
+		HardDrop: e.HardDrop,
+		Adaptive: e.Adaptive,
+	}
+}
+
+// GREDQdiscCreatedEvent is emitted when a GRED qdisc is created
+type GREDQdiscCreatedEvent struct {
+	BaseEvent
+	DeviceName    tc.DeviceName
+	Handle        tc.Handle
+	Queues        uint32
+	Default       uint32
+	GRIO          bool
+	ECN           bool
+	HardDrop      bool
+	VirtualQueues []entities.GREDVirtualQueue
+	Parent        *tc.Handle // nil for root qdiscs, class handle for leaf qdiscs
+}
+
+// NewGREDQdiscCreatedEvent creates a new GREDQdiscCreatedEvent
+func NewGREDQdiscCreatedEvent(aggregateID string, version int, device tc.DeviceName, parent *tc.Handle, handle tc.Handle, params entities.GREDParameters) *GREDQdiscCreatedEvent {
+	return &GREDQdiscCreatedEvent{
+		BaseEvent:     NewBaseEvent(aggregateID, "GREDQdiscCreated", version),
+		DeviceName:    device,
+		Handle:        handle,
+		Queues:        params.Queues,
+		Default:       params.Default,
+		GRIO:          params.GRIO,
+		ECN:           params.ECN,
+		HardDrop:      params.HardDrop,
+		VirtualQueues: append([]entities.GREDVirtualQueue(nil), params.VirtualQueues...),
+		Parent:        parent,
+	}
+}
+
+// Parameters returns the GRED parameters
+func (e *GREDQdiscCreatedEvent) Parameters() entities.GREDParameters {
+	return entities.GREDParameters{
+		Queues:        e.Queues,
+		Default:       e.Default,
+		GRIO:          e.GRIO,
+		ECN:           e.ECN,
+		HardDrop:      e.HardDrop,
+		VirtualQueues: append([]entities.GREDVirtualQueue(nil), e.VirtualQueues...),
+	}
+}
+
+// CODELQdiscCreatedEvent is emitted when a CoDel qdisc is created
+type CODELQdiscCreatedEvent struct {
+	BaseEvent
+	DeviceName  tc.DeviceName
+	Handle      tc.Handle
+	Limit       uint32 // packets
+	Target      uint32 // microseconds
+	Interval    uint32 // microseconds
+	CEThreshold uint32 // microseconds
+	ECN         bool
+	Parent      *tc.Handle // nil for root qdiscs, class handle for leaf qdiscs
+}
+
+// NewCODELQdiscCreatedEvent creates a new CODELQdiscCreatedEvent
+func NewCODELQdiscCreatedEvent(aggregateID string, version int, device tc.DeviceName, parent *tc.Handle, handle tc.Handle, params entities.CODELParameters) *CODELQdiscCreatedEvent {
+	return &CODELQdiscCreatedEvent{
+		BaseEvent:   NewBaseEvent(aggregateID, "CODELQdiscCreated", version),
+		DeviceName:  device,
+		Handle:      handle,
+		Limit:       params.Limit,
+		Target:      params.Target,
+		Interval:    params.Interval,
+		CEThreshold: params.CEThreshold,
+		ECN:         params.ECN,
+		Parent:      parent,
+	}
+}
+
+// Parameters returns the CoDel parameters
+func (e *CODELQdiscCreatedEvent) Parameters() entities.CODELParameters {
+	return entities.CODELParameters{
+		Limit:       e.Limit,
+		Target:      e.Target,
+		Interval:    e.Interval,
+		CEThreshold: e.CEThreshold,
+		ECN:         e.ECN,
+	}
+}
+
+// ETSQdiscCreatedEvent is emitted when an ETS qdisc is created
+type ETSQdiscCreatedEvent struct {
+	BaseEvent
+	DeviceName tc.DeviceName
+	Handle     tc.Handle
+	Bands      uint8
+	Strict     uint8
+	Quanta     []uint32 // bytes
+	Priomap    []uint8
+	Parent     *tc.Handle // nil for root qdiscs, class handle for leaf qdiscs
+}
+
+// NewETSQdiscCreatedEvent creates a new ETSQdiscCreatedEvent
+func NewETSQdiscCreatedEvent(aggregateID string, version int, device tc.DeviceName, parent *tc.Handle, handle tc.Handle, params entities.ETSParameters) *ETSQdiscCreatedEvent {
+	return &ETSQdiscCreatedEvent{
+		BaseEvent:  NewBaseEvent(aggregateID, "ETSQdiscCreated", version),
+		DeviceName: device,
+		Handle:     handle,
+		Bands:      params.EffectiveBands(),
+		Strict:     params.Strict,
+		Quanta:     append([]uint32(nil), params.Quanta...),
+		Priomap:    append([]uint8(nil), params.Priomap...),
+		Parent:     parent,
+	}
+}
+
+// Parameters returns the ETS parameters
+func (e *ETSQdiscCreatedEvent) Parameters() entities.ETSParameters {
+	return entities.ETSParameters{
+		Bands:   e.Bands,
+		Strict:  e.Strict,
+		Quanta:  append([]uint32(nil), e.Quanta...),
+		Priomap: append([]uint8(nil), e.Priomap...),
+	}
+}
+
+// TAPRIOQdiscCreatedEvent is emitted when a taprio qdisc is created
+type TAPRIOQdiscCreatedEvent struct {
+	BaseEvent
+	DeviceName         tc.DeviceName
+	Handle             tc.Handle
+	NumTC              uint8
+	Priomap            []uint8
+	Queues             []entities.TAPRIOQueueRange
+	BaseTime           int64 // nanoseconds
+	CycleTime          int64 // nanoseconds
+	CycleTimeExtension int64 // nanoseconds
+	Clock              entities.TAPRIOClock
+	Schedule           []entities.TAPRIOScheduleEntry
+	TxTimeAssist       bool
+	FullOffload        bool
+	TxTimeDelay        uint32 // nanoseconds
+}
+
+// NewTAPRIOQdiscCreatedEvent creates a new TAPRIOQdiscCreatedEvent
+func NewTAPRIOQdiscCreatedEvent(aggregateID string, version int, device tc.DeviceName, handle tc.Handle, params entities.TAPRIOParameters) *TAPRIOQdiscCreatedEvent {
+	return &TAPRIOQdiscCreatedEvent{
+		BaseEvent:          NewBaseEvent(aggregateID, "TAPRIOQdiscCreated", version),
+		DeviceName:         device,
+		Handle:             handle,
+		NumTC:              params.NumTC,
+		Priomap:            append([]uint8(nil), params.Priomap...),
+		Queues:             append([]entities.TAPRIOQueueRange(nil), params.Queues...),
+		BaseTime:           params.BaseTime,
+		CycleTime:          params.CycleTime,
+		CycleTimeExtension: params.CycleTimeExtension,
+		Clock:              params.Clock,
+		Schedule:           append([]entities.TAPRIOScheduleEntry(nil), params.Schedule...),
+		TxTimeAssist:       params.TxTimeAssist,
+		FullOffload:        params.FullOffload,
+		TxTimeDelay:        params.TxTimeDelay,
+	}
+}
+
+// Parameters returns the taprio parameters
+func (e *TAPRIOQdiscCreatedEvent) Parameters() entities.TAPRIOParameters {
+	return entities.TAPRIOParameters{
+		NumTC:              e.NumTC,
+		Priomap:            append([]uint8(nil), e.Priomap...),
+		Queues:             append([]entities.TAPRIOQueueRange(nil), e.Queues...),
+		BaseTime:           e.BaseTime,
+		CycleTime:          e.CycleTime,
+		CycleTimeExtension: e.CycleTimeExtension,
+		Clock:              e.Clock,
+		Schedule:           append([]entities.TAPRIOScheduleEntry(nil), e.Schedule...),
+		TxTimeAssist:       e.TxTimeAssist,
+		FullOffload:        e.FullOffload,
+		TxTimeDelay:        e.TxTimeDelay,
+	}
+}
+
+// CAKEQdiscCreatedEvent is emitted when a CAKE qdisc is created
+type CAKEQdiscCreatedEvent struct {
+	BaseEvent
+	DeviceName tc.DeviceName
+	Handle     tc.Handle
+	Bandwidth  tc.Bandwidth
+	RTT        uint32 // microseconds
+	Diffserv   entities.CAKEDiffservMode
+	NAT        bool
+	Wash       bool
+	AckFilter  entities.CAKEAckFilter
+}
+
+// NewCAKEQdiscCreatedEvent creates a new CAKEQdiscCreatedEvent
+func NewCAKEQdiscCreatedEvent(aggregateID string, version int, device tc.DeviceName, handle tc.Handle, bandwidth tc.Bandwidth, rtt uint32, diffserv entities.CAKEDiffservMode, nat, wash bool, ackFilter entities.CAKEAckFilter) *CAKEQdiscCreatedEvent {
+	return &CAKEQdiscCreatedEvent{
+		BaseEvent:  NewBaseEvent(aggregateID, "CAKEQdiscCreated", version),
+		DeviceName: device,
+		Handle:     handle,
+		Bandwidth:  bandwidth,
+		RTT:        rtt,
+		Diffserv:   diffserv,
+		NAT:        nat,
+		Wash:       wash,
+		AckFilter:  ackFilter,
+	}
+}
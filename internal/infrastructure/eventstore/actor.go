@@ -0,0 +1,48 @@
+package eventstore
+
+import (
+	"context"
+
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+)
+
+// actorKey is the context key of the actor making changes
+type actorKey struct{}
+
+// WithActor returns a context recording that changes made with it are made by actor.
+// Event stores stamp the actor on the events they save.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor recorded by WithActor, empty when none
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// actorEvent is implemented by events that record who made them
+type actorEvent interface {
+	SetActor(actor string)
+}
+
+// stampActor records the context's actor on events that have none
+func stampActor(ctx context.Context, domainEvents []events.DomainEvent) {
+	actor := ActorFromContext(ctx)
+	if actor == "" {
+		return
+	}
+	for _, event := range domainEvents {
+		if e, ok := event.(actorEvent); ok && eventActor(event) == "" {
+			e.SetActor(actor)
+		}
+	}
+}
+
+// eventActor returns who made an event, empty when it does not record it
+func eventActor(event events.DomainEvent) string {
+	if e, ok := event.(interface{ Actor() string }); ok {
+		return e.Actor()
+	}
+	return ""
+}
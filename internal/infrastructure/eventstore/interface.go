@@ -32,6 +32,12 @@ type EventStoreWithContext interface {
 	GetEventsWithContext(ctx context.Context, aggregateID string, fromVersion int, maxEvents int) ([]interface{}, error)
 }
 
+// PublishingEventStore is implemented by event stores that hand each saved event to a
+// publisher
+type PublishingEventStore interface {
+	SetEventPublisher(publisher EventPublisher)
+}
+
 // EventSourcedAggregate is implemented by aggregates that use event sourcing
 type EventSourcedAggregate interface {
 	GetID() string
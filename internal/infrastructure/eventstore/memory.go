@@ -9,14 +9,16 @@ import (
 
 // MemoryEventStore is an in-memory implementation of event store
 type MemoryEventStore struct {
-	mu     sync.RWMutex
-	events map[string][]events.DomainEvent // aggregateID -> events
+	mu        sync.RWMutex
+	events    map[string][]events.DomainEvent // aggregateID -> events
+	snapshots map[string]Snapshot             // aggregateID -> latest snapshot
 }
 
 // NewMemoryEventStore creates a new in-memory event store
 func NewMemoryEventStore() *MemoryEventStore {
 	return &MemoryEventStore{
-		events: make(map[string][]events.DomainEvent),
+		events:    make(map[string][]events.DomainEvent),
+		snapshots: make(map[string]Snapshot),
 	}
 }
 
@@ -97,10 +99,44 @@ func (m *MemoryEventStore) GetAllEvents() ([]events.DomainEvent, error) {
 	return allEvents, nil
 }
 
-// Clear removes all events (for testing)
+// SaveSnapshot replaces the aggregate's snapshot
+func (m *MemoryEventStore) SaveSnapshot(aggregateID string, snapshot Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := make([]events.DomainEvent, len(snapshot.State))
+	copy(state, snapshot.State)
+	m.snapshots[aggregateID] = Snapshot{Version: snapshot.Version, State: state}
+
+	return nil
+}
+
+// GetSnapshot returns the aggregate's snapshot, or nil when it has none
+func (m *MemoryEventStore) GetSnapshot(aggregateID string) (*Snapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot, exists := m.snapshots[aggregateID]
+	if !exists {
+		return nil, nil
+	}
+
+	state := make([]events.DomainEvent, len(snapshot.State))
+	copy(state, snapshot.State)
+	return &Snapshot{Version: snapshot.Version, State: state}, nil
+}
+
+// Clear removes all events and snapshots (for testing)
 func (m *MemoryEventStore) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.events = make(map[string][]events.DomainEvent)
+	m.snapshots = make(map[string]Snapshot)
 }
+
+// Ensure MemoryEventStore implements EventStore and SnapshotStore
+var (
+	_ EventStore    = (*MemoryEventStore)(nil)
+	_ SnapshotStore = (*MemoryEventStore)(nil)
+)
@@ -2,6 +2,7 @@ package eventstore
 
 import (
 	"context"
+	"fmt"
 )
 
 // EventPublisher is a callback to publish events after they are saved
@@ -25,18 +26,9 @@ func (m *MemoryEventStoreWrapper) SetEventPublisher(publisher EventPublisher) {
 	m.eventPublisher = publisher
 }
 
-// Load loads an aggregate from the event store
+// Load loads an aggregate from its latest snapshot and the events recorded after it
 func (m *MemoryEventStoreWrapper) Load(ctx context.Context, aggregateID string, aggregate EventSourcedAggregate) error {
-	events, err := m.GetEvents(aggregateID)
-	if err != nil {
-		return err
-	}
-
-	if len(events) > 0 {
-		aggregate.LoadFromHistory(events)
-	}
-
-	return nil
+	return LoadAggregate(m.MemoryEventStore, aggregateID, aggregate)
 }
 
 // SaveAggregate saves an aggregate to the event store
@@ -46,19 +38,30 @@ func (m *MemoryEventStoreWrapper) SaveAggregate(ctx context.Context, aggregate E
 		return nil // No events to save
 	}
 
+	stampActor(ctx, uncommittedEvents)
+
 	expectedVersion := aggregate.GetVersion() - len(uncommittedEvents)
 	if err := m.Save(aggregate.GetID(), uncommittedEvents, expectedVersion); err != nil {
 		return err
 	}
 
-	// Publish events if publisher is set
+	aggregate.MarkEventsAsCommitted()
+
+	if snapshotDue(aggregate, len(uncommittedEvents)) {
+		// Snapshots of the memory store cannot fail
+		_ = takeSnapshot(m.MemoryEventStore, aggregate)
+	}
+
+	// Publish events if publisher is set. The events stay saved when publishing fails;
+	// events after the failed one are not published.
 	if m.eventPublisher != nil {
 		for _, event := range uncommittedEvents {
-			_ = m.eventPublisher(ctx, event)
+			if err := m.eventPublisher(ctx, event); err != nil {
+				return fmt.Errorf("event saved but not applied: %w", err)
+			}
 		}
 	}
 
-	aggregate.MarkEventsAsCommitted()
 	return nil
 }
 
@@ -81,5 +84,8 @@ func (m *MemoryEventStoreWrapper) GetEventsWithContext(ctx context.Context, aggr
 	return result, nil
 }
 
-// Ensure MemoryEventStoreWrapper implements EventStoreWithContext
-var _ EventStoreWithContext = (*MemoryEventStoreWrapper)(nil)
+// Ensure MemoryEventStoreWrapper implements EventStoreWithContext and PublishingEventStore
+var (
+	_ EventStoreWithContext = (*MemoryEventStoreWrapper)(nil)
+	_ PublishingEventStore  = (*MemoryEventStoreWrapper)(nil)
+)
@@ -0,0 +1,76 @@
+package eventstore
+
+import (
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+)
+
+// SnapshotInterval is the number of events between the snapshots SaveAggregate takes
+const SnapshotInterval = 100
+
+// Snapshot is the state of an aggregate at a version, as the events that rebuild it
+type Snapshot struct {
+	Version int
+	State   []events.DomainEvent
+}
+
+// SnapshotStore is implemented by event stores that keep the latest snapshot of each
+// aggregate
+type SnapshotStore interface {
+	// SaveSnapshot replaces the aggregate's snapshot
+	SaveSnapshot(aggregateID string, snapshot Snapshot) error
+	// GetSnapshot returns the aggregate's snapshot, or nil when it has none
+	GetSnapshot(aggregateID string) (*Snapshot, error)
+}
+
+// SnapshotAggregate is implemented by aggregates that can be rebuilt from a snapshot
+type SnapshotAggregate interface {
+	EventSourcedAggregate
+	Snapshot() (int, []events.DomainEvent)
+	LoadFromSnapshot(version int, state []events.DomainEvent)
+}
+
+// LoadAggregate rebuilds an aggregate from its latest snapshot and the events recorded
+// after it, or from its whole history when either side does not support snapshots
+func LoadAggregate(store EventStore, aggregateID string, aggregate EventSourcedAggregate) error {
+	fromVersion := 0
+	snapshots, storeOK := store.(SnapshotStore)
+	snapshotAggregate, aggregateOK := aggregate.(SnapshotAggregate)
+	if storeOK && aggregateOK {
+		snapshot, err := snapshots.GetSnapshot(aggregateID)
+		if err != nil {
+			return err
+		}
+		if snapshot != nil {
+			snapshotAggregate.LoadFromSnapshot(snapshot.Version, snapshot.State)
+			fromVersion = snapshot.Version
+		}
+	}
+
+	history, err := store.GetEventsFromVersion(aggregateID, fromVersion)
+	if err != nil {
+		return err
+	}
+	if len(history) > 0 {
+		aggregate.LoadFromHistory(history)
+	}
+	return nil
+}
+
+// snapshotDue reports whether saving events took an aggregate across a multiple of
+// SnapshotInterval
+func snapshotDue(aggregate EventSourcedAggregate, saved int) bool {
+	version := aggregate.GetVersion()
+	return version/SnapshotInterval != (version-saved)/SnapshotInterval
+}
+
+// takeSnapshot stores the aggregate's snapshot when both sides support snapshots
+func takeSnapshot(store EventStore, aggregate EventSourcedAggregate) error {
+	snapshots, storeOK := store.(SnapshotStore)
+	snapshotAggregate, aggregateOK := aggregate.(SnapshotAggregate)
+	if !storeOK || !aggregateOK {
+		return nil
+	}
+
+	version, state := snapshotAggregate.Snapshot()
+	return snapshots.SaveSnapshot(aggregate.GetID(), Snapshot{Version: version, State: state})
+}
@@ -3,6 +3,7 @@ package eventstore
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -52,6 +53,7 @@ func (s *SQLiteEventStore) createTables() error {
 		event_data TEXT NOT NULL,
 		event_version INTEGER NOT NULL,
 		occurred_at TIMESTAMP NOT NULL,
+		actor TEXT NOT NULL DEFAULT '',
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
 
@@ -67,7 +69,35 @@ func (s *SQLiteEventStore) createTables() error {
 	);
 	`
 
-	_, err := s.db.Exec(query)
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+
+	return s.migrateActorColumn()
+}
+
+// migrateActorColumn adds the actor column to databases created before it existed
+func (s *SQLiteEventStore) migrateActorColumn() error {
+	rows, err := s.db.Query("SELECT name FROM pragma_table_info('events')")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == "actor" {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("ALTER TABLE events ADD COLUMN actor TEXT NOT NULL DEFAULT ''")
 	return err
 }
 
@@ -104,8 +134,8 @@ func (s *SQLiteEventStore) Save(aggregateID string, events []events.DomainEvent,
 
 	// Insert events
 	stmt, err := tx.Prepare(`
-		INSERT INTO events (aggregate_id, event_type, event_data, event_version, occurred_at)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO events (aggregate_id, event_type, event_data, event_version, occurred_at, actor)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
@@ -129,6 +159,7 @@ func (s *SQLiteEventStore) Save(aggregateID string, events []events.DomainEvent,
 			eventData,
 			event.EventVersion(),
 			event.Timestamp().UTC(),
+			eventActor(event),
 		)
 		if err != nil {
 			return fmt.Errorf("failed to insert event: %w", err)
@@ -153,7 +184,7 @@ func (s *SQLiteEventStore) GetEvents(aggregateID string) ([]events.DomainEvent,
 	defer s.mu.RUnlock()
 
 	rows, err := s.db.Query(`
-		SELECT event_type, event_data, event_version, occurred_at
+		SELECT event_type, event_data, event_version, occurred_at, actor
 		FROM events
 		WHERE aggregate_id = ?
 		ORDER BY event_version ASC
@@ -170,15 +201,15 @@ func (s *SQLiteEventStore) GetEvents(aggregateID string) ([]events.DomainEvent,
 
 	var result []events.DomainEvent
 	for rows.Next() {
-		var eventType, eventData string
+		var eventType, eventData, actor string
 		var version int
 		var occurredAt time.Time
 
-		if err := rows.Scan(&eventType, &eventData, &version, &occurredAt); err != nil {
+		if err := rows.Scan(&eventType, &eventData, &version, &occurredAt, &actor); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
-		event, err := s.deserializeEvent(aggregateID, eventType, eventData, version, occurredAt)
+		event, err := s.deserializeEvent(aggregateID, eventType, eventData, version, occurredAt, actor)
 		if err != nil {
 			return nil, fmt.Errorf("failed to deserialize event: %w", err)
 		}
@@ -195,7 +226,7 @@ func (s *SQLiteEventStore) GetEventsFromVersion(aggregateID string, fromVersion
 	defer s.mu.RUnlock()
 
 	rows, err := s.db.Query(`
-		SELECT event_type, event_data, event_version, occurred_at
+		SELECT event_type, event_data, event_version, occurred_at, actor
 		FROM events
 		WHERE aggregate_id = ? AND event_version > ?
 		ORDER BY event_version ASC
@@ -212,15 +243,15 @@ func (s *SQLiteEventStore) GetEventsFromVersion(aggregateID string, fromVersion
 
 	var result []events.DomainEvent
 	for rows.Next() {
-		var eventType, eventData string
+		var eventType, eventData, actor string
 		var version int
 		var occurredAt time.Time
 
-		if err := rows.Scan(&eventType, &eventData, &version, &occurredAt); err != nil {
+		if err := rows.Scan(&eventType, &eventData, &version, &occurredAt, &actor); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
-		event, err := s.deserializeEvent(aggregateID, eventType, eventData, version, occurredAt)
+		event, err := s.deserializeEvent(aggregateID, eventType, eventData, version, occurredAt, actor)
 		if err != nil {
 			return nil, fmt.Errorf("failed to deserialize event: %w", err)
 		}
@@ -237,7 +268,7 @@ func (s *SQLiteEventStore) GetAllEvents() ([]events.DomainEvent, error) {
 	defer s.mu.RUnlock()
 
 	rows, err := s.db.Query(`
-		SELECT aggregate_id, event_type, event_data, event_version, occurred_at
+		SELECT aggregate_id, event_type, event_data, event_version, occurred_at, actor
 		FROM events
 		ORDER BY occurred_at ASC, event_version ASC
 	`)
@@ -253,15 +284,15 @@ func (s *SQLiteEventStore) GetAllEvents() ([]events.DomainEvent, error) {
 
 	var result []events.DomainEvent
 	for rows.Next() {
-		var aggregateID, eventType, eventData string
+		var aggregateID, eventType, eventData, actor string
 		var version int
 		var occurredAt time.Time
 
-		if err := rows.Scan(&aggregateID, &eventType, &eventData, &version, &occurredAt); err != nil {
+		if err := rows.Scan(&aggregateID, &eventType, &eventData, &version, &occurredAt, &actor); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
-		event, err := s.deserializeEvent(aggregateID, eventType, eventData, version, occurredAt)
+		event, err := s.deserializeEvent(aggregateID, eventType, eventData, version, occurredAt, actor)
 		if err != nil {
 			return nil, fmt.Errorf("failed to deserialize event: %w", err)
 		}
@@ -272,6 +303,97 @@ func (s *SQLiteEventStore) GetAllEvents() ([]events.DomainEvent, error) {
 	return result, nil
 }
 
+// snapshotEvent is the stored form of an event of a snapshot
+type snapshotEvent struct {
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Actor      string          `json:"actor,omitempty"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// SaveSnapshot replaces the aggregate's snapshot
+func (s *SQLiteEventStore) SaveSnapshot(aggregateID string, snapshot Snapshot) error {
+	state := make([]snapshotEvent, 0, len(snapshot.State))
+	for _, event := range snapshot.State {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to serialize snapshot event: %w", err)
+		}
+		state = append(state, snapshotEvent{
+			Type:       event.EventType(),
+			Version:    event.EventVersion(),
+			OccurredAt: event.Timestamp().UTC(),
+			Actor:      eventActor(event),
+			Data:       data,
+		})
+	}
+
+	snapshotData, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to serialize snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.db.Exec(`
+		INSERT INTO snapshots (aggregate_id, snapshot_data, version)
+		VALUES (?, ?, ?)
+		ON CONFLICT(aggregate_id) DO UPDATE SET
+			snapshot_data = excluded.snapshot_data,
+			version = excluded.version,
+			created_at = CURRENT_TIMESTAMP
+	`, aggregateID, string(snapshotData), snapshot.Version)
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	s.logger.Debug("Saved snapshot",
+		logging.String("aggregate_id", aggregateID),
+		logging.Int("version", snapshot.Version))
+
+	return nil
+}
+
+// GetSnapshot returns the aggregate's snapshot, or nil when it has none
+func (s *SQLiteEventStore) GetSnapshot(aggregateID string) (*Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var snapshotData string
+	var version int
+	err := s.db.QueryRow(
+		"SELECT snapshot_data, version FROM snapshots WHERE aggregate_id = ?",
+		aggregateID,
+	).Scan(&snapshotData, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshot: %w", err)
+	}
+
+	var state []snapshotEvent
+	if err := json.Unmarshal([]byte(snapshotData), &state); err != nil {
+		return nil, fmt.Errorf("failed to deserialize snapshot: %w", err)
+	}
+
+	snapshot := &Snapshot{Version: version, State: make([]events.DomainEvent, 0, len(state))}
+	for _, stored := range state {
+		event, err := events.Decode(stored.Type, stored.Data, aggregateID, stored.Version, stored.OccurredAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to deserialize snapshot event: %w", err)
+		}
+		if e, ok := event.(actorEvent); ok {
+			e.SetActor(stored.Actor)
+		}
+		snapshot.State = append(snapshot.State, event)
+	}
+
+	return snapshot, nil
+}
+
 // Close closes the database connection
 func (s *SQLiteEventStore) Close() error {
 	return s.db.Close()
@@ -286,23 +408,31 @@ func (s *SQLiteEventStore) serializeEvent(event events.DomainEvent) (string, err
 	return string(data), nil
 }
 
-// deserializeEvent deserializes an event from JSON
-func (s *SQLiteEventStore) deserializeEvent(aggregateID, eventType, eventData string, version int, occurredAt time.Time) (events.DomainEvent, error) {
-	// This is a simplified version - in production, you'd have a registry of event types
-	// and proper deserialization logic for each event type
+// deserializeEvent rebuilds an event from JSON. Events of types unknown to this version
+// are returned as a GenericEvent holding their fields.
+func (s *SQLiteEventStore) deserializeEvent(aggregateID, eventType, eventData string, version int, occurredAt time.Time, actor string) (events.DomainEvent, error) {
+	event, err := events.Decode(eventType, []byte(eventData), aggregateID, version, occurredAt)
+	if err == nil {
+		if e, ok := event.(actorEvent); ok {
+			e.SetActor(actor)
+		}
+		return event, nil
+	}
+	if !errors.Is(err, events.ErrUnknownEventType) {
+		return nil, err
+	}
 
-	// For now, we'll create a generic event wrapper
 	var data map[string]interface{}
 	if err := json.Unmarshal([]byte(eventData), &data); err != nil {
 		return nil, err
 	}
 
-	// Return a generic event - in production, you'd reconstruct the specific event type
 	return &GenericEvent{
 		aggregateID: aggregateID,
 		eventType:   eventType,
 		version:     version,
 		timestamp:   occurredAt,
+		actor:       actor,
 		data:        data,
 	}, nil
 }
@@ -313,6 +443,7 @@ type GenericEvent struct {
 	eventType   string
 	version     int
 	timestamp   time.Time
+	actor       string
 	data        map[string]interface{}
 }
 
@@ -320,7 +451,11 @@ func (e *GenericEvent) AggregateID() string          { return e.aggregateID }
 func (e *GenericEvent) EventType() string            { return e.eventType }
 func (e *GenericEvent) EventVersion() int            { return e.version }
 func (e *GenericEvent) Timestamp() time.Time         { return e.timestamp }
+func (e *GenericEvent) Actor() string                { return e.actor }
 func (e *GenericEvent) Data() map[string]interface{} { return e.data }
 
-// Ensure SQLiteEventStore implements EventStore
-var _ EventStore = (*SQLiteEventStore)(nil)
+// Ensure SQLiteEventStore implements EventStore and SnapshotStore
+var (
+	_ EventStore    = (*SQLiteEventStore)(nil)
+	_ SnapshotStore = (*SQLiteEventStore)(nil)
+)
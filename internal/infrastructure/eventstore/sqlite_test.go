@@ -0,0 +1,146 @@
+package eventstore_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/domain/aggregates"
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/internal/domain/events"
+	"github.com/rng999/traffic-control-go/internal/infrastructure/eventstore"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestSQLiteEventStore_PersistsTypedEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	device := tc.MustNewDeviceName("eth0")
+
+	class := events.NewHTBClassCreatedEvent("tc:eth0", 1, device, tc.NewHandle(1, 0x10), tc.NewHandle(1, 1), "web", tc.Mbps(30), tc.Mbps(100))
+	filter := events.NewFilterCreatedEvent("tc:eth0", 2, device, tc.NewHandle(1, 0), 100, tc.NewHandle(0x800, 0x100), tc.NewHandle(1, 0x10))
+	filter.AddMatch(entities.MatchTypePortDestination, "443")
+	filter.AddAction(entities.NewPoliceAction(tc.Mbps(5), 10000, entities.PoliceExceedDrop))
+	filter.AddAction(entities.NewPriorityAction(tc.NewHandle(1, 0x15)))
+	class.SetActor("alice")
+
+	store, err := eventstore.NewSQLiteEventStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Save("tc:eth0", []events.DomainEvent{class, filter}, 0))
+	require.NoError(t, store.Close())
+
+	// A reopened store rebuilds the recorded events with their types and metadata
+	store, err = eventstore.NewSQLiteEventStore(path)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	history, err := store.GetEvents("tc:eth0")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	restoredClass, ok := history[0].(*events.HTBClassCreatedEvent)
+	require.True(t, ok, "got %T", history[0])
+	assert.Equal(t, "HTBClassCreated", restoredClass.EventType())
+	assert.Equal(t, 1, restoredClass.EventVersion())
+	assert.Equal(t, "tc:eth0", restoredClass.AggregateID())
+	assert.WithinDuration(t, class.Timestamp(), restoredClass.Timestamp(), time.Second)
+	assert.Equal(t, device, restoredClass.DeviceName)
+	assert.Equal(t, tc.NewHandle(1, 0x10), restoredClass.Handle)
+	assert.Equal(t, tc.Mbps(30), restoredClass.Rate)
+	assert.Equal(t, "web", restoredClass.Name)
+	assert.Equal(t, "alice", restoredClass.Actor())
+
+	restoredFilter, ok := history[1].(*events.FilterCreatedEvent)
+	require.True(t, ok, "got %T", history[1])
+	assert.Equal(t, filter.Matches, restoredFilter.Matches)
+	assert.Equal(t, filter.Actions, restoredFilter.Actions)
+	assert.Equal(t, tc.NewHandle(1, 0x10), restoredFilter.FlowID)
+
+	// Appending against a stale version is rejected
+	err = store.Save("tc:eth0", []events.DomainEvent{events.NewClassDeletedEvent("tc:eth0", 2, device, tc.NewHandle(1, 0x10))}, 1)
+	assert.ErrorContains(t, err, "concurrency conflict")
+}
+
+func TestSQLiteEventStoreWrapper_PublishesSavedEvents(t *testing.T) {
+	store, err := eventstore.NewSQLiteEventStoreWithContext(filepath.Join(t.TempDir(), "history.db"))
+	require.NoError(t, err)
+
+	var published []interface{}
+	store.(eventstore.PublishingEventStore).SetEventPublisher(func(ctx context.Context, event interface{}) error {
+		published = append(published, event)
+		return nil
+	})
+
+	aggregate := &testAggregate{id: "tc:eth0"}
+	aggregate.uncommitted = []events.DomainEvent{createTestEvent()}
+	aggregate.version = 1
+	require.NoError(t, store.SaveAggregate(context.Background(), aggregate))
+	assert.Len(t, published, 1)
+	assert.Empty(t, aggregate.uncommitted)
+}
+
+type testAggregate struct {
+	id          string
+	version     int
+	uncommitted []events.DomainEvent
+}
+
+func (a *testAggregate) GetID() string                                { return a.id }
+func (a *testAggregate) GetUncommittedEvents() []events.DomainEvent   { return a.uncommitted }
+func (a *testAggregate) MarkEventsAsCommitted()                       { a.uncommitted = nil }
+func (a *testAggregate) LoadFromHistory(history []events.DomainEvent) {}
+func (a *testAggregate) GetVersion() int                              { return a.version }
+
+func TestSQLiteEventStoreWrapper_Snapshots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := eventstore.NewSQLiteEventStoreWithContext(path)
+	require.NoError(t, err)
+	ctx := context.Background()
+	device := tc.MustNewDeviceName("eth0")
+	root := tc.NewHandle(1, 0)
+
+	// Churn a class until the history passes the snapshot interval
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	require.NoError(t, aggregate.AddHTBQdisc(root, tc.NewHandle(1, 999)))
+	for aggregate.GetVersion() <= eventstore.SnapshotInterval {
+		require.NoError(t, aggregate.AddHTBClass(root, tc.NewHandle(1, 10), "web", tc.Mbps(10), tc.Mbps(20)))
+		require.NoError(t, aggregate.DeleteClass(tc.NewHandle(1, 10)))
+	}
+	require.NoError(t, aggregate.AddHTBClass(root, tc.NewHandle(1, 20), "bulk", tc.Mbps(5), tc.Mbps(10)))
+	require.NoError(t, store.SaveAggregate(ctx, aggregate))
+
+	snapshot, err := store.(eventstore.SnapshotStore).GetSnapshot(aggregate.GetID())
+	require.NoError(t, err)
+	require.NotNil(t, snapshot)
+	assert.Equal(t, aggregate.GetVersion(), snapshot.Version)
+	require.Len(t, snapshot.State, 2)
+	_, ok := snapshot.State[1].(*events.HTBClassCreatedEvent)
+	assert.True(t, ok, "got %T", snapshot.State[1])
+
+	// Events after the snapshot are replayed on top of it
+	require.NoError(t, aggregate.DeleteClass(tc.NewHandle(1, 20)))
+	require.NoError(t, store.SaveAggregate(ctx, aggregate))
+
+	restored := aggregates.NewTrafficControlAggregate(device)
+	require.NoError(t, store.Load(ctx, restored.GetID(), restored))
+	assert.Equal(t, aggregate.GetVersion(), restored.GetVersion())
+	assert.Len(t, restored.GetQdiscs(), 1)
+	assert.Empty(t, restored.GetClasses())
+}
+
+func TestLoadAggregate_WithoutSnapshot(t *testing.T) {
+	store := eventstore.NewMemoryEventStore()
+	device := tc.MustNewDeviceName("eth0")
+
+	aggregate := aggregates.NewTrafficControlAggregate(device)
+	require.NoError(t, aggregate.AddHTBQdisc(tc.NewHandle(1, 0), tc.NewHandle(1, 999)))
+	require.NoError(t, store.Save(aggregate.GetID(), aggregate.GetUncommittedEvents(), 0))
+
+	restored := aggregates.NewTrafficControlAggregate(device)
+	require.NoError(t, eventstore.LoadAggregate(store, restored.GetID(), restored))
+	assert.Equal(t, 1, restored.GetVersion())
+	assert.Len(t, restored.GetQdiscs(), 1)
+}
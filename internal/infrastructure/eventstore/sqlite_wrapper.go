@@ -2,11 +2,15 @@ package eventstore
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
 )
 
 // SQLiteEventStoreWrapper wraps the SQLite event store to provide context support
 type SQLiteEventStoreWrapper struct {
 	*SQLiteEventStore
+	eventPublisher EventPublisher
 }
 
 // NewSQLiteEventStoreWithContext creates a new SQLite event store with context support
@@ -21,18 +25,14 @@ func NewSQLiteEventStoreWithContext(dbPath string) (EventStoreWithContext, error
 	}, nil
 }
 
-// Load loads an aggregate from the event store
-func (s *SQLiteEventStoreWrapper) Load(ctx context.Context, aggregateID string, aggregate EventSourcedAggregate) error {
-	events, err := s.GetEvents(aggregateID)
-	if err != nil {
-		return err
-	}
-
-	if len(events) > 0 {
-		aggregate.LoadFromHistory(events)
-	}
+// SetEventPublisher sets the event publisher callback
+func (s *SQLiteEventStoreWrapper) SetEventPublisher(publisher EventPublisher) {
+	s.eventPublisher = publisher
+}
 
-	return nil
+// Load loads an aggregate from its latest snapshot and the events recorded after it
+func (s *SQLiteEventStoreWrapper) Load(ctx context.Context, aggregateID string, aggregate EventSourcedAggregate) error {
+	return LoadAggregate(s.SQLiteEventStore, aggregateID, aggregate)
 }
 
 // SaveAggregate saves an aggregate to the event store
@@ -42,12 +42,34 @@ func (s *SQLiteEventStoreWrapper) SaveAggregate(ctx context.Context, aggregate E
 		return nil // No events to save
 	}
 
+	stampActor(ctx, uncommittedEvents)
+
 	expectedVersion := aggregate.GetVersion() - len(uncommittedEvents)
 	if err := s.Save(aggregate.GetID(), uncommittedEvents, expectedVersion); err != nil {
 		return err
 	}
 
 	aggregate.MarkEventsAsCommitted()
+
+	if snapshotDue(aggregate, len(uncommittedEvents)) {
+		// The events are saved; without the snapshot, loading replays more of them
+		if err := takeSnapshot(s.SQLiteEventStore, aggregate); err != nil {
+			s.logger.Warn("Failed to save snapshot",
+				logging.String("aggregate_id", aggregate.GetID()),
+				logging.Error(err))
+		}
+	}
+
+	// Publish events if publisher is set. The events stay saved when publishing fails;
+	// events after the failed one are not published.
+	if s.eventPublisher != nil {
+		for _, event := range uncommittedEvents {
+			if err := s.eventPublisher(ctx, event); err != nil {
+				return fmt.Errorf("event saved but not applied: %w", err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -70,5 +92,8 @@ func (s *SQLiteEventStoreWrapper) GetEventsWithContext(ctx context.Context, aggr
 	return result, nil
 }
 
-// Ensure SQLiteEventStoreWrapper implements EventStoreWithContext
-var _ EventStoreWithContext = (*SQLiteEventStoreWrapper)(nil)
+// Ensure SQLiteEventStoreWrapper implements EventStoreWithContext and PublishingEventStore
+var (
+	_ EventStoreWithContext = (*SQLiteEventStoreWrapper)(nil)
+	_ PublishingEventStore  = (*SQLiteEventStoreWrapper)(nil)
+)
@@ -0,0 +1,107 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+// hasDSCPRemark reports whether an action chain rewrites the DSCP. The netlink library
+// cannot encode the pedit keys this needs, so filters carrying such chains are sent as raw
+// requests whose actions encodeFilterActions adds.
+func hasDSCPRemark(actions []entities.Action) bool {
+	for _, action := range actions {
+		if _, ok := action.(*entities.DSCPRemarkAction); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeFilterActions adds a filter's action chain to its action attribute. DSCP remarks
+// are encoded here, as they are rewritten in the header of the filter's ethertype, which
+// must be IP or IPv6; the other actions are encoded by the netlink library.
+func encodeFilterActions(attr *nl.RtAttr, actions []entities.Action, ethType uint16) error {
+	index := nl.TCA_ACT_TAB
+	for _, action := range actions {
+		remark, ok := action.(*entities.DSCPRemarkAction)
+		if !ok {
+			converted, err := buildFilterActions([]entities.Action{action})
+			if err != nil {
+				return err
+			}
+			if err := addLibraryAction(attr, index, converted[0]); err != nil {
+				return err
+			}
+			index++
+			continue
+		}
+
+		switch ethType {
+		case syscall.ETH_P_IP:
+			dscpRemarkPedit(remark.DSCP(), false).Encode(attr.AddRtAttr(index, nil))
+			index++
+			// IPv4 headers carry a checksum covering the DSCP
+			csum := netlink.NewCsumAction()
+			csum.UpdateFlags = netlink.TCA_CSUM_UPDATE_FLAG_IPV4HDR
+			if err := addLibraryAction(attr, index, csum); err != nil {
+				return err
+			}
+			index++
+		case syscall.ETH_P_IPV6:
+			dscpRemarkPedit(remark.DSCP(), true).Encode(attr.AddRtAttr(index, nil))
+			index++
+		default:
+			return fmt.Errorf("DSCP remarking requires a filter matching IPv4 or IPv6 packets")
+		}
+	}
+	return nil
+}
+
+// dscpRemarkPedit returns a pedit action setting the DSCP bits of the first word of the IP
+// header and keeping the others. Keys hold the bytes in memory order.
+func dscpRemarkPedit(dscp uint8, ipv6 bool) *nl.TcPedit {
+	keep := []byte{0xff, 0x03, 0xff, 0xff}
+	set := []byte{0, dscp << 2, 0, 0}
+	headerType := nl.PeditHeaderType(nl.TCA_PEDIT_KEY_EX_HDR_TYPE_IP4)
+	if ipv6 {
+		// The traffic class straddles the version and the flow label
+		keep = []byte{0xf0, 0x3f, 0xff, 0xff}
+		set = []byte{dscp >> 2, (dscp & 0x03) << 6, 0, 0}
+		headerType = nl.TCA_PEDIT_KEY_EX_HDR_TYPE_IP6
+	}
+
+	pedit := &nl.TcPedit{}
+	pedit.Sel.Action = int32(netlink.TC_ACT_PIPE)
+	pedit.Keys = append(pedit.Keys, nl.TcPeditKey{
+		Mask: nl.NativeEndian().Uint32(keep),
+		Val:  nl.NativeEndian().Uint32(set),
+	})
+	pedit.KeysEx = append(pedit.KeysEx, nl.TcPeditKeyEx{
+		HeaderType: headerType,
+		Cmd:        nl.TCA_PEDIT_KEY_EX_CMD_SET,
+	})
+	pedit.Sel.NKeys = 1
+	return pedit
+}
+
+// addLibraryAction adds an action the netlink library encodes at an index of the chain. The
+// library numbers the actions it encodes from one, so the action is encoded alone and its
+// entry moved to the index.
+func addLibraryAction(attr *nl.RtAttr, index int, action netlink.Action) error {
+	table := nl.NewRtAttr(0, nil)
+	if err := netlink.EncodeActions(table, []netlink.Action{action}); err != nil {
+		return err
+	}
+	entry := table.Serialize()[syscall.SizeofRtAttr:]
+	length := nl.NativeEndian().Uint16(entry)
+	attr.AddRtAttr(index, entry[syscall.SizeofRtAttr:length])
+	return nil
+}
@@ -0,0 +1,63 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+func TestDSCPRemarkPedit(t *testing.T) {
+	// IPv4: the DSCP is the top six bits of the second byte
+	pedit := dscpRemarkPedit(46, false)
+	require.Len(t, pedit.Keys, 1)
+	assert.Equal(t, []byte{0xff, 0x03, 0xff, 0xff}, nativeBytes(pedit.Keys[0].Mask))
+	assert.Equal(t, []byte{0x00, 0xb8, 0x00, 0x00}, nativeBytes(pedit.Keys[0].Val))
+	assert.Equal(t, nl.PeditHeaderType(nl.TCA_PEDIT_KEY_EX_HDR_TYPE_IP4), pedit.KeysEx[0].HeaderType)
+
+	// IPv6: the traffic class starts after the four version bits
+	pedit = dscpRemarkPedit(46, true)
+	assert.Equal(t, []byte{0xf0, 0x3f, 0xff, 0xff}, nativeBytes(pedit.Keys[0].Mask))
+	assert.Equal(t, []byte{0x0b, 0x80, 0x00, 0x00}, nativeBytes(pedit.Keys[0].Val))
+	assert.Equal(t, nl.PeditHeaderType(nl.TCA_PEDIT_KEY_EX_HDR_TYPE_IP6), pedit.KeysEx[0].HeaderType)
+}
+
+func TestEncodeFilterActions(t *testing.T) {
+	actions := []entities.Action{
+		entities.NewMarkAction(0x10, 0xffffffff),
+		entities.NewDSCPRemarkAction(46),
+	}
+
+	attr := nl.NewRtAttr(nl.TCA_U32_ACT, nil)
+	require.NoError(t, encodeFilterActions(attr, actions, syscall.ETH_P_IP))
+
+	// Mark, pedit and the IPv4 checksum fix-up, numbered in order
+	entries, err := nl.ParseRouteAttr(attr.Serialize()[syscall.SizeofRtAttr:])
+	require.NoError(t, err)
+	var kinds []string
+	for i, entry := range entries {
+		assert.Equal(t, uint16(i+1), entry.Attr.Type)
+		fields, err := nl.ParseRouteAttr(entry.Value)
+		require.NoError(t, err)
+		kinds = append(kinds, string(fields[0].Value[:len(fields[0].Value)-1]))
+	}
+	assert.Equal(t, []string{"skbedit", "pedit", "csum"}, kinds)
+
+	// The DSCP can only be rewritten in an IP header
+	err = encodeFilterActions(nl.NewRtAttr(nl.TCA_FLOWER_ACT, nil), actions, syscall.ETH_P_ALL)
+	assert.Error(t, err)
+}
+
+// nativeBytes returns the memory representation of a key word
+func nativeBytes(word uint32) []byte {
+	b := make([]byte, 4)
+	nl.NativeEndian().PutUint32(b, word)
+	return b
+}
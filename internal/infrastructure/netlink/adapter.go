@@ -6,6 +6,8 @@ package netlink
 import (
 	"context"
 	"fmt"
+	"math"
+	"strings"
 	"syscall"
 
 	"github.com/vishvananda/netlink"
@@ -13,6 +15,7 @@ import (
 	"github.com/rng999/traffic-control-go/internal/domain/entities"
 	"github.com/rng999/traffic-control-go/pkg/logging"
 	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
 	"github.com/rng999/traffic-control-go/pkg/types"
 )
 
@@ -31,6 +34,12 @@ func NewRealNetlinkAdapter() *RealNetlinkAdapter {
 	}
 }
 
+// CheckPrivileges checks that the process holds CAP_NET_ADMIN, without which the kernel
+// refuses every change
+func (a *RealNetlinkAdapter) CheckPrivileges(ctx context.Context) error {
+	return ReadPrivileges().Check()
+}
+
 // AddQdisc adds a qdisc using netlink
 func (a *RealNetlinkAdapter) AddQdisc(ctx context.Context, qdiscEntity *entities.Qdisc) error {
 	a.logger.Info("Adding qdisc",
@@ -43,30 +52,101 @@ func (a *RealNetlinkAdapter) AddQdisc(ctx context.Context, qdiscEntity *entities
 	// Get the network link
 	link, err := netlink.LinkByName(qdiscEntity.Device().String())
 	if err != nil {
-		return fmt.Errorf("failed to find device %s: %w", qdiscEntity.Device(), err)
+		return deviceNotFound(qdiscEntity.Device(), err)
 	}
 
-	// Create HTB qdisc
-	qdisc := &netlink.Htb{
-		QdiscAttrs: netlink.QdiscAttrs{
-			LinkIndex: link.Attrs().Index,
-			Handle:    netlink.MakeHandle(qdiscEntity.Handle().Major(), qdiscEntity.Handle().Minor()),
-			Parent:    netlink.HANDLE_ROOT,
-		},
-		Version:      3,
-		Rate2Quantum: 10,
-		Defcls:       0, // Will be set by the HTB configuration
+	attrs := netlink.QdiscAttrs{
+		LinkIndex: link.Attrs().Index,
+		Handle:    netlink.MakeHandle(qdiscEntity.Handle().Major(), qdiscEntity.Handle().Minor()),
+		Parent:    netlink.HANDLE_ROOT,
 	}
 
 	// Handle parent if not root
 	if qdiscEntity.Parent() != nil {
-		attrs := qdisc.Attrs()
 		attrs.Parent = netlink.MakeHandle(qdiscEntity.Parent().Major(), qdiscEntity.Parent().Minor())
 	}
 
+	var qdisc netlink.Qdisc
+	switch qdiscEntity.Type() {
+	case entities.QdiscTypeFQCODEL:
+		qdisc = &netlink.FqCodel{
+			QdiscAttrs: attrs,
+			Limit:      qdiscParameter(qdiscEntity, "limit", 10240),
+			Flows:      qdiscParameter(qdiscEntity, "flows", 1024),
+			Target:     qdiscParameter(qdiscEntity, "target", 5000),
+			Interval:   qdiscParameter(qdiscEntity, "interval", 100000),
+			Quantum:    qdiscParameter(qdiscEntity, "quantum", 1518),
+			ECN:        qdiscParameter(qdiscEntity, "ecn", 0),
+		}
+	case entities.QdiscTypeSFQ:
+		qdisc = &netlink.Sfq{
+			QdiscAttrs: attrs,
+			Perturb:    int32(qdiscParameter(qdiscEntity, "perturb", 0)), // #nosec G115 - perturbation periods are small
+			Quantum:    qdiscParameter(qdiscEntity, "quantum", 1514),
+			Limit:      qdiscParameter(qdiscEntity, "limit", 127),
+		}
+	case entities.QdiscTypeFQ:
+		fq := &netlink.Fq{
+			QdiscAttrs:      attrs,
+			PacketLimit:     qdiscParameter(qdiscEntity, "limit", 10000),
+			FlowPacketLimit: qdiscParameter(qdiscEntity, "flow_limit", 100),
+			Quantum:         qdiscParameter(qdiscEntity, "quantum", 3028),
+			InitialQuantum:  qdiscParameter(qdiscEntity, "initial_quantum", 15140),
+			Pacing:          qdiscParameter(qdiscEntity, "pacing", 1),
+		}
+		if value, ok := qdiscEntity.GetParameter("max_rate"); ok {
+			if maxRate, ok := value.(tc.Bandwidth); ok && maxRate.BitsPerSecond() > 0 {
+				// The kernel takes the per-flow cap in bytes per second as a 32-bit value
+				fq.FlowMaxRate = uint32(min(maxRate.BitsPerSecond()/8, math.MaxUint32)) // #nosec G115 - clamped above
+			}
+		}
+		qdisc = fq
+	case entities.QdiscTypeNETEM:
+		qdisc = newNetemQdisc(attrs, qdiscEntity)
+	case entities.QdiscTypeCAKE:
+		qdisc = newCAKEQdisc(attrs, qdiscEntity)
+	case entities.QdiscTypeRED:
+		qdisc = newREDQdisc(attrs, qdiscEntity)
+	case entities.QdiscTypeGRED:
+		qdisc = newGREDQdisc(attrs, qdiscEntity)
+	case entities.QdiscTypeCODEL:
+		qdisc = newCODELQdisc(attrs, qdiscEntity)
+	case entities.QdiscTypeETS:
+		qdisc = newETSQdisc(attrs, qdiscEntity)
+	case entities.QdiscTypeTAPRIO:
+		qdisc = newTAPRIOQdisc(attrs, qdiscEntity)
+	case entities.QdiscTypeDRR, entities.QdiscTypeQFQ:
+		// Both take no qdisc options
+		qdisc = &netlink.GenericQdisc{QdiscAttrs: attrs, QdiscType: qdiscEntity.Type().String()}
+	default:
+		// Create HTB qdisc
+		qdisc = &netlink.Htb{
+			QdiscAttrs:   attrs,
+			Version:      3,
+			Rate2Quantum: 10,
+			Defcls:       qdiscParameter(qdiscEntity, "default_class", 0),
+		}
+	}
+
 	// Add the qdisc
-	if err := netlink.QdiscAdd(qdisc); err != nil {
-		return fmt.Errorf("failed to add qdisc: %w", err)
+	switch q := qdisc.(type) {
+	case *cakeQdisc:
+		err = addCAKEQdisc(q)
+	case *redQdisc:
+		err = addREDQdisc(q)
+	case *gredQdisc:
+		err = addGREDQdisc(q)
+	case *codelQdisc:
+		err = addCODELQdisc(q)
+	case *etsQdisc:
+		err = addETSQdisc(q)
+	case *taprioQdisc:
+		err = addTAPRIOQdisc(q)
+	default:
+		err = netlink.QdiscAdd(qdisc)
+	}
+	if err != nil {
+		return tcerrors.FromErrno(err, qdiscObject(qdiscEntity.Handle()), "failed to add qdisc")
 	}
 
 	a.logger.Info("Qdisc added successfully",
@@ -82,7 +162,7 @@ func (a *RealNetlinkAdapter) DeleteQdisc(device tc.DeviceName, handle tc.Handle)
 	// Get the network link
 	link, err := netlink.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[Unit](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[Unit](deviceNotFound(device, err))
 	}
 
 	// Create a generic qdisc with the handle to delete
@@ -96,7 +176,7 @@ func (a *RealNetlinkAdapter) DeleteQdisc(device tc.DeviceName, handle tc.Handle)
 	}
 
 	if err := netlink.QdiscDel(qdisc); err != nil {
-		return types.Failure[Unit](fmt.Errorf("failed to delete qdisc: %w", err))
+		return types.Failure[Unit](tcerrors.FromErrno(err, qdiscObject(handle), "failed to delete qdisc"))
 	}
 
 	return types.Success(Unit{})
@@ -107,7 +187,7 @@ func (a *RealNetlinkAdapter) GetQdiscs(device tc.DeviceName) types.Result[[]Qdis
 	// Get the network link
 	link, err := netlink.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[[]QdiscInfo](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[[]QdiscInfo](deviceNotFound(device, err))
 	}
 
 	// Get all qdiscs for the link
@@ -121,13 +201,9 @@ func (a *RealNetlinkAdapter) GetQdiscs(device tc.DeviceName) types.Result[[]Qdis
 	for _, qdisc := range qdiscs {
 		info := QdiscInfo{
 			Handle: tc.HandleFromUint32(qdisc.Attrs().Handle),
-			Statistics: QdiscStats{
-				BytesSent:    0, // Actual stats would be retrieved differently
-				PacketsSent:  0, // Statistics struct differs across netlink versions
-				BytesDropped: 0,
-				Overlimits:   0,
-				Requeues:     0,
-			},
+		}
+		if qdisc.Attrs().Statistics != nil {
+			info.Statistics = QdiscStatsFromKernel(qdisc.Attrs().Statistics)
 		}
 
 		// Set parent if not root
@@ -140,6 +216,9 @@ func (a *RealNetlinkAdapter) GetQdiscs(device tc.DeviceName) types.Result[[]Qdis
 		switch qdisc.Type() {
 		case "htb":
 			info.Type = entities.QdiscTypeHTB
+			if htb, ok := qdisc.(*netlink.Htb); ok && htb.Defcls != 0 {
+				info.DefaultClass = tc.NewHandle(info.Handle.Major(), uint16(htb.Defcls)) // #nosec G115 - class minors are 16 bits
+			}
 		case "tbf":
 			info.Type = entities.QdiscTypeTBF
 		case "prio":
@@ -148,8 +227,26 @@ func (a *RealNetlinkAdapter) GetQdiscs(device tc.DeviceName) types.Result[[]Qdis
 			info.Type = entities.QdiscTypeFQCODEL
 		case "sfq":
 			info.Type = entities.QdiscTypeSFQ
+		case "fq":
+			info.Type = entities.QdiscTypeFQ
+		case "netem":
+			info.Type = entities.QdiscTypeNETEM
 		case "cake":
 			info.Type = entities.QdiscTypeCAKE
+		case "red":
+			info.Type = entities.QdiscTypeRED
+		case "gred":
+			info.Type = entities.QdiscTypeGRED
+		case "codel":
+			info.Type = entities.QdiscTypeCODEL
+		case "ets":
+			info.Type = entities.QdiscTypeETS
+		case "taprio":
+			info.Type = entities.QdiscTypeTAPRIO
+		case "drr":
+			info.Type = entities.QdiscTypeDRR
+		case "qfq":
+			info.Type = entities.QdiscTypeQFQ
 		}
 
 		result = append(result, info)
@@ -180,68 +277,12 @@ func (a *RealNetlinkAdapter) AddClass(ctx context.Context, classEntity interface
 		// Get the network link
 		link, err := netlink.LinkByName(class.ID().Device().String())
 		if err != nil {
-			return fmt.Errorf("failed to find device %s: %w", class.ID().Device(), err)
-		}
-
-		// Create netlink HTB class
-		nlClass := netlink.NewHtbClass(netlink.ClassAttrs{
-			LinkIndex: link.Attrs().Index,
-			Handle:    netlink.MakeHandle(class.Handle().Major(), class.Handle().Minor()),
-			Parent:    netlink.MakeHandle(class.Parent().Major(), class.Parent().Minor()),
-		}, netlink.HtbClassAttrs{})
-
-		// Set HTB class parameters
-		nlClass.Rate = uint64(class.Rate().BitsPerSecond()) / 8 // Convert to bytes per second
-		nlClass.Ceil = uint64(class.Ceil().BitsPerSecond()) / 8
-
-		// Set burst parameters - use enhanced calculation if available
-		if class.Burst() > 0 {
-			nlClass.Buffer = class.Burst()
-		} else {
-			nlClass.Buffer = class.CalculateEnhancedBurst()
-		}
-
-		if class.Cburst() > 0 {
-			nlClass.Cbuffer = class.Cburst()
-		} else {
-			nlClass.Cbuffer = class.CalculateEnhancedCburst()
-		}
-
-		// Set enhanced HTB parameters if available
-		if class.Quantum() > 0 {
-			nlClass.Quantum = class.Quantum()
-		} else {
-			nlClass.Quantum = class.CalculateQuantum()
-		}
-
-		// Note: Advanced parameters (Overhead, MPU, MTU) are not supported by the current netlink library version
-		// These are tracked in the domain model but not applied via netlink for now
-
-		// Set HTB priority if specified and supported
-		if class.HTBPrio() > 0 {
-			nlClass.Prio = class.HTBPrio()
-		}
-
-		a.logger.Debug("HTB class parameters",
-			logging.String("rate", fmt.Sprintf("%d", nlClass.Rate)),
-			logging.String("ceil", fmt.Sprintf("%d", nlClass.Ceil)),
-			logging.String("buffer", fmt.Sprintf("%d", nlClass.Buffer)),
-			logging.String("cbuffer", fmt.Sprintf("%d", nlClass.Cbuffer)),
-			logging.String("quantum", fmt.Sprintf("%d", nlClass.Quantum)),
-			logging.String("prio", fmt.Sprintf("%d", nlClass.Prio)),
-		)
-
-		// Log advanced parameters for debugging (domain model only)
-		if class.Overhead() > 0 || class.MPU() > 0 || class.MTU() > 0 {
-			a.logger.Debug("Advanced HTB parameters (domain model only)",
-				logging.String("overhead", fmt.Sprintf("%d", class.Overhead())),
-				logging.String("mpu", fmt.Sprintf("%d", class.MPU())),
-				logging.String("mtu", fmt.Sprintf("%d", class.MTU())),
-			)
+			return deviceNotFound(class.ID().Device(), err)
 		}
 
+		nlClass := a.htbClass(class, link)
 		if err := netlink.ClassAdd(nlClass); err != nil {
-			return fmt.Errorf("failed to add HTB class: %w", err)
+			return tcerrors.FromErrno(err, classObject(class.Handle()), "failed to add HTB class")
 		}
 
 		a.logger.Info("HTB class added successfully",
@@ -251,17 +292,127 @@ func (a *RealNetlinkAdapter) AddClass(ctx context.Context, classEntity interface
 
 		return nil
 
+	case *entities.DRRClass:
+		a.logger.Info("Adding DRR class",
+			logging.String("device", class.ID().Device().String()),
+			logging.String("handle", class.Handle().String()),
+			logging.String("operation", logging.OperationCreateClass),
+		)
+		return addFairQueueingClass(class.Class, "drr", drrClassOptions(class))
+
+	case *entities.QFQClass:
+		a.logger.Info("Adding QFQ class",
+			logging.String("device", class.ID().Device().String()),
+			logging.String("handle", class.Handle().String()),
+			logging.String("operation", logging.OperationCreateClass),
+		)
+		return addFairQueueingClass(class.Class, "qfq", qfqClassOptions(class))
+
 	default:
 		return fmt.Errorf("unsupported class type: %T", classEntity)
 	}
 }
 
+// ChangeClass changes the parameters of an existing class in place. Unlike deleting and
+// adding it again, the class keeps its counters, its queued packets and everything attached
+// to it. Only HTB classes can be changed.
+func (a *RealNetlinkAdapter) ChangeClass(ctx context.Context, classEntity interface{}) error {
+	class, ok := classEntity.(*entities.HTBClass)
+	if !ok {
+		return tcerrors.New(tcerrors.CodeUnsupported, "", "changing %T classes in place is not supported", classEntity)
+	}
+
+	a.logger.Info("Changing HTB class",
+		logging.String("device", class.ID().Device().String()),
+		logging.String("handle", class.Handle().String()),
+		logging.String("operation", logging.OperationUpdateClass),
+	)
+
+	link, err := netlink.LinkByName(class.ID().Device().String())
+	if err != nil {
+		return deviceNotFound(class.ID().Device(), err)
+	}
+
+	if err := netlink.ClassChange(a.htbClass(class, link)); err != nil {
+		return tcerrors.FromErrno(err, classObject(class.Handle()), "failed to change HTB class")
+	}
+
+	a.logger.Info("HTB class changed successfully",
+		logging.String("handle", class.Handle().String()),
+		logging.String("rate", class.Rate().String()),
+		logging.String("ceil", class.Ceil().String()),
+	)
+	return nil
+}
+
+// htbClass builds the netlink HTB class of a domain HTB class on the link
+func (a *RealNetlinkAdapter) htbClass(class *entities.HTBClass, link netlink.Link) *netlink.HtbClass {
+	// Create netlink HTB class
+	nlClass := netlink.NewHtbClass(netlink.ClassAttrs{
+		LinkIndex: link.Attrs().Index,
+		Handle:    netlink.MakeHandle(class.Handle().Major(), class.Handle().Minor()),
+		Parent:    netlink.MakeHandle(class.Parent().Major(), class.Parent().Minor()),
+	}, netlink.HtbClassAttrs{})
+
+	// Set HTB class parameters
+	nlClass.Rate = uint64(class.Rate().BitsPerSecond()) / 8 // Convert to bytes per second
+	nlClass.Ceil = uint64(class.Ceil().BitsPerSecond()) / 8
+
+	// Set burst parameters - use enhanced calculation if available
+	if class.Burst() > 0 {
+		nlClass.Buffer = class.Burst()
+	} else {
+		nlClass.Buffer = class.CalculateEnhancedBurst()
+	}
+
+	if class.Cburst() > 0 {
+		nlClass.Cbuffer = class.Cburst()
+	} else {
+		nlClass.Cbuffer = class.CalculateEnhancedCburst()
+	}
+
+	// Set enhanced HTB parameters if available
+	if class.Quantum() > 0 {
+		nlClass.Quantum = class.Quantum()
+	} else {
+		nlClass.Quantum = class.CalculateQuantum()
+	}
+
+	// Note: Advanced parameters (Overhead, MPU, MTU) are not supported by the current netlink library version
+	// These are tracked in the domain model but not applied via netlink for now
+
+	// Set HTB priority if specified and supported
+	if class.HTBPrio() > 0 {
+		nlClass.Prio = class.HTBPrio()
+	}
+
+	a.logger.Debug("HTB class parameters",
+		logging.String("rate", fmt.Sprintf("%d", nlClass.Rate)),
+		logging.String("ceil", fmt.Sprintf("%d", nlClass.Ceil)),
+		logging.String("buffer", fmt.Sprintf("%d", nlClass.Buffer)),
+		logging.String("cbuffer", fmt.Sprintf("%d", nlClass.Cbuffer)),
+		logging.String("quantum", fmt.Sprintf("%d", nlClass.Quantum)),
+		logging.String("prio", fmt.Sprintf("%d", nlClass.Prio)),
+	)
+
+	// Log advanced parameters for debugging (domain model only)
+	if class.Overhead() > 0 || class.MPU() > 0 || class.MTU() > 0 {
+		a.logger.Debug("Advanced HTB parameters (domain model only)",
+			logging.String("overhead", fmt.Sprintf("%d", class.Overhead())),
+			logging.String("mpu", fmt.Sprintf("%d", class.MPU())),
+			logging.String("mtu", fmt.Sprintf("%d", class.MTU())),
+		)
+	}
+
+	return nlClass
+}
+
 // DeleteClass deletes a class using netlink
 func (a *RealNetlinkAdapter) DeleteClass(device tc.DeviceName, handle tc.Handle) types.Result[Unit] {
 	// Get the network link
 	link, err := netlink.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[Unit](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[Unit](deviceNotFound(device, err))
 	}
 
 	// Create a generic class with the handle to delete
@@ -274,7 +425,7 @@ func (a *RealNetlinkAdapter) DeleteClass(device tc.DeviceName, handle tc.Handle)
 	}
 
 	if err := netlink.ClassDel(class); err != nil {
-		return types.Failure[Unit](fmt.Errorf("failed to delete class: %w", err))
+		return types.Failure[Unit](tcerrors.FromErrno(err, classObject(handle), "failed to delete class"))
 	}
 
 	return types.Success(Unit{})
@@ -285,7 +436,7 @@ func (a *RealNetlinkAdapter) GetClasses(device tc.DeviceName) types.Result[[]Cla
 	// Get the network link
 	link, err := netlink.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[[]ClassInfo](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[[]ClassInfo](deviceNotFound(device, err))
 	}
 
 	// Get all classes for the link
@@ -307,21 +458,24 @@ func (a *RealNetlinkAdapter) GetClasses(device tc.DeviceName) types.Result[[]Cla
 			info := ClassInfo{
 				Handle: tc.HandleFromUint32(class.Attrs().Handle),
 				Parent: tc.HandleFromUint32(class.Attrs().Parent),
-				Statistics: ClassStats{
-					BytesSent:      0, // Actual stats would be retrieved differently
-					PacketsSent:    0, // Statistics struct differs across netlink versions
-					BytesDropped:   0,
-					Overlimits:     0,
-					RateBPS:        0,
-					BacklogBytes:   0,
-					BacklogPackets: 0,
-				},
+			}
+			if class.Attrs().Statistics != nil {
+				info.Statistics = ClassStatsFromKernel(class.Attrs().Statistics)
 			}
 
 			// Determine type based on class type
 			switch class.Type() {
 			case "htb":
 				info.Type = entities.QdiscTypeHTB
+				if htb, ok := class.(*netlink.HtbClass); ok {
+					// The kernel keeps the rates in bytes per second
+					info.Rate = tc.Bps(htb.Rate * 8)
+					info.Ceil = tc.Bps(htb.Ceil * 8)
+				}
+			case "drr":
+				info.Type = entities.QdiscTypeDRR
+			case "qfq":
+				info.Type = entities.QdiscTypeQFQ
 			}
 
 			result = append(result, info)
@@ -341,7 +495,34 @@ func (a *RealNetlinkAdapter) AddFilter(ctx context.Context, filterEntity *entiti
 	// Get the network link
 	link, err := netlink.LinkByName(filterEntity.ID().Device().String())
 	if err != nil {
-		return fmt.Errorf("failed to find device %s: %w", filterEntity.ID().Device(), err)
+		return deviceNotFound(filterEntity.ID().Device(), err)
+	}
+
+	switch filterEntity.Kind() {
+	case entities.FilterKindFlower:
+		if err := addFlowerFilter(link.Attrs().Index, filterEntity); err != nil {
+			return tcerrors.FromErrno(err, filterObject(filterEntity.Parent(), filterEntity.Priority()), "failed to add flower filter")
+		}
+
+		a.logger.Info("Flower filter added successfully",
+			logging.String("flow_id", filterEntity.FlowID().String()),
+			logging.Int("priority", int(filterEntity.ID().Priority())),
+		)
+		return nil
+	case entities.FilterKindCgroup:
+		actions, err := buildFilterActions(filterEntity.Actions())
+		if err != nil {
+			return fmt.Errorf("failed to configure filter actions: %w", err)
+		}
+		if err := addCgroupFilter(link, filterEntity, actions); err != nil {
+			return tcerrors.FromErrno(err, filterObject(filterEntity.Parent(), filterEntity.Priority()), "failed to add cgroup filter")
+		}
+
+		a.logger.Info("Cgroup filter added successfully",
+			logging.String("flow_id", filterEntity.FlowID().String()),
+			logging.Int("priority", int(filterEntity.ID().Priority())),
+		)
+		return nil
 	}
 
 	// Create u32 filter with match conditions
@@ -367,8 +548,20 @@ func (a *RealNetlinkAdapter) AddFilter(ctx context.Context, filterEntity *entiti
 		logging.Int("priority", int(filterEntity.ID().Priority())),
 	)
 
-	if err := netlink.FilterAdd(filter); err != nil {
-		return fmt.Errorf("failed to add filter: %w", err)
+	if hasDSCPRemark(filterEntity.Actions()) {
+		if err := addU32Filter(filter, filterEntity.Actions()); err != nil {
+			return tcerrors.FromErrno(err, filterObject(filterEntity.Parent(), filterEntity.Priority()), "failed to add filter")
+		}
+	} else {
+		actions, err := buildFilterActions(filterEntity.Actions())
+		if err != nil {
+			return fmt.Errorf("failed to configure filter actions: %w", err)
+		}
+		filter.Actions = append(filter.Actions, actions...)
+
+		if err := netlink.FilterAdd(filter); err != nil {
+			return tcerrors.FromErrno(err, filterObject(filterEntity.Parent(), filterEntity.Priority()), "failed to add filter")
+		}
 	}
 
 	a.logger.Info("Filter added successfully",
@@ -384,21 +577,23 @@ func (a *RealNetlinkAdapter) DeleteFilter(device tc.DeviceName, parent tc.Handle
 	// Get the network link
 	link, err := netlink.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[Unit](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[Unit](deviceNotFound(device, err))
 	}
 
-	// Create filter to delete
-	filter := &netlink.U32{
+	// Create filter to delete; the kernel refuses deletions naming another classifier
+	parentHandle := netlink.MakeHandle(parent.Major(), parent.Minor())
+	filter := &netlink.GenericFilter{
 		FilterAttrs: netlink.FilterAttrs{
 			LinkIndex: link.Attrs().Index,
-			Parent:    netlink.MakeHandle(parent.Major(), parent.Minor()),
+			Parent:    parentHandle,
 			Priority:  priority,
 			Handle:    netlink.MakeHandle(handle.Major(), handle.Minor()),
 		},
+		FilterType: installedFilterKind(link, parentHandle, priority),
 	}
 
 	if err := netlink.FilterDel(filter); err != nil {
-		return types.Failure[Unit](fmt.Errorf("failed to delete filter: %w", err))
+		return types.Failure[Unit](tcerrors.FromErrno(err, filterObject(parent, priority), "failed to delete filter"))
 	}
 
 	return types.Success(Unit{})
@@ -409,7 +604,7 @@ func (a *RealNetlinkAdapter) GetFilters(device tc.DeviceName) types.Result[[]Fil
 	// Get the network link
 	link, err := netlink.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[[]FilterInfo](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[[]FilterInfo](deviceNotFound(device, err))
 	}
 
 	// Get all qdiscs first
@@ -441,6 +636,14 @@ func (a *RealNetlinkAdapter) GetFilters(device tc.DeviceName) types.Result[[]Fil
 				// Extract matches - this is simplified
 				// Real implementation would need to parse U32 sel
 			}
+			if flower, ok := filter.(*netlink.Flower); ok {
+				info.Kind = entities.FilterKindFlower
+				info.FlowID = tc.HandleFromUint32(flower.ClassId)
+			}
+			if bpf, ok := filter.(*netlink.BpfFilter); ok && strings.HasPrefix(bpf.Name, "cgroup:") {
+				info.Kind = entities.FilterKindCgroup
+				info.FlowID = tc.HandleFromUint32(bpf.ClassId)
+			}
 
 			result = append(result, info)
 		}
@@ -451,6 +654,20 @@ func (a *RealNetlinkAdapter) GetFilters(device tc.DeviceName) types.Result[[]Fil
 
 // Helper functions
 
+// installedFilterKind returns the classifier of the filters at a priority under the parent,
+// u32 when none is found
+func installedFilterKind(link netlink.Link, parent uint32, priority uint16) string {
+	filters, err := netlink.FilterList(link, parent)
+	if err == nil {
+		for _, filter := range filters {
+			if filter.Attrs().Priority == priority {
+				return filter.Type()
+			}
+		}
+	}
+	return "u32"
+}
+
 func convertProtocolBack(p uint16) entities.Protocol {
 	switch p {
 	case 0x0000:
@@ -464,96 +681,49 @@ func convertProtocolBack(p uint16) entities.Protocol {
 	}
 }
 
-// configureU32Matches configures U32 filter match conditions
+// configureU32Matches configures U32 filter match conditions; all of them must hold
 func (a *RealNetlinkAdapter) configureU32Matches(filter *netlink.U32, matches []entities.Match) error {
 	if len(matches) == 0 {
 		// No match conditions - create a match-all filter
 		return nil
 	}
 
-	// For now, we'll implement port matching which is the most common case
-	// U32 filters use selectors to match fields in the packet
+	var keys []netlink.TcU32Key
 	for _, match := range matches {
-		switch match.Type() {
-		case entities.MatchTypePortDestination:
-			if portMatch, ok := match.(*entities.PortMatch); ok {
-				// For destination port matching in TCP/UDP:
-				// The port is at offset 2 in the TCP/UDP header
-				// TCP/UDP header starts at IP header length (variable)
-				// For simplicity, assume standard 20-byte IP header (offset 20)
-				// Destination port is at bytes 22-23 (offset 22)
-
-				port := portMatch.Port()
-
-				// Create U32 selector for destination port
-				// This matches the destination port field in TCP/UDP header
-				sel := &netlink.TcU32Sel{
-					Flags:    0,
-					Offshift: 0,
-					Nkeys:    1,
-					Offmask:  0,
-					Off:      0,
-					Offoff:   0,
-					Hoff:     0,
-					Hmask:    0,
-				}
-
-				// Configure the key to match destination port
-				// Key matches 2 bytes at offset 22 (destination port in TCP/UDP)
-				key := netlink.TcU32Key{
-					Mask:    0x0000ffff,   // Match 2 bytes (port) in lower 16 bits
-					Val:     uint32(port), // Port value
-					Off:     22,           // Offset 22 for destination port in TCP/UDP
-					OffMask: 0,
-				}
-
-				sel.Keys = []netlink.TcU32Key{key}
-				filter.Sel = sel
-
-				a.logger.Debug("Configured destination port match",
-					logging.Int("port", int(port)),
-					logging.String("mask", fmt.Sprintf("0x%08x", key.Mask)),
-					logging.String("val", fmt.Sprintf("0x%08x", key.Val)),
-				)
-			}
-		case entities.MatchTypePortSource:
-			if portMatch, ok := match.(*entities.PortMatch); ok {
-				// Source port is at offset 20 in TCP/UDP header (after IP header)
-				port := portMatch.Port()
-
-				sel := &netlink.TcU32Sel{
-					Flags:    0,
-					Offshift: 0,
-					Nkeys:    1,
-					Offmask:  0,
-					Off:      0,
-					Offoff:   0,
-					Hoff:     0,
-					Hmask:    0,
-				}
-
-				key := netlink.TcU32Key{
-					Mask:    0xffff0000,         // Match 2 bytes (port) at high bits
-					Val:     uint32(port) << 16, // Port value shifted for high bits
-					Off:     20,                 // Offset 20 for source port in TCP/UDP
-					OffMask: 0,
-				}
-
-				sel.Keys = []netlink.TcU32Key{key}
-				filter.Sel = sel
-
-				a.logger.Debug("Configured source port match",
-					logging.Int("port", int(port)),
-				)
-			}
-		default:
-			// For now, skip other match types (IP addresses, etc.)
-			// They can be implemented later as needed
+		matchKeys, err := u32Keys(match)
+		if err != nil {
+			return err
+		}
+		if matchKeys == nil {
+			// Mark, port range and flow matches are not expressed as u32 keys
 			a.logger.Debug("Skipping unsupported match type",
 				logging.String("type", fmt.Sprintf("%v", match.Type())),
 			)
+			continue
 		}
+
+		for _, key := range matchKeys {
+			a.logger.Debug("Configured u32 match key",
+				logging.String("match", match.String()),
+				logging.String("mask", fmt.Sprintf("0x%08x", key.Mask)),
+				logging.String("val", fmt.Sprintf("0x%08x", key.Val)),
+				logging.Int("off", int(key.Off)),
+			)
+		}
+		keys = append(keys, matchKeys...)
 	}
 
+	if len(keys) == 0 {
+		return nil
+	}
+	if len(keys) > u32MaxKeys {
+		return fmt.Errorf("too many u32 match keys: %d, at most %d", len(keys), u32MaxKeys)
+	}
+
+	filter.Sel = &netlink.TcU32Sel{
+		Flags: netlink.TC_U32_TERMINAL,
+		Nkeys: uint8(len(keys)),
+		Keys:  keys,
+	}
 	return nil
 }
@@ -48,6 +48,11 @@ func (a *RealNetlinkAdapter) AddClass(ctx context.Context, class interface{}) er
 	return fmt.Errorf("traffic control operations are not supported on this platform")
 }
 
+// ChangeClass is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) ChangeClass(ctx context.Context, class interface{}) error {
+	return fmt.Errorf("traffic control operations are not supported on this platform")
+}
+
 // DeleteClass is not supported on non-Linux platforms
 func (a *RealNetlinkAdapter) DeleteClass(device tc.DeviceName, handle tc.Handle) types.Result[Unit] {
 	return types.Failure[Unit](fmt.Errorf("traffic control operations are not supported on this platform"))
@@ -87,3 +92,68 @@ func (a *RealNetlinkAdapter) GetDetailedClassStats(device tc.DeviceName, handle
 func (a *RealNetlinkAdapter) GetLinkStats(device tc.DeviceName) types.Result[LinkStats] {
 	return types.Failure[LinkStats](fmt.Errorf("traffic control operations are not supported on this platform"))
 }
+
+// WatchQdiscDeletions is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) WatchQdiscDeletions(ctx context.Context, device tc.DeviceName) (<-chan QdiscDeletion, error) {
+	return nil, fmt.Errorf("traffic control operations are not supported on this platform")
+}
+
+// WatchLinks is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) WatchLinks(ctx context.Context, device tc.DeviceName) (<-chan LinkEvent, error) {
+	return nil, fmt.Errorf("traffic control operations are not supported on this platform")
+}
+
+// GetLinkInfo is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) GetLinkInfo(device tc.DeviceName) types.Result[LinkInfo] {
+	return types.Failure[LinkInfo](fmt.Errorf("traffic control operations are not supported on this platform"))
+}
+
+// GetBridgePorts is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) GetBridgePorts(bridge tc.DeviceName) types.Result[[]tc.DeviceName] {
+	return types.Failure[[]tc.DeviceName](fmt.Errorf("traffic control operations are not supported on this platform"))
+}
+
+// AddIngressRedirect is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) AddIngressRedirect(ctx context.Context, device, ifb tc.DeviceName) error {
+	return fmt.Errorf("traffic control operations are not supported on this platform")
+}
+
+// DeleteIngressRedirect is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) DeleteIngressRedirect(device, ifb tc.DeviceName) types.Result[Unit] {
+	return types.Failure[Unit](fmt.Errorf("traffic control operations are not supported on this platform"))
+}
+
+// EnsureClsactQdisc is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) EnsureClsactQdisc(ctx context.Context, device tc.DeviceName, hook ClsactHook) (bool, error) {
+	return false, fmt.Errorf("traffic control operations are not supported on this platform")
+}
+
+// DeleteClsactQdisc is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) DeleteClsactQdisc(device tc.DeviceName) types.Result[Unit] {
+	return types.Failure[Unit](fmt.Errorf("traffic control operations are not supported on this platform"))
+}
+
+// AttachBPFClassifier is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) AttachBPFClassifier(ctx context.Context, device tc.DeviceName, classifier BPFClassifier) error {
+	return fmt.Errorf("traffic control operations are not supported on this platform")
+}
+
+// DetachBPFClassifier is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) DetachBPFClassifier(device tc.DeviceName, hook ClsactHook, priority uint16) types.Result[Unit] {
+	return types.Failure[Unit](fmt.Errorf("traffic control operations are not supported on this platform"))
+}
+
+// UpdateBPFMap is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) UpdateBPFMap(path string, key, value []byte) error {
+	return fmt.Errorf("traffic control operations are not supported on this platform")
+}
+
+// DeleteBPFMapEntry is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) DeleteBPFMapEntry(path string, key []byte) types.Result[Unit] {
+	return types.Failure[Unit](fmt.Errorf("traffic control operations are not supported on this platform"))
+}
+
+// CheckPrivileges is not supported on non-Linux platforms
+func (a *RealNetlinkAdapter) CheckPrivileges(ctx context.Context) error {
+	return fmt.Errorf("traffic control operations are not supported on this platform")
+}
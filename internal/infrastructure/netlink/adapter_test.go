@@ -51,7 +51,7 @@ func TestConfigureU32Matches_PortFiltering(t *testing.T) {
 		key := filter.Sel.Keys[0]
 		assert.Equal(t, uint32(0x0000ffff), key.Mask, "Should match 2 bytes (port)")
 		assert.Equal(t, uint32(5201), key.Val, "Should match port 5201")
-		assert.Equal(t, int32(22), key.Off, "Should be at offset 22 (dest port)")
+		assert.Equal(t, int32(20), key.Off, "Should be in the word at offset 20 (ports)")
 	})
 
 	t.Run("Source Port Match", func(t *testing.T) {
@@ -101,7 +101,7 @@ func TestConfigureU32Matches_PortFiltering(t *testing.T) {
 		assert.Nil(t, filter.Sel, "Should remain match-all filter")
 	})
 
-	t.Run("Unsupported Match Type", func(t *testing.T) {
+	t.Run("Destination IP Match", func(t *testing.T) {
 		filter := &netlink.U32{
 			FilterAttrs: netlink.FilterAttrs{
 				LinkIndex: 1,
@@ -112,17 +112,38 @@ func TestConfigureU32Matches_PortFiltering(t *testing.T) {
 			ClassId: netlink.MakeHandle(1, 10),
 		}
 
-		// Create IP match (not yet implemented)
-		ipMatch, err := entities.NewIPDestinationMatch("192.168.1.100")
+		ipMatch, err := entities.NewIPDestinationMatch("192.168.1.0/24")
 		require.NoError(t, err)
 		matches := []entities.Match{ipMatch}
 
-		// Should not fail, just skip unsupported matches
 		err = adapter.configureU32Matches(filter, matches)
 		require.NoError(t, err)
 
-		// Should remain nil since IP matching is not implemented yet
-		assert.Nil(t, filter.Sel, "Should skip unsupported IP match")
+		require.NotNil(t, filter.Sel)
+		key := filter.Sel.Keys[0]
+		assert.Equal(t, uint32(0xffffff00), key.Mask, "Should match the /24 prefix")
+		assert.Equal(t, uint32(0xc0a80100), key.Val, "Should match 192.168.1.0")
+		assert.Equal(t, int32(16), key.Off, "Should be at offset 16 (destination address)")
+	})
+
+	t.Run("Unsupported Match Type", func(t *testing.T) {
+		filter := &netlink.U32{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: 1,
+				Parent:    netlink.MakeHandle(1, 0),
+				Priority:  100,
+				Protocol:  0x0800,
+			},
+			ClassId: netlink.MakeHandle(1, 10),
+		}
+
+		// Marks are matched by the fw classifier, not u32
+		matches := []entities.Match{entities.NewMarkMatch(0x10)}
+
+		err := adapter.configureU32Matches(filter, matches)
+		require.NoError(t, err)
+
+		assert.Nil(t, filter.Sel, "Should skip unsupported mark match")
 	})
 
 	t.Run("Multiple Port Matches", func(t *testing.T) {
@@ -136,9 +157,7 @@ func TestConfigureU32Matches_PortFiltering(t *testing.T) {
 			ClassId: netlink.MakeHandle(1, 10),
 		}
 
-		// Create both source and destination port matches
-		// Note: In real U32 filters, only one can be configured per filter
-		// The last one will overwrite the previous
+		// Create both source and destination port matches; both must hold
 		srcMatch := entities.NewPortSourceMatch(8080)
 		dstMatch := entities.NewPortDestinationMatch(5201)
 		matches := []entities.Match{srcMatch, dstMatch}
@@ -147,10 +166,10 @@ func TestConfigureU32Matches_PortFiltering(t *testing.T) {
 		require.NoError(t, err)
 
 		require.NotNil(t, filter.Sel)
-		// Last match (destination) should win
-		key := filter.Sel.Keys[0]
-		assert.Equal(t, uint32(5201), key.Val, "Destination port should be configured")
-		assert.Equal(t, int32(22), key.Off, "Should be destination port offset")
+		require.Equal(t, uint8(2), filter.Sel.Nkeys)
+		assert.Equal(t, uint32(8080<<16), filter.Sel.Keys[0].Val, "Source port should be configured")
+		assert.Equal(t, uint32(5201), filter.Sel.Keys[1].Val, "Destination port should be configured")
+		assert.Equal(t, uint32(netlink.TC_U32_TERMINAL), uint32(filter.Sel.Flags))
 	})
 }
 
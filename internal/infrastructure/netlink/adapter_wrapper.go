@@ -35,6 +35,11 @@ func (a *AdapterWrapper) AddClass(ctx context.Context, class interface{}) error
 	return a.adapter.AddClass(ctx, class)
 }
 
+// ChangeClass changes a class in place from domain entity
+func (a *AdapterWrapper) ChangeClass(ctx context.Context, class interface{}) error {
+	return a.adapter.ChangeClass(ctx, class)
+}
+
 // AddFilter adds a filter from domain entity
 func (a *AdapterWrapper) AddFilter(ctx context.Context, filter *entities.Filter) error {
 	// Delegate directly to the adapter
@@ -85,3 +90,68 @@ func (a *AdapterWrapper) GetDetailedClassStats(device tc.DeviceName, handle tc.H
 func (a *AdapterWrapper) GetLinkStats(device tc.DeviceName) types.Result[LinkStats] {
 	return a.adapter.GetLinkStats(device)
 }
+
+// WatchQdiscDeletions reports qdiscs deleted from the device until the context is cancelled
+func (a *AdapterWrapper) WatchQdiscDeletions(ctx context.Context, device tc.DeviceName) (<-chan QdiscDeletion, error) {
+	return a.adapter.WatchQdiscDeletions(ctx, device)
+}
+
+// WatchLinks reports changes of the device's link state until the context is cancelled
+func (a *AdapterWrapper) WatchLinks(ctx context.Context, device tc.DeviceName) (<-chan LinkEvent, error) {
+	return a.adapter.WatchLinks(ctx, device)
+}
+
+// GetLinkInfo describes the device's link
+func (a *AdapterWrapper) GetLinkInfo(device tc.DeviceName) types.Result[LinkInfo] {
+	return a.adapter.GetLinkInfo(device)
+}
+
+// GetBridgePorts returns the devices enslaved to the bridge
+func (a *AdapterWrapper) GetBridgePorts(bridge tc.DeviceName) types.Result[[]tc.DeviceName] {
+	return a.adapter.GetBridgePorts(bridge)
+}
+
+// AddIngressRedirect redirects traffic received on the device to the IFB device
+func (a *AdapterWrapper) AddIngressRedirect(ctx context.Context, device, ifb tc.DeviceName) error {
+	return a.adapter.AddIngressRedirect(ctx, device, ifb)
+}
+
+// DeleteIngressRedirect removes the ingress redirect and the IFB device
+func (a *AdapterWrapper) DeleteIngressRedirect(device, ifb tc.DeviceName) types.Result[Unit] {
+	return a.adapter.DeleteIngressRedirect(device, ifb)
+}
+
+// EnsureClsactQdisc adds the clsact qdisc to the device unless it has one
+func (a *AdapterWrapper) EnsureClsactQdisc(ctx context.Context, device tc.DeviceName, hook ClsactHook) (bool, error) {
+	return a.adapter.EnsureClsactQdisc(ctx, device, hook)
+}
+
+// DeleteClsactQdisc deletes the device's clsact qdisc unless filters remain on its hooks
+func (a *AdapterWrapper) DeleteClsactQdisc(device tc.DeviceName) types.Result[Unit] {
+	return a.adapter.DeleteClsactQdisc(device)
+}
+
+// AttachBPFClassifier attaches a BPF program to a clsact hook of the device
+func (a *AdapterWrapper) AttachBPFClassifier(ctx context.Context, device tc.DeviceName, classifier BPFClassifier) error {
+	return a.adapter.AttachBPFClassifier(ctx, device, classifier)
+}
+
+// DetachBPFClassifier removes a BPF program from a clsact hook of the device
+func (a *AdapterWrapper) DetachBPFClassifier(device tc.DeviceName, hook ClsactHook, priority uint16) types.Result[Unit] {
+	return a.adapter.DetachBPFClassifier(device, hook, priority)
+}
+
+// UpdateBPFMap sets an entry of a pinned BPF map
+func (a *AdapterWrapper) UpdateBPFMap(path string, key, value []byte) error {
+	return a.adapter.UpdateBPFMap(path, key, value)
+}
+
+// DeleteBPFMapEntry removes an entry from a pinned BPF map
+func (a *AdapterWrapper) DeleteBPFMapEntry(path string, key []byte) types.Result[Unit] {
+	return a.adapter.DeleteBPFMapEntry(path, key)
+}
+
+// CheckPrivileges checks that changes to the device would not be refused for lack of privilege
+func (a *AdapterWrapper) CheckPrivileges(ctx context.Context) error {
+	return a.adapter.CheckPrivileges(ctx)
+}
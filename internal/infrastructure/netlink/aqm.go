@@ -0,0 +1,351 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"fmt"
+	"math"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+// RED, GRED and CoDel option attributes from linux/pkt_sched.h
+const (
+	tcaRedParms = 1
+	tcaRedStab  = 2
+	tcaRedMaxP  = 3
+
+	tcaGredParms = 1
+	tcaGredStab  = 2
+	tcaGredDPs   = 3
+	tcaGredMaxP  = 4
+
+	tcaCodelTarget      = 1
+	tcaCodelLimit       = 2
+	tcaCodelInterval    = 3
+	tcaCodelECN         = 4
+	tcaCodelCEThreshold = 5
+)
+
+// RED flags from linux/pkt_sched.h
+const (
+	tcRedECN      = 1
+	tcRedHardDrop = 2
+	tcRedAdaptive = 4
+)
+
+// redStabSize is the size of the kernel's idle damping table
+const redStabSize = 256
+
+// redQdisc is a RED qdisc; the netlink library has no RED support, so it is
+// serialised by addREDQdisc
+type redQdisc struct {
+	netlink.QdiscAttrs
+	Params entities.REDParameters
+}
+
+// Attrs returns the qdisc attributes
+func (q *redQdisc) Attrs() *netlink.QdiscAttrs {
+	return &q.QdiscAttrs
+}
+
+// Type returns the qdisc kind
+func (q *redQdisc) Type() string {
+	return "red"
+}
+
+// gredQdisc is a GRED qdisc, serialised by addGREDQdisc
+type gredQdisc struct {
+	netlink.QdiscAttrs
+	Params entities.GREDParameters
+}
+
+// Attrs returns the qdisc attributes
+func (q *gredQdisc) Attrs() *netlink.QdiscAttrs {
+	return &q.QdiscAttrs
+}
+
+// Type returns the qdisc kind
+func (q *gredQdisc) Type() string {
+	return "gred"
+}
+
+// codelQdisc is a CoDel qdisc, serialised by addCODELQdisc
+type codelQdisc struct {
+	netlink.QdiscAttrs
+	Limit       uint32 // packets
+	Target      uint32 // microseconds
+	Interval    uint32 // microseconds
+	CEThreshold uint32 // microseconds, zero disables CE marking
+	ECN         bool
+}
+
+// Attrs returns the qdisc attributes
+func (q *codelQdisc) Attrs() *netlink.QdiscAttrs {
+	return &q.QdiscAttrs
+}
+
+// Type returns the qdisc kind
+func (q *codelQdisc) Type() string {
+	return "codel"
+}
+
+// newREDQdisc builds a RED qdisc from the entity parameters
+func newREDQdisc(attrs netlink.QdiscAttrs, qdiscEntity *entities.Qdisc) *redQdisc {
+	qdisc := &redQdisc{QdiscAttrs: attrs}
+	if value, ok := qdiscEntity.GetParameter("red"); ok {
+		qdisc.Params, _ = value.(entities.REDParameters)
+	}
+	return qdisc
+}
+
+// newGREDQdisc builds a GRED qdisc from the entity parameters
+func newGREDQdisc(attrs netlink.QdiscAttrs, qdiscEntity *entities.Qdisc) *gredQdisc {
+	qdisc := &gredQdisc{QdiscAttrs: attrs}
+	if value, ok := qdiscEntity.GetParameter("gred"); ok {
+		qdisc.Params, _ = value.(entities.GREDParameters)
+	}
+	return qdisc
+}
+
+// newCODELQdisc builds a CoDel qdisc from the entity parameters
+func newCODELQdisc(attrs netlink.QdiscAttrs, qdiscEntity *entities.Qdisc) *codelQdisc {
+	return &codelQdisc{
+		QdiscAttrs:  attrs,
+		Limit:       qdiscParameter(qdiscEntity, "limit", 1000),
+		Target:      qdiscParameter(qdiscEntity, "target", 5000),
+		Interval:    qdiscParameter(qdiscEntity, "interval", 100000),
+		CEThreshold: qdiscParameter(qdiscEntity, "ce_threshold", 0),
+		ECN:         qdiscParameter(qdiscEntity, "ecn", 0) != 0,
+	}
+}
+
+// redScaling holds the fixed-point scaling the kernel uses for a RED queue, derived from
+// the thresholds the way tc(8) does
+type redScaling struct {
+	Wlog     uint8  // log2 of the inverse weight of the average queue size
+	Plog     uint8  // log2 of the threshold span divided by the probability
+	ScellLog uint8  // log2 of the idle time cell size in microseconds
+	MaxP     uint32 // probability as a 32-bit fixed-point fraction
+	Stab     []byte // idle damping table, indexed by idle time cell
+}
+
+// evalREDScaling derives the kernel's scaling parameters from RED thresholds
+func evalREDScaling(t entities.REDThresholds) (redScaling, error) {
+	if t.Avpkt == 0 || t.Max <= t.Min || t.Bandwidth.BitsPerSecond() == 0 {
+		return redScaling{}, fmt.Errorf("invalid RED thresholds")
+	}
+	burst := float64(t.EffectiveBurst())
+
+	// The weight is chosen so that a burst of average sized packets arriving at an empty
+	// queue does not push the average past the min threshold
+	wlog := -1
+	a := burst + 1 - float64(t.Min)/float64(t.Avpkt)
+	if a >= 1 {
+		w := 0.5
+		for log := 1; log < 32; log++ {
+			if a <= (1-math.Pow(1-w, burst))/w {
+				wlog = log
+				break
+			}
+			w /= 2
+		}
+	}
+	if wlog < 0 || bitLength(t.Min)+wlog >= 32 {
+		return redScaling{}, fmt.Errorf("burst %d does not fit min threshold %d", t.EffectiveBurst(), t.Min)
+	}
+
+	plog := -1
+	p := t.Probability / float64(t.Max-t.Min)
+	for log := 0; log < 32; log++ {
+		if p > 1 {
+			plog = log
+			break
+		}
+		p *= 2
+	}
+	if plog < 0 {
+		return redScaling{}, fmt.Errorf("probability %g is too small", t.Probability)
+	}
+
+	// While the queue is idle the average decays as if packets of avpkt bytes were sent
+	// at the link rate; the kernel measures idle time in microseconds
+	xmitTime := float64(t.Avpkt) * 8 * 1e6 / float64(t.Bandwidth.BitsPerSecond())
+	lw := -math.Log(1-1/float64(uint64(1)<<wlog)) / xmitTime
+	maxTime := 31 / lw
+	scellLog := -1
+	for log := 0; log < 32; log++ {
+		if maxTime/float64(uint64(1)<<log) < 512 {
+			scellLog = log
+			break
+		}
+	}
+	if scellLog < 0 {
+		return redScaling{}, fmt.Errorf("bandwidth %s is too low for average packet size %d", t.Bandwidth, t.Avpkt)
+	}
+
+	stab := make([]byte, redStabSize)
+	for i := 1; i < redStabSize-1; i++ {
+		stab[i] = byte(math.Min(float64(uint64(i)<<scellLog)*lw, 31))
+	}
+	stab[redStabSize-1] = 31
+
+	return redScaling{
+		Wlog:     uint8(wlog),     // #nosec G115 - below 32
+		Plog:     uint8(plog),     // #nosec G115 - below 32
+		ScellLog: uint8(scellLog), // #nosec G115 - below 32
+		MaxP:     uint32(math.Min(t.Probability*(1<<32), math.MaxUint32)),
+		Stab:     stab,
+	}, nil
+}
+
+// bitLength returns the number of bits needed to represent the value
+func bitLength(value uint32) int {
+	length := 0
+	for ; value != 0; value >>= 1 {
+		length++
+	}
+	return length
+}
+
+// redFlags encodes the RED flags
+func redFlags(ecn, hardDrop, adaptive bool) uint8 {
+	var flags uint8
+	if ecn {
+		flags |= tcRedECN
+	}
+	if hardDrop {
+		flags |= tcRedHardDrop
+	}
+	if adaptive {
+		flags |= tcRedAdaptive
+	}
+	return flags
+}
+
+// addREDQdisc sends an RTM_NEWQDISC request for a RED qdisc
+func addREDQdisc(qdisc *redQdisc) error {
+	scaling, err := evalREDScaling(qdisc.Params.REDThresholds)
+	if err != nil {
+		return err
+	}
+
+	// struct tc_red_qopt
+	parms := make([]byte, 16)
+	native := nl.NativeEndian()
+	native.PutUint32(parms[0:], qdisc.Params.Limit)
+	native.PutUint32(parms[4:], qdisc.Params.Min)
+	native.PutUint32(parms[8:], qdisc.Params.Max)
+	parms[12] = scaling.Wlog
+	parms[13] = scaling.Plog
+	parms[14] = scaling.ScellLog
+	parms[15] = redFlags(qdisc.Params.ECN, qdisc.Params.HardDrop, qdisc.Params.Adaptive)
+
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	options.AddRtAttr(tcaRedParms, parms)
+	options.AddRtAttr(tcaRedStab, scaling.Stab)
+	options.AddRtAttr(tcaRedMaxP, nl.Uint32Attr(scaling.MaxP))
+
+	return executeQdiscRequest(qdisc, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, options)
+}
+
+// addGREDQdisc creates a GRED qdisc and then configures each virtual queue; the kernel
+// only accepts virtual queues once the qdisc exists
+func addGREDQdisc(qdisc *gredQdisc) error {
+	params := qdisc.Params
+
+	// struct tc_gred_sopt
+	sopt := make([]byte, 12)
+	native := nl.NativeEndian()
+	native.PutUint32(sopt[0:], params.Queues)
+	native.PutUint32(sopt[4:], params.Default)
+	if params.GRIO {
+		sopt[8] = 1
+	}
+	sopt[9] = redFlags(params.ECN, params.HardDrop, false)
+
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	options.AddRtAttr(tcaGredDPs, sopt)
+	if err := executeQdiscRequest(qdisc, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, options); err != nil {
+		return err
+	}
+
+	for _, vq := range params.VirtualQueues {
+		if err := changeGREDVirtualQueue(qdisc, vq); err != nil {
+			// Do not leave a qdisc behind that only passes traffic through
+			if delErr := netlink.QdiscDel(qdisc); delErr != nil {
+				return fmt.Errorf("%w (removing the qdisc also failed: %v)", err, delErr)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// changeGREDVirtualQueue configures one virtual queue of an existing GRED qdisc
+func changeGREDVirtualQueue(qdisc *gredQdisc, vq entities.GREDVirtualQueue) error {
+	scaling, err := evalREDScaling(vq.REDThresholds)
+	if err != nil {
+		return fmt.Errorf("virtual queue %d: %w", vq.DP, err)
+	}
+
+	// struct tc_gred_qopt; the counters that follow the thresholds are output only
+	qopt := make([]byte, 52)
+	native := nl.NativeEndian()
+	native.PutUint32(qopt[0:], vq.Limit)
+	native.PutUint32(qopt[4:], vq.Min)
+	native.PutUint32(qopt[8:], vq.Max)
+	native.PutUint32(qopt[12:], vq.DP)
+	qopt[40] = scaling.Wlog
+	qopt[41] = scaling.Plog
+	qopt[42] = scaling.ScellLog
+	qopt[43] = vq.Priority
+
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	options.AddRtAttr(tcaGredParms, qopt)
+	options.AddRtAttr(tcaGredStab, scaling.Stab)
+	options.AddRtAttr(tcaGredMaxP, nl.Uint32Attr(scaling.MaxP))
+
+	if err := executeQdiscRequest(qdisc, 0, options); err != nil {
+		return fmt.Errorf("failed to configure virtual queue %d: %w", vq.DP, err)
+	}
+	return nil
+}
+
+// addCODELQdisc sends an RTM_NEWQDISC request for a CoDel qdisc
+func addCODELQdisc(qdisc *codelQdisc) error {
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	options.AddRtAttr(tcaCodelLimit, nl.Uint32Attr(qdisc.Limit))
+	options.AddRtAttr(tcaCodelTarget, nl.Uint32Attr(qdisc.Target))
+	options.AddRtAttr(tcaCodelInterval, nl.Uint32Attr(qdisc.Interval))
+	options.AddRtAttr(tcaCodelECN, nl.Uint32Attr(boolAttr(qdisc.ECN)))
+	if qdisc.CEThreshold > 0 {
+		options.AddRtAttr(tcaCodelCEThreshold, nl.Uint32Attr(qdisc.CEThreshold))
+	}
+
+	return executeQdiscRequest(qdisc, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, options)
+}
+
+// executeQdiscRequest sends an RTM_NEWQDISC request with the given options; without
+// NLM_F_CREATE it changes an existing qdisc
+func executeQdiscRequest(qdisc netlink.Qdisc, flags int, options *nl.RtAttr) error {
+	attrs := qdisc.Attrs()
+	req := nl.NewNetlinkRequest(syscall.RTM_NEWQDISC, flags|syscall.NLM_F_ACK)
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(attrs.LinkIndex), // #nosec G115 - kernel interface indexes fit in int32
+		Handle:  attrs.Handle,
+		Parent:  attrs.Parent,
+	})
+	req.AddData(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated(qdisc.Type())))
+	req.AddData(options)
+
+	_, err := req.Execute(syscall.NETLINK_ROUTE, 0)
+	return err
+}
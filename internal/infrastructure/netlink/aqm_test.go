@@ -0,0 +1,56 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestEvalREDScaling(t *testing.T) {
+	// tc qdisc add ... red limit 400000 min 30000 max 90000 avpkt 1000 burst 55
+	//   bandwidth 10mbit probability 0.02
+	thresholds := entities.REDThresholds{
+		Limit:       400000,
+		Min:         30000,
+		Max:         90000,
+		Avpkt:       1000,
+		Burst:       55,
+		Bandwidth:   tc.MustParseBandwidth("10mbit"),
+		Probability: 0.02,
+	}
+
+	scaling, err := evalREDScaling(thresholds)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(5), scaling.Wlog)
+	assert.Equal(t, uint8(22), scaling.Plog)
+	assert.Equal(t, uint32(85899345), scaling.MaxP) // 0.02 * 2^32
+
+	require.Len(t, scaling.Stab, redStabSize)
+	assert.Equal(t, byte(0), scaling.Stab[0])
+	assert.Equal(t, byte(31), scaling.Stab[redStabSize-1])
+	for i := 1; i < redStabSize; i++ {
+		assert.GreaterOrEqual(t, scaling.Stab[i], scaling.Stab[i-1], "idle damping must not decrease")
+	}
+
+	t.Run("rejects a burst below the min threshold", func(t *testing.T) {
+		small := thresholds
+		small.Burst = 10
+		_, err := evalREDScaling(small)
+		assert.Error(t, err)
+	})
+
+	t.Run("probability 1 saturates", func(t *testing.T) {
+		certain := thresholds
+		certain.Probability = 1
+		scaling, err := evalREDScaling(certain)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(0xffffffff), scaling.MaxP)
+	})
+}
@@ -0,0 +1,418 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"unsafe"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/types"
+)
+
+// bpfVerifierLogSize bounds the verifier log reported when a program is rejected
+const bpfVerifierLogSize = 1 << 20
+
+// bpfProgLoadAttr is the prefix of union bpf_attr used by BPF_PROG_LOAD
+type bpfProgLoadAttr struct {
+	progType    uint32
+	insnCnt     uint32
+	insns       uint64
+	license     uint64
+	logLevel    uint32
+	logSize     uint32
+	logBuf      uint64
+	kernVersion uint32
+	progFlags   uint32
+	progName    [16]byte
+}
+
+// bpfMapCreateAttr is the prefix of union bpf_attr used by BPF_MAP_CREATE
+type bpfMapCreateAttr struct {
+	mapType    uint32
+	keySize    uint32
+	valueSize  uint32
+	maxEntries uint32
+	mapFlags   uint32
+	innerMapFd uint32
+	numaNode   uint32
+	mapName    [16]byte
+}
+
+// bpfObjAttr is the part of union bpf_attr used by BPF_OBJ_PIN and BPF_OBJ_GET
+type bpfObjAttr struct {
+	pathname  uint64
+	bpfFd     uint32
+	fileFlags uint32
+}
+
+// bpfMapElemAttr is the part of union bpf_attr used by BPF_MAP_UPDATE_ELEM and
+// BPF_MAP_DELETE_ELEM
+type bpfMapElemAttr struct {
+	mapFd uint32
+	_     uint32
+	key   uint64
+	value uint64
+	flags uint64
+}
+
+// bpfObjInfoAttr is the part of union bpf_attr used by BPF_OBJ_GET_INFO_BY_FD
+type bpfObjInfoAttr struct {
+	bpfFd   uint32
+	infoLen uint32
+	info    uint64
+}
+
+// bpfMapInfo is the prefix of struct bpf_map_info
+type bpfMapInfo struct {
+	mapType    uint32
+	id         uint32
+	keySize    uint32
+	valueSize  uint32
+	maxEntries uint32
+	mapFlags   uint32
+}
+
+// bpfProgInfo is the prefix of struct bpf_prog_info
+type bpfProgInfo struct {
+	progType uint32
+	id       uint32
+}
+
+// bpfSyscall runs a bpf(2) command on the attributes
+func bpfSyscall(cmd int, attr unsafe.Pointer, size uintptr) (int, error) {
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// bpfObjectName returns a program or map name the kernel accepts: at most 15 letters,
+// digits, underscores and dots
+func bpfObjectName(name string) [16]byte {
+	var out [16]byte
+	for i := 0; i < len(name) && i < len(out)-1; i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.') {
+			c = '_'
+		}
+		out[i] = c
+	}
+	return out
+}
+
+// loadBPFProgram loads a classifier program and returns its file descriptor. When the
+// verifier rejects it, the program is loaded again to report the verifier log.
+func loadBPFProgram(name string, insns []bpfInsn, license string) (int, error) {
+	licenseBytes := append([]byte(license), 0)
+	attr := bpfProgLoadAttr{
+		progType: unix.BPF_PROG_TYPE_SCHED_CLS,
+		insnCnt:  uint32(len(insns)),
+		insns:    uint64(uintptr(unsafe.Pointer(&insns[0]))),
+		license:  uint64(uintptr(unsafe.Pointer(&licenseBytes[0]))),
+		progName: bpfObjectName(name),
+	}
+
+	fd, err := bpfSyscall(unix.BPF_PROG_LOAD, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	if err == nil {
+		runtime.KeepAlive(insns)
+		runtime.KeepAlive(licenseBytes)
+		return fd, nil
+	}
+
+	log := make([]byte, bpfVerifierLogSize)
+	attr.logLevel = 1
+	attr.logSize = uint32(len(log))
+	attr.logBuf = uint64(uintptr(unsafe.Pointer(&log[0])))
+	fd, err = bpfSyscall(unix.BPF_PROG_LOAD, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(insns)
+	runtime.KeepAlive(licenseBytes)
+	runtime.KeepAlive(log)
+	if err == nil {
+		return fd, nil
+	}
+	if n := bytes.IndexByte(log, 0); n > 0 {
+		return -1, fmt.Errorf("%w: %s", err, bytes.TrimSpace(log[:n]))
+	}
+	return -1, err
+}
+
+// bpfObjGet opens the BPF object pinned at path
+func bpfObjGet(path string) (int, error) {
+	pathname := append([]byte(path), 0)
+	attr := bpfObjAttr{pathname: uint64(uintptr(unsafe.Pointer(&pathname[0])))}
+	fd, err := bpfSyscall(unix.BPF_OBJ_GET, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(pathname)
+	return fd, err
+}
+
+// bpfObjPin pins the BPF object at path
+func bpfObjPin(fd int, path string) error {
+	pathname := append([]byte(path), 0)
+	attr := bpfObjAttr{pathname: uint64(uintptr(unsafe.Pointer(&pathname[0]))), bpfFd: uint32(fd)}
+	_, err := bpfSyscall(unix.BPF_OBJ_PIN, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(pathname)
+	return err
+}
+
+// bpfObjInfo reads the prefix of the information the kernel has on a BPF object into info
+func bpfObjInfo(fd int, info unsafe.Pointer, size uintptr) error {
+	attr := bpfObjInfoAttr{bpfFd: uint32(fd), infoLen: uint32(size), info: uint64(uintptr(info))}
+	_, err := bpfSyscall(unix.BPF_OBJ_GET_INFO_BY_FD, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	return err
+}
+
+// openPinnedProgram opens a classifier program pinned at path
+func openPinnedProgram(path string) (int, error) {
+	fd, err := bpfObjGet(path)
+	if err != nil {
+		return -1, fmt.Errorf("failed to open pinned program %s: %w", path, err)
+	}
+	var info bpfProgInfo
+	if err := bpfObjInfo(fd, unsafe.Pointer(&info), unsafe.Sizeof(info)); err != nil {
+		_ = unix.Close(fd)
+		return -1, fmt.Errorf("%s is not a pinned BPF program: %w", path, err)
+	}
+	if info.progType != unix.BPF_PROG_TYPE_SCHED_CLS && info.progType != unix.BPF_PROG_TYPE_SCHED_ACT {
+		_ = unix.Close(fd)
+		return -1, fmt.Errorf("%s does not hold a tc classifier program (type %d)", path, info.progType)
+	}
+	return fd, nil
+}
+
+// openPinnedMap opens the map pinned at path and checks the sizes of its keys and, unless
+// valueSize is negative, its values
+func openPinnedMap(path string, keySize, valueSize int) (int, *bpfMapInfo, error) {
+	fd, err := bpfObjGet(path)
+	if err != nil {
+		return -1, nil, fmt.Errorf("failed to open pinned map %s: %w", path, err)
+	}
+	info := &bpfMapInfo{}
+	if err := bpfObjInfo(fd, unsafe.Pointer(info), unsafe.Sizeof(*info)); err != nil {
+		_ = unix.Close(fd)
+		return -1, nil, fmt.Errorf("%s is not a pinned BPF map: %w", path, err)
+	}
+	if keySize >= 0 && int(info.keySize) != keySize {
+		_ = unix.Close(fd)
+		return -1, nil, fmt.Errorf("map %s has %d-byte keys, got %d bytes", path, info.keySize, keySize)
+	}
+	if valueSize >= 0 && int(info.valueSize) != valueSize {
+		_ = unix.Close(fd)
+		return -1, nil, fmt.Errorf("map %s has %d-byte values, got %d bytes", path, info.valueSize, valueSize)
+	}
+	return fd, info, nil
+}
+
+// createBPFMap returns a map for the definition. Under a pin path, a map pinned with its
+// name is shared when it matches the definition; otherwise the map is created and pinned.
+func createBPFMap(spec bpfMapSpec, pinPath string) (int, error) {
+	path := ""
+	if pinPath != "" {
+		path = filepath.Join(pinPath, spec.name)
+		fd, info, err := openPinnedMap(path, int(spec.keySize), int(spec.valueSize))
+		switch {
+		case err == nil && (info.mapType != spec.mapType || info.maxEntries != spec.maxEntries):
+			_ = unix.Close(fd)
+			return -1, fmt.Errorf("map pinned at %s differs from the definition of map %q; remove it to recreate it", path, spec.name)
+		case err == nil:
+			return fd, nil
+		case !errors.Is(err, unix.ENOENT):
+			return -1, err
+		}
+	}
+
+	attr := bpfMapCreateAttr{
+		mapType:    spec.mapType,
+		keySize:    spec.keySize,
+		valueSize:  spec.valueSize,
+		maxEntries: spec.maxEntries,
+		mapFlags:   spec.flags,
+		mapName:    bpfObjectName(spec.name),
+	}
+	fd, err := bpfSyscall(unix.BPF_MAP_CREATE, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	if err != nil {
+		return -1, fmt.Errorf("failed to create map %q: %w", spec.name, err)
+	}
+
+	if path != "" {
+		if err := os.MkdirAll(pinPath, 0o700); err != nil {
+			_ = unix.Close(fd)
+			return -1, fmt.Errorf("failed to create pin directory %s: %w", pinPath, err)
+		}
+		if err := bpfObjPin(fd, path); err != nil {
+			_ = unix.Close(fd)
+			return -1, fmt.Errorf("failed to pin map %q at %s: %w", spec.name, path, err)
+		}
+	}
+	return fd, nil
+}
+
+// loadBPFClassifier returns the file descriptor of the classifier's program: the pinned
+// one, or the one loaded from its object with the maps it references
+func loadBPFClassifier(classifier BPFClassifier) (int, error) {
+	if classifier.Pinned != "" {
+		return openPinnedProgram(classifier.Pinned)
+	}
+
+	spec, err := parseBPFObject(classifier.Object, classifier.Section)
+	if err != nil {
+		return -1, err
+	}
+
+	// The program holds its own references to its maps
+	fds := make(map[string]int, len(spec.maps))
+	defer func() {
+		for _, fd := range fds {
+			_ = unix.Close(fd)
+		}
+	}()
+	for _, m := range spec.maps {
+		fd, err := createBPFMap(m, classifier.PinPath)
+		if err != nil {
+			return -1, err
+		}
+		fds[m.name] = fd
+	}
+
+	insns := append([]bpfInsn(nil), spec.insns...)
+	for index, name := range spec.relocations {
+		insns[index].imm = int32(fds[name])
+	}
+	fd, err := loadBPFProgram(classifier.Name, insns, spec.license)
+	if err != nil {
+		return -1, fmt.Errorf("failed to load section %q: %w", spec.section, err)
+	}
+	return fd, nil
+}
+
+// AttachBPFClassifier loads the classifier's program and attaches it in direct-action mode
+// to its hook of the device's clsact qdisc, which is added if missing. A classifier already
+// attached with the same priority is replaced.
+func (a *RealNetlinkAdapter) AttachBPFClassifier(ctx context.Context, device tc.DeviceName, classifier BPFClassifier) error {
+	link, err := netlink.LinkByName(device.String())
+	if err != nil {
+		return deviceNotFound(device, err)
+	}
+	parent, err := clsactParent(classifier.Hook)
+	if err != nil {
+		return err
+	}
+	if _, err := ensureClsactQdisc(link, classifier.Hook); err != nil {
+		return fmt.Errorf("failed to add clsact qdisc to %s: %w", device, err)
+	}
+
+	fd, err := loadBPFClassifier(classifier)
+	if err != nil {
+		return fmt.Errorf("failed to load BPF classifier %s: %w", classifier.Name, err)
+	}
+	// The filter holds its own reference to the program
+	defer func() { _ = unix.Close(fd) }()
+
+	filter := &netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    parent,
+			Priority:  classifier.Priority,
+			Handle:    bpfClassifierHandle,
+			Protocol:  unix.ETH_P_ALL,
+		},
+		Fd:           fd,
+		Name:         classifier.Name,
+		DirectAction: true,
+	}
+	if err := netlink.FilterReplace(filter); err != nil {
+		return fmt.Errorf("failed to attach BPF classifier %s to %s %s: %w", classifier.Name, device, classifier.Hook, err)
+	}
+
+	a.logger.Info("Attached BPF classifier",
+		logging.String("device", device.String()),
+		logging.String("classifier", classifier.Name),
+		logging.String("hook", string(classifier.Hook)),
+	)
+	return nil
+}
+
+// DetachBPFClassifier removes the BPF classifier with the priority from the device's hook.
+// The clsact qdisc is left in place; see DeleteClsactQdisc.
+func (a *RealNetlinkAdapter) DetachBPFClassifier(device tc.DeviceName, hook ClsactHook, priority uint16) types.Result[Unit] {
+	link, err := netlink.LinkByName(device.String())
+	if err != nil {
+		return types.Failure[Unit](deviceNotFound(device, err))
+	}
+	parent, err := clsactParent(hook)
+	if err != nil {
+		return types.Failure[Unit](err)
+	}
+
+	filter := &netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    parent,
+			Priority:  priority,
+			Handle:    bpfClassifierHandle,
+			Protocol:  unix.ETH_P_ALL,
+		},
+	}
+	if err := netlink.FilterDel(filter); err != nil && !isNotFound(err) {
+		return types.Failure[Unit](fmt.Errorf("failed to detach BPF classifier from %s %s: %w", device, hook, err))
+	}
+	return types.Success(Unit{})
+}
+
+// UpdateBPFMap sets the value of the key in the map pinned at path
+func (a *RealNetlinkAdapter) UpdateBPFMap(path string, key, value []byte) error {
+	if len(key) == 0 || len(value) == 0 {
+		return fmt.Errorf("map entries need a key and a value")
+	}
+	fd, _, err := openPinnedMap(path, len(key), len(value))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = unix.Close(fd) }()
+
+	attr := bpfMapElemAttr{
+		mapFd: uint32(fd),
+		key:   uint64(uintptr(unsafe.Pointer(&key[0]))),
+		value: uint64(uintptr(unsafe.Pointer(&value[0]))),
+	}
+	_, err = bpfSyscall(unix.BPF_MAP_UPDATE_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(key)
+	runtime.KeepAlive(value)
+	if err != nil {
+		return fmt.Errorf("failed to update map %s: %w", path, err)
+	}
+	return nil
+}
+
+// DeleteBPFMapEntry removes the key from the map pinned at path. A missing key is not an
+// error.
+func (a *RealNetlinkAdapter) DeleteBPFMapEntry(path string, key []byte) types.Result[Unit] {
+	if len(key) == 0 {
+		return types.Failure[Unit](fmt.Errorf("map entries need a key"))
+	}
+	fd, _, err := openPinnedMap(path, len(key), -1)
+	if err != nil {
+		return types.Failure[Unit](err)
+	}
+	defer func() { _ = unix.Close(fd) }()
+
+	attr := bpfMapElemAttr{mapFd: uint32(fd), key: uint64(uintptr(unsafe.Pointer(&key[0])))}
+	_, err = bpfSyscall(unix.BPF_MAP_DELETE_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(key)
+	if err != nil && !errors.Is(err, unix.ENOENT) {
+		return types.Failure[Unit](fmt.Errorf("failed to delete from map %s: %w", path, err))
+	}
+	return types.Success(Unit{})
+}
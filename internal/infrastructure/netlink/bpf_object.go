@@ -0,0 +1,555 @@
+package netlink
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultBPFPinPath is where tc pins the global maps of BPF objects
+const DefaultBPFPinPath = "/sys/fs/bpf/tc/globals"
+
+// BPFClassifier is a BPF program attached in direct-action mode to a clsact hook, so that
+// its return code is the verdict on the packet. The program is either loaded from an ELF
+// object file or already pinned in the BPF file system, such as a CO-RE program loaded
+// with bpftool.
+type BPFClassifier struct {
+	Name     string // Filter name, as shown by tc
+	Hook     ClsactHook
+	Priority uint16
+	Object   string // ELF object file compiled for the tc hook
+	Section  string // Program section of the object; the first classifier section when empty
+	Pinned   string // Path of a pinned program, used instead of an object
+	// PinPath is the BPF file system directory the maps of the object are pinned in, by
+	// name. Maps already pinned there are shared instead of created. Maps are not pinned
+	// when empty.
+	PinPath string
+}
+
+// bpfInsn is a struct bpf_insn
+type bpfInsn struct {
+	code uint8
+	regs uint8 // Destination register in the low nibble, source in the high nibble
+	off  int16
+	imm  int32
+}
+
+// BPF instruction codes and pseudo registers used by relocations
+const (
+	bpfLdImm64     = 0x18 // ld_imm64, the only instruction referencing a map
+	bpfPseudoMapFD = 1    // Source register marking the immediate of ld_imm64 as a map fd
+)
+
+// bpfMapSpec is the definition of a map in a BPF object
+type bpfMapSpec struct {
+	name       string
+	mapType    uint32
+	keySize    uint32
+	valueSize  uint32
+	maxEntries uint32
+	flags      uint32
+}
+
+// bpfObjectSpec is a program read from a BPF object, with the maps it references
+type bpfObjectSpec struct {
+	section     string
+	insns       []bpfInsn
+	license     string
+	maps        []bpfMapSpec
+	relocations map[int]string // Instruction index -> name of the map it loads
+}
+
+// isClassifierSection reports whether an object section holds a tc program, named the way
+// libbpf and tc name them
+func isClassifierSection(name string) bool {
+	if name == "tc" || name == "classifier" {
+		return true
+	}
+	for _, prefix := range []string{"tc/", "tcx/", "classifier/"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBPFObject reads the program in the section of the ELF object at path, the first
+// classifier section when section is empty. Maps are defined in the legacy "maps" section or
+// in the BTF-described ".maps" section. Objects needing CO-RE relocations, calls to other
+// functions or global variables are rejected: they need a full loader such as bpftool.
+func parseBPFObject(path, section string) (*bpfObjectSpec, error) {
+	file, err := elf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open BPF object %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	if file.Machine != elf.EM_BPF || file.Class != elf.ELFCLASS64 {
+		return nil, fmt.Errorf("%s is not a BPF object", path)
+	}
+	if file.ByteOrder != binary.LittleEndian {
+		return nil, fmt.Errorf("BPF object %s is big-endian; only little-endian (bpfel) objects are supported", path)
+	}
+
+	program := findProgramSection(file, section)
+	if program == nil {
+		if section == "" {
+			return nil, fmt.Errorf("BPF object %s has no classifier section", path)
+		}
+		return nil, fmt.Errorf("BPF object %s has no program section %q", path, section)
+	}
+	code, err := program.Data()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read section %q of %s: %w", program.Name, path, err)
+	}
+	if len(code) == 0 || len(code)%8 != 0 {
+		return nil, fmt.Errorf("section %q of %s does not hold BPF instructions", program.Name, path)
+	}
+
+	spec := &bpfObjectSpec{section: program.Name, relocations: make(map[int]string)}
+	for i := 0; i < len(code); i += 8 {
+		spec.insns = append(spec.insns, bpfInsn{
+			code: code[i],
+			regs: code[i+1],
+			off:  int16(binary.LittleEndian.Uint16(code[i+2:])),
+			imm:  int32(binary.LittleEndian.Uint32(code[i+4:])),
+		})
+	}
+
+	if license := file.Section("license"); license != nil {
+		data, err := license.Data()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the license of %s: %w", path, err)
+		}
+		spec.license, _, _ = strings.Cut(string(data), "\x00")
+	}
+
+	var btf *btfSpec
+	if section := file.Section(".BTF"); section != nil {
+		data, err := section.Data()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the BTF of %s: %w", path, err)
+		}
+		if btf, err = parseBTF(data); err != nil {
+			return nil, fmt.Errorf("invalid BTF in %s: %w", path, err)
+		}
+	}
+	if err := checkNoCORE(file, btf, program.Name); err != nil {
+		return nil, fmt.Errorf("BPF object %s: %w", path, err)
+	}
+
+	maps, err := objectMaps(file, btf)
+	if err != nil {
+		return nil, fmt.Errorf("BPF object %s: %w", path, err)
+	}
+	if err := spec.relocate(file, program, maps); err != nil {
+		return nil, fmt.Errorf("BPF object %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// findProgramSection returns the executable section of the object with the name, or the
+// first classifier section when name is empty
+func findProgramSection(file *elf.File, name string) *elf.Section {
+	for _, section := range file.Sections {
+		if section.Type != elf.SHT_PROGBITS || section.Flags&elf.SHF_EXECINSTR == 0 {
+			continue
+		}
+		if section.Name == name || (name == "" && isClassifierSection(section.Name)) {
+			return section
+		}
+	}
+	return nil
+}
+
+// relocate points the instructions loading maps at the maps they name, and records the
+// maps the program references
+func (spec *bpfObjectSpec) relocate(file *elf.File, program *elf.Section, maps map[string]bpfMapSpec) error {
+	relocations := file.Section(".rel" + program.Name)
+	if relocations == nil {
+		return nil
+	}
+	if relocations.Type != elf.SHT_REL {
+		return fmt.Errorf("unsupported relocation section %s", relocations.Name)
+	}
+	data, err := relocations.Data()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", relocations.Name, err)
+	}
+	symbols, err := file.Symbols()
+	if err != nil {
+		return fmt.Errorf("failed to read symbols: %w", err)
+	}
+
+	referenced := make(map[string]bool)
+	for offset := 0; offset+16 <= len(data); offset += 16 {
+		insn := int(binary.LittleEndian.Uint64(data[offset:]) / 8)
+		info := binary.LittleEndian.Uint64(data[offset+8:])
+		// Symbols does not return the null symbol at index 0
+		index := int(elf.R_SYM64(info)) - 1
+		if index < 0 || index >= len(symbols) || insn < 0 || insn >= len(spec.insns) {
+			return fmt.Errorf("invalid relocation at offset %d of %s", offset, relocations.Name)
+		}
+		symbol := symbols[index]
+
+		target := ""
+		if int(symbol.Section) < len(file.Sections) {
+			target = file.Sections[symbol.Section].Name
+		}
+		switch {
+		case target == "maps" || target == ".maps":
+			if _, ok := maps[symbol.Name]; !ok {
+				return fmt.Errorf("program references undefined map %q", symbol.Name)
+			}
+			if spec.insns[insn].code != bpfLdImm64 {
+				return fmt.Errorf("map %q is referenced by instruction %d, which is not a 64-bit load", symbol.Name, insn)
+			}
+			spec.insns[insn].regs = spec.insns[insn].regs&0x0f | bpfPseudoMapFD<<4
+			spec.relocations[insn] = symbol.Name
+			if !referenced[symbol.Name] {
+				referenced[symbol.Name] = true
+				spec.maps = append(spec.maps, maps[symbol.Name])
+			}
+		case int(symbol.Section) < len(file.Sections) && file.Sections[symbol.Section].Flags&elf.SHF_EXECINSTR != 0:
+			return fmt.Errorf("program calls function %q; calls to other BPF functions are not supported, inline them", symbol.Name)
+		default:
+			return fmt.Errorf("program references %q in section %q; global variables are not supported", symbol.Name, target)
+		}
+	}
+	return nil
+}
+
+// objectMaps returns the maps defined by the object, by name
+func objectMaps(file *elf.File, btf *btfSpec) (map[string]bpfMapSpec, error) {
+	maps := make(map[string]bpfMapSpec)
+
+	if section := file.Section("maps"); section != nil {
+		data, err := section.Data()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read maps: %w", err)
+		}
+		symbols, err := file.Symbols()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read symbols: %w", err)
+		}
+		for _, symbol := range symbols {
+			if int(symbol.Section) >= len(file.Sections) || file.Sections[symbol.Section] != section {
+				continue
+			}
+			// struct bpf_map_def and tc's struct bpf_elf_map both start with these fields
+			if symbol.Value+20 > uint64(len(data)) {
+				return nil, fmt.Errorf("map %q is truncated", symbol.Name)
+			}
+			fields := data[symbol.Value:]
+			maps[symbol.Name] = bpfMapSpec{
+				name:       symbol.Name,
+				mapType:    binary.LittleEndian.Uint32(fields[0:]),
+				keySize:    binary.LittleEndian.Uint32(fields[4:]),
+				valueSize:  binary.LittleEndian.Uint32(fields[8:]),
+				maxEntries: binary.LittleEndian.Uint32(fields[12:]),
+				flags:      binary.LittleEndian.Uint32(fields[16:]),
+			}
+		}
+	}
+
+	if file.Section(".maps") != nil {
+		if btf == nil {
+			return nil, fmt.Errorf("maps in section .maps need BTF, which the object lacks")
+		}
+		btfMaps, err := btf.maps()
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range btfMaps {
+			maps[spec.name] = spec
+		}
+	}
+	return maps, nil
+}
+
+// checkNoCORE fails when the program needs CO-RE relocations, which adapt it to the
+// running kernel's types and which this loader does not perform
+func checkNoCORE(file *elf.File, btf *btfSpec, program string) error {
+	section := file.Section(".BTF.ext")
+	if section == nil {
+		return nil
+	}
+	data, err := section.Data()
+	if err != nil {
+		return fmt.Errorf("failed to read .BTF.ext: %w", err)
+	}
+	// struct btf_ext_header; the CO-RE relocation fields follow the line info ones
+	if len(data) < 8 || binary.LittleEndian.Uint16(data) != btfMagic {
+		return fmt.Errorf("invalid .BTF.ext header")
+	}
+	headerLen := binary.LittleEndian.Uint32(data[4:])
+	if headerLen < 32 || len(data) < 32 {
+		return nil
+	}
+	offset := uint64(headerLen) + uint64(binary.LittleEndian.Uint32(data[24:]))
+	length := uint64(binary.LittleEndian.Uint32(data[28:]))
+	if length == 0 {
+		return nil
+	}
+	if offset+length > uint64(len(data)) || length < 4 || btf == nil {
+		return fmt.Errorf("invalid CO-RE relocations")
+	}
+
+	relocations := data[offset : offset+length]
+	recordSize := uint64(binary.LittleEndian.Uint32(relocations))
+	for at := uint64(4); at+8 <= length; {
+		name := btf.str(binary.LittleEndian.Uint32(relocations[at:]))
+		count := uint64(binary.LittleEndian.Uint32(relocations[at+4:]))
+		if name == program && count > 0 {
+			return fmt.Errorf("section %q needs CO-RE relocations; load it with bpftool and attach the pinned program", program)
+		}
+		at += 8 + count*recordSize
+	}
+	return nil
+}
+
+// btfMagic starts the BTF and BTF.ext sections
+const btfMagic = 0xeb9f
+
+// BTF kinds
+const (
+	btfKindInt       = 1
+	btfKindPtr       = 2
+	btfKindArray     = 3
+	btfKindStruct    = 4
+	btfKindUnion     = 5
+	btfKindEnum      = 6
+	btfKindFwd       = 7
+	btfKindTypedef   = 8
+	btfKindVolatile  = 9
+	btfKindConst     = 10
+	btfKindRestrict  = 11
+	btfKindFunc      = 12
+	btfKindFuncProto = 13
+	btfKindVar       = 14
+	btfKindDatasec   = 15
+	btfKindFloat     = 16
+	btfKindDeclTag   = 17
+	btfKindTypeTag   = 18
+	btfKindEnum64    = 19
+)
+
+// btfType is a BTF type, reduced to what map definitions need
+type btfType struct {
+	name    string
+	kind    uint32
+	size    uint32      // Size, or the referenced type ID for pointers, qualifiers and variables
+	elem    uint32      // Element type ID of an array
+	nelems  uint32      // Length of an array
+	members []btfMember // Members of a struct or union, variables of a data section
+}
+
+// btfMember is a member of a struct or union, or a variable of a data section
+type btfMember struct {
+	name   string
+	typ    uint32
+	offset uint32
+}
+
+// btfSpec is the type information of a BPF object
+type btfSpec struct {
+	types   []btfType // Indexed by type ID; 0 is void
+	strings []byte
+}
+
+// parseBTF decodes the types of a .BTF section
+func parseBTF(data []byte) (*btfSpec, error) {
+	// struct btf_header
+	if len(data) < 24 || binary.LittleEndian.Uint16(data) != btfMagic {
+		return nil, fmt.Errorf("invalid header")
+	}
+	headerLen := uint64(binary.LittleEndian.Uint32(data[4:]))
+	typeOff := headerLen + uint64(binary.LittleEndian.Uint32(data[8:]))
+	typeLen := uint64(binary.LittleEndian.Uint32(data[12:]))
+	strOff := headerLen + uint64(binary.LittleEndian.Uint32(data[16:]))
+	strLen := uint64(binary.LittleEndian.Uint32(data[20:]))
+	if typeOff+typeLen > uint64(len(data)) || strOff+strLen > uint64(len(data)) {
+		return nil, fmt.Errorf("sections out of bounds")
+	}
+
+	spec := &btfSpec{types: []btfType{{}}, strings: data[strOff : strOff+strLen]}
+	types := data[typeOff : typeOff+typeLen]
+	for at := 0; at < len(types); {
+		// struct btf_type, followed by data depending on the kind
+		if at+12 > len(types) {
+			return nil, errors.New("truncated type")
+		}
+		info := binary.LittleEndian.Uint32(types[at+4:])
+		typ := btfType{
+			name: spec.str(binary.LittleEndian.Uint32(types[at:])),
+			kind: info >> 24 & 0x1f,
+			size: binary.LittleEndian.Uint32(types[at+8:]),
+		}
+		vlen := int(info & 0xffff)
+		at += 12
+
+		var extra int
+		switch typ.kind {
+		case btfKindInt, btfKindVar, btfKindDeclTag:
+			extra = 4
+		case btfKindPtr, btfKindFwd, btfKindTypedef, btfKindVolatile, btfKindConst, btfKindRestrict,
+			btfKindFunc, btfKindFloat, btfKindTypeTag:
+		case btfKindArray:
+			extra = 12
+		case btfKindStruct, btfKindUnion, btfKindDatasec, btfKindEnum64:
+			extra = 12 * vlen
+		case btfKindEnum, btfKindFuncProto:
+			extra = 8 * vlen
+		default:
+			return nil, fmt.Errorf("unknown kind %d", typ.kind)
+		}
+		if at+extra > len(types) {
+			return nil, errors.New("truncated type")
+		}
+
+		switch typ.kind {
+		case btfKindArray:
+			typ.elem = binary.LittleEndian.Uint32(types[at:])
+			typ.nelems = binary.LittleEndian.Uint32(types[at+8:])
+		case btfKindStruct, btfKindUnion:
+			// struct btf_member
+			for i := 0; i < vlen; i++ {
+				member := types[at+12*i:]
+				typ.members = append(typ.members, btfMember{
+					name:   spec.str(binary.LittleEndian.Uint32(member)),
+					typ:    binary.LittleEndian.Uint32(member[4:]),
+					offset: binary.LittleEndian.Uint32(member[8:]),
+				})
+			}
+		case btfKindDatasec:
+			// struct btf_var_secinfo
+			for i := 0; i < vlen; i++ {
+				variable := types[at+12*i:]
+				typ.members = append(typ.members, btfMember{
+					typ:    binary.LittleEndian.Uint32(variable),
+					offset: binary.LittleEndian.Uint32(variable[4:]),
+				})
+			}
+		}
+		spec.types = append(spec.types, typ)
+		at += extra
+	}
+	return spec, nil
+}
+
+// str returns the string at the offset of the string section
+func (s *btfSpec) str(offset uint32) string {
+	if int(offset) >= len(s.strings) {
+		return ""
+	}
+	end := bytes.IndexByte(s.strings[offset:], 0)
+	if end < 0 {
+		return ""
+	}
+	return string(s.strings[offset : int(offset)+end])
+}
+
+// resolve returns the type with the ID, skipping typedefs and qualifiers
+func (s *btfSpec) resolve(id uint32) (btfType, error) {
+	for range s.types {
+		if int(id) >= len(s.types) {
+			return btfType{}, fmt.Errorf("invalid type ID %d", id)
+		}
+		typ := s.types[id]
+		switch typ.kind {
+		case btfKindTypedef, btfKindVolatile, btfKindConst, btfKindRestrict, btfKindTypeTag:
+			id = typ.size
+		default:
+			return typ, nil
+		}
+	}
+	return btfType{}, fmt.Errorf("type ID %d is a cycle", id)
+}
+
+// sizeOf returns the size in bytes of the type with the ID
+func (s *btfSpec) sizeOf(id uint32) (uint32, error) {
+	typ, err := s.resolve(id)
+	if err != nil {
+		return 0, err
+	}
+	switch typ.kind {
+	case btfKindInt, btfKindStruct, btfKindUnion, btfKindEnum, btfKindEnum64, btfKindFloat, btfKindDatasec:
+		return typ.size, nil
+	case btfKindPtr:
+		return 8, nil
+	case btfKindArray:
+		elem, err := s.sizeOf(typ.elem)
+		return elem * typ.nelems, err
+	default:
+		return 0, fmt.Errorf("type %q of kind %d has no size", typ.name, typ.kind)
+	}
+}
+
+// maps returns the maps defined in the .maps data section. libbpf encodes each numeric
+// attribute as a pointer to an array of that length, and the key and value as pointers to
+// their types.
+func (s *btfSpec) maps() ([]bpfMapSpec, error) {
+	var section *btfType
+	for i := range s.types {
+		if s.types[i].kind == btfKindDatasec && s.types[i].name == ".maps" {
+			section = &s.types[i]
+			break
+		}
+	}
+	if section == nil {
+		return nil, fmt.Errorf("BTF does not describe section .maps")
+	}
+
+	var maps []bpfMapSpec
+	for _, variable := range section.members {
+		if int(variable.typ) >= len(s.types) || s.types[variable.typ].kind != btfKindVar {
+			return nil, fmt.Errorf("invalid variable in section .maps")
+		}
+		spec := bpfMapSpec{name: s.types[variable.typ].name}
+		definition, err := s.resolve(s.types[variable.typ].size)
+		if err != nil || definition.kind != btfKindStruct {
+			return nil, fmt.Errorf("map %q is not defined by a struct", spec.name)
+		}
+
+		for _, member := range definition.members {
+			pointer, err := s.resolve(member.typ)
+			if err != nil || pointer.kind != btfKindPtr {
+				return nil, fmt.Errorf("attribute %q of map %q is not a pointer", member.name, spec.name)
+			}
+			switch member.name {
+			case "type", "max_entries", "map_flags", "key_size", "value_size":
+				array, err := s.resolve(pointer.size)
+				if err != nil || array.kind != btfKindArray {
+					return nil, fmt.Errorf("attribute %q of map %q is not a number", member.name, spec.name)
+				}
+				switch member.name {
+				case "type":
+					spec.mapType = array.nelems
+				case "max_entries":
+					spec.maxEntries = array.nelems
+				case "map_flags":
+					spec.flags = array.nelems
+				case "key_size":
+					spec.keySize = array.nelems
+				case "value_size":
+					spec.valueSize = array.nelems
+				}
+			case "key", "value":
+				size, err := s.sizeOf(pointer.size)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s of map %q: %w", member.name, spec.name, err)
+				}
+				if member.name == "key" {
+					spec.keySize = size
+				} else {
+					spec.valueSize = size
+				}
+			}
+		}
+		maps = append(maps, spec)
+	}
+	return maps, nil
+}
@@ -0,0 +1,259 @@
+package netlink
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSection is a section of a test BPF object
+type testSection struct {
+	name  string
+	typ   elf.SectionType
+	flags elf.SectionFlag
+	data  []byte
+	link  uint32 // Index of the section, counting the null section
+	info  uint32
+}
+
+// testSymbol is a global symbol of a test BPF object
+type testSymbol struct {
+	name    string
+	section uint16 // Index of the section, counting the null section
+	value   uint64
+}
+
+// writeBPFObject writes a relocatable little-endian BPF ELF object with the sections, and
+// a symbol table listing the symbols, and returns its path. The symbol table is the
+// section after the given ones.
+func writeBPFObject(t *testing.T, sections []testSection, symbols []testSymbol) string {
+	t.Helper()
+	le := binary.LittleEndian
+
+	names := []byte{0}
+	addName := func(name string) uint32 {
+		offset := uint32(len(names))
+		names = append(names, name...)
+		names = append(names, 0)
+		return offset
+	}
+
+	symtab := make([]byte, 24) // Null symbol
+	for _, symbol := range symbols {
+		entry := make([]byte, 24)
+		le.PutUint32(entry, addName(symbol.name))
+		entry[4] = byte(elf.STB_GLOBAL)<<4 | byte(elf.STT_OBJECT)
+		le.PutUint16(entry[6:], symbol.section)
+		le.PutUint64(entry[8:], symbol.value)
+		symtab = append(symtab, entry...)
+	}
+	symtabIndex := uint32(len(sections) + 1)
+	sections = append(sections,
+		testSection{name: ".symtab", typ: elf.SHT_SYMTAB, data: symtab, link: symtabIndex + 1, info: 1},
+		testSection{name: ".strtab", typ: elf.SHT_STRTAB},
+	)
+
+	nameOffsets := make([]uint32, len(sections))
+	for i, section := range sections {
+		nameOffsets[i] = addName(section.name)
+	}
+	sections[len(sections)-1].data = names
+
+	var body bytes.Buffer
+	body.Write(make([]byte, 64))
+	offsets := make([]uint64, len(sections))
+	for i, section := range sections {
+		offsets[i] = uint64(body.Len())
+		body.Write(section.data)
+	}
+	for body.Len()%8 != 0 {
+		body.WriteByte(0)
+	}
+	headers := uint64(body.Len())
+
+	body.Write(make([]byte, 64)) // Null section
+	for i, section := range sections {
+		header := make([]byte, 64)
+		le.PutUint32(header, nameOffsets[i])
+		le.PutUint32(header[4:], uint32(section.typ))
+		le.PutUint64(header[8:], uint64(section.flags))
+		le.PutUint64(header[24:], offsets[i])
+		le.PutUint64(header[32:], uint64(len(section.data)))
+		le.PutUint32(header[40:], section.link)
+		le.PutUint32(header[44:], section.info)
+		le.PutUint64(header[48:], 8)
+		if section.typ == elf.SHT_SYMTAB {
+			le.PutUint64(header[56:], 24)
+		}
+		body.Write(header)
+	}
+
+	data := body.Bytes()
+	copy(data, []byte{0x7f, 'E', 'L', 'F', byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), 1})
+	le.PutUint16(data[16:], uint16(elf.ET_REL))
+	le.PutUint16(data[18:], uint16(elf.EM_BPF))
+	le.PutUint32(data[20:], 1)
+	le.PutUint64(data[40:], headers)
+	le.PutUint16(data[52:], 64)
+	le.PutUint16(data[58:], 64)
+	le.PutUint16(data[60:], uint16(len(sections)+1))
+	le.PutUint16(data[62:], uint16(len(sections))) // .strtab also holds the section names
+
+	path := filepath.Join(t.TempDir(), "classifier.o")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// testProgram loads a map into r1 and returns TC_ACT_OK
+func testProgram() []byte {
+	return []byte{
+		0x18, 0x01, 0, 0, 0, 0, 0, 0, // r1 = map
+		0x00, 0x00, 0, 0, 0, 0, 0, 0,
+		0xb7, 0x00, 0, 0, 0, 0, 0, 0, // r0 = 0
+		0x95, 0x00, 0, 0, 0, 0, 0, 0, // exit
+	}
+}
+
+// testRelocation relocates the first instruction against the symbol
+func testRelocation(symbol uint64) []byte {
+	relocation := make([]byte, 16)
+	binary.LittleEndian.PutUint64(relocation[8:], symbol<<32|1) // R_BPF_64_64
+	return relocation
+}
+
+func u32s(values ...uint32) []byte {
+	out := make([]byte, 4*len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint32(out[4*i:], value)
+	}
+	return out
+}
+
+func TestParseBPFObject_LegacyMaps(t *testing.T) {
+	executable := elf.SHF_ALLOC | elf.SHF_EXECINSTR
+	path := writeBPFObject(t, []testSection{
+		{name: ".text", typ: elf.SHT_PROGBITS, flags: executable},
+		{name: "classifier", typ: elf.SHT_PROGBITS, flags: executable, data: testProgram()},
+		{name: ".relclassifier", typ: elf.SHT_REL, data: testRelocation(2), link: 6, info: 2},
+		{name: "maps", typ: elf.SHT_PROGBITS, flags: elf.SHF_ALLOC | elf.SHF_WRITE, data: u32s(2, 4, 4, 1, 0, 1, 4, 8, 64, 0)},
+		{name: "license", typ: elf.SHT_PROGBITS, flags: elf.SHF_ALLOC, data: []byte("GPL\x00")},
+	}, []testSymbol{
+		{name: "stats", section: 4, value: 0},
+		{name: "classes", section: 4, value: 20},
+	})
+
+	spec, err := parseBPFObject(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "classifier", spec.section)
+	assert.Equal(t, "GPL", spec.license)
+	require.Len(t, spec.insns, 4)
+	assert.Equal(t, uint8(bpfPseudoMapFD<<4|1), spec.insns[0].regs)
+	assert.Equal(t, map[int]string{0: "classes"}, spec.relocations)
+	// Only the referenced map is created
+	assert.Equal(t, []bpfMapSpec{{name: "classes", mapType: 1, keySize: 4, valueSize: 8, maxEntries: 64}}, spec.maps)
+
+	_, err = parseBPFObject(path, "tc/egress")
+	assert.ErrorContains(t, err, `no program section "tc/egress"`)
+}
+
+// testBTF encodes BTF describing a hash map "classes" with int keys and values and 64
+// entries, defined the way libbpf's __uint and __type macros define maps
+func testBTF() []byte {
+	table := []byte("\x00int\x00type\x00max_entries\x00key\x00value\x00classes\x00.maps\x00")
+	name := func(s string) uint32 { return uint32(bytes.Index(table, []byte("\x00"+s+"\x00")) + 1) }
+	info := func(kind, vlen uint32) uint32 { return kind<<24 | vlen }
+
+	var types []byte
+	types = append(types, u32s(name("int"), info(btfKindInt, 0), 4, 32)...) // 1: int
+	types = append(types, u32s(0, info(btfKindArray, 0), 0, 1, 1, 1)...)    // 2: int[1]
+	types = append(types, u32s(0, info(btfKindPtr, 0), 2)...)               // 3: int (*)[1]
+	types = append(types, u32s(0, info(btfKindArray, 0), 0, 1, 1, 64)...)   // 4: int[64]
+	types = append(types, u32s(0, info(btfKindPtr, 0), 4)...)               // 5: int (*)[64]
+	types = append(types, u32s(0, info(btfKindPtr, 0), 1)...)               // 6: int *
+	types = append(types, u32s(0, info(btfKindStruct, 4), 32,               // 7: the definition
+		name("type"), 3, 0, name("max_entries"), 5, 64, name("key"), 6, 128, name("value"), 6, 192)...)
+	types = append(types, u32s(name("classes"), info(btfKindVar, 0), 7, 1)...)           // 8: classes
+	types = append(types, u32s(name(".maps"), info(btfKindDatasec, 1), 32, 8, 0, 32)...) // 9: .maps
+
+	header := u32s(0, 24, 0, uint32(len(types)), uint32(len(types)), uint32(len(table)))
+	binary.LittleEndian.PutUint16(header, btfMagic)
+	header[2] = 1 // Version
+	return append(append(header, types...), table...)
+}
+
+func TestParseBPFObject_BTFMaps(t *testing.T) {
+	executable := elf.SHF_ALLOC | elf.SHF_EXECINSTR
+	path := writeBPFObject(t, []testSection{
+		{name: "tc", typ: elf.SHT_PROGBITS, flags: executable, data: testProgram()},
+		{name: ".reltc", typ: elf.SHT_REL, data: testRelocation(1), link: 5, info: 1},
+		{name: ".maps", typ: elf.SHT_PROGBITS, flags: elf.SHF_ALLOC | elf.SHF_WRITE, data: make([]byte, 32)},
+		{name: ".BTF", typ: elf.SHT_PROGBITS, data: testBTF()},
+	}, []testSymbol{
+		{name: "classes", section: 3, value: 0},
+	})
+
+	spec, err := parseBPFObject(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "tc", spec.section)
+	assert.Equal(t, []bpfMapSpec{{name: "classes", mapType: 1, keySize: 4, valueSize: 4, maxEntries: 64}}, spec.maps)
+}
+
+func TestParseBPFObject_Unsupported(t *testing.T) {
+	executable := elf.SHF_ALLOC | elf.SHF_EXECINSTR
+
+	t.Run("global_variables", func(t *testing.T) {
+		path := writeBPFObject(t, []testSection{
+			{name: "classifier", typ: elf.SHT_PROGBITS, flags: executable, data: testProgram()},
+			{name: ".relclassifier", typ: elf.SHT_REL, data: testRelocation(1), link: 4, info: 1},
+			{name: ".bss", typ: elf.SHT_NOBITS, flags: elf.SHF_ALLOC | elf.SHF_WRITE},
+		}, []testSymbol{{name: "counter", section: 3}})
+		_, err := parseBPFObject(path, "")
+		assert.ErrorContains(t, err, "global variables are not supported")
+	})
+
+	t.Run("core_relocations", func(t *testing.T) {
+		// struct btf_ext_header with one CO-RE relocation for section "classifier"
+		btf := testBTF()
+		btf = append(btf, "classifier\x00"...)
+		binary.LittleEndian.PutUint32(btf[20:], binary.LittleEndian.Uint32(btf[20:])+11)
+		nameOffset := uint32(binary.LittleEndian.Uint32(btf[20:]) - 11)
+
+		ext := u32s(0, 32, 0, 0, 0, 0, 0, 28)
+		binary.LittleEndian.PutUint16(ext, btfMagic)
+		ext[2] = 1
+		ext = append(ext, u32s(16, nameOffset, 1, 0, 0, 0, 0)...)
+
+		path := writeBPFObject(t, []testSection{
+			{name: "classifier", typ: elf.SHT_PROGBITS, flags: executable, data: testProgram()[16:]},
+			{name: ".BTF", typ: elf.SHT_PROGBITS, data: btf},
+			{name: ".BTF.ext", typ: elf.SHT_PROGBITS, data: ext},
+		}, nil)
+		_, err := parseBPFObject(path, "")
+		assert.ErrorContains(t, err, "needs CO-RE relocations")
+	})
+
+	t.Run("no_classifier", func(t *testing.T) {
+		path := writeBPFObject(t, []testSection{
+			{name: "xdp", typ: elf.SHT_PROGBITS, flags: executable, data: testProgram()[16:]},
+		}, nil)
+		_, err := parseBPFObject(path, "")
+		assert.ErrorContains(t, err, "no classifier section")
+
+		spec, err := parseBPFObject(path, "xdp")
+		require.NoError(t, err)
+		assert.Len(t, spec.insns, 2)
+	})
+
+	t.Run("not_bpf", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "classifier.o")
+		require.NoError(t, os.WriteFile(path, []byte("not an object"), 0o600))
+		_, err := parseBPFObject(path, "")
+		assert.Error(t, err)
+	})
+}
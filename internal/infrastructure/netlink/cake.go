@@ -0,0 +1,275 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// CAKE option attributes from linux/pkt_sched.h
+const (
+	tcaCakeBaseRate64   = 2
+	tcaCakeDiffservMode = 3
+	tcaCakeRTT          = 7
+	tcaCakeNAT          = 11
+	tcaCakeWash         = 13
+	tcaCakeAckFilter    = 16
+)
+
+// CAKE statistics attributes from linux/pkt_sched.h
+const (
+	tcaCakeStatsCapacityEstimate64 = 2
+	tcaCakeStatsMemoryLimit        = 3
+	tcaCakeStatsMemoryUsed         = 4
+	tcaCakeStatsTinStats           = 10
+
+	tcaCakeTinStatsSentPackets        = 2
+	tcaCakeTinStatsSentBytes64        = 3
+	tcaCakeTinStatsDroppedPackets     = 4
+	tcaCakeTinStatsDroppedBytes64     = 5
+	tcaCakeTinStatsAcksDroppedPackets = 6
+	tcaCakeTinStatsECNMarkedPackets   = 8
+	tcaCakeTinStatsBacklogPackets     = 10
+	tcaCakeTinStatsBacklogBytes       = 11
+	tcaCakeTinStatsThresholdRate64    = 12
+	tcaCakeTinStatsTargetUs           = 13
+	tcaCakeTinStatsIntervalUs         = 14
+	tcaCakeTinStatsPeakDelayUs        = 18
+	tcaCakeTinStatsAvgDelayUs         = 19
+	tcaCakeTinStatsBaseDelayUs        = 20
+	tcaCakeTinStatsSparseFlows        = 21
+	tcaCakeTinStatsBulkFlows          = 22
+	tcaCakeTinStatsUnresponsiveFlows  = 23
+)
+
+// cakeTinNames names the tins of the diffserv modes whose tins have well-known roles;
+// the tins of other modes are numbered
+var cakeTinNames = map[uint32][]string{
+	uint32(entities.CAKEDiffserv3): {"Bulk", "Best Effort", "Voice"},
+	uint32(entities.CAKEDiffserv4): {"Bulk", "Best Effort", "Video", "Voice"},
+}
+
+// cakeQdisc is a CAKE qdisc; the netlink library has no CAKE support, so it is
+// serialised by addCAKEQdisc
+type cakeQdisc struct {
+	netlink.QdiscAttrs
+	Rate      uint64 // bytes per second, zero for unlimited
+	RTT       uint32 // microseconds
+	Diffserv  uint32
+	NAT       bool
+	Wash      bool
+	AckFilter uint32
+}
+
+// Attrs returns the qdisc attributes
+func (q *cakeQdisc) Attrs() *netlink.QdiscAttrs {
+	return &q.QdiscAttrs
+}
+
+// Type returns the qdisc kind
+func (q *cakeQdisc) Type() string {
+	return "cake"
+}
+
+// newCAKEQdisc builds a CAKE qdisc from the entity parameters
+func newCAKEQdisc(attrs netlink.QdiscAttrs, qdiscEntity *entities.Qdisc) *cakeQdisc {
+	qdisc := &cakeQdisc{
+		QdiscAttrs: attrs,
+		RTT:        qdiscParameter(qdiscEntity, "rtt", 100000),
+		Diffserv:   qdiscParameter(qdiscEntity, "diffserv", uint32(entities.CAKEDiffserv3)),
+		NAT:        qdiscParameter(qdiscEntity, "nat", 0) != 0,
+		Wash:       qdiscParameter(qdiscEntity, "wash", 0) != 0,
+		AckFilter:  qdiscParameter(qdiscEntity, "ack_filter", uint32(entities.CAKEAckFilterNone)),
+	}
+	if value, ok := qdiscEntity.GetParameter("bandwidth"); ok {
+		if bandwidth, ok := value.(tc.Bandwidth); ok {
+			qdisc.Rate = bandwidth.BitsPerSecond() / 8
+		}
+	}
+	return qdisc
+}
+
+// addCAKEQdisc sends an RTM_NEWQDISC request for a CAKE qdisc
+func addCAKEQdisc(qdisc *cakeQdisc) error {
+	req := nl.NewNetlinkRequest(syscall.RTM_NEWQDISC, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(qdisc.LinkIndex), // #nosec G115 - kernel interface indexes fit in int32
+		Handle:  qdisc.Handle,
+		Parent:  qdisc.Parent,
+	})
+	req.AddData(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated(qdisc.Type())))
+
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	options.AddRtAttr(tcaCakeBaseRate64, nl.Uint64Attr(qdisc.Rate))
+	options.AddRtAttr(tcaCakeRTT, nl.Uint32Attr(qdisc.RTT))
+	options.AddRtAttr(tcaCakeDiffservMode, nl.Uint32Attr(qdisc.Diffserv))
+	options.AddRtAttr(tcaCakeNAT, nl.Uint32Attr(boolAttr(qdisc.NAT)))
+	options.AddRtAttr(tcaCakeWash, nl.Uint32Attr(boolAttr(qdisc.Wash)))
+	options.AddRtAttr(tcaCakeAckFilter, nl.Uint32Attr(qdisc.AckFilter))
+	req.AddData(options)
+
+	_, err := req.Execute(syscall.NETLINK_ROUTE, 0)
+	return err
+}
+
+// boolAttr encodes a flag attribute
+func boolAttr(value bool) uint32 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// getCAKEStats parses the statistics of the CAKE qdisc with the given handle
+func getCAKEStats(linkIndex int, handle uint32) (*CAKEQdiscStats, error) {
+	attrs, err := getQdiscAttrs(linkIndex, handle)
+	if err != nil {
+		return nil, err
+	}
+	return parseCAKEQdiscAttrs(attrs)
+}
+
+// parseCAKEQdiscAttrs extracts the CAKE statistics from the attributes of a qdisc message
+func parseCAKEQdiscAttrs(attrs []syscall.NetlinkRouteAttr) (*CAKEQdiscStats, error) {
+	diffserv := uint32(entities.CAKEDiffserv3)
+	var app []byte
+
+	for _, attr := range attrs {
+		switch attr.Attr.Type &^ syscall.NLA_F_NESTED {
+		case nl.TCA_KIND:
+			if kind := strings.TrimRight(string(attr.Value), "\x00"); kind != "cake" {
+				return nil, fmt.Errorf("qdisc is %s, not cake", kind)
+			}
+		case nl.TCA_OPTIONS:
+			options, err := nl.ParseRouteAttr(attr.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse CAKE options: %w", err)
+			}
+			for _, option := range options {
+				if option.Attr.Type&^syscall.NLA_F_NESTED == tcaCakeDiffservMode {
+					diffserv = nl.NativeEndian().Uint32(option.Value)
+				}
+			}
+		case nl.TCA_STATS2:
+			stats, err := nl.ParseRouteAttr(attr.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse qdisc statistics: %w", err)
+			}
+			for _, stat := range stats {
+				if stat.Attr.Type&^syscall.NLA_F_NESTED == nl.TCA_STATS_APP {
+					app = stat.Value
+				}
+			}
+		}
+	}
+
+	if app == nil {
+		return nil, fmt.Errorf("qdisc reported no CAKE statistics")
+	}
+	return parseCAKEStats(app, diffserv)
+}
+
+// parseCAKEStats parses the TCA_STATS_APP payload of a CAKE qdisc
+func parseCAKEStats(data []byte, diffserv uint32) (*CAKEQdiscStats, error) {
+	attrs, err := nl.ParseRouteAttr(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CAKE statistics: %w", err)
+	}
+
+	native := nl.NativeEndian()
+	stats := &CAKEQdiscStats{}
+	for _, attr := range attrs {
+		switch attr.Attr.Type &^ syscall.NLA_F_NESTED {
+		case tcaCakeStatsCapacityEstimate64:
+			stats.CapacityEstimate = native.Uint64(attr.Value)
+		case tcaCakeStatsMemoryLimit:
+			stats.MemoryLimit = native.Uint32(attr.Value)
+		case tcaCakeStatsMemoryUsed:
+			stats.MemoryUsed = native.Uint32(attr.Value)
+		case tcaCakeStatsTinStats:
+			tins, err := nl.ParseRouteAttr(attr.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse CAKE tin statistics: %w", err)
+			}
+			for i, tin := range tins {
+				tinStats, err := parseCAKETinStats(tin.Value)
+				if err != nil {
+					return nil, err
+				}
+				tinStats.Name = cakeTinName(diffserv, i)
+				stats.Tins = append(stats.Tins, tinStats)
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// parseCAKETinStats parses the statistics of a single tin
+func parseCAKETinStats(data []byte) (CAKETinStats, error) {
+	attrs, err := nl.ParseRouteAttr(data)
+	if err != nil {
+		return CAKETinStats{}, fmt.Errorf("failed to parse CAKE tin statistics: %w", err)
+	}
+
+	native := nl.NativeEndian()
+	var tin CAKETinStats
+	for _, attr := range attrs {
+		switch attr.Attr.Type &^ syscall.NLA_F_NESTED {
+		case tcaCakeTinStatsSentPackets:
+			tin.SentPackets = native.Uint32(attr.Value)
+		case tcaCakeTinStatsSentBytes64:
+			tin.SentBytes = native.Uint64(attr.Value)
+		case tcaCakeTinStatsDroppedPackets:
+			tin.DroppedPackets = native.Uint32(attr.Value)
+		case tcaCakeTinStatsDroppedBytes64:
+			tin.DroppedBytes = native.Uint64(attr.Value)
+		case tcaCakeTinStatsAcksDroppedPackets:
+			tin.AckDropPackets = native.Uint32(attr.Value)
+		case tcaCakeTinStatsECNMarkedPackets:
+			tin.ECNMarkedPackets = native.Uint32(attr.Value)
+		case tcaCakeTinStatsBacklogPackets:
+			tin.BacklogPackets = native.Uint32(attr.Value)
+		case tcaCakeTinStatsBacklogBytes:
+			tin.BacklogBytes = native.Uint32(attr.Value)
+		case tcaCakeTinStatsThresholdRate64:
+			tin.ThresholdRate = native.Uint64(attr.Value)
+		case tcaCakeTinStatsTargetUs:
+			tin.TargetUs = native.Uint32(attr.Value)
+		case tcaCakeTinStatsIntervalUs:
+			tin.IntervalUs = native.Uint32(attr.Value)
+		case tcaCakeTinStatsPeakDelayUs:
+			tin.PeakDelayUs = native.Uint32(attr.Value)
+		case tcaCakeTinStatsAvgDelayUs:
+			tin.AvgDelayUs = native.Uint32(attr.Value)
+		case tcaCakeTinStatsBaseDelayUs:
+			tin.BaseDelayUs = native.Uint32(attr.Value)
+		case tcaCakeTinStatsSparseFlows:
+			tin.SparseFlows = native.Uint32(attr.Value)
+		case tcaCakeTinStatsBulkFlows:
+			tin.BulkFlows = native.Uint32(attr.Value)
+		case tcaCakeTinStatsUnresponsiveFlows:
+			tin.UnresponsiveFlows = native.Uint32(attr.Value)
+		}
+	}
+
+	return tin, nil
+}
+
+// cakeTinName returns the name of the i-th tin of a diffserv mode
+func cakeTinName(diffserv uint32, i int) string {
+	if names, ok := cakeTinNames[diffserv]; ok && i < len(names) {
+		return names[i]
+	}
+	return fmt.Sprintf("Tin %d", i)
+}
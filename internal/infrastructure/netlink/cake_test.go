@@ -0,0 +1,78 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+// cakeTinAttr serialises the statistics of one tin
+func cakeTinAttr(index int, sentBytes uint64, drops, peakDelay uint32) *nl.RtAttr {
+	tin := nl.NewRtAttr(index, nil)
+	tin.AddRtAttr(tcaCakeTinStatsSentBytes64, nl.Uint64Attr(sentBytes))
+	tin.AddRtAttr(tcaCakeTinStatsDroppedPackets, nl.Uint32Attr(drops))
+	tin.AddRtAttr(tcaCakeTinStatsPeakDelayUs, nl.Uint32Attr(peakDelay))
+	return tin
+}
+
+func TestParseCAKEStats(t *testing.T) {
+	app := nl.NewRtAttr(nl.TCA_STATS_APP, nil)
+	app.AddRtAttr(tcaCakeStatsCapacityEstimate64, nl.Uint64Attr(12500000))
+	app.AddRtAttr(tcaCakeStatsMemoryUsed, nl.Uint32Attr(4096))
+	tins := app.AddRtAttr(tcaCakeStatsTinStats, nil)
+	for i, sent := range []uint64{100, 2000, 300, 40} {
+		tins.AddChild(cakeTinAttr(i+1, sent, uint32(i), uint32(i*1000)))
+	}
+
+	t.Run("parses_qdisc_and_tin_statistics", func(t *testing.T) {
+		stats, err := parseCAKEStats(app.Serialize()[syscall.SizeofRtAttr:], uint32(entities.CAKEDiffserv4))
+		require.NoError(t, err)
+
+		assert.Equal(t, uint64(12500000), stats.CapacityEstimate)
+		assert.Equal(t, uint32(4096), stats.MemoryUsed)
+		require.Len(t, stats.Tins, 4)
+		assert.Equal(t, "Video", stats.Tins[2].Name)
+		assert.Equal(t, uint64(300), stats.Tins[2].SentBytes)
+		assert.Equal(t, uint32(2), stats.Tins[2].DroppedPackets)
+		assert.Equal(t, uint32(2000), stats.Tins[2].PeakDelayUs)
+	})
+
+	t.Run("numbers_tins_of_unnamed_modes", func(t *testing.T) {
+		stats, err := parseCAKEStats(app.Serialize()[syscall.SizeofRtAttr:], uint32(entities.CAKEDiffserv8))
+		require.NoError(t, err)
+		assert.Equal(t, "Tin 3", stats.Tins[3].Name)
+	})
+
+	t.Run("reads_diffserv_mode_from_qdisc_options", func(t *testing.T) {
+		options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+		options.AddRtAttr(tcaCakeDiffservMode, nl.Uint32Attr(uint32(entities.CAKEDiffserv3)))
+		stats2 := nl.NewRtAttr(nl.TCA_STATS2, nil)
+		stats2.AddChild(app)
+
+		message := append(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated("cake")).Serialize(), options.Serialize()...)
+		message = append(message, stats2.Serialize()...)
+		attrs, err := nl.ParseRouteAttr(message)
+		require.NoError(t, err)
+
+		stats, err := parseCAKEQdiscAttrs(attrs)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bulk", "Best Effort", "Voice", "Tin 3"},
+			[]string{stats.Tins[0].Name, stats.Tins[1].Name, stats.Tins[2].Name, stats.Tins[3].Name})
+	})
+
+	t.Run("rejects_other_qdisc_kinds", func(t *testing.T) {
+		attrs, err := nl.ParseRouteAttr(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated("htb")).Serialize())
+		require.NoError(t, err)
+
+		_, err = parseCAKEQdiscAttrs(attrs)
+		assert.Error(t, err)
+	})
+}
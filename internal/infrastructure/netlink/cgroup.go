@@ -0,0 +1,119 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+// BPF helpers called by the cgroup classifier
+const (
+	bpfFuncGetCgroupClassid = 17 // bpf_get_cgroup_classid: net_cls class ID of the socket's cgroup v1
+	bpfFuncSkbCgroupID      = 79 // bpf_skb_cgroup_id: ID of the socket's cgroup v2
+)
+
+// addCgroupFilter installs a BPF classifier sending the packets of sockets in the cgroup to
+// the filter's class. On cgroup v2 the program compares the socket's cgroup ID; on cgroup v1
+// it compares the net_cls class ID, which is first set to the target class.
+func addCgroupFilter(link netlink.Link, filter *entities.Filter, actions []netlink.Action) error {
+	if len(filter.Matches()) != 1 {
+		return fmt.Errorf("cgroup filters match exactly one cgroup")
+	}
+	match, ok := filter.Matches()[0].(*entities.CgroupMatch)
+	if !ok {
+		return fmt.Errorf("cgroup filters cannot match %s", filter.Matches()[0])
+	}
+	if len(actions) > 0 {
+		// The netlink library cannot attach actions to BPF filters
+		return fmt.Errorf("cgroup filters do not support actions")
+	}
+	classID := netlink.MakeHandle(filter.FlowID().Major(), filter.FlowID().Minor())
+
+	helper, value, err := cgroupIdentity(match.Path(), classID)
+	if err != nil {
+		return err
+	}
+
+	fd, err := loadCgroupClassifier(helper, value)
+	if err != nil {
+		return fmt.Errorf("failed to load cgroup classifier: %w", err)
+	}
+	// The filter holds its own reference to the program
+	defer func() { _ = unix.Close(fd) }()
+
+	bpf := &netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    netlink.MakeHandle(filter.Parent().Major(), filter.Parent().Minor()),
+			Priority:  filter.Priority(),
+			Protocol:  unix.ETH_P_ALL,
+		},
+		ClassId: classID,
+		Fd:      fd,
+		Name:    "cgroup:" + filepath.Base(match.Path()),
+	}
+	return netlink.FilterAdd(bpf)
+}
+
+// cgroupIdentity returns the helper reading a socket's cgroup identity and the value the
+// sockets of the cgroup at path have. On cgroup v1 the cgroup must be in the net_cls
+// hierarchy, and its class ID is set to classID.
+func cgroupIdentity(path string, classID uint32) (int32, uint64, error) {
+	var fs unix.Statfs_t
+	if err := unix.Statfs(path, &fs); err != nil {
+		return 0, 0, fmt.Errorf("failed to access cgroup %s: %w", path, err)
+	}
+
+	switch fs.Type {
+	case unix.CGROUP2_SUPER_MAGIC:
+		handle, _, err := unix.NameToHandleAt(unix.AT_FDCWD, path, 0)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read the ID of cgroup %s: %w", path, err)
+		}
+		if len(handle.Bytes()) != 8 {
+			return 0, 0, fmt.Errorf("unexpected handle size %d for cgroup %s", len(handle.Bytes()), path)
+		}
+		return bpfFuncSkbCgroupID, binary.NativeEndian.Uint64(handle.Bytes()), nil
+	case unix.CGROUP_SUPER_MAGIC:
+		classFile := filepath.Join(path, "net_cls.classid")
+		if err := os.WriteFile(classFile, []byte(fmt.Sprintf("%d\n", classID)), 0); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return 0, 0, fmt.Errorf("cgroup %s is not in the net_cls hierarchy", path)
+			}
+			return 0, 0, fmt.Errorf("failed to set the class ID of cgroup %s: %w", path, err)
+		}
+		return bpfFuncGetCgroupClassid, uint64(classID), nil
+	default:
+		return 0, 0, fmt.Errorf("%s is not a cgroup", path)
+	}
+}
+
+// cgroupClassifierProgram returns a classifier matching packets for which the helper
+// returns the value. Returning -1 selects the filter's class, 0 means no match.
+func cgroupClassifierProgram(helper int32, value uint64) []bpfInsn {
+	return []bpfInsn{
+		{code: 0x85, imm: helper},                // call helper(skb)
+		{code: 0x18, regs: 1, imm: int32(value)}, // r1 = value (low word)
+		{imm: int32(value >> 32)},                // (high word)
+		{code: 0x1d, regs: 1 << 4, off: 2},       // if r0 == r1 goto match
+		{code: 0xb7, imm: 0},                     // r0 = 0
+		{code: 0x95},                             // exit
+		{code: 0xb7, imm: -1},                    // match: r0 = -1
+		{code: 0x95},                             // exit
+	}
+}
+
+// loadCgroupClassifier loads the classifier program and returns its file descriptor
+func loadCgroupClassifier(helper int32, value uint64) (int, error) {
+	return loadBPFProgram("tc_cgroup", cgroupClassifierProgram(helper, value), "Dual MIT/GPL")
+}
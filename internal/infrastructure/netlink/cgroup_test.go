@@ -0,0 +1,26 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCgroupClassifierProgram(t *testing.T) {
+	const cgroupID = 0x0000000100000002
+	program := cgroupClassifierProgram(bpfFuncSkbCgroupID, cgroupID)
+	require.Len(t, program, 8)
+
+	assert.Equal(t, int32(bpfFuncSkbCgroupID), program[0].imm)
+	// ld_imm64 splits the value across two instructions
+	assert.Equal(t, int32(2), program[1].imm)
+	assert.Equal(t, int32(1), program[2].imm)
+	// The comparison jumps to the instructions returning -1
+	assert.Equal(t, int16(2), program[3].off)
+	assert.Equal(t, int32(-1), program[6].imm)
+	assert.Equal(t, uint8(0x95), program[7].code)
+}
@@ -0,0 +1,130 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/types"
+)
+
+// clsactParent returns the filter parent of a clsact hook
+func clsactParent(hook ClsactHook) (uint32, error) {
+	parent, err := hook.Parent()
+	if err != nil {
+		return 0, err
+	}
+	return parent.ToUint32(), nil
+}
+
+// clsactQdisc returns the link's clsact or ingress qdisc, nil if it has neither
+func clsactQdisc(link netlink.Link) (netlink.Qdisc, error) {
+	qdiscs, err := netlink.QdiscList(link)
+	if err != nil {
+		return nil, err
+	}
+	for _, qdisc := range qdiscs {
+		if qdisc.Attrs().Parent == netlink.HANDLE_CLSACT {
+			return qdisc, nil
+		}
+	}
+	return nil, nil
+}
+
+// ensureClsactQdisc adds the clsact qdisc to the link unless it already has one, and
+// reports whether it was added. An ingress qdisc, which only has the ingress hook, serves
+// ingress filters.
+func ensureClsactQdisc(link netlink.Link, hook ClsactHook) (bool, error) {
+	qdisc, err := clsactQdisc(link)
+	if err != nil {
+		return false, err
+	}
+	if qdisc != nil {
+		if qdisc.Type() == "ingress" && hook == HookEgress {
+			return false, fmt.Errorf("device has an ingress qdisc, which has no egress hook; replace it with clsact")
+		}
+		return false, nil
+	}
+
+	err = netlink.QdiscAdd(&netlink.Clsact{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_CLSACT,
+		},
+	})
+	return err == nil, err
+}
+
+// deleteUnusedClsactQdisc deletes the link's clsact or ingress qdisc unless filters remain
+// on one of its hooks
+func deleteUnusedClsactQdisc(link netlink.Link) (bool, error) {
+	qdisc, err := clsactQdisc(link)
+	if err != nil || qdisc == nil {
+		return false, err
+	}
+
+	parents := []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS}
+	if qdisc.Type() == "ingress" {
+		parents = parents[:1]
+	}
+	for _, parent := range parents {
+		filters, err := netlink.FilterList(link, parent)
+		if err != nil {
+			return false, err
+		}
+		if len(filters) > 0 {
+			return false, nil
+		}
+	}
+
+	if err := netlink.QdiscDel(qdisc); err != nil && !isNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// EnsureClsactQdisc adds the clsact qdisc to the device unless it has one, and reports
+// whether it was added. A clsact qdisc added by another tool, such as a CNI plugin, is
+// shared; a plain ingress qdisc serves the ingress hook only.
+func (a *RealNetlinkAdapter) EnsureClsactQdisc(ctx context.Context, device tc.DeviceName, hook ClsactHook) (bool, error) {
+	link, err := netlink.LinkByName(device.String())
+	if err != nil {
+		return false, deviceNotFound(device, err)
+	}
+
+	created, err := ensureClsactQdisc(link, hook)
+	if err != nil {
+		return false, fmt.Errorf("failed to add clsact qdisc to %s: %w", device, err)
+	}
+	if created {
+		a.logger.Info("Added clsact qdisc", logging.String("device", device.String()))
+	}
+	return created, nil
+}
+
+// DeleteClsactQdisc deletes the device's clsact qdisc once no filters remain on its hooks.
+// While another tool still has filters there, the qdisc is left in place.
+func (a *RealNetlinkAdapter) DeleteClsactQdisc(device tc.DeviceName) types.Result[Unit] {
+	link, err := netlink.LinkByName(device.String())
+	if err != nil {
+		return types.Failure[Unit](deviceNotFound(device, err))
+	}
+
+	deleted, err := deleteUnusedClsactQdisc(link)
+	if err != nil {
+		return types.Failure[Unit](fmt.Errorf("failed to delete clsact qdisc of %s: %w", device, err))
+	}
+	if deleted {
+		a.logger.Info("Deleted clsact qdisc", logging.String("device", device.String()))
+	} else {
+		a.logger.Debug("Left clsact qdisc in place", logging.String("device", device.String()))
+	}
+	return types.Success(Unit{})
+}
@@ -0,0 +1,48 @@
+package netlink
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// operationTimeoutKey is the context key of the per-operation netlink timeout
+type operationTimeoutKey struct{}
+
+// WithOperationTimeout returns a context that bounds every netlink operation made with it
+// to the given timeout, on top of the context's own deadline. A non-positive timeout leaves
+// operations bounded by the context alone.
+func WithOperationTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, operationTimeoutKey{}, timeout)
+}
+
+// RunOperation runs a netlink operation until it completes or the context's deadline or
+// operation timeout passes, so a hung netlink socket cannot block the caller. Netlink calls
+// cannot be interrupted: an abandoned operation finishes in the background, and the next
+// reconciliation removes anything it leaves behind.
+func RunOperation(ctx context.Context, operation func() error) error {
+	if timeout, ok := ctx.Value(operationTimeoutKey{}).(time.Duration); ok && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("netlink operation not attempted: %w", err)
+	}
+	if ctx.Done() == nil {
+		return operation()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- operation()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("netlink operation abandoned: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,51 @@
+package netlink
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunOperation(t *testing.T) {
+	t.Run("completes", func(t *testing.T) {
+		err := RunOperation(WithOperationTimeout(context.Background(), time.Second), func() error {
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("returns operation error", func(t *testing.T) {
+		failure := errors.New("failed")
+		err := RunOperation(context.Background(), func() error { return failure })
+		assert.ErrorIs(t, err, failure)
+	})
+
+	t.Run("abandons hung operation", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		err := RunOperation(WithOperationTimeout(context.Background(), 20*time.Millisecond), func() error {
+			<-release
+			return nil
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Contains(t, err.Error(), "abandoned")
+	})
+
+	t.Run("skips when context done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		called := false
+		err := RunOperation(ctx, func() error {
+			called = true
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Contains(t, err.Error(), "not attempted")
+		assert.False(t, called)
+	})
+}
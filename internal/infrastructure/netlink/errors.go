@@ -0,0 +1,27 @@
+package netlink
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// deviceNotFound reports a device the kernel could not find
+func deviceNotFound(device tc.DeviceName, err error) error {
+	return tcerrors.Wrap(err, tcerrors.CodeDeviceNotFound, "device "+device.String(), "failed to find device %s", device)
+}
+
+// qdiscObject, classObject and filterObject name the objects errors are about
+func qdiscObject(handle tc.Handle) string { return "qdisc " + handle.String() }
+func classObject(handle tc.Handle) string { return "class " + handle.String() }
+func filterObject(parent tc.Handle, priority uint16) string {
+	return fmt.Sprintf("filter %s prio %d", parent, priority)
+}
+
+// isNotFound reports whether a delete failed because the object was already gone
+func isNotFound(err error) bool {
+	return errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENODEV)
+}
@@ -0,0 +1,683 @@
+package netlink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+	"github.com/rng999/traffic-control-go/pkg/types"
+)
+
+// DefaultTCPath is where tc(8) is installed on most distributions
+const DefaultTCPath = "/sbin/tc"
+
+// CommandRunner runs the commands of the exec adapter, on the local host or on the host
+// whose traffic is controlled
+type CommandRunner interface {
+	// Run runs the program with the arguments and returns its standard output. A program
+	// that fails returns a *CommandError.
+	Run(ctx context.Context, program string, args ...string) ([]byte, error)
+}
+
+// LocalRunner runs commands as child processes of the current one
+type LocalRunner struct{}
+
+// Run runs the program on the local host
+func (LocalRunner) Run(ctx context.Context, program string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, program, args...) // #nosec G204 -- the adapter builds the arguments from validated entities
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, &CommandError{Command: CommandLine(program, args), Stderr: strings.TrimSpace(stderr.String()), Err: err}
+	}
+	return output, nil
+}
+
+// CommandLine joins a program and its arguments into the line a shell would run
+func CommandLine(program string, args []string) string {
+	return strings.Join(append([]string{program}, args...), " ")
+}
+
+// CommandError is a command that failed
+type CommandError struct {
+	Command string // The command line
+	Stderr  string // What the command printed on its standard error
+	Err     error  // Why it failed, such as its exit status
+}
+
+// Error returns the command with the message it printed
+func (e *CommandError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("%s: %v", e.Command, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Command, e.Stderr)
+}
+
+// Unwrap returns the cause and, when the message names it, the errno the kernel returned,
+// so that the error is classified as a netlink error would be
+func (e *CommandError) Unwrap() []error {
+	var errs []error
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	if errno, ok := messageErrno(e.Stderr); ok {
+		errs = append(errs, errno)
+	}
+	return errs
+}
+
+// errnoMessages are the messages tc(8) and ip(8) print for kernel errors, with their errno;
+// the first one found in the output wins
+var errnoMessages = []struct {
+	message string
+	errno   syscall.Errno
+}{
+	{"Cannot find device", syscall.ENODEV},
+	{"File exists", syscall.EEXIST},
+	{"Exclusivity flag on", syscall.EEXIST},
+	{"Operation not permitted", syscall.EPERM},
+	{"Permission denied", syscall.EACCES},
+	{"Operation not supported", syscall.EOPNOTSUPP},
+	{"kind is unknown", syscall.EOPNOTSUPP},
+	{"No such file or directory", syscall.ENOENT},
+	{"not found", syscall.ENOENT},
+	{"Cannot find", syscall.ENOENT},
+	{"Invalid handle", syscall.EINVAL},
+}
+
+// messageErrno returns the errno of the kernel error a command printed
+func messageErrno(stderr string) (syscall.Errno, bool) {
+	for _, known := range errnoMessages {
+		if strings.Contains(stderr, known.message) {
+			return known.errno, true
+		}
+	}
+	return 0, false
+}
+
+// ExecAdapter is an Adapter running tc(8) and ip(8) instead of speaking netlink. It serves
+// where the netlink path is unavailable: in restricted environments, with kernel features
+// the netlink library cannot configure, or, through another CommandRunner, on another host.
+// Each operation runs the same commands a dry run prints and installs what the netlink
+// adapter installs. Watching for deletions and link changes, cgroup classifiers, which the
+// netlink adapter loads as BPF programs, and pinning the maps of BPF classifiers are not
+// supported.
+type ExecAdapter struct {
+	runner  CommandRunner
+	tc      string
+	ip      string
+	bpftool string
+	logger  logging.Logger
+}
+
+// NewExecAdapter returns an adapter running the tc binary at tcPath, DefaultTCPath when
+// empty, on the local host
+func NewExecAdapter(tcPath string) *ExecAdapter {
+	return NewExecAdapterWithRunner(LocalRunner{}, tcPath)
+}
+
+// NewExecAdapterWithRunner returns an adapter running the tc binary at tcPath, DefaultTCPath
+// when empty, through the runner. ip(8), from the same iproute2 package, is run from the
+// directory of tc, and bpftool(8), only needed to update BPF maps, from the search path.
+func NewExecAdapterWithRunner(runner CommandRunner, tcPath string) *ExecAdapter {
+	if tcPath == "" {
+		tcPath = DefaultTCPath
+	}
+	ipPath := "ip"
+	if strings.Contains(tcPath, "/") {
+		ipPath = path.Join(path.Dir(tcPath), "ip")
+	}
+
+	logger := logging.WithComponent(logging.ComponentNetlink)
+	logger.Info("Initializing exec adapter", logging.String("tc", tcPath))
+	return &ExecAdapter{runner: runner, tc: tcPath, ip: ipPath, bpftool: "bpftool", logger: logger}
+}
+
+var _ Adapter = (*ExecAdapter)(nil)
+
+// runTC runs tc with the arguments of the command line, which holds no quoted arguments
+func (a *ExecAdapter) runTC(ctx context.Context, line string) ([]byte, error) {
+	a.logger.Debug("Running tc", logging.String("command", line))
+	return a.runner.Run(ctx, a.tc, strings.Fields(line)...)
+}
+
+// runIP runs ip with the arguments of the command line
+func (a *ExecAdapter) runIP(ctx context.Context, line string) ([]byte, error) {
+	a.logger.Debug("Running ip", logging.String("command", line))
+	return a.runner.Run(ctx, a.ip, strings.Fields(line)...)
+}
+
+// AddQdisc adds a qdisc with tc
+func (a *ExecAdapter) AddQdisc(ctx context.Context, qdisc *entities.Qdisc) error {
+	a.logger.Info("Adding qdisc",
+		logging.String("device", qdisc.Device().String()),
+		logging.String("qdisc_type", qdisc.Type().String()),
+		logging.String("handle", qdisc.Handle().String()),
+		logging.String("operation", logging.OperationCreateQdisc),
+	)
+
+	for _, line := range qdiscCommands(qdisc) {
+		if _, err := a.runTC(ctx, line); err != nil {
+			return tcerrors.FromErrno(err, qdiscObject(qdisc.Handle()), "failed to add qdisc")
+		}
+	}
+	return nil
+}
+
+// qdiscCommands returns the tc commands adding a qdisc with the parameters of the entity,
+// and the defaults of the netlink adapter for those it lacks
+func qdiscCommands(qdisc *entities.Qdisc) []string {
+	add := fmt.Sprintf("qdisc add dev %s %s handle %s ", qdisc.Device(), ParentArg(qdisc.Parent()), qdisc.Handle())
+
+	switch qdisc.Type() {
+	case entities.QdiscTypeFQCODEL:
+		return []string{add + fmt.Sprintf("fq_codel limit %d flows %d target %dus interval %dus quantum %d %s",
+			qdiscParameter(qdisc, "limit", 10240), qdiscParameter(qdisc, "flows", 1024),
+			qdiscParameter(qdisc, "target", 5000), qdiscParameter(qdisc, "interval", 100000),
+			qdiscParameter(qdisc, "quantum", 1518), flagArg(qdiscParameter(qdisc, "ecn", 0) != 0, "ecn", "noecn"))}
+	case entities.QdiscTypeSFQ:
+		return []string{add + fmt.Sprintf("sfq perturb %d quantum %d limit %d",
+			qdiscParameter(qdisc, "perturb", 0), qdiscParameter(qdisc, "quantum", 1514), qdiscParameter(qdisc, "limit", 127))}
+	case entities.QdiscTypeFQ:
+		args := fmt.Sprintf("fq limit %d flow_limit %d quantum %d initial_quantum %d",
+			qdiscParameter(qdisc, "limit", 10000), qdiscParameter(qdisc, "flow_limit", 100),
+			qdiscParameter(qdisc, "quantum", 3028), qdiscParameter(qdisc, "initial_quantum", 15140))
+		if value, ok := qdisc.GetParameter("max_rate"); ok {
+			if maxRate, ok := value.(tc.Bandwidth); ok && maxRate.BitsPerSecond() > 0 {
+				args += " maxrate " + RateArg(maxRate)
+			}
+		}
+		return []string{add + args + " " + flagArg(qdiscParameter(qdisc, "pacing", 1) != 0, "pacing", "nopacing")}
+	case entities.QdiscTypeNETEM:
+		args := fmt.Sprintf("netem limit %d delay %dus %dus", qdiscParameter(qdisc, "limit", 1000),
+			qdiscParameter(qdisc, "delay", 0), qdiscParameter(qdisc, "jitter", 0))
+		for _, key := range []string{"loss", "duplicate", "corrupt"} {
+			if percent := netemProbability(qdisc, key); percent > 0 {
+				args += fmt.Sprintf(" %s %g%%", key, percent)
+			}
+		}
+		if percent := netemProbability(qdisc, "reorder"); percent > 0 {
+			args += fmt.Sprintf(" reorder %g%% gap %d", percent, qdiscParameter(qdisc, "gap", 0))
+		}
+		return []string{add + args}
+	case entities.QdiscTypeCAKE:
+		bandwidth := "unlimited"
+		if value, ok := qdisc.GetParameter("bandwidth"); ok {
+			if rate, ok := value.(tc.Bandwidth); ok && rate.BitsPerSecond() > 0 {
+				bandwidth = "bandwidth " + RateArg(rate)
+			}
+		}
+		return []string{add + fmt.Sprintf("cake %s rtt %dus %s %s %s %s", bandwidth, qdiscParameter(qdisc, "rtt", 100000),
+			entities.CAKEDiffservMode(qdiscParameter(qdisc, "diffserv", uint32(entities.CAKEDiffserv3))),
+			flagArg(qdiscParameter(qdisc, "nat", 0) != 0, "nat", "nonat"),
+			flagArg(qdiscParameter(qdisc, "wash", 0) != 0, "wash", "nowash"),
+			entities.CAKEAckFilter(qdiscParameter(qdisc, "ack_filter", uint32(entities.CAKEAckFilterNone))))}
+	case entities.QdiscTypeRED:
+		var params entities.REDParameters
+		if value, ok := qdisc.GetParameter("red"); ok {
+			params, _ = value.(entities.REDParameters)
+		}
+		args := "red " + REDArgs(params.REDThresholds)
+		if params.ECN {
+			args += " ecn"
+		}
+		if params.HardDrop {
+			args += " harddrop"
+		}
+		if params.Adaptive {
+			args += " adaptive"
+		}
+		return []string{add + args}
+	case entities.QdiscTypeGRED:
+		var params entities.GREDParameters
+		if value, ok := qdisc.GetParameter("gred"); ok {
+			params, _ = value.(entities.GREDParameters)
+		}
+		return gredCommands(qdisc, add, params)
+	case entities.QdiscTypeCODEL:
+		args := fmt.Sprintf("codel limit %d target %dus interval %dus", qdiscParameter(qdisc, "limit", 1000),
+			qdiscParameter(qdisc, "target", 5000), qdiscParameter(qdisc, "interval", 100000))
+		if threshold := qdiscParameter(qdisc, "ce_threshold", 0); threshold > 0 {
+			args += fmt.Sprintf(" ce_threshold %dus", threshold)
+		}
+		return []string{add + args + " " + flagArg(qdiscParameter(qdisc, "ecn", 0) != 0, "ecn", "noecn")}
+	case entities.QdiscTypeETS:
+		var params entities.ETSParameters
+		if value, ok := qdisc.GetParameter("ets"); ok {
+			params, _ = value.(entities.ETSParameters)
+		}
+		return []string{add + ETSArgs(params)}
+	case entities.QdiscTypeTAPRIO:
+		var params entities.TAPRIOParameters
+		if value, ok := qdisc.GetParameter("taprio"); ok {
+			params, _ = value.(entities.TAPRIOParameters)
+		}
+		return []string{add + TAPRIOArgs(params)}
+	case entities.QdiscTypeDRR, entities.QdiscTypeQFQ:
+		// Both take no qdisc options
+		return []string{add + qdisc.Type().String()}
+	default:
+		// Like the netlink adapter, other qdiscs are added as HTB
+		return []string{add + fmt.Sprintf("htb default %x r2q 10", qdiscParameter(qdisc, "default_class", 0))}
+	}
+}
+
+// gredCommands returns the tc commands setting up a GRED qdisc and each of its virtual
+// queues
+func gredCommands(qdisc *entities.Qdisc, add string, params entities.GREDParameters) []string {
+	setup := fmt.Sprintf("gred setup vqs %d default %d", params.Queues, params.Default)
+	if params.GRIO {
+		setup += " grio"
+	}
+	commands := []string{add + setup}
+
+	for _, vq := range params.VirtualQueues {
+		command := fmt.Sprintf("qdisc change dev %s %s handle %s gred %s DP %d",
+			qdisc.Device(), ParentArg(qdisc.Parent()), qdisc.Handle(), REDArgs(vq.REDThresholds), vq.DP)
+		if params.GRIO {
+			command += fmt.Sprintf(" prio %d", vq.Priority)
+		}
+		if params.ECN {
+			command += " ecn"
+		}
+		if params.HardDrop {
+			command += " harddrop"
+		}
+		commands = append(commands, command)
+	}
+	return commands
+}
+
+// DeleteQdisc deletes a qdisc with tc, which needs its parent, looked up first
+func (a *ExecAdapter) DeleteQdisc(device tc.DeviceName, handle tc.Handle) types.Result[Unit] {
+	ctx := context.Background()
+	qdiscs, err := a.showQdiscs(ctx, device)
+	if err != nil {
+		return types.Failure[Unit](err)
+	}
+
+	for _, qdisc := range qdiscs {
+		info, err := qdisc.info()
+		if err != nil || info.Handle != handle {
+			continue
+		}
+		line := fmt.Sprintf("qdisc del dev %s %s handle %s", device, ParentArg(info.Parent), handle)
+		if _, err := a.runTC(ctx, line); err != nil {
+			return types.Failure[Unit](tcerrors.FromErrno(err, qdiscObject(handle), "failed to delete qdisc"))
+		}
+		return types.Success(Unit{})
+	}
+	return types.Failure[Unit](tcerrors.New(tcerrors.CodeNotFound, qdiscObject(handle),
+		"failed to delete qdisc: no qdisc %s on device %s", handle, device))
+}
+
+// AddClass adds a class with tc
+func (a *ExecAdapter) AddClass(ctx context.Context, classEntity interface{}) error {
+	var line string
+	var handle tc.Handle
+	switch class := classEntity.(type) {
+	case *entities.HTBClass:
+		line, handle = htbClassCommand("add", class), class.Handle()
+	case *entities.DRRClass:
+		args := "drr"
+		if class.Quantum() > 0 {
+			args += fmt.Sprintf(" quantum %d", class.Quantum())
+		}
+		line, handle = classCommand(class.Class, args), class.Handle()
+	case *entities.QFQClass:
+		args := "qfq"
+		if class.Weight() > 0 {
+			args += fmt.Sprintf(" weight %d", class.Weight())
+		}
+		if class.Lmax() > 0 {
+			args += fmt.Sprintf(" maxpkt %d", class.Lmax())
+		}
+		line, handle = classCommand(class.Class, args), class.Handle()
+	case *entities.Class:
+		return fmt.Errorf("basic class creation not implemented")
+	default:
+		return fmt.Errorf("unsupported class type: %T", classEntity)
+	}
+
+	a.logger.Info("Adding class",
+		logging.String("handle", handle.String()),
+		logging.String("operation", logging.OperationCreateClass),
+	)
+	if _, err := a.runTC(ctx, line); err != nil {
+		return tcerrors.FromErrno(err, classObject(handle), "failed to add class")
+	}
+	return nil
+}
+
+// ChangeClass changes the parameters of an existing HTB class in place, keeping its counters
+func (a *ExecAdapter) ChangeClass(ctx context.Context, classEntity interface{}) error {
+	class, ok := classEntity.(*entities.HTBClass)
+	if !ok {
+		return tcerrors.New(tcerrors.CodeUnsupported, "", "changing %T classes in place is not supported", classEntity)
+	}
+
+	a.logger.Info("Changing HTB class",
+		logging.String("handle", class.Handle().String()),
+		logging.String("operation", logging.OperationUpdateClass),
+	)
+	if _, err := a.runTC(ctx, htbClassCommand("change", class)); err != nil {
+		return tcerrors.FromErrno(err, classObject(class.Handle()), "failed to change HTB class")
+	}
+	return nil
+}
+
+// htbClassCommand returns the tc command adding or changing an HTB class, with the bursts
+// and quantum the netlink adapter derives when the class leaves them unset
+func htbClassCommand(verb string, class *entities.HTBClass) string {
+	burst, cburst, quantum := class.Burst(), class.Cburst(), class.Quantum()
+	if burst == 0 {
+		burst = class.CalculateEnhancedBurst()
+	}
+	if cburst == 0 {
+		cburst = class.CalculateEnhancedCburst()
+	}
+	if quantum == 0 {
+		quantum = class.CalculateQuantum()
+	}
+
+	line := fmt.Sprintf("class %s dev %s parent %s classid %s htb rate %s ceil %s burst %d cburst %d quantum %d",
+		verb, class.ID().Device(), class.Parent(), class.Handle(), RateArg(class.Rate()), RateArg(class.Ceil()),
+		burst, cburst, quantum)
+	if class.HTBPrio() > 0 {
+		line += fmt.Sprintf(" prio %d", class.HTBPrio())
+	}
+	return line
+}
+
+// classCommand returns the tc command adding a class of another qdisc
+func classCommand(class *entities.Class, args string) string {
+	return fmt.Sprintf("class add dev %s parent %s classid %s %s", class.ID().Device(), class.Parent(), class.Handle(), args)
+}
+
+// DeleteClass deletes a class with tc
+func (a *ExecAdapter) DeleteClass(device tc.DeviceName, handle tc.Handle) types.Result[Unit] {
+	if _, err := a.runTC(context.Background(), fmt.Sprintf("class del dev %s classid %s", device, handle)); err != nil {
+		return types.Failure[Unit](tcerrors.FromErrno(err, classObject(handle), "failed to delete class"))
+	}
+	return types.Success(Unit{})
+}
+
+// AddFilter adds a u32 or flower filter with tc
+func (a *ExecAdapter) AddFilter(ctx context.Context, filter *entities.Filter) error {
+	object := filterObject(filter.Parent(), filter.Priority())
+	if filter.Kind() == entities.FilterKindCgroup {
+		return tcerrors.New(tcerrors.CodeUnsupported, object, "cgroup filters cannot be added with tc").
+			WithHint("match the traffic by address or port, or use the netlink backend")
+	}
+	line, err := filterCommand(filter)
+	if err != nil {
+		return fmt.Errorf("failed to configure filter matches: %w", err)
+	}
+
+	a.logger.Info("Adding filter",
+		logging.String("device", filter.ID().Device().String()),
+		logging.String("operation", logging.OperationCreateFilter),
+	)
+	if _, err := a.runTC(ctx, line); err != nil {
+		return tcerrors.FromErrno(err, object, "failed to add filter")
+	}
+	return nil
+}
+
+// filterCommand returns the tc command adding a filter. Filters on a clsact hook select no
+// class.
+func filterCommand(filter *entities.Filter) (string, error) {
+	selector, err := FilterSelector(filter.Kind(), filter.Matches())
+	if err != nil {
+		return "", err
+	}
+	line := fmt.Sprintf("filter add dev %s parent %s protocol %s prio %d %s", filter.ID().Device(), filter.Parent(),
+		ProtocolArg(filter.Protocol()), filter.Priority(), selector)
+	if filter.FlowID() != (tc.Handle{}) {
+		line += " flowid " + filter.FlowID().String()
+	}
+	return line + ActionArgs(filter.Actions(), filter.Protocol()), nil
+}
+
+// DeleteFilter deletes the filters at the priority under the parent with tc. The planner
+// gives every filter a priority of its own, so the handle is not needed.
+func (a *ExecAdapter) DeleteFilter(device tc.DeviceName, parent tc.Handle, priority uint16, handle tc.Handle) types.Result[Unit] {
+	line := fmt.Sprintf("filter del dev %s parent %s prio %d", device, parent, priority)
+	if _, err := a.runTC(context.Background(), line); err != nil {
+		return types.Failure[Unit](tcerrors.FromErrno(err, filterObject(parent, priority), "failed to delete filter"))
+	}
+	return types.Success(Unit{})
+}
+
+// WatchQdiscDeletions is not supported: tc cannot subscribe to the kernel's notifications
+func (a *ExecAdapter) WatchQdiscDeletions(ctx context.Context, device tc.DeviceName) (<-chan QdiscDeletion, error) {
+	return nil, unsupportedByExec("watching for qdisc deletions")
+}
+
+// WatchLinks is not supported: ip cannot subscribe to the kernel's notifications
+func (a *ExecAdapter) WatchLinks(ctx context.Context, device tc.DeviceName) (<-chan LinkEvent, error) {
+	return nil, unsupportedByExec("watching links")
+}
+
+// unsupportedByExec is the error of an operation the exec adapter cannot run
+func unsupportedByExec(operation string) error {
+	return tcerrors.New(tcerrors.CodeUnsupported, "", "%s is not supported by the exec backend", operation).
+		WithHint("use the netlink backend for this feature")
+}
+
+// AddIngressRedirect creates the IFB device if needed and redirects all traffic received on
+// the device to it, as the netlink adapter does
+func (a *ExecAdapter) AddIngressRedirect(ctx context.Context, device, ifb tc.DeviceName) error {
+	if _, err := a.runIP(ctx, fmt.Sprintf("link add %s type ifb", ifb)); err != nil && !errors.Is(err, syscall.EEXIST) {
+		return fmt.Errorf("failed to create IFB device %s: %w", ifb, err)
+	}
+	if _, err := a.runIP(ctx, fmt.Sprintf("link set dev %s up", ifb)); err != nil {
+		return fmt.Errorf("failed to bring up IFB device %s: %w", ifb, err)
+	}
+	if _, err := a.EnsureClsactQdisc(ctx, device, HookIngress); err != nil {
+		return err
+	}
+
+	// A single all-zero key matches every packet
+	line := fmt.Sprintf("filter replace dev %s ingress protocol all prio %d handle 800::800 u32 match u32 0 0 action mirred egress redirect dev %s",
+		device, ingressRedirectPriority, ifb)
+	if _, err := a.runTC(ctx, line); err != nil {
+		return fmt.Errorf("failed to redirect ingress of %s to %s: %w", device, ifb, err)
+	}
+
+	a.logger.Info("Redirected ingress traffic",
+		logging.String("device", device.String()),
+		logging.String("ifb", ifb.String()),
+	)
+	return nil
+}
+
+// DeleteIngressRedirect removes the redirect filter, the clsact qdisc unless other filters
+// remain on it, and the IFB device. Parts already gone are skipped.
+func (a *ExecAdapter) DeleteIngressRedirect(device, ifb tc.DeviceName) types.Result[Unit] {
+	ctx := context.Background()
+	var errs []error
+
+	line := fmt.Sprintf("filter del dev %s ingress protocol all prio %d", device, ingressRedirectPriority)
+	if _, err := a.runTC(ctx, line); err != nil && !isNotFound(err) {
+		errs = append(errs, fmt.Errorf("failed to delete ingress redirect of %s: %w", device, err))
+	} else if result := a.DeleteClsactQdisc(device); result.IsFailure() && !isNotFound(result.Error()) {
+		errs = append(errs, result.Error())
+	}
+
+	link := a.GetLinkInfo(ifb)
+	switch {
+	case link.IsFailure():
+	case link.Value().Kind != "ifb":
+		errs = append(errs, fmt.Errorf("refusing to delete %s: not an IFB device", ifb))
+	default:
+		if _, err := a.runIP(ctx, fmt.Sprintf("link del %s", ifb)); err != nil && !isNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete IFB device %s: %w", ifb, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return types.Failure[Unit](err)
+	}
+	return types.Success(Unit{})
+}
+
+// EnsureClsactQdisc adds the clsact qdisc to the device unless it has one, and reports
+// whether it was added. A plain ingress qdisc serves the ingress hook only.
+func (a *ExecAdapter) EnsureClsactQdisc(ctx context.Context, device tc.DeviceName, hook ClsactHook) (bool, error) {
+	qdisc, err := a.clsactQdisc(ctx, device)
+	if err != nil {
+		return false, err
+	}
+	if qdisc != nil {
+		if qdisc.Kind == "ingress" && hook == HookEgress {
+			return false, fmt.Errorf("failed to add clsact qdisc to %s: device has an ingress qdisc, which has no egress hook; replace it with clsact", device)
+		}
+		return false, nil
+	}
+
+	if _, err := a.runTC(ctx, fmt.Sprintf("qdisc add dev %s clsact", device)); err != nil {
+		return false, fmt.Errorf("failed to add clsact qdisc to %s: %w", device, err)
+	}
+	a.logger.Info("Added clsact qdisc", logging.String("device", device.String()))
+	return true, nil
+}
+
+// DeleteClsactQdisc deletes the device's clsact or ingress qdisc once no filters remain on
+// its hooks
+func (a *ExecAdapter) DeleteClsactQdisc(device tc.DeviceName) types.Result[Unit] {
+	ctx := context.Background()
+	qdisc, err := a.clsactQdisc(ctx, device)
+	if err != nil {
+		return types.Failure[Unit](err)
+	}
+	if qdisc == nil {
+		return types.Success(Unit{})
+	}
+
+	hooks := []ClsactHook{HookIngress, HookEgress}
+	if qdisc.Kind == "ingress" {
+		hooks = hooks[:1]
+	}
+	for _, hook := range hooks {
+		parent, _ := hook.Parent()
+		filters, err := a.showFilters(ctx, device, parent)
+		if err != nil {
+			return types.Failure[Unit](fmt.Errorf("failed to delete clsact qdisc of %s: %w", device, err))
+		}
+		if len(filters) > 0 {
+			a.logger.Debug("Left clsact qdisc in place", logging.String("device", device.String()))
+			return types.Success(Unit{})
+		}
+	}
+
+	if _, err := a.runTC(ctx, fmt.Sprintf("qdisc del dev %s %s", device, qdisc.Kind)); err != nil && !isNotFound(err) {
+		return types.Failure[Unit](fmt.Errorf("failed to delete clsact qdisc of %s: %w", device, err))
+	}
+	a.logger.Info("Deleted clsact qdisc", logging.String("device", device.String()))
+	return types.Success(Unit{})
+}
+
+// AttachBPFClassifier attaches the classifier's program in direct-action mode with tc, which
+// loads the object itself. Maps are pinned by tc's own rules, so PinPath is not supported.
+func (a *ExecAdapter) AttachBPFClassifier(ctx context.Context, device tc.DeviceName, classifier BPFClassifier) error {
+	if classifier.PinPath != "" {
+		return unsupportedByExec("pinning the maps of a BPF classifier")
+	}
+	if _, err := classifier.Hook.Parent(); err != nil {
+		return err
+	}
+	if _, err := a.EnsureClsactQdisc(ctx, device, classifier.Hook); err != nil {
+		return err
+	}
+
+	// Paths are passed as single arguments, so they may hold spaces
+	args := strings.Fields(fmt.Sprintf("filter replace dev %s %s protocol all prio %d handle %d bpf da",
+		device, classifier.Hook, classifier.Priority, bpfClassifierHandle))
+	if classifier.Pinned != "" {
+		args = append(args, "pinned", classifier.Pinned)
+	} else {
+		args = append(args, "obj", classifier.Object)
+		if classifier.Section != "" {
+			args = append(args, "sec", classifier.Section)
+		}
+	}
+	if _, err := a.runner.Run(ctx, a.tc, args...); err != nil {
+		return fmt.Errorf("failed to attach BPF classifier %s to %s %s: %w", classifier.Name, device, classifier.Hook, err)
+	}
+
+	a.logger.Info("Attached BPF classifier",
+		logging.String("device", device.String()),
+		logging.String("classifier", classifier.Name),
+		logging.String("hook", string(classifier.Hook)),
+	)
+	return nil
+}
+
+// DetachBPFClassifier removes the BPF classifier with the priority from the device's hook
+func (a *ExecAdapter) DetachBPFClassifier(device tc.DeviceName, hook ClsactHook, priority uint16) types.Result[Unit] {
+	if _, err := hook.Parent(); err != nil {
+		return types.Failure[Unit](err)
+	}
+	line := fmt.Sprintf("filter del dev %s %s protocol all prio %d handle %d bpf", device, hook, priority, bpfClassifierHandle)
+	if _, err := a.runTC(context.Background(), line); err != nil && !isNotFound(err) {
+		return types.Failure[Unit](fmt.Errorf("failed to detach BPF classifier from %s %s: %w", device, hook, err))
+	}
+	return types.Success(Unit{})
+}
+
+// UpdateBPFMap sets the value of the key in the map pinned at path with bpftool
+func (a *ExecAdapter) UpdateBPFMap(path string, key, value []byte) error {
+	if len(key) == 0 || len(value) == 0 {
+		return fmt.Errorf("map entries need a key and a value")
+	}
+	args := append([]string{"map", "update", "pinned", path, "key", "hex"}, hexBytes(key)...)
+	args = append(append(args, "value", "hex"), hexBytes(value)...)
+	if _, err := a.runner.Run(context.Background(), a.bpftool, args...); err != nil {
+		return fmt.Errorf("failed to update map %s: %w", path, err)
+	}
+	return nil
+}
+
+// DeleteBPFMapEntry removes the key from the map pinned at path with bpftool. A missing key
+// is not an error.
+func (a *ExecAdapter) DeleteBPFMapEntry(path string, key []byte) types.Result[Unit] {
+	if len(key) == 0 {
+		return types.Failure[Unit](fmt.Errorf("map entries need a key"))
+	}
+	args := append([]string{"map", "delete", "pinned", path, "key", "hex"}, hexBytes(key)...)
+	if _, err := a.runner.Run(context.Background(), a.bpftool, args...); err != nil && !errors.Is(err, syscall.ENOENT) {
+		return types.Failure[Unit](fmt.Errorf("failed to delete from map %s: %w", path, err))
+	}
+	return types.Success(Unit{})
+}
+
+// hexBytes returns the bytes as the separate hex arguments bpftool takes
+func hexBytes(data []byte) []string {
+	args := make([]string, len(data))
+	for i, b := range data {
+		args[i] = fmt.Sprintf("%02x", b)
+	}
+	return args
+}
+
+// CheckPrivileges reads the privileges commands run with, on the host the runner runs them
+// on. Hosts whose privileges cannot be read are left to refuse the changes themselves.
+func (a *ExecAdapter) CheckPrivileges(ctx context.Context) error {
+	proc, err := a.runner.Run(ctx, "cat", "/proc/self/status", "/proc/self/mounts")
+	if err != nil {
+		a.logger.Debug("Failed to read privileges", logging.Error(err))
+		return nil
+	}
+	return ParsePrivileges(string(proc)).Check()
+}
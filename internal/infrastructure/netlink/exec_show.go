@@ -0,0 +1,684 @@
+package netlink
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/types"
+)
+
+// tcQdisc is a qdisc as `tc -j -s qdisc show` prints it
+type tcQdisc struct {
+	Kind       string `json:"kind"`
+	Handle     string `json:"handle"`
+	Parent     string `json:"parent"`
+	Root       bool   `json:"root"`
+	Bytes      uint64 `json:"bytes"`
+	Packets    uint64 `json:"packets"`
+	Drops      uint64 `json:"drops"`
+	Overlimits uint64 `json:"overlimits"`
+	Requeues   uint64 `json:"requeues"`
+	Backlog    uint64 `json:"backlog"`
+	Qlen       uint64 `json:"qlen"`
+	Options    struct {
+		DirectPackets uint32 `json:"direct_packets_stat"` // HTB
+		Default       string `json:"default"`             // HTB, in hexadecimal
+	} `json:"options"`
+	Xstats *tcXstats `json:"-"` // Decoded apart, so that a counter tc prints oddly costs only itself
+}
+
+// tcXstats are the qdisc-specific counters tc prints beside the common ones, for the
+// fq_codel and codel kinds
+type tcXstats struct {
+	MaxPacket      *uint32 `json:"maxpacket"` // Present when the counters are
+	DropOverlimit  uint32  `json:"drop_overlimit"`
+	ECNMark        uint32  `json:"ecn_mark"`
+	CEMark         uint32  `json:"ce_mark"`
+	NewFlowCount   uint32  `json:"new_flow_count"` // fq_codel
+	NewFlowsLen    uint32  `json:"new_flows_len"`
+	OldFlowsLen    uint32  `json:"old_flows_len"`
+	MemoryUsed     uint32  `json:"memory_used"`
+	DropOvermemory uint32  `json:"drop_overmemory"`
+	Count          uint32  `json:"count"` // codel
+	LastCount      uint32  `json:"lastcount"`
+	LDelay         uint32  `json:"ldelay"`    // Microseconds
+	DropNext       int32   `json:"drop_next"` // Microseconds
+	Dropping       bool    `json:"dropping"`
+}
+
+// fqCodel returns the counters of an fq_codel qdisc, nil when tc printed none
+func (x *tcXstats) fqCodel() *FQCodelQdiscStats {
+	if x == nil || x.MaxPacket == nil {
+		return nil
+	}
+	return &FQCodelQdiscStats{
+		MaxPacket:      *x.MaxPacket,
+		DropOverlimit:  x.DropOverlimit,
+		ECNMarks:       x.ECNMark,
+		NewFlowCount:   x.NewFlowCount,
+		NewFlows:       x.NewFlowsLen,
+		OldFlows:       x.OldFlowsLen,
+		CEMarks:        x.CEMark,
+		MemoryUsage:    x.MemoryUsed,
+		DropOvermemory: x.DropOvermemory,
+	}
+}
+
+// codel returns the counters of a CoDel qdisc, nil when tc printed none
+func (x *tcXstats) codel() *CodelQdiscStats {
+	if x == nil || x.MaxPacket == nil {
+		return nil
+	}
+	return &CodelQdiscStats{
+		MaxPacket:     *x.MaxPacket,
+		Count:         x.Count,
+		LastCount:     x.LastCount,
+		LDelay:        time.Duration(x.LDelay) * time.Microsecond,
+		DropNext:      time.Duration(x.DropNext) * time.Microsecond,
+		DropOverlimit: x.DropOverlimit,
+		ECNMarks:      x.ECNMark,
+		Dropping:      x.Dropping,
+		CEMarks:       x.CEMark,
+	}
+}
+
+// qdiscTypes are the qdisc kinds the adapters report, by tc name
+var qdiscTypes = map[string]entities.QdiscType{
+	"htb": entities.QdiscTypeHTB, "tbf": entities.QdiscTypeTBF, "prio": entities.QdiscTypePRIO,
+	"fq_codel": entities.QdiscTypeFQCODEL, "sfq": entities.QdiscTypeSFQ, "fq": entities.QdiscTypeFQ,
+	"netem": entities.QdiscTypeNETEM, "cake": entities.QdiscTypeCAKE, "red": entities.QdiscTypeRED,
+	"gred": entities.QdiscTypeGRED, "codel": entities.QdiscTypeCODEL, "ets": entities.QdiscTypeETS,
+	"taprio": entities.QdiscTypeTAPRIO, "drr": entities.QdiscTypeDRR, "qfq": entities.QdiscTypeQFQ,
+}
+
+// info converts the qdisc to the adapter's description of it
+func (q tcQdisc) info() (QdiscInfo, error) {
+	handle, err := tc.ParseHandle(q.Handle)
+	if err != nil {
+		return QdiscInfo{}, err
+	}
+	info := QdiscInfo{
+		Handle: handle,
+		Type:   qdiscTypes[q.Kind],
+		Statistics: QdiscStats{
+			BytesSent:      q.Bytes,
+			PacketsSent:    q.Packets,
+			PacketsDropped: q.Drops,
+			Overlimits:     q.Overlimits,
+			Requeues:       q.Requeues,
+		},
+	}
+	if !q.Root && q.Parent != "" {
+		parent, err := tc.ParseHandle(q.Parent)
+		if err != nil {
+			return QdiscInfo{}, err
+		}
+		info.Parent = &parent
+	}
+	if q.Kind == "htb" && q.Options.Default != "" {
+		minor, err := strconv.ParseUint(strings.TrimPrefix(q.Options.Default, "0x"), 16, 16)
+		if err != nil {
+			return QdiscInfo{}, fmt.Errorf("invalid default class %q: %w", q.Options.Default, err)
+		}
+		if minor != 0 {
+			info.DefaultClass = tc.NewHandle(handle.Major(), uint16(minor))
+		}
+	}
+	return info, nil
+}
+
+// tcFilter is a filter as `tc -j filter show` prints it
+type tcFilter struct {
+	Protocol string `json:"protocol"`
+	Priority uint16 `json:"pref"`
+	Kind     string `json:"kind"`
+	Options  *struct {
+		Handle   string `json:"fh"` // u32
+		BPFName  string `json:"bpf_name"`
+		FlowID   string `json:"flowid"`  // u32, bpf
+		ClassID  string `json:"classid"` // flower
+		HandleID string `json:"handle"`  // flower, bpf
+	} `json:"options"`
+}
+
+// tcClass is a class as `tc -s class show` prints it; classes are not printed as JSON by
+// all tc versions
+type tcClass struct {
+	Kind   string
+	Handle tc.Handle
+	Parent tc.Handle // The qdisc for classes at the root of their qdisc
+	Stats  ClassStats
+	HTB    HTBClassStats
+}
+
+// show runs a command listing the objects or state of the device
+func (a *ExecAdapter) show(ctx context.Context, device tc.DeviceName, run func(context.Context, string) ([]byte, error), line string) ([]byte, error) {
+	output, err := run(ctx, line)
+	if errors.Is(err, syscall.ENODEV) {
+		return nil, deviceNotFound(device, err)
+	}
+	return output, err
+}
+
+// showQdiscs lists the device's qdiscs with their counters
+func (a *ExecAdapter) showQdiscs(ctx context.Context, device tc.DeviceName) ([]tcQdisc, error) {
+	output, err := a.show(ctx, device, a.runTC, fmt.Sprintf("-j -s qdisc show dev %s", device))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list qdiscs: %w", err)
+	}
+	var qdiscs []tcQdisc
+	if err := decodeJSON(output, &qdiscs); err != nil {
+		return nil, fmt.Errorf("failed to list qdiscs: %w", err)
+	}
+	var xstats []tcXstats
+	if err := decodeJSON(output, &xstats); err == nil && len(xstats) == len(qdiscs) {
+		for i := range qdiscs {
+			qdiscs[i].Xstats = &xstats[i]
+		}
+	}
+	return qdiscs, nil
+}
+
+// clsactQdisc returns the device's clsact or ingress qdisc, nil if it has neither
+func (a *ExecAdapter) clsactQdisc(ctx context.Context, device tc.DeviceName) (*tcQdisc, error) {
+	qdiscs, err := a.showQdiscs(ctx, device)
+	if err != nil {
+		return nil, err
+	}
+	for i, qdisc := range qdiscs {
+		if qdisc.Kind == "clsact" || qdisc.Kind == "ingress" {
+			return &qdiscs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// showFilters lists the filters under the parent. The u32 hash tables tc prints along with
+// the filters are left out.
+func (a *ExecAdapter) showFilters(ctx context.Context, device tc.DeviceName, parent tc.Handle) ([]FilterInfo, error) {
+	output, err := a.show(ctx, device, a.runTC, fmt.Sprintf("-j filter show dev %s parent %s", device, parent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list filters: %w", err)
+	}
+	var filters []tcFilter
+	if err := decodeJSON(output, &filters); err != nil {
+		return nil, fmt.Errorf("failed to list filters: %w", err)
+	}
+
+	var result []FilterInfo
+	for _, filter := range filters {
+		options := filter.Options
+		if options == nil || filter.Kind == "u32" && !strings.Contains(options.Handle, "::") {
+			continue
+		}
+		info := FilterInfo{
+			Parent:   parent,
+			Priority: filter.Priority,
+			Protocol: protocolOf(filter.Protocol),
+		}
+		switch filter.Kind {
+		case "u32":
+			info.Handle = u32Handle(options.Handle)
+		default:
+			if id, err := strconv.ParseUint(options.HandleID, 0, 16); err == nil {
+				info.Handle = tc.NewHandle(0, uint16(id))
+			}
+		}
+		if filter.Kind == "flower" {
+			info.Kind = entities.FilterKindFlower
+		}
+		if filter.Kind == "bpf" && strings.HasPrefix(options.BPFName, "cgroup:") {
+			info.Kind = entities.FilterKindCgroup
+		}
+		for _, flowID := range []string{options.FlowID, options.ClassID} {
+			if handle, err := tc.ParseHandle(flowID); err == nil {
+				info.FlowID = handle
+			}
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// showClasses lists the device's classes with their counters
+func (a *ExecAdapter) showClasses(ctx context.Context, device tc.DeviceName) ([]tcClass, error) {
+	output, err := a.show(ctx, device, a.runTC, fmt.Sprintf("-s class show dev %s", device))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list classes: %w", err)
+	}
+	return parseClasses(output)
+}
+
+// GetQdiscs returns the device's qdiscs
+func (a *ExecAdapter) GetQdiscs(device tc.DeviceName) types.Result[[]QdiscInfo] {
+	qdiscs, err := a.showQdiscs(context.Background(), device)
+	if err != nil {
+		return types.Failure[[]QdiscInfo](err)
+	}
+
+	var result []QdiscInfo
+	for _, qdisc := range qdiscs {
+		info, err := qdisc.info()
+		if err != nil {
+			return types.Failure[[]QdiscInfo](fmt.Errorf("failed to read %s qdisc %s: %w", qdisc.Kind, qdisc.Handle, err))
+		}
+		result = append(result, info)
+	}
+	return types.Success(result)
+}
+
+// GetClasses returns the device's classes
+func (a *ExecAdapter) GetClasses(device tc.DeviceName) types.Result[[]ClassInfo] {
+	classes, err := a.showClasses(context.Background(), device)
+	if err != nil {
+		return types.Failure[[]ClassInfo](err)
+	}
+
+	var result []ClassInfo
+	for _, class := range classes {
+		result = append(result, ClassInfo{
+			Handle:     class.Handle,
+			Parent:     class.Parent,
+			Type:       qdiscTypes[class.Kind],
+			Rate:       tc.Bps(class.HTB.Rate * 8),
+			Ceil:       tc.Bps(class.HTB.Ceil * 8),
+			Statistics: class.Stats,
+		})
+	}
+	return types.Success(result)
+}
+
+// GetFilters returns the filters of the device's qdiscs, and those on the hooks of its
+// clsact qdisc
+func (a *ExecAdapter) GetFilters(device tc.DeviceName) types.Result[[]FilterInfo] {
+	ctx := context.Background()
+	qdiscs, err := a.showQdiscs(ctx, device)
+	if err != nil {
+		return types.Failure[[]FilterInfo](err)
+	}
+
+	var result []FilterInfo
+	for _, qdisc := range qdiscs {
+		var parents []tc.Handle
+		switch qdisc.Kind {
+		case "clsact":
+			ingress, _ := HookIngress.Parent()
+			egress, _ := HookEgress.Parent()
+			parents = []tc.Handle{ingress, egress}
+		case "ingress":
+			ingress, _ := HookIngress.Parent()
+			parents = []tc.Handle{ingress}
+		default:
+			handle, err := tc.ParseHandle(qdisc.Handle)
+			if err != nil || handle.Major() == 0 {
+				continue // Default qdiscs without a handle hold no filters
+			}
+			parents = []tc.Handle{handle}
+		}
+
+		for _, parent := range parents {
+			filters, err := a.showFilters(ctx, device, parent)
+			if err != nil {
+				return types.Failure[[]FilterInfo](err)
+			}
+			result = append(result, filters...)
+		}
+	}
+	return types.Success(result)
+}
+
+// GetDetailedQdiscStats returns the counters of a qdisc
+func (a *ExecAdapter) GetDetailedQdiscStats(device tc.DeviceName, handle tc.Handle) types.Result[DetailedQdiscStats] {
+	qdiscs, err := a.showQdiscs(context.Background(), device)
+	if err != nil {
+		return types.Failure[DetailedQdiscStats](err)
+	}
+
+	for _, qdisc := range qdiscs {
+		info, err := qdisc.info()
+		if err != nil || info.Handle != handle {
+			continue
+		}
+		stats := DetailedQdiscStats{
+			BasicStats:   info.Statistics,
+			QueueLength:  uint32(min(qdisc.Qlen, uint64(^uint32(0)))),    // #nosec G115 - clamped
+			Backlog:      uint32(min(qdisc.Backlog, uint64(^uint32(0)))), // #nosec G115 - clamped
+			BacklogBytes: qdisc.Backlog,
+		}
+		switch qdisc.Kind {
+		case "htb":
+			stats.HTBStats = &HTBQdiscStats{DirectPackets: qdisc.Options.DirectPackets, Version: 3}
+		case "fq_codel":
+			stats.FQCodelStats = qdisc.Xstats.fqCodel()
+		case "codel":
+			stats.CodelStats = qdisc.Xstats.codel()
+		}
+		return types.Success(stats)
+	}
+	return types.Failure[DetailedQdiscStats](fmt.Errorf("qdisc %s not found on device %s", handle, device))
+}
+
+// GetDetailedClassStats returns the counters of a class, with the lending and token state
+// of HTB classes
+func (a *ExecAdapter) GetDetailedClassStats(device tc.DeviceName, handle tc.Handle) types.Result[DetailedClassStats] {
+	classes, err := a.showClasses(context.Background(), device)
+	if err != nil {
+		return types.Failure[DetailedClassStats](err)
+	}
+
+	for _, class := range classes {
+		if class.Handle != handle {
+			continue
+		}
+		stats := DetailedClassStats{BasicStats: class.Stats}
+		if class.Kind == "htb" {
+			htb := class.HTB
+			stats.HTBStats = &htb
+		}
+		return types.Success(stats)
+	}
+	return types.Failure[DetailedClassStats](fmt.Errorf("class %s not found on device %s", handle, device))
+}
+
+// ipLink is a link as `ip -j -s -d link show` prints it
+type ipLink struct {
+	Index     int      `json:"ifindex"`
+	Name      string   `json:"ifname"`
+	Link      string   `json:"link"`
+	Master    string   `json:"master"`
+	Flags     []string `json:"flags"`
+	OperState string   `json:"operstate"`
+	LinkInfo  *struct {
+		Kind string `json:"info_kind"`
+		Data *struct {
+			ID uint16 `json:"id"` // VLAN
+		} `json:"info_data"`
+	} `json:"linkinfo"`
+	Stats *struct {
+		RX ipLinkCounters `json:"rx"`
+		TX ipLinkCounters `json:"tx"`
+	} `json:"stats64"`
+}
+
+// ipLinkCounters are the counters of a direction of a link
+type ipLinkCounters struct {
+	Bytes   uint64 `json:"bytes"`
+	Packets uint64 `json:"packets"`
+	Errors  uint64 `json:"errors"`
+	Dropped uint64 `json:"dropped"`
+}
+
+// showLinks runs ip to list links and decodes them
+func (a *ExecAdapter) showLinks(device tc.DeviceName, line string) ([]ipLink, error) {
+	output, err := a.show(context.Background(), device, a.runIP, line)
+	if err != nil {
+		return nil, err
+	}
+	var links []ipLink
+	if err := decodeJSON(output, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// showLink describes the device's link
+func (a *ExecAdapter) showLink(device tc.DeviceName) (ipLink, error) {
+	links, err := a.showLinks(device, fmt.Sprintf("-j -s -d link show dev %s", device))
+	if err != nil {
+		return ipLink{}, err
+	}
+	if len(links) != 1 {
+		return ipLink{}, deviceNotFound(device, syscall.ENODEV)
+	}
+	return links[0], nil
+}
+
+// GetLinkInfo describes the device's link. The speed of the link is not reported.
+func (a *ExecAdapter) GetLinkInfo(device tc.DeviceName) types.Result[LinkInfo] {
+	link, err := a.showLink(device)
+	if err != nil {
+		return types.Failure[LinkInfo](err)
+	}
+
+	info := LinkInfo{Name: device, Index: link.Index, Kind: "device"}
+	for _, flag := range link.Flags {
+		if flag == "UP" {
+			info.Up = link.OperState != "DOWN" && link.OperState != "LOWERLAYERDOWN"
+		}
+	}
+	if link.LinkInfo != nil && link.LinkInfo.Kind != "" {
+		info.Kind = link.LinkInfo.Kind
+	}
+	if info.IsVLAN() && link.LinkInfo.Data != nil {
+		info.VLANID = link.LinkInfo.Data.ID
+		if info.Parent, err = tc.NewDeviceName(link.Link); err != nil {
+			return types.Failure[LinkInfo](fmt.Errorf("failed to describe device %s: parent of VLAN %d: %w", device, info.VLANID, err))
+		}
+	}
+	if link.Master != "" {
+		if info.Master, err = tc.NewDeviceName(link.Master); err != nil {
+			return types.Failure[LinkInfo](fmt.Errorf("failed to describe device %s: master: %w", device, err))
+		}
+	}
+	return types.Success(info)
+}
+
+// GetBridgePorts returns the devices enslaved to the bridge
+func (a *ExecAdapter) GetBridgePorts(bridge tc.DeviceName) types.Result[[]tc.DeviceName] {
+	info := a.GetLinkInfo(bridge)
+	if info.IsFailure() {
+		return types.Failure[[]tc.DeviceName](info.Error())
+	}
+	if !info.Value().IsBridge() {
+		return types.Failure[[]tc.DeviceName](fmt.Errorf("device %s is a %s, not a bridge", bridge, info.Value().Kind))
+	}
+
+	links, err := a.showLinks(bridge, fmt.Sprintf("-j link show master %s", bridge))
+	if err != nil {
+		return types.Failure[[]tc.DeviceName](fmt.Errorf("failed to list devices: %w", err))
+	}
+	ports := make([]tc.DeviceName, 0, len(links))
+	for _, link := range links {
+		if port, err := tc.NewDeviceName(link.Name); err == nil {
+			ports = append(ports, port)
+		}
+	}
+	return types.Success(ports)
+}
+
+// GetLinkStats returns the device's counters
+func (a *ExecAdapter) GetLinkStats(device tc.DeviceName) types.Result[LinkStats] {
+	link, err := a.showLink(device)
+	if err != nil {
+		return types.Failure[LinkStats](err)
+	}
+	if link.Stats == nil {
+		return types.Success(LinkStats{})
+	}
+	rx, tx := link.Stats.RX, link.Stats.TX
+	return types.Success(LinkStats{
+		RxBytes: rx.Bytes, TxBytes: tx.Bytes,
+		RxPackets: rx.Packets, TxPackets: tx.Packets,
+		RxErrors: rx.Errors, TxErrors: tx.Errors,
+		RxDropped: rx.Dropped, TxDropped: tx.Dropped,
+	})
+}
+
+// decodeJSON decodes the JSON tc or ip printed; nothing printed decodes to nothing
+func decodeJSON(output []byte, v interface{}) error {
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("failed to decode output: %w", err)
+	}
+	return nil
+}
+
+// protocolOf returns the filter protocol of its tc name
+func protocolOf(name string) entities.Protocol {
+	switch name {
+	case "all":
+		return entities.ProtocolAll
+	case "ipv6":
+		return entities.ProtocolIPv6
+	default:
+		return entities.ProtocolIP
+	}
+}
+
+// u32Handle returns the handle of a u32 filter, printed as table:bucket:node, as the table
+// and node halves the netlink adapter reports
+func u32Handle(handle string) tc.Handle {
+	parts := strings.Split(handle, ":")
+	if len(parts) != 3 {
+		return tc.Handle{}
+	}
+	table, _ := strconv.ParseUint(parts[0], 16, 16)
+	node, _ := strconv.ParseUint(parts[2], 16, 16)
+	return tc.NewHandle(uint16(table), uint16(node)) // #nosec G115 - bounds checked by ParseUint bitSize 16
+}
+
+// parseClasses reads the classes `tc -s class show` prints:
+//
+//	class htb 1:10 parent 1:1 leaf 10: prio 1 rate 10Mbit ceil 20Mbit burst 15000b cburst 15000b
+//	 Sent 866 bytes 11 pkt (dropped 0, overlimits 0 requeues 0)
+//	 backlog 0b 0p requeues 0
+//	 lended: 11 borrowed: 0 giants: 0
+//	 tokens: 186625 ctokens: 93312
+func parseClasses(output []byte) ([]tcClass, error) {
+	var classes []tcClass
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "class" {
+			class, err := parseClassLine(fields)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read class %q: %w", scanner.Text(), err)
+			}
+			classes = append(classes, class)
+			continue
+		}
+		if len(classes) == 0 {
+			continue
+		}
+		class := &classes[len(classes)-1]
+
+		for i := 0; i+1 < len(fields); i++ {
+			value := strings.TrimRight(fields[i+1], ",")
+			switch fields[i] {
+			case "Sent":
+				class.Stats.BytesSent = parseCounter(value)
+				if i+3 < len(fields) {
+					class.Stats.PacketsSent = parseCounter(fields[i+3])
+				}
+			case "(dropped":
+				class.Stats.PacketsDropped = parseCounter(value)
+			case "overlimits":
+				class.Stats.Overlimits = parseCounter(value)
+			case "rate":
+				if rate, err := parseRate(value); err == nil {
+					class.Stats.RateBPS = rate.BitsPerSecond()
+				}
+			case "backlog":
+				class.Stats.BacklogBytes = parseSize(value)
+				if i+2 < len(fields) {
+					class.Stats.BacklogPackets = parseCounter(strings.TrimSuffix(fields[i+2], "p"))
+				}
+			case "lended:":
+				class.HTB.Lends = uint32(parseCounter(value)) // #nosec G115 - the kernel's counter is 32 bits
+			case "borrowed:":
+				class.HTB.Borrows = uint32(parseCounter(value)) // #nosec G115 - the kernel's counter is 32 bits
+			case "giants:":
+				class.HTB.Giants = uint32(parseCounter(value)) // #nosec G115 - the kernel's counter is 32 bits
+			case "tokens:":
+				class.HTB.Tokens = uint32(parseCounter(value)) // #nosec G115 - the kernel's counter is 32 bits
+			case "ctokens:":
+				class.HTB.CTokens = uint32(parseCounter(value)) // #nosec G115 - the kernel's counter is 32 bits
+			}
+		}
+	}
+	return classes, scanner.Err()
+}
+
+// parseClassLine reads the first line of a class: its kind, handle, parent and, for HTB,
+// rates
+func parseClassLine(fields []string) (tcClass, error) {
+	if len(fields) < 4 {
+		return tcClass{}, fmt.Errorf("too few fields")
+	}
+	handle, err := tc.ParseHandle(fields[2])
+	if err != nil {
+		return tcClass{}, err
+	}
+	class := tcClass{Kind: fields[1], Handle: handle, Parent: tc.NewHandle(handle.Major(), 0)}
+
+	for i := 3; i+1 < len(fields); i++ {
+		switch fields[i] {
+		case "parent":
+			if class.Parent, err = tc.ParseHandle(fields[i+1]); err != nil {
+				return tcClass{}, err
+			}
+		case "rate", "ceil":
+			rate, err := parseRate(fields[i+1])
+			if err != nil {
+				return tcClass{}, err
+			}
+			// In bytes per second, as the netlink adapter reports them
+			if fields[i] == "rate" {
+				class.HTB.Rate = rate.BitsPerSecond() / 8
+			} else {
+				class.HTB.Ceil = rate.BitsPerSecond() / 8
+			}
+		}
+	}
+	return class, nil
+}
+
+// parseCounter reads a counter, zero when it is not one; the tokens of a class in debt are
+// negative and read as zero
+func parseCounter(value string) uint64 {
+	counter, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return counter
+}
+
+// parseSize reads a size tc prints in bytes, such as 1514b, 15Kb or 2Mb
+func parseSize(value string) uint64 {
+	multiplier := 1.0
+	for _, unit := range []struct {
+		suffix string
+		bytes  float64
+	}{{"Gb", 1 << 30}, {"Mb", 1 << 20}, {"Kb", 1 << 10}, {"b", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value, multiplier = strings.TrimSuffix(value, unit.suffix), unit.bytes
+			break
+		}
+	}
+	size, err := strconv.ParseFloat(value, 64)
+	if err != nil || size < 0 {
+		return 0
+	}
+	return uint64(size * multiplier)
+}
+
+// parseRate reads a rate tc prints, such as 1200bit, 15Kbit or 10Mbit
+func parseRate(value string) (tc.Bandwidth, error) {
+	return tc.ParseBandwidth(strings.ToLower(value))
+}
@@ -0,0 +1,285 @@
+package netlink
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// fakeRunner records the commands it is given and answers them from canned output, keyed by
+// the start of the command line
+type fakeRunner struct {
+	commands []string
+	outputs  map[string]string
+	failures map[string]string // Standard error of the commands that fail
+}
+
+func (r *fakeRunner) Run(ctx context.Context, program string, args ...string) ([]byte, error) {
+	line := CommandLine(program, args)
+	r.commands = append(r.commands, line)
+	for prefix, stderr := range r.failures {
+		if strings.HasPrefix(line, prefix) {
+			return nil, &CommandError{Command: line, Stderr: stderr, Err: &exec.ExitError{}}
+		}
+	}
+	for prefix, output := range r.outputs {
+		if strings.HasPrefix(line, prefix) {
+			return []byte(output), nil
+		}
+	}
+	return nil, nil
+}
+
+const execQdiscs = `[{"kind":"htb","handle":"1:","root":true,"refcnt":2,"options":{"r2q":10,"default":"0x10","direct_packets_stat":3,"direct_qlen":1000},"bytes":516,"packets":6,"drops":2,"overlimits":1,"requeues":0,"backlog":1514,"qlen":1},` +
+	`{"kind":"clsact","handle":"ffff:","parent":"ffff:fff1","options":{},"bytes":0,"packets":0,"drops":0,"overlimits":0,"requeues":0,"backlog":0,"qlen":0}]`
+
+const execClasses = `class htb 1:1 root rate 100Mbit ceil 100Mbit burst 1600b cburst 1600b
+ Sent 1866 bytes 21 pkt (dropped 0, overlimits 0 requeues 0)
+ backlog 0b 0p requeues 0
+ lended: 0 borrowed: 0 giants: 0
+ tokens: 2000 ctokens: 2000
+
+class htb 1:10 parent 1:1 prio 1 rate 10Mbit ceil 20Mbit burst 15Kb cburst 15000b
+ Sent 866 bytes 11 pkt (dropped 4, overlimits 7 requeues 0)
+ rate 1200bit 2pps backlog 0b 0p requeues 0
+ backlog 3Kb 2p requeues 0
+ lended: 11 borrowed: 5 giants: 0
+ tokens: -186625 ctokens: 93312
+`
+
+func TestExecAdapter_AddCommands(t *testing.T) {
+	ctx := context.Background()
+	device := tc.MustNewDeviceName("eth0")
+	root := tc.NewHandle(1, 0)
+	runner := &fakeRunner{}
+	adapter := NewExecAdapterWithRunner(runner, "/usr/sbin/tc")
+
+	qdisc := entities.NewHTBQdisc(device, root, tc.NewHandle(1, 0x999))
+	require.NoError(t, adapter.AddQdisc(ctx, qdisc.Qdisc))
+
+	class := entities.NewHTBClass(device, tc.NewHandle(1, 10), root, "web", entities.Priority(1))
+	class.SetRate(tc.Mbps(10))
+	class.SetCeil(tc.Mbps(20))
+	require.NoError(t, adapter.AddClass(ctx, class))
+
+	filter := entities.NewFilter(device, root, 100, tc.NewHandle(0x800, 100))
+	filter.SetFlowID(tc.NewHandle(1, 10))
+	filter.AddMatch(entities.NewPortDestinationMatch(443))
+	require.NoError(t, adapter.AddFilter(ctx, filter))
+
+	require.Len(t, runner.commands, 3)
+	assert.Equal(t, "/usr/sbin/tc qdisc add dev eth0 root handle 1: htb default 999 r2q 10", runner.commands[0])
+	assert.Equal(t, "/usr/sbin/tc class add dev eth0 parent 1: classid 1:a htb rate 10000000bit ceil 20000000bit burst 80000 cburst 160000 quantum 1250",
+		runner.commands[1])
+	assert.Equal(t, "/usr/sbin/tc filter add dev eth0 parent 1: protocol ip prio 100 u32 match ip dport 443 0xffff flowid 1:a", runner.commands[2])
+}
+
+func TestExecAdapter_Errors(t *testing.T) {
+	ctx := context.Background()
+	device := tc.MustNewDeviceName("eth9")
+	runner := &fakeRunner{failures: map[string]string{
+		"tc qdisc add":   "Error: Exclusivity flag on, cannot modify.",
+		"tc -j -s qdisc": `Cannot find device "eth9"`,
+	}}
+	adapter := NewExecAdapterWithRunner(runner, "tc")
+
+	err := adapter.AddQdisc(ctx, entities.NewHTBQdisc(device, tc.NewHandle(1, 0), tc.NewHandle(1, 999)).Qdisc)
+	assert.True(t, errors.Is(err, tcerrors.ErrHandleConflict), err)
+
+	result := adapter.GetQdiscs(device)
+	require.True(t, result.IsFailure())
+	assert.True(t, errors.Is(result.Error(), tcerrors.ErrDeviceNotFound), result.Error())
+
+	filter := entities.NewFilter(device, tc.NewHandle(1, 0), 100, tc.NewHandle(0x800, 100))
+	filter.SetKind(entities.FilterKindCgroup)
+	assert.True(t, errors.Is(adapter.AddFilter(ctx, filter), tcerrors.ErrUnsupported))
+
+	_, err = adapter.WatchLinks(ctx, device)
+	assert.True(t, errors.Is(err, tcerrors.ErrUnsupported))
+}
+
+func TestExecAdapter_Qdiscs(t *testing.T) {
+	device := tc.MustNewDeviceName("eth0")
+	runner := &fakeRunner{outputs: map[string]string{"tc -j -s qdisc show dev eth0": execQdiscs}}
+	adapter := NewExecAdapterWithRunner(runner, "tc")
+
+	qdiscs := adapter.GetQdiscs(device)
+	require.True(t, qdiscs.IsSuccess(), qdiscs.Error())
+	require.Len(t, qdiscs.Value(), 2)
+	htb := qdiscs.Value()[0]
+	assert.Equal(t, tc.NewHandle(1, 0), htb.Handle)
+	assert.Nil(t, htb.Parent)
+	assert.Equal(t, entities.QdiscTypeHTB, htb.Type)
+	assert.Equal(t, tc.NewHandle(1, 0x10), htb.DefaultClass)
+	assert.Equal(t, QdiscStats{BytesSent: 516, PacketsSent: 6, PacketsDropped: 2, Overlimits: 1}, htb.Statistics)
+
+	stats := adapter.GetDetailedQdiscStats(device, tc.NewHandle(1, 0))
+	require.True(t, stats.IsSuccess(), stats.Error())
+	assert.Equal(t, uint32(1), stats.Value().QueueLength)
+	assert.Equal(t, uint64(1514), stats.Value().BacklogBytes)
+	require.NotNil(t, stats.Value().HTBStats)
+	assert.Equal(t, uint32(3), stats.Value().HTBStats.DirectPackets)
+
+	assert.True(t, adapter.GetDetailedQdiscStats(device, tc.NewHandle(2, 0)).IsFailure())
+
+	result := adapter.DeleteQdisc(device, tc.NewHandle(1, 0))
+	require.True(t, result.IsSuccess(), result.Error())
+	assert.Equal(t, "tc qdisc del dev eth0 root handle 1:", runner.commands[len(runner.commands)-1])
+
+	result = adapter.DeleteQdisc(device, tc.NewHandle(5, 0))
+	assert.True(t, errors.Is(result.Error(), tcerrors.ErrNotFound))
+}
+
+func TestExecAdapter_QdiscXstats(t *testing.T) {
+	device := tc.MustNewDeviceName("eth0")
+	qdiscs := `[{"kind":"fq_codel","handle":"10:","parent":"1:10","options":{"limit":10240,"flows":1024},` +
+		`"bytes":3028,"packets":2,"drops":0,"overlimits":0,"requeues":0,"backlog":0,"qlen":0,` +
+		`"maxpacket":1514,"drop_overlimit":3,"new_flow_count":40,"ecn_mark":2,"new_flows_len":1,"old_flows_len":4,"memory_used":2048},` +
+		`{"kind":"codel","handle":"20:","parent":"1:20","options":{"limit":1000,"ecn":true},` +
+		`"bytes":1514,"packets":1,"drops":5,"overlimits":0,"requeues":0,"backlog":0,"qlen":0,` +
+		`"count":5,"lastcount":3,"ldelay":7500,"dropping":true,"drop_next":1200,"maxpacket":1514,"ecn_mark":0,"drop_overlimit":0}]`
+	runner := &fakeRunner{outputs: map[string]string{"tc -j -s qdisc show dev eth0": qdiscs}}
+	adapter := NewExecAdapterWithRunner(runner, "tc")
+
+	fqCodel := adapter.GetDetailedQdiscStats(device, tc.NewHandle(0x10, 0))
+	require.True(t, fqCodel.IsSuccess(), fqCodel.Error())
+	assert.Equal(t, &FQCodelQdiscStats{MaxPacket: 1514, DropOverlimit: 3, ECNMarks: 2, NewFlowCount: 40, NewFlows: 1, OldFlows: 4, MemoryUsage: 2048},
+		fqCodel.Value().FQCodelStats)
+
+	codel := adapter.GetDetailedQdiscStats(device, tc.NewHandle(0x20, 0))
+	require.True(t, codel.IsSuccess(), codel.Error())
+	assert.Equal(t, &CodelQdiscStats{MaxPacket: 1514, Count: 5, LastCount: 3, LDelay: 7500 * time.Microsecond, DropNext: 1200 * time.Microsecond, Dropping: true},
+		codel.Value().CodelStats)
+
+	// Other kinds have none of the counters
+	htb := NewExecAdapterWithRunner(&fakeRunner{outputs: map[string]string{"tc -j -s qdisc show dev eth0": execQdiscs}}, "tc").
+		GetDetailedQdiscStats(device, tc.NewHandle(1, 0))
+	require.True(t, htb.IsSuccess(), htb.Error())
+	assert.Nil(t, htb.Value().FQCodelStats)
+}
+
+func TestExecAdapter_Classes(t *testing.T) {
+	device := tc.MustNewDeviceName("eth0")
+	runner := &fakeRunner{outputs: map[string]string{"tc -s class show dev eth0": execClasses}}
+	adapter := NewExecAdapterWithRunner(runner, "tc")
+
+	classes := adapter.GetClasses(device)
+	require.True(t, classes.IsSuccess(), classes.Error())
+	require.Len(t, classes.Value(), 2)
+	assert.Equal(t, tc.NewHandle(1, 0), classes.Value()[0].Parent)
+	assert.Equal(t, tc.NewHandle(1, 1), classes.Value()[1].Parent)
+	assert.Equal(t, tc.NewHandle(1, 0x10), classes.Value()[1].Handle) // tc prints minor numbers in hex
+	assert.Equal(t, tc.Mbps(10), classes.Value()[1].Rate)
+	assert.Equal(t, tc.Mbps(20), classes.Value()[1].Ceil)
+	assert.Equal(t, ClassStats{
+		BytesSent:      866,
+		PacketsSent:    11,
+		PacketsDropped: 4,
+		Overlimits:     7,
+		RateBPS:        1200,
+		BacklogBytes:   3072,
+		BacklogPackets: 2,
+	}, classes.Value()[1].Statistics)
+
+	stats := adapter.GetDetailedClassStats(device, tc.NewHandle(1, 0x10))
+	require.True(t, stats.IsSuccess(), stats.Error())
+	require.NotNil(t, stats.Value().HTBStats)
+	htb := stats.Value().HTBStats
+	assert.Equal(t, uint32(11), htb.Lends)
+	assert.Equal(t, uint32(5), htb.Borrows)
+	assert.Equal(t, uint32(0), htb.Tokens)
+	assert.Equal(t, uint32(93312), htb.CTokens)
+	assert.Equal(t, uint64(10_000_000/8), htb.Rate)
+	assert.Equal(t, uint64(20_000_000/8), htb.Ceil)
+}
+
+func TestExecAdapter_Filters(t *testing.T) {
+	device := tc.MustNewDeviceName("eth0")
+	runner := &fakeRunner{outputs: map[string]string{
+		"tc -j -s qdisc show dev eth0": execQdiscs,
+		"tc -j filter show dev eth0 parent 1:": `[{"protocol":"ip","pref":100,"kind":"u32","chain":0},` +
+			`{"protocol":"ip","pref":100,"kind":"u32","chain":0,"options":{"fh":"800:","ht_divisor":1}},` +
+			`{"protocol":"ip","pref":100,"kind":"u32","chain":0,"options":{"fh":"800::800","order":2048,"key_ht":"800","bkt":"0","flowid":"1:10"}}]`,
+		"tc -j filter show dev eth0 parent ffff:fff3": `[{"protocol":"all","pref":10,"kind":"flower","chain":0},` +
+			`{"protocol":"all","pref":10,"kind":"flower","chain":0,"options":{"handle":"0x1","classid":"1:20"}}]`,
+	}}
+	adapter := NewExecAdapterWithRunner(runner, "tc")
+
+	filters := adapter.GetFilters(device)
+	require.True(t, filters.IsSuccess(), filters.Error())
+	assert.Equal(t, []FilterInfo{
+		{Parent: tc.NewHandle(1, 0), Priority: 100, Handle: tc.NewHandle(0x800, 0x800), Protocol: entities.ProtocolIP, FlowID: tc.NewHandle(1, 0x10)},
+		{Parent: tc.NewHandle(0xffff, 0xfff3), Priority: 10, Handle: tc.NewHandle(0, 1), Kind: entities.FilterKindFlower, Protocol: entities.ProtocolAll, FlowID: tc.NewHandle(1, 0x20)},
+	}, filters.Value())
+}
+
+func TestExecAdapter_Links(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{
+		"/sbin/ip -j -s -d link show dev eth0.100": `[{"ifindex":7,"ifname":"eth0.100","link":"eth0","flags":["BROADCAST","UP","LOWER_UP"],"master":"br0","operstate":"UP",` +
+			`"linkinfo":{"info_kind":"vlan","info_data":{"protocol":"802.1Q","id":100}},"stats64":{"rx":{"bytes":10,"packets":1,"errors":0,"dropped":2},"tx":{"bytes":20,"packets":3,"errors":1,"dropped":0}}}]`,
+		"/sbin/ip -j -s -d link show dev br0": `[{"ifindex":5,"ifname":"br0","flags":["BROADCAST"],"operstate":"DOWN","linkinfo":{"info_kind":"bridge"}}]`,
+		"/sbin/ip -j link show master br0":    `[{"ifindex":7,"ifname":"eth0.100"},{"ifindex":8,"ifname":"eth1"}]`,
+	}}
+	adapter := NewExecAdapterWithRunner(runner, "/sbin/tc")
+
+	info := adapter.GetLinkInfo(tc.MustNewDeviceName("eth0.100"))
+	require.True(t, info.IsSuccess(), info.Error())
+	assert.Equal(t, LinkInfo{
+		Name:   tc.MustNewDeviceName("eth0.100"),
+		Index:  7,
+		Kind:   "vlan",
+		Parent: tc.MustNewDeviceName("eth0"),
+		VLANID: 100,
+		Master: tc.MustNewDeviceName("br0"),
+		Up:     true,
+	}, info.Value())
+	assert.Equal(t, "/sbin/ip -j -s -d link show dev eth0.100", runner.commands[0])
+
+	stats := adapter.GetLinkStats(tc.MustNewDeviceName("eth0.100"))
+	require.True(t, stats.IsSuccess(), stats.Error())
+	assert.Equal(t, LinkStats{RxBytes: 10, TxBytes: 20, RxPackets: 1, TxPackets: 3, TxErrors: 1, RxDropped: 2}, stats.Value())
+
+	ports := adapter.GetBridgePorts(tc.MustNewDeviceName("br0"))
+	require.True(t, ports.IsSuccess(), ports.Error())
+	assert.Equal(t, []tc.DeviceName{tc.MustNewDeviceName("eth0.100"), tc.MustNewDeviceName("eth1")}, ports.Value())
+
+	assert.True(t, adapter.GetBridgePorts(tc.MustNewDeviceName("eth0.100")).IsFailure())
+}
+
+func TestExecAdapter_IngressRedirect(t *testing.T) {
+	ctx := context.Background()
+	runner := &fakeRunner{
+		outputs:  map[string]string{"tc -j -s qdisc show dev eth0": `[{"kind":"noqueue","handle":"0:","root":true}]`},
+		failures: map[string]string{"ip link add": "RTNETLINK answers: File exists"},
+	}
+	adapter := NewExecAdapterWithRunner(runner, "tc")
+
+	require.NoError(t, adapter.AddIngressRedirect(ctx, tc.MustNewDeviceName("eth0"), tc.MustNewDeviceName("ifb0")))
+	assert.Equal(t, []string{
+		"ip link add ifb0 type ifb",
+		"ip link set dev ifb0 up",
+		"tc -j -s qdisc show dev eth0",
+		"tc qdisc add dev eth0 clsact",
+		"tc filter replace dev eth0 ingress protocol all prio 1 handle 800::800 u32 match u32 0 0 action mirred egress redirect dev ifb0",
+	}, runner.commands)
+}
+
+func TestCommandError(t *testing.T) {
+	err := &CommandError{Command: "tc qdisc del dev eth0 root", Stderr: "RTNETLINK answers: No such file or directory", Err: &exec.ExitError{}}
+	assert.Equal(t, "tc qdisc del dev eth0 root: RTNETLINK answers: No such file or directory", err.Error())
+	assert.True(t, isNotFound(err))
+	assert.True(t, errors.Is(tcerrors.FromErrno(err, "qdisc 1:0", "failed"), tcerrors.ErrNotFound))
+
+	err = &CommandError{Command: "tc qdisc add dev eth0 root fq_codel", Stderr: "Error: Specified qdisc kind is unknown."}
+	assert.True(t, errors.Is(tcerrors.FromErrno(err, "qdisc 1:0", "failed"), tcerrors.ErrUnsupported))
+}
@@ -0,0 +1,68 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+// DRR and QFQ class option attributes from linux/pkt_sched.h
+const (
+	tcaDRRQuantum = 1
+
+	tcaQFQWeight = 1
+	tcaQFQLmax   = 2
+)
+
+// drrClassOptions encodes the options of a DRR class; a zero quantum is left to the kernel,
+// which uses the device MTU
+func drrClassOptions(class *entities.DRRClass) *nl.RtAttr {
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	if class.Quantum() > 0 {
+		options.AddRtAttr(tcaDRRQuantum, nl.Uint32Attr(class.Quantum()))
+	}
+	return options
+}
+
+// qfqClassOptions encodes the options of a QFQ class; zero values are left to the kernel
+func qfqClassOptions(class *entities.QFQClass) *nl.RtAttr {
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	if class.Weight() > 0 {
+		options.AddRtAttr(tcaQFQWeight, nl.Uint32Attr(class.Weight()))
+	}
+	if class.Lmax() > 0 {
+		options.AddRtAttr(tcaQFQLmax, nl.Uint32Attr(class.Lmax()))
+	}
+	return options
+}
+
+// addFairQueueingClass sends an RTM_NEWTCLASS request for a DRR or QFQ class, which the
+// netlink library cannot serialise
+func addFairQueueingClass(class *entities.Class, kind string, options *nl.RtAttr) error {
+	link, err := netlink.LinkByName(class.ID().Device().String())
+	if err != nil {
+		return deviceNotFound(class.ID().Device(), err)
+	}
+
+	req := nl.NewNetlinkRequest(syscall.RTM_NEWTCLASS, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(link.Attrs().Index), // #nosec G115 - kernel interface indexes fit in int32
+		Handle:  class.Handle().ToUint32(),
+		Parent:  class.Parent().ToUint32(),
+	})
+	req.AddData(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated(kind)))
+	req.AddData(options)
+
+	if _, err := req.Execute(syscall.NETLINK_ROUTE, 0); err != nil {
+		return fmt.Errorf("failed to add %s class: %w", kind, err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestFairQueueingClassOptions(t *testing.T) {
+	device := tc.MustNewDeviceName("eth0")
+	root := tc.NewHandle(1, 0)
+
+	t.Run("drr quantum", func(t *testing.T) {
+		class := entities.NewDRRClass(device, tc.NewHandle(1, 1), root, "bulk")
+		class.SetQuantum(4500)
+		attrs := parseOptions(t, drrClassOptions(class).Serialize()[syscall.SizeofRtAttr:])
+		require.Len(t, attrs[tcaDRRQuantum], 1)
+		assert.Equal(t, uint32(4500), nl.NativeEndian().Uint32(attrs[tcaDRRQuantum][0].Value))
+
+		// The kernel picks the MTU when the quantum is left out
+		empty := entities.NewDRRClass(device, tc.NewHandle(1, 2), root, "default")
+		assert.Len(t, drrClassOptions(empty).Serialize(), syscall.SizeofRtAttr)
+	})
+
+	t.Run("qfq weight and lmax", func(t *testing.T) {
+		class := entities.NewQFQClass(device, tc.NewHandle(1, 1), root, "video")
+		class.SetWeight(8)
+		class.SetLmax(9000)
+		attrs := parseOptions(t, qfqClassOptions(class).Serialize()[syscall.SizeofRtAttr:])
+		assert.Equal(t, uint32(8), nl.NativeEndian().Uint32(attrs[tcaQFQWeight][0].Value))
+		assert.Equal(t, uint32(9000), nl.NativeEndian().Uint32(attrs[tcaQFQLmax][0].Value))
+	})
+}
@@ -0,0 +1,106 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"fmt"
+	"math"
+
+	nl "github.com/vishvananda/netlink"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+// buildFilterActions converts a filter's action chain to netlink actions. Every action but
+// a redirect, drop or pass pipes the packet on, so the filter's class still applies once the
+// chain ends.
+func buildFilterActions(actions []entities.Action) ([]nl.Action, error) {
+	result := make([]nl.Action, 0, len(actions))
+
+	for _, action := range actions {
+		switch a := action.(type) {
+		case *entities.MarkAction:
+			mark, mask := a.Mark(), a.Mask()
+			skbedit := nl.NewSkbEditAction()
+			skbedit.Mark = &mark
+			skbedit.Mask = &mask
+			result = append(result, skbedit)
+
+		case *entities.PriorityAction:
+			priority := a.Priority().ToUint32()
+			skbedit := nl.NewSkbEditAction()
+			skbedit.Priority = &priority
+			result = append(result, skbedit)
+
+		case *entities.QueueMappingAction:
+			queue := a.Queue()
+			skbedit := nl.NewSkbEditAction()
+			skbedit.QueueMapping = &queue
+			result = append(result, skbedit)
+
+		case *entities.GactAction:
+			verdict := nl.TC_ACT_OK
+			if a.Type() == entities.ActionTypeDrop {
+				verdict = nl.TC_ACT_SHOT
+			}
+			result = append(result, &nl.GenericAction{ActionAttrs: nl.ActionAttrs{Action: verdict}})
+
+		case *entities.PoliceAction:
+			police := nl.NewPoliceAction()
+			// The kernel takes the rate in bytes per second as a 32-bit value
+			police.Rate = uint32(min(a.Rate().BitsPerSecond()/8, math.MaxUint32)) // #nosec G115 - clamped above
+			police.Burst = a.Burst()
+			police.NotExceedAction = nl.TC_POLICE_PIPE
+			switch a.Exceed() {
+			case entities.PoliceExceedReclassify:
+				police.ExceedAction = nl.TC_POLICE_RECLASSIFY
+			default:
+				police.ExceedAction = nl.TC_POLICE_SHOT
+			}
+			result = append(result, police)
+
+		case *entities.MirredAction:
+			link, err := nl.LinkByName(a.Target().String())
+			if err != nil {
+				return nil, fmt.Errorf("failed to find %s target %s: %w", a.Type(), a.Target(), err)
+			}
+			mirred := nl.NewMirredAction(link.Attrs().Index)
+			if a.Type() == entities.ActionTypeMirror {
+				mirred.MirredAction = nl.TCA_EGRESS_MIRROR
+				mirred.Attrs().Action = nl.TC_ACT_PIPE
+			}
+			result = append(result, mirred)
+
+		default:
+			return nil, fmt.Errorf("unsupported filter action %v", action)
+		}
+	}
+
+	return result, nil
+}
+
+// sumRedirectedTraffic adds up the traffic that filters classifying into the class redirected
+// to other devices. Redirected packets never reach the class queue, so the class counters
+// alone miss them.
+func sumRedirectedTraffic(filters []nl.Filter, classID uint32) (bytes, packets uint64) {
+	for _, filter := range filters {
+		u32, ok := filter.(*nl.U32)
+		if !ok || u32.ClassId != classID {
+			continue
+		}
+
+		for _, action := range u32.Actions {
+			mirred, ok := action.(*nl.MirredAction)
+			if !ok || mirred.MirredAction != nl.TCA_EGRESS_REDIR {
+				continue
+			}
+			if stats := mirred.Attrs().Statistics; stats != nil && stats.Basic != nil {
+				bytes += stats.Basic.Bytes
+				packets += uint64(stats.Basic.Packets)
+			}
+		}
+	}
+
+	return bytes, packets
+}
@@ -0,0 +1,64 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	nl "github.com/vishvananda/netlink"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestBuildFilterActions(t *testing.T) {
+	actions, err := buildFilterActions([]entities.Action{
+		entities.NewPriorityAction(tc.NewHandle(1, 10)),
+		entities.NewQueueMappingAction(3),
+		entities.NewDropAction(),
+	})
+	require.NoError(t, err)
+	require.Len(t, actions, 3)
+
+	priority, ok := actions[0].(*nl.SkbEditAction)
+	require.True(t, ok)
+	assert.Equal(t, nl.MakeHandle(1, 10), *priority.Priority)
+	assert.Equal(t, nl.TC_ACT_PIPE, priority.Attrs().Action)
+
+	queue, ok := actions[1].(*nl.SkbEditAction)
+	require.True(t, ok)
+	assert.Equal(t, uint16(3), *queue.QueueMapping)
+
+	drop, ok := actions[2].(*nl.GenericAction)
+	require.True(t, ok)
+	assert.Equal(t, nl.TC_ACT_SHOT, drop.Attrs().Action)
+
+	actions, err = buildFilterActions([]entities.Action{entities.NewPassAction()})
+	require.NoError(t, err)
+	assert.Equal(t, nl.TC_ACT_OK, actions[0].Attrs().Action)
+}
+
+func TestSumRedirectedTraffic(t *testing.T) {
+	classID := nl.MakeHandle(1, 10)
+
+	redirect := nl.NewMirredAction(7)
+	redirect.Statistics = &nl.ActionStatistic{Basic: &nl.GnetStatsBasic{Bytes: 3000, Packets: 2}}
+	mirror := nl.NewMirredAction(8)
+	mirror.MirredAction = nl.TCA_EGRESS_MIRROR
+	mirror.Statistics = &nl.ActionStatistic{Basic: &nl.GnetStatsBasic{Bytes: 9000, Packets: 6}}
+	otherClass := nl.NewMirredAction(7)
+	otherClass.Statistics = &nl.ActionStatistic{Basic: &nl.GnetStatsBasic{Bytes: 500, Packets: 1}}
+
+	filters := []nl.Filter{
+		&nl.U32{ClassId: classID, Actions: []nl.Action{nl.NewSkbEditAction(), redirect}},
+		&nl.U32{ClassId: classID, Actions: []nl.Action{mirror}},
+		&nl.U32{ClassId: nl.MakeHandle(1, 20), Actions: []nl.Action{otherClass}},
+	}
+
+	bytes, packets := sumRedirectedTraffic(filters, classID)
+	assert.Equal(t, uint64(3000), bytes)
+	assert.Equal(t, uint64(2), packets)
+}
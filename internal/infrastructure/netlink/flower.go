@@ -0,0 +1,204 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"encoding/binary"
+	"fmt"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// addFlowerFilter sends an RTM_NEWTFILTER request for a flower filter. The netlink library's
+// Flower type can neither match VLAN priorities nor carry actions, so the request is built here.
+func addFlowerFilter(linkIndex int, filter *entities.Filter) error {
+	protocol := flowerProtocol(filter.Matches())
+
+	req := nl.NewNetlinkRequest(syscall.RTM_NEWTFILTER, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(linkIndex), // #nosec G115 - kernel interface indexes fit in int32
+		Parent:  netlink.MakeHandle(filter.Parent().Major(), filter.Parent().Minor()),
+		Info:    netlink.MakeHandle(filter.Priority(), nl.Swap16(protocol)),
+	})
+	req.AddData(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated("flower")))
+
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	if err := encodeFlowerOptions(options, filter, protocol); err != nil {
+		return err
+	}
+	req.AddData(options)
+
+	_, err := req.Execute(syscall.NETLINK_ROUTE, 0)
+	return err
+}
+
+// flowerProtocol returns the ethertype a flower filter is installed for: 802.1Q when it
+// matches VLAN tags, IP or IPv6 when it matches network or transport headers, all otherwise
+func flowerProtocol(matches []entities.Match) uint16 {
+	if flowerMatchesVLAN(matches) {
+		return syscall.ETH_P_8021Q
+	}
+	if ethType, ok := flowerNetworkProtocol(matches); ok {
+		return ethType
+	}
+	return syscall.ETH_P_ALL
+}
+
+// flowerMatchesVLAN reports whether any match needs the VLAN tag
+func flowerMatchesVLAN(matches []entities.Match) bool {
+	for _, match := range matches {
+		if match.Type() == entities.MatchTypeVLANID || match.Type() == entities.MatchTypeVLANPriority {
+			return true
+		}
+	}
+	return false
+}
+
+// flowerNetworkProtocol returns the ethertype of the network header the matches inspect,
+// if any
+func flowerNetworkProtocol(matches []entities.Match) (uint16, bool) {
+	found := false
+	for _, match := range matches {
+		switch m := match.(type) {
+		case *entities.IPMatch:
+			if m.Network().IP.To4() == nil {
+				return syscall.ETH_P_IPV6, true
+			}
+			found = true
+		case *entities.PortMatch, *entities.PortRangeMatch, *entities.ProtocolMatch:
+			found = true
+		}
+	}
+	return syscall.ETH_P_IP, found
+}
+
+// encodeFlowerOptions adds the flow keys, target class and actions of a flower filter
+func encodeFlowerOptions(options *nl.RtAttr, filter *entities.Filter, protocol uint16) error {
+	matches := filter.Matches()
+
+	if protocol != syscall.ETH_P_ALL {
+		options.AddRtAttr(nl.TCA_FLOWER_KEY_ETH_TYPE, be16(protocol))
+	}
+	if ethType, ok := flowerNetworkProtocol(matches); ok && protocol == syscall.ETH_P_8021Q {
+		options.AddRtAttr(nl.TCA_FLOWER_KEY_VLAN_ETH_TYPE, be16(ethType))
+	}
+
+	var ipProto entities.TransportProtocol
+	for _, match := range matches {
+		if m, ok := match.(*entities.ProtocolMatch); ok {
+			ipProto = m.Protocol()
+			options.AddRtAttr(nl.TCA_FLOWER_KEY_IP_PROTO, []byte{uint8(ipProto)})
+		}
+	}
+
+	for _, match := range matches {
+		switch m := match.(type) {
+		case *entities.MACMatch:
+			key, mask := nl.TCA_FLOWER_KEY_ETH_SRC, nl.TCA_FLOWER_KEY_ETH_SRC_MASK
+			if m.Type() == entities.MatchTypeMACDestination {
+				key, mask = nl.TCA_FLOWER_KEY_ETH_DST, nl.TCA_FLOWER_KEY_ETH_DST_MASK
+			}
+			options.AddRtAttr(key, []byte(m.Address()))
+			options.AddRtAttr(mask, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+		case *entities.VLANIDMatch:
+			options.AddRtAttr(nl.TCA_FLOWER_KEY_VLAN_ID, nl.Uint16Attr(m.ID()))
+		case *entities.VLANPriorityMatch:
+			options.AddRtAttr(nl.TCA_FLOWER_KEY_VLAN_PRIO, []byte{m.Priority()})
+		case *entities.IPMatch:
+			encodeFlowerIP(options, m)
+		case *entities.PortMatch:
+			key, err := flowerPortKey(ipProto, m.Type() == entities.MatchTypePortSource)
+			if err != nil {
+				return err
+			}
+			options.AddRtAttr(key, be16(m.Port()))
+		case *entities.PortRangeMatch:
+			minKey, maxKey := nl.TCA_FLOWER_KEY_PORT_DST_MIN, nl.TCA_FLOWER_KEY_PORT_DST_MAX
+			if m.IsSource() {
+				minKey, maxKey = nl.TCA_FLOWER_KEY_PORT_SRC_MIN, nl.TCA_FLOWER_KEY_PORT_SRC_MAX
+			}
+			options.AddRtAttr(minKey, be16(m.StartPort()))
+			options.AddRtAttr(maxKey, be16(m.EndPort()))
+		case *entities.ProtocolMatch:
+			// Encoded above, as the port keys depend on it
+		default:
+			return fmt.Errorf("flower cannot match %s", match)
+		}
+	}
+
+	// Filters on a clsact hook have no class
+	if flowID := filter.FlowID(); flowID != (tc.Handle{}) {
+		options.AddRtAttr(nl.TCA_FLOWER_CLASSID, nl.Uint32Attr(netlink.MakeHandle(flowID.Major(), flowID.Minor())))
+	}
+	options.AddRtAttr(nl.TCA_FLOWER_FLAGS, nl.Uint32Attr(0))
+
+	if actions := filter.Actions(); len(actions) > 0 {
+		if err := encodeFilterActions(options.AddRtAttr(nl.TCA_FLOWER_ACT, nil), actions, protocol); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeFlowerIP adds an IPv4 or IPv6 address key and its mask
+func encodeFlowerIP(options *nl.RtAttr, match *entities.IPMatch) {
+	source := match.Type() == entities.MatchTypeIPSource
+	ip, mask := match.Network().IP, match.Network().Mask
+
+	if ip4 := ip.To4(); ip4 != nil {
+		if len(mask) == 16 {
+			mask = mask[12:]
+		}
+		key, maskKey := nl.TCA_FLOWER_KEY_IPV4_DST, nl.TCA_FLOWER_KEY_IPV4_DST_MASK
+		if source {
+			key, maskKey = nl.TCA_FLOWER_KEY_IPV4_SRC, nl.TCA_FLOWER_KEY_IPV4_SRC_MASK
+		}
+		options.AddRtAttr(key, []byte(ip4))
+		options.AddRtAttr(maskKey, []byte(mask))
+		return
+	}
+
+	key, maskKey := nl.TCA_FLOWER_KEY_IPV6_DST, nl.TCA_FLOWER_KEY_IPV6_DST_MASK
+	if source {
+		key, maskKey = nl.TCA_FLOWER_KEY_IPV6_SRC, nl.TCA_FLOWER_KEY_IPV6_SRC_MASK
+	}
+	options.AddRtAttr(key, []byte(ip.To16()))
+	options.AddRtAttr(maskKey, []byte(mask))
+}
+
+// flowerPortKey returns the key of a single port for the transport protocol
+func flowerPortKey(protocol entities.TransportProtocol, source bool) (int, error) {
+	switch protocol {
+	case entities.TransportProtocolTCP:
+		if source {
+			return nl.TCA_FLOWER_KEY_TCP_SRC, nil
+		}
+		return nl.TCA_FLOWER_KEY_TCP_DST, nil
+	case entities.TransportProtocolUDP:
+		if source {
+			return nl.TCA_FLOWER_KEY_UDP_SRC, nil
+		}
+		return nl.TCA_FLOWER_KEY_UDP_DST, nil
+	case entities.TransportProtocolSCTP:
+		if source {
+			return nl.TCA_FLOWER_KEY_SCTP_SRC, nil
+		}
+		return nl.TCA_FLOWER_KEY_SCTP_DST, nil
+	default:
+		return 0, fmt.Errorf("flower port matches require a tcp, udp or sctp protocol match")
+	}
+}
+
+// be16 encodes a value in network byte order
+func be16(value uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, value)
+	return b
+}
@@ -0,0 +1,77 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestEncodeFlowerOptions(t *testing.T) {
+	filter := entities.NewFilter(tc.MustNewDeviceName("br0"), tc.NewHandle(1, 0), 100, tc.NewHandle(0x800, 100))
+	filter.SetKind(entities.FilterKindFlower)
+	filter.SetFlowID(tc.NewHandle(1, 10))
+
+	mac, err := entities.NewMACDestinationMatch("02:00:00:00:00:01")
+	require.NoError(t, err)
+	vlan, err := entities.NewVLANIDMatch(100)
+	require.NoError(t, err)
+	priority, err := entities.NewVLANPriorityMatch(5)
+	require.NoError(t, err)
+	source, err := entities.NewIPSourceMatch("10.0.0.0/8")
+	require.NoError(t, err)
+	for _, match := range []entities.Match{
+		mac, vlan, priority, source,
+		entities.NewProtocolMatch(entities.TransportProtocolTCP),
+		entities.NewPortDestinationMatch(443),
+		entities.NewPortSourceRangeMatch(1024, 65535),
+	} {
+		filter.AddMatch(match)
+	}
+
+	protocol := flowerProtocol(filter.Matches())
+	assert.Equal(t, uint16(syscall.ETH_P_8021Q), protocol)
+
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	require.NoError(t, encodeFlowerOptions(options, filter, protocol))
+
+	attrs, err := nl.ParseRouteAttrAsMap(options.Serialize()[4:])
+	require.NoError(t, err)
+	value := func(key int) []byte {
+		attr, ok := attrs[uint16(key)]
+		require.True(t, ok, "missing attribute %d", key)
+		return attr.Value
+	}
+
+	assert.Equal(t, []byte{0x81, 0x00}, value(nl.TCA_FLOWER_KEY_ETH_TYPE))
+	assert.Equal(t, []byte{0x08, 0x00}, value(nl.TCA_FLOWER_KEY_VLAN_ETH_TYPE))
+	assert.Equal(t, nl.Uint16Attr(100), value(nl.TCA_FLOWER_KEY_VLAN_ID))
+	assert.Equal(t, []byte{5}, value(nl.TCA_FLOWER_KEY_VLAN_PRIO))
+	assert.Equal(t, []byte{0x02, 0, 0, 0, 0, 0x01}, value(nl.TCA_FLOWER_KEY_ETH_DST))
+	assert.Equal(t, []byte{10, 0, 0, 0}, value(nl.TCA_FLOWER_KEY_IPV4_SRC))
+	assert.Equal(t, []byte{255, 0, 0, 0}, value(nl.TCA_FLOWER_KEY_IPV4_SRC_MASK))
+	assert.Equal(t, []byte{6}, value(nl.TCA_FLOWER_KEY_IP_PROTO))
+	assert.Equal(t, []byte{0x01, 0xbb}, value(nl.TCA_FLOWER_KEY_TCP_DST))
+	assert.Equal(t, []byte{0x04, 0x00}, value(nl.TCA_FLOWER_KEY_PORT_SRC_MIN))
+	assert.Equal(t, []byte{0xff, 0xff}, value(nl.TCA_FLOWER_KEY_PORT_SRC_MAX))
+	assert.Equal(t, nl.Uint32Attr(0x0001000a), value(nl.TCA_FLOWER_CLASSID))
+}
+
+func TestFlowerProtocol(t *testing.T) {
+	mac, err := entities.NewMACSourceMatch("02:00:00:00:00:01")
+	require.NoError(t, err)
+	ipv6, err := entities.NewIPDestinationMatch("2001:db8::/32")
+	require.NoError(t, err)
+
+	assert.Equal(t, uint16(syscall.ETH_P_ALL), flowerProtocol([]entities.Match{mac}))
+	assert.Equal(t, uint16(syscall.ETH_P_IP), flowerProtocol([]entities.Match{mac, entities.NewProtocolMatch(entities.TransportProtocolUDP)}))
+	assert.Equal(t, uint16(syscall.ETH_P_IPV6), flowerProtocol([]entities.Match{ipv6}))
+}
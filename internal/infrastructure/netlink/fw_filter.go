@@ -26,7 +26,7 @@ func (a *RealNetlinkAdapter) AddFwFilter(device tc.DeviceName, config FwFilterCo
 	// Get the network link
 	link, err := nl.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[Unit](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[Unit](deviceNotFound(device, err))
 	}
 
 	// Create FW filter
@@ -66,7 +66,7 @@ func (a *RealNetlinkAdapter) DeleteFwFilter(device tc.DeviceName, parent tc.Hand
 	// Get the network link
 	link, err := nl.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[Unit](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[Unit](deviceNotFound(device, err))
 	}
 
 	// Create filter to delete
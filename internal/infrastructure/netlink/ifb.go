@@ -0,0 +1,142 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/types"
+)
+
+// ifbModulePath exists while the ifb kernel module is loaded
+const ifbModulePath = "/sys/module/ifb"
+
+// AddIngressRedirect creates the IFB device if needed and redirects all traffic received on
+// the device to it, through a match-all u32 filter with a mirred action on the ingress hook
+// of the clsact qdisc, which is added if missing. Traffic can then be shaped by the qdiscs
+// of the IFB device. Adding it again is harmless.
+func (a *RealNetlinkAdapter) AddIngressRedirect(ctx context.Context, device, ifb tc.DeviceName) error {
+	link, err := netlink.LinkByName(device.String())
+	if err != nil {
+		return deviceNotFound(device, err)
+	}
+
+	ifbLink, err := a.ensureIFBDevice(ifb)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ensureClsactQdisc(link, HookIngress); err != nil {
+		return fmt.Errorf("failed to add clsact qdisc to %s: %w", device, err)
+	}
+
+	filter := &netlink.U32{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    netlink.HANDLE_MIN_INGRESS,
+			Priority:  ingressRedirectPriority,
+			Handle:    ingressRedirectHandle,
+			Protocol:  syscall.ETH_P_ALL,
+		},
+		// A single all-zero key matches every packet
+		Sel: &netlink.TcU32Sel{
+			Flags: netlink.TC_U32_TERMINAL,
+			Nkeys: 1,
+			Keys:  []netlink.TcU32Key{{Mask: 0, Val: 0, Off: 0}},
+		},
+		Actions: []netlink.Action{netlink.NewMirredAction(ifbLink.Attrs().Index)},
+	}
+	if err := netlink.FilterReplace(filter); err != nil {
+		return fmt.Errorf("failed to redirect ingress of %s to %s: %w", device, ifb, err)
+	}
+
+	a.logger.Info("Redirected ingress traffic",
+		logging.String("device", device.String()),
+		logging.String("ifb", ifb.String()),
+	)
+	return nil
+}
+
+// DeleteIngressRedirect removes the redirect filter from the device, and the clsact qdisc
+// unless other filters remain on it, and deletes the IFB device with any qdiscs shaping it.
+// Parts already gone are skipped.
+func (a *RealNetlinkAdapter) DeleteIngressRedirect(device, ifb tc.DeviceName) types.Result[Unit] {
+	var errs []error
+
+	if link, err := netlink.LinkByName(device.String()); err == nil {
+		redirect := &netlink.U32{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: link.Attrs().Index,
+				Parent:    netlink.HANDLE_MIN_INGRESS,
+				Priority:  ingressRedirectPriority,
+				Handle:    ingressRedirectHandle,
+				Protocol:  syscall.ETH_P_ALL,
+			},
+		}
+		if err := netlink.FilterDel(redirect); err != nil && !isNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete ingress redirect of %s: %w", device, err))
+		} else if _, err := deleteUnusedClsactQdisc(link); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete clsact qdisc of %s: %w", device, err))
+		}
+	}
+
+	if ifbLink, err := netlink.LinkByName(ifb.String()); err == nil {
+		if ifbLink.Type() != "ifb" {
+			errs = append(errs, fmt.Errorf("refusing to delete %s: not an IFB device", ifb))
+		} else if err := netlink.LinkDel(ifbLink); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete IFB device %s: %w", ifb, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return types.Failure[Unit](err)
+	}
+
+	a.logger.Info("Removed ingress redirect",
+		logging.String("device", device.String()),
+		logging.String("ifb", ifb.String()),
+	)
+	return types.Success(Unit{})
+}
+
+// ensureIFBDevice returns the IFB device, creating it when missing, and brings it up
+func (a *RealNetlinkAdapter) ensureIFBDevice(ifb tc.DeviceName) (netlink.Link, error) {
+	link, err := netlink.LinkByName(ifb.String())
+	if err == nil && link.Type() != "ifb" {
+		return nil, fmt.Errorf("device %s exists and is not an IFB device", ifb)
+	}
+
+	if err != nil {
+		if _, statErr := os.Stat(ifbModulePath); statErr != nil {
+			// Creating the device makes the kernel load the module when it can
+			a.logger.Debug("ifb module not loaded, relying on module autoloading",
+				logging.String("ifb", ifb.String()),
+			)
+		}
+
+		if err := netlink.LinkAdd(&netlink.Ifb{LinkAttrs: netlink.LinkAttrs{Name: ifb.String()}}); err != nil {
+			if errors.Is(err, syscall.EOPNOTSUPP) {
+				return nil, fmt.Errorf("failed to create IFB device %s: the ifb kernel module is not available, load it with 'modprobe ifb': %w", ifb, err)
+			}
+			return nil, fmt.Errorf("failed to create IFB device %s: %w", ifb, err)
+		}
+		if link, err = netlink.LinkByName(ifb.String()); err != nil {
+			return nil, fmt.Errorf("failed to find IFB device %s: %w", ifb, err)
+		}
+		a.logger.Info("Created IFB device", logging.String("ifb", ifb.String()))
+	}
+
+	if err := netlink.LinkSetUp(link); err != nil {
+		return nil, fmt.Errorf("failed to bring up IFB device %s: %w", ifb, err)
+	}
+	return link, nil
+}
@@ -2,6 +2,7 @@ package netlink
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/rng999/traffic-control-go/internal/domain/entities"
 	"github.com/rng999/traffic-control-go/pkg/tc"
@@ -17,6 +18,7 @@ type Adapter interface {
 
 	// Class operations
 	AddClass(ctx context.Context, class interface{}) error
+	ChangeClass(ctx context.Context, class interface{}) error // In place, keeping the class's counters
 	DeleteClass(device tc.DeviceName, handle tc.Handle) types.Result[Unit]
 	GetClasses(device tc.DeviceName) types.Result[[]ClassInfo]
 
@@ -29,8 +31,103 @@ type Adapter interface {
 	GetDetailedQdiscStats(device tc.DeviceName, handle tc.Handle) types.Result[DetailedQdiscStats]
 	GetDetailedClassStats(device tc.DeviceName, handle tc.Handle) types.Result[DetailedClassStats]
 	GetLinkStats(device tc.DeviceName) types.Result[LinkStats]
+
+	// Event operations
+	WatchQdiscDeletions(ctx context.Context, device tc.DeviceName) (<-chan QdiscDeletion, error)
+	WatchLinks(ctx context.Context, device tc.DeviceName) (<-chan LinkEvent, error)
+
+	// Link operations
+	GetLinkInfo(device tc.DeviceName) types.Result[LinkInfo]
+	GetBridgePorts(bridge tc.DeviceName) types.Result[[]tc.DeviceName]
+
+	// Ingress redirect operations
+	AddIngressRedirect(ctx context.Context, device, ifb tc.DeviceName) error
+	DeleteIngressRedirect(device, ifb tc.DeviceName) types.Result[Unit]
+
+	// Clsact operations; filters are added to a hook with AddFilter, using the hook's parent
+	EnsureClsactQdisc(ctx context.Context, device tc.DeviceName, hook ClsactHook) (bool, error)
+	DeleteClsactQdisc(device tc.DeviceName) types.Result[Unit]
+
+	// BPF classifier operations
+	AttachBPFClassifier(ctx context.Context, device tc.DeviceName, classifier BPFClassifier) error
+	DetachBPFClassifier(device tc.DeviceName, hook ClsactHook, priority uint16) types.Result[Unit]
+	UpdateBPFMap(path string, key, value []byte) error
+	DeleteBPFMapEntry(path string, key []byte) types.Result[Unit]
+
+	// Privilege operations; reads need no privilege
+	CheckPrivileges(ctx context.Context) error // Fails with a permission error when changes would be refused
+}
+
+// QdiscDeletion reports a qdisc removed from a device, by this process or any other
+type QdiscDeletion struct {
+	Handle tc.Handle
+	Root   bool // The root qdisc was removed, taking all classes and filters with it
+}
+
+// LinkEvent reports the state of a device's link after a change. Devices are followed by
+// name, so a device deleted and created again is reported with its new index.
+type LinkEvent struct {
+	Index   int  // Interface index
+	Up      bool // Administratively up and not reporting its carrier down
+	Removed bool // The device was deleted
+}
+
+// LinkInfo describes a device's link: what kind of device it is and how it is stacked on
+// other devices
+type LinkInfo struct {
+	Name   tc.DeviceName
+	Index  int
+	Kind   string        // Link type, such as "device", "vlan", "bridge" or "veth"
+	Parent tc.DeviceName // The device a VLAN is stacked on; empty for other kinds
+	VLANID uint16        // The VLAN ID of a VLAN device
+	Master tc.DeviceName // The bridge or bond the device is a port of; empty if none
+	Up     bool
+	Speed  tc.Bandwidth // Negotiated link speed; zero when the driver does not report one
+}
+
+// IsVLAN reports whether the link is a VLAN sub-interface
+func (l LinkInfo) IsVLAN() bool {
+	return l.Kind == "vlan"
+}
+
+// IsBridge reports whether the link is a bridge
+func (l LinkInfo) IsBridge() bool {
+	return l.Kind == "bridge"
+}
+
+// ClsactHook is one of the two hooks of the clsact qdisc
+type ClsactHook string
+
+// Hooks of the clsact qdisc
+const (
+	HookIngress ClsactHook = "ingress" // Traffic received by the device, before any qdisc
+	HookEgress  ClsactHook = "egress"  // Traffic sent by the device, before its root qdisc
+)
+
+// Parent returns the filter parent of the hook, ffff:fff2 for ingress and ffff:fff3 for
+// egress; the ingress parent also serves a plain ingress qdisc
+func (h ClsactHook) Parent() (tc.Handle, error) {
+	switch h {
+	case HookIngress:
+		return tc.NewHandle(0xffff, 0xfff2), nil
+	case HookEgress:
+		return tc.NewHandle(0xffff, 0xfff3), nil
+	default:
+		return tc.Handle{}, fmt.Errorf("unknown clsact hook %q", h)
+	}
 }
 
+// bpfClassifierHandle is the filter handle of BPF classifiers, so that attaching one again
+// replaces it
+const bpfClassifierHandle = 1
+
+// ingressRedirectPriority and ingressRedirectHandle identify the redirect filter, so that
+// adding it again replaces it instead of stacking a second copy
+const (
+	ingressRedirectPriority = 1
+	ingressRedirectHandle   = 0x80000800 // 800::800, the first entry of the default u32 table
+)
+
 // Unit represents an empty value (like void)
 type Unit struct{}
 
@@ -44,19 +141,20 @@ type QdiscConfig struct {
 
 // QdiscInfo represents information about an existing qdisc
 type QdiscInfo struct {
-	Handle     tc.Handle
-	Parent     *tc.Handle
-	Type       entities.QdiscType
-	Statistics QdiscStats
+	Handle       tc.Handle
+	Parent       *tc.Handle
+	Type         entities.QdiscType
+	DefaultClass tc.Handle // HTB: class of unclassified traffic, zero when it is sent unshaped
+	Statistics   QdiscStats
 }
 
 // QdiscStats represents qdisc statistics
 type QdiscStats struct {
-	BytesSent    uint64
-	PacketsSent  uint64
-	BytesDropped uint64
-	Overlimits   uint64
-	Requeues     uint64
+	BytesSent      uint64
+	PacketsSent    uint64
+	PacketsDropped uint64
+	Overlimits     uint64
+	Requeues       uint64
 }
 
 // ClassConfig represents configuration for creating a class
@@ -72,6 +170,8 @@ type ClassInfo struct {
 	Handle     tc.Handle
 	Parent     tc.Handle
 	Type       entities.QdiscType
+	Rate       tc.Bandwidth // HTB: guaranteed rate
+	Ceil       tc.Bandwidth // HTB: maximum rate
 	Statistics ClassStats
 }
 
@@ -79,7 +179,7 @@ type ClassInfo struct {
 type ClassStats struct {
 	BytesSent      uint64
 	PacketsSent    uint64
-	BytesDropped   uint64
+	PacketsDropped uint64
 	Overlimits     uint64
 	RateBPS        uint64 // Current rate in bits per second
 	BacklogBytes   uint64
@@ -94,6 +194,7 @@ type FilterConfig struct {
 	Protocol entities.Protocol
 	FlowID   tc.Handle
 	Matches  []FilterMatch
+	Actions  []entities.Action
 }
 
 // FilterMatch represents a filter match configuration
@@ -107,9 +208,11 @@ type FilterInfo struct {
 	Parent   tc.Handle
 	Priority uint16
 	Handle   tc.Handle
+	Kind     entities.FilterKind
 	Protocol entities.Protocol
 	FlowID   tc.Handle
 	Matches  []FilterMatch
+	Actions  []entities.Action
 }
 
 // LinkStats represents network interface statistics
@@ -0,0 +1,275 @@
+package netlink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// OperationKind identifies the kind of a journaled netlink operation
+type OperationKind int
+
+const (
+	OperationAddQdisc OperationKind = iota
+	OperationAddClass
+	OperationAddFilter
+	OperationChangeClass
+	OperationDeleteQdisc
+	OperationDeleteClass
+	OperationDeleteFilter
+)
+
+// String returns the string representation of the operation kind
+func (k OperationKind) String() string {
+	switch k {
+	case OperationAddQdisc:
+		return "add_qdisc"
+	case OperationAddClass:
+		return "add_class"
+	case OperationAddFilter:
+		return "add_filter"
+	case OperationChangeClass:
+		return "change_class"
+	case OperationDeleteQdisc:
+		return "delete_qdisc"
+	case OperationDeleteClass:
+		return "delete_class"
+	case OperationDeleteFilter:
+		return "delete_filter"
+	default:
+		return "unknown"
+	}
+}
+
+// Operation is a successfully applied netlink change that can be undone
+type Operation struct {
+	Kind     OperationKind
+	Device   tc.DeviceName
+	Handle   tc.Handle
+	Parent   tc.Handle   // Filters only
+	Priority uint16      // Filters only
+	Previous interface{} // Changed classes only: the class as it was before the change
+	Created  interface{} // Deletions only: the event that created the object, nil when unknown
+}
+
+// Restorer re-creates a deleted object from the event that created it
+type Restorer func(ctx context.Context, created interface{}) error
+
+// JournalingAdapter wraps an Adapter and, while a device is recording, keeps a journal of
+// every successful add, class change and deletion on that device so that a partially applied
+// configuration can be rolled back. Each device has its own journal, so that transactions on
+// different devices neither block nor undo each other. Operations are bounded by the
+// deadline and operation timeout of their context.
+type JournalingAdapter struct {
+	Adapter
+	mu       sync.Mutex
+	journals map[tc.DeviceName][]Operation // Of the devices recording
+	restorer Restorer
+	logger   logging.Logger
+}
+
+// NewJournalingAdapter creates a journaling wrapper around the given adapter
+func NewJournalingAdapter(adapter Adapter) *JournalingAdapter {
+	return &JournalingAdapter{
+		Adapter:  adapter,
+		journals: make(map[tc.DeviceName][]Operation),
+		logger:   logging.WithComponent(logging.ComponentNetlink),
+	}
+}
+
+// SetRestorer sets how a rollback re-creates the objects deleted while recording
+func (j *JournalingAdapter) SetRestorer(restorer Restorer) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.restorer = restorer
+}
+
+// Begin starts recording the operations on a device
+func (j *JournalingAdapter) Begin(device tc.DeviceName) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, recording := j.journals[device]; recording {
+		return fmt.Errorf("netlink journal of %s is already recording", device)
+	}
+
+	j.journals[device] = []Operation{}
+	return nil
+}
+
+// Commit stops recording the operations on a device and forgets its journal
+func (j *JournalingAdapter) Commit(device tc.DeviceName) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	delete(j.journals, device)
+}
+
+// Operations returns a copy of the operations journaled on a device in the order they were
+// applied
+func (j *JournalingAdapter) Operations(device tc.DeviceName) []Operation {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	result := make([]Operation, len(j.journals[device]))
+	copy(result, j.journals[device])
+	return result
+}
+
+// Rollback stops recording the operations on a device and undoes every one journaled in
+// reverse order. Deleted objects are re-created from their creation events; an object deleted
+// without one cannot be. All operations are attempted; failures are joined into the returned
+// error.
+func (j *JournalingAdapter) Rollback(device tc.DeviceName) error {
+	j.mu.Lock()
+	operations := j.journals[device]
+	restorer := j.restorer
+	delete(j.journals, device)
+	j.mu.Unlock()
+
+	var errs []error
+	for i := len(operations) - 1; i >= 0; i-- {
+		op := operations[i]
+
+		var err error
+		switch op.Kind {
+		case OperationAddFilter:
+			err = j.Adapter.DeleteFilter(op.Device, op.Parent, op.Priority, op.Handle).Error()
+		case OperationAddClass:
+			err = j.Adapter.DeleteClass(op.Device, op.Handle).Error()
+		case OperationAddQdisc:
+			err = j.Adapter.DeleteQdisc(op.Device, op.Handle).Error()
+		case OperationChangeClass:
+			err = j.Adapter.ChangeClass(context.Background(), op.Previous)
+		case OperationDeleteQdisc, OperationDeleteClass, OperationDeleteFilter:
+			if op.Created == nil || restorer == nil {
+				err = fmt.Errorf("it was not created by this library and cannot be re-created")
+			} else {
+				err = restorer(context.Background(), op.Created)
+			}
+		}
+
+		if err != nil {
+			j.logger.Error("Failed to roll back netlink operation",
+				logging.String("operation", op.Kind.String()),
+				logging.String("device", op.Device.String()),
+				logging.String("handle", op.Handle.String()),
+				logging.Error(err),
+			)
+			errs = append(errs, fmt.Errorf("undo %s %s: %w", op.Kind, op.Handle, err))
+		}
+	}
+
+	j.logger.Info("Rolled back netlink operations",
+		logging.String("device", device.String()),
+		logging.Int("operations", len(operations)),
+		logging.Int("failures", len(errs)),
+	)
+
+	return errors.Join(errs...)
+}
+
+// record appends an operation to the journal of its device if the device is recording
+func (j *JournalingAdapter) record(op Operation) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if operations, recording := j.journals[op.Device]; recording {
+		j.journals[op.Device] = append(operations, op)
+	}
+}
+
+// AddQdisc adds a qdisc and journals it on success
+func (j *JournalingAdapter) AddQdisc(ctx context.Context, qdisc *entities.Qdisc) error {
+	if err := RunOperation(ctx, func() error { return j.Adapter.AddQdisc(ctx, qdisc) }); err != nil {
+		return err
+	}
+
+	j.record(Operation{Kind: OperationAddQdisc, Device: qdisc.Device(), Handle: qdisc.Handle()})
+	return nil
+}
+
+// AddClass adds a class and journals it on success
+func (j *JournalingAdapter) AddClass(ctx context.Context, class interface{}) error {
+	if err := RunOperation(ctx, func() error { return j.Adapter.AddClass(ctx, class) }); err != nil {
+		return err
+	}
+
+	switch c := class.(type) {
+	case *entities.HTBClass:
+		j.record(Operation{Kind: OperationAddClass, Device: c.ID().Device(), Handle: c.Handle()})
+	case *entities.DRRClass:
+		j.record(Operation{Kind: OperationAddClass, Device: c.ID().Device(), Handle: c.Handle()})
+	case *entities.QFQClass:
+		j.record(Operation{Kind: OperationAddClass, Device: c.ID().Device(), Handle: c.Handle()})
+	case *entities.Class:
+		j.record(Operation{Kind: OperationAddClass, Device: c.ID().Device(), Handle: c.Handle()})
+	}
+	return nil
+}
+
+// ChangeClassFrom changes an HTB class in place and journals it on success, so that a
+// rollback changes it back to the previous class
+func (j *JournalingAdapter) ChangeClassFrom(ctx context.Context, previous, class *entities.HTBClass) error {
+	if err := RunOperation(ctx, func() error { return j.Adapter.ChangeClass(ctx, class) }); err != nil {
+		return err
+	}
+
+	j.record(Operation{Kind: OperationChangeClass, Device: class.ID().Device(), Handle: class.Handle(), Previous: previous})
+	return nil
+}
+
+// AddFilter adds a filter and journals it on success
+func (j *JournalingAdapter) AddFilter(ctx context.Context, filter *entities.Filter) error {
+	if err := RunOperation(ctx, func() error { return j.Adapter.AddFilter(ctx, filter) }); err != nil {
+		return err
+	}
+
+	j.record(Operation{
+		Kind:     OperationAddFilter,
+		Device:   filter.ID().Device(),
+		Handle:   filter.Handle(),
+		Parent:   filter.Parent(),
+		Priority: filter.Priority(),
+	})
+	return nil
+}
+
+// DeleteQdiscCreatedBy deletes a qdisc and journals it on success with the event that
+// created it, so that a rollback re-creates it
+func (j *JournalingAdapter) DeleteQdiscCreatedBy(ctx context.Context, device tc.DeviceName, handle tc.Handle, created interface{}) error {
+	if err := RunOperation(ctx, func() error { return j.Adapter.DeleteQdisc(device, handle).Error() }); err != nil {
+		return err
+	}
+
+	j.record(Operation{Kind: OperationDeleteQdisc, Device: device, Handle: handle, Created: created})
+	return nil
+}
+
+// DeleteClassCreatedBy deletes a class and journals it on success with the event that
+// created it, so that a rollback re-creates it
+func (j *JournalingAdapter) DeleteClassCreatedBy(ctx context.Context, device tc.DeviceName, handle tc.Handle, created interface{}) error {
+	if err := RunOperation(ctx, func() error { return j.Adapter.DeleteClass(device, handle).Error() }); err != nil {
+		return err
+	}
+
+	j.record(Operation{Kind: OperationDeleteClass, Device: device, Handle: handle, Created: created})
+	return nil
+}
+
+// DeleteFilterCreatedBy deletes a filter and journals it on success with the event that
+// created it, so that a rollback re-creates it
+func (j *JournalingAdapter) DeleteFilterCreatedBy(ctx context.Context, device tc.DeviceName, parent tc.Handle, priority uint16, handle tc.Handle, created interface{}) error {
+	if err := RunOperation(ctx, func() error { return j.Adapter.DeleteFilter(device, parent, priority, handle).Error() }); err != nil {
+		return err
+	}
+
+	j.record(Operation{Kind: OperationDeleteFilter, Device: device, Handle: handle, Parent: parent, Priority: priority, Created: created})
+	return nil
+}
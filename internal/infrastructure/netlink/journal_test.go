@@ -0,0 +1,131 @@
+package netlink
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestJournalingAdapter_Rollback(t *testing.T) {
+	ctx := context.Background()
+	device, _ := tc.NewDeviceName("eth0")
+	root := tc.NewHandle(1, 0)
+	classHandle := tc.NewHandle(1, 10)
+
+	mock := NewMockAdapter()
+	journal := NewJournalingAdapter(mock)
+	require.NoError(t, journal.Begin(device))
+
+	qdisc := entities.NewHTBQdisc(device, root, tc.NewHandle(1, 999))
+	require.NoError(t, journal.AddQdisc(ctx, qdisc.Qdisc))
+
+	class := entities.NewHTBClass(device, classHandle, root, "web", entities.Priority(1))
+	class.SetRate(tc.Mbps(10))
+	class.SetCeil(tc.Mbps(20))
+	require.NoError(t, journal.AddClass(ctx, class))
+
+	filter := entities.NewFilter(device, root, 100, tc.NewHandle(0x800, 100))
+	filter.SetFlowID(classHandle)
+	require.NoError(t, journal.AddFilter(ctx, filter))
+
+	ops := journal.Operations(device)
+	require.Len(t, ops, 3)
+	assert.Equal(t, OperationAddQdisc, ops[0].Kind)
+	assert.Equal(t, OperationAddClass, ops[1].Kind)
+	assert.Equal(t, OperationAddFilter, ops[2].Kind)
+
+	require.NoError(t, journal.Rollback(device))
+
+	assert.Empty(t, journal.Operations(device))
+	assert.Empty(t, mock.GetQdiscs(device).Value())
+	assert.Empty(t, mock.GetClasses(device).Value())
+	assert.Empty(t, mock.GetFilters(device).Value())
+}
+
+func TestJournalingAdapter_RecordsOnlyWhileActive(t *testing.T) {
+	ctx := context.Background()
+	device, _ := tc.NewDeviceName("eth0")
+
+	journal := NewJournalingAdapter(NewMockAdapter())
+
+	qdisc := entities.NewHTBQdisc(device, tc.NewHandle(1, 0), tc.NewHandle(1, 999))
+	require.NoError(t, journal.AddQdisc(ctx, qdisc.Qdisc))
+	assert.Empty(t, journal.Operations(device))
+
+	require.NoError(t, journal.Begin(device))
+	assert.Error(t, journal.Begin(device), "nested transactions are not supported")
+
+	other := entities.NewHTBQdisc(device, tc.NewHandle(2, 0), tc.NewHandle(2, 999))
+	require.NoError(t, journal.AddQdisc(ctx, other.Qdisc))
+	assert.Len(t, journal.Operations(device), 1)
+
+	journal.Commit(device)
+	assert.Empty(t, journal.Operations(device))
+}
+
+func TestJournalingAdapter_JournalsPerDevice(t *testing.T) {
+	ctx := context.Background()
+	eth0, _ := tc.NewDeviceName("eth0")
+	eth1, _ := tc.NewDeviceName("eth1")
+
+	mock := NewMockAdapter()
+	journal := NewJournalingAdapter(mock)
+	require.NoError(t, journal.Begin(eth0))
+	require.NoError(t, journal.Begin(eth1), "transactions on different devices run side by side")
+
+	for _, device := range []tc.DeviceName{eth0, eth1} {
+		qdisc := entities.NewHTBQdisc(device, tc.NewHandle(1, 0), tc.NewHandle(1, 999))
+		require.NoError(t, journal.AddQdisc(ctx, qdisc.Qdisc))
+	}
+	require.Len(t, journal.Operations(eth0), 1)
+	require.Len(t, journal.Operations(eth1), 1)
+
+	// Rolling back one device leaves the other's changes and journal alone
+	require.NoError(t, journal.Rollback(eth0))
+	assert.Empty(t, mock.GetQdiscs(eth0).Value())
+	assert.Len(t, mock.GetQdiscs(eth1).Value(), 1)
+	assert.Len(t, journal.Operations(eth1), 1)
+
+	journal.Commit(eth1)
+	assert.Empty(t, journal.Operations(eth1))
+	assert.Len(t, mock.GetQdiscs(eth1).Value(), 1)
+}
+
+func TestJournalingAdapter_RollbackRestoresChangedClass(t *testing.T) {
+	ctx := context.Background()
+	device, _ := tc.NewDeviceName("eth0")
+	root := tc.NewHandle(1, 0)
+	classHandle := tc.NewHandle(1, 10)
+
+	mock := NewMockAdapter()
+	journal := NewJournalingAdapter(mock)
+
+	previous := entities.NewHTBClass(device, classHandle, root, "web", entities.Priority(1))
+	previous.SetRate(tc.Mbps(10))
+	previous.SetCeil(tc.Mbps(20))
+	require.NoError(t, journal.AddClass(ctx, previous))
+	mock.SetClassStatistics(device, classHandle, ClassStats{BytesSent: 1500})
+
+	require.NoError(t, journal.Begin(device))
+	changed := entities.NewHTBClass(device, classHandle, root, "web", entities.Priority(1))
+	changed.SetRate(tc.Mbps(10))
+	changed.SetCeil(tc.Mbps(80))
+	require.NoError(t, journal.ChangeClassFrom(ctx, previous, changed))
+
+	ops := journal.Operations(device)
+	require.Len(t, ops, 1)
+	assert.Equal(t, OperationChangeClass, ops[0].Kind)
+
+	require.NoError(t, journal.Rollback(device))
+
+	// The class is changed back rather than deleted, keeping its statistics
+	classes := mock.GetClasses(device).Value()
+	require.Len(t, classes, 1)
+	assert.Equal(t, uint64(1500), classes[0].Statistics.BytesSent)
+	assert.Equal(t, []tc.Handle{classHandle, classHandle}, mock.ChangedClasses(device))
+}
@@ -0,0 +1,128 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/types"
+)
+
+// GetLinkInfo describes the device's link, with the names of the devices it is stacked on
+func (a *RealNetlinkAdapter) GetLinkInfo(device tc.DeviceName) types.Result[LinkInfo] {
+	link, err := netlink.LinkByName(device.String())
+	if err != nil {
+		return types.Failure[LinkInfo](deviceNotFound(device, err))
+	}
+
+	info, err := linkInfoOf(link, linkNameByIndex)
+	if err != nil {
+		return types.Failure[LinkInfo](fmt.Errorf("failed to describe device %s: %w", device, err))
+	}
+	info.Speed = readLinkSpeed(filepath.Join(sysfsNetPath, device.String(), "speed"))
+	return types.Success(info)
+}
+
+// GetBridgePorts returns the devices enslaved to the bridge, in the kernel's order
+func (a *RealNetlinkAdapter) GetBridgePorts(bridge tc.DeviceName) types.Result[[]tc.DeviceName] {
+	link, err := netlink.LinkByName(bridge.String())
+	if err != nil {
+		return types.Failure[[]tc.DeviceName](deviceNotFound(bridge, err))
+	}
+	if link.Type() != "bridge" {
+		return types.Failure[[]tc.DeviceName](fmt.Errorf("device %s is a %s, not a bridge", bridge, link.Type()))
+	}
+
+	links, err := netlink.LinkList()
+	if err != nil {
+		return types.Failure[[]tc.DeviceName](fmt.Errorf("failed to list devices: %w", err))
+	}
+	return types.Success(bridgePorts(links, link.Attrs().Index))
+}
+
+// linkInfoOf describes a link, looking up the names of its parent and master by index
+func linkInfoOf(link netlink.Link, nameOf func(index int) (string, error)) (LinkInfo, error) {
+	attrs := link.Attrs()
+	name, err := tc.NewDeviceName(attrs.Name)
+	if err != nil {
+		return LinkInfo{}, err
+	}
+
+	info := LinkInfo{
+		Name:  name,
+		Index: attrs.Index,
+		Kind:  link.Type(),
+		Up:    linkUp(attrs),
+	}
+
+	if vlan, ok := link.(*netlink.Vlan); ok {
+		info.VLANID = uint16(vlan.VlanId)
+		if info.Parent, err = deviceByIndex(attrs.ParentIndex, nameOf); err != nil {
+			return LinkInfo{}, fmt.Errorf("parent of VLAN %d: %w", vlan.VlanId, err)
+		}
+	}
+	if attrs.MasterIndex != 0 {
+		if info.Master, err = deviceByIndex(attrs.MasterIndex, nameOf); err != nil {
+			return LinkInfo{}, fmt.Errorf("master: %w", err)
+		}
+	}
+	return info, nil
+}
+
+// bridgePorts picks the links enslaved to the bridge with the given index
+func bridgePorts(links []netlink.Link, bridgeIndex int) []tc.DeviceName {
+	ports := make([]tc.DeviceName, 0)
+	for _, link := range links {
+		attrs := link.Attrs()
+		if attrs.MasterIndex != bridgeIndex {
+			continue
+		}
+		if port, err := tc.NewDeviceName(attrs.Name); err == nil {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// deviceByIndex names the device with the interface index
+func deviceByIndex(index int, nameOf func(index int) (string, error)) (tc.DeviceName, error) {
+	name, err := nameOf(index)
+	if err != nil {
+		return tc.DeviceName{}, err
+	}
+	return tc.NewDeviceName(name)
+}
+
+// sysfsNetPath is where the kernel describes network devices
+const sysfsNetPath = "/sys/class/net"
+
+// readLinkSpeed reads the speed a driver reports in sysfs, in Mbit/s. Virtual devices,
+// and NICs without a link, report no speed or -1, read as zero.
+func readLinkSpeed(path string) tc.Bandwidth {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return tc.Bandwidth{}
+	}
+	mbps, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || mbps <= 0 {
+		return tc.Bandwidth{}
+	}
+	return tc.Mbps(float64(mbps))
+}
+
+// linkNameByIndex looks up a device's name in the current network namespace
+func linkNameByIndex(index int) (string, error) {
+	link, err := netlink.LinkByIndex(index)
+	if err != nil {
+		return "", fmt.Errorf("failed to find device with index %d: %w", index, err)
+	}
+	return link.Attrs().Name, nil
+}
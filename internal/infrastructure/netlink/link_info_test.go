@@ -0,0 +1,85 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func TestLinkInfoOf(t *testing.T) {
+	names := map[int]string{2: "eth0", 5: "br0"}
+	nameOf := func(index int) (string, error) {
+		if name, ok := names[index]; ok {
+			return name, nil
+		}
+		return "", fmt.Errorf("no device with index %d", index)
+	}
+
+	vlan := &netlink.Vlan{
+		LinkAttrs: netlink.LinkAttrs{Name: "eth0.100", Index: 7, ParentIndex: 2, MasterIndex: 5, Flags: net.FlagUp, OperState: netlink.OperUp},
+		VlanId:    100,
+	}
+	info, err := linkInfoOf(vlan, nameOf)
+	require.NoError(t, err)
+	assert.Equal(t, LinkInfo{
+		Name:   tc.MustNewDeviceName("eth0.100"),
+		Index:  7,
+		Kind:   "vlan",
+		Parent: tc.MustNewDeviceName("eth0"),
+		VLANID: 100,
+		Master: tc.MustNewDeviceName("br0"),
+		Up:     true,
+	}, info)
+	assert.True(t, info.IsVLAN())
+
+	bridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "br0", Index: 5}}
+	info, err = linkInfoOf(bridge, nameOf)
+	require.NoError(t, err)
+	assert.True(t, info.IsBridge())
+	assert.False(t, info.Up)
+	assert.Equal(t, tc.DeviceName{}, info.Parent)
+
+	vlan.ParentIndex = 9
+	_, err = linkInfoOf(vlan, nameOf)
+	assert.Error(t, err)
+}
+
+func TestBridgePorts(t *testing.T) {
+	links := []netlink.Link{
+		&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "br0", Index: 5}},
+		&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "veth1", Index: 6, MasterIndex: 5}},
+		&netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "eth1", Index: 3}},
+		&netlink.Vlan{LinkAttrs: netlink.LinkAttrs{Name: "eth0.100", Index: 7, MasterIndex: 5}},
+		&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "veth2", Index: 8, MasterIndex: 9}},
+	}
+
+	assert.Equal(t, []tc.DeviceName{
+		tc.MustNewDeviceName("veth1"),
+		tc.MustNewDeviceName("eth0.100"),
+	}, bridgePorts(links, 5))
+	assert.Empty(t, bridgePorts(links, 4))
+}
+
+func TestReadLinkSpeed(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	assert.Equal(t, tc.Gbps(1), readLinkSpeed(write("nic", "1000\n")))
+	assert.Equal(t, tc.Bandwidth{}, readLinkSpeed(write("nolink", "-1\n")), "a NIC without a link reports -1")
+	assert.Equal(t, tc.Bandwidth{}, readLinkSpeed(filepath.Join(dir, "missing")))
+}
@@ -0,0 +1,96 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// WatchLinks reports changes of the device's link state until the context is cancelled,
+// using the kernel's link multicast group. The device need not exist when watching starts.
+// The channel is closed when watching stops.
+func (a *RealNetlinkAdapter) WatchLinks(ctx context.Context, device tc.DeviceName) (<-chan LinkEvent, error) {
+	updates := make(chan netlink.LinkUpdate)
+	done := make(chan struct{})
+	err := netlink.LinkSubscribeWithOptions(updates, done, netlink.LinkSubscribeOptions{
+		ErrorCallback: func(err error) {
+			if ctx.Err() == nil {
+				a.logger.Error("Link event subscription failed",
+					logging.String("device", device.String()),
+					logging.Error(err),
+				)
+			}
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to link events: %w", err)
+	}
+
+	events := make(chan LinkEvent)
+	go func() {
+		defer close(events)
+		// Closing done closes the socket, which ends the subscription and closes updates
+		defer func() {
+			close(done)
+			for range updates {
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+				event, ok := parseLinkUpdate(update, device.String())
+				if !ok {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// parseLinkUpdate extracts the state of the named device from a link update
+func parseLinkUpdate(update netlink.LinkUpdate, name string) (LinkEvent, bool) {
+	if update.Link == nil || update.Link.Attrs().Name != name {
+		return LinkEvent{}, false
+	}
+
+	attrs := update.Link.Attrs()
+	event := LinkEvent{Index: attrs.Index}
+	switch update.Header.Type {
+	case syscall.RTM_DELLINK:
+		event.Removed = true
+	case syscall.RTM_NEWLINK:
+		event.Up = linkUp(attrs)
+	default:
+		return LinkEvent{}, false
+	}
+	return event, true
+}
+
+// linkUp reports whether a link is administratively up and not reporting its carrier down.
+// Virtual devices such as tun report an unknown operational state while up.
+func linkUp(attrs *netlink.LinkAttrs) bool {
+	return attrs.Flags&net.FlagUp != 0 &&
+		attrs.OperState != netlink.OperDown && attrs.OperState != netlink.OperLowerLayerDown
+}
@@ -0,0 +1,44 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+func linkUpdate(msgType uint16, name string, index int, flags net.Flags, state netlink.LinkOperState) netlink.LinkUpdate {
+	return netlink.LinkUpdate{
+		Header: unix.NlMsghdr{Type: msgType},
+		Link: &netlink.Tuntap{LinkAttrs: netlink.LinkAttrs{
+			Name: name, Index: index, Flags: flags, OperState: state,
+		}},
+	}
+}
+
+func TestParseLinkUpdate(t *testing.T) {
+	event, ok := parseLinkUpdate(linkUpdate(syscall.RTM_NEWLINK, "tun0", 7, net.FlagUp, netlink.OperUnknown), "tun0")
+	assert.True(t, ok)
+	assert.Equal(t, LinkEvent{Index: 7, Up: true}, event)
+
+	event, ok = parseLinkUpdate(linkUpdate(syscall.RTM_NEWLINK, "tun0", 7, net.FlagUp, netlink.OperDown), "tun0")
+	assert.True(t, ok)
+	assert.False(t, event.Up, "no carrier")
+
+	event, ok = parseLinkUpdate(linkUpdate(syscall.RTM_NEWLINK, "tun0", 7, 0, netlink.OperUp), "tun0")
+	assert.True(t, ok)
+	assert.False(t, event.Up, "administratively down")
+
+	event, ok = parseLinkUpdate(linkUpdate(syscall.RTM_DELLINK, "tun0", 7, net.FlagUp, netlink.OperUp), "tun0")
+	assert.True(t, ok)
+	assert.Equal(t, LinkEvent{Index: 7, Removed: true}, event)
+
+	_, ok = parseLinkUpdate(linkUpdate(syscall.RTM_NEWLINK, "eth0", 2, net.FlagUp, netlink.OperUp), "tun0")
+	assert.False(t, ok, "other devices are ignored")
+}
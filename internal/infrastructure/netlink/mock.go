@@ -2,20 +2,47 @@ package netlink
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/rng999/traffic-control-go/internal/domain/entities"
 	"github.com/rng999/traffic-control-go/pkg/tc"
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
 	"github.com/rng999/traffic-control-go/pkg/types"
 )
 
+// errLinkNotFound is the cause of the errors for devices removed from the mock
+var errLinkNotFound = errors.New("link not found")
+
 // MockAdapter is a mock implementation for testing
 type MockAdapter struct {
 	mu      sync.RWMutex
 	qdiscs  map[string]map[tc.Handle]QdiscInfo // device -> handle -> qdisc
 	classes map[string]map[tc.Handle]ClassInfo // device -> handle -> class
 	filters map[string][]FilterInfo            // device -> filters
+
+	changedClasses map[string][]tc.Handle // device -> classes changed in place, in order
+
+	cakeStats    map[string]map[tc.Handle]*CAKEQdiscStats    // device -> handle -> CAKE stats
+	fqCodelStats map[string]map[tc.Handle]*FQCodelQdiscStats // device -> handle -> fq_codel stats
+	sfqStats     map[string]map[tc.Handle]*SFQQdiscStats     // device -> handle -> SFQ stats
+	codelStats   map[string]map[tc.Handle]*CodelQdiscStats   // device -> handle -> CoDel stats
+	watchers     map[string][]chan QdiscDeletion             // device -> qdisc deletion subscribers
+	ingress      map[string]string                           // device -> IFB device receiving its ingress
+	clsact       map[string]bool                             // devices with a clsact qdisc
+
+	bpfClassifiers map[string][]BPFClassifier   // device -> attached BPF classifiers
+	bpfMaps        map[string]map[string][]byte // pinned map path -> key -> value
+
+	linkWatchers map[string][]chan LinkEvent // device -> link event subscribers
+	linkIndexes  map[string]int              // device -> interface index, changed when recreated
+	nextIndex    int
+	links        map[string]LinkInfo // device -> link, for devices given a kind or state
+	removedLinks map[string]bool     // devices deleted with RemoveLink and not brought back
+
+	privileges Privileges // Unknown unless set, so that changes are allowed
 }
 
 // NewMockAdapter creates a new mock adapter
@@ -24,6 +51,25 @@ func NewMockAdapter() *MockAdapter {
 		qdiscs:  make(map[string]map[tc.Handle]QdiscInfo),
 		classes: make(map[string]map[tc.Handle]ClassInfo),
 		filters: make(map[string][]FilterInfo),
+
+		changedClasses: make(map[string][]tc.Handle),
+
+		cakeStats:    make(map[string]map[tc.Handle]*CAKEQdiscStats),
+		fqCodelStats: make(map[string]map[tc.Handle]*FQCodelQdiscStats),
+		sfqStats:     make(map[string]map[tc.Handle]*SFQQdiscStats),
+		codelStats:   make(map[string]map[tc.Handle]*CodelQdiscStats),
+		watchers:     make(map[string][]chan QdiscDeletion),
+		ingress:      make(map[string]string),
+		clsact:       make(map[string]bool),
+
+		bpfClassifiers: make(map[string][]BPFClassifier),
+		bpfMaps:        make(map[string]map[string][]byte),
+
+		linkWatchers: make(map[string][]chan LinkEvent),
+		linkIndexes:  make(map[string]int),
+		nextIndex:    2, // 1 is the loopback device
+		links:        make(map[string]LinkInfo),
+		removedLinks: make(map[string]bool),
 	}
 }
 
@@ -41,16 +87,20 @@ func (m *MockAdapter) AddQdisc(ctx context.Context, qdisc *entities.Qdisc) error
 
 	// Check if qdisc already exists
 	if _, exists := m.qdiscs[deviceStr][qdisc.Handle()]; exists {
-		return fmt.Errorf("qdisc %s already exists on device %s", qdisc.Handle(), qdisc.ID().Device())
+		return tcerrors.New(tcerrors.CodeHandleConflict, qdiscObject(qdisc.Handle()), "qdisc %s already exists on device %s", qdisc.Handle(), qdisc.ID().Device())
 	}
 
 	// Add the qdisc
-	m.qdiscs[deviceStr][qdisc.Handle()] = QdiscInfo{
+	info := QdiscInfo{
 		Handle:     qdisc.Handle(),
 		Parent:     qdisc.Parent(),
 		Type:       qdisc.Type(),
 		Statistics: QdiscStats{},
 	}
+	if minor, ok := qdisc.GetParameter("default_class"); ok && minor.(uint32) != 0 {
+		info.DefaultClass = tc.NewHandle(qdisc.Handle().Major(), uint16(minor.(uint32))) // #nosec G115 - class minors are 16 bits
+	}
+	m.qdiscs[deviceStr][qdisc.Handle()] = info
 
 	return nil
 }
@@ -63,13 +113,14 @@ func (m *MockAdapter) DeleteQdisc(device tc.DeviceName, handle tc.Handle) types.
 	deviceStr := device.String()
 
 	if qdiscs, exists := m.qdiscs[deviceStr]; exists {
-		if _, qdiscExists := qdiscs[handle]; qdiscExists {
+		if qdisc, qdiscExists := qdiscs[handle]; qdiscExists {
 			delete(qdiscs, handle)
+			m.notifyQdiscDeletion(deviceStr, QdiscDeletion{Handle: handle, Root: qdisc.Parent == nil})
 			return types.Success(Unit{})
 		}
 	}
 
-	return types.Failure[Unit](fmt.Errorf("qdisc %s not found on device %s", handle, device))
+	return types.Failure[Unit](tcerrors.New(tcerrors.CodeNotFound, qdiscObject(handle), "qdisc %s not found on device %s", handle, device))
 }
 
 // GetQdiscs returns all qdiscs for a device
@@ -106,7 +157,7 @@ func (m *MockAdapter) AddClass(ctx context.Context, classEntity interface{}) err
 
 		// Check if class already exists
 		if _, exists := m.classes[deviceStr][class.Handle()]; exists {
-			return fmt.Errorf("class %s already exists on device %s", class.Handle(), class.ID().Device())
+			return tcerrors.New(tcerrors.CodeHandleConflict, classObject(class.Handle()), "class %s already exists on device %s", class.Handle(), class.ID().Device())
 		}
 
 		// Add the class
@@ -129,7 +180,7 @@ func (m *MockAdapter) AddClass(ctx context.Context, classEntity interface{}) err
 
 		// Check if class already exists
 		if _, exists := m.classes[deviceStr][class.Handle()]; exists {
-			return fmt.Errorf("HTB class %s already exists on device %s", class.Handle(), class.ID().Device())
+			return tcerrors.New(tcerrors.CodeHandleConflict, classObject(class.Handle()), "HTB class %s already exists on device %s", class.Handle(), class.ID().Device())
 		}
 
 		// Add the HTB class
@@ -137,16 +188,76 @@ func (m *MockAdapter) AddClass(ctx context.Context, classEntity interface{}) err
 			Handle:     class.Handle(),
 			Parent:     class.Parent(),
 			Type:       entities.QdiscTypeHTB,
+			Rate:       class.Rate(),
+			Ceil:       class.Ceil(),
 			Statistics: ClassStats{},
 		}
 
 		return nil
 
+	case *entities.DRRClass:
+		return m.addFairQueueingClass(class.Class, entities.QdiscTypeDRR)
+
+	case *entities.QFQClass:
+		return m.addFairQueueingClass(class.Class, entities.QdiscTypeQFQ)
+
 	default:
 		return fmt.Errorf("unsupported class type: %T", classEntity)
 	}
 }
 
+// addFairQueueingClass records a DRR or QFQ class; the caller holds the lock
+func (m *MockAdapter) addFairQueueingClass(class *entities.Class, qdiscType entities.QdiscType) error {
+	deviceStr := class.ID().Device().String()
+	if _, exists := m.classes[deviceStr]; !exists {
+		m.classes[deviceStr] = make(map[tc.Handle]ClassInfo)
+	}
+
+	if _, exists := m.classes[deviceStr][class.Handle()]; exists {
+		return tcerrors.New(tcerrors.CodeHandleConflict, classObject(class.Handle()), "%s class %s already exists on device %s", qdiscType, class.Handle(), class.ID().Device())
+	}
+
+	m.classes[deviceStr][class.Handle()] = ClassInfo{
+		Handle:     class.Handle(),
+		Parent:     class.Parent(),
+		Type:       qdiscType,
+		Statistics: ClassStats{},
+	}
+	return nil
+}
+
+// ChangeClass changes an HTB class in place; like the kernel, it keeps the class's statistics
+func (m *MockAdapter) ChangeClass(ctx context.Context, classEntity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	class, ok := classEntity.(*entities.HTBClass)
+	if !ok {
+		return tcerrors.New(tcerrors.CodeUnsupported, "", "changing %T classes in place is not supported", classEntity)
+	}
+
+	deviceStr := class.ID().Device().String()
+	current, exists := m.classes[deviceStr][class.Handle()]
+	if !exists {
+		return tcerrors.New(tcerrors.CodeNotFound, classObject(class.Handle()), "class %s not found on device %s", class.Handle(), class.ID().Device())
+	}
+	if current.Parent != class.Parent() {
+		return fmt.Errorf("class %s cannot move from parent %s to %s", class.Handle(), current.Parent, class.Parent())
+	}
+
+	current.Rate, current.Ceil = class.Rate(), class.Ceil()
+	m.classes[deviceStr][class.Handle()] = current
+	m.changedClasses[deviceStr] = append(m.changedClasses[deviceStr], class.Handle())
+	return nil
+}
+
+// ChangedClasses returns the handles of the classes changed in place on the device, in order
+func (m *MockAdapter) ChangedClasses(device tc.DeviceName) []tc.Handle {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]tc.Handle(nil), m.changedClasses[device.String()]...)
+}
+
 // DeleteClass deletes a class
 func (m *MockAdapter) DeleteClass(device tc.DeviceName, handle tc.Handle) types.Result[Unit] {
 	m.mu.Lock()
@@ -161,7 +272,7 @@ func (m *MockAdapter) DeleteClass(device tc.DeviceName, handle tc.Handle) types.
 		}
 	}
 
-	return types.Failure[Unit](fmt.Errorf("class %s not found on device %s", handle, device))
+	return types.Failure[Unit](tcerrors.New(tcerrors.CodeNotFound, classObject(handle), "class %s not found on device %s", handle, device))
 }
 
 // GetClasses returns all classes for a device
@@ -199,8 +310,10 @@ func (m *MockAdapter) AddFilter(ctx context.Context, filter *entities.Filter) er
 		Priority: filter.ID().Priority(),
 		Protocol: filter.Protocol(),
 		Handle:   filter.ID().Handle(),
+		Kind:     filter.Kind(),
 		FlowID:   filter.FlowID(),
 		Matches:  make([]FilterMatch, 0),
+		Actions:  filter.Actions(),
 	}
 
 	// Convert matches
@@ -222,17 +335,26 @@ func (m *MockAdapter) DeleteFilter(device tc.DeviceName, parent tc.Handle, prior
 
 	deviceStr := device.String()
 
+	// Like the kernel, a zero handle deletes every filter at the priority
+	wildcard := handle == tc.Handle{}
 	if filters, exists := m.filters[deviceStr]; exists {
-		for i, filter := range filters {
-			if filter.Parent == parent && filter.Priority == priority && filter.Handle == handle {
-				// Remove filter from slice
-				m.filters[deviceStr] = append(filters[:i], filters[i+1:]...)
-				return types.Success(Unit{})
+		removed := false
+		kept := make([]FilterInfo, 0, len(filters))
+		for _, filter := range filters {
+			if (wildcard || !removed) && filter.Parent == parent && filter.Priority == priority &&
+				(wildcard || filter.Handle == handle) {
+				removed = true
+				continue
 			}
+			kept = append(kept, filter)
+		}
+		if removed {
+			m.filters[deviceStr] = kept
+			return types.Success(Unit{})
 		}
 	}
 
-	return types.Failure[Unit](fmt.Errorf("filter not found on device %s", device))
+	return types.Failure[Unit](tcerrors.New(tcerrors.CodeNotFound, filterObject(parent, priority), "filter not found on device %s", device))
 }
 
 // GetFilters returns all filters for a device
@@ -280,6 +402,54 @@ func (m *MockAdapter) SetClassStatistics(device tc.DeviceName, handle tc.Handle,
 	}
 }
 
+// SetCAKEStatistics sets the CAKE statistics reported for a qdisc
+func (m *MockAdapter) SetCAKEStatistics(device tc.DeviceName, handle tc.Handle, stats *CAKEQdiscStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deviceStr := device.String()
+	if _, exists := m.cakeStats[deviceStr]; !exists {
+		m.cakeStats[deviceStr] = make(map[tc.Handle]*CAKEQdiscStats)
+	}
+	m.cakeStats[deviceStr][handle] = stats
+}
+
+// SetFQCodelStatistics sets the fq_codel statistics reported for a qdisc
+func (m *MockAdapter) SetFQCodelStatistics(device tc.DeviceName, handle tc.Handle, stats *FQCodelQdiscStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deviceStr := device.String()
+	if _, exists := m.fqCodelStats[deviceStr]; !exists {
+		m.fqCodelStats[deviceStr] = make(map[tc.Handle]*FQCodelQdiscStats)
+	}
+	m.fqCodelStats[deviceStr][handle] = stats
+}
+
+// SetSFQStatistics sets the SFQ statistics reported for a qdisc
+func (m *MockAdapter) SetSFQStatistics(device tc.DeviceName, handle tc.Handle, stats *SFQQdiscStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deviceStr := device.String()
+	if _, exists := m.sfqStats[deviceStr]; !exists {
+		m.sfqStats[deviceStr] = make(map[tc.Handle]*SFQQdiscStats)
+	}
+	m.sfqStats[deviceStr][handle] = stats
+}
+
+// SetCodelStatistics sets the CoDel statistics reported for a qdisc
+func (m *MockAdapter) SetCodelStatistics(device tc.DeviceName, handle tc.Handle, stats *CodelQdiscStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deviceStr := device.String()
+	if _, exists := m.codelStats[deviceStr]; !exists {
+		m.codelStats[deviceStr] = make(map[tc.Handle]*CodelQdiscStats)
+	}
+	m.codelStats[deviceStr][handle] = stats
+}
+
 // GetDetailedQdiscStats returns detailed qdisc statistics for mock testing
 func (m *MockAdapter) GetDetailedQdiscStats(device tc.DeviceName, handle tc.Handle) types.Result[DetailedQdiscStats] {
 	m.mu.RLock()
@@ -307,11 +477,24 @@ func (m *MockAdapter) GetDetailedQdiscStats(device tc.DeviceName, handle tc.Hand
 				}
 			}
 
+			if qdisc.Type == entities.QdiscTypeCAKE {
+				detailedStats.CAKEStats = m.cakeStats[deviceStr][handle]
+			}
+			if qdisc.Type == entities.QdiscTypeFQCODEL {
+				detailedStats.FQCodelStats = m.fqCodelStats[deviceStr][handle]
+			}
+			if qdisc.Type == entities.QdiscTypeSFQ {
+				detailedStats.SFQStats = m.sfqStats[deviceStr][handle]
+			}
+			if qdisc.Type == entities.QdiscTypeCODEL {
+				detailedStats.CodelStats = m.codelStats[deviceStr][handle]
+			}
+
 			return types.Success(detailedStats)
 		}
 	}
 
-	return types.Failure[DetailedQdiscStats](fmt.Errorf("qdisc %s not found on device %s", handle, device))
+	return types.Failure[DetailedQdiscStats](tcerrors.New(tcerrors.CodeNotFound, qdiscObject(handle), "qdisc %s not found on device %s", handle, device))
 }
 
 // GetDetailedClassStats returns detailed class statistics for mock testing
@@ -346,7 +529,7 @@ func (m *MockAdapter) GetDetailedClassStats(device tc.DeviceName, handle tc.Hand
 		}
 	}
 
-	return types.Failure[DetailedClassStats](fmt.Errorf("class %s not found on device %s", handle, device))
+	return types.Failure[DetailedClassStats](tcerrors.New(tcerrors.CodeNotFound, classObject(handle), "class %s not found on device %s", handle, device))
 }
 
 // GetLinkStats returns mock link statistics for testing
@@ -365,3 +548,420 @@ func (m *MockAdapter) GetLinkStats(device tc.DeviceName) types.Result[LinkStats]
 
 	return types.Success(stats)
 }
+
+// WatchQdiscDeletions reports qdiscs deleted from the mock device until the context is cancelled
+func (m *MockAdapter) WatchQdiscDeletions(ctx context.Context, device tc.DeviceName) (<-chan QdiscDeletion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deviceStr := device.String()
+	ch := make(chan QdiscDeletion, 16)
+	m.watchers[deviceStr] = append(m.watchers[deviceStr], ch)
+
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		watchers := m.watchers[deviceStr]
+		for i, watcher := range watchers {
+			if watcher == ch {
+				m.watchers[deviceStr] = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// RemoveRootQdisc removes the root qdisc with all classes and filters, as another process
+// such as NetworkManager would, and notifies deletion watchers
+func (m *MockAdapter) RemoveRootQdisc(device tc.DeviceName) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deviceStr := device.String()
+	for handle, qdisc := range m.qdiscs[deviceStr] {
+		if qdisc.Parent == nil {
+			m.notifyQdiscDeletion(deviceStr, QdiscDeletion{Handle: handle, Root: true})
+		}
+	}
+	delete(m.qdiscs, deviceStr)
+	delete(m.classes, deviceStr)
+	delete(m.filters, deviceStr)
+}
+
+// notifyQdiscDeletion sends a deletion to the device's watchers; the caller holds the lock
+func (m *MockAdapter) notifyQdiscDeletion(device string, deletion QdiscDeletion) {
+	for _, watcher := range m.watchers[device] {
+		select {
+		case watcher <- deletion:
+		default: // A slow watcher misses events rather than blocking the adapter
+		}
+	}
+}
+
+// WatchLinks reports link state changes of the mock device, made with SetLinkState and
+// RemoveLink, until the context is cancelled
+func (m *MockAdapter) WatchLinks(ctx context.Context, device tc.DeviceName) (<-chan LinkEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deviceStr := device.String()
+	ch := make(chan LinkEvent, 16)
+	m.linkWatchers[deviceStr] = append(m.linkWatchers[deviceStr], ch)
+
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		watchers := m.linkWatchers[deviceStr]
+		for i, watcher := range watchers {
+			if watcher == ch {
+				m.linkWatchers[deviceStr] = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// SetLinkState brings the device's link up or down and notifies link watchers. A device
+// removed with RemoveLink is created again, with a new index.
+func (m *MockAdapter) SetLinkState(device tc.DeviceName, up bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	link := m.link(device)
+	link.Up = up
+	m.links[device.String()] = link
+	delete(m.removedLinks, device.String())
+	m.notifyLink(device.String(), LinkEvent{Index: m.linkIndex(device.String()), Up: up})
+}
+
+// SetLinkSpeed sets the speed the device reports, as a NIC negotiating a link does
+func (m *MockAdapter) SetLinkSpeed(device tc.DeviceName, speed tc.Bandwidth) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	link := m.link(device)
+	link.Speed = speed
+	m.links[device.String()] = link
+}
+
+// RemoveLink deletes the device, taking its qdiscs, classes and filters with it, as
+// restarting a VPN does with its tun device, and notifies link watchers. VLANs stacked on
+// the device are deleted with it, and ports of a removed bridge are released.
+func (m *MockAdapter) RemoveLink(device tc.DeviceName) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeLink(device.String())
+}
+
+// removeLink deletes the device and the VLANs on it; the caller holds the lock
+func (m *MockAdapter) removeLink(deviceStr string) {
+	event := LinkEvent{Index: m.linkIndex(deviceStr), Removed: true}
+	delete(m.linkIndexes, deviceStr)
+	delete(m.links, deviceStr)
+	m.removedLinks[deviceStr] = true
+	delete(m.qdiscs, deviceStr)
+	delete(m.classes, deviceStr)
+	delete(m.filters, deviceStr)
+	m.notifyLink(deviceStr, event)
+
+	for name, link := range m.links {
+		if link.Parent.String() == deviceStr {
+			m.removeLink(name)
+		} else if link.Master.String() == deviceStr {
+			link.Master = tc.DeviceName{}
+			m.links[name] = link
+		}
+	}
+}
+
+// AddVLAN creates a VLAN sub-interface of the parent device, up
+func (m *MockAdapter) AddVLAN(device, parent tc.DeviceName, id uint16) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	link := m.link(device)
+	link.Kind = "vlan"
+	link.Parent = parent
+	link.VLANID = id
+	m.links[device.String()] = link
+	delete(m.removedLinks, device.String())
+}
+
+// AddBridge creates a bridge, up, and enslaves the ports to it
+func (m *MockAdapter) AddBridge(bridge tc.DeviceName, ports ...tc.DeviceName) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	link := m.link(bridge)
+	link.Kind = "bridge"
+	m.links[bridge.String()] = link
+	delete(m.removedLinks, bridge.String())
+
+	for _, port := range ports {
+		link := m.link(port)
+		link.Master = bridge
+		m.links[port.String()] = link
+		delete(m.removedLinks, port.String())
+	}
+}
+
+// GetLinkInfo describes the mock device. Any device exists as an ordinary device that is
+// up, unless it was given another kind or state, or removed with RemoveLink.
+func (m *MockAdapter) GetLinkInfo(device tc.DeviceName) types.Result[LinkInfo] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.removedLinks[device.String()] {
+		return types.Failure[LinkInfo](deviceNotFound(device, errLinkNotFound))
+	}
+	return types.Success(m.link(device))
+}
+
+// GetBridgePorts returns the ports of a bridge created with AddBridge, ordered by index
+func (m *MockAdapter) GetBridgePorts(bridge tc.DeviceName) types.Result[[]tc.DeviceName] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.removedLinks[bridge.String()] {
+		return types.Failure[[]tc.DeviceName](deviceNotFound(bridge, errLinkNotFound))
+	}
+	if link := m.link(bridge); !link.IsBridge() {
+		return types.Failure[[]tc.DeviceName](fmt.Errorf("device %s is a %s, not a bridge", bridge, link.Kind))
+	}
+
+	ports := make([]LinkInfo, 0)
+	for _, link := range m.links {
+		if link.Master.Equals(bridge) {
+			ports = append(ports, link)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Index < ports[j].Index })
+
+	names := make([]tc.DeviceName, 0, len(ports))
+	for _, port := range ports {
+		names = append(names, port.Name)
+	}
+	return types.Success(names)
+}
+
+// link returns the device's link, as an ordinary device that is up if it has not been
+// described; the caller holds the lock
+func (m *MockAdapter) link(device tc.DeviceName) LinkInfo {
+	link, exists := m.links[device.String()]
+	if !exists {
+		link = LinkInfo{Name: device, Kind: "device", Up: true}
+	}
+	link.Index = m.linkIndex(device.String())
+	return link
+}
+
+// linkIndex returns the device's index, assigning one if the device is new; the caller
+// holds the lock
+func (m *MockAdapter) linkIndex(device string) int {
+	index, exists := m.linkIndexes[device]
+	if !exists {
+		index = m.nextIndex
+		m.nextIndex++
+		m.linkIndexes[device] = index
+	}
+	return index
+}
+
+// notifyLink sends a link event to the device's watchers; the caller holds the lock
+func (m *MockAdapter) notifyLink(device string, event LinkEvent) {
+	for _, watcher := range m.linkWatchers[device] {
+		select {
+		case watcher <- event:
+		default: // A slow watcher misses events rather than blocking the adapter
+		}
+	}
+}
+
+// AddIngressRedirect records that traffic received on the device is redirected to the IFB device
+func (m *MockAdapter) AddIngressRedirect(ctx context.Context, device, ifb tc.DeviceName) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if device == ifb {
+		return fmt.Errorf("cannot redirect ingress of %s to itself", device)
+	}
+	if current, ok := m.ingress[device.String()]; ok && current != ifb.String() {
+		return fmt.Errorf("ingress of %s is already redirected to %s", device, current)
+	}
+	m.ingress[device.String()] = ifb.String()
+	m.clsact[device.String()] = true
+	return nil
+}
+
+// DeleteIngressRedirect removes the redirect and, like deleting the IFB device, its qdiscs,
+// classes and filters
+func (m *MockAdapter) DeleteIngressRedirect(device, ifb tc.DeviceName) types.Result[Unit] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.ingress, device.String())
+	delete(m.qdiscs, ifb.String())
+	delete(m.classes, ifb.String())
+	delete(m.filters, ifb.String())
+	return types.Success(Unit{})
+}
+
+// IngressRedirect returns the IFB device receiving the device's ingress traffic, if any
+func (m *MockAdapter) IngressRedirect(device tc.DeviceName) (tc.DeviceName, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ifb, ok := m.ingress[device.String()]
+	if !ok {
+		return tc.DeviceName{}, false
+	}
+	return tc.MustNewDeviceName(ifb), true
+}
+
+// EnsureClsactQdisc records a clsact qdisc on the device and reports whether it was missing
+func (m *MockAdapter) EnsureClsactQdisc(ctx context.Context, device tc.DeviceName, hook ClsactHook) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := hook.Parent(); err != nil {
+		return false, err
+	}
+	if m.clsact[device.String()] {
+		return false, nil
+	}
+	m.clsact[device.String()] = true
+	return true, nil
+}
+
+// DeleteClsactQdisc removes the device's clsact qdisc unless filters, BPF classifiers or
+// an ingress redirect remain on its hooks
+func (m *MockAdapter) DeleteClsactQdisc(device tc.DeviceName) types.Result[Unit] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deviceStr := device.String()
+	if len(m.bpfClassifiers[deviceStr]) > 0 || m.ingress[deviceStr] != "" {
+		return types.Success(Unit{})
+	}
+	for _, filter := range m.filters[deviceStr] {
+		if filter.Parent.Major() == 0xffff {
+			return types.Success(Unit{})
+		}
+	}
+	delete(m.clsact, deviceStr)
+	return types.Success(Unit{})
+}
+
+// HasClsactQdisc reports whether the device has a clsact qdisc
+func (m *MockAdapter) HasClsactQdisc(device tc.DeviceName) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.clsact[device.String()]
+}
+
+// AttachBPFClassifier records the classifier, replacing one with the same hook and priority
+func (m *MockAdapter) AttachBPFClassifier(ctx context.Context, device tc.DeviceName, classifier BPFClassifier) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if (classifier.Object == "") == (classifier.Pinned == "") {
+		return fmt.Errorf("BPF classifier %s needs either an object or a pinned program", classifier.Name)
+	}
+	if classifier.Hook != HookIngress && classifier.Hook != HookEgress {
+		return fmt.Errorf("unknown clsact hook %q", classifier.Hook)
+	}
+
+	m.clsact[device.String()] = true
+	attached := m.bpfClassifiers[device.String()]
+	for i, existing := range attached {
+		if existing.Hook == classifier.Hook && existing.Priority == classifier.Priority {
+			attached[i] = classifier
+			return nil
+		}
+	}
+	m.bpfClassifiers[device.String()] = append(attached, classifier)
+	return nil
+}
+
+// DetachBPFClassifier removes the classifier with the hook and priority, if attached
+func (m *MockAdapter) DetachBPFClassifier(device tc.DeviceName, hook ClsactHook, priority uint16) types.Result[Unit] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attached := m.bpfClassifiers[device.String()]
+	for i, existing := range attached {
+		if existing.Hook == hook && existing.Priority == priority {
+			m.bpfClassifiers[device.String()] = append(attached[:i], attached[i+1:]...)
+			break
+		}
+	}
+	return types.Success(Unit{})
+}
+
+// UpdateBPFMap sets the value of the key in the map at path
+func (m *MockAdapter) UpdateBPFMap(path string, key, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(key) == 0 || len(value) == 0 {
+		return fmt.Errorf("map entries need a key and a value")
+	}
+	if m.bpfMaps[path] == nil {
+		m.bpfMaps[path] = make(map[string][]byte)
+	}
+	m.bpfMaps[path][string(key)] = append([]byte(nil), value...)
+	return nil
+}
+
+// DeleteBPFMapEntry removes the key from the map at path
+func (m *MockAdapter) DeleteBPFMapEntry(path string, key []byte) types.Result[Unit] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.bpfMaps[path], string(key))
+	return types.Success(Unit{})
+}
+
+// CheckPrivileges checks the privileges set with SetPrivileges
+func (m *MockAdapter) CheckPrivileges(ctx context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.privileges.Check()
+}
+
+// SetPrivileges sets the privileges of the process, as a test running unprivileged would
+// have them. The mock itself still accepts changes.
+func (m *MockAdapter) SetPrivileges(privileges Privileges) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.privileges = privileges
+}
+
+// BPFClassifiers returns the BPF classifiers attached to the device
+func (m *MockAdapter) BPFClassifiers(device tc.DeviceName) []BPFClassifier {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]BPFClassifier(nil), m.bpfClassifiers[device.String()]...)
+}
+
+// BPFMapEntry returns the value of the key in the map at path, if set
+func (m *MockAdapter) BPFMapEntry(path string, key []byte) ([]byte, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	value, ok := m.bpfMaps[path][string(key)]
+	return value, ok
+}
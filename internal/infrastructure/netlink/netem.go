@@ -9,6 +9,7 @@ import (
 
 	nl "github.com/vishvananda/netlink"
 
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
 	"github.com/rng999/traffic-control-go/pkg/tc"
 	"github.com/rng999/traffic-control-go/pkg/types"
 )
@@ -34,7 +35,7 @@ func (a *RealNetlinkAdapter) AddNetemQdisc(device tc.DeviceName, handle tc.Handl
 	// Get the network link
 	link, err := nl.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[Unit](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[Unit](deviceNotFound(device, err))
 	}
 
 	// Create NETEM qdisc
@@ -102,46 +103,18 @@ func (a *RealNetlinkAdapter) AddNetemQdisc(device tc.DeviceName, handle tc.Handl
 	return types.Success(Unit{})
 }
 
-// Example implementation in the adapter switch statement:
-// This function shows how NETEM would be integrated, kept for reference
-func _addNetemToSwitch(config QdiscConfig) (nl.Qdisc, error) { //nolint:unused
-	// This would be added to the main adapter's switch statement
-	netem := nl.NewNetem(nl.QdiscAttrs{}, nl.NetemQdiscAttrs{})
-
-	// Parse NETEM-specific parameters from config.Parameters
-	if delay, ok := config.Parameters["delay"].(time.Duration); ok {
-		delayMicros := delay.Nanoseconds() / 1000
-		if delayMicros > 0x7FFFFFFF { // Use signed max to avoid potential issues
-			return nil, fmt.Errorf("delay %v exceeds maximum value", delay)
-		}
-		netem.Latency = uint32(delayMicros) // #nosec G115 - range checked above
-	}
-	if jitter, ok := config.Parameters["jitter"].(time.Duration); ok {
-		jitterMicros := jitter.Nanoseconds() / 1000
-		if jitterMicros > 0x7FFFFFFF {
-			return nil, fmt.Errorf("jitter %v exceeds maximum value", jitter)
-		}
-		netem.Jitter = uint32(jitterMicros) // #nosec G115 - range checked above
-	}
-	if loss, ok := config.Parameters["loss"].(float32); ok {
-		// Convert percentage to kernel's representation
-		netem.Loss = uint32((loss / 100.0) * float32(^uint32(0)))
-	}
-	if duplicate, ok := config.Parameters["duplicate"].(float32); ok {
-		// Convert percentage to kernel's representation
-		netem.Duplicate = uint32((duplicate / 100.0) * float32(^uint32(0)))
-	}
-	if corrupt, ok := config.Parameters["corrupt"].(float32); ok {
-		// Convert percentage to kernel's representation
-		netem.CorruptProb = uint32((corrupt / 100.0) * float32(^uint32(0)))
-	}
-	if reorder, ok := config.Parameters["reorder"].(float32); ok {
-		// Convert percentage to kernel's representation
-		netem.ReorderProb = uint32((reorder / 100.0) * float32(^uint32(0)))
-	}
-	if limit, ok := config.Parameters["limit"].(uint32); ok {
-		netem.Limit = limit
-	}
-
-	return netem, nil
+// newNetemQdisc builds a NETEM qdisc from the entity parameters. Delay and jitter are
+// stored in microseconds and probabilities in percent; NewNetem converts both to the
+// kernel's tick and fixed-point representations.
+func newNetemQdisc(attrs nl.QdiscAttrs, qdiscEntity *entities.Qdisc) *nl.Netem {
+	return nl.NewNetem(attrs, nl.NetemQdiscAttrs{
+		Latency:     qdiscParameter(qdiscEntity, "delay", 0),
+		Jitter:      qdiscParameter(qdiscEntity, "jitter", 0),
+		Loss:        netemProbability(qdiscEntity, "loss"),
+		Duplicate:   netemProbability(qdiscEntity, "duplicate"),
+		CorruptProb: netemProbability(qdiscEntity, "corrupt"),
+		ReorderProb: netemProbability(qdiscEntity, "reorder"),
+		Gap:         qdiscParameter(qdiscEntity, "gap", 0),
+		Limit:       qdiscParameter(qdiscEntity, "limit", 1000),
+	})
 }
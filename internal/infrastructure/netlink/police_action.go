@@ -37,7 +37,7 @@ func (a *RealNetlinkAdapter) AddPoliceFilter(device tc.DeviceName, parent tc.Han
 	// Get the network link
 	link, err := nl.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[Unit](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[Unit](deviceNotFound(device, err))
 	}
 
 	// Create basic filter with police action
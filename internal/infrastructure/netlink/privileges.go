@@ -0,0 +1,95 @@
+package netlink
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+// capNetAdmin is the number of CAP_NET_ADMIN, the capability the kernel requires to change
+// qdiscs, classes and filters
+const capNetAdmin = 12
+
+// Privileges describes what a process may do to the traffic control configuration of its
+// host. Reading the configuration and its statistics needs no privilege; changing it needs
+// CAP_NET_ADMIN, which root usually holds but may have dropped.
+type Privileges struct {
+	Known           bool // The process status could be read; the other fields are meaningless otherwise
+	EffectiveUID    int
+	NetAdmin        bool // CAP_NET_ADMIN is among the effective capabilities
+	ProcSysReadOnly bool // /proc/sys is mounted read-only, as in a container without network privileges
+}
+
+// ReadPrivileges reads the privileges of the current process from /proc. On hosts without
+// /proc the privileges are unknown.
+func ReadPrivileges() Privileges {
+	status, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return Privileges{}
+	}
+	mounts, _ := os.ReadFile("/proc/self/mounts")
+	return ParsePrivileges(string(status) + string(mounts))
+}
+
+// ParsePrivileges reads privileges from the contents of /proc/<pid>/status followed by those
+// of /proc/<pid>/mounts
+func ParsePrivileges(proc string) Privileges {
+	var privileges Privileges
+	for _, line := range strings.Split(proc, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) >= 3 && fields[0] == "Uid:":
+			// Real, effective, saved and filesystem IDs
+			if uid, err := strconv.Atoi(fields[2]); err == nil {
+				privileges.EffectiveUID = uid
+			}
+		case len(fields) == 2 && fields[0] == "CapEff:":
+			capabilities, err := strconv.ParseUint(fields[1], 16, 64)
+			if err != nil {
+				continue
+			}
+			privileges.Known = true
+			privileges.NetAdmin = capabilities&(1<<capNetAdmin) != 0
+		case len(fields) >= 4 && fields[1] == "/proc/sys":
+			// The last mount on /proc/sys is the one in effect
+			privileges.ProcSysReadOnly = mountedReadOnly(fields[3])
+		}
+	}
+	return privileges
+}
+
+func mountedReadOnly(options string) bool {
+	for _, option := range strings.Split(options, ",") {
+		if option == "ro" {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns nil when the process may change the traffic control configuration, or when
+// its privileges are unknown and the kernel is left to decide. Otherwise it returns a
+// permission denied error whose hint explains how to grant the privilege in the process's
+// situation.
+func (p Privileges) Check() error {
+	if !p.Known || p.NetAdmin {
+		return nil
+	}
+
+	var hint string
+	switch {
+	case p.ProcSysReadOnly:
+		hint = "the process appears to run in a container without network privileges; " +
+			"start it with --cap-add=NET_ADMIN, and on the host's network to shape the host's devices"
+	case p.EffectiveUID == 0:
+		hint = "the process runs as root but CAP_NET_ADMIN was dropped from its capabilities; " +
+			"keep it in the service's capability bounding set, or run the process without the restriction"
+	default:
+		hint = "run as root, or grant the process CAP_NET_ADMIN, " +
+			"for instance with AmbientCapabilities=CAP_NET_ADMIN in its systemd unit or setcap cap_net_admin+ep on its binary"
+	}
+	return tcerrors.New(tcerrors.CodePermissionDenied, "",
+		"the process lacks CAP_NET_ADMIN, which changing traffic control requires").WithHint(hint)
+}
@@ -0,0 +1,62 @@
+package netlink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rng999/traffic-control-go/pkg/tcerrors"
+)
+
+const procStatus = `Name:	cat
+Umask:	0022
+State:	R (running)
+Uid:	0	0	0	0
+Gid:	0	0	0	0
+CapInh:	0000000000000000
+CapPrm:	000001ffffffffff
+CapEff:	%s
+CapBnd:	000001ffffffffff
+`
+
+const containerMounts = `overlay / overlay rw,relatime 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+proc /proc/sys proc ro,nosuid,nodev,noexec,relatime 0 0
+`
+
+func TestParsePrivileges(t *testing.T) {
+	root := ParsePrivileges(fmt.Sprintf(procStatus, "000001ffffffffff"))
+	assert.Equal(t, Privileges{Known: true, NetAdmin: true}, root)
+	assert.NoError(t, root.Check())
+
+	// Root in a container started without NET_ADMIN
+	container := ParsePrivileges(fmt.Sprintf(procStatus, "00000000a80425fb") + containerMounts)
+	assert.Equal(t, Privileges{Known: true, ProcSysReadOnly: true}, container)
+	err := container.Check()
+	assert.True(t, errors.Is(err, tcerrors.ErrPermissionDenied))
+	assert.Contains(t, tcerrors.HintOf(err), "--cap-add=NET_ADMIN")
+
+	// Root with the capability dropped, as a systemd unit may do
+	dropped := ParsePrivileges(fmt.Sprintf(procStatus, "000001fffeffefff"))
+	assert.Contains(t, tcerrors.HintOf(dropped.Check()), "runs as root")
+
+	user := ParsePrivileges("Uid:\t1000\t1000\t1000\t1000\nCapEff:\t0000000000000000\n")
+	assert.Equal(t, 1000, user.EffectiveUID)
+	assert.Contains(t, tcerrors.HintOf(user.Check()), "setcap")
+
+	assert.NoError(t, ParsePrivileges("").Check(), "unknown privileges are left to the kernel")
+}
+
+func TestExecAdapter_CheckPrivileges(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{
+		"cat /proc/self/status /proc/self/mounts": "Uid:\t1000\t1000\t1000\t1000\nCapEff:\t0000000000000000\n",
+	}}
+	err := NewExecAdapterWithRunner(runner, "").CheckPrivileges(context.Background())
+	assert.True(t, errors.Is(err, tcerrors.ErrPermissionDenied))
+
+	failing := &fakeRunner{failures: map[string]string{"cat": "cat: /proc/self/status: No such file or directory"}}
+	assert.NoError(t, NewExecAdapterWithRunner(failing, "").CheckPrivileges(context.Background()))
+}
@@ -0,0 +1,93 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/pkg/logging"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// qdiscWatchPollInterval bounds how long the watcher blocks in the kernel before it
+// checks for cancellation
+var qdiscWatchPollInterval = syscall.Timeval{Sec: 1}
+
+// WatchQdiscDeletions reports qdiscs deleted from the device until the context is cancelled,
+// using the kernel's traffic control multicast group. The channel is closed when watching stops.
+func (a *RealNetlinkAdapter) WatchQdiscDeletions(ctx context.Context, device tc.DeviceName) (<-chan QdiscDeletion, error) {
+	link, err := netlink.LinkByName(device.String())
+	if err != nil {
+		return nil, deviceNotFound(device, err)
+	}
+
+	socket, err := nl.Subscribe(syscall.NETLINK_ROUTE, syscall.RTNLGRP_TC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to traffic control events: %w", err)
+	}
+	if err := syscall.SetsockoptTimeval(socket.GetFd(), syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &qdiscWatchPollInterval); err != nil {
+		socket.Close()
+		return nil, fmt.Errorf("failed to set receive timeout: %w", err)
+	}
+
+	linkIndex := link.Attrs().Index
+	deletions := make(chan QdiscDeletion)
+
+	go func() {
+		defer close(deletions)
+		defer socket.Close()
+
+		for ctx.Err() == nil {
+			messages, _, err := socket.Receive()
+			if err != nil {
+				if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+					continue
+				}
+				a.logger.Error("Traffic control event subscription failed",
+					logging.String("device", device.String()),
+					logging.Error(err),
+				)
+				return
+			}
+
+			for _, message := range messages {
+				deletion, ok := parseQdiscDeletion(message, linkIndex)
+				if !ok {
+					continue
+				}
+				select {
+				case deletions <- deletion:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return deletions, nil
+}
+
+// parseQdiscDeletion extracts a qdisc deletion on the link from a netlink message. Deletions
+// of the kernel's default qdiscs, which have no handle, are ignored.
+func parseQdiscDeletion(message syscall.NetlinkMessage, linkIndex int) (QdiscDeletion, bool) {
+	if message.Header.Type != syscall.RTM_DELQDISC || len(message.Data) < nl.SizeofTcMsg {
+		return QdiscDeletion{}, false
+	}
+
+	msg := nl.DeserializeTcMsg(message.Data)
+	if int(msg.Ifindex) != linkIndex || msg.Handle == 0 {
+		return QdiscDeletion{}, false
+	}
+
+	return QdiscDeletion{
+		Handle: tc.HandleFromUint32(msg.Handle),
+		Root:   msg.Parent == netlink.HANDLE_ROOT,
+	}, true
+}
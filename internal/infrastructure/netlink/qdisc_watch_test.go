@@ -0,0 +1,44 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+func qdiscMessage(msgType uint16, ifindex int32, handle, parent uint32) syscall.NetlinkMessage {
+	msg := nl.TcMsg{Family: syscall.AF_UNSPEC, Ifindex: ifindex, Handle: handle, Parent: parent}
+	return syscall.NetlinkMessage{
+		Header: syscall.NlMsghdr{Type: msgType},
+		Data:   msg.Serialize(),
+	}
+}
+
+func TestParseQdiscDeletion(t *testing.T) {
+	root := qdiscMessage(syscall.RTM_DELQDISC, 2, 0x10000, netlink.HANDLE_ROOT)
+	deletion, ok := parseQdiscDeletion(root, 2)
+	assert.True(t, ok)
+	assert.Equal(t, QdiscDeletion{Handle: tc.NewHandle(1, 0), Root: true}, deletion)
+
+	child := qdiscMessage(syscall.RTM_DELQDISC, 2, 0x100000, 0x10010)
+	deletion, ok = parseQdiscDeletion(child, 2)
+	assert.True(t, ok)
+	assert.False(t, deletion.Root)
+
+	_, ok = parseQdiscDeletion(root, 3)
+	assert.False(t, ok, "other links are ignored")
+
+	_, ok = parseQdiscDeletion(qdiscMessage(syscall.RTM_NEWQDISC, 2, 0x10000, netlink.HANDLE_ROOT), 2)
+	assert.False(t, ok, "additions are ignored")
+
+	_, ok = parseQdiscDeletion(qdiscMessage(syscall.RTM_DELQDISC, 2, 0, netlink.HANDLE_ROOT), 2)
+	assert.False(t, ok, "default qdiscs are ignored")
+}
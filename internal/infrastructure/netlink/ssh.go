@@ -0,0 +1,138 @@
+package netlink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sshConnectionFailed is the exit status of ssh(1) when it fails itself, rather than the
+// remote command
+const sshConnectionFailed = 255
+
+// sshOutputDelay bounds the wait for the output of ssh once it exits. The connection it
+// leaves open for the next command holds on to its standard error.
+const sshOutputDelay = time.Second
+
+// SSHRunner runs commands on a remote host with the ssh(1) client, so that the user's
+// configuration, keys and agent apply. Commands share one connection, kept open for a
+// minute after the last, so that applying a configuration does not log in once per command.
+// Its socket is kept in $XDG_RUNTIME_DIR/tcgo-ssh, or in ~/.ssh without a runtime directory;
+// when neither is private to the user, commands do not share a connection. Password prompts
+// are disabled: the host must accept a key.
+type SSHRunner struct {
+	Destination  string   // [user@]host, or a Host of ssh_config
+	Port         int      // Zero for the port of ssh_config or 22
+	IdentityFile string   // Private key; empty for those of ssh_config and the agent
+	Options      []string // Further ssh_config options, such as "StrictHostKeyChecking=yes"
+	Program      string   // The ssh client; "ssh" when empty
+}
+
+// Run runs the program on the remote host. A program that fails returns a *CommandError; a
+// connection that fails returns another error, so that it is not taken for a tc error.
+func (r SSHRunner) Run(ctx context.Context, program string, args ...string) ([]byte, error) {
+	command := CommandLine(shellQuote(program), quoteAll(args))
+	sshArgs := append(r.args(), "--", r.Destination, command)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.program(), sshArgs...) // #nosec G204 -- the arguments are quoted for the remote shell
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = sshOutputDelay
+	output, err := cmd.Output()
+	if err == nil || errors.Is(err, exec.ErrWaitDelay) {
+		return output, nil
+	}
+
+	message := strings.TrimSpace(stderr.String())
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == sshConnectionFailed {
+		return nil, fmt.Errorf("failed to reach %s over ssh: %s", r.Destination, message)
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.As(err, new(*os.PathError)) {
+		return nil, fmt.Errorf("failed to run ssh: %w", err)
+	}
+	return nil, &CommandError{Command: r.Destination + ": " + command, Stderr: message, Err: err}
+}
+
+// args returns the options of the ssh client
+func (r SSHRunner) args() []string {
+	args := []string{"-o", "BatchMode=yes"}
+	if dir, err := sshControlDir(); err == nil {
+		args = append(args,
+			"-o", "ControlMaster=auto",
+			"-o", "ControlPersist=60",
+			"-o", "ControlPath="+filepath.Join(dir, "tcgo-ssh-%C"),
+		)
+	} else {
+		args = append(args, "-o", "ControlMaster=no")
+	}
+	if r.Port > 0 {
+		args = append(args, "-p", strconv.Itoa(r.Port))
+	}
+	if r.IdentityFile != "" {
+		args = append(args, "-i", r.IdentityFile)
+	}
+	for _, option := range r.Options {
+		args = append(args, "-o", option)
+	}
+	return args
+}
+
+// sshControlDir returns the directory of the shared connections' sockets, creating it
+// private to the user. Whoever can write to the directory can put their own socket in place
+// of the connection's, so a shared directory such as /tmp is never used.
+func sshControlDir() (string, error) {
+	dir := ""
+	if runtime := os.Getenv("XDG_RUNTIME_DIR"); runtime != "" {
+		dir = filepath.Join(runtime, "tcgo-ssh")
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".ssh")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	// An existing directory keeps its mode; it must not be a link or writable by others
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() || info.Mode().Perm()&0o022 != 0 {
+		return "", fmt.Errorf("%s is not a directory only its owner can write to", dir)
+	}
+	return dir, nil
+}
+
+func (r SSHRunner) program() string {
+	if r.Program == "" {
+		return "ssh"
+	}
+	return r.Program
+}
+
+// shellQuote quotes an argument for the remote shell, which ssh passes the command line to
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_./:@%+=,-") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func quoteAll(args []string) []string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return quoted
+}
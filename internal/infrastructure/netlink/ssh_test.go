@@ -0,0 +1,100 @@
+package netlink
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSSH writes an ssh client printing its arguments, one per line, that fails as ssh
+// does for the host "down" and as tc does for the device "eth9"
+func fakeSSH(t *testing.T) string {
+	script := `#!/bin/sh
+for arg in "$@"; do
+	case "$arg" in
+	down) echo "ssh: connect to host down port 22: Connection refused" >&2; exit 255 ;;
+	*eth9*) echo 'Cannot find device "eth9"' >&2; exit 1 ;;
+	esac
+done
+printf '%s\n' "$@"
+`
+	program := filepath.Join(t.TempDir(), "ssh")
+	require.NoError(t, os.WriteFile(program, []byte(script), 0o700)) // #nosec G306 -- an executable test script
+	return program
+}
+
+func TestSSHRunner(t *testing.T) {
+	ctx := context.Background()
+	runtime := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtime)
+	runner := SSHRunner{Destination: "admin@edge1", Port: 2222, IdentityFile: "/keys/edge", Options: []string{"StrictHostKeyChecking=yes"}, Program: fakeSSH(t)}
+
+	output, err := runner.Run(ctx, "/sbin/tc", "filter", "replace", "dev", "eth0", "bpf", "obj", "/srv/my prog.o", "it's")
+	require.NoError(t, err)
+	args := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+	assert.Contains(t, args, "BatchMode=yes")
+	assert.Contains(t, args, "2222")
+	assert.Contains(t, args, "/keys/edge")
+	assert.Contains(t, args, "StrictHostKeyChecking=yes")
+	assert.Contains(t, args, "ControlPath="+filepath.Join(runtime, "tcgo-ssh", "tcgo-ssh-%C"))
+	assert.Equal(t, []string{"--", "admin@edge1", `/sbin/tc filter replace dev eth0 bpf obj '/srv/my prog.o' 'it'\''s'`}, args[len(args)-3:])
+
+	_, err = runner.Run(ctx, "/sbin/tc", "qdisc", "show", "dev", "eth9")
+	var commandErr *CommandError
+	require.True(t, errors.As(err, &commandErr), err)
+	assert.True(t, errors.Is(err, syscall.ENODEV))
+	assert.Equal(t, "admin@edge1: /sbin/tc qdisc show dev eth9", commandErr.Command)
+
+	runner.Destination = "down"
+	_, err = runner.Run(ctx, "/sbin/tc", "qdisc", "show")
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &commandErr))
+	assert.Contains(t, err.Error(), "Connection refused")
+}
+
+func TestSSHRunner_ControlPath(t *testing.T) {
+	controlOptions := func() []string {
+		var options []string
+		for _, arg := range (SSHRunner{}).args() {
+			if strings.HasPrefix(arg, "Control") {
+				options = append(options, arg)
+			}
+		}
+		return options
+	}
+
+	// In the runtime directory, private to the user
+	runtime := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtime)
+	assert.Contains(t, controlOptions(), "ControlPath="+filepath.Join(runtime, "tcgo-ssh", "tcgo-ssh-%C"))
+	info, err := os.Stat(filepath.Join(runtime, "tcgo-ssh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	// In ~/.ssh without a runtime directory
+	home := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("HOME", home)
+	assert.Contains(t, controlOptions(), "ControlPath="+filepath.Join(home, ".ssh", "tcgo-ssh-%C"))
+	info, err = os.Stat(filepath.Join(home, ".ssh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	// Not shared through a directory others can write to
+	require.NoError(t, os.Chmod(filepath.Join(home, ".ssh"), 0o777)) // #nosec G302 -- the insecure directory under test
+	assert.Equal(t, []string{"ControlMaster=no"}, controlOptions())
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "1:10", shellQuote("1:10"))
+	assert.Equal(t, "''", shellQuote(""))
+	assert.Equal(t, "'a b'", shellQuote("a b"))
+	assert.Equal(t, `'$(reboot)'`, shellQuote("$(reboot)"))
+}
@@ -17,7 +17,7 @@ func (a *RealNetlinkAdapter) GetDetailedQdiscStats(device tc.DeviceName, handle
 	// Get the network link
 	link, err := nl.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[DetailedQdiscStats](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[DetailedQdiscStats](deviceNotFound(device, err))
 	}
 
 	// Get all qdiscs for the link
@@ -34,16 +34,7 @@ func (a *RealNetlinkAdapter) GetDetailedQdiscStats(device tc.DeviceName, handle
 			// Get basic statistics if available
 			if qdisc.Attrs().Statistics != nil {
 				qs := qdisc.Attrs().Statistics
-				if qs.Basic != nil {
-					stats.BasicStats = QdiscStats{
-						BytesSent:   qs.Basic.Bytes,
-						PacketsSent: uint64(qs.Basic.Packets),
-						// Note: Drops, Overlimits, and Requeues are not available in GnetStatsBasic
-						BytesDropped: 0,
-						Overlimits:   0,
-						Requeues:     0,
-					}
-				}
+				stats.BasicStats = QdiscStatsFromKernel(qs)
 				if qs.Queue != nil {
 					stats.Backlog = qs.Queue.Backlog
 					stats.QueueLength = qs.Queue.Qlen
@@ -58,6 +49,42 @@ func (a *RealNetlinkAdapter) GetDetailedQdiscStats(device tc.DeviceName, handle
 				}
 			}
 
+			// Get CAKE tin stats if applicable
+			if qdisc.Type() == "cake" {
+				cakeStats, err := getCAKEStats(link.Attrs().Index, qdisc.Attrs().Handle)
+				if err != nil {
+					return types.Failure[DetailedQdiscStats](fmt.Errorf("failed to read CAKE statistics: %w", err))
+				}
+				stats.CAKEStats = cakeStats
+			}
+
+			// Get fq_codel drop and flow counters if applicable
+			if qdisc.Type() == "fq_codel" {
+				fqCodelStats, err := getFQCodelStats(link.Attrs().Index, qdisc.Attrs().Handle)
+				if err != nil {
+					return types.Failure[DetailedQdiscStats](fmt.Errorf("failed to read fq_codel statistics: %w", err))
+				}
+				stats.FQCodelStats = fqCodelStats
+			}
+
+			// Get CoDel drop state if applicable
+			if qdisc.Type() == "codel" {
+				codelStats, err := getCodelStats(link.Attrs().Index, qdisc.Attrs().Handle)
+				if err != nil {
+					return types.Failure[DetailedQdiscStats](fmt.Errorf("failed to read codel statistics: %w", err))
+				}
+				stats.CodelStats = codelStats
+			}
+
+			// Get SFQ flow state if applicable
+			if sfq, ok := qdisc.(*nl.Sfq); ok {
+				sfqStats, err := getSFQStats(link, sfq)
+				if err != nil {
+					return types.Failure[DetailedQdiscStats](fmt.Errorf("failed to read SFQ statistics: %w", err))
+				}
+				stats.SFQStats = sfqStats
+			}
+
 			return types.Success(stats)
 		}
 	}
@@ -70,7 +97,7 @@ func (a *RealNetlinkAdapter) GetDetailedClassStats(device tc.DeviceName, handle
 	// Get the network link
 	link, err := nl.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[DetailedClassStats](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[DetailedClassStats](deviceNotFound(device, err))
 	}
 
 	// Get all qdiscs first
@@ -92,22 +119,7 @@ func (a *RealNetlinkAdapter) GetDetailedClassStats(device tc.DeviceName, handle
 
 				// Get basic statistics
 				if class.Attrs().Statistics != nil {
-					cs := class.Attrs().Statistics
-					if cs.Basic != nil {
-						stats.BasicStats = ClassStats{
-							BytesSent:    cs.Basic.Bytes,
-							PacketsSent:  uint64(cs.Basic.Packets),
-							BytesDropped: 0, // Not available in GnetStatsBasic
-							Overlimits:   0, // Not available in GnetStatsBasic
-						}
-					}
-					if cs.RateEst != nil {
-						stats.BasicStats.RateBPS = uint64(cs.RateEst.Bps)
-					}
-					if cs.Queue != nil {
-						stats.BasicStats.BacklogBytes = uint64(cs.Queue.Backlog)
-						stats.BasicStats.BacklogPackets = uint64(cs.Queue.Qlen)
-					}
+					stats.BasicStats = ClassStatsFromKernel(class.Attrs().Statistics)
 				}
 
 				// Get HTB-specific stats if applicable
@@ -128,6 +140,11 @@ func (a *RealNetlinkAdapter) GetDetailedClassStats(device tc.DeviceName, handle
 					}
 				}
 
+				// Account traffic the class's filters redirected elsewhere
+				if filters, err := nl.FilterList(link, qdisc.Attrs().Handle); err == nil {
+					stats.RedirectedBytes, stats.RedirectedPackets = sumRedirectedTraffic(filters, class.Attrs().Handle)
+				}
+
 				return types.Success(stats)
 			}
 		}
@@ -136,12 +153,48 @@ func (a *RealNetlinkAdapter) GetDetailedClassStats(device tc.DeviceName, handle
 	return types.Failure[DetailedClassStats](fmt.Errorf("class %s not found on device %s", handle, device))
 }
 
+// QdiscStatsFromKernel maps the generic statistics the kernel reports for a qdisc. The drop,
+// overlimit and requeue counters come with the queue statistics, not the basic ones.
+func QdiscStatsFromKernel(qs *nl.QdiscStatistics) QdiscStats {
+	var stats QdiscStats
+	if qs.Basic != nil {
+		stats.BytesSent = qs.Basic.Bytes
+		stats.PacketsSent = uint64(qs.Basic.Packets)
+	}
+	if qs.Queue != nil {
+		stats.PacketsDropped = uint64(qs.Queue.Drops)
+		stats.Overlimits = uint64(qs.Queue.Overlimits)
+		stats.Requeues = uint64(qs.Queue.Requeues)
+	}
+	return stats
+}
+
+// ClassStatsFromKernel maps the generic statistics the kernel reports for a class, like
+// QdiscStatsFromKernel does for a qdisc
+func ClassStatsFromKernel(cs *nl.ClassStatistics) ClassStats {
+	var stats ClassStats
+	if cs.Basic != nil {
+		stats.BytesSent = cs.Basic.Bytes
+		stats.PacketsSent = uint64(cs.Basic.Packets)
+	}
+	if cs.RateEst != nil {
+		stats.RateBPS = uint64(cs.RateEst.Bps) * 8 // The estimator counts bytes
+	}
+	if cs.Queue != nil {
+		stats.PacketsDropped = uint64(cs.Queue.Drops)
+		stats.Overlimits = uint64(cs.Queue.Overlimits)
+		stats.BacklogBytes = uint64(cs.Queue.Backlog)
+		stats.BacklogPackets = uint64(cs.Queue.Qlen)
+	}
+	return stats
+}
+
 // GetLinkStats returns network interface statistics
 func (a *RealNetlinkAdapter) GetLinkStats(device tc.DeviceName) types.Result[LinkStats] {
 	// Get the network link
 	link, err := nl.LinkByName(device.String())
 	if err != nil {
-		return types.Failure[LinkStats](fmt.Errorf("failed to find device %s: %w", device, err))
+		return types.Failure[LinkStats](deviceNotFound(device, err))
 	}
 
 	// Get link statistics
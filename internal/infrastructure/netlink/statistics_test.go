@@ -0,0 +1,33 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	nl "github.com/vishvananda/netlink"
+)
+
+func TestStatsFromKernel(t *testing.T) {
+	basic := &nl.GnetStatsBasic{Bytes: 1500000, Packets: 1000}
+	queue := &nl.GnetStatsQueue{Qlen: 3, Backlog: 4500, Drops: 12, Requeues: 2, Overlimits: 40}
+
+	qdisc := QdiscStatsFromKernel(&nl.QdiscStatistics{Basic: basic, Queue: queue})
+	assert.Equal(t, QdiscStats{BytesSent: 1500000, PacketsSent: 1000, PacketsDropped: 12, Overlimits: 40, Requeues: 2}, qdisc)
+
+	class := ClassStatsFromKernel(&nl.ClassStatistics{Basic: basic, Queue: queue, RateEst: &nl.GnetStatsRateEst{Bps: 125000, Pps: 100}})
+	assert.Equal(t, ClassStats{
+		BytesSent:      1500000,
+		PacketsSent:    1000,
+		PacketsDropped: 12,
+		Overlimits:     40,
+		RateBPS:        1000000,
+		BacklogBytes:   4500,
+		BacklogPackets: 3,
+	}, class)
+
+	// Kernels reporting no queue statistics leave the counters at zero
+	assert.Equal(t, ClassStats{BytesSent: 1500000, PacketsSent: 1000}, ClassStatsFromKernel(&nl.ClassStatistics{Basic: basic}))
+}
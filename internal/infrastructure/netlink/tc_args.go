@@ -0,0 +1,242 @@
+package netlink
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+	"github.com/rng999/traffic-control-go/pkg/tc"
+)
+
+// The functions below render entities in tc(8) syntax, for the exec adapter and for the
+// commands a dry run prints. Each installs what the netlink adapter installs.
+
+// qdiscParameter reads a numeric qdisc parameter, falling back to the given default
+func qdiscParameter(qdisc *entities.Qdisc, key string, defaultValue uint32) uint32 {
+	value, ok := qdisc.GetParameter(key)
+	if !ok {
+		return defaultValue
+	}
+
+	switch v := value.(type) {
+	case uint32:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	default:
+		return defaultValue
+	}
+}
+
+// netemProbability reads a percentage parameter, defaulting to zero
+func netemProbability(qdiscEntity *entities.Qdisc, key string) float32 {
+	if value, ok := qdiscEntity.GetParameter(key); ok {
+		if percent, ok := value.(float32); ok {
+			return percent
+		}
+	}
+	return 0
+}
+
+// RateArg returns a rate in the bits per second tc expects, as tc reads "bps" as bytes
+func RateArg(rate tc.Bandwidth) string {
+	return fmt.Sprintf("%dbit", rate.BitsPerSecond())
+}
+
+// ParentArg returns the tc arguments placing a qdisc at the root or under a class
+func ParentArg(parent *tc.Handle) string {
+	if parent == nil {
+		return "root"
+	}
+	return "parent " + parent.String()
+}
+
+// ProtocolArg returns the tc name of a filter's protocol
+func ProtocolArg(protocol entities.Protocol) string {
+	switch protocol {
+	case entities.ProtocolIP:
+		return "ip"
+	case entities.ProtocolIPv6:
+		return "ipv6"
+	default:
+		return "all"
+	}
+}
+
+// FilterSelector returns the classifier and its matches in tc syntax
+func FilterSelector(kind entities.FilterKind, matches []entities.Match) (string, error) {
+	var selector []string
+	switch kind {
+	case entities.FilterKindFlower:
+		selector = append(selector, "flower")
+		for _, match := range matches {
+			arg, err := flowerArg(match)
+			if err != nil {
+				return "", err
+			}
+			selector = append(selector, arg)
+		}
+	default:
+		selector = append(selector, "u32")
+		for _, match := range matches {
+			selector = append(selector, "match "+u32Arg(match))
+		}
+	}
+	return strings.Join(selector, " "), nil
+}
+
+// ActionArgs returns the tc arguments of an action chain, each preceded by "action"
+func ActionArgs(actions []entities.Action, protocol entities.Protocol) string {
+	var args string
+	for _, action := range actions {
+		args += " action " + actionArg(action, protocol)
+	}
+	return args
+}
+
+// REDArgs returns the tc arguments of RED thresholds
+func REDArgs(t entities.REDThresholds) string {
+	args := fmt.Sprintf("limit %d min %d max %d avpkt %d", t.Limit, t.Min, t.Max, t.Avpkt)
+	if t.Burst > 0 {
+		args += fmt.Sprintf(" burst %d", t.Burst)
+	}
+	return args + fmt.Sprintf(" bandwidth %s probability %g", RateArg(t.Bandwidth), t.Probability)
+}
+
+// ETSArgs returns the tc arguments of an ETS qdisc
+func ETSArgs(p entities.ETSParameters) string {
+	args := fmt.Sprintf("ets bands %d", p.EffectiveBands())
+	if p.Strict > 0 {
+		args += fmt.Sprintf(" strict %d", p.Strict)
+	}
+	if len(p.Quanta) > 0 {
+		args += " quanta" + joinArgs(p.Quanta)
+	}
+	if len(p.Priomap) > 0 {
+		args += " priomap" + joinArgs(p.Priomap)
+	}
+	return args
+}
+
+// TAPRIOArgs returns the tc arguments of a taprio qdisc
+func TAPRIOArgs(p entities.TAPRIOParameters) string {
+	args := fmt.Sprintf("taprio num_tc %d", p.NumTC)
+	if len(p.Priomap) > 0 {
+		args += " map" + joinArgs(p.Priomap)
+	}
+	args += " queues"
+	for _, queues := range p.Queues {
+		args += " " + queues.String()
+	}
+	args += fmt.Sprintf(" base-time %d", p.BaseTime)
+	if p.CycleTime > 0 {
+		args += fmt.Sprintf(" cycle-time %d", p.CycleTime)
+	}
+	if p.CycleTimeExtension > 0 {
+		args += fmt.Sprintf(" cycle-time-extension %d", p.CycleTimeExtension)
+	}
+	for _, entry := range p.Schedule {
+		args += fmt.Sprintf(" sched-entry %s %02x %d", entry.Command, entry.GateMask, entry.Interval)
+	}
+	switch {
+	case p.FullOffload:
+		args += " flags 0x2"
+	case p.TxTimeAssist:
+		args += fmt.Sprintf(" flags 0x1 txtime-delay %d clockid %s", p.TxTimeDelay, p.Clock)
+	default:
+		args += " clockid " + p.Clock.String()
+	}
+	return args
+}
+
+// u32Arg returns the u32 selector of a match. Most matches are stored in u32 syntax; the
+// others are rewritten as raw matches at their header offset.
+func u32Arg(match entities.Match) string {
+	switch m := match.(type) {
+	case *entities.TCPFlagsMatch:
+		return fmt.Sprintf("u8 0x%02x 0x%02x at nexthdr+13", m.Flags(), m.Mask())
+	case *entities.PacketLengthMatch:
+		return fmt.Sprintf("u16 0x%04x 0x%04x at 2", m.Length(), m.Mask())
+	case *entities.RawMatch:
+		return strings.TrimPrefix(m.String(), "u32 ")
+	}
+	return match.String()
+}
+
+// flowerArg returns the flower key of a match
+func flowerArg(match entities.Match) (string, error) {
+	switch m := match.(type) {
+	case *entities.IPMatch:
+		return fmt.Sprintf("%s_ip %s", direction(m.Type() == entities.MatchTypeIPSource), m.Network()), nil
+	case *entities.PortMatch:
+		return fmt.Sprintf("%s_port %d", direction(m.Type() == entities.MatchTypePortSource), m.Port()), nil
+	case *entities.PortRangeMatch:
+		return fmt.Sprintf("%s_port %d-%d", direction(m.IsSource()), m.StartPort(), m.EndPort()), nil
+	case *entities.ProtocolMatch:
+		return "ip_proto " + transportArg(m.Protocol()), nil
+	case *entities.MACMatch:
+		return fmt.Sprintf("%s_mac %s", direction(m.Type() == entities.MatchTypeMACSource), m.Address()), nil
+	case *entities.VLANIDMatch:
+		return fmt.Sprintf("vlan_id %d", m.ID()), nil
+	case *entities.VLANPriorityMatch:
+		return fmt.Sprintf("vlan_prio %d", m.Priority()), nil
+	case *entities.TOSMatch:
+		return fmt.Sprintf("ip_tos 0x%x/0x%x", m.TOS(), m.Mask()), nil
+	case *entities.DSCPMatch:
+		return fmt.Sprintf("ip_tos 0x%x/0xfc", m.DSCP()<<2), nil
+	}
+	return "", fmt.Errorf("flower filters cannot match %s", match)
+}
+
+// actionArg returns the tc arguments of an action
+func actionArg(action entities.Action, protocol entities.Protocol) string {
+	switch a := action.(type) {
+	case *entities.PoliceAction:
+		return fmt.Sprintf("police rate %s burst %d conform-exceed %s/pipe", RateArg(a.Rate()), a.Burst(), a.Exceed())
+	case *entities.DSCPRemarkAction:
+		// The netlink adapter follows the rewrite of an IPv4 header with a checksum update
+		if protocol == entities.ProtocolIPv6 {
+			return fmt.Sprintf("pedit ex munge ip6 traffic_class set 0x%x retain 0xfc pipe", a.DSCP()<<2)
+		}
+		return fmt.Sprintf("pedit ex munge ip dsfield set 0x%x retain 0xfc pipe action csum ip", a.DSCP()<<2)
+	}
+	return action.String()
+}
+
+func transportArg(protocol entities.TransportProtocol) string {
+	switch protocol {
+	case entities.TransportProtocolTCP:
+		return "tcp"
+	case entities.TransportProtocolUDP:
+		return "udp"
+	case entities.TransportProtocolICMP:
+		return "icmp"
+	case entities.TransportProtocolSCTP:
+		return "sctp"
+	default:
+		return fmt.Sprint(uint8(protocol))
+	}
+}
+
+func direction(source bool) string {
+	return flagArg(source, "src", "dst")
+}
+
+func flagArg(set bool, on, off string) string {
+	if set {
+		return on
+	}
+	return off
+}
+
+// joinArgs formats numbers as tc arguments, each preceded by a space
+func joinArgs[T uint8 | uint32](values []T) string {
+	args := ""
+	for _, value := range values {
+		args += fmt.Sprintf(" %d", value)
+	}
+	return args
+}
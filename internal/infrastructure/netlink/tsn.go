@@ -0,0 +1,186 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+// ETS and taprio option attributes from linux/pkt_sched.h
+const (
+	tcaETSNBands      = 1
+	tcaETSNStrict     = 2
+	tcaETSQuanta      = 3
+	tcaETSQuantaBand  = 4
+	tcaETSPriomap     = 5
+	tcaETSPriomapBand = 6
+
+	tcaTaprioAttrPriomap           = 1
+	tcaTaprioAttrSchedEntryList    = 2
+	tcaTaprioAttrSchedBaseTime     = 3
+	tcaTaprioAttrSchedClockID      = 5
+	tcaTaprioAttrSchedCycleTime    = 8
+	tcaTaprioAttrSchedCycleTimeExt = 9
+	tcaTaprioAttrFlags             = 10
+	tcaTaprioAttrTxTimeDelay       = 11
+	tcaTaprioSchedEntry            = 1
+	tcaTaprioSchedEntryCmd         = 2
+	tcaTaprioSchedEntryGateMask    = 3
+	tcaTaprioSchedEntryInterval    = 4
+)
+
+// taprio flags from linux/pkt_sched.h
+const (
+	taprioFlagTxTimeAssist = 0x1
+	taprioFlagFullOffload  = 0x2
+)
+
+// tcQoptMaxQueue is TC_QOPT_MAX_QUEUE, the size of the arrays of struct tc_mqprio_qopt
+const tcQoptMaxQueue = 16
+
+// etsQdisc is an ETS qdisc; the netlink library has no ETS support, so it is
+// serialised by addETSQdisc
+type etsQdisc struct {
+	netlink.QdiscAttrs
+	Params entities.ETSParameters
+}
+
+// Attrs returns the qdisc attributes
+func (q *etsQdisc) Attrs() *netlink.QdiscAttrs {
+	return &q.QdiscAttrs
+}
+
+// Type returns the qdisc kind
+func (q *etsQdisc) Type() string {
+	return "ets"
+}
+
+// taprioQdisc is a taprio qdisc, serialised by addTAPRIOQdisc
+type taprioQdisc struct {
+	netlink.QdiscAttrs
+	Params entities.TAPRIOParameters
+}
+
+// Attrs returns the qdisc attributes
+func (q *taprioQdisc) Attrs() *netlink.QdiscAttrs {
+	return &q.QdiscAttrs
+}
+
+// Type returns the qdisc kind
+func (q *taprioQdisc) Type() string {
+	return "taprio"
+}
+
+// newETSQdisc builds an ETS qdisc from the entity parameters
+func newETSQdisc(attrs netlink.QdiscAttrs, qdiscEntity *entities.Qdisc) *etsQdisc {
+	qdisc := &etsQdisc{QdiscAttrs: attrs}
+	if value, ok := qdiscEntity.GetParameter("ets"); ok {
+		qdisc.Params, _ = value.(entities.ETSParameters)
+	}
+	return qdisc
+}
+
+// newTAPRIOQdisc builds a taprio qdisc from the entity parameters
+func newTAPRIOQdisc(attrs netlink.QdiscAttrs, qdiscEntity *entities.Qdisc) *taprioQdisc {
+	qdisc := &taprioQdisc{QdiscAttrs: attrs}
+	if value, ok := qdiscEntity.GetParameter("taprio"); ok {
+		qdisc.Params, _ = value.(entities.TAPRIOParameters)
+	}
+	return qdisc
+}
+
+// addETSQdisc sends an RTM_NEWQDISC request for an ETS qdisc
+func addETSQdisc(qdisc *etsQdisc) error {
+	return executeQdiscRequest(qdisc, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, etsOptions(qdisc.Params))
+}
+
+// addTAPRIOQdisc sends an RTM_NEWQDISC request for a taprio qdisc
+func addTAPRIOQdisc(qdisc *taprioQdisc) error {
+	return executeQdiscRequest(qdisc, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, taprioOptions(qdisc.Params))
+}
+
+// etsOptions encodes the options of an ETS qdisc. The kernel parses them strictly, so the
+// nested attributes carry NLA_F_NESTED.
+func etsOptions(params entities.ETSParameters) *nl.RtAttr {
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	options.AddRtAttr(tcaETSNBands, nl.Uint8Attr(params.EffectiveBands()))
+	if params.Strict > 0 {
+		options.AddRtAttr(tcaETSNStrict, nl.Uint8Attr(params.Strict))
+	}
+	if len(params.Quanta) > 0 {
+		quanta := options.AddRtAttr(tcaETSQuanta|int(nl.NLA_F_NESTED), nil)
+		for _, quantum := range params.Quanta {
+			quanta.AddRtAttr(tcaETSQuantaBand, nl.Uint32Attr(quantum))
+		}
+	}
+	if len(params.Priomap) > 0 {
+		priomap := options.AddRtAttr(tcaETSPriomap|int(nl.NLA_F_NESTED), nil)
+		for _, band := range params.Priomap {
+			priomap.AddRtAttr(tcaETSPriomapBand, nl.Uint8Attr(band))
+		}
+	}
+	return options
+}
+
+// taprioOptions encodes the options of a taprio qdisc
+func taprioOptions(params entities.TAPRIOParameters) *nl.RtAttr {
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	options.AddRtAttr(tcaTaprioAttrPriomap, mqprioQopt(params))
+
+	entries := options.AddRtAttr(tcaTaprioAttrSchedEntryList|int(nl.NLA_F_NESTED), nil)
+	for _, entry := range params.Schedule {
+		attr := entries.AddRtAttr(tcaTaprioSchedEntry|int(nl.NLA_F_NESTED), nil)
+		attr.AddRtAttr(tcaTaprioSchedEntryCmd, nl.Uint8Attr(uint8(entry.Command)))
+		attr.AddRtAttr(tcaTaprioSchedEntryGateMask, nl.Uint32Attr(entry.GateMask))
+		attr.AddRtAttr(tcaTaprioSchedEntryInterval, nl.Uint32Attr(entry.Interval))
+	}
+
+	options.AddRtAttr(tcaTaprioAttrSchedBaseTime, nl.Uint64Attr(uint64(params.BaseTime))) // #nosec G115 - validated non-negative
+	if params.CycleTime > 0 {
+		options.AddRtAttr(tcaTaprioAttrSchedCycleTime, nl.Uint64Attr(uint64(params.CycleTime))) // #nosec G115 - positive
+	}
+	if params.CycleTimeExtension > 0 {
+		options.AddRtAttr(tcaTaprioAttrSchedCycleTimeExt, nl.Uint64Attr(uint64(params.CycleTimeExtension))) // #nosec G115 - positive
+	}
+
+	// The kernel rejects a clock for fully offloaded schedules, which run on the NIC's clock
+	var flags uint32
+	switch {
+	case params.FullOffload:
+		flags = taprioFlagFullOffload
+	case params.TxTimeAssist:
+		flags = taprioFlagTxTimeAssist
+		options.AddRtAttr(tcaTaprioAttrTxTimeDelay, nl.Uint32Attr(params.TxTimeDelay))
+	}
+	if !params.FullOffload {
+		options.AddRtAttr(tcaTaprioAttrSchedClockID, nl.Uint32Attr(uint32(params.Clock))) // #nosec G115 - clock IDs are small
+	}
+	if flags != 0 {
+		options.AddRtAttr(tcaTaprioAttrFlags, nl.Uint32Attr(flags))
+	}
+	return options
+}
+
+// mqprioQopt encodes struct tc_mqprio_qopt: the traffic class count, the priority to
+// traffic class map and the queue range of each traffic class
+func mqprioQopt(params entities.TAPRIOParameters) []byte {
+	// num_tc, prio_tc_map[16], hw, count[16], offset[16]
+	qopt := make([]byte, 2+tcQoptMaxQueue+4*tcQoptMaxQueue)
+	native := nl.NativeEndian()
+
+	qopt[0] = params.NumTC
+	copy(qopt[1:1+tcQoptMaxQueue], params.Priomap)
+	counts := 2 + tcQoptMaxQueue
+	offsets := counts + 2*tcQoptMaxQueue
+	for i, queues := range params.Queues {
+		native.PutUint16(qopt[counts+2*i:], queues.Count)
+		native.PutUint16(qopt[offsets+2*i:], queues.Offset)
+	}
+	return qopt
+}
@@ -0,0 +1,118 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+// parseOptions splits serialised TCA_OPTIONS into attributes keyed by type, with the
+// NLA_F_NESTED flag cleared
+func parseOptions(t *testing.T, data []byte) map[uint16][]syscall.NetlinkRouteAttr {
+	t.Helper()
+	attrs, err := nl.ParseRouteAttr(data)
+	require.NoError(t, err)
+	byType := make(map[uint16][]syscall.NetlinkRouteAttr)
+	for _, attr := range attrs {
+		kind := attr.Attr.Type &^ nl.NLA_F_NESTED
+		byType[kind] = append(byType[kind], attr)
+	}
+	return byType
+}
+
+func TestETSOptions(t *testing.T) {
+	// tc qdisc add ... ets bands 4 strict 1 quanta 1500 3000 4500 priomap 3 2 1 0
+	options := etsOptions(entities.ETSParameters{
+		Bands:   4,
+		Strict:  1,
+		Quanta:  []uint32{1500, 3000, 4500},
+		Priomap: []uint8{3, 2, 1, 0},
+	})
+	attrs := parseOptions(t, options.Serialize()[syscall.SizeofRtAttr:])
+
+	require.Len(t, attrs[tcaETSNBands], 1)
+	assert.Equal(t, uint8(4), attrs[tcaETSNBands][0].Value[0])
+	require.Len(t, attrs[tcaETSNStrict], 1)
+	assert.Equal(t, uint8(1), attrs[tcaETSNStrict][0].Value[0])
+
+	require.Len(t, attrs[tcaETSQuanta], 1)
+	assert.NotZero(t, attrs[tcaETSQuanta][0].Attr.Type&nl.NLA_F_NESTED, "quanta must be flagged nested")
+	quanta := parseOptions(t, attrs[tcaETSQuanta][0].Value)[tcaETSQuantaBand]
+	require.Len(t, quanta, 3)
+	assert.Equal(t, uint32(3000), nl.NativeEndian().Uint32(quanta[1].Value))
+
+	require.Len(t, attrs[tcaETSPriomap], 1)
+	priomap := parseOptions(t, attrs[tcaETSPriomap][0].Value)[tcaETSPriomapBand]
+	require.Len(t, priomap, 4)
+	assert.Equal(t, uint8(3), priomap[0].Value[0])
+	assert.Equal(t, uint8(0), priomap[3].Value[0])
+}
+
+func TestTAPRIOOptions(t *testing.T) {
+	// tc qdisc add ... taprio num_tc 2 map 0 1 queues 1@0 1@1 base-time 1000
+	//   sched-entry S 01 300000 sched-entry S 02 700000 clockid CLOCK_TAI
+	params := entities.TAPRIOParameters{
+		NumTC:   2,
+		Priomap: []uint8{0, 1},
+		Queues:  []entities.TAPRIOQueueRange{{Count: 1, Offset: 0}, {Count: 1, Offset: 1}},
+		Schedule: []entities.TAPRIOScheduleEntry{
+			{Command: entities.TAPRIOSetGates, GateMask: 0x1, Interval: 300000},
+			{Command: entities.TAPRIOSetGates, GateMask: 0x2, Interval: 700000},
+		},
+		BaseTime: 1000,
+		Clock:    entities.TAPRIOClockTAI,
+	}
+
+	t.Run("software schedule", func(t *testing.T) {
+		attrs := parseOptions(t, taprioOptions(params).Serialize()[syscall.SizeofRtAttr:])
+
+		require.Len(t, attrs[tcaTaprioAttrPriomap], 1)
+		qopt := attrs[tcaTaprioAttrPriomap][0].Value
+		require.Len(t, qopt, 82)
+		assert.Equal(t, uint8(2), qopt[0])
+		assert.Equal(t, uint8(1), qopt[2])
+		assert.Equal(t, uint16(1), nl.NativeEndian().Uint16(qopt[18+2:]), "count of class 1")
+		assert.Equal(t, uint16(1), nl.NativeEndian().Uint16(qopt[50+2:]), "offset of class 1")
+
+		require.Len(t, attrs[tcaTaprioAttrSchedEntryList], 1)
+		entries := parseOptions(t, attrs[tcaTaprioAttrSchedEntryList][0].Value)[tcaTaprioSchedEntry]
+		require.Len(t, entries, 2)
+		second := parseOptions(t, entries[1].Value)
+		assert.Equal(t, uint8(entities.TAPRIOSetGates), second[tcaTaprioSchedEntryCmd][0].Value[0])
+		assert.Equal(t, uint32(0x2), nl.NativeEndian().Uint32(second[tcaTaprioSchedEntryGateMask][0].Value))
+		assert.Equal(t, uint32(700000), nl.NativeEndian().Uint32(second[tcaTaprioSchedEntryInterval][0].Value))
+
+		assert.Equal(t, uint64(1000), nl.NativeEndian().Uint64(attrs[tcaTaprioAttrSchedBaseTime][0].Value))
+		assert.Equal(t, uint32(entities.TAPRIOClockTAI), nl.NativeEndian().Uint32(attrs[tcaTaprioAttrSchedClockID][0].Value))
+		assert.Empty(t, attrs[tcaTaprioAttrFlags])
+		assert.Empty(t, attrs[tcaTaprioAttrSchedCycleTime])
+	})
+
+	t.Run("txtime assist", func(t *testing.T) {
+		assisted := params
+		assisted.TxTimeAssist = true
+		assisted.TxTimeDelay = 200000
+		attrs := parseOptions(t, taprioOptions(assisted).Serialize()[syscall.SizeofRtAttr:])
+		assert.Equal(t, uint32(taprioFlagTxTimeAssist), nl.NativeEndian().Uint32(attrs[tcaTaprioAttrFlags][0].Value))
+		assert.Equal(t, uint32(200000), nl.NativeEndian().Uint32(attrs[tcaTaprioAttrTxTimeDelay][0].Value))
+		assert.Len(t, attrs[tcaTaprioAttrSchedClockID], 1)
+	})
+
+	t.Run("full offload omits the clock", func(t *testing.T) {
+		offloaded := params
+		offloaded.FullOffload = true
+		offloaded.CycleTime = 1000000
+		attrs := parseOptions(t, taprioOptions(offloaded).Serialize()[syscall.SizeofRtAttr:])
+		assert.Equal(t, uint32(taprioFlagFullOffload), nl.NativeEndian().Uint32(attrs[tcaTaprioAttrFlags][0].Value))
+		assert.Empty(t, attrs[tcaTaprioAttrSchedClockID])
+		assert.Equal(t, uint64(1000000), nl.NativeEndian().Uint64(attrs[tcaTaprioAttrSchedCycleTime][0].Value))
+	})
+}
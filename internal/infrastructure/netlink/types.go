@@ -1,5 +1,7 @@
 package netlink
 
+import "time"
+
 // DetailedQdiscStats represents detailed qdisc statistics
 type DetailedQdiscStats struct {
 	BasicStats   QdiscStats
@@ -11,6 +13,14 @@ type DetailedQdiscStats struct {
 	PacketsPerSecond uint64
 	// HTB specific
 	HTBStats *HTBQdiscStats
+	// CAKE specific
+	CAKEStats *CAKEQdiscStats
+	// fq_codel specific
+	FQCodelStats *FQCodelQdiscStats
+	// SFQ specific
+	SFQStats *SFQQdiscStats
+	// CoDel specific
+	CodelStats *CodelQdiscStats
 }
 
 // HTBQdiscStats represents HTB-specific statistics
@@ -19,11 +29,77 @@ type HTBQdiscStats struct {
 	Version       uint32
 }
 
+// CAKEQdiscStats represents CAKE-specific statistics
+type CAKEQdiscStats struct {
+	CapacityEstimate uint64 // bytes per second
+	MemoryLimit      uint32
+	MemoryUsed       uint32
+	Tins             []CAKETinStats
+}
+
+// CAKETinStats represents the statistics of a single CAKE priority tin
+type CAKETinStats struct {
+	Name              string
+	SentPackets       uint32
+	SentBytes         uint64
+	DroppedPackets    uint32
+	DroppedBytes      uint64
+	AckDropPackets    uint32
+	ECNMarkedPackets  uint32
+	BacklogPackets    uint32
+	BacklogBytes      uint32
+	ThresholdRate     uint64 // bytes per second
+	TargetUs          uint32
+	IntervalUs        uint32
+	PeakDelayUs       uint32
+	AvgDelayUs        uint32
+	BaseDelayUs       uint32
+	SparseFlows       uint32
+	BulkFlows         uint32
+	UnresponsiveFlows uint32
+}
+
+// FQCodelQdiscStats represents fq_codel-specific statistics
+type FQCodelQdiscStats struct {
+	MaxPacket      uint32 // largest packet seen, in bytes
+	DropOverlimit  uint32 // packets dropped because the qdisc was over its limit
+	ECNMarks       uint32
+	NewFlowCount   uint32 // flows that entered the new-flows list
+	NewFlows       uint32 // flows currently on the new-flows list
+	OldFlows       uint32 // flows currently on the old-flows list
+	CEMarks        uint32 // packets marked above the CE threshold
+	MemoryUsage    uint32 // bytes
+	DropOvermemory uint32 // packets dropped because the memory limit was hit
+}
+
+// CodelQdiscStats represents CoDel-specific statistics
+type CodelQdiscStats struct {
+	MaxPacket     uint32        // largest packet seen, in bytes
+	Count         uint32        // packets dropped since the qdisc entered the dropping state
+	LastCount     uint32        // count when the dropping state was entered last
+	LDelay        time.Duration // queueing delay of the packet dequeued last
+	DropNext      time.Duration // until the next drop while dropping; negative when overdue
+	DropOverlimit uint32        // packets dropped because the qdisc was over its limit
+	ECNMarks      uint32
+	Dropping      bool
+	CEMarks       uint32 // packets marked above the CE threshold
+}
+
+// SFQQdiscStats represents SFQ-specific statistics
+type SFQQdiscStats struct {
+	ActiveFlows uint32 // flows currently holding packets
+	FlowBuckets uint32
+	Limit       uint32
+}
+
 // DetailedClassStats represents detailed class statistics
 type DetailedClassStats struct {
 	BasicStats ClassStats
 	// HTB specific
 	HTBStats *HTBClassStats
+	// Traffic classified into the class but redirected to another device by a filter action
+	RedirectedBytes   uint64
+	RedirectedPackets uint64
 }
 
 // HTBClassStats represents HTB class-specific statistics
@@ -0,0 +1,143 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+// Offsets of the 32-bit words holding header fields, from the start of the IP header.
+// Transport headers are assumed to follow a 20-byte IPv4 header without options, as
+// tc's own "match ip dport" does.
+const (
+	u32OffsetTOSLength = 0  // Version, IHL, TOS, total length
+	u32OffsetProtocol  = 8  // TTL, protocol, checksum
+	u32OffsetSource    = 12 // Source address
+	u32OffsetDest      = 16 // Destination address
+	u32OffsetPorts     = 20 // Source port, destination port
+	u32OffsetTCPFlags  = 32 // TCP data offset, flags, window
+)
+
+// u32MaxKeys is the number of keys a selector can hold; it counts them in a byte
+const u32MaxKeys = 255
+
+// u32Keys converts a match into the u32 keys that all must hold for a packet to match. The
+// keys compare host-order words; netlink converts them to network order.
+func u32Keys(match entities.Match) ([]netlink.TcU32Key, error) {
+	switch m := match.(type) {
+	case *entities.IPMatch:
+		ip := m.Network().IP.To4()
+		if ip == nil {
+			return nil, fmt.Errorf("u32 matches IPv4 addresses only, got %s", m.Network())
+		}
+		mask := m.Network().Mask
+		if len(mask) == 16 {
+			mask = mask[12:]
+		}
+		off := int32(u32OffsetSource)
+		if m.Type() == entities.MatchTypeIPDestination {
+			off = u32OffsetDest
+		}
+		return []netlink.TcU32Key{{
+			Mask: word(mask),
+			Val:  word(ip) & word(mask),
+			Off:  off,
+		}}, nil
+	case *entities.PortMatch:
+		if m.Type() == entities.MatchTypePortSource {
+			return []netlink.TcU32Key{{Mask: 0xffff0000, Val: uint32(m.Port()) << 16, Off: u32OffsetPorts}}, nil
+		}
+		return []netlink.TcU32Key{{Mask: 0x0000ffff, Val: uint32(m.Port()), Off: u32OffsetPorts}}, nil
+	case *entities.ProtocolMatch:
+		return []netlink.TcU32Key{secondByteKey(u32OffsetProtocol, uint8(m.Protocol()), 0xff)}, nil
+	case *entities.TOSMatch:
+		return []netlink.TcU32Key{secondByteKey(u32OffsetTOSLength, m.TOS(), m.Mask())}, nil
+	case *entities.DSCPMatch:
+		return []netlink.TcU32Key{secondByteKey(u32OffsetTOSLength, m.DSCP()<<2, 0xfc)}, nil
+	case *entities.TCPFlagsMatch:
+		return []netlink.TcU32Key{
+			secondByteKey(u32OffsetProtocol, uint8(entities.TransportProtocolTCP), 0xff),
+			secondByteKey(u32OffsetTCPFlags, m.Flags(), m.Mask()),
+		}, nil
+	case *entities.PacketLengthMatch:
+		return []netlink.TcU32Key{{Mask: uint32(m.Mask()), Val: uint32(m.Length()), Off: u32OffsetTOSLength}}, nil
+	case *entities.RawMatch:
+		return []netlink.TcU32Key{{Mask: m.Mask(), Val: m.Value(), Off: m.Offset()}}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// addU32Filter sends an RTM_NEWTFILTER request for a u32 filter whose action chain the
+// netlink library cannot encode, see hasDSCPRemark
+func addU32Filter(filter *netlink.U32, actions []entities.Action) error {
+	attrs := filter.Attrs()
+	req := nl.NewNetlinkRequest(syscall.RTM_NEWTFILTER, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(attrs.LinkIndex), // #nosec G115 - kernel interface indexes fit in int32
+		Handle:  attrs.Handle,
+		Parent:  attrs.Parent,
+		Info:    netlink.MakeHandle(attrs.Priority, nl.Swap16(attrs.Protocol)),
+	})
+	req.AddData(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated("u32")))
+
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	options.AddRtAttr(nl.TCA_U32_SEL, u32NetworkOrder(filter.Sel).Serialize())
+	// Filters on a clsact hook have no class
+	if filter.ClassId != 0 {
+		options.AddRtAttr(nl.TCA_U32_CLASSID, nl.Uint32Attr(filter.ClassId))
+	}
+	if err := encodeFilterActions(options.AddRtAttr(nl.TCA_U32_ACT, nil), actions, attrs.Protocol); err != nil {
+		return err
+	}
+	req.AddData(options)
+
+	_, err := req.Execute(syscall.NETLINK_ROUTE, 0)
+	return err
+}
+
+// u32NetworkOrder returns a copy of the selector with its keys in network byte order, as the
+// netlink library sends them; a nil selector matches every packet
+func u32NetworkOrder(sel *netlink.TcU32Sel) *netlink.TcU32Sel {
+	if sel == nil {
+		return &netlink.TcU32Sel{
+			Nkeys: 1,
+			Flags: netlink.TC_U32_TERMINAL,
+			Keys:  []netlink.TcU32Key{{}},
+		}
+	}
+
+	converted := *sel
+	converted.Keys = make([]netlink.TcU32Key, len(sel.Keys))
+	for i, key := range sel.Keys {
+		key.Mask = nl.Swap32(key.Mask)
+		key.Val = nl.Swap32(key.Val)
+		converted.Keys[i] = key
+	}
+	converted.Nkeys = uint8(len(converted.Keys)) // #nosec G115 - at most u32MaxKeys keys
+	converted.Offmask = nl.Swap16(sel.Offmask)
+	converted.Hmask = nl.Swap32(sel.Hmask)
+	return &converted
+}
+
+// secondByteKey matches the second byte of the word at the offset
+func secondByteKey(off int32, value, mask uint8) netlink.TcU32Key {
+	return netlink.TcU32Key{
+		Mask: uint32(mask) << 16,
+		Val:  uint32(value&mask) << 16,
+		Off:  off,
+	}
+}
+
+// word returns the big-endian 32-bit value of a 4-byte address or mask
+func word(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
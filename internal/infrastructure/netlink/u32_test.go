@@ -0,0 +1,55 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+
+	"github.com/rng999/traffic-control-go/internal/domain/entities"
+)
+
+func TestU32Keys(t *testing.T) {
+	rawMatch, err := entities.NewRawMatch(8, 0x00060000, 0x00ff0000)
+	require.NoError(t, err)
+	sourceMatch, err := entities.NewIPSourceMatch("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		match    entities.Match
+		expected []netlink.TcU32Key
+	}{
+		{"source_cidr", sourceMatch, []netlink.TcU32Key{{Mask: 0xff000000, Val: 0x0a000000, Off: 12}}},
+		{"protocol", entities.NewProtocolMatch(entities.TransportProtocolUDP), []netlink.TcU32Key{{Mask: 0x00ff0000, Val: 0x00110000, Off: 8}}},
+		{"tos", entities.NewTOSMaskMatch(0x10, 0x1e), []netlink.TcU32Key{{Mask: 0x001e0000, Val: 0x00100000, Off: 0}}},
+		{"dscp", entities.NewDSCPMatch(46), []netlink.TcU32Key{{Mask: 0x00fc0000, Val: 0x00b80000, Off: 0}}},
+		{"tcp_flags", entities.NewTCPFlagsMatch(entities.TCPFlagSYN, entities.TCPFlagSYN|entities.TCPFlagACK), []netlink.TcU32Key{
+			{Mask: 0x00ff0000, Val: 0x00060000, Off: 8},
+			{Mask: 0x00120000, Val: 0x00020000, Off: 32},
+		}},
+		{"packet_length", entities.NewPacketLengthMatch(0, 0xffc0), []netlink.TcU32Key{{Mask: 0x0000ffc0, Val: 0, Off: 0}}},
+		{"raw", rawMatch, []netlink.TcU32Key{{Mask: 0x00ff0000, Val: 0x00060000, Off: 8}}},
+		{"mark_unsupported", entities.NewMarkMatch(1), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := u32Keys(tt.match)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, keys)
+		})
+	}
+
+	t.Run("ipv6_rejected", func(t *testing.T) {
+		match, err := entities.NewIPDestinationMatch("2001:db8::/32")
+		require.NoError(t, err)
+
+		_, err = u32Keys(match)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,193 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// fq_codel statistics type from linux/pkt_sched.h
+const tcaFQCodelXstatsQdisc = 0
+
+// fqCodelQdiscXstatsMinLen covers the type and the first six counters of
+// struct tc_fq_codel_qd_stats, which every kernel with fq_codel reports
+const fqCodelQdiscXstatsMinLen = 4 + 6*4
+
+// codelXstatsMinLen covers the counters of struct tc_codel_xstats up to dropping, which
+// every kernel with CoDel reports
+const codelXstatsMinLen = 8 * 4
+
+// getQdiscAttrs dumps the qdiscs of a link and returns the attributes of the qdisc with
+// the given handle. The netlink library drops application statistics, so the dump is
+// done here.
+func getQdiscAttrs(linkIndex int, handle uint32) ([]syscall.NetlinkRouteAttr, error) {
+	req := nl.NewNetlinkRequest(syscall.RTM_GETQDISC, syscall.NLM_F_DUMP)
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(linkIndex), // #nosec G115 - kernel interface indexes fit in int32
+	})
+
+	msgs, err := req.Execute(syscall.NETLINK_ROUTE, syscall.RTM_NEWQDISC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump qdiscs: %w", err)
+	}
+
+	for _, m := range msgs {
+		msg := nl.DeserializeTcMsg(m)
+		if msg.Ifindex != int32(linkIndex) || msg.Handle != handle { // #nosec G115 - see above
+			continue
+		}
+
+		attrs, err := nl.ParseRouteAttr(m[nl.SizeofTcMsg:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse qdisc attributes: %w", err)
+		}
+		return attrs, nil
+	}
+
+	return nil, fmt.Errorf("qdisc %x not found", handle)
+}
+
+// qdiscAppStats returns the kind of a qdisc and its TCA_STATS_APP payload, or nil when
+// the qdisc reported none
+func qdiscAppStats(attrs []syscall.NetlinkRouteAttr) (string, []byte, error) {
+	var kind string
+	var app []byte
+
+	for _, attr := range attrs {
+		switch attr.Attr.Type &^ syscall.NLA_F_NESTED {
+		case nl.TCA_KIND:
+			kind = strings.TrimRight(string(attr.Value), "\x00")
+		case nl.TCA_STATS2:
+			stats, err := nl.ParseRouteAttr(attr.Value)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to parse qdisc statistics: %w", err)
+			}
+			for _, stat := range stats {
+				if stat.Attr.Type&^syscall.NLA_F_NESTED == nl.TCA_STATS_APP {
+					app = stat.Value
+				}
+			}
+		}
+	}
+
+	return kind, app, nil
+}
+
+// getAppStats reads the TCA_STATS_APP payload of the qdisc of the kind with the given handle
+func getAppStats(linkIndex int, handle uint32, want string) ([]byte, error) {
+	attrs, err := getQdiscAttrs(linkIndex, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, app, err := qdiscAppStats(attrs)
+	if err != nil {
+		return nil, err
+	}
+	if kind != want {
+		return nil, fmt.Errorf("qdisc is %s, not %s", kind, want)
+	}
+	if app == nil {
+		return nil, fmt.Errorf("qdisc reported no %s statistics", want)
+	}
+	return app, nil
+}
+
+// getFQCodelStats reads the statistics of the fq_codel qdisc with the given handle
+func getFQCodelStats(linkIndex int, handle uint32) (*FQCodelQdiscStats, error) {
+	app, err := getAppStats(linkIndex, handle, "fq_codel")
+	if err != nil {
+		return nil, err
+	}
+	return parseFQCodelStats(app)
+}
+
+// getCodelStats reads the statistics of the CoDel qdisc with the given handle
+func getCodelStats(linkIndex int, handle uint32) (*CodelQdiscStats, error) {
+	app, err := getAppStats(linkIndex, handle, "codel")
+	if err != nil {
+		return nil, err
+	}
+	return parseCodelStats(app)
+}
+
+// parseFQCodelStats parses the TCA_STATS_APP payload of an fq_codel qdisc, a struct
+// tc_fq_codel_xstats. Counters added by newer kernels are left zero when absent.
+func parseFQCodelStats(data []byte) (*FQCodelQdiscStats, error) {
+	if len(data) < fqCodelQdiscXstatsMinLen {
+		return nil, fmt.Errorf("fq_codel statistics too short: %d bytes", len(data))
+	}
+
+	native := nl.NativeEndian()
+	if statsType := native.Uint32(data); statsType != tcaFQCodelXstatsQdisc {
+		return nil, fmt.Errorf("fq_codel statistics are of type %d, not qdisc", statsType)
+	}
+
+	counters := make([]uint32, 9)
+	for i := range counters {
+		offset := 4 + i*4
+		if offset+4 > len(data) {
+			break
+		}
+		counters[i] = native.Uint32(data[offset:])
+	}
+
+	return &FQCodelQdiscStats{
+		MaxPacket:      counters[0],
+		DropOverlimit:  counters[1],
+		ECNMarks:       counters[2],
+		NewFlowCount:   counters[3],
+		NewFlows:       counters[4],
+		OldFlows:       counters[5],
+		CEMarks:        counters[6],
+		MemoryUsage:    counters[7],
+		DropOvermemory: counters[8],
+	}, nil
+}
+
+// parseCodelStats parses the TCA_STATS_APP payload of a CoDel qdisc, a struct
+// tc_codel_xstats. The CE mark counter of newer kernels is left zero when absent.
+func parseCodelStats(data []byte) (*CodelQdiscStats, error) {
+	if len(data) < codelXstatsMinLen {
+		return nil, fmt.Errorf("codel statistics too short: %d bytes", len(data))
+	}
+
+	native := nl.NativeEndian()
+	stats := &CodelQdiscStats{
+		MaxPacket:     native.Uint32(data[0:]),
+		Count:         native.Uint32(data[4:]),
+		LastCount:     native.Uint32(data[8:]),
+		LDelay:        time.Duration(native.Uint32(data[12:])) * time.Microsecond,
+		DropNext:      time.Duration(int32(native.Uint32(data[16:]))) * time.Microsecond, // #nosec G115 - a signed field
+		DropOverlimit: native.Uint32(data[20:]),
+		ECNMarks:      native.Uint32(data[24:]),
+		Dropping:      native.Uint32(data[28:]) != 0,
+	}
+	if len(data) >= codelXstatsMinLen+4 {
+		stats.CEMarks = native.Uint32(data[32:])
+	}
+	return stats, nil
+}
+
+// getSFQStats reads the flow state of an SFQ qdisc. SFQ reports each flow holding
+// packets as a class of the qdisc, so the active flows are counted from its classes.
+func getSFQStats(link netlink.Link, sfq *netlink.Sfq) (*SFQQdiscStats, error) {
+	flows, err := netlink.ClassList(link, sfq.Attrs().Handle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SFQ flows: %w", err)
+	}
+
+	return &SFQQdiscStats{
+		ActiveFlows: uint32(len(flows)), // #nosec G115 - SFQ holds at most 65536 flows
+		FlowBuckets: sfq.Divisor,
+		Limit:       sfq.Limit,
+	}, nil
+}
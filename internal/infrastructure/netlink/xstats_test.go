@@ -0,0 +1,111 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// fqCodelXstats serialises a struct tc_fq_codel_xstats of the given type and counters
+func fqCodelXstats(statsType uint32, counters ...uint32) []byte {
+	data := nl.Uint32Attr(statsType)
+	for _, counter := range counters {
+		data = append(data, nl.Uint32Attr(counter)...)
+	}
+	return data
+}
+
+func TestParseFQCodelStats(t *testing.T) {
+	t.Run("parses_qdisc_counters", func(t *testing.T) {
+		stats, err := parseFQCodelStats(fqCodelXstats(tcaFQCodelXstatsQdisc, 1514, 20, 7, 300, 2, 5, 1, 65536, 3))
+		require.NoError(t, err)
+
+		assert.Equal(t, uint32(1514), stats.MaxPacket)
+		assert.Equal(t, uint32(20), stats.DropOverlimit)
+		assert.Equal(t, uint32(7), stats.ECNMarks)
+		assert.Equal(t, uint32(300), stats.NewFlowCount)
+		assert.Equal(t, uint32(2), stats.NewFlows)
+		assert.Equal(t, uint32(5), stats.OldFlows)
+		assert.Equal(t, uint32(1), stats.CEMarks)
+		assert.Equal(t, uint32(65536), stats.MemoryUsage)
+		assert.Equal(t, uint32(3), stats.DropOvermemory)
+	})
+
+	t.Run("leaves_counters_of_older_kernels_zero", func(t *testing.T) {
+		stats, err := parseFQCodelStats(fqCodelXstats(tcaFQCodelXstatsQdisc, 1514, 20, 7, 300, 2, 5))
+		require.NoError(t, err)
+		assert.Equal(t, uint32(5), stats.OldFlows)
+		assert.Zero(t, stats.MemoryUsage)
+		assert.Zero(t, stats.DropOvermemory)
+	})
+
+	t.Run("rejects_class_statistics", func(t *testing.T) {
+		_, err := parseFQCodelStats(fqCodelXstats(1, 0, 0, 0, 0, 0, 0))
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects_truncated_statistics", func(t *testing.T) {
+		_, err := parseFQCodelStats(fqCodelXstats(tcaFQCodelXstatsQdisc, 1514))
+		assert.Error(t, err)
+	})
+}
+
+func TestParseCodelStats(t *testing.T) {
+	overdue := int32(-2000)
+	codelXstats := func(counters ...uint32) []byte {
+		var data []byte
+		for _, counter := range counters {
+			data = append(data, nl.Uint32Attr(counter)...)
+		}
+		return data
+	}
+
+	t.Run("parses_drop_state", func(t *testing.T) {
+		stats, err := parseCodelStats(codelXstats(1514, 12, 9, 7500, uint32(overdue), 4, 6, 1, 2))
+		require.NoError(t, err)
+
+		assert.Equal(t, uint32(1514), stats.MaxPacket)
+		assert.Equal(t, uint32(12), stats.Count)
+		assert.Equal(t, uint32(9), stats.LastCount)
+		assert.Equal(t, 7500*time.Microsecond, stats.LDelay)
+		assert.Equal(t, -2*time.Millisecond, stats.DropNext)
+		assert.Equal(t, uint32(4), stats.DropOverlimit)
+		assert.Equal(t, uint32(6), stats.ECNMarks)
+		assert.True(t, stats.Dropping)
+		assert.Equal(t, uint32(2), stats.CEMarks)
+	})
+
+	t.Run("leaves_ce_marks_of_older_kernels_zero", func(t *testing.T) {
+		stats, err := parseCodelStats(codelXstats(1514, 0, 0, 100, 0, 0, 0, 0))
+		require.NoError(t, err)
+		assert.False(t, stats.Dropping)
+		assert.Zero(t, stats.CEMarks)
+	})
+
+	t.Run("rejects_truncated_statistics", func(t *testing.T) {
+		_, err := parseCodelStats(codelXstats(1514, 12))
+		assert.Error(t, err)
+	})
+}
+
+func TestQdiscAppStats(t *testing.T) {
+	app := nl.NewRtAttr(nl.TCA_STATS_APP, fqCodelXstats(tcaFQCodelXstatsQdisc, 1514, 20, 7, 300, 2, 5))
+	stats2 := nl.NewRtAttr(nl.TCA_STATS2, nil)
+	stats2.AddChild(app)
+
+	message := append(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated("fq_codel")).Serialize(), stats2.Serialize()...)
+	attrs, err := nl.ParseRouteAttr(message)
+	require.NoError(t, err)
+
+	kind, data, err := qdiscAppStats(attrs)
+	require.NoError(t, err)
+	assert.Equal(t, "fq_codel", kind)
+	assert.Equal(t, app.Serialize()[syscall.SizeofRtAttr:], data)
+}
@@ -0,0 +1,176 @@
+package statstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore is a Store kept in a SQLite database. It may share the database of a
+// configuration history, so that one file holds both.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens the database at path, creating its table of samples if needed
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS class_samples (
+		device TEXT NOT NULL,
+		handle TEXT NOT NULL,
+		name TEXT NOT NULL,
+		sampled_at INTEGER NOT NULL,
+		bytes_sent INTEGER NOT NULL,
+		packets_sent INTEGER NOT NULL,
+		packets_dropped INTEGER NOT NULL,
+		overlimits INTEGER NOT NULL,
+		backlog_bytes INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_class_samples ON class_samples(device, name, sampled_at);
+	CREATE INDEX IF NOT EXISTS idx_class_samples_time ON class_samples(sampled_at);
+	`)
+	if err != nil {
+		if closeErr := db.Close(); closeErr != nil {
+			return nil, fmt.Errorf("failed to create tables: %w, also failed to close db: %w", err, closeErr)
+		}
+		return nil, fmt.Errorf("failed to create tables: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Append records samples in one transaction
+func (s *SQLiteStore) Append(ctx context.Context, samples []ClassSample) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, sample := range samples {
+		// Counters are stored as SQLite's signed integers; they do not reach 2^63
+		_, err := tx.ExecContext(ctx, `INSERT INTO class_samples
+			(device, handle, name, sampled_at, bytes_sent, packets_sent, packets_dropped, overlimits, backlog_bytes)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			sample.Device, sample.Handle, sample.Name, sample.Time.UnixNano(),
+			int64(sample.BytesSent), int64(sample.PacketsSent), int64(sample.PacketsDropped), // #nosec G115
+			int64(sample.Overlimits), int64(sample.BacklogBytes), // #nosec G115
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record sample of %s: %w", sample.Handle, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ClassSamples returns the samples of the named class taken in [from, to], oldest first
+func (s *SQLiteStore) ClassSamples(ctx context.Context, device, name string, from, to time.Time) ([]ClassSample, error) {
+	query := `SELECT handle, sampled_at, bytes_sent, packets_sent, packets_dropped, overlimits, backlog_bytes
+		FROM class_samples WHERE device = ? AND name = ?`
+	args := []interface{}{device, name}
+	if !from.IsZero() {
+		query += " AND sampled_at >= ?"
+		args = append(args, from.UnixNano())
+	}
+	if !to.IsZero() {
+		query += " AND sampled_at <= ?"
+		args = append(args, to.UnixNano())
+	}
+	query += " ORDER BY sampled_at, rowid"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query samples: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var samples []ClassSample
+	for rows.Next() {
+		sample := ClassSample{Device: device, Name: name}
+		var sampledAt, bytesSent, packetsSent, packetsDropped, overlimits, backlog int64
+		if err := rows.Scan(&sample.Handle, &sampledAt, &bytesSent, &packetsSent, &packetsDropped, &overlimits, &backlog); err != nil {
+			return nil, fmt.Errorf("failed to read sample: %w", err)
+		}
+		sample.Time = time.Unix(0, sampledAt)
+		sample.BytesSent, sample.PacketsSent = uint64(bytesSent), uint64(packetsSent)         // #nosec G115
+		sample.PacketsDropped, sample.Overlimits = uint64(packetsDropped), uint64(overlimits) // #nosec G115
+		sample.BacklogBytes = uint64(backlog)                                                 // #nosec G115
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+// Prune deletes the samples taken before the time
+func (s *SQLiteStore) Prune(ctx context.Context, before time.Time) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM class_samples WHERE sampled_at < ?", before.UnixNano()); err != nil {
+		return fmt.Errorf("failed to prune samples: %w", err)
+	}
+	return nil
+}
+
+// Downsample thins the samples taken before the time to one per class and interval, in one
+// transaction
+func (s *SQLiteStore) Downsample(ctx context.Context, before time.Time, resolution time.Duration) error {
+	if resolution <= 0 {
+		return nil
+	}
+	before = before.Truncate(resolution)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `SELECT rowid, device, handle, name, sampled_at, bytes_sent, packets_sent, backlog_bytes
+		FROM class_samples WHERE sampled_at < ? ORDER BY device, name, handle, sampled_at, rowid`, before.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to query samples: %w", err)
+	}
+	var ids []int64
+	var samples []ClassSample
+	var backlogs []uint64 // As stored, so that only the backlogs raised are written back
+	for rows.Next() {
+		var id, sampledAt, bytesSent, packetsSent, backlog int64
+		var sample ClassSample
+		if err := rows.Scan(&id, &sample.Device, &sample.Handle, &sample.Name, &sampledAt, &bytesSent, &packetsSent, &backlog); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to read sample: %w", err)
+		}
+		sample.Time = time.Unix(0, sampledAt)
+		sample.BytesSent, sample.PacketsSent, sample.BacklogBytes = uint64(bytesSent), uint64(packetsSent), uint64(backlog) // #nosec G115
+		ids = append(ids, id)
+		samples = append(samples, sample)
+		backlogs = append(backlogs, sample.BacklogBytes)
+	}
+	if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+		return fmt.Errorf("failed to read samples: %w", err)
+	}
+
+	for i, keep := range thin(samples, resolution) {
+		switch {
+		case !keep:
+			_, err = tx.ExecContext(ctx, "DELETE FROM class_samples WHERE rowid = ?", ids[i])
+		case samples[i].BacklogBytes != backlogs[i]:
+			_, err = tx.ExecContext(ctx, "UPDATE class_samples SET backlog_bytes = ? WHERE rowid = ?", int64(samples[i].BacklogBytes), ids[i]) // #nosec G115
+		}
+		if err != nil {
+			return fmt.Errorf("failed to downsample samples: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
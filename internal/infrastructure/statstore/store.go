@@ -0,0 +1,175 @@
+// Package statstore keeps a history of traffic class statistics: samples of the kernel's
+// counters taken at intervals, from which the traffic of a class over a time window is
+// derived.
+package statstore
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ClassSample is the counters of a traffic class at one moment. The counters are the
+// kernel's, cumulative since the class was created.
+type ClassSample struct {
+	Device         string
+	Handle         string
+	Name           string // Name the class was applied under
+	Time           time.Time
+	BytesSent      uint64
+	PacketsSent    uint64
+	PacketsDropped uint64
+	Overlimits     uint64
+	BacklogBytes   uint64
+}
+
+// Store keeps class samples
+type Store interface {
+	// Append records samples
+	Append(ctx context.Context, samples []ClassSample) error
+	// ClassSamples returns the samples of the named class of the device taken in [from, to],
+	// oldest first. A zero bound leaves that side of the window open.
+	ClassSamples(ctx context.Context, device, name string, from, to time.Time) ([]ClassSample, error)
+	// Prune deletes the samples taken before the time
+	Prune(ctx context.Context, before time.Time) error
+	// Downsample thins the samples taken before the time to the last of each class in each
+	// interval of the resolution, aligned to the clock. The counters are cumulative, so the
+	// traffic between the samples kept is unchanged; each keeps the largest backlog of the
+	// samples it replaces. Intervals the time falls in are left alone.
+	Downsample(ctx context.Context, before time.Time, resolution time.Duration) error
+	Close() error
+}
+
+// MemoryStore is a Store kept in memory, lost when the process exits
+type MemoryStore struct {
+	mu      sync.RWMutex
+	samples []ClassSample
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Append records samples
+func (s *MemoryStore) Append(ctx context.Context, samples []ClassSample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples = append(s.samples, samples...)
+	return nil
+}
+
+// ClassSamples returns the samples of the named class taken in [from, to], oldest first
+func (s *MemoryStore) ClassSamples(ctx context.Context, device, name string, from, to time.Time) ([]ClassSample, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var samples []ClassSample
+	for _, sample := range s.samples {
+		if sample.Device == device && sample.Name == name && inWindow(sample.Time, from, to) {
+			samples = append(samples, sample)
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples, nil
+}
+
+// Prune deletes the samples taken before the time
+func (s *MemoryStore) Prune(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.samples[:0]
+	for _, sample := range s.samples {
+		if !sample.Time.Before(before) {
+			kept = append(kept, sample)
+		}
+	}
+	s.samples = kept
+	return nil
+}
+
+// Downsample thins the samples taken before the time to one per class and interval
+func (s *MemoryStore) Downsample(ctx context.Context, before time.Time, resolution time.Duration) error {
+	if resolution <= 0 {
+		return nil
+	}
+	before = before.Truncate(resolution)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var old, recent []ClassSample
+	for _, sample := range s.samples {
+		if sample.Time.Before(before) {
+			old = append(old, sample)
+		} else {
+			recent = append(recent, sample)
+		}
+	}
+	sort.SliceStable(old, func(i, j int) bool {
+		a, b := old[i], old[j]
+		if a.Device != b.Device {
+			return a.Device < b.Device
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Handle != b.Handle {
+			return a.Handle < b.Handle
+		}
+		return a.Time.Before(b.Time)
+	})
+
+	kept := make([]ClassSample, 0, len(old)+len(recent))
+	for i, keep := range thin(old, resolution) {
+		if keep {
+			kept = append(kept, old[i])
+		}
+	}
+	s.samples = append(kept, recent...)
+	return nil
+}
+
+// Close releases nothing; the samples stay readable
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func inWindow(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to))
+}
+
+// thin returns which of the samples, sorted by class and then time, a downsample keeps: the
+// first of each class, which the traffic is counted from, the last of its class in its
+// interval, and the last before the counters of the class restart so that the traffic of a
+// recreated class is not lost. A sample kept takes the largest backlog of those it replaces.
+func thin(samples []ClassSample, resolution time.Duration) []bool {
+	keep := make([]bool, len(samples))
+	var backlog uint64
+	for i := range samples {
+		backlog = max(backlog, samples[i].BacklogBytes)
+		first := i == 0 || !sameClass(samples[i-1], samples[i])
+		if !first && i+1 < len(samples) && sameInterval(samples[i], samples[i+1], resolution) {
+			continue
+		}
+		keep[i] = true
+		samples[i].BacklogBytes = backlog
+		backlog = 0
+	}
+	return keep
+}
+
+// sameInterval reports whether the next sample of a class was taken in the same interval
+// and continues the counters of the sample before it
+func sameInterval(sample, next ClassSample, resolution time.Duration) bool {
+	return sameClass(sample, next) && sample.Time.Truncate(resolution).Equal(next.Time.Truncate(resolution)) &&
+		next.BytesSent >= sample.BytesSent && next.PacketsSent >= sample.PacketsSent
+}
+
+// sameClass reports whether two samples are of the same class
+func sameClass(a, b ClassSample) bool {
+	return a.Device == b.Device && a.Name == b.Name && a.Handle == b.Handle
+}
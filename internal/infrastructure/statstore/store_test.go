@@ -0,0 +1,120 @@
+package statstore_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rng999/traffic-control-go/internal/infrastructure/statstore"
+)
+
+// stores opens an empty store of each kind
+var stores = map[string]func(t *testing.T) statstore.Store{
+	"memory": func(t *testing.T) statstore.Store { return statstore.NewMemoryStore() },
+	"sqlite": func(t *testing.T) statstore.Store {
+		store, err := statstore.NewSQLiteStore(filepath.Join(t.TempDir(), "history.db"))
+		require.NoError(t, err)
+		return store
+	},
+}
+
+func TestStores(t *testing.T) {
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			defer func() { assert.NoError(t, store.Close()) }()
+			ctx := context.Background()
+			start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+			var samples []statstore.ClassSample
+			for i := 0; i < 4; i++ {
+				samples = append(samples, statstore.ClassSample{
+					Device: "eth0", Handle: "1:11", Name: "web",
+					Time:      start.Add(time.Duration(3-i) * time.Minute),
+					BytesSent: uint64(3-i) * 1000, PacketsSent: uint64(3 - i), BacklogBytes: 64,
+				})
+			}
+			samples = append(samples, statstore.ClassSample{Device: "eth0", Handle: "1:12", Name: "ssh", Time: start})
+			require.NoError(t, store.Append(ctx, samples))
+
+			web, err := store.ClassSamples(ctx, "eth0", "web", time.Time{}, time.Time{})
+			require.NoError(t, err)
+			require.Len(t, web, 4)
+			assert.True(t, web[0].Time.Equal(start), "oldest first")
+			assert.Equal(t, statstore.ClassSample{
+				Device: "eth0", Handle: "1:11", Name: "web", Time: web[3].Time,
+				BytesSent: 3000, PacketsSent: 3, BacklogBytes: 64,
+			}, web[3])
+
+			window, err := store.ClassSamples(ctx, "eth0", "web", start.Add(time.Minute), start.Add(2*time.Minute))
+			require.NoError(t, err)
+			assert.Len(t, window, 2)
+
+			require.NoError(t, store.Prune(ctx, start.Add(2*time.Minute)))
+			web, err = store.ClassSamples(ctx, "eth0", "web", time.Time{}, time.Time{})
+			require.NoError(t, err)
+			assert.Len(t, web, 2)
+		})
+	}
+}
+
+func TestStores_Downsample(t *testing.T) {
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			defer func() { assert.NoError(t, store.Close()) }()
+			ctx := context.Background()
+			start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+			// A sample of web every 10 minutes for two hours; the class is recreated at 12:40,
+			// restarting its counters. ssh is sampled once an hour.
+			var samples []statstore.ClassSample
+			sent := uint64(0)
+			for i := 0; i < 12; i++ {
+				sent += 1000
+				if i == 4 {
+					sent = 500
+				}
+				samples = append(samples, statstore.ClassSample{
+					Device: "eth0", Handle: "1:11", Name: "web",
+					Time: start.Add(time.Duration(i) * 10 * time.Minute), BytesSent: sent, BacklogBytes: uint64(i),
+				})
+			}
+			samples = append(samples,
+				statstore.ClassSample{Device: "eth0", Handle: "1:12", Name: "ssh", Time: start, BytesSent: 10},
+				statstore.ClassSample{Device: "eth0", Handle: "1:12", Name: "ssh", Time: start.Add(time.Hour), BytesSent: 20})
+			require.NoError(t, store.Append(ctx, samples))
+
+			// The hour 13:00 is still running at 13:30, so only 12:00 is thinned
+			require.NoError(t, store.Downsample(ctx, start.Add(90*time.Minute), time.Hour))
+			web, err := store.ClassSamples(ctx, "eth0", "web", time.Time{}, time.Time{})
+			require.NoError(t, err)
+			times := make([]time.Duration, 0, len(web))
+			for _, sample := range web {
+				times = append(times, sample.Time.Sub(start))
+			}
+			assert.Equal(t, []time.Duration{
+				0, 30 * time.Minute, 50 * time.Minute, // The first, before the restart and the end of the hour
+				60 * time.Minute, 70 * time.Minute, 80 * time.Minute, 90 * time.Minute, 100 * time.Minute, 110 * time.Minute,
+			}, times)
+			assert.Equal(t, uint64(4000), web[1].BytesSent)
+			assert.Equal(t, uint64(3), web[1].BacklogBytes, "the largest backlog of the samples replaced")
+			assert.Equal(t, uint64(1500), web[2].BytesSent)
+			assert.Equal(t, uint64(5), web[2].BacklogBytes)
+
+			ssh, err := store.ClassSamples(ctx, "eth0", "ssh", time.Time{}, time.Time{})
+			require.NoError(t, err)
+			assert.Len(t, ssh, 2, "one sample per hour is kept as it is")
+
+			// Downsampling again changes nothing
+			require.NoError(t, store.Downsample(ctx, start.Add(90*time.Minute), time.Hour))
+			again, err := store.ClassSamples(ctx, "eth0", "web", time.Time{}, time.Time{})
+			require.NoError(t, err)
+			assert.Equal(t, web, again)
+		})
+	}
+}
@@ -24,6 +24,16 @@ func convertMatchData(matches []events.MatchData) map[string]string {
 			matchTypeName = "protocol"
 		case entities.MatchTypeMark:
 			matchTypeName = "mark"
+		case entities.MatchTypeMACSource:
+			matchTypeName = "src_mac"
+		case entities.MatchTypeMACDestination:
+			matchTypeName = "dst_mac"
+		case entities.MatchTypeVLANID:
+			matchTypeName = "vlan_id"
+		case entities.MatchTypeVLANPriority:
+			matchTypeName = "vlan_prio"
+		case entities.MatchTypeCgroup:
+			matchTypeName = "cgroup"
 		default:
 			matchTypeName = "unknown"
 		}
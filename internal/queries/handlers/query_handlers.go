@@ -29,16 +29,12 @@ func (h *GetQdiscByDeviceHandler) Handle(ctx context.Context, query interface{})
 		return nil, fmt.Errorf("invalid query type: expected *GetQdiscByDeviceQuery, got %T", query)
 	}
 
-	// Load aggregate
-	aggregateID := fmt.Sprintf("tc:%s", qdiscQuery.DeviceName())
-	events, err := h.eventStore.GetEvents(aggregateID)
-	if err != nil {
+	// Load aggregate from its latest snapshot and subsequent events
+	aggregate := aggregates.NewTrafficControlAggregate(qdiscQuery.DeviceName())
+	if err := eventstore.LoadAggregate(h.eventStore, aggregate.GetID(), aggregate); err != nil {
 		return nil, fmt.Errorf("failed to load events: %w", err)
 	}
 
-	// Reconstruct aggregate from events
-	aggregate := aggregates.FromEvents(qdiscQuery.DeviceName(), events)
-
 	// Convert to view models
 	var views []models.QdiscView
 	for _, qdisc := range aggregate.GetQdiscs() {
@@ -69,16 +65,12 @@ func (h *GetClassesByDeviceHandler) Handle(ctx context.Context, query interface{
 		return nil, fmt.Errorf("invalid query type: expected *GetClassesByDeviceQuery, got %T", query)
 	}
 
-	// Load aggregate
-	aggregateID := fmt.Sprintf("tc:%s", classQuery.DeviceName())
-	events, err := h.eventStore.GetEvents(aggregateID)
-	if err != nil {
+	// Load aggregate from its latest snapshot and subsequent events
+	aggregate := aggregates.NewTrafficControlAggregate(classQuery.DeviceName())
+	if err := eventstore.LoadAggregate(h.eventStore, aggregate.GetID(), aggregate); err != nil {
 		return nil, fmt.Errorf("failed to load events: %w", err)
 	}
 
-	// Reconstruct aggregate from events
-	aggregate := aggregates.FromEvents(classQuery.DeviceName(), events)
-
 	// Convert to view models
 	var views []models.ClassView
 	for _, class := range aggregate.GetClasses() {
@@ -109,16 +101,12 @@ func (h *GetFiltersByDeviceHandler) Handle(ctx context.Context, query interface{
 		return nil, fmt.Errorf("invalid query type: expected *GetFiltersByDeviceQuery, got %T", query)
 	}
 
-	// Load aggregate
-	aggregateID := fmt.Sprintf("tc:%s", filterQuery.DeviceName())
-	events, err := h.eventStore.GetEvents(aggregateID)
-	if err != nil {
+	// Load aggregate from its latest snapshot and subsequent events
+	aggregate := aggregates.NewTrafficControlAggregate(filterQuery.DeviceName())
+	if err := eventstore.LoadAggregate(h.eventStore, aggregate.GetID(), aggregate); err != nil {
 		return nil, fmt.Errorf("failed to load events: %w", err)
 	}
 
-	// Reconstruct aggregate from events
-	aggregate := aggregates.FromEvents(filterQuery.DeviceName(), events)
-
 	// Convert to view models
 	var views []models.FilterView
 	for _, filter := range aggregate.GetFilters() {
@@ -149,16 +137,12 @@ func (h *GetTrafficControlConfigHandler) Handle(ctx context.Context, query inter
 		return nil, fmt.Errorf("invalid query type: expected *GetTrafficControlConfigQuery, got %T", query)
 	}
 
-	// Load aggregate
-	aggregateID := fmt.Sprintf("tc:%s", configQuery.DeviceName())
-	events, err := h.eventStore.GetEvents(aggregateID)
-	if err != nil {
+	// Load aggregate from its latest snapshot and subsequent events
+	aggregate := aggregates.NewTrafficControlAggregate(configQuery.DeviceName())
+	if err := eventstore.LoadAggregate(h.eventStore, aggregate.GetID(), aggregate); err != nil {
 		return nil, fmt.Errorf("failed to load events: %w", err)
 	}
 
-	// Reconstruct aggregate from events
-	aggregate := aggregates.FromEvents(configQuery.DeviceName(), events)
-
 	// Build complete view
 	view := models.TrafficControlConfigView{
 		DeviceName: configQuery.DeviceName().String(),
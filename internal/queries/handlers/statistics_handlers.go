@@ -53,6 +53,7 @@ type ClassStatistics struct {
 	Name          string                      `json:"name"`
 	Stats         netlink.ClassStats          `json:"stats"`
 	DetailedStats *netlink.DetailedClassStats `json:"detailed_stats,omitempty"`
+	LeafQdisc     *QdiscStatistics            `json:"leaf_qdisc,omitempty"`
 }
 
 // FilterStatistics represents filter statistics with metadata
@@ -80,6 +81,11 @@ func (s *StatisticsQueryService) GetDeviceStatistics(ctx context.Context, device
 	s.logger.Info("Getting device statistics",
 		logging.String("device", deviceName))
 
+	device, err := tc.NewDevice(deviceName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device name: %w", err)
+	}
+
 	// Get configuration from read model
 	var readModel projections.TrafficControlReadModel
 	modelID := fmt.Sprintf("tc:%s", deviceName)
@@ -88,12 +94,9 @@ func (s *StatisticsQueryService) GetDeviceStatistics(ctx context.Context, device
 		s.logger.Warn("No configuration found for device",
 			logging.String("device", deviceName),
 			logging.Error(err))
-		// Continue anyway - we can still get raw statistics
-	}
-
-	device, err := tc.NewDevice(deviceName)
-	if err != nil {
-		return nil, fmt.Errorf("invalid device name: %w", err)
+		// Report what is installed instead, as for a monitor running unprivileged beside
+		// the process that configured the device; reading statistics needs no privilege
+		readModel = s.installedConfiguration(device)
 	}
 
 	stats := &DeviceStatistics{
@@ -137,6 +140,9 @@ func (s *StatisticsQueryService) GetDeviceStatistics(ctx context.Context, device
 							BytesPerSecond:   detailedStats.BytesPerSecond,
 							PacketsPerSecond: detailedStats.PacketsPerSecond,
 							HTBStats:         detailedStats.HTBStats,
+							CAKEStats:        detailedStats.CAKEStats,
+							FQCodelStats:     detailedStats.FQCodelStats,
+							SFQStats:         detailedStats.SFQStats,
 						}
 					} else {
 						s.logger.Debug("Failed to get detailed qdisc statistics",
@@ -187,6 +193,8 @@ func (s *StatisticsQueryService) GetDeviceStatistics(ctx context.Context, device
 							logging.Error(detailedResult.Error()))
 					}
 
+					classStat.LeafQdisc = leafQdiscStatistics(s.netlinkAdapter, device, handle)
+
 					stats.ClassStats = append(stats.ClassStats, classStat)
 					break
 				}
@@ -234,6 +242,31 @@ func (s *StatisticsQueryService) GetDeviceStatistics(ctx context.Context, device
 	return stats, nil
 }
 
+// installedConfiguration describes the qdiscs and classes installed on the device, for
+// devices this process has no configuration of
+func (s *StatisticsQueryService) installedConfiguration(device tc.DeviceName) projections.TrafficControlReadModel {
+	readModel := projections.TrafficControlReadModel{DeviceName: device.String()}
+	if result := s.netlinkAdapter.GetQdiscs(device); result.IsSuccess() {
+		for _, qdisc := range result.Value() {
+			model := projections.QdiscReadModel{Handle: qdisc.Handle.String(), Type: qdisc.Type.String()}
+			if qdisc.Parent != nil {
+				model.Parent = qdisc.Parent.String()
+			}
+			readModel.Qdiscs = append(readModel.Qdiscs, model)
+		}
+	}
+	if result := s.netlinkAdapter.GetClasses(device); result.IsSuccess() {
+		for _, class := range result.Value() {
+			readModel.Classes = append(readModel.Classes, projections.ClassReadModel{
+				Handle: class.Handle.String(),
+				Parent: class.Parent.String(),
+				Type:   class.Type.String(),
+			})
+		}
+	}
+	return readModel
+}
+
 // GetRealtimeStatistics gets real-time statistics without read model
 func (s *StatisticsQueryService) GetRealtimeStatistics(ctx context.Context, deviceName string) (*DeviceStatistics, error) {
 	device, err := tc.NewDevice(deviceName)
@@ -267,9 +300,10 @@ func (s *StatisticsQueryService) GetRealtimeStatistics(ctx context.Context, devi
 	if classResult.IsSuccess() {
 		for _, info := range classResult.Value() {
 			classStat := ClassStatistics{
-				Handle: info.Handle.String(),
-				Parent: info.Parent.String(),
-				Stats:  info.Statistics,
+				Handle:    info.Handle.String(),
+				Parent:    info.Parent.String(),
+				Stats:     info.Statistics,
+				LeafQdisc: leafQdiscStatistics(s.netlinkAdapter, device, info.Handle),
 			}
 			stats.ClassStats = append(stats.ClassStats, classStat)
 		}
@@ -278,6 +312,34 @@ func (s *StatisticsQueryService) GetRealtimeStatistics(ctx context.Context, devi
 	return stats, nil
 }
 
+// leafQdiscStatistics returns the statistics of the qdisc attached under a class, such as
+// the fq_codel or sfq queueing its traffic, or nil when the class has none
+func leafQdiscStatistics(adapter netlink.Adapter, device tc.DeviceName, class tc.Handle) *QdiscStatistics {
+	qdiscs := adapter.GetQdiscs(device)
+	if !qdiscs.IsSuccess() {
+		return nil
+	}
+
+	for _, info := range qdiscs.Value() {
+		if info.Parent == nil || !info.Parent.Equals(class) {
+			continue
+		}
+
+		leaf := &QdiscStatistics{
+			Handle: info.Handle.String(),
+			Type:   info.Type.String(),
+			Stats:  info.Statistics,
+		}
+		if detailedResult := adapter.GetDetailedQdiscStats(device, info.Handle); detailedResult.IsSuccess() {
+			detailedStats := detailedResult.Value()
+			leaf.DetailedStats = &detailedStats
+		}
+		return leaf
+	}
+
+	return nil
+}
+
 // GetDeviceStatisticsHandler handles queries for device statistics
 type GetDeviceStatisticsHandler struct {
 	statisticsService *StatisticsQueryService
@@ -360,30 +422,7 @@ func convertDeviceStatisticsToView(stats *DeviceStatistics) models.DeviceStatist
 
 	// Convert qdisc statistics
 	for _, qdisc := range stats.QdiscStats {
-		qdiscView := models.QdiscStatisticsView{
-			Handle:        qdisc.Handle,
-			Type:          qdisc.Type,
-			BytesSent:     qdisc.Stats.BytesSent,
-			PacketsSent:   qdisc.Stats.PacketsSent,
-			BytesDropped:  qdisc.Stats.BytesDropped,
-			Overlimits:    qdisc.Stats.Overlimits,
-			Requeues:      qdisc.Stats.Requeues,
-			DetailedStats: make(map[string]interface{}),
-		}
-
-		if qdisc.DetailedStats != nil {
-			qdiscView.Backlog = qdisc.DetailedStats.Backlog
-			qdiscView.QueueLength = qdisc.DetailedStats.QueueLength
-			qdiscView.DetailedStats["backlog_bytes"] = qdisc.DetailedStats.BacklogBytes
-			qdiscView.DetailedStats["bytes_per_second"] = qdisc.DetailedStats.BytesPerSecond
-			qdiscView.DetailedStats["packets_per_second"] = qdisc.DetailedStats.PacketsPerSecond
-			if qdisc.DetailedStats.HTBStats != nil {
-				qdiscView.DetailedStats["htb_direct_packets"] = qdisc.DetailedStats.HTBStats.DirectPackets
-				qdiscView.DetailedStats["htb_version"] = qdisc.DetailedStats.HTBStats.Version
-			}
-		}
-
-		view.QdiscStats = append(view.QdiscStats, qdiscView)
+		view.QdiscStats = append(view.QdiscStats, NewQdiscStatisticsView(qdisc.Handle, qdisc.Type, qdisc.Stats, qdisc.DetailedStats))
 	}
 
 	// Convert class statistics
@@ -394,7 +433,7 @@ func convertDeviceStatisticsToView(stats *DeviceStatistics) models.DeviceStatist
 			Name:           class.Name,
 			BytesSent:      class.Stats.BytesSent,
 			PacketsSent:    class.Stats.PacketsSent,
-			BytesDropped:   class.Stats.BytesDropped,
+			PacketsDropped: class.Stats.PacketsDropped,
 			Overlimits:     class.Stats.Overlimits,
 			BacklogBytes:   class.Stats.BacklogBytes,
 			BacklogPackets: class.Stats.BacklogPackets,
@@ -412,6 +451,14 @@ func convertDeviceStatisticsToView(stats *DeviceStatistics) models.DeviceStatist
 			classView.DetailedStats["htb_ceil"] = class.DetailedStats.HTBStats.Ceil
 			classView.DetailedStats["htb_level"] = class.DetailedStats.HTBStats.Level
 		}
+		if class.DetailedStats != nil && class.DetailedStats.RedirectedPackets > 0 {
+			classView.DetailedStats["redirected_bytes"] = class.DetailedStats.RedirectedBytes
+			classView.DetailedStats["redirected_packets"] = class.DetailedStats.RedirectedPackets
+		}
+		if class.LeafQdisc != nil {
+			leafView := NewQdiscStatisticsView(class.LeafQdisc.Handle, class.LeafQdisc.Type, class.LeafQdisc.Stats, class.LeafQdisc.DetailedStats)
+			classView.LeafQdisc = &leafView
+		}
 
 		view.ClassStats = append(view.ClassStats, classView)
 	}
@@ -459,32 +506,15 @@ func (h *GetQdiscStatisticsHandler) Handle(ctx context.Context, query interface{
 	// Find the specific qdisc
 	for _, qdisc := range qdiscs.Value() {
 		if qdisc.Handle.String() == qdiscQuery.Handle().String() {
-			view := models.QdiscStatisticsView{
-				Handle:        qdisc.Handle.String(),
-				Type:          qdisc.Type.String(),
-				BytesSent:     qdisc.Statistics.BytesSent,
-				PacketsSent:   qdisc.Statistics.PacketsSent,
-				BytesDropped:  qdisc.Statistics.BytesDropped,
-				Overlimits:    qdisc.Statistics.Overlimits,
-				Requeues:      qdisc.Statistics.Requeues,
-				DetailedStats: make(map[string]interface{}),
-			}
-
 			// Get detailed statistics through adapter interface
+			var detailedStats *netlink.DetailedQdiscStats
 			detailedResult := h.netlinkAdapter.GetDetailedQdiscStats(qdiscQuery.DeviceName(), qdiscQuery.Handle())
 			if detailedResult.IsSuccess() {
-				detailedStats := detailedResult.Value()
-				view.Backlog = detailedStats.Backlog
-				view.QueueLength = detailedStats.QueueLength
-				view.DetailedStats["backlog_bytes"] = detailedStats.BacklogBytes
-				view.DetailedStats["bytes_per_second"] = detailedStats.BytesPerSecond
-				view.DetailedStats["packets_per_second"] = detailedStats.PacketsPerSecond
-				if detailedStats.HTBStats != nil {
-					view.DetailedStats["htb_direct_packets"] = detailedStats.HTBStats.DirectPackets
-					view.DetailedStats["htb_version"] = detailedStats.HTBStats.Version
-				}
+				detailed := detailedResult.Value()
+				detailedStats = &detailed
 			}
 
+			view := NewQdiscStatisticsView(qdisc.Handle.String(), qdisc.Type.String(), qdisc.Statistics, detailedStats)
 			return view, nil
 		}
 	}
@@ -492,6 +522,107 @@ func (h *GetQdiscStatisticsHandler) Handle(ctx context.Context, query interface{
 	return nil, fmt.Errorf("qdisc %s not found on device %s", qdiscQuery.Handle(), qdiscQuery.DeviceName())
 }
 
+// NewQdiscStatisticsView converts the counters of a qdisc, and its detailed statistics when
+// available, to a view
+func NewQdiscStatisticsView(handle, qdiscType string, stats netlink.QdiscStats, detailed *netlink.DetailedQdiscStats) models.QdiscStatisticsView {
+	view := models.QdiscStatisticsView{
+		Handle:         handle,
+		Type:           qdiscType,
+		BytesSent:      stats.BytesSent,
+		PacketsSent:    stats.PacketsSent,
+		PacketsDropped: stats.PacketsDropped,
+		Overlimits:     stats.Overlimits,
+		Requeues:       stats.Requeues,
+		DetailedStats:  make(map[string]interface{}),
+	}
+	if detailed == nil {
+		return view
+	}
+
+	view.Backlog = detailed.Backlog
+	view.QueueLength = detailed.QueueLength
+	view.DetailedStats["backlog_bytes"] = detailed.BacklogBytes
+	view.DetailedStats["bytes_per_second"] = detailed.BytesPerSecond
+	view.DetailedStats["packets_per_second"] = detailed.PacketsPerSecond
+	if detailed.HTBStats != nil {
+		view.DetailedStats["htb_direct_packets"] = detailed.HTBStats.DirectPackets
+		view.DetailedStats["htb_version"] = detailed.HTBStats.Version
+		view.HTB = &models.HTBQdiscStatisticsView{
+			DirectPackets: detailed.HTBStats.DirectPackets,
+			Version:       detailed.HTBStats.Version,
+		}
+	}
+	if detailed.CAKEStats != nil {
+		AddCAKEStatistics(&view, detailed.CAKEStats)
+	}
+	if detailed.FQCodelStats != nil {
+		view.FQCodel = &models.FQCodelStatisticsView{
+			MaxPacket:      detailed.FQCodelStats.MaxPacket,
+			DropOverlimit:  detailed.FQCodelStats.DropOverlimit,
+			DropOvermemory: detailed.FQCodelStats.DropOvermemory,
+			ECNMarks:       detailed.FQCodelStats.ECNMarks,
+			CEMarks:        detailed.FQCodelStats.CEMarks,
+			NewFlowCount:   detailed.FQCodelStats.NewFlowCount,
+			NewFlows:       detailed.FQCodelStats.NewFlows,
+			OldFlows:       detailed.FQCodelStats.OldFlows,
+			MemoryUsage:    detailed.FQCodelStats.MemoryUsage,
+		}
+	}
+	if detailed.CodelStats != nil {
+		view.Codel = &models.CodelStatisticsView{
+			MaxPacket:     detailed.CodelStats.MaxPacket,
+			Count:         detailed.CodelStats.Count,
+			LastCount:     detailed.CodelStats.LastCount,
+			LDelayUs:      uint32(detailed.CodelStats.LDelay.Microseconds()),  // #nosec G115 - from a uint32
+			DropNextUs:    int32(detailed.CodelStats.DropNext.Microseconds()), // #nosec G115 - from an int32
+			DropOverlimit: detailed.CodelStats.DropOverlimit,
+			ECNMarks:      detailed.CodelStats.ECNMarks,
+			CEMarks:       detailed.CodelStats.CEMarks,
+			Dropping:      detailed.CodelStats.Dropping,
+		}
+	}
+	if detailed.SFQStats != nil {
+		view.SFQ = &models.SFQStatisticsView{
+			ActiveFlows: detailed.SFQStats.ActiveFlows,
+			FlowBuckets: detailed.SFQStats.FlowBuckets,
+			Limit:       detailed.SFQStats.Limit,
+		}
+	}
+
+	return view
+}
+
+// AddCAKEStatistics adds CAKE qdisc and per-tin statistics to a qdisc view
+func AddCAKEStatistics(view *models.QdiscStatisticsView, stats *netlink.CAKEQdiscStats) {
+	view.DetailedStats["cake_capacity_estimate_bps"] = stats.CapacityEstimate * 8
+	view.DetailedStats["cake_memory_limit"] = stats.MemoryLimit
+	view.DetailedStats["cake_memory_used"] = stats.MemoryUsed
+
+	view.CAKETins = make([]models.CAKETinStatisticsView, 0, len(stats.Tins))
+	for _, tin := range stats.Tins {
+		view.CAKETins = append(view.CAKETins, models.CAKETinStatisticsView{
+			Name:              tin.Name,
+			PacketsSent:       tin.SentPackets,
+			BytesSent:         tin.SentBytes,
+			PacketsDropped:    tin.DroppedPackets,
+			BytesDropped:      tin.DroppedBytes,
+			AckDrops:          tin.AckDropPackets,
+			ECNMarks:          tin.ECNMarkedPackets,
+			BacklogPackets:    tin.BacklogPackets,
+			BacklogBytes:      tin.BacklogBytes,
+			ThresholdRateBPS:  tin.ThresholdRate * 8,
+			TargetUs:          tin.TargetUs,
+			IntervalUs:        tin.IntervalUs,
+			PeakDelayUs:       tin.PeakDelayUs,
+			AvgDelayUs:        tin.AvgDelayUs,
+			BaseDelayUs:       tin.BaseDelayUs,
+			SparseFlows:       tin.SparseFlows,
+			BulkFlows:         tin.BulkFlows,
+			UnresponsiveFlows: tin.UnresponsiveFlows,
+		})
+	}
+}
+
 // GetClassStatisticsHandler handles queries for class statistics
 type GetClassStatisticsHandler struct {
 	netlinkAdapter netlink.Adapter
@@ -527,7 +658,7 @@ func (h *GetClassStatisticsHandler) Handle(ctx context.Context, query interface{
 				Name:           "", // Name not available from netlink directly
 				BytesSent:      class.Statistics.BytesSent,
 				PacketsSent:    class.Statistics.PacketsSent,
-				BytesDropped:   class.Statistics.BytesDropped,
+				PacketsDropped: class.Statistics.PacketsDropped,
 				Overlimits:     class.Statistics.Overlimits,
 				BacklogBytes:   class.Statistics.BacklogBytes,
 				BacklogPackets: class.Statistics.BacklogPackets,
@@ -549,6 +680,15 @@ func (h *GetClassStatisticsHandler) Handle(ctx context.Context, query interface{
 					view.DetailedStats["htb_ceil"] = detailedStats.HTBStats.Ceil
 					view.DetailedStats["htb_level"] = detailedStats.HTBStats.Level
 				}
+				if detailedStats.RedirectedPackets > 0 {
+					view.DetailedStats["redirected_bytes"] = detailedStats.RedirectedBytes
+					view.DetailedStats["redirected_packets"] = detailedStats.RedirectedPackets
+				}
+			}
+
+			if leaf := leafQdiscStatistics(h.netlinkAdapter, classQuery.DeviceName(), class.Handle); leaf != nil {
+				leafView := NewQdiscStatisticsView(leaf.Handle, leaf.Type, leaf.Stats, leaf.DetailedStats)
+				view.LeafQdisc = &leafView
 			}
 
 			return view, nil
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/rng999/traffic-control-go/internal/domain/entities"
 	"github.com/rng999/traffic-control-go/pkg/tc"
 )
@@ -39,9 +41,11 @@ type FilterView struct {
 	Parent     string            `json:"parent"`
 	Priority   uint16            `json:"priority"`
 	Handle     string            `json:"handle"`
+	Kind       string            `json:"kind"`
 	Protocol   string            `json:"protocol"`
 	FlowID     string            `json:"flow_id"`
 	Matches    map[string]string `json:"matches"`
+	Actions    []string          `json:"actions,omitempty"`
 }
 
 // MatchView is a read model for filter matches
@@ -131,9 +135,10 @@ func NewClassView(device tc.DeviceName, class interface{}) ClassView {
 func NewFilterView(device tc.DeviceName, filter *entities.Filter) FilterView {
 	view := FilterView{
 		DeviceName: device.String(),
-		Parent:     filter.ID().String(),
+		Parent:     filter.ID().Parent().String(),
 		Priority:   filter.ID().Priority(),
 		Handle:     filter.ID().Handle().String(),
+		Kind:       filter.Kind().String(),
 		FlowID:     filter.FlowID().String(),
 		Matches:    make(map[string]string),
 	}
@@ -153,6 +158,11 @@ func NewFilterView(device tc.DeviceName, filter *entities.Filter) FilterView {
 		view.Matches[getMatchTypeName(match.Type())] = match.String()
 	}
 
+	// Convert actions, keeping their execution order
+	for _, action := range filter.Actions() {
+		view.Actions = append(view.Actions, action.String())
+	}
+
 	return view
 }
 
@@ -170,6 +180,18 @@ func getMatchTypeName(matchType entities.MatchType) string {
 		return "Protocol"
 	case entities.MatchTypeMark:
 		return "Firewall Mark"
+	case entities.MatchTypePortRange:
+		return "Port Range"
+	case entities.MatchTypeMACSource:
+		return "Source MAC"
+	case entities.MatchTypeMACDestination:
+		return "Destination MAC"
+	case entities.MatchTypeVLANID:
+		return "VLAN ID"
+	case entities.MatchTypeVLANPriority:
+		return "VLAN Priority"
+	case entities.MatchTypeCgroup:
+		return "Cgroup"
 	default:
 		return "Unknown"
 	}
@@ -191,6 +213,63 @@ type ConfigurationView struct {
 	Version    int          `json:"version"`
 }
 
+// PlanView is the fully resolved configuration an apply would install, including the
+// values the library computes for what the configuration leaves out
+type PlanView struct {
+	DeviceName string              `json:"device_name"`
+	Qdiscs     []PlannedQdiscView  `json:"qdiscs"`
+	Classes    []PlannedClassView  `json:"classes"`
+	Filters    []PlannedFilterView `json:"filters"`
+	// Foreign lists the objects of other tools on the device, which Apply leaves in place;
+	// only reported under the coexistence ownership policy
+	Foreign []ForeignObjectView `json:"foreign,omitempty"`
+}
+
+// PlannedQdiscView is a qdisc of a plan with its parameters, such as the default class
+// of an HTB qdisc or the priority-to-band map of a PRIO qdisc
+type PlannedQdiscView struct {
+	Handle     string                 `json:"handle"`
+	Parent     string                 `json:"parent,omitempty"`
+	Type       string                 `json:"type"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// PlannedClassView is a class of a plan with its generated handle and computed HTB
+// parameters. Bursts and quantum are in bytes.
+type PlannedClassView struct {
+	Name     string `json:"name"`
+	Handle   string `json:"handle"`
+	Parent   string `json:"parent"`
+	Rate     string `json:"rate"`
+	Ceil     string `json:"ceil"`
+	Priority int    `json:"priority"` // Priority as configured
+	HTBPrio  uint32 `json:"htb_prio"` // Priority HTB schedules the class with, 0 is served first
+	Burst    uint32 `json:"burst"`
+	Cburst   uint32 `json:"cburst"`
+	Quantum  uint32 `json:"quantum"`
+	Weight   uint32 `json:"weight,omitempty"` // QFQ share relative to the sibling classes
+	Lmax     uint32 `json:"lmax,omitempty"`   // QFQ largest packet in bytes
+}
+
+// PlannedFilterView is a filter of a plan with the priority it was given and the class
+// it sends packets to
+type PlannedFilterView struct {
+	Parent   string   `json:"parent"`
+	Priority uint16   `json:"priority"`
+	Kind     string   `json:"kind"`
+	Protocol string   `json:"protocol"`
+	FlowID   string   `json:"flow_id"`
+	Class    string   `json:"class,omitempty"` // Name of the class at FlowID
+	Matches  []string `json:"matches"`
+	Actions  []string `json:"actions,omitempty"`
+}
+
+// ForeignObjectView is a qdisc, class or filter installed on the device by another tool
+type ForeignObjectView struct {
+	Object string `json:"object"` // e.g. "qdisc 8001:" or "filter 8001: prio 1 flowid 8001:1"
+	Parent string `json:"parent,omitempty"`
+}
+
 // DeviceStatisticsView represents statistics for a device
 type DeviceStatisticsView struct {
 	DeviceName  string                 `json:"device_name"`
@@ -203,16 +282,83 @@ type DeviceStatisticsView struct {
 
 // QdiscStatisticsView represents qdisc statistics with metadata
 type QdiscStatisticsView struct {
-	Handle        string                 `json:"handle"`
-	Type          string                 `json:"type"`
-	BytesSent     uint64                 `json:"bytes_sent"`
-	PacketsSent   uint64                 `json:"packets_sent"`
-	BytesDropped  uint64                 `json:"bytes_dropped"`
-	Overlimits    uint64                 `json:"overlimits"`
-	Requeues      uint64                 `json:"requeues"`
-	Backlog       uint32                 `json:"backlog"`
-	QueueLength   uint32                 `json:"queue_length"`
-	DetailedStats map[string]interface{} `json:"detailed_stats,omitempty"`
+	Handle         string                  `json:"handle"`
+	Type           string                  `json:"type"`
+	BytesSent      uint64                  `json:"bytes_sent"`
+	PacketsSent    uint64                  `json:"packets_sent"`
+	PacketsDropped uint64                  `json:"packets_dropped"`
+	Overlimits     uint64                  `json:"overlimits"`
+	Requeues       uint64                  `json:"requeues"`
+	Backlog        uint32                  `json:"backlog"`
+	QueueLength    uint32                  `json:"queue_length"`
+	DetailedStats  map[string]interface{}  `json:"detailed_stats,omitempty"`
+	CAKETins       []CAKETinStatisticsView `json:"cake_tins,omitempty"`
+	FQCodel        *FQCodelStatisticsView  `json:"fq_codel,omitempty"`
+	SFQ            *SFQStatisticsView      `json:"sfq,omitempty"`
+	HTB            *HTBQdiscStatisticsView `json:"htb,omitempty"`
+	Codel          *CodelStatisticsView    `json:"codel,omitempty"`
+	Rates          *CounterRatesView       `json:"rates,omitempty"` // Since the previous query; nil on the first
+}
+
+// CAKETinStatisticsView represents the statistics of a CAKE priority tin
+type CAKETinStatisticsView struct {
+	Name              string `json:"name"`
+	PacketsSent       uint32 `json:"packets_sent"`
+	BytesSent         uint64 `json:"bytes_sent"`
+	PacketsDropped    uint32 `json:"packets_dropped"`
+	BytesDropped      uint64 `json:"bytes_dropped"`
+	AckDrops          uint32 `json:"ack_drops"`
+	ECNMarks          uint32 `json:"ecn_marks"`
+	BacklogPackets    uint32 `json:"backlog_packets"`
+	BacklogBytes      uint32 `json:"backlog_bytes"`
+	ThresholdRateBPS  uint64 `json:"threshold_rate_bps"`
+	TargetUs          uint32 `json:"target_us"`
+	IntervalUs        uint32 `json:"interval_us"`
+	PeakDelayUs       uint32 `json:"peak_delay_us"`
+	AvgDelayUs        uint32 `json:"avg_delay_us"`
+	BaseDelayUs       uint32 `json:"base_delay_us"`
+	SparseFlows       uint32 `json:"sparse_flows"`
+	BulkFlows         uint32 `json:"bulk_flows"`
+	UnresponsiveFlows uint32 `json:"unresponsive_flows"`
+}
+
+// FQCodelStatisticsView represents the drop and flow counters of an fq_codel qdisc
+type FQCodelStatisticsView struct {
+	MaxPacket      uint32 `json:"max_packet"`
+	DropOverlimit  uint32 `json:"drop_overlimit"`
+	DropOvermemory uint32 `json:"drop_overmemory"`
+	ECNMarks       uint32 `json:"ecn_marks"`
+	CEMarks        uint32 `json:"ce_marks"`
+	NewFlowCount   uint32 `json:"new_flow_count"`
+	NewFlows       uint32 `json:"new_flows"`
+	OldFlows       uint32 `json:"old_flows"`
+	MemoryUsage    uint32 `json:"memory_usage"`
+}
+
+// SFQStatisticsView represents the flow state of an SFQ qdisc
+type SFQStatisticsView struct {
+	ActiveFlows uint32 `json:"active_flows"`
+	FlowBuckets uint32 `json:"flow_buckets"`
+	Limit       uint32 `json:"limit"`
+}
+
+// HTBQdiscStatisticsView represents the counters of an HTB qdisc
+type HTBQdiscStatisticsView struct {
+	DirectPackets uint32 `json:"direct_packets"` // Packets sent unshaped, matching no class
+	Version       uint32 `json:"version"`
+}
+
+// CodelStatisticsView represents the drop state of a CoDel qdisc
+type CodelStatisticsView struct {
+	MaxPacket     uint32 `json:"max_packet"`
+	Count         uint32 `json:"count"`      // Drops since entering the dropping state
+	LastCount     uint32 `json:"last_count"` // Count when the dropping state was entered last
+	LDelayUs      uint32 `json:"ldelay_us"`  // Queueing delay of the packet dequeued last
+	DropNextUs    int32  `json:"drop_next_us"`
+	DropOverlimit uint32 `json:"drop_overlimit"`
+	ECNMarks      uint32 `json:"ecn_marks"`
+	CEMarks       uint32 `json:"ce_marks"`
+	Dropping      bool   `json:"dropping"`
 }
 
 // ClassStatisticsView represents class statistics with metadata
@@ -222,12 +368,20 @@ type ClassStatisticsView struct {
 	Name           string                 `json:"name"`
 	BytesSent      uint64                 `json:"bytes_sent"`
 	PacketsSent    uint64                 `json:"packets_sent"`
-	BytesDropped   uint64                 `json:"bytes_dropped"`
+	PacketsDropped uint64                 `json:"packets_dropped"`
 	Overlimits     uint64                 `json:"overlimits"`
 	BacklogBytes   uint64                 `json:"backlog_bytes"`
 	BacklogPackets uint64                 `json:"backlog_packets"`
 	RateBPS        uint64                 `json:"rate_bps"`
 	DetailedStats  map[string]interface{} `json:"detailed_stats,omitempty"`
+	LeafQdisc      *QdiscStatisticsView   `json:"leaf_qdisc,omitempty"` // Qdisc attached under the class
+	Rates          *CounterRatesView      `json:"rates,omitempty"`      // Since the previous query; nil on the first
+}
+
+// CounterRatesView represents the bytes and packets per second of a qdisc or class
+type CounterRatesView struct {
+	BytesPerSecond   float64 `json:"bytes_per_second"`
+	PacketsPerSecond float64 `json:"packets_per_second"`
 }
 
 // FilterStatisticsView represents filter statistics with metadata
@@ -251,3 +405,16 @@ type LinkStatisticsView struct {
 	RxDropped uint64 `json:"rx_dropped"`
 	TxDropped uint64 `json:"tx_dropped"`
 }
+
+// AuditEntryView describes one recorded configuration change
+type AuditEntryView struct {
+	Time    time.Time              `json:"time"`
+	Actor   string                 `json:"actor,omitempty"`
+	Device  string                 `json:"device"`
+	Version int                    `json:"version"`
+	Event   string                 `json:"event"`
+	Object  string                 `json:"object"` // qdisc, class or filter
+	Action  string                 `json:"action"` // added, modified or deleted
+	Handle  string                 `json:"handle,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
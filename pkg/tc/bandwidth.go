@@ -127,25 +127,106 @@ func (b Bandwidth) LessThan(other Bandwidth) bool {
 	return b.value < other.value
 }
 
+// GreaterOrEqual checks if this bandwidth is at least another
+func (b Bandwidth) GreaterOrEqual(other Bandwidth) bool {
+	return b.value >= other.value
+}
+
+// LessOrEqual checks if this bandwidth is at most another
+func (b Bandwidth) LessOrEqual(other Bandwidth) bool {
+	return b.value <= other.value
+}
+
+// Compare returns -1, 0 or 1 as this bandwidth is less than, equal to or greater than another
+func (b Bandwidth) Compare(other Bandwidth) int {
+	switch {
+	case b.value < other.value:
+		return -1
+	case b.value > other.value:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsZero checks if the bandwidth is zero
+func (b Bandwidth) IsZero() bool {
+	return b.value == 0
+}
+
+// MinBandwidth returns the smaller of two bandwidths
+func MinBandwidth(a, b Bandwidth) Bandwidth {
+	if a.value < b.value {
+		return a
+	}
+	return b
+}
+
+// MaxBandwidth returns the larger of two bandwidths
+func MaxBandwidth(a, b Bandwidth) Bandwidth {
+	if a.value > b.value {
+		return a
+	}
+	return b
+}
+
 // Add returns a new Bandwidth that is the sum of two bandwidths
 func (b Bandwidth) Add(other Bandwidth) Bandwidth {
 	return Bandwidth{value: b.value + other.value}
 }
 
-// Subtract returns a new Bandwidth that is the difference of two bandwidths
-func (b Bandwidth) Subtract(other Bandwidth) Bandwidth {
+// Sub returns a new Bandwidth that is the difference of two bandwidths, zero when the
+// other is larger
+func (b Bandwidth) Sub(other Bandwidth) Bandwidth {
 	if b.value < other.value {
 		return Bandwidth{value: 0}
 	}
 	return Bandwidth{value: b.value - other.value}
 }
 
-// MultiplyBy returns a new Bandwidth multiplied by a factor
-func (b Bandwidth) MultiplyBy(factor float64) Bandwidth {
+// Subtract is Sub
+func (b Bandwidth) Subtract(other Bandwidth) Bandwidth {
+	return b.Sub(other)
+}
+
+// MulScalar returns a new Bandwidth multiplied by a factor
+func (b Bandwidth) MulScalar(factor float64) Bandwidth {
 	return Bandwidth{value: uint64(float64(b.value) * factor)}
 }
 
+// MultiplyBy is MulScalar
+func (b Bandwidth) MultiplyBy(factor float64) Bandwidth {
+	return b.MulScalar(factor)
+}
+
+// DivScalar returns a new Bandwidth divided by a divisor, zero for a non-positive divisor
+func (b Bandwidth) DivScalar(divisor float64) Bandwidth {
+	if divisor <= 0 {
+		return Bandwidth{value: 0}
+	}
+	return Bandwidth{value: uint64(float64(b.value) / divisor)}
+}
+
 // Percentage returns a percentage of the bandwidth
 func (b Bandwidth) Percentage(percent float64) Bandwidth {
-	return b.MultiplyBy(percent / 100.0)
+	return b.MulScalar(percent / 100.0)
+}
+
+// MarshalText encodes the bandwidth exactly, in bits per second
+func (b Bandwidth) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatUint(b.value, 10) + "bps"), nil
+}
+
+// UnmarshalText decodes a bandwidth in any format ParseBandwidth accepts
+func (b *Bandwidth) UnmarshalText(text []byte) error {
+	if value, err := strconv.ParseUint(strings.TrimSuffix(string(text), "bps"), 10, 64); err == nil {
+		b.value = value
+		return nil
+	}
+	parsed, err := ParseBandwidth(string(text))
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
 }
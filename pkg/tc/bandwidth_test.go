@@ -1,10 +1,12 @@
 package tc_test
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
 
 	"github.com/rng999/traffic-control-go/pkg/tc"
 )
@@ -207,6 +209,20 @@ func TestBandwidthComparisons(t *testing.T) {
 	assert.True(t, b2.LessThan(b1))
 	assert.False(t, b1.LessThan(b2))
 	assert.False(t, b1.LessThan(b3))
+
+	assert.True(t, b1.GreaterOrEqual(b3))
+	assert.False(t, b2.GreaterOrEqual(b1))
+	assert.True(t, b1.LessOrEqual(b3))
+	assert.False(t, b1.LessOrEqual(b2))
+
+	assert.Equal(t, 1, b1.Compare(b2))
+	assert.Equal(t, -1, b2.Compare(b1))
+	assert.Equal(t, 0, b1.Compare(b3))
+
+	assert.Equal(t, b2, tc.MinBandwidth(b1, b2))
+	assert.Equal(t, b1, tc.MaxBandwidth(b1, b2))
+	assert.True(t, tc.Bandwidth{}.IsZero())
+	assert.False(t, b1.IsZero())
 }
 
 func TestBandwidthArithmetic(t *testing.T) {
@@ -237,6 +253,13 @@ func TestBandwidthArithmetic(t *testing.T) {
 		result := b1.Percentage(25)
 		assert.Equal(t, tc.Mbps(25).BitsPerSecond(), result.BitsPerSecond())
 	})
+
+	t.Run("Scalar", func(t *testing.T) {
+		assert.Equal(t, tc.Mbps(50), b1.Sub(b2))
+		assert.Equal(t, tc.Mbps(250), b1.MulScalar(2.5))
+		assert.Equal(t, tc.Mbps(25), b1.DivScalar(4))
+		assert.Equal(t, uint64(0), b1.DivScalar(0).BitsPerSecond())
+	})
 }
 
 func TestBandwidthImmutability(t *testing.T) {
@@ -414,3 +437,37 @@ func BenchmarkBandwidthParsingVsCreation(b *testing.B) {
 		}
 	})
 }
+
+func TestBandwidthText(t *testing.T) {
+	text, err := tc.Bps(1234567).MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, "1234567bps", string(text))
+
+	var b tc.Bandwidth
+	require.NoError(t, b.UnmarshalText(text))
+	assert.Equal(t, uint64(1234567), b.BitsPerSecond())
+	require.NoError(t, b.UnmarshalText([]byte("1.5Mbps")))
+	assert.Equal(t, uint64(1500000), b.BitsPerSecond())
+	assert.Error(t, b.UnmarshalText([]byte("fast")))
+}
+
+func TestBandwidthJSONAndYAML(t *testing.T) {
+	type limits struct {
+		Rate tc.Bandwidth `json:"rate" yaml:"rate"`
+	}
+
+	data, err := json.Marshal(limits{Rate: tc.Gbps(1.5)})
+	require.NoError(t, err)
+	assert.Equal(t, `{"rate":"1500000000bps"}`, string(data))
+
+	var decoded limits
+	require.NoError(t, json.Unmarshal([]byte(`{"rate":"1.5Gbps"}`), &decoded))
+	assert.Equal(t, tc.Gbps(1.5), decoded.Rate)
+	assert.Equal(t, "1.5Gbps", decoded.Rate.String())
+
+	data, err = yaml.Marshal(limits{Rate: tc.Mbps(30)})
+	require.NoError(t, err)
+	assert.Equal(t, "rate: 30000000bps\n", string(data))
+	require.NoError(t, yaml.Unmarshal([]byte("rate: 250kbps\n"), &decoded))
+	assert.Equal(t, tc.Kbps(250), decoded.Rate)
+}
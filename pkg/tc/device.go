@@ -3,8 +3,13 @@ package tc
 import (
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
 )
 
+// MaxVLANID is the highest usable 802.1Q VLAN ID; 4095 is reserved
+const MaxVLANID = 4094
+
 // DeviceName represents a network interface name
 type DeviceName struct {
 	value string
@@ -32,6 +37,15 @@ func MustNewDeviceName(name string) DeviceName {
 	return d
 }
 
+// NewVLANDeviceName returns the conventional name of a VLAN sub-interface of the parent
+// device, such as eth0.100
+func NewVLANDeviceName(parent DeviceName, id uint16) (DeviceName, error) {
+	if id == 0 || id > MaxVLANID {
+		return DeviceName{}, fmt.Errorf("invalid VLAN ID %d (must be 1-%d)", id, MaxVLANID)
+	}
+	return NewDeviceName(fmt.Sprintf("%s.%d", parent, id))
+}
+
 // validateDeviceName checks if the device name is valid
 func validateDeviceName(name string) error {
 	if name == "" {
@@ -56,7 +70,43 @@ func (d DeviceName) String() string {
 	return d.value
 }
 
+// VLAN splits a VLAN sub-interface name, such as eth0.100, into its parent device and
+// VLAN ID. It only looks at the name; a VLAN device may be named otherwise, and the netlink
+// adapter reports the actual parent of a device.
+func (d DeviceName) VLAN() (DeviceName, uint16, bool) {
+	dot := strings.LastIndexByte(d.value, '.')
+	if dot <= 0 || dot == len(d.value)-1 {
+		return DeviceName{}, 0, false
+	}
+
+	id, err := strconv.ParseUint(d.value[dot+1:], 10, 16)
+	if err != nil || id == 0 || id > MaxVLANID {
+		return DeviceName{}, 0, false
+	}
+	return DeviceName{value: d.value[:dot]}, uint16(id), true
+}
+
 // Equals checks if two device names are equal
 func (d DeviceName) Equals(other DeviceName) bool {
 	return d.value == other.value
 }
+
+// MarshalText encodes the device name
+func (d DeviceName) MarshalText() ([]byte, error) {
+	return []byte(d.value), nil
+}
+
+// UnmarshalText decodes and validates a device name; empty text leaves the zero value,
+// which marks an unset device
+func (d *DeviceName) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*d = DeviceName{}
+		return nil
+	}
+	parsed, err := NewDeviceName(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
@@ -350,3 +350,59 @@ func BenchmarkDeviceNameCommonPatterns(b *testing.B) {
 		})
 	}
 }
+
+func TestDeviceNameText(t *testing.T) {
+	text, err := tc.MustNewDeviceName("eth0").MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, "eth0", string(text))
+
+	var d tc.DeviceName
+	require.NoError(t, d.UnmarshalText([]byte("wlan0")))
+	assert.Equal(t, "wlan0", d.String())
+	require.NoError(t, d.UnmarshalText(nil))
+	assert.Equal(t, tc.DeviceName{}, d)
+	assert.Error(t, d.UnmarshalText([]byte("bad name")))
+}
+
+func TestDeviceNameVLAN(t *testing.T) {
+	tests := []struct {
+		input  string
+		parent string
+		id     uint16
+		ok     bool
+	}{
+		{input: "eth0.100", parent: "eth0", id: 100, ok: true},
+		{input: "bond0.4094", parent: "bond0", id: 4094, ok: true},
+		{input: "eth0.1.20", parent: "eth0.1", id: 20, ok: true},
+		{input: "eth0"},
+		{input: "eth0.0"},
+		{input: "eth0.4095"},
+		{input: "eth0.abc"},
+		{input: "eth0."},
+		{input: ".100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			parent, id, ok := tc.MustNewDeviceName(tt.input).VLAN()
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.parent, parent.String())
+				assert.Equal(t, tt.id, id)
+			}
+		})
+	}
+}
+
+func TestNewVLANDeviceName(t *testing.T) {
+	d, err := tc.NewVLANDeviceName(tc.MustNewDeviceName("eth0"), 100)
+	require.NoError(t, err)
+	assert.Equal(t, "eth0.100", d.String())
+
+	_, err = tc.NewVLANDeviceName(tc.MustNewDeviceName("eth0"), 0)
+	assert.Error(t, err)
+	_, err = tc.NewVLANDeviceName(tc.MustNewDeviceName("eth0"), 4095)
+	assert.Error(t, err)
+	_, err = tc.NewVLANDeviceName(tc.MustNewDeviceName("enp0s31f6abc"), 100)
+	assert.Error(t, err, "too long for IFNAMSIZ")
+}
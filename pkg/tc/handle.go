@@ -95,3 +95,18 @@ func HandleFromUint32(u uint32) Handle {
 		minor: uint16(u & 0xFFFF), // #nosec G115 - safe conversion masked to 16 bits
 	}
 }
+
+// MarshalText encodes the handle in "major:minor" format
+func (h Handle) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText decodes a handle in "major:minor" format
+func (h *Handle) UnmarshalText(text []byte) error {
+	parsed, err := ParseHandle(string(text))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
@@ -392,3 +392,14 @@ func BenchmarkHandleParsingVsCreation(b *testing.B) {
 		}
 	})
 }
+
+func TestHandleText(t *testing.T) {
+	text, err := tc.NewHandle(1, 0x10).MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, "1:10", string(text))
+
+	var h tc.Handle
+	require.NoError(t, h.UnmarshalText([]byte("ffff:")))
+	assert.Equal(t, tc.NewHandle(0xffff, 0), h)
+	assert.Error(t, h.UnmarshalText([]byte("invalid")))
+}
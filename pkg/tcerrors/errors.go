@@ -0,0 +1,147 @@
+// Package tcerrors defines the typed errors of the library. Every error carries a stable
+// code, the object it is about and, where there is an obvious one, a hint at the fix, so
+// that callers can branch on the cause of a failure with errors.Is and errors.As instead of
+// matching messages:
+//
+//	if errors.Is(err, tcerrors.ErrPermissionDenied) {
+//		// ask for CAP_NET_ADMIN
+//	}
+package tcerrors
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// Code identifies the cause of an error; codes are stable and safe to persist or compare
+type Code string
+
+// Error codes
+const (
+	CodeDeviceNotFound       Code = "device_not_found"
+	CodePermissionDenied     Code = "permission_denied"
+	CodeBandwidthExceeded    Code = "bandwidth_exceeded"
+	CodeHandleConflict       Code = "handle_conflict"
+	CodeNotFound             Code = "not_found"
+	CodeInvalidConfiguration Code = "invalid_configuration"
+	CodeUnsupported          Code = "unsupported"
+)
+
+// Sentinels to compare errors against with errors.Is, which matches any error of the code
+var (
+	ErrDeviceNotFound       = &Error{Code: CodeDeviceNotFound, Message: "device not found"}
+	ErrPermissionDenied     = &Error{Code: CodePermissionDenied, Message: "permission denied"}
+	ErrBandwidthExceeded    = &Error{Code: CodeBandwidthExceeded, Message: "bandwidth exceeded"}
+	ErrHandleConflict       = &Error{Code: CodeHandleConflict, Message: "handle conflict"}
+	ErrNotFound             = &Error{Code: CodeNotFound, Message: "not found"}
+	ErrInvalidConfiguration = &Error{Code: CodeInvalidConfiguration, Message: "invalid configuration"}
+	ErrUnsupported          = &Error{Code: CodeUnsupported, Message: "unsupported"}
+)
+
+// defaultHints are the remediation hints of errors created without one
+var defaultHints = map[Code]string{
+	CodeDeviceNotFound:    "check the device name with `ip link`; the device must exist before it is shaped",
+	CodePermissionDenied:  "run as root or grant the process CAP_NET_ADMIN",
+	CodeBandwidthExceeded: "lower the class bandwidths or raise the total bandwidth of the device",
+	CodeHandleConflict:    "delete the existing object, or reset the device, before applying",
+	CodeUnsupported:       "load the kernel module of the qdisc or filter, or use a newer kernel",
+}
+
+// Error is an error of the library with a code identifying its cause
+type Error struct {
+	Code    Code
+	Entity  string // Object the error is about, e.g. "device eth0" or "class 1:11"; empty when none
+	Message string
+	Hint    string // What the caller can do about it; empty when there is nothing obvious
+	Err     error  // Underlying cause, nil when none
+}
+
+// New creates an error of the code about the entity, with the code's default hint
+func New(code Code, entity, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Entity: entity, Message: fmt.Sprintf(format, args...), Hint: defaultHints[code]}
+}
+
+// Wrap creates an error of the code about the entity caused by err
+func Wrap(err error, code Code, entity, format string, args ...interface{}) *Error {
+	e := New(code, entity, format, args...)
+	e.Err = err
+	return e
+}
+
+// WithHint returns a copy of the error with its remediation hint replaced. The receiver is
+// left alone, as it may be one of the shared sentinels.
+func (e *Error) WithHint(hint string) *Error {
+	c := *e
+	c.Hint = hint
+	return &c
+}
+
+// Error implements the error interface. The entity and hint are left out: messages already
+// name the object they are about, and the hint is advice for the user, to be shown apart
+// from the failure as the traffic-control command does with HintOf.
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Message
+	case e.Message == "":
+		return e.Err.Error()
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an Error of the same code, so that every error of a code
+// matches its sentinel
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// CodeOf returns the code of the first Error in the chain of err, or "" when there is none
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// HintOf returns the remediation hint of the first Error in the chain of err, or ""
+func HintOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Hint
+	}
+	return ""
+}
+
+// FromErrno wraps an error returned by the kernel, giving it the code of its errno. An error
+// without a known errno is wrapped with fmt.Errorf and keeps its message.
+func FromErrno(err error, entity, format string, args ...interface{}) error {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return fmt.Errorf(format+": %w", append(args, err)...)
+	}
+
+	var code Code
+	switch errno {
+	case syscall.EPERM, syscall.EACCES:
+		code = CodePermissionDenied
+	case syscall.EEXIST:
+		code = CodeHandleConflict
+	case syscall.ENODEV:
+		code = CodeDeviceNotFound
+	case syscall.ENOENT:
+		code = CodeNotFound
+	case syscall.EOPNOTSUPP:
+		code = CodeUnsupported
+	default:
+		return fmt.Errorf(format+": %w", append(args, err)...)
+	}
+	return Wrap(err, code, entity, format, args...)
+}
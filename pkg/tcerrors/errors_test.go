@@ -0,0 +1,70 @@
+package tcerrors
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError_MatchesSentinelOfCode(t *testing.T) {
+	err := fmt.Errorf("apply failed: %w", New(CodeDeviceNotFound, "device eth9", "device %s does not exist", "eth9"))
+
+	assert.True(t, errors.Is(err, ErrDeviceNotFound))
+	assert.False(t, errors.Is(err, ErrPermissionDenied))
+	assert.Equal(t, "apply failed: device eth9 does not exist", err.Error())
+	assert.Equal(t, CodeDeviceNotFound, CodeOf(err))
+	assert.Contains(t, HintOf(err), "ip link")
+
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "device eth9", e.Entity)
+}
+
+func TestError_WrapKeepsCause(t *testing.T) {
+	cause := errors.New("link not found")
+	err := Wrap(cause, CodeDeviceNotFound, "device eth9", "failed to find device %s", "eth9").WithHint("create it first")
+
+	assert.Equal(t, "failed to find device eth9: link not found", err.Error())
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, "create it first", err.Hint)
+}
+
+func TestError_WithHintLeavesReceiver(t *testing.T) {
+	err := ErrUnsupported.WithHint("use the netlink backend")
+
+	assert.Equal(t, "use the netlink backend", err.Hint)
+	assert.Empty(t, ErrUnsupported.Hint, "the sentinel is shared")
+	assert.True(t, errors.Is(err, ErrUnsupported))
+
+	base := New(CodeDeviceNotFound, "device eth9", "device %s does not exist", "eth9")
+	_ = base.WithHint("create it first")
+	assert.Contains(t, base.Hint, "ip link")
+}
+
+func TestFromErrno(t *testing.T) {
+	tests := []struct {
+		errno syscall.Errno
+		code  Code
+	}{
+		{syscall.EPERM, CodePermissionDenied},
+		{syscall.EACCES, CodePermissionDenied},
+		{syscall.EEXIST, CodeHandleConflict},
+		{syscall.ENODEV, CodeDeviceNotFound},
+		{syscall.ENOENT, CodeNotFound},
+		{syscall.EOPNOTSUPP, CodeUnsupported},
+		{syscall.EINVAL, ""},
+	}
+
+	for _, tt := range tests {
+		err := FromErrno(tt.errno, "qdisc 1:", "failed to add qdisc")
+		assert.Equal(t, tt.code, CodeOf(err), tt.errno.Error())
+		assert.Equal(t, "failed to add qdisc: "+tt.errno.Error(), err.Error())
+		assert.True(t, errors.Is(err, tt.errno))
+	}
+
+	assert.Equal(t, Code(""), CodeOf(FromErrno(errors.New("boom"), "", "failed to add %s", "qdisc")))
+	assert.Equal(t, Code(""), CodeOf(nil))
+}
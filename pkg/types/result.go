@@ -82,6 +82,25 @@ func (r Result[T]) OrElse(defaultValue T) T {
 	return r.value
 }
 
+// FromValue creates a Result from the value and error pair returned by most Go functions.
+func FromValue[T any](value T, err error) Result[T] {
+	if err != nil {
+		return Failure[T](err)
+	}
+
+	return Success(value)
+}
+
+// AndThen applies a function returning a Result of another type if successful, so that
+// steps producing different types can be chained.
+func AndThen[T, U any](r Result[T], f func(T) Result[U]) Result[U] {
+	if r.err != nil {
+		return Failure[U](r.err)
+	}
+
+	return f(r.value)
+}
+
 // Option represents a value that may or may not be present.
 type Option[T any] struct {
 	value   *T
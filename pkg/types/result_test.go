@@ -0,0 +1,39 @@
+package types
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResult_FromValue(t *testing.T) {
+	assert.Equal(t, 42, FromValue(strconv.Atoi("42")).Value())
+
+	result := FromValue(strconv.Atoi("x"))
+	assert.True(t, result.IsFailure())
+	assert.Equal(t, 0, result.OrElse(0))
+}
+
+func TestResult_AndThen(t *testing.T) {
+	parse := func(s string) Result[int] { return FromValue(strconv.Atoi(s)) }
+	positive := func(n int) Result[bool] {
+		if n <= 0 {
+			return Failure[bool](errors.New("not positive"))
+		}
+		return Success(true)
+	}
+
+	assert.True(t, AndThen(parse("7"), positive).Value())
+	assert.EqualError(t, AndThen(parse("-7"), positive).Error(), "not positive")
+
+	// A failure skips the rest of the chain
+	called := false
+	result := AndThen(parse("x"), func(n int) Result[bool] {
+		called = true
+		return Success(true)
+	})
+	assert.True(t, result.IsFailure())
+	assert.False(t, called)
+}
@@ -98,7 +98,7 @@ func TestStatisticsIntegration(t *testing.T) {
 		assert.NotEmpty(t, stats.Type)
 
 		t.Logf("Qdisc 1:0: %d bytes sent, %d packets sent, %d dropped",
-			stats.BytesSent, stats.PacketsSent, stats.BytesDropped)
+			stats.BytesSent, stats.PacketsSent, stats.PacketsDropped)
 
 		// Verify detailed stats if available
 		if len(stats.DetailedStats) > 0 {
@@ -35,11 +35,11 @@ func TestMockAdapter_QdiscStatistics(t *testing.T) {
 	// Set mock statistics
 	handle := tc.NewHandle(1, 0)
 	stats := netlink.QdiscStats{
-		BytesSent:    1000000,
-		PacketsSent:  10000,
-		BytesDropped: 100,
-		Overlimits:   10,
-		Requeues:     5,
+		BytesSent:      1000000,
+		PacketsSent:    10000,
+		PacketsDropped: 100,
+		Overlimits:     10,
+		Requeues:       5,
 	}
 
 	adapter.SetQdiscStatistics(device, handle, stats)
@@ -60,7 +60,7 @@ func TestMockAdapter_ClassStatistics(t *testing.T) {
 	stats := netlink.ClassStats{
 		BytesSent:      2000000,
 		PacketsSent:    20000,
-		BytesDropped:   200,
+		PacketsDropped: 200,
 		Overlimits:     15,
 		RateBPS:        100000000,
 		BacklogBytes:   5000,